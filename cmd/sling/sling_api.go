@@ -0,0 +1,575 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/sling"
+	"github.com/slingdata-io/sling-cli/core/store"
+	"github.com/spf13/cast"
+	"gopkg.in/yaml.v2"
+)
+
+var cliAPI = &g.CliSC{
+	Name:                  "api",
+	Description:           "Serve a REST API to submit replication / task configs as jobs, query their status and history, and cancel them",
+	AdditionalHelpPrepend: "\nSee more details at https://docs.slingdata.io/sling-cli/",
+	ExecuteWithoutFlags:   true,
+	Flags: []g.Flag{
+		{
+			Name:        "host",
+			ShortName:   "",
+			Type:        "string",
+			Description: "The host to listen on (default localhost). A token is required for a non-loopback host.",
+		},
+		{
+			Name:        "port",
+			ShortName:   "p",
+			Type:        "string",
+			Description: "The port to listen on (default 8816).",
+		},
+		{
+			Name:        "token",
+			ShortName:   "",
+			Type:        "string",
+			Description: "The bearer token required from clients (default from the SLING_API_TOKEN env var).",
+		},
+		{
+			Name:        "max-jobs",
+			ShortName:   "",
+			Type:        "string",
+			Description: "The maximum number of jobs running concurrently, others are queued (default 4).",
+		},
+		{
+			Name:        "allow-commands",
+			ShortName:   "",
+			Type:        "bool",
+			Description: "Allow the submitted jobs to have hooks running local commands (command, and dbt without job_id).",
+		},
+	},
+	ExecProcess: processAPI,
+}
+
+func processAPI(c *g.CliSC) (ok bool, err error) {
+	ok = true
+
+	host := cast.ToString(c.Vals["host"])
+	if host == "" {
+		host = "localhost"
+	}
+	port := cast.ToString(c.Vals["port"])
+	if port == "" {
+		port = "8816"
+	}
+	token := cast.ToString(c.Vals["token"])
+	if token == "" {
+		token = os.Getenv("SLING_API_TOKEN")
+	}
+	if token == "" && !isLoopbackHost(host) {
+		return ok, g.Error("a token is required to serve on %s, which is not a loopback host (see --token or SLING_API_TOKEN)", host)
+	}
+	maxJobs := cast.ToInt(c.Vals["max-jobs"])
+	if maxJobs <= 0 {
+		maxJobs = 4
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return ok, g.Error(err, "could not listen on %s:%s", host, port)
+	}
+
+	sling.ShowProgress = false
+	server := newAPIServer(token, maxJobs)
+	server.allowCommands = cast.ToBool(c.Vals["allow-commands"])
+	return ok, server.Serve(ctx.Ctx, listener)
+}
+
+// apiServer runs the submitted jobs with a pool of workers. Endpoints:
+//   - `POST /jobs`: submits a replication or task config (YAML or JSON), returns the job
+//   - `GET /jobs`: lists the jobs of the server (queued, running and finished)
+//   - `GET /jobs/{id}`: returns the job, with the status of each stream
+//   - `DELETE /jobs/{id}`: cancels a queued or running job
+//   - `GET /history`: lists the past runs from the store (`last` and `stream` params)
+//   - `GET /history/{exec_id}`: returns the stream runs of a past run
+type apiServer struct {
+	token         string
+	maxJobs       int
+	allowCommands bool // whether jobs can have hooks running local commands
+
+	jobs  map[string]*apiJob
+	order []string // the job ids, in submission order
+	queue chan *apiJob
+	ctx   *g.Context
+	mux   sync.Mutex
+}
+
+// apiMaxBodySize is the max size of a submitted config
+var apiMaxBodySize int64 = 10 * 1024 * 1024
+
+// apiJob is a replication or task submitted to the server
+type apiJob struct {
+	ID        string           `json:"id"` // the exec_id of the run
+	Kind      string           `json:"kind"`
+	Status    sling.ExecStatus `json:"status"`
+	Error     string           `json:"error,omitempty"`
+	Streams   []apiJobStream   `json:"streams"`
+	CreatedAt time.Time        `json:"created_at"`
+	StartTime *time.Time       `json:"start_time,omitempty"`
+	EndTime   *time.Time       `json:"end_time,omitempty"`
+
+	replication sling.ReplicationConfig
+	tasks       []*sling.TaskExecution
+	ctx         *g.Context
+}
+
+// apiJobStream is the status of a stream of a job
+type apiJobStream struct {
+	Stream string           `json:"stream"`
+	Status sling.ExecStatus `json:"status"`
+	Rows   uint64           `json:"rows"`
+	Bytes  uint64           `json:"bytes"`
+	Error  string           `json:"error,omitempty"`
+}
+
+func newAPIServer(token string, maxJobs int) *apiServer {
+	return &apiServer{
+		token:   token,
+		maxJobs: maxJobs,
+		jobs:    map[string]*apiJob{},
+		queue:   make(chan *apiJob, 10000),
+	}
+}
+
+// Serve starts the workers and serves on the listener, until the context is done
+func (s *apiServer) Serve(parent context.Context, listener net.Listener) (err error) {
+	s.start(parent)
+	server := &http.Server{Handler: s.handler()}
+
+	go func() {
+		<-s.ctx.Ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	g.Info("serving on http://%s (max %d concurrent jobs)", listener.Addr(), s.maxJobs)
+	if err = server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return g.Error(err, "could not serve")
+	}
+	return nil
+}
+
+// start starts the workers, until the context is done
+func (s *apiServer) start(parent context.Context) {
+	s.ctx = g.NewContext(parent)
+	for i := 0; i < s.maxJobs; i++ {
+		go s.work()
+	}
+}
+
+// handler returns the handler of the endpoints
+func (s *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", s.handleSubmit)
+	mux.HandleFunc("GET /jobs", s.handleJobs)
+	mux.HandleFunc("GET /jobs/{id}", s.handleJob)
+	mux.HandleFunc("DELETE /jobs/{id}", s.handleCancel)
+	mux.HandleFunc("GET /history", s.handleHistory)
+	mux.HandleFunc("GET /history/{exec_id}", s.handleHistory)
+	return s.authorize(mux)
+}
+
+// authorize checks the bearer token of the requests
+func (s *apiServer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if s.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			apiRespond(w, http.StatusUnauthorized, g.M("error", "invalid or missing token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func apiRespond(w http.ResponseWriter, code int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write([]byte(g.Marshal(payload)))
+}
+
+func (s *apiServer) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, apiMaxBodySize))
+	if maxErr := (*http.MaxBytesError)(nil); errors.As(err, &maxErr) {
+		apiRespond(w, http.StatusRequestEntityTooLarge, g.M("error", g.F("the config is larger than %d bytes", maxErr.Limit)))
+		return
+	} else if err != nil {
+		apiRespond(w, http.StatusBadRequest, g.M("error", "could not read body: "+err.Error()))
+		return
+	}
+
+	job, err := newAPIJob(string(body))
+	if err != nil {
+		apiRespond(w, http.StatusBadRequest, g.M("error", g.ErrMsg(err)))
+		return
+	} else if ids := commandHooks(job.replication); len(ids) > 0 && !s.allowCommands {
+		apiRespond(w, http.StatusForbidden, g.M("error", g.F("hooks running local commands are not allowed (%s), see --allow-commands", strings.Join(ids, ", "))))
+		return
+	}
+	job.ctx = g.NewContext(s.ctx.Ctx)
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	select {
+	case s.queue <- job:
+	default:
+		apiRespond(w, http.StatusServiceUnavailable, g.M("error", "the job queue is full"))
+		return
+	}
+	s.jobs[job.ID] = job
+	s.order = append(s.order, job.ID)
+
+	// forget the jobs finished for a day
+	order := []string{}
+	for _, id := range s.order {
+		if j := s.jobs[id]; j.EndTime != nil && time.Since(*j.EndTime) > 24*time.Hour {
+			delete(s.jobs, id)
+			continue
+		}
+		order = append(order, id)
+	}
+	s.order = order
+
+	apiRespond(w, http.StatusAccepted, job.snapshot())
+}
+
+func (s *apiServer) handleJobs(w http.ResponseWriter, r *http.Request) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	jobs := []apiJob{}
+	for _, id := range s.order {
+		jobs = append(jobs, s.jobs[id].snapshot())
+	}
+	apiRespond(w, http.StatusOK, jobs)
+}
+
+func (s *apiServer) handleJob(w http.ResponseWriter, r *http.Request) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	job, ok := s.jobs[r.PathValue("id")]
+	if !ok {
+		apiRespond(w, http.StatusNotFound, g.M("error", "job not found: "+r.PathValue("id")))
+		return
+	}
+	apiRespond(w, http.StatusOK, job.snapshot())
+}
+
+func (s *apiServer) handleCancel(w http.ResponseWriter, r *http.Request) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	job, ok := s.jobs[r.PathValue("id")]
+	if !ok {
+		apiRespond(w, http.StatusNotFound, g.M("error", "job not found: "+r.PathValue("id")))
+		return
+	}
+
+	if !job.Status.IsFinished() && job.Status != sling.ExecStatusCancelled {
+		job.Status = sling.ExecStatusCancelled
+		job.ctx.Cancel()
+		if job.StartTime == nil {
+			job.EndTime = g.Ptr(time.Now())
+		}
+	}
+	apiRespond(w, http.StatusOK, job.snapshot())
+}
+
+func (s *apiServer) handleHistory(w http.ResponseWriter, r *http.Request) {
+	var execs []store.Execution
+	var err error
+
+	if execID := r.PathValue("exec_id"); execID != "" {
+		if execs, err = store.GetExecutions(execID); err != nil {
+			apiRespond(w, http.StatusNotFound, g.M("error", g.ErrMsg(err)))
+			return
+		}
+	} else {
+		execs, err = store.History(store.HistoryOptions{
+			Last:   cast.ToInt(r.URL.Query().Get("last")),
+			Stream: r.URL.Query().Get("stream"),
+		})
+		if err != nil {
+			apiRespond(w, http.StatusInternalServerError, g.M("error", g.ErrMsg(err)))
+			return
+		}
+	}
+
+	apiRespond(w, http.StatusOK, execs)
+}
+
+// newAPIJob compiles a replication or task config into a job
+func newAPIJob(body string) (job *apiJob, err error) {
+	m := g.M()
+	if err = yaml.Unmarshal([]byte(body), &m); err != nil {
+		return nil, g.Error(err, "could not parse config")
+	}
+
+	job = &apiJob{
+		ID:        sling.NewExecID(),
+		Status:    sling.ExecStatusQueued,
+		CreatedAt: time.Now(),
+	}
+
+	if _, ok := m["streams"]; ok {
+		job.Kind = "replication"
+		job.replication, err = sling.LoadReplicationConfig(body)
+		if err != nil {
+			return nil, g.Error(err, "invalid replication config")
+		}
+		job.replication.Embedded = true // jobs run concurrently, do not set their env into the process
+		if err = job.replication.Compile(nil); err != nil {
+			return nil, g.Error(err, "could not compile replication config")
+		}
+	} else {
+		job.Kind = "task"
+		cfg := &sling.Config{}
+		if err = cfg.Unmarshal(body); err != nil {
+			return nil, g.Error(err, "invalid task config")
+		}
+		cfg.Options.Embedded = true
+		if err = cfg.Prepare(); err != nil {
+			return nil, g.Error(err, "invalid task config")
+		}
+		job.replication = cfg.AsReplication()
+		job.replication.Tasks = []*sling.Config{cfg}
+		job.replication.Embedded = true
+	}
+
+	for _, cfg := range job.replication.Tasks {
+		if cfg.ReplicationStream == nil || !cfg.ReplicationStream.Disabled {
+			job.Streams = append(job.Streams, apiJobStream{Stream: cfg.StreamLabel(), Status: sling.ExecStatusQueued})
+		}
+	}
+
+	return job, nil
+}
+
+// commandHooks returns the ids of the hooks running local commands:
+// command hooks, and dbt hooks without job_id (dbt CLI)
+func commandHooks(replication sling.ReplicationConfig) (ids []string) {
+	check := func(stage sling.HookStage, hooks []any) {
+		for i, raw := range hooks {
+			var hook sling.HookConfig
+			if err := g.Unmarshal(g.Marshal(raw), &hook); err != nil {
+				continue // invalid hooks fail when parsed
+			}
+			hookType := sling.HookType(strings.ToLower(string(hook.Type)))
+			if hookType == sling.HookTypeCommand || (hookType == sling.HookTypeDBT && hook.JobID == "") {
+				if hook.ID == "" {
+					hook.ID = g.F("%s_%d", stage, i)
+				}
+				ids = append(ids, hook.ID)
+			}
+		}
+	}
+
+	hookMaps := []sling.HookMap{replication.Defaults.Hooks}
+	for _, cfg := range replication.Tasks {
+		if cfg.ReplicationStream != nil {
+			hookMaps = append(hookMaps, cfg.ReplicationStream.Hooks)
+		}
+	}
+	for _, stream := range replication.Streams {
+		if stream != nil {
+			hookMaps = append(hookMaps, stream.Hooks)
+		}
+	}
+
+	for _, hookMap := range hookMaps {
+		check(sling.HookStageStart, hookMap.Start)
+		check(sling.HookStageEnd, hookMap.End)
+		check(sling.HookStagePre, hookMap.Pre)
+		check(sling.HookStagePost, hookMap.Post)
+	}
+	ids = lo.Uniq(ids)
+	sort.Strings(ids)
+	return ids
+}
+
+// snapshot returns a copy of the job with the current status of its streams,
+// must be called with the server lock held
+func (j *apiJob) snapshot() (snap apiJob) {
+	snap = apiJob{
+		ID:        j.ID,
+		Kind:      j.Kind,
+		Status:    j.Status,
+		Error:     j.Error,
+		Streams:   append([]apiJobStream{}, j.Streams...),
+		CreatedAt: j.CreatedAt,
+		StartTime: j.StartTime,
+		EndTime:   j.EndTime,
+	}
+
+	for i, task := range j.tasks {
+		if i >= len(snap.Streams) {
+			break
+		}
+		stream := &snap.Streams[i]
+		stream.Status = task.GetStatus()
+		stream.Rows = task.GetCount()
+		stream.Bytes, _ = task.GetBytes()
+		if task.Err != nil {
+			stream.Error = g.ErrMsg(task.Err)
+		}
+	}
+
+	return snap
+}
+
+// work runs the queued jobs, one at a time
+func (s *apiServer) work() {
+	for {
+		select {
+		case <-s.ctx.Ctx.Done():
+			return
+		case job := <-s.queue:
+			s.mux.Lock()
+			if job.Status == sling.ExecStatusCancelled {
+				s.mux.Unlock()
+				continue
+			}
+			job.Status = sling.ExecStatusRunning
+			job.StartTime = g.Ptr(time.Now())
+			s.mux.Unlock()
+
+			err := s.runJob(job)
+
+			s.mux.Lock()
+			if job.Status != sling.ExecStatusCancelled {
+				job.Status = sling.ExecStatusSuccess
+				if err != nil {
+					job.Status = sling.ExecStatusError
+				}
+			}
+			if err != nil {
+				job.Error = g.ErrMsg(err)
+			}
+			job.EndTime = g.Ptr(time.Now())
+			s.mux.Unlock()
+		}
+	}
+}
+
+// runJob runs the streams of a job in order, the runs being recorded in the store
+func (s *apiServer) runJob(job *apiJob) (err error) {
+	replication := &job.replication
+
+	// variables are computed at run time, compile again to render them
+	if len(replication.Variables) > 0 {
+		if _, err = replication.ComputeVariables(); err != nil {
+			return g.Error(err, "could not compute replication variables")
+		}
+		replication.Tasks = nil
+		if err = replication.Compile(nil); err != nil {
+			return g.Error(err, "could not compile replication config")
+		}
+	}
+
+	startHooks, err := replication.ParseDefaultHook(sling.HookStageStart)
+	if err != nil {
+		return g.Error(err, "could not parse start hooks")
+	}
+	endHooks, err := replication.ParseDefaultHook(sling.HookStageEnd)
+	if err != nil {
+		return g.Error(err, "could not parse end hooks")
+	}
+
+	if err = startHooks.Execute(); err != nil {
+		return g.Error(err, "error executing start hooks")
+	}
+
+	eG := g.ErrorGroup{}
+	for _, cfg := range replication.Tasks {
+		if cfg.ReplicationStream != nil && cfg.ReplicationStream.Disabled {
+			continue
+		} else if job.ctx.Ctx.Err() != nil {
+			eG.Capture(g.Error("job was cancelled"), cfg.StreamName)
+			break
+		}
+
+		task := sling.NewTask(job.ID, cfg)
+		task.Replication = replication
+		task.Context = job.ctx
+
+		s.mux.Lock()
+		job.tasks = append(job.tasks, task)
+		s.mux.Unlock()
+
+		if err = s.runJobTask(task); err != nil {
+			eG.Capture(err, cfg.StreamName)
+			if replication.OnStreamError == sling.StreamErrorFailFast {
+				break
+			}
+		}
+	}
+
+	if err = endHooks.Execute(); err != nil {
+		eG.Capture(err, "end-hooks")
+	}
+
+	// write the run report
+	if replication.Report != nil {
+		streams := []sling.RunReportStream{}
+		for _, task := range job.tasks {
+			streams = append(streams, sling.NewRunReportStream(task, nil))
+		}
+		report := sling.NewRunReport(replication, job.ID, *job.StartTime, streams)
+		if _, err = replication.WriteReport(job.ctx.Ctx, report); err != nil {
+			g.Warn("could not write run report of job %s: %s", job.ID, g.ErrMsgSimple(err))
+		}
+	}
+
+	return eG.Err()
+}
+
+func (s *apiServer) runJobTask(task *sling.TaskExecution) (err error) {
+	// prevent concurrent runs of the same stream
+	var lock *store.StreamLock
+	if task.Err == nil {
+		var lockErr error
+		lock, lockErr = store.LockStream(task.Context.Ctx, task.Config, task.ExecID)
+		if lockErr != nil {
+			task.SetStatus(sling.ExecStatusError)
+			task.Err = lockErr
+		}
+		defer lock.Release()
+	}
+
+	sling.StateSet(task) // set into store
+	if task.Err != nil {
+		return g.Error(task.Err)
+	}
+	defer sling.StateSet(task)
+
+	// cancelled if the run lock is lost
+	task.Context = lock.Context(task.Context)
+
+	err = task.Execute()
+	if lockErr := lock.Err(); lockErr != nil {
+		task.SetStatus(sling.ExecStatusError)
+		err = g.Error(lockErr, "stream run stopped")
+	}
+	return err
+}
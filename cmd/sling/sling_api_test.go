@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/sling"
+	"github.com/slingdata-io/sling-cli/core/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestAPI serves an api server, with the workers started
+func newTestAPI(t *testing.T, server *apiServer) (request func(method, path, body string) (int, string)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	server.start(ctx)
+	httpServer := httptest.NewServer(server.handler())
+	t.Cleanup(func() {
+		httpServer.Close()
+		cancel()
+	})
+
+	return func(method, path, body string) (int, string) {
+		req, err := http.NewRequest(method, httpServer.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+server.token)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+}
+
+func TestAPIAuth(t *testing.T) {
+	request := newTestAPI(t, newAPIServer("secret", 1))
+
+	code, _ := request("GET", "/jobs", "")
+	assert.Equal(t, http.StatusOK, code)
+
+	for _, header := range []string{"", "Bearer wrong", "secret"} {
+		req, _ := http.NewRequest("GET", "/jobs", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		recorder := httptest.NewRecorder()
+		newAPIServer("secret", 1).handler().ServeHTTP(recorder, req)
+		expected := http.StatusUnauthorized
+		if header == "secret" {
+			expected = http.StatusOK // the Bearer prefix is optional
+		}
+		assert.Equal(t, expected, recorder.Code, header)
+	}
+
+	t.Setenv("SLING_API_TOKEN", "")
+	_, err := processAPI(&g.CliSC{Vals: g.M("host", "0.0.0.0", "port", "0")})
+	assert.ErrorContains(t, err, "token is required")
+}
+
+func TestAPISubmit(t *testing.T) {
+	initTestStore(t)
+	t.Setenv("SLING_DUCKDB_COMPUTE", "false")
+	folder := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(folder, "users.csv"), []byte("id,name\n1,a\n2,b\n"), 0644))
+
+	request := newTestAPI(t, newAPIServer("secret", 1))
+	task := g.Marshal(g.M(
+		"source", g.M("conn", "local", "stream", "file://"+path.Join(folder, "users.csv")),
+		"target", g.M("conn", "local", "object", "file://"+path.Join(folder, "out.csv")),
+		"env", g.M("SLING_TEST_API_ENV", "job"),
+	))
+
+	code, body := request("POST", "/jobs", task)
+	require.Equal(t, http.StatusAccepted, code, body)
+	var job apiJob
+	require.NoError(t, g.Unmarshal(body, &job))
+	assert.Equal(t, "task", job.Kind)
+
+	for i := 0; i < 100 && !job.Status.IsFinished(); i++ {
+		time.Sleep(50 * time.Millisecond)
+		_, body = request("GET", "/jobs/"+job.ID, "")
+		require.NoError(t, g.Unmarshal(body, &job))
+	}
+	assert.Equal(t, sling.ExecStatusSuccess, job.Status, job.Error)
+	if assert.Len(t, job.Streams, 1) {
+		assert.EqualValues(t, 2, job.Streams[0].Rows)
+	}
+	assert.Empty(t, os.Getenv("SLING_TEST_API_ENV"), "job env should not leak into the process")
+
+	code, body = request("GET", "/jobs", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, job.ID)
+
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{name: "invalid config", body: "source: [", code: http.StatusBadRequest},
+		{name: "unknown stream conn", body: "source: MISSING\ntarget: MISSING\nstreams:\n  public.users:\n", code: http.StatusBadRequest},
+		{name: "command hook", body: "source: local\ntarget: local\ndefaults:\n  object: file://" + folder + "/{stream_file_name}.out.csv\n  hooks:\n    post: [{type: command, command: touch /tmp/x}]\nstreams:\n  file://" + folder + "/users.csv:\n", code: http.StatusForbidden},
+		{name: "dbt cli hook", body: "source: local\ntarget: local\ndefaults:\n  object: file://" + folder + "/{stream_file_name}.out.csv\nstreams:\n  file://" + folder + "/users.csv:\n    hooks:\n      pre: [{type: dbt, id: models, project_dir: /tmp}]\n", code: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		code, body := request("POST", "/jobs", tt.body)
+		assert.Equal(t, tt.code, code, tt.name+": "+body)
+	}
+
+	defer func(size int64) { apiMaxBodySize = size }(apiMaxBodySize)
+	apiMaxBodySize = 64
+	code, body = request("POST", "/jobs", task)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code, body)
+
+	code, _ = request("GET", "/jobs/missing", "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestAPICancel(t *testing.T) {
+	initTestStore(t)
+	folder := t.TempDir()
+	task := g.Marshal(g.M(
+		"source", g.M("conn", "local", "stream", "file://"+path.Join(folder, "users.csv")),
+		"target", g.M("conn", "local", "object", "file://"+path.Join(folder, "out.csv")),
+	))
+
+	// no workers, the jobs stay queued
+	server := newAPIServer("", 0)
+	server.queue = make(chan *apiJob, 1)
+	request := newTestAPI(t, server)
+
+	code, body := request("POST", "/jobs", task)
+	require.Equal(t, http.StatusAccepted, code, body)
+	var job apiJob
+	require.NoError(t, g.Unmarshal(body, &job))
+	assert.Equal(t, sling.ExecStatusQueued, job.Status)
+
+	// the queue is full
+	code, body = request("POST", "/jobs", task)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, body, "queue is full")
+
+	code, body = request("DELETE", "/jobs/"+job.ID, "")
+	assert.Equal(t, http.StatusOK, code)
+	require.NoError(t, g.Unmarshal(body, &job))
+	assert.Equal(t, sling.ExecStatusCancelled, job.Status)
+	assert.NotNil(t, job.EndTime)
+
+	code, _ = request("DELETE", "/jobs/missing", "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestAPIHistory(t *testing.T) {
+	initTestStore(t)
+	execs := []store.Execution{
+		{ExecID: "exec1", StreamName: "public.orders", Status: sling.ExecStatusSuccess, Rows: 10},
+		{ExecID: "exec2", StreamName: "public.users", Status: sling.ExecStatusError},
+		{ExecID: "exec2", StreamName: "sales.items", Status: sling.ExecStatusSuccess, Rows: 1},
+	}
+	require.NoError(t, store.Db.Create(&execs).Error)
+
+	request := newTestAPI(t, newAPIServer("", 1))
+
+	tests := []struct {
+		path     string
+		code     int
+		expected []string // exec_id/stream
+	}{
+		{path: "/history", code: http.StatusOK, expected: []string{"exec2/sales.items", "exec2/public.users", "exec1/public.orders"}},
+		{path: "/history?last=1", code: http.StatusOK, expected: []string{"exec2/sales.items"}},
+		{path: "/history?stream=public.*", code: http.StatusOK, expected: []string{"exec2/public.users", "exec1/public.orders"}},
+		{path: "/history/exec2", code: http.StatusOK, expected: []string{"exec2/public.users", "exec2/sales.items"}},
+		{path: "/history/missing", code: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		code, body := request("GET", tt.path, "")
+		if !assert.Equal(t, tt.code, code, tt.path) || tt.expected == nil {
+			continue
+		}
+		var result []store.Execution
+		require.NoError(t, g.Unmarshal(body, &result))
+		streams := []string{}
+		for _, exec := range result {
+			streams = append(streams, exec.ExecID+"/"+exec.StreamName)
+		}
+		assert.Equal(t, tt.expected, streams, tt.path)
+	}
+}
+
+func TestCommandHooks(t *testing.T) {
+	replication, err := sling.LoadReplicationConfig(`
+source: local
+target: local
+defaults:
+  hooks:
+    start: [{type: http, url: "http://localhost"}]
+    end: [{type: COMMAND, command: echo}]
+streams:
+  users:
+    hooks:
+      post: [{type: dbt, job_id: "1", account_id: "1", token: x}, {type: dbt, id: local_dbt}]
+`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"end_0", "local_dbt"}, commandHooks(replication))
+}
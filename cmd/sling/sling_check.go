@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/integrii/flaggy"
+	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+	"github.com/slingdata-io/sling-cli/core/env"
+	"github.com/slingdata-io/sling-cli/core/sling"
+	"github.com/spf13/cast"
+)
+
+var cliCheck = &g.CliSC{
+	Name:                  "check",
+	Description:           "Check a replication against the live systems (source objects, keys, target permissions, volumes)",
+	AdditionalHelpPrepend: "\nSee more details at https://docs.slingdata.io/sling-cli/",
+	Flags: []g.Flag{
+		{
+			Name:        "replication",
+			ShortName:   "r",
+			Type:        "string",
+			Description: "The replication config file to use (JSON or YAML).",
+		},
+		{
+			Name:        "streams",
+			ShortName:   "",
+			Type:        "string",
+			Description: "Only check specific streams from a replication. Comma separated, e.g. `stream1,stream2`.",
+		},
+		{
+			Name:        "debug",
+			ShortName:   "d",
+			Type:        "bool",
+			Description: "Set logging level to DEBUG.",
+		},
+	},
+	ExecProcess: processCheck,
+}
+
+// processCheck runs the health checks of a replication, and returns
+// an error if any check failed (to be used as a pre-deploy gate)
+func processCheck(c *g.CliSC) (ok bool, err error) {
+	ok = true
+
+	cfgPath := cast.ToString(c.Vals["replication"])
+	if cfgPath == "" {
+		flaggy.ShowHelp("")
+		return ok, nil
+	}
+
+	if cast.ToBool(c.Vals["debug"]) {
+		os.Setenv("DEBUG", "LOW")
+		env.InitLogger()
+	}
+
+	selectStreams := []string{}
+	if val := cast.ToString(c.Vals["streams"]); val != "" {
+		selectStreams = strings.Split(val, ",")
+	}
+
+	defer connection.CloseAll()
+
+	replication, err := sling.LoadReplicationConfigFromFile(cfgPath)
+	if err != nil {
+		return ok, g.Error(err, "Error parsing replication config")
+	}
+
+	if err = replication.Compile(nil, selectStreams...); err != nil {
+		return ok, g.Error(err, "Error compiling replication config")
+	}
+
+	results := replication.Check(context.Background())
+	if os.Getenv("SLING_OUTPUT") == "json" {
+		fmt.Println(g.Marshal(results))
+	} else {
+		fmt.Println(results.Matrix())
+	}
+
+	if results.Failed() {
+		return ok, g.Error("replication check failed")
+	}
+
+	g.Info("replication check passed")
+	return ok, nil
+}
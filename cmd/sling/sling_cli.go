@@ -115,6 +115,30 @@ var cliRunFlags = []g.Flag{
 		Type:        "string",
 		Description: "Only run specific streams from a replication. (comma separated)",
 	},
+	{
+		Name:        "tags",
+		ShortName:   "",
+		Type:        "string",
+		Description: "Only run the replication streams with specific tags, or without when prefixed with `-` (comma separated).",
+	},
+	{
+		Name:        "vars",
+		ShortName:   "",
+		Type:        "string",
+		Description: "Runtime variables to render as `{var.name}` (e.g. 'start_date=2024-01-01,region=eu').",
+	},
+	{
+		Name:        "env-profile",
+		ShortName:   "",
+		Type:        "string",
+		Description: "The environment profile of the replication `overrides` to apply (e.g. dev, prod).",
+	},
+	{
+		Name:        "stdin-format",
+		ShortName:   "",
+		Type:        "string",
+		Description: "The format of the data piped into standard input (STDIN): csv, json, jsonl, xml, parquet, avro or arrow. Auto-detected by default (gzip is decompressed).",
+	},
 	{
 		Name:        "stdout",
 		ShortName:   "",
@@ -131,7 +155,7 @@ var cliRunFlags = []g.Flag{
 		Name:        "mode",
 		ShortName:   "m",
 		Type:        "string",
-		Description: "The target load mode to use: backfill, incremental, truncate, snapshot, full-refresh.\n                       Default is full-refresh. For incremental, must provide `update-key` and `primary-key` values.\n                       All modes load into a new temp table on tgtConn prior to final load.",
+		Description: "The target load mode to use: backfill, incremental, truncate, snapshot, full-refresh, scd2, partition-overwrite.\n                       Default is full-refresh. For incremental, must provide `update-key` and `primary-key` values.\n                       All modes load into a new temp table on tgtConn prior to final load.",
 	},
 	{
 		Name:        "limit",
@@ -139,11 +163,17 @@ var cliRunFlags = []g.Flag{
 		Type:        "string",
 		Description: "The maximum number of rows to pull.",
 	},
+	{
+		Name:        "sample",
+		ShortName:   "",
+		Type:        "string",
+		Description: "The number of rows to sample, to test a pipeline cheaply. Use source option `sample: {rows: N, method: random}` for random rows.",
+	},
 	{
 		Name:        "offset",
 		ShortName:   "o",
 		Type:        "string",
-		Description: "The number of rows to offset by.",
+		Description: "The number of rows to offset by. Uses keyset pagination if a single-column primary key is provided.",
 	},
 	{
 		Name:        "range",
@@ -163,6 +193,66 @@ var cliRunFlags = []g.Flag{
 		Type:        "string",
 		Description: "The update key to use for incremental.\n",
 	},
+	{
+		Name:        "show-config",
+		ShortName:   "",
+		Type:        "bool",
+		Description: "Print the effective config of each stream, with the source of each value, and exit.",
+	},
+	{
+		Name:        "dry-run",
+		ShortName:   "",
+		Type:        "bool",
+		Description: "Print the planned SQL (source query, temp table DDL, merge / upsert) and file paths of each stream, without writing to the target.",
+	},
+	{
+		Name:        "resume-last",
+		ShortName:   "",
+		Type:        "bool",
+		Description: "Resume the last run of the replication, from the first unfinished stream.",
+	},
+	{
+		Name:        "output",
+		ShortName:   "",
+		Type:        "string",
+		Description: "The output format: `text` (default) or `json`, which prints a final JSON result (rows, bytes, duration, columns, warnings, error) to stdout, and the logs to stderr.",
+	},
+	{
+		Name:        "record",
+		ShortName:   "",
+		Type:        "string",
+		Description: "Record the source streams into the provided folder (one parquet fixture per stream), while running.",
+	},
+	{
+		Name:        "replay",
+		ShortName:   "",
+		Type:        "string",
+		Description: "Replay the source streams from the fixtures of the provided folder, instead of reading from the source.",
+	},
+	{
+		Name:        "watch",
+		ShortName:   "",
+		Type:        "bool",
+		Description: "Keep running, loading new or modified files of the source folder as they arrive (file source tasks). With the `tail` source option, only the lines appended to log files are loaded.",
+	},
+	{
+		Name:        "lock",
+		ShortName:   "",
+		Type:        "string",
+		Description: "Prevent concurrent runs of the same stream: `fail` (fail fast if already running) or `wait` (wait until it finishes).",
+	},
+	{
+		Name:        "http-port",
+		ShortName:   "",
+		Type:        "string",
+		Description: "Serve the live progress of the streams (rows, rate, ETA, status) on the provided local port, as JSON (/progress) and Server-Sent Events (/progress/events).",
+	},
+	{
+		Name:        "log-sql",
+		ShortName:   "",
+		Type:        "string",
+		Description: "Log every executed SQL statement, with its args, duration and affected rows, to the provided file (JSON lines).",
+	},
 	{
 		Name:        "debug",
 		ShortName:   "d",
@@ -231,7 +321,13 @@ var cliConns = &g.CliSC{
 					Name:        "columns",
 					ShortName:   "",
 					Type:        "bool",
-					Description: "Show column level metadata.",
+					Description: "Show the columns of each stream (name, type, nullability).",
+				},
+				{
+					Name:        "output",
+					ShortName:   "",
+					Type:        "string",
+					Description: "The output format: `text` (default), `json` or `yaml`.",
 				},
 				{
 					Name:        "debug",
@@ -295,6 +391,24 @@ var cliConns = &g.CliSC{
 				},
 			},
 		},
+		{
+			Name:        "clone",
+			Description: "clone a connection into the sling env file, with different properties (e.g. database, schema, role)",
+			PosFlags: []g.Flag{
+				{
+					Name:        "source",
+					ShortName:   "",
+					Type:        "string",
+					Description: "The name of the connection to clone",
+				},
+				{
+					Name:        "name",
+					ShortName:   "",
+					Type:        "string",
+					Description: "The name of the new connection, optionally followed by key=value properties to override (e.g. database=dev schema=staging)",
+				},
+			},
+		},
 		{
 			Name:        "exec",
 			Description: "execute a SQL query on a Database connection",
@@ -372,6 +486,19 @@ func init() {
 
 	cliConns.Make().Add()
 	cliRun.Make().Add()
+	cliState.Make().Add()
+	cliCheck.Make().Add()
+	cliHistory.Make().Add()
+	cliGenerate.Make().Add()
+	cliValidate.Make().Add()
+	cliDiff.Make().Add()
+	cliPreview.Make().Add()
+	cliProfile.Make().Add()
+	cliCompare.Make().Add()
+	cliSchema.Make().Add()
+	cliServe.Make().Add()
+	cliAPI.Make().Add()
+	cliIPC.Make().Add()
 	cliUpdate.Make().Add()
 
 	if projectID == "" {
@@ -464,9 +591,14 @@ func main() {
 				env.Println("\ninterrupting...")
 				interrupted = true
 				ctx.Cancel()
+				// allow the current stream to finish or roll back
+				timeout := 5 * time.Second
+				if val := cast.ToInt(os.Getenv("SLING_INTERRUPT_TIMEOUT")); val > 0 {
+					timeout = time.Duration(val) * time.Second
+				}
 				select {
 				case <-done:
-				case <-time.After(5 * time.Second):
+				case <-time.After(timeout):
 				}
 			}
 			exit()
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/integrii/flaggy"
+	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+	"github.com/slingdata-io/sling-cli/core/env"
+	"github.com/slingdata-io/sling-cli/core/sling"
+	"github.com/spf13/cast"
+)
+
+var cliCompare = &g.CliSC{
+	Name:                  "compare",
+	Description:           "Compare a stream between two connections (row counts, checksums per primary key bucket, and optionally row diffs), for post-migration validation",
+	AdditionalHelpPrepend: "\nSee more details at https://docs.slingdata.io/sling-cli/",
+	Flags: []g.Flag{
+		{
+			Name:        "src-conn",
+			ShortName:   "",
+			Type:        "string",
+			Description: "The source database / storage connection (name, conn string or URL).",
+		},
+		{
+			Name:        "tgt-conn",
+			ShortName:   "",
+			Type:        "string",
+			Description: "The target database / storage connection (name, conn string or URL).",
+		},
+		{
+			Name:        "stream",
+			ShortName:   "",
+			Type:        "string",
+			Description: "The stream to compare (schema.table, or file path).",
+		},
+		{
+			Name:        "tgt-stream",
+			ShortName:   "",
+			Type:        "string",
+			Description: "The target stream, if different from the source stream.",
+		},
+		{
+			Name:        "primary-key",
+			ShortName:   "",
+			Type:        "string",
+			Description: "The primary key columns to bucket and match rows by (comma separated).",
+		},
+		{
+			Name:        "select",
+			ShortName:   "s",
+			Type:        "string",
+			Description: "Select or exclude specific columns to compare. (comma separated). Use '-' prefix to exclude.",
+		},
+		{
+			Name:        "where",
+			ShortName:   "",
+			Type:        "string",
+			Description: "Specify the WHERE clause to filter both streams.",
+		},
+		{
+			Name:        "buckets",
+			ShortName:   "",
+			Type:        "string",
+			Description: "The number of primary key buckets to checksum (default 64).",
+		},
+		{
+			Name:        "rows",
+			ShortName:   "",
+			Type:        "bool",
+			Description: "Report the rows which differ (requires a primary key).",
+		},
+		{
+			Name:        "max-diffs",
+			ShortName:   "",
+			Type:        "string",
+			Description: "The maximum number of row diffs to report (default 100).",
+		},
+		{
+			Name:        "output",
+			ShortName:   "o",
+			Type:        "string",
+			Description: "The output format: text (default) or json.",
+		},
+		{
+			Name:        "debug",
+			ShortName:   "d",
+			Type:        "bool",
+			Description: "Set logging level to DEBUG.",
+		},
+	},
+	ExecProcess: processCompare,
+}
+
+// processCompare compares a stream between two connections
+func processCompare(c *g.CliSC) (ok bool, err error) {
+	ok = true
+
+	srcConn := cast.ToString(c.Vals["src-conn"])
+	tgtConn := cast.ToString(c.Vals["tgt-conn"])
+	stream := cast.ToString(c.Vals["stream"])
+	if srcConn == "" || tgtConn == "" || stream == "" {
+		flaggy.ShowHelp("")
+		return ok, nil
+	}
+
+	tgtStream := cast.ToString(c.Vals["tgt-stream"])
+	if tgtStream == "" {
+		tgtStream = stream
+	}
+
+	newConfig := func(conn, stream string) *sling.Config {
+		cfg := &sling.Config{}
+		cfg.Source.Conn = conn
+		cfg.Source.Stream = stream
+		cfg.Source.Where = cast.ToString(c.Vals["where"])
+		if val := cast.ToString(c.Vals["select"]); val != "" {
+			cfg.Source.Select = strings.Split(val, ",")
+		}
+		return cfg
+	}
+
+	opts := sling.CompareOptions{
+		Buckets:  cast.ToInt(c.Vals["buckets"]),
+		Rows:     cast.ToBool(c.Vals["rows"]),
+		MaxDiffs: cast.ToInt(c.Vals["max-diffs"]),
+	}
+	if val := cast.ToString(c.Vals["primary-key"]); val != "" {
+		opts.PrimaryKey = strings.Split(val, ",")
+	}
+
+	output := strings.ToLower(cast.ToString(c.Vals["output"]))
+	switch output {
+	case "json":
+		os.Setenv("SLING_OUTPUT", output)
+		env.SetLogger()
+	case "", "text":
+	default:
+		return ok, g.Error("invalid output format: %s", output)
+	}
+
+	if cast.ToBool(c.Vals["debug"]) {
+		os.Setenv("DEBUG", "LOW")
+		env.SetLogger()
+	}
+
+	defer connection.CloseAll()
+
+	report, err := sling.Compare(newConfig(srcConn, stream), newConfig(tgtConn, tgtStream), opts)
+	if err != nil {
+		return ok, g.Error(err, "could not compare streams")
+	}
+
+	if output == "json" {
+		fmt.Println(g.Marshal(report))
+	} else {
+		fmt.Println(report.String())
+	}
+
+	if !report.Match {
+		return ok, g.Error("streams do not match")
+	}
+
+	return ok, nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -12,13 +13,15 @@ import (
 	"github.com/slingdata-io/sling-cli/core/dbio"
 	"github.com/slingdata-io/sling-cli/core/dbio/connection"
 	"github.com/slingdata-io/sling-cli/core/dbio/database"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
 	"github.com/slingdata-io/sling-cli/core/env"
 	"github.com/slingdata-io/sling-cli/core/sling"
 	"github.com/spf13/cast"
+	"gopkg.in/yaml.v2"
 )
 
 var (
-	connsDiscover = func(*g.CliSC) error { return g.Error("please use the official build of Sling CLI to use this command") }
+	connsDiscover = discoverConns
 	connsCheck    = func(*g.CliSC) error { return g.Error("please use the official build of Sling CLI to use this command") }
 )
 
@@ -74,6 +77,24 @@ func processConns(c *g.CliSC) (ok bool, err error) {
 			return ok, g.Error(err, "could not set %s (See https://docs.slingdata.io/sling-cli/environment)", name)
 		}
 		g.Info("connection `%s` has been set in %s. Please test with `sling conns test %s`", name, ec.EnvFile.Path, name)
+	case "clone":
+		srcName := strings.ToUpper(cast.ToString(c.Vals["source"]))
+		name := strings.ToUpper(cast.ToString(c.Vals["name"]))
+		if srcName == "" || name == "" {
+			flaggy.ShowHelp("")
+			return ok, nil
+		}
+
+		overrides := map[string]any{}
+		for k, v := range g.KVArrToMap(flaggy.TrailingArguments...) {
+			overrides[strings.ToLower(k)] = v
+		}
+
+		err := ec.Clone(srcName, name, overrides)
+		if err != nil {
+			return ok, g.Error(err, "could not clone %s into %s", srcName, name)
+		}
+		g.Info("connection `%s` has been cloned into `%s` in %s. Please test with `sling conns test %s`", srcName, name, ec.EnvFile.Path, name)
 	case "exec":
 		env.SetTelVal("task", g.Marshal(g.M("type", sling.ConnExec)))
 
@@ -207,3 +228,115 @@ func processConns(c *g.CliSC) (ok bool, err error) {
 	}
 	return ok, nil
 }
+
+// DiscoveredStream is a stream (table, view or file) listed by `conns discover`
+type DiscoveredStream struct {
+	Name    string             `json:"name" yaml:"name"`
+	Type    string             `json:"type" yaml:"type"`
+	Columns []DiscoveredColumn `json:"columns,omitempty" yaml:"columns,omitempty"`
+}
+
+// DiscoveredColumn is a column of a discovered stream
+type DiscoveredColumn struct {
+	Name     string `json:"name" yaml:"name"`
+	Type     string `json:"type" yaml:"type"`
+	DbType   string `json:"db_type,omitempty" yaml:"db_type,omitempty"`
+	Nullable *bool  `json:"nullable,omitempty" yaml:"nullable,omitempty"` // nil if unknown
+}
+
+func newDiscoveredColumns(columns iop.Columns) (cols []DiscoveredColumn) {
+	columns = append(iop.Columns{}, columns...)
+	sort.SliceStable(columns, func(i, j int) bool { return columns[i].Position < columns[j].Position })
+
+	for _, col := range columns {
+		column := DiscoveredColumn{Name: col.Name, Type: string(col.Type), DbType: col.DbType}
+		if val, ok := col.Metadata["nullable"]; ok {
+			column.Nullable = g.Ptr(cast.ToBool(val))
+		}
+		cols = append(cols, column)
+	}
+	return cols
+}
+
+func discoverConns(c *g.CliSC) (err error) {
+	name := cast.ToString(c.Vals["name"])
+	if name == "" {
+		flaggy.ShowHelp("")
+		return nil
+	}
+
+	output := strings.ToLower(cast.ToString(c.Vals["output"]))
+	if output == "" {
+		output = lo.Ternary(os.Getenv("SLING_OUTPUT") == "json", "json", "text")
+	} else if !g.In(output, "text", "json", "yaml") {
+		return g.Error("invalid output format: %s (expected text, json or yaml)", output)
+	}
+
+	withColumns := cast.ToBool(c.Vals["columns"])
+	opt := &connection.DiscoverOptions{
+		Pattern:   cast.ToString(c.Vals["pattern"]),
+		Level:     lo.Ternary(withColumns, database.SchemataLevelColumn, database.SchemataLevelTable),
+		Recursive: cast.ToBool(c.Vals["recursive"]),
+	}
+
+	env.SetTelVal("task", g.Marshal(g.M("type", sling.ConnDiscover)))
+	if conn := connection.GetLocalConns().Get(name); conn.Name != "" {
+		env.SetTelVal("conn_type", conn.Connection.Type.String())
+	}
+
+	nodes, schemata, err := connection.GetLocalConns().Discover(name, opt)
+	if err != nil {
+		return g.Error(err, "could not discover %s", name)
+	}
+
+	streams := []DiscoveredStream{}
+	for _, table := range schemata.Tables() {
+		streams = append(streams, DiscoveredStream{
+			Name:    table.Schema + "." + table.Name,
+			Type:    lo.Ternary(table.IsView, "view", "table"),
+			Columns: newDiscoveredColumns(table.Columns),
+		})
+	}
+	for _, node := range nodes {
+		streams = append(streams, DiscoveredStream{
+			Name:    node.URI,
+			Type:    lo.Ternary(node.IsDir, "directory", "file"),
+			Columns: newDiscoveredColumns(node.Columns),
+		})
+	}
+	sort.SliceStable(streams, func(i, j int) bool { return streams[i].Name < streams[j].Name })
+
+	switch output {
+	case "json":
+		fmt.Println(g.Marshal(streams))
+	case "yaml":
+		payload, err := yaml.Marshal(streams)
+		if err != nil {
+			return g.Error(err, "could not marshal streams to yaml")
+		}
+		fmt.Print(string(payload))
+	default:
+		if !withColumns {
+			rows := [][]any{}
+			for i, stream := range streams {
+				rows = append(rows, []any{i + 1, stream.Name, stream.Type})
+			}
+			fmt.Println(g.PrettyTable([]string{"#", "Name", "Type"}, rows))
+			break
+		}
+
+		rows := [][]any{}
+		for _, stream := range streams {
+			for i, col := range stream.Columns {
+				nullable := ""
+				if col.Nullable != nil {
+					nullable = cast.ToString(*col.Nullable)
+				}
+				rows = append(rows, []any{stream.Name, i + 1, col.Name, col.Type, col.DbType, nullable})
+			}
+		}
+		fmt.Println(g.PrettyTable([]string{"Stream", "#", "Column", "Type", "Db Type", "Nullable"}, rows))
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/integrii/flaggy"
+	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+	"github.com/slingdata-io/sling-cli/core/env"
+	"github.com/slingdata-io/sling-cli/core/sling"
+	"github.com/spf13/cast"
+)
+
+var cliDiff = &g.CliSC{
+	Name:                  "diff",
+	Description:           "Compare the source columns of a replication to the existing target tables, and show the schema changes (add_new_columns, adjust_column_type), without altering anything",
+	AdditionalHelpPrepend: "\nSee more details at https://docs.slingdata.io/sling-cli/",
+	PosFlags: []g.Flag{
+		{
+			Name:        "replication",
+			ShortName:   "",
+			Type:        "string",
+			Description: "The replication config file to compare (JSON or YAML).",
+		},
+	},
+	Flags: []g.Flag{
+		{
+			Name:        "streams",
+			ShortName:   "",
+			Type:        "string",
+			Description: "Only compare the specified streams (comma separated).",
+		},
+		{
+			Name:        "debug",
+			ShortName:   "d",
+			Type:        "bool",
+			Description: "Set logging level to DEBUG.",
+		},
+	},
+	ExecProcess: processDiff,
+}
+
+// processDiff prints the schema changes of the target tables of a replication
+func processDiff(c *g.CliSC) (ok bool, err error) {
+	ok = true
+
+	cfgPath := cast.ToString(c.Vals["replication"])
+	if cfgPath == "" {
+		flaggy.ShowHelp("")
+		return ok, nil
+	}
+
+	if cast.ToBool(c.Vals["debug"]) {
+		os.Setenv("DEBUG", "LOW")
+		env.InitLogger()
+	}
+
+	var selectStreams []string
+	if val := cast.ToString(c.Vals["streams"]); val != "" {
+		selectStreams = strings.Split(val, ",")
+	}
+
+	defer connection.CloseAll()
+
+	replication, err := sling.LoadReplicationConfigFromFile(cfgPath)
+	if err != nil {
+		return ok, g.Error(err, "Error parsing replication config")
+	}
+
+	if err = replication.Compile(nil, selectStreams...); err != nil {
+		return ok, g.Error(err, "Error compiling replication config")
+	}
+
+	eG := g.ErrorGroup{}
+	diffs := []sling.SchemaDiff{}
+	for _, cfg := range replication.Tasks {
+		if cfg.ReplicationStream.Disabled {
+			continue
+		}
+
+		if err = cfg.Prepare(); err != nil {
+			eG.Capture(g.Error(err, "could not set task configuration"), cfg.StreamName)
+			continue
+		}
+
+		task := sling.NewTask(os.Getenv("SLING_EXEC_ID"), cfg)
+		if task.Err != nil {
+			eG.Capture(task.Err, cfg.StreamName)
+			continue
+		}
+
+		diff, err := task.SchemaDiff()
+		if err != nil {
+			eG.Capture(err, cfg.StreamName)
+			continue
+		}
+		diffs = append(diffs, diff)
+
+		if os.Getenv("SLING_OUTPUT") != "json" {
+			fmt.Println(diff.String())
+		}
+	}
+
+	if os.Getenv("SLING_OUTPUT") == "json" {
+		fmt.Println(g.Marshal(diffs))
+	}
+
+	return ok, eG.Err()
+}
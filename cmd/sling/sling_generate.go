@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/flarco/g"
+	"github.com/integrii/flaggy"
+	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+	"github.com/slingdata-io/sling-cli/core/env"
+	"github.com/slingdata-io/sling-cli/core/sling"
+	"github.com/spf13/cast"
+)
+
+var cliGenerate = &g.CliSC{
+	Name:                  "generate",
+	Description:           "Generate configuration files",
+	AdditionalHelpPrepend: "\nSee more details at https://docs.slingdata.io/sling-cli/",
+	SubComs: []*g.CliSC{
+		{
+			Name:        "replication",
+			Description: "generate a replication YAML from the tables of a source schema (primary keys, update keys)",
+			Flags: []g.Flag{
+				{
+					Name:        "src-conn",
+					ShortName:   "",
+					Type:        "string",
+					Description: "The name of the source database connection.",
+				},
+				{
+					Name:        "schema",
+					ShortName:   "",
+					Type:        "string",
+					Description: "The source schema to introspect.",
+				},
+				{
+					Name:        "tgt-conn",
+					ShortName:   "",
+					Type:        "string",
+					Description: "The name of the target connection.",
+				},
+				{
+					Name:        "output",
+					ShortName:   "o",
+					Type:        "string",
+					Description: "The file path to write the replication YAML to (default is stdout).",
+				},
+				{
+					Name:        "debug",
+					ShortName:   "d",
+					Type:        "bool",
+					Description: "Set logging level to DEBUG.",
+				},
+			},
+		},
+	},
+	ExecProcess: processGenerate,
+}
+
+func processGenerate(c *g.CliSC) (ok bool, err error) {
+	ok = true
+
+	if cast.ToBool(c.Vals["debug"]) {
+		os.Setenv("DEBUG", "LOW")
+		env.InitLogger()
+	}
+
+	switch c.UsedSC() {
+	case "replication":
+		srcName, schema, tgtName := cast.ToString(c.Vals["src-conn"]), cast.ToString(c.Vals["schema"]), cast.ToString(c.Vals["tgt-conn"])
+		if srcName == "" || schema == "" || tgtName == "" {
+			flaggy.ShowHelp("")
+			return ok, g.Error("the --src-conn, --schema and --tgt-conn flags are required")
+		}
+		return ok, generateReplication(srcName, schema, tgtName, cast.ToString(c.Vals["output"]))
+
+	default:
+		flaggy.ShowHelp("")
+	}
+
+	return ok, nil
+}
+
+// generateReplication writes a replication scaffold of the source schema
+func generateReplication(srcName, schema, tgtName, output string) (err error) {
+	defer connection.CloseAll()
+
+	entry := connection.GetLocalConns().Get(srcName)
+	if entry.Name == "" {
+		return g.Error("did not find connection %s", srcName)
+	} else if !entry.Connection.Type.IsDb() {
+		return g.Error("connection %s is not a database", srcName)
+	}
+
+	conn, err := entry.Connection.AsDatabase()
+	if err != nil {
+		return g.Error(err, "could not initialize connection %s", srcName)
+	}
+
+	if err = conn.Connect(); err != nil {
+		return g.Error(err, "could not connect to %s", srcName)
+	}
+	defer conn.Close()
+
+	generated, err := sling.GenerateReplication(conn, srcName, schema, tgtName)
+	if err != nil {
+		return g.Error(err, "could not generate replication")
+	}
+
+	if output == "" {
+		fmt.Println(generated.YAML())
+		return nil
+	}
+
+	if err = os.WriteFile(output, []byte(generated.YAML()), 0644); err != nil {
+		return g.Error(err, "could not write replication to %s", output)
+	}
+	g.Info("wrote replication with %d streams to %s", len(generated.Streams), output)
+
+	return nil
+}
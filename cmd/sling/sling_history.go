@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/flarco/g"
+	"github.com/integrii/flaggy"
+	"github.com/slingdata-io/sling-cli/core/env"
+	"github.com/slingdata-io/sling-cli/core/store"
+	"github.com/spf13/cast"
+)
+
+var cliHistory = &g.CliSC{
+	Name:                  "history",
+	Description:           "List the past runs recorded in the local store (status, rows, duration, error)",
+	AdditionalHelpPrepend: "\nSee more details at https://docs.slingdata.io/sling-cli/",
+	ExecuteWithoutFlags:   true,
+	Flags: []g.Flag{
+		{
+			Name:        "last",
+			ShortName:   "n",
+			Type:        "string",
+			Description: "The number of most recent stream runs to list (default 20).",
+		},
+		{
+			Name:        "stream",
+			ShortName:   "",
+			Type:        "string",
+			Description: "Filter by stream name. Supports `*` wildcards, e.g. `public.*`.",
+		},
+		{
+			Name:        "json",
+			ShortName:   "",
+			Type:        "bool",
+			Description: "Output as JSON.",
+		},
+	},
+	SubComs: []*g.CliSC{
+		{
+			Name:        "show",
+			Description: "show the full details of a run",
+			PosFlags: []g.Flag{
+				{
+					Name:        "exec_id",
+					ShortName:   "",
+					Type:        "string",
+					Description: "The execution ID of the run",
+				},
+			},
+		},
+	},
+	ExecProcess: processHistory,
+}
+
+func processHistory(c *g.CliSC) (ok bool, err error) {
+	ok = true
+	asJSON := cast.ToBool(c.Vals["json"]) || os.Getenv("SLING_OUTPUT") == "json"
+
+	switch c.UsedSC() {
+	case "show":
+		execID := cast.ToString(c.Vals["exec_id"])
+		if execID == "" {
+			flaggy.ShowHelp("")
+			return ok, nil
+		}
+
+		execs, err := store.GetExecutions(execID)
+		if err != nil {
+			return ok, g.Error(err, "could not get run %s", execID)
+		}
+
+		if asJSON {
+			fmt.Println(g.Marshal(execs))
+			return ok, nil
+		}
+
+		for _, exec := range execs {
+			rows := [][]any{
+				{"Exec ID", exec.ExecID},
+				{"Stream", exec.StreamName},
+				{"Object", exec.Object},
+				{"Status", strings.ToUpper(string(exec.Status))},
+				{"Start Time", historyTime(exec)},
+				{"Duration", historyDuration(exec)},
+				{"Rows", humanize.Comma(cast.ToInt64(exec.Rows))},
+				{"Bytes", humanize.Bytes(exec.Bytes)},
+				{"Version", exec.Version},
+			}
+			if exec.FilePath != nil && *exec.FilePath != "" {
+				rows = append(rows, []any{"Config", *exec.FilePath})
+			}
+			fmt.Println(g.PrettyTable([]string{"Field", "Value"}, rows))
+
+			if exec.Err != nil {
+				fmt.Println(env.RedString(*exec.Err))
+			}
+		}
+	default:
+		execs, err := store.History(store.HistoryOptions{
+			Last:   cast.ToInt(c.Vals["last"]),
+			Stream: cast.ToString(c.Vals["stream"]),
+		})
+		if err != nil {
+			return ok, g.Error(err, "could not get history")
+		}
+
+		if asJSON {
+			fmt.Println(g.Marshal(execs))
+			return ok, nil
+		} else if len(execs) == 0 {
+			g.Info("no runs found")
+			return ok, nil
+		}
+
+		rows := [][]any{}
+		for _, exec := range execs {
+			errMsg := ""
+			if exec.Err != nil {
+				// the root cause is on the last line
+				lines := strings.Split(strings.TrimSpace(*exec.Err), "\n")
+				errMsg = lines[len(lines)-1]
+				if len(errMsg) > 80 {
+					errMsg = errMsg[:77] + "..."
+				}
+			}
+
+			rows = append(rows, []any{
+				exec.ExecID,
+				exec.StreamName,
+				strings.ToUpper(string(exec.Status)),
+				humanize.Comma(cast.ToInt64(exec.Rows)),
+				historyDuration(exec),
+				historyTime(exec),
+				errMsg,
+			})
+		}
+
+		header := []string{"Exec ID", "Stream", "Status", "Rows", "Duration", "Start Time", "Error"}
+		fmt.Println(g.PrettyTable(header, rows))
+	}
+
+	return ok, nil
+}
+
+// historyTime returns the local start time of the execution
+func historyTime(exec store.Execution) string {
+	if exec.StartTime == nil {
+		return ""
+	}
+	return exec.StartTime.Local().Format("2006-01-02 15:04:05")
+}
+
+// historyDuration returns the duration of the execution
+func historyDuration(exec store.Execution) string {
+	if exec.StartTime == nil || exec.EndTime == nil {
+		return ""
+	}
+	return g.DurationString(exec.EndTime.Sub(*exec.StartTime))
+}
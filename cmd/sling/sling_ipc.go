@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net"
+	"os"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/sling"
+	slingsdk "github.com/slingdata-io/sling-cli/pkg/sling"
+	"github.com/spf13/cast"
+)
+
+var cliIPC = &g.CliSC{
+	Name:                  "ipc",
+	Description:           "Run tasks with a JSON lines protocol over stdin / stdout (or a unix socket), with progress events and final stats, for wrappers such as sling-python",
+	AdditionalHelpPrepend: "\nSee more details at https://docs.slingdata.io/sling-cli/",
+	ExecuteWithoutFlags:   true,
+	Flags: []g.Flag{
+		{
+			Name:        "socket",
+			ShortName:   "",
+			Type:        "string",
+			Description: "The path of a unix socket to listen on, instead of stdin / stdout.",
+		},
+	},
+	ExecProcess: processIPC,
+}
+
+func processIPC(c *g.CliSC) (ok bool, err error) {
+	ok = true
+	sling.ShowProgress = false
+
+	socketPath := cast.ToString(c.Vals["socket"])
+	if socketPath == "" {
+		return ok, slingsdk.ServeIPC(ctx.Ctx, os.Stdin, os.Stdout)
+	}
+
+	if info, err := os.Stat(socketPath); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(socketPath) // stale socket
+	}
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return ok, g.Error(err, "could not listen on %s", socketPath)
+	}
+	defer os.Remove(socketPath)
+
+	go func() {
+		<-ctx.Ctx.Done()
+		listener.Close()
+	}()
+
+	g.Info("serving on %s (ipc protocol v%d)", socketPath, slingsdk.IPCProtocolVersion)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Ctx.Err() != nil {
+				return ok, nil
+			}
+			return ok, g.Error(err, "could not accept connection")
+		}
+
+		go func() {
+			defer conn.Close()
+			if err := slingsdk.ServeIPC(ctx.Ctx, conn, conn); err != nil {
+				g.LogError(err, "ipc connection error")
+			}
+		}()
+	}
+}
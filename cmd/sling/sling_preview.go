@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/integrii/flaggy"
+	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+	"github.com/slingdata-io/sling-cli/core/env"
+	"github.com/slingdata-io/sling-cli/core/sling"
+	"github.com/spf13/cast"
+	"gopkg.in/yaml.v2"
+)
+
+var cliPreview = &g.CliSC{
+	Name:                  "preview",
+	Description:           "Print the first rows of a source stream with the inferred column types, with the transforms applied, without a target",
+	AdditionalHelpPrepend: "\nSee more details at https://docs.slingdata.io/sling-cli/",
+	Flags: []g.Flag{
+		{
+			Name:        "src-conn",
+			ShortName:   "",
+			Type:        "string",
+			Description: "The source database / storage connection (name, conn string or URL).",
+		},
+		{
+			Name:        "src-stream",
+			ShortName:   "",
+			Type:        "string",
+			Description: "The source table (schema.table), local / cloud file path.\n                       Can also be the path of sql file or in-line text to use as query. Use `file://` for local paths.",
+		},
+		{
+			Name:        "src-options",
+			ShortName:   "",
+			Type:        "string",
+			Description: "in-line options to further configure source (JSON or YAML).",
+		},
+		{
+			Name:        "select",
+			ShortName:   "s",
+			Type:        "string",
+			Description: "Select or exclude specific columns from the source stream. (comma separated). Use '-' prefix to exclude.",
+		},
+		{
+			Name:        "where",
+			ShortName:   "",
+			Type:        "string",
+			Description: "Specify the WHERE clause to filter (if not providing custom SQL)",
+		},
+		{
+			Name:        "transforms",
+			ShortName:   "",
+			Type:        "string",
+			Description: "An object/map, or array/list of built-in transforms to apply to records (JSON or YAML).",
+		},
+		{
+			Name:        "columns",
+			ShortName:   "",
+			Type:        "string",
+			Description: "An object/map to specify the type that a column should be cast as (JSON or YAML).",
+		},
+		{
+			Name:        "limit",
+			ShortName:   "l",
+			Type:        "string",
+			Description: "The maximum number of rows to show (default 100).",
+		},
+		{
+			Name:        "output",
+			ShortName:   "o",
+			Type:        "string",
+			Description: "The output format: table (default) or json.",
+		},
+		{
+			Name:        "debug",
+			ShortName:   "d",
+			Type:        "bool",
+			Description: "Set logging level to DEBUG.",
+		},
+	},
+	ExecProcess: processPreview,
+}
+
+// processPreview prints the first rows and column types of a source stream
+func processPreview(c *g.CliSC) (ok bool, err error) {
+	ok = true
+
+	cfg, err := parseSourceFlags(c)
+	if err != nil {
+		return ok, err
+	} else if cfg == nil {
+		flaggy.ShowHelp("")
+		return ok, nil
+	}
+
+	limit := 100
+	if val := cast.ToString(c.Vals["limit"]); val != "" {
+		if limit, err = cast.ToIntE(val); err != nil || limit < 1 {
+			return ok, g.Error("invalid limit: %s", val)
+		}
+	}
+
+	output := strings.ToLower(cast.ToString(c.Vals["output"]))
+	switch output {
+	case "json":
+		os.Setenv("SLING_OUTPUT", output)
+		env.SetLogger()
+	case "", "table":
+	default:
+		return ok, g.Error("invalid output format: %s", output)
+	}
+
+	defer connection.CloseAll()
+
+	data, err := sling.Preview(cfg, limit)
+	if err != nil {
+		return ok, g.Error(err, "could not preview stream")
+	}
+
+	if output == "json" {
+		columns := []map[string]any{}
+		for _, col := range data.Columns {
+			columns = append(columns, g.M("name", col.Name, "type", col.Type, "db_type", col.DbType))
+		}
+		fmt.Println(g.Marshal(g.M("columns", columns, "rows", data.Rows)))
+		return ok, nil
+	}
+
+	fmt.Println(data.Columns.PrettyTable(false))
+	fmt.Println(data.PrettyTable())
+
+	return ok, nil
+}
+
+// parseSourceFlags builds the config of the source stream from the flags
+// (src-conn, src-stream, src-options, select, where, transforms, columns, debug).
+// Returns nil if the stream is not specified
+func parseSourceFlags(c *g.CliSC) (cfg *sling.Config, err error) {
+	cfg = &sling.Config{}
+	cfg.Source.Conn = cast.ToString(c.Vals["src-conn"])
+	cfg.Source.Stream = cast.ToString(c.Vals["src-stream"])
+	if cfg.Source.Stream == "" {
+		return nil, nil
+	} else if strings.Contains(cfg.Source.Stream, "://") && cfg.Source.Conn == "" {
+		cfg.Source.Conn = cfg.Source.Stream
+	}
+
+	if payload := cast.ToString(c.Vals["src-options"]); payload != "" {
+		options, err := parsePayload(payload, true)
+		if err != nil {
+			return nil, g.Error(err, "invalid source options -> %s", payload)
+		}
+
+		err = g.JSONConvert(options, &cfg.Source.Options)
+		if err != nil {
+			return nil, g.Error(err, "invalid source options -> %s", payload)
+		}
+	}
+
+	if val := cast.ToString(c.Vals["select"]); val != "" {
+		cfg.Source.Select = strings.Split(val, ",")
+	}
+	cfg.Source.Where = cast.ToString(c.Vals["where"])
+
+	if payload := cast.ToString(c.Vals["transforms"]); payload != "" {
+		if err = yaml.Unmarshal([]byte(payload), &cfg.Transforms); err != nil {
+			return nil, g.Error(err, "invalid transforms -> %s", payload)
+		}
+	}
+
+	if payload := cast.ToString(c.Vals["columns"]); payload != "" {
+		if err = yaml.Unmarshal([]byte(payload), &cfg.Target.Columns); err != nil {
+			return nil, g.Error(err, "invalid columns -> %s", payload)
+		}
+	}
+
+	if cast.ToBool(c.Vals["debug"]) {
+		cfg.Options.Debug = true
+		os.Setenv("DEBUG", "LOW")
+		env.SetLogger()
+	}
+
+	return cfg, nil
+}
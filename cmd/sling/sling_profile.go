@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/integrii/flaggy"
+	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+	"github.com/slingdata-io/sling-cli/core/env"
+	"github.com/slingdata-io/sling-cli/core/sling"
+	"github.com/spf13/cast"
+)
+
+var cliProfile = &g.CliSC{
+	Name:                  "profile",
+	Description:           "Stream a source and print per-column statistics (null rate, distinct estimate, min/max, length distribution)",
+	AdditionalHelpPrepend: "\nSee more details at https://docs.slingdata.io/sling-cli/",
+	Flags: []g.Flag{
+		{
+			Name:        "src-conn",
+			ShortName:   "",
+			Type:        "string",
+			Description: "The source database / storage connection (name, conn string or URL).",
+		},
+		{
+			Name:        "src-stream",
+			ShortName:   "",
+			Type:        "string",
+			Description: "The source table (schema.table), local / cloud file path.\n                       Can also be the path of sql file or in-line text to use as query. Use `file://` for local paths.",
+		},
+		{
+			Name:        "src-options",
+			ShortName:   "",
+			Type:        "string",
+			Description: "in-line options to further configure source (JSON or YAML).",
+		},
+		{
+			Name:        "select",
+			ShortName:   "s",
+			Type:        "string",
+			Description: "Select or exclude specific columns from the source stream. (comma separated). Use '-' prefix to exclude.",
+		},
+		{
+			Name:        "where",
+			ShortName:   "",
+			Type:        "string",
+			Description: "Specify the WHERE clause to filter (if not providing custom SQL)",
+		},
+		{
+			Name:        "transforms",
+			ShortName:   "",
+			Type:        "string",
+			Description: "An object/map, or array/list of built-in transforms to apply to records (JSON or YAML).",
+		},
+		{
+			Name:        "columns",
+			ShortName:   "",
+			Type:        "string",
+			Description: "An object/map to specify the type that a column should be cast as (JSON or YAML).",
+		},
+		{
+			Name:        "limit",
+			ShortName:   "l",
+			Type:        "string",
+			Description: "The maximum number of rows to profile (default all).",
+		},
+		{
+			Name:        "output",
+			ShortName:   "o",
+			Type:        "string",
+			Description: "The output format: markdown (default) or json.",
+		},
+		{
+			Name:        "debug",
+			ShortName:   "d",
+			Type:        "bool",
+			Description: "Set logging level to DEBUG.",
+		},
+	},
+	ExecProcess: processProfile,
+}
+
+// processProfile prints the column statistics of a source stream
+func processProfile(c *g.CliSC) (ok bool, err error) {
+	ok = true
+
+	cfg, err := parseSourceFlags(c)
+	if err != nil {
+		return ok, err
+	} else if cfg == nil {
+		flaggy.ShowHelp("")
+		return ok, nil
+	}
+
+	limit := 0
+	if val := cast.ToString(c.Vals["limit"]); val != "" {
+		if limit, err = cast.ToIntE(val); err != nil || limit < 1 {
+			return ok, g.Error("invalid limit: %s", val)
+		}
+	}
+
+	output := strings.ToLower(cast.ToString(c.Vals["output"]))
+	switch output {
+	case "json":
+		os.Setenv("SLING_OUTPUT", output)
+		env.SetLogger()
+	case "", "markdown", "md":
+	default:
+		return ok, g.Error("invalid output format: %s", output)
+	}
+
+	defer connection.CloseAll()
+
+	profile, err := sling.Profile(cfg, limit)
+	if err != nil {
+		return ok, g.Error(err, "could not profile stream")
+	}
+
+	if output == "json" {
+		fmt.Println(g.Marshal(profile))
+		return ok, nil
+	}
+
+	fmt.Println(profile.Markdown())
+
+	return ok, nil
+}
@@ -11,10 +11,14 @@ import (
 
 	"gopkg.in/yaml.v2"
 
+	"github.com/samber/lo"
 	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/slingdata-io/sling-cli/core/dbio"
 	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
 	"github.com/slingdata-io/sling-cli/core/env"
 	"github.com/slingdata-io/sling-cli/core/sling"
+	"github.com/slingdata-io/sling-cli/core/store"
 
 	"github.com/flarco/g"
 	"github.com/spf13/cast"
@@ -28,6 +32,11 @@ var (
 	totalBytes        = uint64(0)
 	constraintFails   = uint64(0)
 	lookupReplication = func(id string) (r sling.ReplicationConfig, e error) { return }
+	showConfig        = false
+	resumeLast        = false
+	streamOutputs     = []streamOutput{}
+	reportStreams     = []sling.RunReportStream{}
+	progressServer    *sling.ProgressServer
 
 	runReplication func(string, *sling.Config, ...string) error = replicationRun
 )
@@ -42,6 +51,8 @@ func processRun(c *g.CliSC) (ok bool, err error) {
 	taskCfgStr := ""
 	showExamples := false
 	selectStreams := []string{}
+	httpPort := 0
+	watch := false
 
 	// recover from panic
 	defer func() {
@@ -101,6 +112,9 @@ func processRun(c *g.CliSC) (ok bool, err error) {
 		case "limit":
 			cfg.Source.Options.Limit = g.Int(cast.ToInt(v))
 
+		case "sample":
+			cfg.Source.Options.Sample = &sling.SampleOptions{Rows: cast.ToInt(v)}
+
 		case "offset":
 			cfg.Source.Options.Offset = g.Int(cast.ToInt(v))
 
@@ -136,6 +150,12 @@ func processRun(c *g.CliSC) (ok bool, err error) {
 			if err != nil {
 				return ok, g.Error(err, "invalid env variable map -> %s", payload)
 			}
+		case "stdin-format":
+			format := dbio.FileType(strings.ToLower(cast.ToString(v)))
+			if format == "jsonl" {
+				format = dbio.FileTypeJsonLines
+			}
+			cfg.Source.Options.Format = &format
 		case "stdout":
 			cfg.Options.StdOut = cast.ToBool(v)
 		case "mode":
@@ -157,7 +177,19 @@ func processRun(c *g.CliSC) (ok bool, err error) {
 		case "where":
 			cfg.Source.Where = cast.ToString(v)
 		case "streams":
-			selectStreams = strings.Split(cast.ToString(v), ",")
+			selectStreams = append(selectStreams, strings.Split(cast.ToString(v), ",")...)
+		case "tags":
+			for _, tag := range strings.Split(cast.ToString(v), ",") {
+				if tag = strings.TrimSpace(tag); strings.HasPrefix(tag, "-") {
+					selectStreams = append(selectStreams, "-tag:"+strings.TrimPrefix(tag, "-"))
+				} else if tag != "" {
+					selectStreams = append(selectStreams, "tag:"+tag)
+				}
+			}
+		case "vars":
+			os.Setenv("SLING_VARS", cast.ToString(v))
+		case "env-profile":
+			os.Setenv("SLING_ENV_PROFILE", cast.ToString(v))
 		case "debug":
 			cfg.Options.Debug = cast.ToBool(v)
 			if cfg.Options.Debug && os.Getenv("DEBUG") == "" {
@@ -166,7 +198,55 @@ func processRun(c *g.CliSC) (ok bool, err error) {
 			}
 		case "examples":
 			showExamples = cast.ToBool(v)
+		case "show-config":
+			showConfig = cast.ToBool(v)
+		case "resume-last":
+			resumeLast = cast.ToBool(v)
+		case "dry-run":
+			if cast.ToBool(v) {
+				os.Setenv("SLING_DRY_RUN", "true")
+			}
+		case "log-sql":
+			os.Setenv("SLING_LOG_SQL", cast.ToString(v))
+		case "record":
+			os.Setenv("SLING_RECORD", cast.ToString(v))
+		case "replay":
+			os.Setenv("SLING_REPLAY", cast.ToString(v))
+		case "http-port":
+			httpPort = cast.ToInt(v)
+		case "watch":
+			watch = cast.ToBool(v)
+		case "lock":
+			os.Setenv("SLING_RUN_LOCK", cast.ToString(v))
+		case "output":
+			switch output := strings.ToLower(cast.ToString(v)); output {
+			case "json":
+				os.Setenv("SLING_OUTPUT", output)
+				env.SetLogger()
+			case "text":
+			default:
+				return ok, g.Error("invalid output format: %s", output)
+			}
+		}
+	}
+
+	if os.Getenv("SLING_RECORD") != "" && os.Getenv("SLING_REPLAY") != "" {
+		return ok, g.Error("cannot use --record and --replay together")
+	} else if watch && replicationCfgPath != "" {
+		return ok, g.Error("cannot use --watch with a replication")
+	}
+
+	if httpPort > 0 {
+		progressServer = sling.NewProgressServer(httpPort)
+		if err = progressServer.Start(); err != nil {
+			return ok, g.Error(err, "could not start progress server")
 		}
+		defer progressServer.Close()
+	}
+
+	if os.Getenv("SLING_OUTPUT") == "json" {
+		startTime := time.Now()
+		defer func() { printRunOutput(startTime, err) }()
 	}
 
 	if showExamples {
@@ -211,8 +291,29 @@ runReplication:
 		// run task, add replication config for md5
 		rc := cfg.AsReplication()
 
-		// run as replication is stream is wildcard
-		if cfg.HasWildcard() {
+		if showConfig {
+			replication, err := sling.LoadReplicationConfig(g.Marshal(rc))
+			if err != nil {
+				return ok, g.Error(err, "could not parse task configuration")
+			}
+
+			if err = replication.Compile(cfg); err != nil {
+				return ok, g.Error(err, "could not compile task configuration")
+			}
+
+			fmt.Println(replication.ShowConfig(cfg))
+			return ok, nil
+		}
+
+		if watch {
+			if err = runWatch(cfg); err != nil {
+				return ok, g.Error(err, "failure watching task (see docs @ https://docs.slingdata.io/sling-cli)")
+			}
+			return ok, nil
+		}
+
+		// run as replication is stream is wildcard or sharded
+		if cfg.HasWildcard() || cfg.IsSharded() {
 			replicationCfgPath = path.Join(env.GetTempFolder(), g.NewTsID("replication.temp")+".json")
 			err = os.WriteFile(replicationCfgPath, []byte(g.Marshal(rc)), 0775)
 			if err != nil {
@@ -234,6 +335,27 @@ runReplication:
 	return ok, err
 }
 
+// runWatch keeps running the task, with each batch of new or modified source files
+func runWatch(cfg *sling.Config) (err error) {
+	watcher, err := sling.NewWatcher(ctx.Ctx, cfg)
+	if err != nil {
+		return g.Error(err, "could not init watcher")
+	}
+
+	return watcher.Run(ctx.Ctx, func(files []string) error {
+		taskCfg := *cfg
+		taskCfg.Source.Options = nil
+		g.Unmarshal(g.Marshal(cfg.Source.Options), &taskCfg.Source.Options)
+		taskCfg.Source.Options.FileSelect = &files
+		taskCfg.Source.Options.TailOffsets = watcher.TailOffsets()
+		taskCfg.Env = lo.Assign(cfg.Env)
+
+		os.Setenv("SLING_EXEC_ID", sling.NewExecID())
+		rc := taskCfg.AsReplication()
+		return runTask(&taskCfg, &rc)
+	})
+}
+
 func runTask(cfg *sling.Config, replication *sling.ReplicationConfig) (err error) {
 	var task *sling.TaskExecution
 
@@ -330,6 +452,11 @@ func runTask(cfg *sling.Config, replication *sling.ReplicationConfig) (err error
 		env.SetTelVal("task_options", g.Marshal(taskOptions))
 		env.SetTelVal("task", g.Marshal(taskMap))
 
+		if task != nil && (task.StartTime != nil || err != nil) {
+			streamOutputs = append(streamOutputs, newStreamOutput(task, err))
+			reportStreams = append(reportStreams, sling.NewRunReportStream(task, err))
+		}
+
 		// telemetry
 		Track("run")
 	}()
@@ -362,8 +489,12 @@ func runTask(cfg *sling.Config, replication *sling.ReplicationConfig) (err error
 	task = sling.NewTask(os.Getenv("SLING_EXEC_ID"), cfg)
 	task.Replication = replication
 
+	if progressServer != nil {
+		progressServer.Track(task)
+	}
+
 	if cast.ToBool(cfg.Env["SLING_DRY_RUN"]) || cast.ToBool(os.Getenv("SLING_DRY_RUN")) {
-		return nil
+		return printPlan(task)
 	} else if replication.FailErr != "" {
 		task.Status = sling.ExecStatusError
 		task.Err = g.Error(replication.FailErr)
@@ -374,6 +505,18 @@ func runTask(cfg *sling.Config, replication *sling.ReplicationConfig) (err error
 		task.AppendOutput(ll)
 	}
 
+	// prevent concurrent runs of the same stream
+	var lock *store.StreamLock
+	if task.Err == nil {
+		var lockErr error
+		lock, lockErr = store.LockStream(ctx.Ctx, cfg, task.ExecID)
+		if lockErr != nil {
+			task.Status = sling.ExecStatusError
+			task.Err = lockErr
+		}
+		defer lock.Release()
+	}
+
 	sling.StateSet(task) // set into store
 
 	if task.Err != nil {
@@ -381,8 +524,8 @@ func runTask(cfg *sling.Config, replication *sling.ReplicationConfig) (err error
 		return
 	}
 
-	// set context
-	task.Context = ctx
+	// set context, cancelled if the run lock is lost
+	task.Context = lock.Context(ctx)
 
 	// set into store after
 	defer sling.StateSet(task)
@@ -390,8 +533,15 @@ func runTask(cfg *sling.Config, replication *sling.ReplicationConfig) (err error
 	// run task
 	setTM()
 	err = task.Execute()
+	if lockErr := lock.Err(); lockErr != nil {
+		task.Status = sling.ExecStatusError
+		err = g.Error(lockErr, "stream run stopped")
+	}
 
 	if err != nil {
+		if interrupted {
+			task.Status = sling.ExecStatusInterrupted
+		}
 
 		if replication != nil && (len(replication.Tasks) > 1 || projectID != "") {
 			// print error right after stream run if there are multiple runs
@@ -429,6 +579,27 @@ func runTask(cfg *sling.Config, replication *sling.ReplicationConfig) (err error
 	return nil
 }
 
+// printPlan prints the planned SQL and file paths of the task, without running it (dry-run)
+func printPlan(task *sling.TaskExecution) (err error) {
+	if task.Err != nil {
+		return g.Error(task.Err)
+	}
+
+	task.Context = ctx
+	plan, err := task.Plan()
+	if err != nil {
+		return g.Error(err, "could not plan stream %s", task.Config.StreamLabel())
+	}
+
+	if os.Getenv("SLING_OUTPUT") == "json" {
+		fmt.Println(g.Marshal(plan))
+	} else {
+		fmt.Println(plan.String())
+	}
+
+	return nil
+}
+
 func replicationRun(cfgPath string, cfgOverwrite *sling.Config, selectStreams ...string) (err error) {
 	startTime := time.Now()
 
@@ -444,6 +615,13 @@ func replicationRun(cfgPath string, cfgOverwrite *sling.Config, selectStreams ..
 		}
 	}
 
+	// variables are computed at run time only, since they run queries
+	if !showConfig && !cast.ToBool(os.Getenv("SLING_DRY_RUN")) {
+		if _, err = replication.ComputeVariables(); err != nil {
+			return g.Error(err, "could not compute replication variables")
+		}
+	}
+
 	err = replication.Compile(cfgOverwrite, selectStreams...)
 	if err != nil {
 		return g.Error(err, "Error compiling replication config")
@@ -454,6 +632,19 @@ func replicationRun(cfgPath string, cfgOverwrite *sling.Config, selectStreams ..
 		return
 	}
 
+	if showConfig {
+		fmt.Println(replication.ShowConfig(cfgOverwrite))
+		return
+	}
+
+	if resumeLast {
+		if err = resumeLastRun(&replication); err != nil {
+			return g.Error(err, "could not resume last run")
+		} else if len(replication.Tasks) == 0 {
+			return
+		}
+	}
+
 	// parse hooks
 	startHooks, err := replication.ParseDefaultHook(sling.HookStageStart)
 	if err != nil {
@@ -464,16 +655,27 @@ func replicationRun(cfgPath string, cfgOverwrite *sling.Config, selectStreams ..
 		return g.Error(err, "could not parse end hooks")
 	}
 
+	// hooks are not executed in dry-run
+	if cast.ToBool(os.Getenv("SLING_DRY_RUN")) {
+		startHooks, endHooks = nil, nil
+	}
+
 	eG := g.ErrorGroup{}
 	successes := 0
 
 	// get final stream count
 	streamCnt := 0
+	streamNames := []string{}
 	for _, cfg := range replication.Tasks {
 		if cfg.ReplicationStream.Disabled {
 			continue
 		}
 		streamCnt++
+		streamNames = append(streamNames, cfg.StreamLabel())
+	}
+
+	if progressServer != nil {
+		progressServer.Plan(streamNames...)
 	}
 
 	if streamCnt > 1 {
@@ -485,10 +687,20 @@ func replicationRun(cfgPath string, cfgOverwrite *sling.Config, selectStreams ..
 		return g.Error(err, "error executing start hooks")
 	}
 
-	counter := 0
+	counter, skipped, quarantined, stopped := 0, 0, 0, false
 	for _, cfg := range replication.Tasks {
-		if interrupted {
-			break
+		if interrupted || stopped {
+			// mark remaining streams as skipped, to be resumed with --resume-last
+			if !cfg.ReplicationStream.Disabled {
+				sling.StateSet(&sling.TaskExecution{
+					ExecID:      os.Getenv("SLING_EXEC_ID"),
+					Config:      cfg,
+					Status:      sling.ExecStatusSkipped,
+					Replication: &replication,
+				})
+				skipped++
+			}
+			continue
 		}
 
 		env.LogSink = nil // clear log sink
@@ -497,7 +709,19 @@ func replicationRun(cfgPath string, cfgOverwrite *sling.Config, selectStreams ..
 			println()
 			g.Debug("skipping stream %s since it is disabled", cfg.StreamName)
 			continue
-		} else if streamCnt == 1 {
+		} else if replication.OnStreamError == sling.StreamErrorQuarantine {
+			if reason, err := sling.GetQuarantine(cfg); err != nil {
+				g.Warn("could not get quarantine state of stream %s: %s", cfg.StreamName, err.Error())
+			} else if reason != "" {
+				println()
+				counter++
+				quarantined++
+				g.Warn("skipping stream %s since it is quarantined (%s). Use `sling state clear-quarantine` to clear it.", cfg.StreamName, reason)
+				continue
+			}
+		}
+
+		if streamCnt == 1 {
 			g.Info("Sling Replication | %s -> %s | %s", replication.Source, replication.Target, cfg.StreamName)
 		} else {
 			println()
@@ -515,6 +739,19 @@ func replicationRun(cfgPath string, cfgOverwrite *sling.Config, selectStreams ..
 			if e, ok := err.(*g.ErrType); ok && strings.Contains(e.Debug(), "Could not connect to ") {
 				replication.FailErr = g.ErrMsg(e)
 			}
+
+			switch replication.OnStreamError {
+			case sling.StreamErrorFailFast:
+				g.Warn("stopping replication since stream %s failed (on_stream_error: fail_fast)", cfg.StreamName)
+				stopped = true
+			case sling.StreamErrorQuarantine:
+				reason := strings.TrimPrefix(strings.Split(g.ErrMsg(err), "\n")[0], "~ ")
+				if e := sling.SetQuarantine(cfg, reason); e != nil {
+					g.Warn("could not quarantine stream %s: %s", cfg.StreamName, e.Error())
+				} else {
+					g.Warn("quarantined stream %s, it will be skipped on the next runs", cfg.StreamName)
+				}
+			}
 		} else {
 			successes++
 		}
@@ -525,6 +762,16 @@ func replicationRun(cfgPath string, cfgOverwrite *sling.Config, selectStreams ..
 		eG.Capture(err, "end-hooks")
 	}
 
+	// write the run report
+	if replication.Report != nil {
+		report := sling.NewRunReport(&replication, os.Getenv("SLING_EXEC_ID"), startTime, reportStreams)
+		if location, err := replication.WriteReport(ctx.Ctx, report); err != nil {
+			g.Warn("could not write run report: %s", g.ErrMsgSimple(err))
+		} else {
+			g.Info("wrote run report to %s", location)
+		}
+	}
+
 	println()
 	delta := time.Since(startTime)
 
@@ -536,6 +783,14 @@ func replicationRun(cfgPath string, cfgOverwrite *sling.Config, selectStreams ..
 		failureStr = env.GreenString(failureStr)
 	}
 
+	if skipped > 0 {
+		failureStr = failureStr + " | " + env.MagentaString(g.F("%d Skipped", skipped))
+		g.Warn("replication was %s, %d streams were skipped. Use the --resume-last flag to continue.", lo.Ternary(stopped, "stopped", "interrupted"), skipped)
+	}
+	if quarantined > 0 {
+		failureStr = failureStr + " | " + env.MagentaString(g.F("%d Quarantined", quarantined))
+	}
+
 	if streamCnt > 1 {
 		g.Info("Sling Replication Completed in %s | %s -> %s | %s | %s\n", g.DurationString(delta), replication.Source, replication.Target, successStr, failureStr)
 	}
@@ -543,6 +798,61 @@ func replicationRun(cfgPath string, cfgOverwrite *sling.Config, selectStreams ..
 	return eG.Err()
 }
 
+// resumeLastRun removes the streams that completed successfully in the
+// runs since the last fully successful one, up to the first unfinished stream
+func resumeLastRun(replication *sling.ReplicationConfig) (err error) {
+	runs, err := store.ReplicationRuns(replication.MD5())
+	if err != nil {
+		return g.Error(err, "could not get last replication run")
+	} else if len(runs) == 0 {
+		g.Warn("no previous run found for replication, running all streams")
+		return nil
+	}
+
+	streamIDs := []string{}
+	for _, cfg := range replication.Tasks {
+		if !cfg.ReplicationStream.Disabled {
+			streamIDs = append(streamIDs, cfg.StreamID())
+		}
+	}
+
+	// merge the runs since the last fully successful one, since an
+	// interrupted resumed run only holds the streams it was resuming
+	statuses := map[string]sling.ExecStatus{}
+	for i, run := range runs {
+		successful := lo.EveryBy(streamIDs, func(id string) bool { return run.Statuses[id] == sling.ExecStatusSuccess })
+		if i > 0 && successful {
+			break
+		}
+		for id, status := range run.Statuses {
+			if _, ok := statuses[id]; !ok {
+				statuses[id] = status // newest first
+			}
+		}
+		if lo.EveryBy(streamIDs, func(id string) bool { _, ok := statuses[id]; return ok }) {
+			break
+		}
+	}
+
+	execID := runs[0].ExecID
+	for i, cfg := range replication.Tasks {
+		if cfg.ReplicationStream.Disabled {
+			continue
+		} else if statuses[cfg.StreamID()] != sling.ExecStatusSuccess {
+			if i > 0 {
+				g.Info("resuming last run (%s) from stream %s, skipping %d completed streams", execID, cfg.StreamName, i)
+			}
+			replication.Tasks = replication.Tasks[i:]
+			return nil
+		}
+	}
+
+	g.Info("all streams of the last run (%s) were successful, nothing to resume", execID)
+	replication.Tasks = nil
+
+	return nil
+}
+
 func parsePayload(payload string, validate bool) (options map[string]any, err error) {
 	payload = strings.TrimSpace(payload)
 	if payload == "" {
@@ -638,3 +948,79 @@ func testOutput(rowCnt int64, totalBytes, constraintFails uint64) error {
 
 	return nil
 }
+
+// runOutput is the machine-readable result of a run (with `--output json`)
+type runOutput struct {
+	Status   sling.ExecStatus `json:"status"`
+	Rows     int64            `json:"rows"`
+	Bytes    uint64           `json:"bytes"`
+	Duration float64          `json:"duration"` // in seconds
+	Streams  []streamOutput   `json:"streams"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// streamOutput is the result of a stream run
+type streamOutput struct {
+	Stream   string           `json:"stream"`
+	Object   string           `json:"object,omitempty"`
+	Status   sling.ExecStatus `json:"status"`
+	Rows     uint64           `json:"rows"`
+	Bytes    uint64           `json:"bytes"`
+	Duration float64          `json:"duration"` // in seconds
+	Columns  []columnOutput   `json:"columns,omitempty"`
+	Warnings []string         `json:"warnings,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+type columnOutput struct {
+	Name string         `json:"name"`
+	Type iop.ColumnType `json:"type"`
+}
+
+func newStreamOutput(task *sling.TaskExecution, err error) (so streamOutput) {
+	inBytes, outBytes := task.GetBytes()
+	so = streamOutput{
+		Stream:   task.Config.StreamName,
+		Object:   task.Config.Target.Object,
+		Status:   task.Status,
+		Rows:     task.GetCount(),
+		Bytes:    lo.Ternary(inBytes == 0, outBytes, inBytes),
+		Warnings: task.Warnings(),
+	}
+
+	if task.StartTime != nil {
+		endTime := lo.Ternary(task.EndTime != nil, g.PtrVal(task.EndTime), time.Now())
+		so.Duration = endTime.Sub(*task.StartTime).Seconds()
+	}
+
+	if df := task.Df(); df != nil {
+		for _, col := range df.Columns {
+			so.Columns = append(so.Columns, columnOutput{Name: col.Name, Type: col.Type})
+		}
+	}
+
+	if err != nil {
+		so.Status = sling.ExecStatusError
+		so.Error = g.ErrMsgSimple(err)
+	}
+
+	return so
+}
+
+// printRunOutput prints the result of the run as JSON, to stdout
+func printRunOutput(startTime time.Time, err error) {
+	output := runOutput{
+		Status:   sling.ExecStatusSuccess,
+		Rows:     rowCount,
+		Bytes:    totalBytes,
+		Duration: time.Since(startTime).Seconds(),
+		Streams:  streamOutputs,
+	}
+
+	if err != nil {
+		output.Status = sling.ExecStatusError
+		output.Error = g.ErrMsgSimple(err)
+	}
+
+	fmt.Fprintln(os.Stdout, g.Marshal(output))
+}
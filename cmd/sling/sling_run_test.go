@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/sling"
+	"github.com/slingdata-io/sling-cli/core/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResumeLastRun(t *testing.T) {
+	initTestStore(t)
+
+	newReplication := func() *sling.ReplicationConfig {
+		replication := &sling.ReplicationConfig{}
+		for _, name := range []string{"s1", "s2", "s3"} {
+			replication.Tasks = append(replication.Tasks, &sling.Config{StreamName: name, ReplicationStream: &sling.ReplicationStreamConfig{}})
+		}
+		return replication
+	}
+	streamsOf := func(replication *sling.ReplicationConfig) []string {
+		return lo.Map(replication.Tasks, func(cfg *sling.Config, i int) string { return cfg.StreamName })
+	}
+	replication := newReplication()
+	record := func(execID string, statuses ...sling.ExecStatus) {
+		for i, status := range statuses {
+			cfg := replication.Tasks[len(replication.Tasks)-len(statuses)+i]
+			exec := store.Execution{ExecID: execID, ReplicationMD5: replication.MD5(), StreamID: cfg.StreamID(), StreamName: cfg.StreamName, Status: status}
+			require.NoError(t, store.Db.Create(&exec).Error)
+		}
+	}
+
+	resumed := newReplication()
+	require.NoError(t, resumeLastRun(resumed))
+	assert.Equal(t, []string{"s1", "s2", "s3"}, streamsOf(resumed), "no previous run")
+
+	// a full run, then an interrupted one
+	record("exec1", sling.ExecStatusSuccess, sling.ExecStatusSuccess, sling.ExecStatusSuccess)
+	record("exec2", sling.ExecStatusSuccess, sling.ExecStatusError, sling.ExecStatusSkipped)
+	resumed = newReplication()
+	require.NoError(t, resumeLastRun(resumed))
+	assert.Equal(t, []string{"s2", "s3"}, streamsOf(resumed))
+
+	// the resumed run is interrupted as well, s2 stays completed
+	record("exec3", sling.ExecStatusSuccess, sling.ExecStatusError)
+	resumed = newReplication()
+	require.NoError(t, resumeLastRun(resumed))
+	assert.Equal(t, []string{"s3"}, streamsOf(resumed))
+
+	record("exec4", sling.ExecStatusSuccess)
+	resumed = newReplication()
+	require.NoError(t, resumeLastRun(resumed))
+	assert.Empty(t, resumed.Tasks, "nothing to resume")
+}
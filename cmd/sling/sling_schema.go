@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/integrii/flaggy"
+	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+	"github.com/slingdata-io/sling-cli/core/dbio/database"
+	"github.com/slingdata-io/sling-cli/core/env"
+	"github.com/slingdata-io/sling-cli/core/sling"
+	"github.com/spf13/cast"
+)
+
+var cliSchema = &g.CliSC{
+	Name:                  "schema",
+	Description:           "Manage database schemas",
+	AdditionalHelpPrepend: "\nSee more details at https://docs.slingdata.io/sling-cli/",
+	SubComs: []*g.CliSC{
+		{
+			Name:        "copy",
+			Description: "create the tables of a source schema (columns, primary keys, indexes) as empty tables on the target database, without data",
+			Flags: []g.Flag{
+				{
+					Name:        "src-conn",
+					ShortName:   "",
+					Type:        "string",
+					Description: "The source database connection.",
+				},
+				{
+					Name:        "tgt-conn",
+					ShortName:   "",
+					Type:        "string",
+					Description: "The target database connection.",
+				},
+				{
+					Name:        "schema",
+					ShortName:   "",
+					Type:        "string",
+					Description: "The source schema to copy.",
+				},
+				{
+					Name:        "tgt-schema",
+					ShortName:   "",
+					Type:        "string",
+					Description: "The target schema (defaults to the source schema).",
+				},
+				{
+					Name:        "tables",
+					ShortName:   "",
+					Type:        "string",
+					Description: "Only copy the specified tables (comma separated, glob patterns accepted).",
+				},
+				{
+					Name:        "ddl-only",
+					ShortName:   "",
+					Type:        "bool",
+					Description: "Print the DDL statements instead of executing them.",
+				},
+				{
+					Name:        "debug",
+					ShortName:   "d",
+					Type:        "bool",
+					Description: "Set logging level to DEBUG.",
+				},
+			},
+		},
+	},
+	ExecProcess: processSchema,
+}
+
+// processSchema runs the schema sub-commands
+func processSchema(c *g.CliSC) (ok bool, err error) {
+	ok = true
+
+	if cast.ToBool(c.Vals["debug"]) {
+		os.Setenv("DEBUG", "LOW")
+		env.InitLogger()
+	}
+
+	switch c.UsedSC() {
+	case "copy":
+		opts := sling.SchemaCopyOptions{
+			Schema:       cast.ToString(c.Vals["schema"]),
+			TargetSchema: cast.ToString(c.Vals["tgt-schema"]),
+			DDLOnly:      cast.ToBool(c.Vals["ddl-only"]),
+		}
+		if val := cast.ToString(c.Vals["tables"]); val != "" {
+			opts.Tables = strings.Split(val, ",")
+		}
+
+		srcName, tgtName := cast.ToString(c.Vals["src-conn"]), cast.ToString(c.Vals["tgt-conn"])
+		if srcName == "" || tgtName == "" || opts.Schema == "" {
+			flaggy.ShowHelp("")
+			return ok, nil
+		}
+
+		return ok, schemaCopy(srcName, tgtName, opts)
+	case "":
+		return false, nil
+	}
+
+	return ok, nil
+}
+
+func schemaCopy(srcName, tgtName string, opts sling.SchemaCopyOptions) (err error) {
+	defer connection.CloseAll()
+
+	srcConn, err := connectDatabase(srcName)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	tgtConn, err := connectDatabase(tgtName)
+	if err != nil {
+		return err
+	}
+	defer tgtConn.Close()
+
+	tables, err := sling.SchemaCopy(srcConn, tgtConn, opts)
+	if err != nil {
+		return g.Error(err, "could not copy schema %s", opts.Schema)
+	}
+
+	if opts.DDLOnly {
+		for _, table := range tables {
+			fmt.Println(strings.Join(table.Statements, ";\n") + ";\n")
+		}
+		return nil
+	}
+
+	created := 0
+	for _, table := range tables {
+		if !table.Exists {
+			created++
+		}
+	}
+	g.Info("created %d of %d tables in %s", created, len(tables), tgtName)
+
+	return nil
+}
+
+// connectDatabase connects to a local database connection
+func connectDatabase(name string) (conn database.Connection, err error) {
+	entry := connection.GetLocalConns().Get(name)
+	if entry.Name == "" {
+		return nil, g.Error("did not find connection %s", name)
+	} else if !entry.Connection.Type.IsDb() {
+		return nil, g.Error("connection %s is not a database", name)
+	}
+
+	conn, err = entry.Connection.AsDatabase()
+	if err != nil {
+		return nil, g.Error(err, "could not initialize connection %s", name)
+	}
+
+	if err = conn.Connect(); err != nil {
+		return nil, g.Error(err, "could not connect to %s", name)
+	}
+
+	return conn, nil
+}
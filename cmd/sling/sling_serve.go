@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net"
+	"os"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/sling"
+	"github.com/spf13/cast"
+)
+
+var cliServe = &g.CliSC{
+	Name:                  "serve",
+	Description:           "Serve sling over Arrow Flight (gRPC), to run tasks (RunTask, GetStatus, CancelTask actions) and stream rows (DoGet) from other services",
+	AdditionalHelpPrepend: "\nSee more details at https://docs.slingdata.io/sling-cli/",
+	ExecuteWithoutFlags:   true,
+	Flags: []g.Flag{
+		{
+			Name:        "host",
+			ShortName:   "",
+			Type:        "string",
+			Description: "The host to listen on (default localhost). A token is required for a non-loopback host.",
+		},
+		{
+			Name:        "port",
+			ShortName:   "p",
+			Type:        "string",
+			Description: "The port to listen on (default 8815).",
+		},
+		{
+			Name:        "token",
+			ShortName:   "",
+			Type:        "string",
+			Description: "The bearer token required from clients (default from the SLING_SERVE_TOKEN env var).",
+		},
+	},
+	ExecProcess: processServe,
+}
+
+func processServe(c *g.CliSC) (ok bool, err error) {
+	ok = true
+
+	host := cast.ToString(c.Vals["host"])
+	if host == "" {
+		host = "localhost"
+	}
+	port := cast.ToString(c.Vals["port"])
+	if port == "" {
+		port = "8815"
+	}
+	token := cast.ToString(c.Vals["token"])
+	if token == "" {
+		token = os.Getenv("SLING_SERVE_TOKEN")
+	}
+	if token == "" && !isLoopbackHost(host) {
+		return ok, g.Error("a token is required to serve on %s, which is not a loopback host (see --token or SLING_SERVE_TOKEN)", host)
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return ok, g.Error(err, "could not listen on %s:%s", host, port)
+	}
+
+	sling.ShowProgress = false
+	return ok, sling.NewFlightServer(token).Serve(ctx.Ctx, listener)
+}
+
+// isLoopbackHost returns true if the host only accepts local connections
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/flarco/g"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsLoopbackHost(t *testing.T) {
+	hosts := map[string]bool{
+		"localhost":   true,
+		"LOCALHOST":   true,
+		"127.0.0.1":   true,
+		"127.0.0.2":   true,
+		"::1":         true,
+		"[::1]":       true,
+		"0.0.0.0":     false,
+		"":            false,
+		"192.168.1.5": false,
+		"example.com": false,
+	}
+	for host, expected := range hosts {
+		assert.Equal(t, expected, isLoopbackHost(host), host)
+	}
+}
+
+func TestServeToken(t *testing.T) {
+	t.Setenv("SLING_SERVE_TOKEN", "")
+	_, err := processServe(&g.CliSC{Vals: g.M("host", "0.0.0.0", "port", "0")})
+	assert.ErrorContains(t, err, "token is required")
+}
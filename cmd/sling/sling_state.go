@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/integrii/flaggy"
+	"github.com/slingdata-io/sling-cli/core/store"
+	"github.com/spf13/cast"
+)
+
+var cliState = &g.CliSC{
+	Name:                  "state",
+	Singular:              "incremental state",
+	Description:           "Manage the incremental values of the streams, persisted in the local store or a remote backend (env vars SLING_STATE, SLING_STATE_CONNECTION)",
+	AdditionalHelpPrepend: "\nSee more details at https://docs.slingdata.io/sling-cli/",
+	SubComs: []*g.CliSC{
+		{
+			Name:        "list",
+			Description: "list the incremental values of the streams",
+		},
+		{
+			Name:        "get",
+			Description: "get the incremental value of a stream",
+			PosFlags: []g.Flag{
+				{
+					Name:        "key",
+					ShortName:   "",
+					Type:        "string",
+					Description: "The state key of the stream (SOURCE/TARGET/stream_name)",
+				},
+			},
+		},
+		{
+			Name:        "set",
+			Description: "set the incremental value of a stream",
+			PosFlags: []g.Flag{
+				{
+					Name:        "key",
+					ShortName:   "",
+					Type:        "string",
+					Description: "The state key of the stream (SOURCE/TARGET/stream_name)",
+				},
+				{
+					Name:        "value",
+					ShortName:   "",
+					Type:        "string",
+					Description: "The incremental value, as a SQL literal (e.g. `'2024-01-01 00:00:00'` or `100`)",
+				},
+			},
+		},
+		{
+			Name:        "export",
+			Description: "export all the incremental values into a JSON file",
+			PosFlags: []g.Flag{
+				{
+					Name:        "file",
+					ShortName:   "",
+					Type:        "string",
+					Description: "The path of the JSON file to write",
+				},
+			},
+		},
+		{
+			Name:        "import",
+			Description: "import the incremental values from a JSON file",
+			PosFlags: []g.Flag{
+				{
+					Name:        "file",
+					ShortName:   "",
+					Type:        "string",
+					Description: "The path of the JSON file to read",
+				},
+			},
+		},
+		{
+			Name:        "clear-quarantine",
+			Description: "clear the quarantine of a stream, or of all streams, to run them again",
+			Flags: []g.Flag{
+				{
+					Name:        "key",
+					ShortName:   "",
+					Type:        "string",
+					Description: "The state key of the stream (SOURCE/TARGET/stream_name), defaults to all",
+				},
+			},
+		},
+	},
+	ExecProcess: processState,
+}
+
+func processState(c *g.CliSC) (ok bool, err error) {
+	ok = true
+
+	backend, err := store.NewStateBackend(os.Getenv("SLING_STATE"))
+	if err != nil {
+		return ok, g.Error(err, "could not init state backend")
+	}
+
+	switch c.UsedSC() {
+	case "list":
+		states, err := backend.List()
+		if err != nil {
+			return ok, err
+		}
+
+		if os.Getenv("SLING_OUTPUT") == "json" {
+			fmt.Println(g.Marshal(states))
+			return ok, nil
+		}
+
+		rows := [][]any{}
+		for _, state := range states {
+			rows = append(rows, []any{state.Key, state.Value, state.UpdatedDt.Local().Format("2006-01-02 15:04:05")})
+		}
+		fmt.Println(g.PrettyTable([]string{"Key", "Value", "Updated"}, rows))
+
+	case "get":
+		key := cast.ToString(c.Vals["key"])
+		if key == "" {
+			flaggy.ShowHelp("")
+			return ok, nil
+		}
+
+		value, found, err := backend.Get(key)
+		if err != nil {
+			return ok, err
+		} else if !found {
+			return ok, g.Error("no state found for %s", key)
+		}
+		fmt.Println(value)
+
+	case "set":
+		key, value := cast.ToString(c.Vals["key"]), cast.ToString(c.Vals["value"])
+		if key == "" || value == "" {
+			flaggy.ShowHelp("")
+			return ok, nil
+		}
+
+		if err = backend.Set(store.State{Key: key, Value: value}); err != nil {
+			return ok, err
+		}
+		g.Info("set state of %s to %s", key, value)
+
+	case "export":
+		filePath := cast.ToString(c.Vals["file"])
+		if filePath == "" {
+			flaggy.ShowHelp("")
+			return ok, nil
+		}
+
+		states, err := backend.List()
+		if err != nil {
+			return ok, err
+		}
+
+		if err = os.WriteFile(filePath, []byte(g.Pretty(states)), 0644); err != nil {
+			return ok, g.Error(err, "could not write %s", filePath)
+		}
+		g.Info("exported %d states into %s", len(states), filePath)
+
+	case "import":
+		filePath := cast.ToString(c.Vals["file"])
+		if filePath == "" {
+			flaggy.ShowHelp("")
+			return ok, nil
+		}
+
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return ok, g.Error(err, "could not read %s", filePath)
+		}
+
+		states := []store.State{}
+		if err = g.Unmarshal(string(data), &states); err != nil {
+			return ok, g.Error(err, "could not parse %s", filePath)
+		}
+
+		if err = backend.Set(states...); err != nil {
+			return ok, err
+		}
+		g.Info("imported %d states from %s", len(states), filePath)
+
+	case "clear-quarantine":
+		key := strings.TrimPrefix(cast.ToString(c.Vals["key"]), store.QuarantinePrefix)
+
+		states, err := backend.List()
+		if err != nil {
+			return ok, err
+		}
+
+		cleared := []store.State{}
+		for _, state := range states {
+			if !strings.HasPrefix(state.Key, store.QuarantinePrefix) || state.Value == "" {
+				continue
+			} else if key == "" || strings.EqualFold(state.Key, store.QuarantinePrefix+key) {
+				g.Info("clearing quarantine of %s (%s)", strings.TrimPrefix(state.Key, store.QuarantinePrefix), state.Value)
+				cleared = append(cleared, store.State{Key: state.Key, Value: ""})
+			}
+		}
+
+		if len(cleared) == 0 {
+			g.Info("no quarantined streams found")
+			return ok, nil
+		}
+
+		if err = backend.Set(cleared...); err != nil {
+			return ok, err
+		}
+		g.Info("cleared the quarantine of %d streams", len(cleared))
+
+	default:
+		return false, nil
+	}
+
+	return ok, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/flarco/g"
+	"github.com/integrii/flaggy"
+	"github.com/slingdata-io/sling-cli/core/env"
+	"github.com/slingdata-io/sling-cli/core/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// initTestStore points the store to a temporary .sling.db
+func initTestStore(t *testing.T) {
+	t.Setenv("SLING_STATE_CONNECTION", "")
+	t.Setenv("SLING_STATE", "")
+
+	homeDir, db, conn := env.HomeDir, store.Db, store.Conn
+	env.HomeDir, store.Db = t.TempDir(), nil
+	store.InitDB()
+	require.NotNil(t, store.Db)
+
+	t.Cleanup(func() {
+		store.Conn.Close()
+		env.HomeDir, store.Db, store.Conn = homeDir, db, conn
+	})
+}
+
+// runSubCommand runs a sub-command of a cli command, returning its stdout
+func runSubCommand(t *testing.T, cli *g.CliSC, name string, vals map[string]any) (stdout string, err error) {
+	c := &g.CliSC{
+		Sc:   &flaggy.Subcommand{Subcommands: []*flaggy.Subcommand{{Name: name, Used: true}}},
+		Vals: vals,
+	}
+
+	reader, writer, _ := os.Pipe()
+	origStdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = origStdout }()
+
+	_, err = cli.ExecProcess(c)
+	writer.Close()
+	out, _ := io.ReadAll(reader)
+	return strings.TrimSpace(string(out)), err
+}
+
+func TestStateCommand(t *testing.T) {
+	initTestStore(t)
+	exportPath := path.Join(t.TempDir(), "states.json")
+
+	tests := []struct {
+		name     string
+		sub      string
+		vals     map[string]any
+		expected string // stdout
+		wantErr  bool
+	}{
+		{name: "get missing", sub: "get", vals: g.M("key", "PG/SF/public.orders"), wantErr: true},
+		{name: "set", sub: "set", vals: g.M("key", "PG/SF/public.orders", "value", "100")},
+		{name: "set other", sub: "set", vals: g.M("key", "PG/SF/public.users", "value", "'2024-01-01 00:00:00'")},
+		{name: "get", sub: "get", vals: g.M("key", "PG/SF/public.orders"), expected: "100"},
+		{name: "export", sub: "export", vals: g.M("file", exportPath)},
+		{name: "overwrite", sub: "set", vals: g.M("key", "PG/SF/public.orders", "value", "200")},
+		{name: "import", sub: "import", vals: g.M("file", exportPath)},
+		{name: "get imported", sub: "get", vals: g.M("key", "PG/SF/public.orders"), expected: "100"},
+		{name: "import missing file", sub: "import", vals: g.M("file", exportPath+".missing"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		stdout, err := runSubCommand(t, cliState, tt.sub, tt.vals)
+		if tt.wantErr {
+			assert.Error(t, err, tt.name)
+			continue
+		} else if !assert.NoError(t, err, tt.name) {
+			continue
+		}
+		if tt.expected != "" {
+			assert.Equal(t, tt.expected, stdout, tt.name)
+		}
+	}
+
+	// the export has all the states
+	var states []store.State
+	data, err := os.ReadFile(exportPath)
+	require.NoError(t, err)
+	require.NoError(t, g.Unmarshal(string(data), &states))
+	if assert.Len(t, states, 2) {
+		assert.Equal(t, "PG/SF/public.orders", states[0].Key)
+		assert.Equal(t, "'2024-01-01 00:00:00'", states[1].Value)
+	}
+}
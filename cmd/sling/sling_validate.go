@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/flarco/g"
+	"github.com/integrii/flaggy"
+	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+	"github.com/slingdata-io/sling-cli/core/env"
+	"github.com/slingdata-io/sling-cli/core/sling"
+	"github.com/spf13/cast"
+)
+
+var cliValidate = &g.CliSC{
+	Name:                  "validate",
+	Description:           "Validate a replication config (connections, wildcards, modes and keys), without running it",
+	AdditionalHelpPrepend: "\nSee more details at https://docs.slingdata.io/sling-cli/",
+	PosFlags: []g.Flag{
+		{
+			Name:        "replication",
+			ShortName:   "",
+			Type:        "string",
+			Description: "The replication config file to validate (JSON or YAML).",
+		},
+	},
+	Flags: []g.Flag{
+		{
+			Name:        "debug",
+			ShortName:   "d",
+			Type:        "bool",
+			Description: "Set logging level to DEBUG.",
+		},
+	},
+	ExecProcess: processValidate,
+}
+
+// processValidate reports all the errors of a replication config at once
+func processValidate(c *g.CliSC) (ok bool, err error) {
+	ok = true
+
+	cfgPath := cast.ToString(c.Vals["replication"])
+	if cfgPath == "" {
+		flaggy.ShowHelp("")
+		return ok, nil
+	}
+
+	if cast.ToBool(c.Vals["debug"]) {
+		os.Setenv("DEBUG", "LOW")
+		env.InitLogger()
+	}
+
+	defer connection.CloseAll()
+
+	var errs sling.ValidationErrors
+	replication, err := sling.LoadReplicationConfigFromFile(cfgPath)
+	if err != nil {
+		errs = sling.ValidationErrors{{Message: g.ErrMsgSimple(err)}}
+	} else {
+		errs = replication.Validate()
+	}
+
+	if os.Getenv("SLING_OUTPUT") == "json" {
+		fmt.Println(g.Marshal(g.M("valid", len(errs) == 0, "errors", errs)))
+	} else if len(errs) > 0 {
+		fmt.Println(errs.Error())
+	}
+
+	if len(errs) > 0 {
+		return ok, g.Error("replication is invalid: %d error(s) found", len(errs))
+	}
+
+	g.Info("replication is valid")
+	return ok, nil
+}
@@ -227,7 +227,7 @@ func (c *Connection) URL() string {
 			url = "file://"
 		case dbio.TypeFileSftp:
 			url = g.F("%s://%s:%s", c.Type.String(), c.Data["host"], cast.ToString(c.Data["port"]))
-		case dbio.TypeFileFtp:
+		case dbio.TypeFileFtp, dbio.TypeFileImap:
 			url = g.F("%s://%s:%s", c.Type.String(), c.Data["host"], cast.ToString(c.Data["port"]))
 		case dbio.TypeFileS3:
 			url = g.F("%s://%s", c.Type.String(), c.Data["bucket"])
@@ -273,6 +273,10 @@ func (c *Connection) AsDatabaseContext(ctx context.Context, cache ...bool) (dc d
 		return nil, g.Error("not a database type: %s", c.Type)
 	}
 
+	if err = c.ResolveSecrets(); err != nil {
+		return nil, err
+	}
+
 	// default cache to true
 	if len(cache) == 0 || (len(cache) > 0 && cache[0]) {
 		if cc, ok := connCache.Get(c.Hash()); ok {
@@ -308,6 +312,10 @@ func (c *Connection) AsFileContext(ctx context.Context, cache ...bool) (fc files
 		return nil, g.Error("not a file system type: %s", c.Type)
 	}
 
+	if err = c.ResolveSecrets(); err != nil {
+		return nil, err
+	}
+
 	// default cache to true
 	if len(cache) == 0 || (len(cache) > 0 && cache[0]) {
 		if cc, ok := connCache.Get(c.Hash()); ok {
@@ -370,6 +378,11 @@ func (c *Connection) ConnSetDatabase(dbName string) *Connection {
 func (c *Connection) setURL() (err error) {
 	c.setFromEnv()
 
+	// secrets are resolved when the connection is used (see ResolveSecrets)
+	if hasSecretRefs(c.Data) {
+		return nil
+	}
+
 	// setIfMissing sets a default value if key is not present
 	setIfMissing := func(key string, val interface{}) {
 		if v, ok := c.Data[key]; !ok || v == "" {
@@ -441,7 +454,7 @@ func (c *Connection) setURL() (err error) {
 				setIfMissing(k, v)
 			}
 		}
-		if g.In(c.Type, dbio.TypeFileSftp, dbio.TypeFileFtp) {
+		if g.In(c.Type, dbio.TypeFileSftp, dbio.TypeFileFtp, dbio.TypeFileImap) {
 			setIfMissing("user", U.Username())
 			setIfMissing("host", U.Hostname())
 			setIfMissing("password", U.Password())
@@ -495,10 +508,22 @@ func (c *Connection) setURL() (err error) {
 	case dbio.TypeDbPostgres:
 		setIfMissing("username", c.Data["user"])
 		setIfMissing("password", "")
+		if cast.ToBool(c.Data["tls_skip_verify"]) {
+			setIfMissing("sslmode", "require") // encrypted, not verified
+		} else if cast.ToString(c.Data["ssl_root_cert"]) != "" {
+			setIfMissing("sslmode", "verify-full")
+		}
 		setIfMissing("sslmode", "disable")
 		setIfMissing("port", c.Type.DefPort())
 		setIfMissing("database", c.Data["dbname"])
 		template = "postgresql://{username}:{password}@{host}:{port}/{database}?sslmode={sslmode}"
+
+		if rootCert := cast.ToString(c.Data["ssl_root_cert"]); rootCert != "" && !cast.ToBool(c.Data["tls_skip_verify"]) {
+			if c.Data["sslrootcert"], err = dbio.RootCertFile(rootCert); err != nil {
+				return g.Error(err, "could not prepare ssl_root_cert")
+			}
+			template = template + "&sslrootcert={sslrootcert}"
+		}
 	case dbio.TypeDbRedshift:
 		setIfMissing("username", c.Data["user"])
 		setIfMissing("password", "")
@@ -573,6 +598,9 @@ func (c *Connection) setURL() (err error) {
 		}
 
 		template = "prometheus://{host}"
+	case dbio.TypeApiHubSpot, dbio.TypeApiStripe, dbio.TypeApiShopify, dbio.TypeApiGA4, dbio.TypeApiGoogleAds,
+		dbio.TypeApiJira, dbio.TypeApiGitHub:
+		template = c.Type.String() + "://"
 	case dbio.TypeDbBigTable:
 		template = "bigtable://{project}/{instance}?"
 		if _, ok := c.Data["keyfile"]; ok {
@@ -685,7 +713,7 @@ func (c *Connection) setURL() (err error) {
 		setIfMissing("schema", c.Data["database"])
 		setIfMissing("port", c.Type.DefPort())
 		setIfMissing("secure", "false")
-		setIfMissing("skip_verify", "false")
+		setIfMissing("skip_verify", cast.ToBool(c.Data["tls_skip_verify"]))
 
 		// parse http url
 		if httpUrlStr, ok := c.Data["http_url"]; ok {
@@ -706,7 +734,7 @@ func (c *Connection) setURL() (err error) {
 		setIfMissing("schema", c.Data["database"])
 		setIfMissing("port", c.Type.DefPort())
 		setIfMissing("secure", "false")
-		setIfMissing("skip_verify", "false")
+		setIfMissing("skip_verify", cast.ToBool(c.Data["tls_skip_verify"]))
 
 		// parse http url
 		if httpUrlStr, ok := c.Data["http_url"]; ok {
@@ -720,7 +748,7 @@ func (c *Connection) setURL() (err error) {
 		}
 
 		template = "proton://{username}:{password}@{host}:{port}/{database}?secure={secure}&skip_verify={skip_verify}"
-	case dbio.TypeFileSftp, dbio.TypeFileFtp:
+	case dbio.TypeFileSftp, dbio.TypeFileFtp, dbio.TypeFileImap:
 		setIfMissing("password", "")
 		setIfMissing("port", c.Type.DefPort())
 		template = c.Type.String() + "://{user}:{password}@{host}:{port}/"
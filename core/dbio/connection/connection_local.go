@@ -272,6 +272,51 @@ func (ec *EnvFileConns) Set(name string, kvMap map[string]any) (err error) {
 	return
 }
 
+// Clone duplicates a connection (from the env file, env vars or dbt profiles)
+// into the env file under a new name, with the provided properties overridden
+func (ec *EnvFileConns) Clone(srcName, newName string, overrides map[string]any) (err error) {
+	if srcName == "" || newName == "" {
+		return g.Error("source and new connection names are required")
+	} else if _, ok := ec.EnvFile.Connections[newName]; ok {
+		return g.Error("connection `%s` already exists in %s", newName, ec.EnvFile.Path)
+	}
+
+	kvMap := map[string]any{}
+	if props, ok := ec.EnvFile.Connections[strings.ToUpper(srcName)]; ok {
+		for k, v := range props {
+			kvMap[k] = v
+		}
+	} else if conn := GetLocalConns(true).Get(srcName); conn.Name != "" {
+		for k, v := range conn.Connection.Data {
+			kvMap[k] = v
+		}
+	} else {
+		return g.Error("did not find connection `%s`", srcName)
+	}
+
+	// the url is used as-is when provided, so the overrides
+	// are applied to the properties parsed from it instead
+	if url := cast.ToString(kvMap["url"]); url != "" && len(overrides) > 0 {
+		conn, err := NewConnection(newName, "", kvMap)
+		if err != nil {
+			return g.Error(err, "could not parse connection `%s`", srcName)
+		}
+		kvMap = conn.Data
+		delete(kvMap, "url")
+	}
+
+	for k, v := range kvMap {
+		if v == "" {
+			delete(kvMap, k) // blank properties parsed from the url
+		}
+	}
+	for k, v := range overrides {
+		kvMap[strings.ToLower(k)] = v
+	}
+
+	return ec.Set(newName, kvMap)
+}
+
 func (ec *EnvFileConns) Unset(name string) (err error) {
 	if name == "" {
 		return g.Error("name is blank")
@@ -1,9 +1,15 @@
 package connection
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
 	"testing"
 
 	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/env"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -266,3 +272,107 @@ func TestQueryURL(t *testing.T) {
 	// println(url.QueryEscape(password))
 	_ = password
 }
+
+func TestConnectionTLS(t *testing.T) {
+	data := g.M("host", "localhost", "user", "user", "database", "db", "ssl_root_cert", "/path/to/ca.pem")
+	conn, err := NewConnection("pg", dbio.TypeDbPostgres, data)
+	if assert.NoError(t, err) {
+		assert.Contains(t, conn.URL(), "sslmode=verify-full")
+		assert.Contains(t, conn.URL(), "sslrootcert=/path/to/ca.pem")
+	}
+
+	data = g.M("host", "localhost", "user", "user", "database", "db", "tls_skip_verify", true)
+	conn, err = NewConnection("pg", dbio.TypeDbPostgres, data)
+	if assert.NoError(t, err) {
+		assert.Contains(t, conn.URL(), "sslmode=require")
+		assert.NotContains(t, conn.URL(), "sslrootcert")
+	}
+
+	data = g.M("host", "localhost", "database", "db", "tls_skip_verify", true)
+	conn, err = NewConnection("ch", dbio.TypeDbClickhouse, data)
+	if assert.NoError(t, err) {
+		assert.Contains(t, conn.URL(), "skip_verify=true")
+	}
+}
+
+func TestEnvFileConnsClone(t *testing.T) {
+	envPath := path.Join(t.TempDir(), "env.yaml")
+	ec := EnvFileConns{EnvFile: &env.EnvFile{
+		Path: envPath,
+		Connections: map[string]map[string]any{
+			"PG": {"type": "postgres", "host": "db.local", "user": "me", "password": "secret", "database": "prod"},
+		},
+	}}
+
+	err := ec.Clone("pg", "PG_DEV", map[string]any{"database": "dev", "Schema": "staging"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "dev", ec.EnvFile.Connections["PG_DEV"]["database"])
+	assert.Equal(t, "staging", ec.EnvFile.Connections["PG_DEV"]["schema"])
+	assert.Equal(t, "secret", ec.EnvFile.Connections["PG_DEV"]["password"])
+	assert.Equal(t, "prod", ec.EnvFile.Connections["PG"]["database"])
+
+	written, _ := os.ReadFile(envPath)
+	assert.Contains(t, string(written), "PG_DEV:")
+
+	// url: overrides apply to the parsed properties
+	t.Setenv("CLONE_TEST_URL", "postgresql://u:p@h1:5433/proddb?sslmode=disable")
+	if assert.NoError(t, ec.Clone("CLONE_TEST_URL", "URL_DEV", map[string]any{"database": "devdb"})) {
+		props := ec.EnvFile.Connections["URL_DEV"]
+		assert.Nil(t, props["url"])
+		assert.EqualValues(t, "postgres", props["type"])
+		assert.Equal(t, "h1", props["host"])
+		assert.Equal(t, "devdb", props["database"])
+	}
+
+	assert.Error(t, ec.Clone("PG", "PG_DEV", nil))        // already exists
+	assert.Error(t, ec.Clone("MISSING_CONN", "NEW", nil)) // not found
+}
+
+func TestResolveSecrets(t *testing.T) {
+	// vault KV v2, referenced without the data segment
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+		} else if r.URL.Path == "/v1/secret/data/sling/pg" {
+			w.Write([]byte(`{"data": {"data": {"user": "me", "password": "s3cret"}, "metadata": {"version": 1}}}`))
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	os.Setenv("VAULT_ADDR", server.URL)
+	os.Setenv("VAULT_TOKEN", "test-token")
+	defer os.Unsetenv("VAULT_ADDR")
+	defer os.Unsetenv("VAULT_TOKEN")
+
+	conn, err := NewConnection("PG", dbio.TypeDbPostgres, g.M(
+		"host", "db.local", "database", "prod",
+		"user", "vault:secret/sling/pg#user", "password", "vault:secret/sling/pg#password",
+	))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Empty(t, conn.URL()) // deferred until resolved
+
+	if assert.NoError(t, conn.ResolveSecrets()) {
+		assert.Equal(t, "s3cret", conn.Data["password"])
+		assert.Contains(t, conn.URL(), "me:s3cret@db.local")
+	}
+
+	// cached
+	requestsBefore := requests
+	val, err := ResolveSecret("vault:secret/sling/pg#user")
+	assert.NoError(t, err)
+	assert.Equal(t, "me", val)
+	assert.Equal(t, requestsBefore, requests)
+
+	_, err = ResolveSecret("vault:secret/sling/pg#missing")
+	assert.Error(t, err)
+	_, err = ResolveSecret("vault:secret/sling/other#key")
+	assert.Error(t, err)
+}
@@ -0,0 +1,206 @@
+package connection
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/flarco/g"
+	"github.com/spf13/cast"
+)
+
+// SecretProvider fetches a secret by path, returning its raw value
+// (a JSON object, when the secret has multiple keys)
+type SecretProvider func(path string) (value string, err error)
+
+// SecretProviders are the providers of the secret references in connection
+// properties, formatted as `<provider>:<path>#<key>` (e.g. `vault:secret/db#password`)
+var SecretProviders = map[string]SecretProvider{
+	"vault":  getVaultSecret,
+	"aws-sm": getAwsSecret,
+}
+
+var (
+	secretCache    = map[string]string{}
+	secretCacheMux sync.Mutex
+)
+
+// isSecretRef returns whether the value references a secret
+func isSecretRef(val any) bool {
+	s, ok := val.(string)
+	if !ok {
+		return false
+	}
+	provider, _, found := strings.Cut(s, ":")
+	_, known := SecretProviders[provider]
+	return found && known
+}
+
+// hasSecretRefs returns whether any of the properties references a secret
+func hasSecretRefs(data map[string]any) bool {
+	for _, v := range data {
+		if isSecretRef(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveSecret resolves a secret reference (`<provider>:<path>#<key>`).
+// Secrets are fetched once per process.
+func ResolveSecret(ref string) (value string, err error) {
+	providerName, secretPath, _ := strings.Cut(ref, ":")
+	provider, ok := SecretProviders[providerName]
+	if !ok {
+		return "", g.Error("unknown secret provider: %s", providerName)
+	}
+
+	key := ""
+	if i := strings.LastIndex(secretPath, "#"); i > -1 {
+		secretPath, key = secretPath[:i], secretPath[i+1:]
+	}
+
+	secretCacheMux.Lock()
+	defer secretCacheMux.Unlock()
+
+	cacheKey := providerName + ":" + secretPath
+	raw, cached := secretCache[cacheKey]
+	if !cached {
+		if raw, err = provider(secretPath); err != nil {
+			return "", g.Error(err, "could not get secret %s", cacheKey)
+		}
+		secretCache[cacheKey] = raw
+	}
+
+	if key == "" {
+		return raw, nil
+	}
+
+	secret, err := g.UnmarshalMap(raw)
+	if err != nil {
+		return "", g.Error(err, "secret %s is not a JSON object, cannot get key %s", cacheKey, key)
+	}
+
+	val, ok := secret[key]
+	if !ok {
+		return "", g.Error("key %s not found in secret %s", key, cacheKey)
+	}
+	return cast.ToString(val), nil
+}
+
+// ResolveSecrets replaces the secret references in the connection
+// properties with their values, and sets up the connection
+func (c *Connection) ResolveSecrets() (err error) {
+	if !hasSecretRefs(c.Data) {
+		return nil
+	}
+
+	for k, v := range c.Data {
+		if !isSecretRef(v) {
+			continue
+		}
+		if c.Data[k], err = ResolveSecret(cast.ToString(v)); err != nil {
+			return g.Error(err, "could not resolve property %s of connection %s", k, c.Name)
+		}
+	}
+
+	return c.setURL()
+}
+
+// getVaultSecret reads a secret from HashiCorp Vault (env vars `VAULT_ADDR`,
+// `VAULT_TOKEN` and `VAULT_NAMESPACE`). KV v2 paths can omit the `data/` segment.
+func getVaultSecret(secretPath string) (value string, err error) {
+	addr := strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/")
+	if addr == "" {
+		return "", g.Error("env var VAULT_ADDR is not set")
+	}
+
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		home, _ := os.UserHomeDir()
+		tokenBytes, _ := os.ReadFile(path.Join(home, ".vault-token"))
+		token = strings.TrimSpace(string(tokenBytes))
+	}
+
+	get := func(secretPath string) (status int, body map[string]any, err error) {
+		req, err := http.NewRequest(http.MethodGet, addr+"/v1/"+strings.TrimPrefix(secretPath, "/"), nil)
+		if err != nil {
+			return 0, nil, g.Error(err, "could not create vault request")
+		}
+		req.Header.Set("X-Vault-Token", token)
+		if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+			req.Header.Set("X-Vault-Namespace", namespace)
+		}
+
+		resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+		if err != nil {
+			return 0, nil, g.Error(err, "could not request vault")
+		}
+		defer resp.Body.Close()
+
+		payload, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, nil, nil
+		}
+		body, err = g.UnmarshalMap(string(payload))
+		if err != nil {
+			return resp.StatusCode, nil, g.Error(err, "could not parse vault response")
+		}
+		return resp.StatusCode, body, nil
+	}
+
+	status, body, err := get(secretPath)
+	if err == nil && status == http.StatusNotFound && !strings.Contains(secretPath, "/data/") {
+		// KV v2, without the data segment
+		if mount, rest, found := strings.Cut(secretPath, "/"); found {
+			status, body, err = get(mount + "/data/" + rest)
+		}
+	}
+	if err != nil {
+		return "", err
+	} else if status != http.StatusOK {
+		return "", g.Error("vault returned status %d for %s", status, secretPath)
+	}
+
+	data, _ := body["data"].(map[string]any)
+	if inner, ok := data["data"].(map[string]any); ok && data["metadata"] != nil {
+		data = inner // KV v2
+	}
+	return g.Marshal(data), nil
+}
+
+// getAwsSecret reads a secret from AWS Secrets Manager, by name or ARN,
+// with the default AWS credentials chain
+func getAwsSecret(name string) (value string, err error) {
+	config := aws.Config{}
+	if arnParts := strings.Split(name, ":"); len(arnParts) > 3 && arnParts[0] == "arn" {
+		config.Region = aws.String(arnParts[3])
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            config,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return "", g.Error(err, "could not create AWS session")
+	}
+
+	output, err := secretsmanager.New(sess).GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		return "", g.Error(err, "could not get secret value")
+	}
+
+	if output.SecretString != nil {
+		return *output.SecretString, nil
+	}
+	return string(output.SecretBinary), nil
+}
@@ -5,12 +5,18 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"io"
 	"math"
+	gonet "net"
 	"net/url"
 	"os"
 	"path"
+	"regexp"
 	"runtime/debug"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -83,6 +89,8 @@ type Connection interface {
 	GenerateDDL(table Table, data iop.Dataset, temporary bool) (string, error)
 	GenerateInsertStatement(tableName string, cols iop.Columns, numRows int) string
 	GenerateUpsertSQL(srcTable string, tgtTable string, pkFields []string) (sql string, err error)
+	GenerateSCD2SQL(srcTable string, tgtTable string, opts SCD2Options) (sql string, err error)
+	GeneratePartitionOverwriteSQL(srcTable string, tgtTable string, partitionCol string) (sql string, err error)
 	GetAnalysis(string, map[string]interface{}) (string, error)
 	GetColumns(tableFName string, fields ...string) (iop.Columns, error)
 	GetColumnsFull(string) (iop.Dataset, error)
@@ -138,6 +146,7 @@ type Connection interface {
 	Upsert(srcTable string, tgtTable string, pkFields []string) (rowAffCnt int64, err error)
 	ValidateColumnNames(tgtCols iop.Columns, colNames []string, quote bool) (newCols iop.Columns, err error)
 	AddMissingColumns(table Table, newCols iop.Columns) (ok bool, err error)
+	SetColumnTags(table Table, columnTags map[string][]string) (err error)
 }
 
 type ConnInfo struct {
@@ -155,19 +164,21 @@ type ConnInfo struct {
 // BaseConn is a database connection
 type BaseConn struct {
 	Connection
-	URL         string
-	Type        dbio.Type // the type of database for sqlx: postgres, mysql, sqlite
-	db          *sqlx.DB
-	tx          Transaction
-	Data        iop.Dataset
-	defaultPort int
-	instance    *Connection
-	context     *g.Context
-	template    dbio.Template
-	schemata    Schemata
-	properties  map[string]string
-	sshClient   *iop.SSHClient
-	Log         []string
+	URL            string
+	Type           dbio.Type // the type of database for sqlx: postgres, mysql, sqlite
+	db             *sqlx.DB
+	tx             Transaction
+	Data           iop.Dataset
+	defaultPort    int
+	instance       *Connection
+	context        *g.Context
+	template       dbio.Template
+	schemata       Schemata
+	properties     map[string]string
+	sshClient      *iop.SSHClient
+	proxyListener  gonet.Listener
+	plannedColumns map[string]iop.Columns
+	Log            []string
 }
 
 // Pool is a pool of connections
@@ -274,6 +285,11 @@ func NewConnContext(ctx context.Context, URL string, props ...string) (Connectio
 		conn = &ElasticsearchConn{URL: URL}
 	} else if strings.HasPrefix(URL, "prometheus") {
 		conn = &PrometheusConn{URL: URL}
+	} else if strings.HasPrefix(URL, "hubspot:") || strings.HasPrefix(URL, "stripe:") || strings.HasPrefix(URL, "shopify:") ||
+		strings.HasPrefix(URL, "jira:") || strings.HasPrefix(URL, "github:") {
+		conn = &APIConn{URL: URL}
+	} else if strings.HasPrefix(URL, "ga4:") || strings.HasPrefix(URL, "googleads:") {
+		conn = &GoogleReportConn{URL: URL}
 	} else if strings.HasPrefix(URL, "mariadb:") {
 		conn = &MySQLConn{URL: URL}
 	} else if strings.HasPrefix(URL, "oracle:") {
@@ -435,6 +451,12 @@ func (conn *BaseConn) Init() (err error) {
 		return err
 	}
 
+	for _, key := range []string{"conn_max_lifetime", "statement_timeout"} {
+		if _, err = conn.propDuration(key); err != nil {
+			return err
+		}
+	}
+
 	conn.schemata = Schemata{
 		Databases: map[string]Database{},
 	}
@@ -608,7 +630,16 @@ func (conn *BaseConn) Connect(timeOut ...int) (err error) {
 			)
 		}
 
-		localPort, err := iop.OpenTunnelSSH(connHost, connPort, sshURL, conn.GetProp("SSH_PRIVATE_KEY"), conn.GetProp("SSH_PASSPHRASE"))
+		if conn.sshClient != nil {
+			conn.sshClient.Close()
+		}
+		conn.sshClient, err = iop.NewSSHTunnel(sshURL, conn.GetProp("SSH_PRIVATE_KEY"), conn.GetProp("SSH_PASSPHRASE"))
+		if err != nil {
+			return g.Error(err, "could not parse ssh tunnel")
+		}
+		conn.sshClient.TgtHost, conn.sshClient.TgtPort = connHost, connPort
+
+		localPort, err := conn.sshClient.OpenPortForward()
 		if err != nil {
 			return g.Error(err, "could not connect to ssh tunnel server")
 		}
@@ -621,6 +652,37 @@ func (conn *BaseConn) Connect(timeOut ...int) (err error) {
 		conn.SetProp("ssh_url", connURL) // set ssh url for 3rd party bulk loading
 	}
 
+	// forward connections through the proxy prop (SOCKS5 or HTTP CONNECT).
+	// Snowflake connects over HTTPS, and honors the HTTPS_PROXY env var.
+	if proxyURL, err := dbio.ProxyURL(conn.GetProp("proxy")); err != nil {
+		return g.Error(err, "could not make proxy config")
+	} else if proxyURL != nil && conn.GetProp("SSH_TUNNEL") == "" && conn.Type != dbio.TypeDbSnowflake {
+		connU, err := url.Parse(connURL)
+		if err != nil {
+			return g.Error(err, "could not parse connection URL for proxy forwarding")
+		}
+
+		if connHost := connU.Hostname(); connHost != "" {
+			connPort := cast.ToInt(connU.Port())
+			if connPort == 0 {
+				connPort = conn.defaultPort
+			}
+
+			if conn.proxyListener != nil {
+				conn.proxyListener.Close()
+			}
+
+			localPort, listener, err := dbio.ProxyForward(proxyURL, gonet.JoinHostPort(connHost, cast.ToString(connPort)))
+			if err != nil {
+				return g.Error(err, "could not connect through proxy")
+			}
+			conn.proxyListener = listener
+
+			connURL = strings.Replace(connURL, connU.Host, g.F("127.0.0.1:%d", localPort), 1)
+			g.Trace("new connection URL: " + conn.Self().GetURL(connURL))
+		}
+	}
+
 	if conn.db == nil {
 		connURL = conn.Self().GetURL(connURL)
 		connPool.Mux.Lock()
@@ -629,7 +691,14 @@ func (conn *BaseConn) Connect(timeOut ...int) (err error) {
 		g.Trace("connURL -> %s", connURL)
 
 		if !usePool || !poolOk {
-			db, err = sqlx.Open(getDriverName(conn.Type), connURL)
+			if tokens := conn.OAuthTokenSource(); tokens != nil {
+				var sqlDB *sql.DB
+				if sqlDB, err = newTokenDB(getDriverName(conn.Type), connURL, tokens); err == nil {
+					db = sqlx.NewDb(sqlDB, getDriverName(conn.Type))
+				}
+			} else {
+				db, err = sqlx.Open(getDriverName(conn.Type), connURL)
+			}
 			if err != nil {
 				return g.Error(err, "Could not connect to DB: "+getDriverName(conn.Type))
 			}
@@ -638,6 +707,7 @@ func (conn *BaseConn) Connect(timeOut ...int) (err error) {
 		}
 
 		conn.db = db
+		conn.setPoolSettings()
 
 	retry:
 		tryNum++
@@ -698,6 +768,154 @@ func (conn *BaseConn) Connect(timeOut ...int) (err error) {
 	return nil
 }
 
+// propDuration returns a duration prop, in seconds (`300`) or as a duration (`5m`)
+func (conn *BaseConn) propDuration(key string) (time.Duration, error) {
+	val := strings.TrimSpace(conn.GetProp(key))
+	if val == "" {
+		return 0, nil
+	} else if seconds, err := cast.ToIntE(val); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	duration, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, g.Error("invalid %s '%s', expected seconds or a duration (e.g. 5m)", key, val)
+	}
+	return duration, nil
+}
+
+// setPoolSettings applies the `max_open_conns`, `max_idle_conns` and `conn_max_lifetime` props
+func (conn *BaseConn) setPoolSettings() {
+	if conn.db == nil {
+		return
+	}
+
+	if val := cast.ToInt(conn.GetProp("max_open_conns")); val > 0 {
+		conn.db.SetMaxOpenConns(val)
+	}
+	if val := conn.GetProp("max_idle_conns"); val != "" {
+		conn.db.SetMaxIdleConns(cast.ToInt(val))
+	}
+	if lifetime, _ := conn.propDuration("conn_max_lifetime"); lifetime > 0 {
+		conn.db.SetConnMaxLifetime(lifetime)
+	}
+}
+
+// statementTimeout returns the `statement_timeout` prop, 0 if not set
+func (conn *BaseConn) statementTimeout() time.Duration {
+	timeout, _ := conn.propDuration("statement_timeout")
+	return timeout
+}
+
+// statementTimer cancels the query context if the query does not return its first rows
+// within the statement_timeout (e.g. waiting on a lock). The returned func stops the
+// timer, once the first rows are read, and wraps the error if the timeout was exceeded.
+func (conn *BaseConn) statementTimer(queryContext *g.Context) (stop func(err error) error) {
+	timeout := conn.statementTimeout()
+	if timeout <= 0 {
+		return func(err error) error { return err }
+	}
+
+	timer := time.AfterFunc(timeout, queryContext.Cancel)
+	return func(err error) error {
+		if timer.Stop() {
+			return err
+		}
+		return g.Error("statement_timeout of %s exceeded, the query was canceled", timeout)
+	}
+}
+
+// timeoutErr replaces the error of a statement canceled by the statement_timeout
+// (the driver only returns a context error)
+func (conn *BaseConn) timeoutErr(ctx context.Context, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return g.Error("statement_timeout of %s exceeded, the statement was canceled", conn.statementTimeout())
+	}
+	return err
+}
+
+// droppedConnErrors are the errors of a connection dropped by the server or the network
+var droppedConnErrors = []string{
+	"bad connection",
+	"broken pipe",
+	"connection reset by peer",
+	"server closed the connection unexpectedly",
+	"use of closed network connection",
+	"unexpected eof",
+	"invalid connection",
+}
+
+var (
+	writeCTERegex    = regexp.MustCompile(`\b(insert|update|delete|merge)\b`)
+	ifExistsDDLRegex = regexp.MustCompile(`\bif\s+(not\s+)?exists\b`)
+)
+
+// isDroppedConnErr returns true if the error is from a dropped connection
+func isDroppedConnErr(err error) bool {
+	if err == nil {
+		return false
+	} else if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	if msg == "eof" || strings.HasSuffix(msg, "\neof") {
+		return true
+	}
+	for _, text := range droppedConnErrors {
+		if strings.Contains(msg, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// idempotentSQL returns true if the statement can be safely executed again
+// (reads, and DDL with `if exists` / `if not exists`)
+func idempotentSQL(q string) bool {
+	q = strings.ToLower(strings.TrimLeft(q, " \t\r\n("))
+	switch strings.SplitN(strings.Join(strings.Fields(q), " "), " ", 2)[0] {
+	case "select", "show", "describe", "desc", "explain", "values", "pragma":
+		return true
+	case "with":
+		return !writeCTERegex.MatchString(q)
+	case "create", "drop":
+		return ifExistsDDLRegex.MatchString(q)
+	}
+	return false
+}
+
+// retryDropped waits and reconnects if the statement failed because the connection
+// was dropped, and returns true if it should be retried. Statements in a transaction
+// are not retried, since the transaction is lost with the connection.
+// The number of attempts is set with the `reconnect_attempts` prop (default 3, 0 to disable).
+func (conn *BaseConn) retryDropped(ctx context.Context, q string, err error, attempt int) bool {
+	attempts := 3
+	if val := conn.GetProp("reconnect_attempts"); val != "" {
+		attempts = cast.ToInt(val)
+	}
+
+	if attempt > attempts || conn.tx != nil || ctx.Err() != nil || !isDroppedConnErr(err) || !idempotentSQL(q) {
+		return false
+	} else if conn.Type == dbio.TypeDbClickhouse && err.Error() == "EOF" {
+		return false // query without result
+	}
+
+	g.Warn("connection dropped (%s), reconnecting to retry statement (attempt %d of %d)", err.Error(), attempt, attempts)
+	time.Sleep(time.Duration(attempt) * time.Second)
+
+	// the pool replaces bad connections, reconnect fully if it cannot
+	if conn.db != nil && conn.db.PingContext(ctx) == nil {
+		return true
+	}
+
+	conn.Self().Close()
+	if err = conn.Self().Connect(); err != nil {
+		g.Warn("could not reconnect: %s", err.Error())
+	}
+	return true
+}
+
 func reconnectIfClosed(conn Connection) (err error) {
 	// g.Warn("connected => %s", conn.GetProp("connected"))
 	if conn.GetProp("connected") != "true" {
@@ -727,6 +945,10 @@ func (conn *BaseConn) Close() error {
 		conn.sshClient.Close()
 		conn.sshClient = nil
 	}
+	if conn.proxyListener != nil {
+		conn.proxyListener.Close()
+		conn.proxyListener = nil
+	}
 	conn.SetProp("connected", "false")
 	return err
 }
@@ -744,6 +966,17 @@ func (conn *BaseConn) LogSQL(query string, args ...any) {
 	env.LogSQL(conn.Props(), query, args...)
 }
 
+// logSQLResult logs the executed query to the sql log file, if enabled
+func (conn *BaseConn) logSQLResult(query string, args []any, start time.Time, result sql.Result, err error) {
+	var rowsAffected *int64
+	if result != nil && err == nil {
+		if ra, raErr := result.RowsAffected(); raErr == nil {
+			rowsAffected = &ra
+		}
+	}
+	env.LogSQLToFile(conn.Props(), query, args, time.Since(start), rowsAffected, err)
+}
+
 // GetGormConn returns the gorm db connection
 func (conn *BaseConn) GetGormConn(config *gorm.Config) (*gorm.DB, error) {
 	return gorm.Open(getDialector(conn), config)
@@ -797,16 +1030,6 @@ func (conn *BaseConn) StreamRowsContext(ctx context.Context, query string, optio
 
 	opts := getQueryOptions(options)
 
-	Limit := uint64(0) // infinite
-	if val := cast.ToUint64(opts["limit"]); val > 0 {
-		Limit = val
-	}
-
-	fetchedColumns := iop.Columns{}
-	if val, ok := opts["columns"].(iop.Columns); ok {
-		fetchedColumns = val
-	}
-
 	start := time.Now()
 	if strings.TrimSpace(query) == "" {
 		return ds, g.Error("Empty Query")
@@ -815,17 +1038,27 @@ func (conn *BaseConn) StreamRowsContext(ctx context.Context, query string, optio
 	queryContext := g.NewContext(ctx)
 
 	conn.LogSQL(query)
+
+	stopTimer := conn.statementTimer(queryContext)
+
 	var result *sqlx.Rows
 	if conn.tx != nil {
 		result, err = conn.tx.QueryContext(queryContext.Ctx, query)
 	} else {
-		result, err = conn.db.QueryxContext(queryContext.Ctx, query)
+		for attempt := 1; ; attempt++ {
+			result, err = conn.db.QueryxContext(queryContext.Ctx, query)
+			conn.logSQLResult(query, nil, start, nil, err)
+			if !conn.retryDropped(queryContext.Ctx, query, err, attempt) || conn.db == nil {
+				break
+			}
+		}
 	}
 
 	if err != nil && err.Error() == "EOF" {
 		// for clickhouse
 		err = nil
 	} else if err != nil {
+		err = stopTimer(err)
 		queryContext.Cancel()
 		if strings.Contains(query, noDebugKey) && !g.IsDebugLow() {
 			return ds, g.Error(err, "SQL Error")
@@ -833,6 +1066,24 @@ func (conn *BaseConn) StreamRowsContext(ctx context.Context, query string, optio
 		return ds, g.Error(err, "SQL Error for:\n"+query)
 	}
 
+	ds, err = conn.streamResult(queryContext, query, result, opts, start, nil)
+	return ds, stopTimer(err)
+}
+
+// streamResult streams the rows of a query result. nextResult is optional, it is called
+// when the rows of a result are exhausted, with their count, and returns the next result
+// of the query (e.g. the next fetch of a cursor), or nil when done.
+func (conn *BaseConn) streamResult(queryContext *g.Context, query string, result *sqlx.Rows, opts map[string]interface{}, start time.Time, nextResult func(count uint64) (*sqlx.Rows, error)) (ds *iop.Datastream, err error) {
+	Limit := uint64(0) // infinite
+	if val := cast.ToUint64(opts["limit"]); val > 0 {
+		Limit = val
+	}
+
+	fetchedColumns := iop.Columns{}
+	if val, ok := opts["columns"].(iop.Columns); ok {
+		fetchedColumns = val
+	}
+
 	var colTypes []ColumnType
 	if result != nil {
 		dbColTypes, err := getColumnTypes(result)
@@ -886,7 +1137,9 @@ func (conn *BaseConn) StreamRowsContext(ctx context.Context, query string, optio
 
 	g.Trace("query responded in %f secs", conn.Data.Duration)
 
-	nextFunc := func(it *iop.Iterator) bool {
+	var resultCount uint64
+	var nextFunc func(it *iop.Iterator) bool
+	nextFunc = func(it *iop.Iterator) bool {
 		if result == nil {
 			return false
 		} else if err = result.Err(); err != nil {
@@ -907,6 +1160,7 @@ func (conn *BaseConn) StreamRowsContext(ctx context.Context, query string, optio
 			if err != nil {
 				it.Context.CaptureErr(g.Error(err, "failed to scan"))
 			} else {
+				resultCount++
 				return true
 			}
 		}
@@ -917,6 +1171,14 @@ func (conn *BaseConn) StreamRowsContext(ctx context.Context, query string, optio
 		} else if err = result.Err(); err != nil {
 			// Double check result.Err() in case error occurred between previous check
 			it.Context.CaptureErr(g.Error(err, "error after row iteration"))
+		} else if !next && nextResult != nil {
+			result.Close()
+			if result, err = nextResult(resultCount); err != nil {
+				it.Context.CaptureErr(g.Error(err, "could not get next result"))
+				return false
+			}
+			resultCount = 0
+			return nextFunc(it)
 		}
 
 		result.Close()
@@ -1134,16 +1396,30 @@ func (conn *BaseConn) ExecContext(ctx context.Context, q string, args ...interfa
 		return
 	}
 
+	if timeout := conn.statementTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
 	if conn.tx != nil {
 		result, err = conn.tx.ExecContext(ctx, q, args...)
 		q = q + noDebugKey // just to not show twice the sql in error since tx does
 	} else if conn.db != nil {
 		conn.LogSQL(q, args...)
-		result, err = conn.db.ExecContext(ctx, q, args...)
+		for attempt := 1; ; attempt++ {
+			start := time.Now()
+			result, err = conn.db.ExecContext(ctx, q, args...)
+			conn.logSQLResult(q, args, start, result, err)
+			if !conn.retryDropped(ctx, q, err, attempt) || conn.db == nil {
+				break
+			}
+		}
 	} else {
 		err = g.Error("no connection instance")
 	}
 	if err != nil {
+		err = conn.timeoutErr(ctx, err)
 		if strings.Contains(q, noDebugKey) {
 			err = g.Error(err, "Error executing query [tx: %t]", conn.tx != nil)
 		} else {
@@ -1588,9 +1864,28 @@ func (conn *BaseConn) GetColumns(tableFName string, fields ...string) (columns i
 		return columns, g.Error(err, "could not parse table name: "+tableFName)
 	}
 
+	if columns, ok := conn.plannedColumns[strings.ToLower(table.FullName())]; ok {
+		return columns, nil
+	}
+
 	return conn.Self().GetTableColumns(&table, fields...)
 }
 
+// PlanColumns sets the columns of a table which is not created yet, returned by
+// GetColumns, to generate the SQL using it without executing (dry-run)
+func (conn *BaseConn) PlanColumns(tableFName string, columns iop.Columns) error {
+	table, err := ParseTableName(tableFName, conn.Type)
+	if err != nil {
+		return g.Error(err, "could not parse table name: "+tableFName)
+	}
+
+	if conn.plannedColumns == nil {
+		conn.plannedColumns = map[string]iop.Columns{}
+	}
+	conn.plannedColumns[strings.ToLower(table.FullName())] = columns
+	return nil
+}
+
 // GetColumnsFull returns columns for given table. `tableName` should
 // include schema and table, example: `schema1.table2`
 // fields should be `schema_name|table_name|table_type|column_name|data_type|column_id`
@@ -1882,6 +2177,11 @@ func (conn *BaseConn) GetSchemata(level SchemataLevel, schemaName string, tableN
 				DbType:   dataType,
 			}
 
+			// not all templates provide the nullability
+			if val := rec["is_nullable"]; val != nil {
+				column.SetMetadata("nullable", cast.ToString(cast.ToBool(data.Sp.ProcessVal(val))))
+			}
+
 			table.Columns = append(table.Columns, column)
 		}
 
@@ -2648,33 +2948,73 @@ func (conn *BaseConn) GetColumnStats(tableName string, fields ...string) (column
 
 }
 
-// GetOptimizeTableStatements analyzes the table and alters the table with
-// the columns data type based on its analysis result
-// if table is missing, it is created with a new DDl
-// Hole in this: will truncate data points, since it is based
-// only on new data being inserted... would need a complete
-// stats of the target table to properly optimize.
-func GetOptimizeTableStatements(conn Connection, table *Table, newColumns iop.Columns, isTemp bool) (ok bool, ddlParts []string, err error) {
-	if missing := table.Columns.GetMissing(newColumns...); len(missing) > 0 {
-		return false, ddlParts, g.Error("missing columns: %#v\ntable.Columns: %#v\nnewColumns: %#v", missing.Names(), table.Columns.Names(), newColumns.Names())
-	} else if g.In(conn.GetType(), dbio.TypeDbSQLite, dbio.TypeDbD1) {
-		return false, ddlParts, nil
+// declaredPrecisionChanged returns true if the new column was declared with a
+// length / precision / scale that differs from the known one of the existing column
+func declaredPrecisionChanged(col, newCol iop.Column) bool {
+	if !newCol.IsDeclared() || newCol.DbPrecision == 0 || col.DbPrecision == 0 {
+		return false
+	}
+	return col.DbPrecision != newCol.DbPrecision || (newCol.IsDecimal() && col.DbScale != newCol.DbScale)
+}
+
+// isTypeNarrowing returns true if changing the column type could truncate
+// or fail to cast the existing values (e.g. decimal to integer)
+func isTypeNarrowing(col, newCol iop.Column) bool {
+	switch {
+	case col.IsString() && !newCol.IsString():
+		return true
+	case col.Type == iop.TextType && newCol.Type != iop.TextType:
+		return true
+	case (col.IsDecimal() || col.IsFloat()) && newCol.IsInteger():
+		return true
+	case col.Type == iop.BigIntType && g.In(newCol.Type, iop.IntegerType, iop.SmallIntType):
+		return true
+	case col.Type == iop.IntegerType && newCol.Type == iop.SmallIntType:
+		return true
+	case col.IsDatetime() && !newCol.IsDatetime() && !newCol.IsString():
+		return true
+	case col.IsNumber() && !newCol.IsNumber() && !newCol.IsString():
+		return true
+	}
+
+	// smaller length / precision / scale, for the same kind of type
+	if col.Type == newCol.Type && col.DbPrecision > 0 && newCol.DbPrecision > 0 {
+		return newCol.DbPrecision < col.DbPrecision || (newCol.IsDecimal() && newCol.DbScale < col.DbScale)
+	}
+
+	return false
+}
+
+// ColumnTypeChange is a type change of a table column, to fit the new data
+type ColumnTypeChange struct {
+	Index     int        // the position of the column in the table columns
+	Column    iop.Column // the column, with the new type and native type
+	OldColumn iop.Column
+	Narrowing bool // whether the existing values could be truncated
+}
+
+// GetColumnTypeChanges returns the type changes needed for the table columns
+// to fit the new columns. Temp tables have the complete stats (all rows were streamed).
+func GetColumnTypeChanges(conn Connection, table Table, newColumns iop.Columns, isTemp bool) (changes []ColumnTypeChange, err error) {
+	if g.In(conn.GetType(), dbio.TypeDbSQLite, dbio.TypeDbD1) {
+		return nil, nil
 	}
 
 	newColumnsMap := lo.KeyBy(newColumns, func(c iop.Column) string {
 		return strings.ToLower(c.Name)
 	})
 
-	var oldCols, colsChanging iop.Columns
 	for i, col := range table.Columns {
 		newCol, ok := newColumnsMap[strings.ToLower(col.Name)]
 		if !ok {
 			continue
-		} else if col.Type == newCol.Type {
+		} else if col.Type == newCol.Type && !declaredPrecisionChanged(col, newCol) {
 			continue
 		}
 		msg := g.F("optimizing existing '%s' (%s) vs new '%s' (%s) => ", col.Name, col.Type, newCol.Name, newCol.Type)
 		switch {
+		case newCol.IsDeclared():
+			// use declared type, with its length / precision / scale
 		case col.Type.IsDecimal() && newCol.Type.IsDecimal():
 			continue
 		case col.Type.IsDatetime() && newCol.Type.IsDatetime():
@@ -2703,30 +3043,71 @@ func GetOptimizeTableStatements(conn Connection, table *Table, newColumns iop.Co
 			newCol.Type = iop.StringType
 		}
 
-		if col.Type == newCol.Type {
+		if col.Type == newCol.Type && !declaredPrecisionChanged(col, newCol) {
 			continue
 		}
 
-		g.Debug(msg + string(newCol.Type))
-
 		oldNativeType, err := conn.GetNativeType(col)
 		if err != nil {
-			return false, ddlParts, g.Error(err, "no native mapping for `%s`", newCol.Type)
+			return nil, g.Error(err, "no native mapping for `%s`", newCol.Type)
 		}
 
 		newNativeType, err := conn.GetNativeType(newCol)
 		if err != nil {
-			return false, ddlParts, g.Error(err, "no native mapping for `%s`", newCol.Type)
+			return nil, g.Error(err, "no native mapping for `%s`", newCol.Type)
 		}
 
 		if oldNativeType == newNativeType {
 			continue
 		}
 
-		table.Columns[i].Type = newCol.Type
-		table.Columns[i].DbType = newNativeType
-		colsChanging = append(colsChanging, table.Columns[i])
-		oldCols = append(oldCols, col)
+		g.Debug(msg + string(newCol.Type))
+
+		changed := col
+		changed.Type = newCol.Type
+		changed.DbType = newNativeType
+		changes = append(changes, ColumnTypeChange{
+			Index:     i,
+			Column:    changed,
+			OldColumn: col,
+			Narrowing: isTypeNarrowing(col, newCol),
+		})
+	}
+
+	return changes, nil
+}
+
+// GetOptimizeTableStatements analyzes the table and alters the table with
+// the columns data type based on its analysis result
+// if table is missing, it is created with a new DDl
+// Narrowing a type is based only on the new data being inserted, so it is
+// refused on final tables, unless the `allow_type_narrowing` prop is set.
+// Temp tables have the complete stats (all rows were streamed).
+func GetOptimizeTableStatements(conn Connection, table *Table, newColumns iop.Columns, isTemp bool) (ok bool, ddlParts []string, err error) {
+	if missing := table.Columns.GetMissing(newColumns...); len(missing) > 0 {
+		return false, ddlParts, g.Error("missing columns: %#v\ntable.Columns: %#v\nnewColumns: %#v", missing.Names(), table.Columns.Names(), newColumns.Names())
+	}
+
+	changes, err := GetColumnTypeChanges(conn, *table, newColumns, isTemp)
+	if err != nil {
+		return false, ddlParts, err
+	}
+
+	allowNarrowing := cast.ToBool(conn.GetProp("allow_type_narrowing"))
+
+	var oldCols, colsChanging iop.Columns
+	for _, change := range changes {
+		if !isTemp && !allowNarrowing && change.Narrowing {
+			g.Warn(
+				"not narrowing column %s of %s from %s to %s, since existing values could be truncated. Set target option `allow_type_narrowing` to allow.",
+				change.OldColumn.Name, table.FullName(), change.OldColumn.Type, change.Column.Type,
+			)
+			continue
+		}
+
+		table.Columns[change.Index] = change.Column
+		colsChanging = append(colsChanging, change.Column)
+		oldCols = append(oldCols, change.OldColumn)
 	}
 
 	if len(colsChanging) == 0 {
@@ -2844,6 +3225,7 @@ func (conn *BaseConn) OptimizeTable(table *Table, newColumns iop.Columns, isTemp
 	if len(isTemp) > 0 {
 		IsTemp = isTemp[0]
 	}
+	oldColumns := table.Columns.Clone()
 	ok, ddlParts, err := GetOptimizeTableStatements(conn, table, newColumns, IsTemp)
 	if err != nil {
 		return ok, err
@@ -2854,9 +3236,93 @@ func (conn *BaseConn) OptimizeTable(table *Table, newColumns iop.Columns, isTemp
 		return false, g.Error(err, "could not alter columns on table "+table.FullName())
 	}
 
+	logColumnTypeChanges(*table, oldColumns)
+
 	return ok, nil
 }
 
+// logColumnTypeChanges logs the column types altered on the table, for auditing
+func logColumnTypeChanges(table Table, oldColumns iop.Columns) {
+	for _, col := range table.Columns {
+		oldCol := oldColumns.GetColumn(col.Name)
+		if oldCol == nil || (oldCol.Type == col.Type && oldCol.DbType == col.DbType) {
+			continue
+		}
+		g.Info("altered column %s of %s from %s (%s) to %s (%s)", col.Name, table.FullName(), oldCol.DbType, oldCol.Type, col.DbType, col.Type)
+	}
+}
+
+// SetColumnTags applies the classification tags (e.g. pii, sensitive) to the
+// table columns, using the `create_tag` and `set_column_tag` templates.
+// A tag can be specified as `name` or `name=value` (value defaults to `true`).
+func (conn *BaseConn) SetColumnTags(table Table, columnTags map[string][]string) (err error) {
+	if len(columnTags) == 0 {
+		return nil
+	}
+
+	if conn.GetTemplateValue("core.set_column_tag") == "" {
+		g.Warn("setting column tags is not supported for %s", conn.GetType())
+		return nil
+	}
+
+	for _, sql := range conn.columnTagsSQL(table, columnTags) {
+		if _, err = conn.Exec(sql); err != nil {
+			return g.Error(err, "could not set column tags on %s", table.FullName())
+		}
+	}
+
+	return nil
+}
+
+// columnTagsSQL returns the statements creating the tags (if needed), then
+// setting them on the columns, in column name order
+func (conn *BaseConn) columnTagsSQL(table Table, columnTags map[string][]string) (sqls []string) {
+	tagName := func(tag string) string {
+		return conn.Quote(table.Schema) + "." + conn.Quote(tag)
+	}
+
+	columns := lo.Keys(columnTags)
+	sort.Strings(columns)
+
+	// create tags if needed
+	created := map[string]bool{}
+	createTagTemplate := conn.GetTemplateValue("core.create_tag")
+	for _, column := range columns {
+		for _, tag := range columnTags[column] {
+			name, _ := ParseColumnTag(tag)
+			if createTagTemplate == "" || created[name] {
+				continue
+			}
+			sqls = append(sqls, g.R(createTagTemplate, "tag", tagName(name)))
+			created[name] = true
+		}
+	}
+
+	for _, column := range columns {
+		for _, tag := range columnTags[column] {
+			name, value := ParseColumnTag(tag)
+			sqls = append(sqls, g.R(
+				conn.GetTemplateValue("core.set_column_tag"),
+				"table", table.FullName(),
+				"column", conn.Quote(column),
+				"tag", tagName(name),
+				"value", strings.ReplaceAll(value, "'", "''"),
+			))
+		}
+	}
+
+	return sqls
+}
+
+// ParseColumnTag splits a `name=value` column tag
+func ParseColumnTag(tag string) (name, value string) {
+	name, value, found := strings.Cut(strings.TrimSpace(tag), "=")
+	if !found {
+		value = "true"
+	}
+	return strings.TrimSpace(name), strings.TrimSpace(value)
+}
+
 // CompareChecksums compares the checksum values from the database side
 // to the checkum values from the StreamProcessor
 func (conn *BaseConn) CompareChecksums(tableName string, columns iop.Columns) (err error) {
@@ -3085,6 +3551,20 @@ func (conn *BaseConn) makeTlsConfig() (tlsConfig *tls.Config, err error) {
 		}
 	}
 
+	// custom CA bundle and verification toggle
+	customTLS, err := dbio.TLSConfig(conn.GetProp("ssl_root_cert"), cast.ToBool(conn.GetProp("tls_skip_verify")))
+	if err != nil {
+		return nil, g.Error(err, "Failed to load ssl_root_cert")
+	} else if customTLS != nil {
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		tlsConfig.InsecureSkipVerify = customTLS.InsecureSkipVerify
+		if customTLS.RootCAs != nil {
+			tlsConfig.RootCAs = customTLS.RootCAs
+		}
+	}
+
 	return
 }
 
@@ -3108,31 +3588,23 @@ func settingMppBulkImportFlow(conn Connection, compressor iop.CompressorType) {
 }
 
 func (conn *BaseConn) AddMissingColumns(table Table, newCols iop.Columns) (ok bool, err error) {
-	cols, err := conn.GetColumns(table.FullName())
+	table.Columns, err = conn.GetColumns(table.FullName())
 	if err != nil {
 		err = g.Error(err, "could not obtain table columns for adding %s", g.Marshal(newCols.Names()))
 		return
 	}
 
-	missing := cols.GetMissing(newCols...)
-
-	// generate alter commands
-	for _, col := range missing {
-		nativeType, err := conn.GetNativeType(col)
-		if err != nil {
-			return false, g.Error(err, "no native mapping")
-		}
-		sql := g.R(
-			conn.Template().Core["add_column"],
-			"table", table.FullName(),
-			"column", conn.Self().Quote(col.Name),
-			"type", nativeType,
-		)
+	missing, ddlParts, err := GetAddColumnsStatements(conn, table, newCols)
+	if err != nil {
+		return false, err
+	}
 
-		g.Debug("adding new column: %s", col.Name)
+	// execute alter commands
+	for i, sql := range ddlParts {
+		g.Debug("adding new column: %s", missing[i].Name)
 		_, err = conn.Exec(sql)
 		if err != nil {
-			return false, g.Error(err, "could not add column %s to table %s", col.Name, table.FullName())
+			return false, g.Error(err, "could not add column %s to table %s", missing[i].Name, table.FullName())
 		}
 
 		if g.In(conn.GetType(), dbio.TypeDbBigQuery) {
@@ -3144,6 +3616,28 @@ func (conn *BaseConn) AddMissingColumns(table Table, newCols iop.Columns) (ok bo
 	return len(missing) > 0, nil
 }
 
+// GetAddColumnsStatements returns the new columns missing in the table columns,
+// with the statements adding them
+func GetAddColumnsStatements(conn Connection, table Table, newCols iop.Columns) (missing iop.Columns, ddlParts []string, err error) {
+	missing = table.Columns.GetMissing(newCols...)
+
+	// generate alter commands
+	for _, col := range missing {
+		nativeType, err := conn.GetNativeType(col)
+		if err != nil {
+			return nil, nil, g.Error(err, "no native mapping")
+		}
+		ddlParts = append(ddlParts, g.R(
+			conn.Template().Core["add_column"],
+			"table", table.FullName(),
+			"column", conn.Self().Quote(col.Name),
+			"type", nativeType,
+		))
+	}
+
+	return missing, ddlParts, nil
+}
+
 // TestPermissions tests the needed permissions in a given connection
 func TestPermissions(conn Connection, tableName string) (err error) {
 
@@ -0,0 +1,765 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+)
+
+// APIConn is a SaaS API connection (HubSpot, Stripe, Shopify, Jira or GitHub).
+// The objects of the API are read as tables, page by page with the API cursor
+type APIConn struct {
+	BaseConn
+	URL    string
+	client *http.Client
+}
+
+// apiSpec describes how to read the objects of an API
+type apiSpec struct {
+	objects      []string                   // listed in discovery, other objects can be read as well
+	updateKey    func(object string) string // the default incremental key, filtered server-side
+	baseURL      func(conn *APIConn) string
+	path         func(conn *APIConn, object string) string
+	recordsKey   func(object string) string // empty if the response is the list of records
+	authenticate func(conn *APIConn, req *http.Request)
+	params       func(object, since string, opts map[string]any) url.Values
+	next         func(u *url.URL, resp *http.Response, body any, records []any) string
+	ping         string                                                      // the path requested to check the credentials, defaults to the first object
+	decorate     func(u *url.URL, object string, record map[string]any) bool // optional, adds fields to a record, false to skip it
+}
+
+var apiSpecs = map[dbio.Type]apiSpec{
+	dbio.TypeApiHubSpot: {
+		objects:   []string{"contacts", "companies", "deals", "tickets", "products", "line_items", "quotes", "owners"},
+		updateKey: func(object string) string { return "updatedAt" },
+		baseURL:   func(conn *APIConn) string { return "https://api.hubapi.com" },
+		path: func(conn *APIConn, object string) string {
+			if object == "owners" {
+				return "/crm/v3/owners"
+			}
+			return "/crm/v3/objects/" + object
+		},
+		recordsKey: func(object string) string { return "results" },
+		authenticate: func(conn *APIConn, req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+conn.GetProp("access_token"))
+		},
+		params: func(object, since string, opts map[string]any) url.Values {
+			// the list endpoints cannot filter on updatedAt, records are filtered as read
+			return url.Values{"limit": {"100"}}
+		},
+		next: func(u *url.URL, resp *http.Response, body any, records []any) string {
+			after := cast.ToString(nestedValue(body, "paging", "next", "after"))
+			if after == "" {
+				return ""
+			}
+			q := u.Query()
+			q.Set("after", after)
+			u.RawQuery = q.Encode()
+			return u.String()
+		},
+	},
+	dbio.TypeApiStripe: {
+		objects: []string{
+			"customers", "charges", "invoices", "subscriptions", "products", "prices", "payment_intents",
+			"refunds", "balance_transactions", "payouts", "disputes", "coupons", "events",
+		},
+		updateKey:  func(object string) string { return "created" },
+		baseURL:    func(conn *APIConn) string { return "https://api.stripe.com" },
+		path:       func(conn *APIConn, object string) string { return "/v1/" + object },
+		recordsKey: func(object string) string { return "data" },
+		authenticate: func(conn *APIConn, req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+conn.GetProp("api_key"))
+		},
+		params: func(object, since string, opts map[string]any) url.Values {
+			params := url.Values{"limit": {"100"}}
+			if since != "" {
+				created := cast.ToInt64(since) // unix timestamp
+				if created == 0 {
+					created = cast.ToTime(since).Unix()
+				}
+				params.Set("created[gte]", cast.ToString(created))
+			}
+			return params
+		},
+		next: func(u *url.URL, resp *http.Response, body any, records []any) string {
+			if !cast.ToBool(nestedValue(body, "has_more")) || len(records) == 0 {
+				return ""
+			}
+			q := u.Query()
+			q.Set("starting_after", cast.ToString(nestedValue(records[len(records)-1], "id")))
+			u.RawQuery = q.Encode()
+			return u.String()
+		},
+	},
+	dbio.TypeApiShopify: {
+		objects:   []string{"orders", "customers", "products", "custom_collections", "smart_collections", "draft_orders", "price_rules", "locations"},
+		updateKey: func(object string) string { return "updated_at" },
+		baseURL: func(conn *APIConn) string {
+			store := conn.GetProp("store")
+			if !strings.Contains(store, ".") {
+				store = store + ".myshopify.com"
+			}
+			return "https://" + store
+		},
+		path: func(conn *APIConn, object string) string {
+			version := lo.Ternary(conn.GetProp("api_version") != "", conn.GetProp("api_version"), "2024-07")
+			return "/admin/api/" + version + "/" + object + ".json"
+		},
+		recordsKey: func(object string) string { return object },
+		authenticate: func(conn *APIConn, req *http.Request) {
+			req.Header.Set("X-Shopify-Access-Token", conn.GetProp("access_token"))
+		},
+		params: func(object, since string, opts map[string]any) url.Values {
+			params := url.Values{"limit": {"250"}}
+			if object == "orders" {
+				params.Set("status", "any") // defaults to open orders
+			}
+			if since != "" {
+				params.Set("updated_at_min", cast.ToTime(since).Format(time.RFC3339))
+			}
+			return params
+		},
+		next: linkHeaderNext,
+	},
+	dbio.TypeApiJira: {
+		objects: []string{"issues", "projects"},
+		updateKey: func(object string) string {
+			return lo.Ternary(object == "issues", "fields__updated", "")
+		},
+		baseURL: func(conn *APIConn) string {
+			site := conn.GetProp("site")
+			if !strings.Contains(site, ".") {
+				site = site + ".atlassian.net"
+			}
+			return "https://" + site
+		},
+		path: func(conn *APIConn, object string) string {
+			if object == "projects" {
+				return "/rest/api/3/project/search"
+			}
+			return "/rest/api/3/search/jql"
+		},
+		recordsKey: func(object string) string {
+			return lo.Ternary(object == "projects", "values", "issues")
+		},
+		ping: "/rest/api/3/myself",
+		authenticate: func(conn *APIConn, req *http.Request) {
+			req.SetBasicAuth(conn.GetProp("user"), conn.GetProp("api_token"))
+		},
+		params: func(object, since string, opts map[string]any) url.Values {
+			if object == "projects" {
+				return url.Values{"maxResults": {"50"}}
+			}
+
+			// the JQL timestamps are in the user timezone, a day earlier is read
+			// and the records are filtered as read
+			jql := cast.ToString(opts["jql"])
+			if since != "" {
+				updated := g.F(`updated >= "%s"`, cast.ToTime(since).Add(-24*time.Hour).Format("2006-01-02 15:04"))
+				jql = lo.Ternary(jql != "", "("+jql+") AND "+updated, updated)
+			} else if jql == "" {
+				jql = `updated >= "1970-01-01"` // the search requires a bounded query
+			}
+			return url.Values{
+				"jql":        {jql + " ORDER BY updated ASC"},
+				"fields":     {"*navigable"},
+				"maxResults": {"100"},
+			}
+		},
+		next: func(u *url.URL, resp *http.Response, body any, records []any) string {
+			if cast.ToBool(nestedValue(body, "isLast")) || len(records) == 0 {
+				return ""
+			}
+			q := u.Query()
+			if token := cast.ToString(nestedValue(body, "nextPageToken")); token != "" {
+				q.Set("nextPageToken", token)
+			} else if u.Path == "/rest/api/3/project/search" {
+				q.Set("startAt", cast.ToString(cast.ToInt(q.Get("startAt"))+len(records)))
+			} else {
+				return ""
+			}
+			u.RawQuery = q.Encode()
+			return u.String()
+		},
+	},
+	dbio.TypeApiGitHub: {
+		objects: []string{"issues", "pulls", "commits"},
+		updateKey: func(object string) string {
+			return lo.Ternary(object == "commits", "commit__committer__date", "updated_at")
+		},
+		baseURL: func(conn *APIConn) string { return "https://api.github.com" },
+		path: func(conn *APIConn, object string) string {
+			return "/repos/{repo}/" + object // expanded with the repos
+		},
+		recordsKey: func(object string) string { return "" },
+		authenticate: func(conn *APIConn, req *http.Request) {
+			req.Header.Set("Accept", "application/vnd.github+json")
+			req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+			if token := conn.GetProp("token"); token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+		},
+		params: func(object, since string, opts map[string]any) url.Values {
+			params := url.Values{"per_page": {"100"}}
+			if object != "commits" {
+				params.Set("state", "all")
+				params.Set("sort", "updated")
+				params.Set("direction", "asc")
+			}
+			if since != "" && object != "pulls" {
+				// pull requests cannot be filtered server-side, records are filtered as read
+				params.Set("since", cast.ToTime(since).UTC().Format(time.RFC3339))
+			}
+			return params
+		},
+		next: linkHeaderNext,
+		ping: "/rate_limit",
+		decorate: func(u *url.URL, object string, record map[string]any) bool {
+			if object == "issues" && record["pull_request"] != nil {
+				return false // pull requests are read as pulls
+			}
+			// /repos/{owner}/{repo}/...
+			if parts := strings.Split(u.Path, "/"); len(parts) > 3 {
+				record["repository"] = parts[2] + "/" + parts[3]
+			}
+			return true
+		},
+	},
+}
+
+// linkHeaderNext returns the next page from the Link header:
+// <https://...&page_info=xyz>; rel="next"
+func linkHeaderNext(u *url.URL, resp *http.Response, body any, records []any) string {
+	for _, link := range strings.Split(resp.Header.Get("Link"), ",") {
+		if m := linkNextRegex.FindStringSubmatch(link); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+var linkNextRegex = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// Init initiates the object
+func (conn *APIConn) Init() error {
+
+	conn.BaseConn.URL = conn.URL
+	conn.BaseConn.Type = dbio.Type(strings.Split(conn.URL, ":")[0])
+
+	if _, ok := apiSpecs[conn.BaseConn.Type]; !ok {
+		return g.Error("unsupported api type: %s", conn.BaseConn.Type)
+	}
+
+	instance := Connection(conn)
+	conn.BaseConn.instance = &instance
+	return conn.BaseConn.Init()
+}
+
+func (conn *APIConn) spec() apiSpec {
+	return apiSpecs[conn.Type]
+}
+
+// Connect connects to the API, and checks the credentials
+func (conn *APIConn) Connect(timeOut ...int) (err error) {
+	to := 30
+	if len(timeOut) > 0 && timeOut[0] > 0 {
+		to = timeOut[0]
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig, err := conn.makeTlsConfig()
+	if err != nil {
+		return g.Error(err)
+	} else if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	conn.client = &http.Client{Transport: transport, Timeout: time.Duration(to) * time.Second}
+
+	spec := conn.spec()
+	pingURL := conn.baseURL() + spec.ping
+	if spec.ping == "" {
+		pingURL = conn.baseURL() + spec.path(conn, spec.objects[0]) + "?limit=1"
+	}
+	_, _, err = conn.request(conn.Context().Ctx, pingURL)
+	if err != nil {
+		return g.Error(err, "Failed to connect to %s", conn.Type.NameLong())
+	}
+
+	g.Debug(`opened "%s" connection (%s)`, conn.Type, conn.GetProp("sling_conn_id"))
+
+	return nil
+}
+
+func (conn *APIConn) Close() error {
+	g.Debug(`closed "%s" connection (%s)`, conn.Type, conn.GetProp("sling_conn_id"))
+	return nil
+}
+
+// NewTransaction creates a new transaction
+func (conn *APIConn) NewTransaction(ctx context.Context, options ...*sql.TxOptions) (tx Transaction, err error) {
+	// does not support transaction
+	return
+}
+
+// GetTableColumns returns the columns of an object, from a sample of records
+func (conn *APIConn) GetTableColumns(table *Table, fields ...string) (columns iop.Columns, err error) {
+	ds, err := conn.StreamRows(table.Name, g.M("limit", 10))
+	if err != nil {
+		return columns, g.Error(err, "could not query to get columns")
+	}
+
+	data, err := ds.Collect(10)
+	if err != nil {
+		return columns, g.Error(err, "could not collect to get columns")
+	}
+
+	for i := range data.Columns {
+		data.Columns[i].Schema = table.Schema
+		data.Columns[i].Table = table.Name
+		data.Columns[i].DbType = "-"
+	}
+
+	return data.Columns, nil
+}
+
+// GetSQLColumns returns the columns of a stream definition (e.g. `{"object": "issues", "jql": "project = ENG"}`),
+// from a sample of records
+func (conn *APIConn) GetSQLColumns(table Table) (columns iop.Columns, err error) {
+	options, err := g.UnmarshalMap(table.SQL)
+	if err != nil {
+		return conn.GetTableColumns(&table)
+	}
+	options["limit"] = 10
+
+	ds, err := conn.StreamRows(table.Name, options)
+	if err != nil {
+		return columns, g.Error(err, "could not query to get columns")
+	}
+	data, err := ds.Collect(10)
+	if err != nil {
+		return columns, g.Error(err, "could not collect to get columns")
+	}
+	return data.Columns, nil
+}
+
+func (conn *APIConn) ExecContext(ctx context.Context, sql string, args ...interface{}) (result sql.Result, err error) {
+	return nil, g.Error("ExecContext not implemented on APIConn")
+}
+
+func (conn *APIConn) BulkExportFlow(table Table) (df *iop.Dataflow, err error) {
+	options, _ := g.UnmarshalMap(table.SQL)
+	ds, err := conn.StreamRowsContext(conn.Context().Ctx, table.Name, options)
+	if err != nil {
+		return df, g.Error(err, "could start datastream")
+	}
+
+	df, err = iop.MakeDataFlow(ds)
+	if err != nil {
+		return df, g.Error(err, "could start dataflow")
+	}
+
+	return
+}
+
+// StreamRowsContext streams the records of an object. With an `update_key`
+// and a `value` (incremental) or `start_value`/`end_value` (backfill),
+// only the records within the range are returned. The `object` option
+// overrides the object name, for stream definitions
+func (conn *APIConn) StreamRowsContext(ctx context.Context, object string, Opts ...map[string]interface{}) (ds *iop.Datastream, err error) {
+	opts := getQueryOptions(Opts)
+	Limit := cast.ToUint64(opts["limit"]) // 0 is infinite
+
+	if val := cast.ToString(opts["object"]); val != "" {
+		object = val
+	} else if table, err := ParseTableName(object, conn.Type); err == nil && table.Name != "" {
+		object = table.Name
+	}
+	if strings.TrimSpace(object) == "" {
+		return ds, g.Error("no object provided")
+	}
+
+	updateKey := cast.ToString(opts["update_key"])
+	incrementalValue := strings.Trim(cast.ToString(opts["value"]), "'")
+	startValue := strings.Trim(cast.ToString(opts["start_value"]), "'")
+	endValue := strings.Trim(cast.ToString(opts["end_value"]), "'")
+	gte := cast.ToString(opts["gt"]) == ">="
+	if incrementalValue == "null" {
+		incrementalValue = ""
+	}
+
+	// filter server-side on the default key, if possible
+	spec := conn.spec()
+	since := ""
+	if defaultKey := spec.updateKey(object); defaultKey != "" && strings.EqualFold(updateKey, defaultKey) {
+		since = lo.Ternary(incrementalValue != "", incrementalValue, startValue)
+	}
+
+	urls, err := conn.objectURLs(object, spec.params(object, since, opts), opts)
+	if err != nil {
+		return ds, g.Error(err, "could not get the urls of %s", object)
+	}
+
+	queryContext := g.NewContext(ctx)
+	reader := &apiReader{
+		conn:   conn,
+		ctx:    queryContext.Ctx,
+		object: object,
+		urls:   urls,
+	}
+	conn.LogSQL(g.Marshal(g.M("object", object, "urls", urls)))
+
+	ds = iop.NewDatastreamContext(queryContext.Ctx, nil)
+
+	flatten := true
+	if val := conn.GetProp("flatten"); val != "" {
+		flatten = cast.ToBool(val)
+	}
+	js := iop.NewJSONStream(ds, reader, flatten, conn.GetProp("jmespath"))
+	js.HasMapPayload = true
+
+	// inRange returns true if the record is within the incremental / backfill range.
+	// The columns are added as the records are read, so the index of the update
+	// key is only looked up again when the number of columns changes.
+	keyIndex, keyColumns := -1, 0
+	inRange := func(row []any) bool {
+		if updateKey == "" || (incrementalValue == "" && startValue == "") {
+			return true
+		}
+		if len(ds.Columns) != keyColumns {
+			keyColumns = len(ds.Columns)
+			keyIndex = lo.IndexOf(ds.Columns.Names(true), strings.ToLower(updateKey))
+		}
+		if keyIndex < 0 || keyIndex >= len(row) {
+			return true
+		}
+		val := row[keyIndex]
+		if incrementalValue != "" {
+			return apiCompare(val, incrementalValue) > 0 || (gte && apiCompare(val, incrementalValue) == 0)
+		}
+		return apiCompare(val, startValue) >= 0 && (endValue == "" || apiCompare(val, endValue) <= 0)
+	}
+
+	nextFunc := func(it *iop.Iterator) bool {
+		if Limit > 0 && it.Counter >= Limit {
+			return false
+		} else if it.Context.Err() != nil {
+			return false
+		}
+
+		for js.NextFunc(it) {
+			if inRange(it.Row) {
+				return true
+			}
+		}
+		return false
+	}
+
+	ds.SetIterator(ds.NewIterator(ds.Columns, nextFunc))
+	ds.SetMetadata(conn.GetProp("METADATA"))
+	ds.SetConfig(conn.Props())
+
+	err = ds.Start()
+	if err != nil {
+		queryContext.Cancel()
+		return ds, g.Error(err, "could start datastream")
+	}
+
+	return
+}
+
+func (conn *APIConn) baseURL() string {
+	baseURL := lo.Ternary(conn.GetProp("base_url") != "", conn.GetProp("base_url"), conn.spec().baseURL(conn))
+	return strings.TrimSuffix(baseURL, "/")
+}
+
+// objectURLs returns the URLs of the first pages of an object
+func (conn *APIConn) objectURLs(object string, params url.Values, opts map[string]any) (urls []string, err error) {
+	paths := []string{conn.spec().path(conn, object)}
+	if strings.Contains(paths[0], "{repo}") {
+		if paths, err = conn.repoPaths(paths[0], opts); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, path := range paths {
+		urls = append(urls, conn.baseURL()+path+"?"+params.Encode())
+	}
+	return urls, nil
+}
+
+// repoPaths returns a path per repository of the `repos` option or prop, a list
+// of owner/name with wildcards (e.g. `my-org/*, other-org/api-*`)
+func (conn *APIConn) repoPaths(path string, opts map[string]any) (paths []string, err error) {
+	repos := cast.ToString(opts["repos"])
+	if repos == "" {
+		repos = conn.GetProp("repos")
+	}
+	if repos == "" {
+		return nil, g.Error("no repos provided (e.g. `my-org/*` or `my-org/my-repo`)")
+	}
+
+	for _, pattern := range strings.Split(repos, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if !strings.Contains(pattern, "*") {
+			paths = append(paths, strings.Replace(path, "{repo}", pattern, 1))
+			continue
+		}
+
+		names, err := conn.githubRepos(strings.Split(pattern, "/")[0])
+		if err != nil {
+			return nil, g.Error(err, "could not list repositories for %s", pattern)
+		}
+		for _, name := range names {
+			if matched, _ := filepath.Match(strings.ToLower(pattern), strings.ToLower(name)); matched {
+				paths = append(paths, strings.Replace(path, "{repo}", name, 1))
+			}
+		}
+	}
+	return paths, nil
+}
+
+// githubRepos returns the repositories (owner/name) of an organization or user
+func (conn *APIConn) githubRepos(owner string) (names []string, err error) {
+	nextURL := conn.baseURL() + "/orgs/" + owner + "/repos?per_page=100"
+	for nextURL != "" {
+		resp, body, err := conn.request(conn.Context().Ctx, nextURL)
+		if resp != nil && resp.StatusCode == http.StatusNotFound && strings.Contains(nextURL, "/orgs/") {
+			nextURL = conn.baseURL() + "/users/" + owner + "/repos?per_page=100" // not an organization
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		records, _ := body.([]any)
+		for _, record := range records {
+			names = append(names, cast.ToString(nestedValue(record, "full_name")))
+		}
+		u, _ := url.Parse(nextURL)
+		nextURL = linkHeaderNext(u, resp, body, records)
+	}
+	return names, nil
+}
+
+// request gets a page, retrying when rate limited
+func (conn *APIConn) request(ctx context.Context, uri string) (resp *http.Response, body any, err error) {
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+		if err != nil {
+			return nil, nil, g.Error(err, "could not create request")
+		}
+		req.Header.Set("Accept", "application/json")
+		conn.spec().authenticate(conn, req)
+
+		resp, err = conn.client.Do(req)
+		if err != nil {
+			return nil, nil, g.Error(err, "could not request %s", req.URL.Path)
+		}
+		respBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return resp, nil, g.Error(err, "could not read response")
+		}
+
+		if (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500) && attempt < 5 {
+			wait := time.Duration(attempt) * time.Second
+			if seconds := cast.ToFloat64(resp.Header.Get("Retry-After")); seconds > 0 {
+				wait = time.Duration(seconds * float64(time.Second))
+			}
+			g.Debug("%s returned status %d, retrying in %s", conn.Type, resp.StatusCode, wait)
+			select {
+			case <-time.After(wait):
+				continue
+			case <-ctx.Done():
+				return resp, nil, ctx.Err()
+			}
+		} else if resp.StatusCode >= 300 {
+			return resp, nil, g.Error("%s returned status %d: %s", conn.Type, resp.StatusCode, string(respBytes))
+		}
+
+		if err = json.Unmarshal(respBytes, &body); err != nil {
+			return resp, nil, g.Error(err, "could not decode response")
+		}
+		return resp, body, nil
+	}
+}
+
+// apiReader pages through the records of an object, one record per Decode.
+// The urls are the next pages to read, one per path of the object
+type apiReader struct {
+	conn    *APIConn
+	ctx     context.Context
+	object  string
+	urls    []string
+	pageURL *url.URL
+	records []any
+}
+
+func (r *apiReader) Decode(obj any) (err error) {
+	for {
+		for len(r.records) == 0 {
+			if len(r.urls) == 0 {
+				return io.EOF
+			} else if err = r.fetch(); err != nil {
+				return err
+			}
+		}
+
+		record, ok := r.records[0].(map[string]any)
+		r.records = r.records[1:]
+		if decorate := r.conn.spec().decorate; ok && decorate != nil && !decorate(r.pageURL, r.object, record) {
+			continue
+		}
+		if ptr, ok2 := obj.(*map[string]any); ok && ok2 {
+			*ptr = record
+		}
+		return nil
+	}
+}
+
+func (r *apiReader) fetch() (err error) {
+	pageURL := r.urls[0]
+	r.urls = r.urls[1:]
+	if r.pageURL, err = url.Parse(pageURL); err != nil {
+		return g.Error(err, "invalid page url")
+	}
+
+	resp, body, err := r.conn.request(r.ctx, pageURL)
+	if err != nil {
+		return g.Error(err, "could not read %s", r.object)
+	}
+
+	spec := r.conn.spec()
+	if key := spec.recordsKey(r.object); key != "" {
+		r.records, _ = nestedValue(body, key).([]any)
+	} else {
+		r.records, _ = body.([]any)
+	}
+
+	// copy the url, since next can modify it
+	u := *r.pageURL
+	if nextURL := spec.next(&u, resp, body, r.records); nextURL != "" {
+		r.urls = append([]string{nextURL}, r.urls...)
+	}
+	return nil
+}
+
+// nestedValue returns the value of nested keys of a map
+func nestedValue(obj any, keys ...string) any {
+	for _, key := range keys {
+		m, ok := obj.(map[string]any)
+		if !ok {
+			return nil
+		}
+		obj = m[key]
+	}
+	return obj
+}
+
+// apiCompare compares a record value with a range value, as numbers,
+// timestamps or strings
+func apiCompare(val any, rangeVal string) int {
+	if val == nil {
+		return -1
+	}
+
+	if num, err := cast.ToFloat64E(rangeVal); err == nil {
+		valNum := cast.ToFloat64(val)
+		return lo.Ternary(valNum > num, 1, lo.Ternary(valNum < num, -1, 0))
+	}
+
+	valTime, err1 := apiTime(val)
+	rangeTime, err2 := apiTime(rangeVal)
+	if err1 == nil && err2 == nil {
+		return valTime.Compare(rangeTime)
+	}
+
+	return strings.Compare(cast.ToString(val), rangeVal)
+}
+
+// apiTime parses a timestamp value, including the Jira format (2024-01-15T10:00:00.000+0000)
+func apiTime(val any) (time.Time, error) {
+	if t, err := cast.ToTimeE(val); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02T15:04:05.000-0700", cast.ToString(val))
+}
+
+// GetSchemas returns schemas
+func (conn *APIConn) GetSchemas() (data iop.Dataset, err error) {
+	data = iop.NewDataset(iop.NewColumnsFromFields("schema_name"))
+	data.Append([]interface{}{conn.Type.String()})
+	return data, nil
+}
+
+// GetTables returns the objects of the API
+func (conn *APIConn) GetTables(schema string) (data iop.Dataset, err error) {
+	data = iop.NewDataset(iop.NewColumnsFromFields("table_name"))
+	for _, object := range conn.spec().objects {
+		data.Append([]interface{}{object})
+	}
+	return data, nil
+}
+
+// GetSchemata obtain full schemata info for a schema and/or table in current database
+func (conn *APIConn) GetSchemata(level SchemataLevel, schemaName string, tableNames ...string) (Schemata, error) {
+	database := conn.Type.String()
+	schemata := Schemata{
+		Databases: map[string]Database{},
+		conn:      conn,
+	}
+
+	schema := Schema{
+		Name:   conn.Type.String(),
+		Tables: map[string]Table{},
+	}
+
+	objects := conn.spec().objects
+	if len(tableNames) > 0 && tableNames[0] != "" {
+		objects = lo.Map(tableNames, func(name string, i int) string {
+			table, _ := ParseTableName(name, conn.Type)
+			return table.Name
+		})
+	}
+
+	if g.In(level, SchemataLevelTable, SchemataLevelColumn) {
+		for _, object := range objects {
+			table := Table{
+				Name:     object,
+				Schema:   schema.Name,
+				Database: database,
+				Columns:  iop.Columns{},
+				Dialect:  conn.GetType(),
+			}
+
+			if level == SchemataLevelColumn {
+				columns, err := conn.GetTableColumns(&table)
+				if err != nil {
+					return schemata, g.Error(err, "could not get columns of %s", object)
+				}
+				table.Columns = columns
+			}
+
+			schema.Tables[strings.ToLower(object)] = table
+		}
+	}
+
+	schemata.Databases[strings.ToLower(database)] = Database{
+		Name:    database,
+		Schemas: map[string]Schema{strings.ToLower(schema.Name): schema},
+	}
+
+	return schemata, nil
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/flarco/g"
 	"github.com/slingdata-io/sling-cli/core/dbio/iop"
 	"github.com/spf13/cast"
+	"google.golang.org/api/impersonate"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
@@ -130,6 +131,18 @@ func (conn *BigQueryConn) getNewClient(timeOut ...int) (client *bigquery.Client,
 		authOption = option.WithCredentials(creds)
 	}
 
+	// impersonate a service account with the credentials, tokens are refreshed when expired
+	if sa := conn.GetProp("impersonate_service_account"); sa != "" {
+		tokens, err := impersonate.CredentialsTokenSource(conn.BaseConn.Context().Ctx, impersonate.CredentialsConfig{
+			TargetPrincipal: sa,
+			Scopes:          []string{bigquery.Scope, "https://www.googleapis.com/auth/cloud-platform"},
+		}, authOption)
+		if err != nil {
+			return client, g.Error(err, "could not impersonate service account %s", sa)
+		}
+		authOption = option.WithTokenSource(tokens)
+	}
+
 	if conn.ProjectID == "" && credJsonBody != "" {
 		m := g.M()
 		g.Unmarshal(credJsonBody, &m)
@@ -230,6 +243,9 @@ func (conn *BigQueryConn) ExecContext(ctx context.Context, sql string, args ...i
 	res := bqResult{}
 	conn.LogSQL(sql)
 
+	start := time.Now()
+	defer func() { conn.logSQLResult(sql, nil, start, result, err) }()
+
 	q := conn.Client.Query(sql)
 	q.JobIDConfig.Location = conn.Location
 	q.QueryConfig = bigquery.QueryConfig{
@@ -268,25 +284,74 @@ func (conn *BigQueryConn) GenerateDDL(table Table, data iop.Dataset, temporary b
 	}
 
 	partitionBy := ""
-	if keys, ok := table.Keys[iop.PartitionKey]; ok {
+	if expr := conn.partitionExpr(data.Columns.GetKeys(iop.PartitionKey)); expr != "" {
+		partitionBy = g.F("partition by %s", expr)
+	} else if keys, ok := table.Keys[iop.PartitionKey]; ok {
 		// allow custom SQL expression for partitioning
 		partitionBy = g.F("partition by %s", strings.Join(keys, ", "))
-	} else if keyCols := data.Columns.GetKeys(iop.PartitionKey); len(keyCols) > 0 {
-		colNames := conn.GetType().QuoteNames(keyCols.Names()...)
-		partitionBy = g.F("partition by %s", strings.Join(colNames, ", "))
 	}
 	sql = strings.ReplaceAll(sql, "{partition_by}", partitionBy)
 
 	clusterBy := ""
-	if keyCols := data.Columns.GetKeys(iop.ClusterKey); len(keyCols) > 0 {
-		colNames := conn.GetType().QuoteNames(keyCols.Names()...)
-		clusterBy = g.F("cluster by %s", strings.Join(colNames, ", "))
+	if fields := keyFields(conn, table, data.Columns, iop.ClusterKey); len(fields) > 0 {
+		clusterBy = g.F("cluster by %s", strings.Join(fields, ", "))
 	}
 	sql = strings.ReplaceAll(sql, "{cluster_by}", clusterBy)
 
+	// not for the temp table, which is read without partition filter
+	tableOptions := []string{}
+	if !temporary {
+		if partitionBy != "" && cast.ToBool(conn.GetProp("require_partition_filter")) {
+			tableOptions = append(tableOptions, "require_partition_filter = true")
+		}
+		if days := cast.ToInt(conn.GetProp("table_expiration_days")); days > 0 {
+			tableOptions = append(tableOptions, g.F("expiration_timestamp = timestamp_add(current_timestamp(), interval %d day)", days))
+		}
+	}
+	if len(tableOptions) > 0 {
+		sql = strings.ReplaceAll(sql, "{table_options}", g.F("options(%s)", strings.Join(tableOptions, ", ")))
+	}
+	sql = strings.ReplaceAll(sql, "{table_options}", "")
+
 	return strings.TrimSpace(sql), nil
 }
 
+// partitionExpr returns the partition expression of the partition column,
+// truncated to the `partition_type` (hour, day, month or year). The
+// `ingestion` type partitions on the ingestion date, without column.
+func (conn *BigQueryConn) partitionExpr(keyCols iop.Columns) string {
+	partitionType := strings.ToLower(conn.GetProp("partition_type"))
+	if partitionType == "ingestion" {
+		return "_PARTITIONDATE"
+	} else if len(keyCols) == 0 {
+		return ""
+	}
+
+	col := keyCols[0] // bigquery only partitions on one column
+	name := conn.Quote(col.Name)
+
+	switch {
+	case col.Type.IsDate():
+		if g.In(partitionType, "month", "year") {
+			return g.F("date_trunc(%s, %s)", name, strings.ToUpper(partitionType))
+		} else if partitionType == "hour" {
+			g.Warn("partition_type hour is not possible for date column %s, using day", col.Name)
+		}
+		return name
+	case col.Type.IsDatetime():
+		if partitionType == "" {
+			partitionType = "day"
+		}
+		nativeType, _ := conn.GetNativeType(col)
+		if strings.HasPrefix(strings.ToLower(nativeType), "datetime") {
+			return g.F("datetime_trunc(%s, %s)", name, strings.ToUpper(partitionType))
+		}
+		return g.F("timestamp_trunc(%s, %s)", name, strings.ToUpper(partitionType))
+	}
+
+	return name
+}
+
 type bQTypeCols struct {
 	numericCols  []int
 	datetimeCols []int
@@ -396,6 +461,7 @@ func (conn *BigQueryConn) StreamRowsContext(ctx context.Context, sql string, opt
 	}
 
 	it, err := q.Read(queryContext.Ctx)
+	conn.logSQLResult(sql, nil, start, nil, err)
 	if err != nil {
 		if strings.Contains(sql, noDebugKey) && !g.IsDebugLow() {
 			err = g.Error(err, "SQL Error")
@@ -1027,6 +1093,54 @@ func (conn *BigQueryConn) CastColumnForSelect(srcCol iop.Column, tgtCol iop.Colu
 }
 
 // GenerateUpsertSQL generates the upsert SQL
+// partitionRangeFilter returns a filter of the target partition column on the range
+// of the source values, so that the delete of the upsert only scans those partitions.
+// The rows are expected to not move partitions (e.g. partitioned on a creation date),
+// set `partition_pruning: false` otherwise.
+func (conn *BigQueryConn) partitionRangeFilter(srcTable, tgtTable string) string {
+	if val := conn.GetProp("partition_pruning"); val != "" && !cast.ToBool(val) {
+		return ""
+	}
+
+	table, err := ParseTableName(tgtTable, conn.GetType())
+	if err != nil {
+		return ""
+	}
+
+	sql := g.F(
+		"select column_name, data_type from `%s`.INFORMATION_SCHEMA.COLUMNS where table_name = '%s' and is_partitioning_column = 'YES'",
+		table.Schema, table.Name,
+	)
+	data, err := conn.Query(sql + noDebugKey)
+	if err != nil || len(data.Rows) == 0 {
+		return ""
+	}
+
+	colName, dataType := cast.ToString(data.Rows[0][0]), strings.ToUpper(cast.ToString(data.Rows[0][1]))
+	if !g.In(dataType, "DATE", "DATETIME", "TIMESTAMP", "INT64") {
+		return ""
+	}
+
+	col := conn.Quote(colName)
+	sql = g.F("select cast(min(%s) as string), cast(max(%s) as string), countif(%s is null) from %s", col, col, col, srcTable)
+	data, err = conn.Query(sql + noDebugKey)
+	if err != nil || len(data.Rows) == 0 || data.Rows[0][0] == nil {
+		g.Debug("could not get partition range of %s, not pruning", srcTable)
+		return ""
+	}
+
+	return bigQueryPartitionFilter(col, dataType, cast.ToString(data.Rows[0][0]), cast.ToString(data.Rows[0][1]), cast.ToInt64(data.Rows[0][2]) > 0)
+}
+
+// bigQueryPartitionFilter returns the range filter of the partition column, as a prefix of the where clause
+func bigQueryPartitionFilter(col, dataType, minVal, maxVal string, hasNulls bool) string {
+	filter := g.F("tgt.%s between cast('%s' as %s) and cast('%s' as %s)", col, minVal, dataType, maxVal, dataType)
+	if hasNulls {
+		filter = g.F("%s or tgt.%s is null", filter, col)
+	}
+	return "(" + filter + ") and "
+}
+
 func (conn *BigQueryConn) GenerateUpsertSQL(srcTable string, tgtTable string, pkFields []string) (sql string, err error) {
 
 	upsertMap, err := conn.BaseConn.GenerateUpsertExpressions(srcTable, tgtTable, pkFields)
@@ -1037,7 +1151,7 @@ func (conn *BigQueryConn) GenerateUpsertSQL(srcTable string, tgtTable string, pk
 
 	sqlTempl := `
 	delete from {tgt_table} tgt
-	where exists (
+	where {partition_filter}exists (
 			select 1
 			from {src_table} src
 			where {src_tgt_pk_equal}
@@ -1065,6 +1179,7 @@ func (conn *BigQueryConn) GenerateUpsertSQL(srcTable string, tgtTable string, pk
 		sqlTempl,
 		"src_table", srcTable,
 		"tgt_table", tgtTable,
+		"partition_filter", conn.partitionRangeFilter(srcTable, tgtTable),
 		"src_tgt_pk_equal", upsertMap["src_tgt_pk_equal"],
 		"set_fields", upsertMap["set_fields"],
 		"insert_fields", upsertMap["insert_fields"],
@@ -1114,6 +1229,61 @@ func (conn *BigQueryConn) GetSchemas() (iop.Dataset, error) {
 	return data, nil
 }
 
+// SetColumnTags sets the policy tags on the table columns. In BigQuery, the tags
+// must be the policy tag resource names from Data Catalog, such as
+// `projects/<project>/locations/<location>/taxonomies/<id>/policyTags/<id>`.
+func (conn *BigQueryConn) SetColumnTags(table Table, columnTags map[string][]string) (err error) {
+	if len(columnTags) == 0 {
+		return nil
+	}
+
+	client, err := conn.getNewClient()
+	if err != nil {
+		return g.Error(err, "Failed to connect to client")
+	}
+	defer client.Close()
+
+	bqTable := client.Dataset(table.Schema).Table(table.Name)
+	md, err := bqTable.Metadata(conn.Context().Ctx)
+	if err != nil {
+		return g.Error(err, "could not get metadata for table %s", table.FullName())
+	}
+
+	schema := setPolicyTags(md.Schema, columnTags)
+	_, err = bqTable.Update(conn.Context().Ctx, bigquery.TableMetadataToUpdate{Schema: schema}, md.ETag)
+	if err != nil {
+		return g.Error(err, "could not set policy tags for table %s", table.FullName())
+	}
+
+	return nil
+}
+
+// setPolicyTags sets the policy tags of the matching schema fields
+func setPolicyTags(schema bigquery.Schema, columnTags map[string][]string) bigquery.Schema {
+	for _, field := range schema {
+		for column, tags := range columnTags {
+			if !strings.EqualFold(field.Name, column) {
+				continue
+			}
+
+			policyTags := &bigquery.PolicyTagList{}
+			for _, tag := range tags {
+				name, _ := ParseColumnTag(tag)
+				if !strings.HasPrefix(name, "projects/") {
+					g.Warn("skipping tag '%s' for column %s. BigQuery policy tags should be resource names (projects/.../policyTags/...)", name, column)
+					continue
+				}
+				policyTags.Names = append(policyTags.Names, name)
+			}
+
+			if len(policyTags.Names) > 0 {
+				field.PolicyTags = policyTags
+			}
+		}
+	}
+	return schema
+}
+
 // GetSchemata obtain full schemata info for a schema and/or table in current database
 func (conn *BigQueryConn) GetSchemata(level SchemataLevel, schemaName string, tableNames ...string) (Schemata, error) {
 	schemata := Schemata{
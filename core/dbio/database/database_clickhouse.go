@@ -90,6 +90,9 @@ func (conn *ClickhouseConn) GenerateDDL(table Table, data iop.Dataset, temporary
 		primaryKey = g.F("primary key (%s)", strings.Join(colNames, ", "))
 		orderBy = strings.Join(colNames, ", ")
 	}
+	if fields := keyFields(conn, table, data.Columns, iop.SortKey); len(fields) > 0 {
+		orderBy = strings.Join(fields, ", ")
+	}
 	ddl = g.R(ddl, "primary_key", primaryKey, "order_by", orderBy)
 
 	partitionBy := ""
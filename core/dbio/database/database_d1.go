@@ -188,7 +188,9 @@ func (conn *D1Conn) ExecContext(ctx context.Context, q string, args ...interface
 
 	conn.LogSQL(q, args...)
 
+	start := time.Now()
 	resp, err := conn.makeRequest(queryContext.Ctx, "POST", "/raw", strings.NewReader(g.Marshal(payload)))
+	conn.logSQLResult(q, args, start, nil, err)
 	if err != nil {
 		if strings.Contains(q, noDebugKey) {
 			err = g.Error(err, "Error executing query")
@@ -236,6 +238,7 @@ func (conn *D1Conn) StreamRowsContext(ctx context.Context, query string, options
 	payload := g.M("sql", query, "params", []string{})
 
 	resp, err := conn.makeRequest(queryContext.Ctx, "POST", "/raw", strings.NewReader(g.Marshal(payload)))
+	conn.logSQLResult(query, nil, start, nil, err)
 	if err != nil {
 		return ds, g.Error(err, "could not make request")
 	}
@@ -0,0 +1,463 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	analyticsdata "google.golang.org/api/analyticsdata/v1beta"
+	"google.golang.org/api/option"
+)
+
+// GoogleReportConn is a Google Analytics 4 (Data API) or Google Ads (GAQL)
+// reporting connection. The stream is a report definition, e.g.
+// `{"dimensions": ["date", "country"], "metrics": ["sessions"]}` for GA4, or
+// `{"query": "select campaign.name, metrics.clicks, segments.date from campaign
+// where segments.date between '{start_date}' and '{end_date}'"}` for Google Ads,
+// read over the date range of the incremental / backfill run
+type GoogleReportConn struct {
+	BaseConn
+	URL    string
+	tokens oauth2.TokenSource
+	ga4    *analyticsdata.Service
+}
+
+// ReportDefinition is the definition of a report stream
+type ReportDefinition struct {
+	Dimensions      []string `json:"dimensions,omitempty"`       // ga4
+	Metrics         []string `json:"metrics,omitempty"`          // ga4
+	DimensionFilter any      `json:"dimension_filter,omitempty"` // ga4, as a FilterExpression
+	MetricFilter    any      `json:"metric_filter,omitempty"`    // ga4, as a FilterExpression
+	Query           string   `json:"query,omitempty"`            // google ads GAQL
+	StartDate       string   `json:"start_date,omitempty"`       // defaults to 30 days ago
+	EndDate         string   `json:"end_date,omitempty"`         // defaults to today
+
+	// merged from the incremental / backfill run
+	UpdateKey  string `json:"update_key,omitempty"`
+	Value      string `json:"value,omitempty"`
+	StartValue string `json:"start_value,omitempty"`
+	EndValue   string `json:"end_value,omitempty"`
+}
+
+// DateRange returns the date range of the report. In incremental mode, the
+// last date is read again, since the metrics of a day settle after it
+func (rd ReportDefinition) DateRange() (start, end string) {
+	layout := "2006-01-02"
+	toDate := func(val string) string {
+		val = strings.Trim(val, "'")
+		if t, err := time.Parse("20060102", val); err == nil {
+			return t.Format(layout) // ga4 date dimension
+		} else if t, err := cast.ToTimeE(val); err == nil {
+			return t.Format(layout)
+		}
+		return val // e.g. `7daysAgo`
+	}
+
+	start = lo.Ternary(rd.StartDate != "", rd.StartDate, time.Now().AddDate(0, 0, -30).Format(layout))
+	end = lo.Ternary(rd.EndDate != "", rd.EndDate, time.Now().Format(layout))
+
+	if rd.StartValue != "" && rd.EndValue != "" {
+		start, end = rd.StartValue, rd.EndValue
+	} else if rd.Value != "" && rd.Value != "null" {
+		start = rd.Value
+	}
+
+	return toDate(start), toDate(end)
+}
+
+// Init initiates the object
+func (conn *GoogleReportConn) Init() error {
+
+	conn.BaseConn.URL = conn.URL
+	conn.BaseConn.Type = dbio.Type(strings.Split(conn.URL, ":")[0])
+
+	if !g.In(conn.BaseConn.Type, dbio.TypeApiGA4, dbio.TypeApiGoogleAds) {
+		return g.Error("unsupported report type: %s", conn.BaseConn.Type)
+	}
+
+	if conn.GetProp("GC_KEY_FILE") == "" {
+		conn.SetProp("GC_KEY_FILE", conn.GetProp("keyfile"))
+	}
+	if conn.GetProp("GC_KEY_FILE") == "" {
+		conn.SetProp("GC_KEY_FILE", conn.GetProp("GOOGLE_APPLICATION_CREDENTIALS"))
+	}
+
+	instance := Connection(conn)
+	conn.BaseConn.instance = &instance
+	return conn.BaseConn.Init()
+}
+
+// getTokenSource returns the oauth tokens, from the `access_token`, the
+// service account key (GC_KEY_BODY, GC_KEY_FILE) or the default credentials
+func (conn *GoogleReportConn) getTokenSource() (tokens oauth2.TokenSource, err error) {
+	ctx := conn.BaseConn.Context().Ctx
+	scope := lo.Ternary(
+		conn.Type == dbio.TypeApiGA4,
+		analyticsdata.AnalyticsReadonlyScope,
+		"https://www.googleapis.com/auth/adwords",
+	)
+
+	var creds *google.Credentials
+	if val := conn.GetProp("access_token"); val != "" {
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: val}), nil
+	} else if val := conn.GetProp("GC_KEY_BODY"); val != "" {
+		creds, err = google.CredentialsFromJSON(ctx, []byte(val), scope)
+	} else if val := conn.GetProp("GC_KEY_FILE"); val != "" {
+		b, readErr := os.ReadFile(val)
+		if readErr != nil {
+			return nil, g.Error(readErr, "could not read google cloud key file")
+		}
+		creds, err = google.CredentialsFromJSON(ctx, b, scope)
+	} else {
+		creds, err = google.FindDefaultCredentials(ctx, scope)
+	}
+	if err != nil {
+		return nil, g.Error(err, "No Google credentials provided or could not find Application Default Credentials.")
+	}
+
+	return creds.TokenSource, nil
+}
+
+// Connect connects to the API
+func (conn *GoogleReportConn) Connect(timeOut ...int) (err error) {
+	conn.tokens, err = conn.getTokenSource()
+	if err != nil {
+		return g.Error(err, "Failed to get credentials")
+	}
+
+	switch conn.Type {
+	case dbio.TypeApiGA4:
+		if conn.GetProp("property_id") == "" {
+			return g.Error("property_id is required for %s", conn.Type.NameLong())
+		}
+		opts := []option.ClientOption{option.WithTokenSource(conn.tokens)}
+		if val := conn.GetProp("base_url"); val != "" {
+			opts = append(opts, option.WithEndpoint(strings.TrimSuffix(val, "/")+"/"))
+		}
+		conn.ga4, err = analyticsdata.NewService(conn.BaseConn.Context().Ctx, opts...)
+		if err != nil {
+			return g.Error(err, "Failed to create client")
+		}
+	case dbio.TypeApiGoogleAds:
+		if conn.GetProp("customer_id") == "" || conn.GetProp("developer_token") == "" {
+			return g.Error("customer_id and developer_token are required for %s", conn.Type.NameLong())
+		}
+	}
+
+	g.Debug(`opened "%s" connection (%s)`, conn.Type, conn.GetProp("sling_conn_id"))
+
+	return nil
+}
+
+func (conn *GoogleReportConn) Close() error {
+	g.Debug(`closed "%s" connection (%s)`, conn.Type, conn.GetProp("sling_conn_id"))
+	return nil
+}
+
+// NewTransaction creates a new transaction
+func (conn *GoogleReportConn) NewTransaction(ctx context.Context, options ...*sql.TxOptions) (tx Transaction, err error) {
+	// does not support transaction
+	return
+}
+
+// GetSQLColumns returns the columns of a report definition, from its first row
+func (conn *GoogleReportConn) GetSQLColumns(table Table) (columns iop.Columns, err error) {
+	ds, err := conn.StreamRows(table.SQL, g.M("limit", 1))
+	if err != nil {
+		return columns, g.Error(err, "could not get report columns")
+	}
+	ds.Collect(0)
+	return ds.Columns, nil
+}
+
+// GetTableColumns is not supported, the streams are report definitions
+func (conn *GoogleReportConn) GetTableColumns(table *Table, fields ...string) (columns iop.Columns, err error) {
+	return nil, g.Error("%s streams are report definitions, not tables", conn.Type.NameLong())
+}
+
+func (conn *GoogleReportConn) ExecContext(ctx context.Context, sql string, args ...interface{}) (result sql.Result, err error) {
+	return nil, g.Error("ExecContext not implemented on GoogleReportConn")
+}
+
+func (conn *GoogleReportConn) BulkExportFlow(table Table) (df *iop.Dataflow, err error) {
+	ds, err := conn.StreamRowsContext(conn.Context().Ctx, table.SQL)
+	if err != nil {
+		return df, g.Error(err, "could start datastream")
+	}
+
+	df, err = iop.MakeDataFlow(ds)
+	if err != nil {
+		return df, g.Error(err, "could start dataflow")
+	}
+
+	return
+}
+
+// StreamRowsContext runs a report definition, and streams its typed rows
+func (conn *GoogleReportConn) StreamRowsContext(ctx context.Context, query string, Opts ...map[string]interface{}) (ds *iop.Datastream, err error) {
+	opts := getQueryOptions(Opts)
+	limit := cast.ToInt64(opts["limit"]) // 0 is infinite
+
+	var rd ReportDefinition
+	if err = g.Unmarshal(query, &rd); err != nil {
+		return ds, g.Error(err, "invalid report definition, expected a JSON object: %s", query)
+	}
+
+	startDate, endDate := rd.DateRange()
+	conn.LogSQL(g.Marshal(g.M("definition", rd, "start_date", startDate, "end_date", endDate)))
+
+	var data iop.Dataset
+	switch conn.Type {
+	case dbio.TypeApiGA4:
+		data, err = conn.runGA4Report(ctx, rd, startDate, endDate, limit)
+	case dbio.TypeApiGoogleAds:
+		data, err = conn.runAdsQuery(ctx, rd, startDate, endDate, limit)
+	}
+	if err != nil {
+		return ds, err
+	}
+
+	data.Inferred = true // typed from the report headers
+	ds = data.Stream(conn.Props())
+	ds.SetMetadata(conn.GetProp("METADATA"))
+
+	return ds, nil
+}
+
+// runGA4Report runs a GA4 report, page by page
+func (conn *GoogleReportConn) runGA4Report(ctx context.Context, rd ReportDefinition, startDate, endDate string, limit int64) (data iop.Dataset, err error) {
+	if len(rd.Metrics) == 0 && len(rd.Dimensions) == 0 {
+		return data, g.Error("report definition requires dimensions and/or metrics")
+	}
+
+	request := &analyticsdata.RunReportRequest{
+		DateRanges: []*analyticsdata.DateRange{{StartDate: startDate, EndDate: endDate}},
+		Dimensions: lo.Map(rd.Dimensions, func(name string, i int) *analyticsdata.Dimension {
+			return &analyticsdata.Dimension{Name: name}
+		}),
+		Metrics: lo.Map(rd.Metrics, func(name string, i int) *analyticsdata.Metric {
+			return &analyticsdata.Metric{Name: name}
+		}),
+		Limit: lo.Ternary(limit > 0 && limit < 100000, limit, 100000),
+	}
+	if rd.DimensionFilter != nil {
+		g.JSONConvert(rd.DimensionFilter, &request.DimensionFilter)
+	}
+	if rd.MetricFilter != nil {
+		g.JSONConvert(rd.MetricFilter, &request.MetricFilter)
+	}
+
+	property := conn.GetProp("property_id")
+	if !strings.HasPrefix(property, "properties/") {
+		property = "properties/" + property
+	}
+
+	for {
+		resp, err := conn.ga4.Properties.RunReport(property, request).Context(ctx).Do()
+		if err != nil {
+			return data, g.Error(err, "could not run GA4 report")
+		}
+
+		if len(data.Columns) == 0 {
+			columns := iop.Columns{}
+			for _, header := range resp.DimensionHeaders {
+				colType := iop.StringType
+				switch header.Name {
+				case "date":
+					colType = iop.DateType
+				case "dateHour", "dateHourMinute":
+					colType = iop.DatetimeType
+				}
+				columns = append(columns, iop.Column{Name: header.Name, Type: colType, Position: len(columns) + 1})
+			}
+			for _, header := range resp.MetricHeaders {
+				colType := lo.Ternary(header.Type == "TYPE_INTEGER", iop.BigIntType, iop.DecimalType)
+				columns = append(columns, iop.Column{Name: header.Name, Type: colType, Position: len(columns) + 1})
+			}
+			data = iop.NewDataset(columns)
+		}
+
+		for _, row := range resp.Rows {
+			values := make([]any, 0, len(data.Columns))
+			for i, val := range row.DimensionValues {
+				values = append(values, ga4Value(data.Columns[i], val.Value))
+			}
+			for i, val := range row.MetricValues {
+				values = append(values, ga4Value(data.Columns[len(row.DimensionValues)+i], val.Value))
+			}
+			data.Append(values)
+		}
+
+		request.Offset += int64(len(resp.Rows))
+		if len(resp.Rows) == 0 || request.Offset >= resp.RowCount || (limit > 0 && request.Offset >= limit) {
+			break
+		}
+	}
+
+	return data, nil
+}
+
+func ga4Value(col iop.Column, value string) any {
+	switch {
+	case value == "(not set)" && col.Type != iop.StringType:
+		return nil
+	case col.Type == iop.DateType:
+		t, _ := time.Parse("20060102", value)
+		return t
+	case col.Type == iop.DatetimeType && len(value) <= 14:
+		t, _ := time.Parse("20060102150405"[:len(value)], value) // dateHour, dateHourMinute
+		return t
+	case col.Type == iop.BigIntType:
+		return cast.ToInt64(value)
+	case col.Type == iop.DecimalType:
+		return cast.ToFloat64(value)
+	}
+	return value
+}
+
+var (
+	gaqlSelectRegex = regexp.MustCompile(`(?is)^\s*select\s+(.+?)\s+from\s`)
+	gaqlLimitRegex  = regexp.MustCompile(`(?i)\slimit\s+\d+\s*$`)
+)
+
+// runAdsQuery runs a Google Ads GAQL query, with the searchStream endpoint
+func (conn *GoogleReportConn) runAdsQuery(ctx context.Context, rd ReportDefinition, startDate, endDate string, limit int64) (data iop.Dataset, err error) {
+	m := gaqlSelectRegex.FindStringSubmatch(rd.Query)
+	if m == nil {
+		return data, g.Error("report definition requires a GAQL query, e.g. select campaign.name, metrics.clicks from campaign")
+	}
+
+	// columns from the selected fields, e.g. `metrics.cost_micros` => `metrics_cost_micros`
+	fields := lo.Map(strings.Split(m[1], ","), func(f string, i int) string { return strings.TrimSpace(f) })
+	columns := iop.Columns{}
+	for _, field := range fields {
+		colType := iop.StringType
+		switch {
+		case field == "segments.date":
+			colType = iop.DateType
+		case strings.HasPrefix(field, "metrics."):
+			colType = iop.DecimalType
+		case strings.HasSuffix(field, ".id"):
+			colType = iop.BigIntType
+		}
+		columns = append(columns, iop.Column{Name: strings.ReplaceAll(field, ".", "_"), Type: colType, Position: len(columns) + 1})
+	}
+	data = iop.NewDataset(columns)
+
+	query := g.R(rd.Query, "start_date", startDate, "end_date", endDate)
+	if limit > 0 && !gaqlLimitRegex.MatchString(query) {
+		query = g.F("%s limit %d", query, limit)
+	}
+
+	baseURL := lo.Ternary(conn.GetProp("base_url") != "", conn.GetProp("base_url"), "https://googleads.googleapis.com")
+	version := lo.Ternary(conn.GetProp("api_version") != "", conn.GetProp("api_version"), "v17")
+	customerID := strings.ReplaceAll(conn.GetProp("customer_id"), "-", "")
+	url := g.F("%s/%s/customers/%s/googleAds:searchStream", strings.TrimSuffix(baseURL, "/"), version, customerID)
+
+	token, err := conn.tokens.Token()
+	if err != nil {
+		return data, g.Error(err, "could not get access token")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBufferString(g.Marshal(g.M("query", query))))
+	if err != nil {
+		return data, g.Error(err, "could not create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("developer-token", conn.GetProp("developer_token"))
+	if val := conn.GetProp("login_customer_id"); val != "" {
+		req.Header.Set("login-customer-id", strings.ReplaceAll(val, "-", ""))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return data, g.Error(err, "could not run Google Ads query")
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return data, g.Error(err, "could not read Google Ads response")
+	} else if resp.StatusCode >= 300 {
+		return data, g.Error("Google Ads returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	// the results are streamed in batches
+	batches := []struct {
+		Results []map[string]any `json:"results"`
+	}{}
+	if err = json.Unmarshal(body, &batches); err != nil {
+		return data, g.Error(err, "could not decode Google Ads response")
+	}
+
+	for _, batch := range batches {
+		for _, result := range batch.Results {
+			row := make([]any, len(fields))
+			for i, field := range fields {
+				// the response keys are camel case, e.g. `metrics.costMicros`
+				keys := lo.Map(strings.Split(field, "."), func(k string, i int) string { return snakeToCamel(k) })
+				row[i] = nestedValue(result, keys...)
+				if row[i] != nil && columns[i].Type == iop.DateType {
+					row[i] = cast.ToTime(row[i])
+				}
+			}
+			data.Append(row)
+		}
+	}
+
+	return data, nil
+}
+
+func snakeToCamel(s string) string {
+	parts := strings.Split(s, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// GetSchemas returns schemas
+func (conn *GoogleReportConn) GetSchemas() (data iop.Dataset, err error) {
+	data = iop.NewDataset(iop.NewColumnsFromFields("schema_name"))
+	data.Append([]interface{}{conn.Type.String()})
+	return data, nil
+}
+
+// GetTables returns no tables, the streams are report definitions
+func (conn *GoogleReportConn) GetTables(schema string) (data iop.Dataset, err error) {
+	data = iop.NewDataset(iop.NewColumnsFromFields("table_name"))
+	return data, nil
+}
+
+// GetSchemata returns an empty schema, the streams are report definitions
+func (conn *GoogleReportConn) GetSchemata(level SchemataLevel, schemaName string, tableNames ...string) (Schemata, error) {
+	database := conn.Type.String()
+	schema := Schema{Name: conn.Type.String(), Tables: map[string]Table{}}
+	schemata := Schemata{
+		Databases: map[string]Database{
+			strings.ToLower(database): {
+				Name:    database,
+				Schemas: map[string]Schema{strings.ToLower(schema.Name): schema},
+			},
+		},
+		conn: conn,
+	}
+	return schemata, nil
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/flarco/g"
 	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
 	"github.com/xo/dburl"
 )
 
@@ -101,8 +102,9 @@ func (conn *MySQLConn) GetURL(newURL ...string) string {
 		}
 	}
 
-	// make tls
-	if query.Get("tls") == "custom" {
+	// make tls, also with a custom CA bundle or skip verification
+	customTLS := conn.GetProp("ssl_root_cert") != "" || cast.ToBool(conn.GetProp("tls_skip_verify"))
+	if query.Get("tls") == "custom" || (query.Get("tls") == "" && customTLS) {
 		query.Set("tls", conn.GetProp("sling_conn_id"))
 	}
 
@@ -183,6 +185,10 @@ func (conn *MySQLConn) BulkExportStream(table Table) (ds *iop.Datastream, err er
 
 // BulkImportStream bulk import stream
 func (conn *MySQLConn) BulkImportStream(tableFName string, ds *iop.Datastream) (count uint64, err error) {
+	if cast.ToBool(conn.GetProp("local_infile")) {
+		return conn.LoadDataLocal(tableFName, ds)
+	}
+
 	_, err = exec.LookPath("mysql")
 	if err != nil {
 		g.Trace("mysql not found in path. Using cursor...")
@@ -312,6 +318,71 @@ func (conn *MySQLConn) LoadDataInFile(tableFName string, ds *iop.Datastream) (co
 	return ds.Count, nil
 }
 
+// LoadDataLocal imports the stream with LOAD DATA LOCAL INFILE, streamed over the
+// connection from a registered reader, without a temp file or the mysql client.
+// The server needs `local_infile=ON`, else the rows are inserted in batches.
+func (conn *MySQLConn) LoadDataLocal(tableFName string, ds *iop.Datastream) (count uint64, err error) {
+	// needs to get columns to shape stream
+	columns, err := conn.GetColumns(tableFName)
+	if err != nil {
+		err = g.Error(err, "could not get column list")
+		return
+	}
+
+	ds, err = ds.Shape(columns)
+	if err != nil {
+		err = g.Error(err, "could not shape stream")
+		return
+	}
+
+	// the reader is only requested by the server if local infile is allowed
+	requested := false
+	readerName := "sling_" + strings.ToLower(g.RandString(g.AlphaRunes, 12))
+	mysql.RegisterReaderHandler(readerName, func() io.Reader {
+		requested = true
+		sc := iop.LoaderStreamConfig(true)
+		sc.NullAs = ""
+		sc.DatetimeFormat = "2006-01-02 15:04:05.000000"
+		return ds.NewCsvReader(sc)
+	})
+	defer mysql.DeregisterReaderHandler(readerName)
+
+	_, err = conn.ExecContext(ds.Context.Ctx, conn.loadDataLocalSQL(tableFName, columns, readerName))
+	if err != nil && !requested {
+		g.Warn("could not LOAD DATA LOCAL INFILE, inserting in batches instead (is `local_infile` enabled on the server?): %s", err.Error())
+		return conn.BaseConn.InsertBatchStream(tableFName, ds)
+	} else if err != nil {
+		return ds.Count, g.Error(err, "could not load data into %s", tableFName)
+	}
+
+	return ds.Count, nil
+}
+
+// loadDataLocalSQL returns the LOAD DATA LOCAL INFILE statement of a registered reader.
+// Values are read in variables, so that empty values are loaded as null.
+func (conn *MySQLConn) loadDataLocalSQL(tableFName string, columns iop.Columns, readerName string) string {
+	vars := make([]string, len(columns))
+	sets := make([]string, len(columns))
+	for i, col := range columns {
+		vars[i] = g.F("@v%d", i+1)
+		sets[i] = g.F("%s = nullif(@v%d, '')", conn.Quote(col.Name), i+1)
+	}
+
+	return g.R(
+		`load data local infile 'Reader::{reader}' into table {table}
+		character set utf8mb4
+		fields terminated by ',' optionally enclosed by '"' escaped by ''
+		lines terminated by '\n'
+		ignore 1 lines
+		({vars})
+		set {sets}`,
+		"reader", readerName,
+		"table", tableFName,
+		"vars", strings.Join(vars, ", "),
+		"sets", strings.Join(sets, ", "),
+	)
+}
+
 // UPSERT
 // https://vladmihalcea.com/how-do-upsert-and-merge-work-in-oracle-sql-server-postgresql-and-mysql/
 // GenerateUpsertSQL generates the upsert SQL
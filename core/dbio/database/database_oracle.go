@@ -55,6 +55,10 @@ func (conn *OracleConn) Init() error {
 		conn.SetProp("allow_bulk_import", "true")
 	}
 
+	if method := conn.GetProp("bulk_method"); method != "" && !g.In(strings.ToLower(method), "sqlldr", "array") {
+		return g.Error("invalid bulk_method '%s', expected `sqlldr` or `array`", method)
+	}
+
 	instance := Connection(conn)
 	conn.BaseConn.instance = &instance
 
@@ -236,14 +240,23 @@ func (conn *OracleConn) sqlldrPath() string {
 	return "sqlldr"
 }
 
+// bulkMethod returns the bulk import method: `sqlldr` if found in path (default), else `array`
+func (conn *OracleConn) bulkMethod() string {
+	if strings.EqualFold(conn.GetProp("bulk_method"), "array") {
+		return "array"
+	} else if _, err := exec.LookPath(conn.sqlldrPath()); err != nil {
+		g.Debug("sqlldr not found in path. Using array inserts...")
+		return "array"
+	}
+	return "sqlldr"
+}
+
 // BulkImportStream bulk import stream
 func (conn *OracleConn) BulkImportStream(tableFName string, ds *iop.Datastream) (count uint64, err error) {
-	_, err = exec.LookPath(conn.sqlldrPath())
-	if err != nil {
-		g.Debug("sqlldr not found in path. Using cursor...")
-		return conn.BaseConn.InsertBatchStream(tableFName, ds)
-	} else if conn.GetProp("allow_bulk_import") != "true" {
+	if conn.GetProp("allow_bulk_import") != "true" {
 		return conn.BaseConn.InsertBatchStream(tableFName, ds)
+	} else if conn.bulkMethod() == "array" {
+		return conn.ArrayInsert(tableFName, ds)
 	}
 
 	// needs to get columns to shape stream
@@ -409,6 +422,113 @@ func (conn *OracleConn) SQLLoad(tableFName string, ds *iop.Datastream) (count ui
 	return ds.Count, err
 }
 
+// ArrayInsert imports the stream with array-bound direct-path inserts (INSERT /*+ APPEND_VALUES */).
+// Each array is committed, since a table loaded with direct-path cannot be read or
+// modified again in the same transaction.
+func (conn *OracleConn) ArrayInsert(tableFName string, ds *iop.Datastream) (count uint64, err error) {
+	var columns iop.Columns
+	var insertSQL string
+
+	arraySize := cast.ToInt(conn.GetProp("array_size"))
+	if arraySize <= 0 {
+		arraySize = 10000
+	}
+
+	mux := ds.Context.Mux
+	if df := ds.Df(); df != nil {
+		mux = df.Context.Mux
+	}
+
+	// column arrays
+	values := [][]any{}
+
+	insertArrays := func() error {
+		if len(values) == 0 || len(values[0]) == 0 {
+			return nil
+		}
+
+		args := lo.Map(values, func(colValues []any, i int) any { return colValues })
+
+		mux.Lock()
+		defer mux.Unlock()
+
+		var err error
+		if tx := conn.Tx(); tx != nil {
+			_, err = tx.ExecContext(ds.Context.Ctx, insertSQL, args...)
+			if err == nil {
+				err = conn.Commit()
+			}
+		} else {
+			_, err = conn.Db().ExecContext(ds.Context.Ctx, insertSQL, args...)
+		}
+		if err != nil {
+			return g.Error(err, "could not insert %d rows into %s", len(values[0]), tableFName)
+		}
+
+		count += cast.ToUint64(len(values[0]))
+		values = lo.Map(values, func(colValues []any, i int) []any { return colValues[:0] })
+		return nil
+	}
+
+	for batch := range ds.BatchChan {
+		if batch.ColumnsChanged() || batch.IsFirst() {
+			if err = insertArrays(); err != nil {
+				break
+			}
+
+			mux.Lock()
+			columns, err = conn.GetColumns(tableFName, batch.Columns.Names()...)
+			mux.Unlock()
+			if err != nil {
+				return count, g.Error(err, "could not get matching list of columns from table")
+			}
+
+			err = batch.Shape(columns)
+			if err != nil {
+				return count, g.Error(err, "could not shape batch stream")
+			}
+
+			placeholders := lo.Map(columns, func(col iop.Column, i int) string { return g.F(":%d", i+1) })
+			insertSQL = g.F(
+				"insert /*+ APPEND_VALUES */ into %s (%s) values (%s)",
+				tableFName,
+				strings.Join(conn.GetType().QuoteNames(columns.Names()...), ", "),
+				strings.Join(placeholders, ", "),
+			)
+			conn.LogSQL(insertSQL)
+
+			values = make([][]any, len(columns))
+		}
+
+		for row := range batch.Rows {
+			for i := range values {
+				values[i] = append(values[i], row[i])
+			}
+			if len(values[0]) == arraySize {
+				if err = insertArrays(); err != nil {
+					break
+				}
+			}
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		err = insertArrays()
+	}
+
+	if err != nil {
+		ds.Context.CaptureErr(err)
+		ds.Context.Cancel()
+		return count, g.Error(err, "could not array insert")
+	}
+
+	return count, nil
+}
+
 func (conn *OracleConn) getColumnsString(ds *iop.Datastream) string {
 	columnsString := ""
 	for _, col := range ds.Columns {
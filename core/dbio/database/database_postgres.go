@@ -2,6 +2,8 @@ package database
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"fmt"
 	"io"
 	"os/exec"
@@ -13,6 +15,7 @@ import (
 	"github.com/spf13/cast"
 
 	"github.com/flarco/g"
+	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 	"github.com/slingdata-io/sling-cli/core/dbio/iop"
 )
@@ -35,6 +38,10 @@ func (conn *PostgresConn) Init() error {
 	// also is slower than just select?
 	conn.BaseConn.SetProp("allow_bulk_export", "false")
 
+	if format := conn.GetProp("copy_format"); format != "" && !g.In(strings.ToLower(format), "text", "binary") {
+		return g.Error("invalid copy_format '%s', expected `text` or `binary`", format)
+	}
+
 	instance := Connection(conn)
 	conn.BaseConn.instance = &instance
 
@@ -96,6 +103,97 @@ func (conn *PostgresConn) GenerateDDL(table Table, data iop.Dataset, temporary b
 	return strings.TrimSpace(ddl), nil
 }
 
+// StreamRowsContext streams the rows of a sql query. With the `fetch_size` prop, a select
+// is read with a server-side cursor, `fetch_size` rows at a time, so that large tables
+// are streamed with bounded memory.
+func (conn *PostgresConn) StreamRowsContext(ctx context.Context, query string, options ...map[string]interface{}) (ds *iop.Datastream, err error) {
+	fetchSize := cast.ToInt(conn.GetProp("fetch_size"))
+	if fetchSize <= 0 || strings.Contains(query, noDebugKey) || !postgresCursorQuery(query) {
+		return conn.BaseConn.StreamRowsContext(ctx, query, options...)
+	}
+
+	err = reconnectIfClosed(conn)
+	if err != nil {
+		return ds, g.Error(err, "Could not reconnect")
+	}
+
+	start := time.Now()
+	queryContext := g.NewContext(ctx)
+
+	// cursors live in a transaction
+	var tx *sqlx.Tx
+	queryTx := func(q string) (*sqlx.Rows, error) {
+		if tx != nil {
+			return tx.QueryxContext(queryContext.Ctx, q)
+		}
+		return conn.tx.QueryContext(queryContext.Ctx, q)
+	}
+	execTx := func(q string) (err error) {
+		if tx != nil {
+			_, err = tx.ExecContext(queryContext.Ctx, q)
+		} else {
+			_, err = conn.tx.ExecContext(queryContext.Ctx, q)
+		}
+		return err
+	}
+
+	if conn.tx == nil {
+		tx, err = conn.Db().BeginTxx(queryContext.Ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			queryContext.Cancel()
+			return ds, g.Error(err, "could not begin transaction for cursor")
+		}
+	}
+
+	cursor := "sling_cursor_" + strings.ToLower(g.RandString(g.AlphaRunes, 8))
+	fetchSQL := g.F("fetch forward %d from %s", fetchSize, cursor)
+
+	conn.LogSQL(query)
+	stopTimer := conn.statementTimer(queryContext)
+	if err = execTx(g.F("declare %s no scroll cursor for %s", cursor, query)); err != nil {
+		err = stopTimer(err)
+		queryContext.Cancel()
+		return ds, g.Error(err, "SQL Error for:\n"+query)
+	}
+
+	result, err := queryTx(fetchSQL)
+	if err != nil {
+		err = stopTimer(err)
+		queryContext.Cancel()
+		return ds, g.Error(err, "could not fetch from cursor")
+	}
+
+	nextResult := func(count uint64) (*sqlx.Rows, error) {
+		if count == uint64(fetchSize) {
+			return queryTx(fetchSQL)
+		}
+
+		// last fetch
+		if err := execTx("close " + cursor); err != nil {
+			return nil, g.Error(err, "could not close cursor")
+		} else if tx != nil {
+			if err = tx.Commit(); err != nil {
+				return nil, g.Error(err, "could not commit cursor transaction")
+			}
+		}
+		return nil, nil
+	}
+
+	ds, err = conn.streamResult(queryContext, query, result, getQueryOptions(options), start, nextResult)
+	return ds, stopTimer(err)
+}
+
+// postgresCursorQuery returns true if a cursor can be declared for the query
+func postgresCursorQuery(query string) bool {
+	query = strings.ToLower(strings.TrimLeft(query, " \t\r\n("))
+	for _, prefix := range []string{"select", "with", "values", "table"} {
+		if strings.HasPrefix(query, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // BulkExportStream uses the bulk dumping (COPY)
 func (conn *PostgresConn) BulkExportStream(table Table) (ds *iop.Datastream, err error) {
 	_, err = exec.LookPath("psql")
@@ -153,6 +251,12 @@ func (conn *PostgresConn) BulkImportStream(tableFName string, ds *iop.Datastream
 		}
 	}
 
+	// COPY ... (FORMAT binary), falls back to text for unsupported column types
+	var oids []uint32
+	useBinary := conn.copyBinary()
+	binaryCopier := &pgBinaryCopier{conn: conn}
+	defer binaryCopier.close()
+
 	for batch := range ds.BatchChan {
 		if batch.ColumnsChanged() || batch.IsFirst() {
 			mux.Lock()
@@ -166,6 +270,29 @@ func (conn *PostgresConn) BulkImportStream(tableFName string, ds *iop.Datastream
 			if err != nil {
 				return count, g.Error(err, "could not shape batch stream")
 			}
+
+			if conn.copyBinary() {
+				oids, useBinary, err = binaryCopier.columnOIDs(table, columns)
+				if err != nil {
+					return count, g.Error(err, "could not prepare binary copy")
+				}
+			}
+		}
+
+		if useBinary {
+			// the rows are copied on a separate connection, which needs the table committed
+			if err = conn.Commit(); err != nil {
+				return count, g.Error(err, "could not commit transaction")
+			}
+
+			batchCount, err := binaryCopier.copyBatch(table, columns, oids, batch)
+			count += batchCount
+			if err != nil {
+				ds.Context.CaptureErr(err)
+				ds.Context.Cancel()
+				return count, g.Error(err, "could not copy data")
+			}
+			continue
 		}
 
 		err = func() error {
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+)
+
+// pgCopySignature is the header of the COPY binary format:
+// signature, flags (int32) and header extension length (int32)
+var pgCopySignature = []byte("PGCOPY\n\377\r\n\000\000\000\000\000\000\000\000\000")
+
+// copyBinary returns true when the bulk import should use COPY ... (FORMAT binary)
+func (conn *PostgresConn) copyBinary() bool {
+	return strings.EqualFold(conn.GetProp("copy_format"), "binary")
+}
+
+// pgBinaryCopier loads batches with COPY ... FROM STDIN (FORMAT binary), on a
+// separate pgx connection, since lib/pq only supports the text format
+type pgBinaryCopier struct {
+	conn    *PostgresConn
+	pgConn  *pgconn.PgConn
+	typeMap *pgtype.Map
+}
+
+func (c *pgBinaryCopier) close() {
+	if c.pgConn != nil {
+		c.pgConn.Close(c.conn.Context().Ctx)
+	}
+}
+
+// columnOIDs returns the type OIDs of the table columns, and false if a type
+// cannot be encoded in binary (e.g. custom enum or domain types)
+func (c *pgBinaryCopier) columnOIDs(table Table, columns iop.Columns) (oids []uint32, ok bool, err error) {
+	ctx := c.conn.Context().Ctx
+	if c.pgConn == nil {
+		c.pgConn, err = pgconn.Connect(ctx, c.conn.URL)
+		if err != nil {
+			return nil, false, g.Error(err, "could not connect for binary copy")
+		}
+		c.typeMap = pgtype.NewMap()
+	}
+
+	sql := g.F(
+		"select attname, atttypid from pg_attribute where attrelid = '%s'::regclass and attnum > 0 and not attisdropped",
+		strings.ReplaceAll(table.FullName(), "'", "''"),
+	)
+	result := c.pgConn.ExecParams(ctx, sql, nil, nil, nil, nil).Read()
+	if result.Err != nil {
+		return nil, false, g.Error(result.Err, "could not get column types of %s", table.FullName())
+	}
+
+	typeOIDs := map[string]uint32{}
+	for _, row := range result.Rows {
+		typeOIDs[string(row[0])] = cast.ToUint32(string(row[1]))
+	}
+
+	for _, col := range columns {
+		oid, found := typeOIDs[col.Name]
+		if !found {
+			return nil, false, g.Error("could not find column %s in %s", col.Name, table.FullName())
+		} else if _, known := c.typeMap.TypeForOID(oid); !known {
+			g.Debug("column %s (OID %d) cannot be encoded in binary, using text COPY", col.Name, oid)
+			return nil, false, nil
+		}
+		oids = append(oids, oid)
+	}
+
+	return oids, true, nil
+}
+
+// copyBatch loads the rows of a batch, and returns the number of rows copied
+func (c *pgBinaryCopier) copyBatch(table Table, columns iop.Columns, oids []uint32, batch *iop.Batch) (count uint64, err error) {
+	pr, pw := io.Pipe()
+	encodeErr := make(chan error, 1)
+
+	go func() {
+		var err error
+		defer func() {
+			pw.CloseWithError(err)
+			encodeErr <- err
+		}()
+
+		w := bufio.NewWriterSize(pw, 1024*1024)
+		buf := append([]byte{}, pgCopySignature...)
+		for row := range batch.Rows {
+			buf, err = encodePgCopyRow(buf, c.typeMap, oids, row)
+			if err != nil {
+				g.Trace("error for rec: %s", g.Pretty(batch.Columns.MakeRec(row)))
+				return
+			} else if _, err = w.Write(buf); err != nil {
+				return
+			}
+			buf = buf[:0]
+		}
+
+		// trailer
+		if _, err = w.Write(binary.BigEndian.AppendUint16(buf, 0xffff)); err == nil {
+			err = w.Flush()
+		}
+	}()
+
+	sql := g.F(
+		"copy %s (%s) from stdin with (format binary)",
+		table.FullName(), strings.Join(c.conn.GetType().QuoteNames(columns.Names()...), ", "),
+	)
+	tag, err := c.pgConn.CopyFrom(c.conn.Context().Ctx, pr, sql)
+	pr.Close()
+	if encErr := <-encodeErr; encErr != nil && err == nil {
+		return 0, g.Error(encErr, "could not encode row")
+	} else if err != nil {
+		return 0, g.Error(err, "could not COPY into table %s", table.FullName())
+	}
+
+	return uint64(tag.RowsAffected()), nil
+}
+
+// encodePgCopyRow appends a row in the COPY binary format: the number of fields
+// (int16), then for each field its length (int32, -1 for null) and its bytes.
+func encodePgCopyRow(buf []byte, m *pgtype.Map, oids []uint32, row []any) ([]byte, error) {
+	if len(row) != len(oids) {
+		return buf, g.Error("row has %d values, expected %d", len(row), len(oids))
+	}
+
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(row)))
+	for i, val := range row {
+		lenPos := len(buf)
+		buf = append(buf, 0, 0, 0, 0)
+
+		encoded, err := m.Encode(oids[i], pgtype.BinaryFormatCode, val, buf)
+		if err != nil {
+			// the value is not natively encodable (e.g. a string for a numeric),
+			// parse it with the text format of the type first
+			encoded, err = encodePgText(m, oids[i], val, buf)
+			if err != nil {
+				return buf, err
+			}
+		}
+
+		if encoded == nil {
+			buf = binary.BigEndian.AppendUint32(buf[:lenPos], 0xffffffff)
+			continue
+		}
+		buf = encoded
+		binary.BigEndian.PutUint32(buf[lenPos:], uint32(len(buf)-lenPos-4))
+	}
+
+	return buf, nil
+}
+
+// encodePgText decodes the value from the text format of the type, and encodes it in binary
+func encodePgText(m *pgtype.Map, oid uint32, val any, buf []byte) ([]byte, error) {
+	pgType, ok := m.TypeForOID(oid)
+	if !ok {
+		return nil, g.Error("unknown type OID %d", oid)
+	}
+
+	text, err := cast.ToStringE(val)
+	if err != nil {
+		return nil, g.Error(err, "could not convert %#v to %s", val, pgType.Name)
+	}
+
+	native, err := pgType.Codec.DecodeValue(m, oid, pgtype.TextFormatCode, []byte(text))
+	if err != nil {
+		return nil, g.Error(err, "could not convert %#v to %s", val, pgType.Name)
+	}
+
+	return m.Encode(oid, pgtype.BinaryFormatCode, native, buf)
+}
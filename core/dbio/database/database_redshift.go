@@ -70,16 +70,16 @@ func (conn *RedshiftConn) GenerateDDL(table Table, data iop.Dataset, temporary b
 	}
 
 	distKey := ""
-	if keyCols := data.Columns.GetKeys(iop.DistributionKey); len(keyCols) > 0 {
-		colNames := conn.GetType().QuoteNames(keyCols.Names()...)
-		distKey = g.F("distkey(%s)", strings.Join(colNames, ", "))
+	if fields := keyFields(conn, table, data.Columns, iop.DistributionKey); len(fields) == 1 && g.In(strings.ToLower(fields[0]), "all", "even", "auto") {
+		distKey = "diststyle " + strings.ToLower(fields[0])
+	} else if len(fields) > 0 {
+		distKey = g.F("distkey(%s)", strings.Join(fields, ", "))
 	}
 	sql = strings.ReplaceAll(sql, "{dist_key}", distKey)
 
 	sortKey := ""
-	if keyCols := data.Columns.GetKeys(iop.SortKey); len(keyCols) > 0 {
-		colNames := conn.GetType().QuoteNames(keyCols.Names()...)
-		sortKey = g.F("compound sortkey(%s)", strings.Join(colNames, ", "))
+	if fields := keyFields(conn, table, data.Columns, iop.SortKey); len(fields) > 0 {
+		sortKey = g.F("compound sortkey(%s)", strings.Join(fields, ", "))
 	}
 	sql = strings.ReplaceAll(sql, "{sort_key}", sortKey)
 
@@ -5,9 +5,11 @@ import (
 	"encoding/pem"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -56,6 +58,10 @@ func (conn *SnowflakeConn) Init() error {
 		conn.CopyMethod = conn.GetProp("copy_method")
 	}
 
+	if f := strings.ToLower(conn.GetProp("copy_method_format")); !g.In(f, "", "csv", "parquet") {
+		return g.Error("invalid copy_method_format (%s), must be csv or parquet", f)
+	}
+
 	if val := cast.ToInt(conn.GetProp("max_chunk_download_workers")); val > 0 {
 		gosnowflake.MaxChunkDownloadWorkers = val
 	}
@@ -112,6 +118,34 @@ func (conn *SnowflakeConn) ConnString() string {
 		connString = connString + "&authenticator=SNOWFLAKE_JWT&privateKey=" + epk
 	}
 
+	// OAuth access token, fetched for each new connection if refreshable
+	if strings.EqualFold(conn.GetProp("authenticator"), "oauth") {
+		if conn.OAuthTokenSource() != nil {
+			connString = connString + "&token=" + oauthTokenKey
+		} else if token := conn.GetProp("token"); token != "" {
+			connString = connString + "&token=" + url.QueryEscape(token)
+		}
+	}
+
+	// session parameters and query tag, as DSN params so that each session of the pool has them
+	params := map[string]any{}
+	if val := conn.GetProp("session_parameters"); val != "" {
+		if err := g.Unmarshal(val, &params); err != nil {
+			g.Warn("could not parse session_parameters: %s", g.ErrMsgSimple(err))
+		}
+	}
+	if tag := conn.GetProp("query_tag"); tag != "" {
+		params["QUERY_TAG"] = snowflakeQueryTag(tag)
+	}
+	keys := lo.Keys(params)
+	sort.Strings(keys)
+	if len(keys) > 0 && !strings.Contains(connString, "?") {
+		connString = connString + "?"
+	}
+	for _, key := range keys {
+		connString = connString + g.F("&%s=%s", strings.ToUpper(key), url.QueryEscape(cast.ToString(params[key])))
+	}
+
 	connString = strings.TrimSuffix(connString, "?")
 
 	connString = strings.ReplaceAll(
@@ -123,6 +157,14 @@ func (conn *SnowflakeConn) ConnString() string {
 	return connString
 }
 
+// snowflakeQueryTag truncates the tag to the QUERY_TAG limit of 2000 characters
+func snowflakeQueryTag(tag string) string {
+	if runes := []rune(tag); len(runes) > 2000 {
+		return string(runes[:2000])
+	}
+	return tag
+}
+
 // Connect connects to the database
 func (conn *SnowflakeConn) Connect(timeOut ...int) error {
 	err := conn.BaseConn.Connect(timeOut...)
@@ -254,6 +296,12 @@ func (conn *SnowflakeConn) BulkExportFlow(table Table) (df *iop.Dataflow, err er
 				err = g.Error(err, "Could not copy to S3.")
 				return
 			}
+		case "GCS":
+			filePath, err = conn.CopyToGCS(table)
+			if err != nil {
+				err = g.Error(err, "Could not copy to GCS.")
+				return
+			}
 		default:
 			if stage := conn.getOrCreateStage(table.Schema); stage != "" {
 				var unloaded int64
@@ -433,6 +481,68 @@ func (conn *SnowflakeConn) CopyToAzure(tables ...Table) (azPath string, err erro
 	return azPath, err
 }
 
+// CopyToGCS exports a query to a Google Cloud Storage location, via the
+// storage integration (Snowflake does not accept GCS credentials in COPY)
+func (conn *SnowflakeConn) CopyToGCS(tables ...Table) (gcsPath string, err error) {
+	gcBucket := conn.GetProp("GC_BUCKET")
+	integration := conn.GetProp("GCS_STORAGE_INTEGRATION", "STORAGE_INTEGRATION")
+	if gcBucket == "" || integration == "" {
+		err = g.Error("Need to set 'GC_BUCKET' and 'GCS_STORAGE_INTEGRATION' to copy to GCS from snowflake")
+		return
+	}
+
+	context := g.NewContext(conn.Context().Ctx)
+	unload := func(table Table, gcsPathPart string) {
+
+		defer context.Wg.Write.Done()
+
+		unloadSQL := g.R(
+			conn.template.Core["copy_to_gcs"],
+			"sql", table.Select(),
+			"gcs_path", snowflakeGCSPath(gcsPathPart),
+			"storage_integration", integration,
+		)
+		_, err = conn.Exec(unloadSQL)
+		if err != nil {
+			err = g.Error(err, fmt.Sprintf("SQL Error for %s", gcsPathPart))
+			context.CaptureErr(err)
+		}
+
+	}
+
+	gcsFs, err := filesys.NewFileSysClient(dbio.TypeFileGoogle, conn.PropArrExclude("url")...)
+	if err != nil {
+		err = g.Error(err, "Could not get fs client for GCS")
+		return
+	}
+
+	gcsPath = fmt.Sprintf("gs://%s/%s/stream/%s.csv", gcBucket, tempCloudStorageFolder, cast.ToString(g.Now()))
+
+	filesys.Delete(gcsFs, gcsPath)
+	for i, table := range tables {
+		if context.Err() != nil {
+			break
+		}
+		gcsPathPart := fmt.Sprintf("%s/u%02d-", gcsPath, i+1)
+		context.Wg.Write.Add()
+		go unload(table, gcsPathPart)
+	}
+
+	context.Wg.Write.Wait()
+	err = context.Err()
+
+	if err == nil {
+		g.Debug("Unloaded to %s", gcsPath)
+	}
+
+	return gcsPath, err
+}
+
+// snowflakeGCSPath returns the path with the `gcs://` scheme used by Snowflake
+func snowflakeGCSPath(gsPath string) string {
+	return strings.Replace(gsPath, "gs://", "gcs://", 1)
+}
+
 // BulkImportFlow bulk import flow
 func (conn *SnowflakeConn) BulkImportFlow(tableFName string, df *iop.Dataflow) (count uint64, err error) {
 	defer df.CleanUp()
@@ -467,6 +577,10 @@ func (conn *SnowflakeConn) BulkImportFlow(tableFName string, df *iop.Dataflow) (
 			return conn.CopyViaAWS(tableFName, df)
 		case "AZURE":
 			return conn.CopyViaAzure(tableFName, df)
+		case "GCS":
+			return conn.CopyViaGCS(tableFName, df)
+		case "SNOWPIPE_STREAMING", "snowpipe_streaming":
+			return conn.CopyViaSnowpipeStreaming(tableFName, df)
 		default:
 		}
 
@@ -652,6 +766,78 @@ func (conn *SnowflakeConn) CopyFromAzure(tableFName, azPath string) (err error)
 	return nil
 }
 
+// CopyViaGCS uses the Snowflake COPY INTO Table command from Google Cloud Storage
+// https://docs.snowflake.com/en/user-guide/data-load-gcs-config
+func (conn *SnowflakeConn) CopyViaGCS(tableFName string, df *iop.Dataflow) (count uint64, err error) {
+	settingMppBulkImportFlow(conn, iop.ZStandardCompressorType)
+	if conn.GetProp("GC_BUCKET") == "" {
+		err = g.Error("Need to set 'GC_BUCKET' to copy to snowflake from GCS")
+		return
+	}
+
+	gcsPath := fmt.Sprintf(
+		"gs://%s/%s/%s",
+		conn.GetProp("GC_BUCKET"),
+		tempCloudStorageFolder,
+		tableFName,
+	)
+
+	gcsFs, err := filesys.NewFileSysClient(dbio.TypeFileGoogle, conn.PropArrExclude("url")...)
+	if err != nil {
+		err = g.Error(err, "Could not get fs client for GCS")
+		return
+	}
+
+	err = filesys.Delete(gcsFs, gcsPath)
+	if err != nil {
+		return count, g.Error(err, "Could not Delete: "+gcsPath)
+	}
+
+	df.Defer(func() {
+		if !cast.ToBool(os.Getenv("SLING_KEEP_TEMP")) {
+			filesys.Delete(gcsFs, gcsPath)
+		}
+	}) // cleanup
+
+	g.Info("writing to gcs for snowflake import")
+	gcsFs.SetProp("null_as", `\N`)
+	bw, err := filesys.WriteDataflow(gcsFs, df, gcsPath)
+	if err != nil {
+		return df.Count(), g.Error(err, "Error in FileSysWriteDataflow")
+	}
+	g.DebugLow("total written: %s to %s", humanize.Bytes(cast.ToUint64(bw)), gcsPath)
+
+	return df.Count(), conn.CopyFromGCS(tableFName, gcsPath)
+}
+
+// CopyFromGCS uses the Snowflake COPY INTO Table command from Google Cloud Storage,
+// via the storage integration
+// https://docs.snowflake.com/en/sql-reference/sql/copy-into-table.html
+func (conn *SnowflakeConn) CopyFromGCS(tableFName, gcsPath string) (err error) {
+	integration := conn.GetProp("GCS_STORAGE_INTEGRATION", "STORAGE_INTEGRATION")
+	if integration == "" {
+		err = g.Error("Need to set 'GCS_STORAGE_INTEGRATION' to copy to snowflake from GCS")
+		return
+	}
+
+	sql := g.R(
+		conn.template.Core["copy_from_gcs"],
+		"table", tableFName,
+		"gcs_path", snowflakeGCSPath(gcsPath),
+		"storage_integration", integration,
+	)
+	sql = conn.setEmptyAsNull(sql)
+
+	g.Info("copying into snowflake from gcs")
+	g.Debug("url: " + gcsPath)
+	_, err = conn.Exec(sql)
+	if err != nil {
+		return g.Error(err, "SQL Error")
+	}
+
+	return nil
+}
+
 func (conn *SnowflakeConn) UnloadViaStage(tables ...Table) (filePath string, unloaded int64, err error) {
 
 	stageFolderPath := fmt.Sprintf(
@@ -769,6 +955,10 @@ func (conn *SnowflakeConn) CopyViaStage(tableFName string, df *iop.Dataflow) (co
 			return
 		}
 
+		if conn.stageParquet() {
+			fs.SetProp("format", "parquet")
+		}
+
 		config := iop.LoaderStreamConfig(true)
 		_, err = fs.WriteDataflowReady(df, folderPath, fileReadyChn, config)
 
@@ -820,26 +1010,7 @@ func (conn *SnowflakeConn) CopyViaStage(tableFName string, df *iop.Dataflow) (co
 			return
 		}
 
-		tgtColumns := make([]string, len(file.Columns))
-		for i, name := range file.Columns.Names() {
-			colName, _ := ParseColumnName(name, conn.GetType())
-			tgtColumns[i] = conn.Quote(colName)
-		}
-
-		srcColumns := make([]string, len(file.Columns))
-		for i := range file.Columns {
-			srcColumns[i] = g.F("T.$%d", i+1)
-		}
-
-		sql := g.R(
-			conn.template.Core["copy_from_stage"],
-			"table", tableFName,
-			"tgt_columns", strings.Join(tgtColumns, ", "),
-			"src_columns", strings.Join(srcColumns, ", "),
-			"stage_path", stageFilePath,
-		)
-		sql = conn.setEmptyAsNull(sql)
-
+		sql := conn.copyFromStageSQL(tableFName, file.Columns, stageFilePath)
 		_, err = conn.Exec(sql)
 		if err != nil {
 			err = g.Error(err, "Error with COPY INTO")
@@ -854,26 +1025,7 @@ func (conn *SnowflakeConn) CopyViaStage(tableFName string, df *iop.Dataflow) (co
 			return
 		}
 
-		tgtColumns := make([]string, len(df.Columns))
-		for i, name := range df.Columns.Names() {
-			colName, _ := ParseColumnName(name, conn.GetType())
-			tgtColumns[i] = conn.Quote(colName)
-		}
-
-		srcColumns := make([]string, len(df.Columns))
-		for i := range df.Columns {
-			srcColumns[i] = g.F("T.$%d", i+1)
-		}
-
-		sql := g.R(
-			conn.template.Core["copy_from_stage"],
-			"table", tableFName,
-			"tgt_columns", strings.Join(tgtColumns, ", "),
-			"src_columns", strings.Join(srcColumns, ", "),
-			"stage_path", stageFolderPath,
-		)
-		sql = conn.setEmptyAsNull(sql)
-
+		sql := conn.copyFromStageSQL(tableFName, df.Columns, stageFolderPath)
 		data, err := conn.Query(sql)
 		if err != nil {
 			err = g.Error(err, "Error with COPY INTO")
@@ -910,6 +1062,44 @@ func (conn *SnowflakeConn) CopyViaStage(tableFName string, df *iop.Dataflow) (co
 	return df.Count(), nil
 }
 
+// stageParquet returns true if the stage files are written as parquet
+// (copy_method_format), instead of CSV
+func (conn *SnowflakeConn) stageParquet() bool {
+	return strings.EqualFold(conn.GetProp("copy_method_format"), "parquet")
+}
+
+// copyFromStageSQL returns the COPY INTO statement of the staged files. Parquet
+// files are matched by column name, CSV files by position.
+func (conn *SnowflakeConn) copyFromStageSQL(tableFName string, columns iop.Columns, stagePath string) string {
+	if conn.stageParquet() {
+		return g.R(
+			conn.template.Core["copy_from_stage_parquet"],
+			"table", tableFName,
+			"stage_path", stagePath,
+		)
+	}
+
+	tgtColumns := make([]string, len(columns))
+	for i, name := range columns.Names() {
+		colName, _ := ParseColumnName(name, conn.GetType())
+		tgtColumns[i] = conn.Quote(colName)
+	}
+
+	srcColumns := make([]string, len(columns))
+	for i := range columns {
+		srcColumns[i] = g.F("T.$%d", i+1)
+	}
+
+	sql := g.R(
+		conn.template.Core["copy_from_stage"],
+		"table", tableFName,
+		"tgt_columns", strings.Join(tgtColumns, ", "),
+		"src_columns", strings.Join(srcColumns, ", "),
+		"stage_path", stagePath,
+	)
+	return conn.setEmptyAsNull(sql)
+}
+
 func (conn *SnowflakeConn) setEmptyAsNull(sql string) string {
 	if cast.ToBool(conn.GetProp("empty_as_null")) {
 		sql = strings.ReplaceAll(sql, "EMPTY_FIELD_AS_NULL = FALSE", "EMPTY_FIELD_AS_NULL = TRUE")
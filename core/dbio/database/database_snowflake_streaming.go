@@ -0,0 +1,342 @@
+package database
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/flarco/g/net"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+	"github.com/youmark/pkcs8"
+)
+
+const (
+	snowpipeStreamingMaxRows  = 10000
+	snowpipeStreamingMaxBytes = 4 * 1024 * 1024 // requests are limited to 16MB
+)
+
+// snowpipeStreamingChannel is a channel of the Snowpipe Streaming REST API,
+// appending rows to a table through its pipe
+// https://docs.snowflake.com/en/user-guide/snowpipe-streaming/snowpipe-streaming-high-performance-rest-api
+type snowpipeStreamingChannel struct {
+	conn         *SnowflakeConn
+	ingestURL    string
+	headers      map[string]string
+	path         string // databases/{db}/schemas/{schema}/pipes/{pipe}
+	name         string
+	continuation string
+}
+
+// CopyViaSnowpipeStreaming appends the rows via the Snowpipe Streaming API,
+// instead of staging files and running COPY INTO. The table's default pipe
+// (`<table>-STREAMING`) is used, unless the `streaming_pipe` prop is set.
+func (conn *SnowflakeConn) CopyViaSnowpipeStreaming(tableFName string, df *iop.Dataflow) (count uint64, err error) {
+	table, err := ParseTableName(tableFName, conn.Type)
+	if err != nil {
+		return 0, g.Error(err, "could not parse table name: "+tableFName)
+	}
+
+	database := lo.Ternary(table.Database != "", table.Database, conn.GetProp("database"))
+	schema := lo.Ternary(table.Schema != "", table.Schema, conn.GetProp("schema"))
+	if database == "" || schema == "" {
+		return 0, g.Error("database and schema are required for snowpipe streaming into %s", tableFName)
+	}
+	pipe := lo.Ternary(conn.GetProp("streaming_pipe") != "", conn.GetProp("streaming_pipe"), table.Name+"-STREAMING")
+
+	channel, err := conn.openStreamingChannel(database, schema, pipe)
+	if err != nil {
+		return 0, g.Error(err, "could not open snowpipe streaming channel for %s", tableFName)
+	}
+	defer channel.drop()
+
+	g.Info("streaming into snowflake via snowpipe streaming (pipe %s)", pipe)
+
+	var buf bytes.Buffer
+	var batchRows int
+	flush := func() error {
+		if batchRows == 0 {
+			return nil
+		}
+		err := channel.appendRows(buf.Bytes(), count)
+		buf.Reset()
+		batchRows = 0
+		return err
+	}
+
+	for ds := range df.StreamCh {
+		colNames := make([]string, len(ds.Columns))
+		for i, col := range ds.Columns {
+			colNames[i], _ = ParseColumnName(col.Name, conn.GetType())
+		}
+
+		for row := range ds.Rows() {
+			rec := make(map[string]any, len(row))
+			for i, val := range row {
+				if i < len(colNames) {
+					rec[colNames[i]] = val
+				}
+			}
+			line, err := json.Marshal(rec)
+			if err != nil {
+				df.Context.CaptureErr(g.Error(err, "could not encode row"))
+				break
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+			batchRows++
+			count++
+
+			if batchRows >= snowpipeStreamingMaxRows || buf.Len() >= snowpipeStreamingMaxBytes {
+				if err = flush(); err != nil {
+					df.Context.CaptureErr(err)
+					break
+				}
+			}
+		}
+
+		if df.Err() != nil {
+			return count, g.Error(df.Err(), "could not stream rows into %s", tableFName)
+		}
+	}
+
+	if err = flush(); err != nil {
+		return count, err
+	} else if df.Err() != nil {
+		return count, g.Error(df.Err(), "could not stream rows into %s", tableFName)
+	}
+
+	// rows are only visible once committed
+	timeout := 300 * time.Second
+	if val := cast.ToInt(conn.GetProp("streaming_commit_timeout")); val > 0 {
+		timeout = time.Duration(val) * time.Second
+	}
+	if err = channel.waitCommitted(count, timeout); err != nil {
+		return count, g.Error(err, "rows streamed into %s were not committed", tableFName)
+	}
+
+	return count, nil
+}
+
+// openStreamingChannel authenticates, and opens a new channel on the pipe
+func (conn *SnowflakeConn) openStreamingChannel(database, schema, pipe string) (channel *snowpipeStreamingChannel, err error) {
+	connURL, err := net.NewURL(conn.URL)
+	if err != nil {
+		return nil, g.Error(err, "invalid conn url")
+	}
+	account := strings.TrimSuffix(connURL.Hostname(), ".snowflakecomputing.com")
+
+	accountURL := strings.TrimSuffix(conn.GetProp("streaming_url"), "/")
+	if accountURL == "" {
+		accountURL = g.F("https://%s.snowflakecomputing.com", account)
+	}
+
+	token, tokenType, err := conn.streamingToken(account, connURL.Username())
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{
+		"Authorization":                        "Bearer " + token,
+		"X-Snowflake-Authorization-Token-Type": tokenType,
+	}
+
+	_, respBytes, err := net.ClientDo(http.MethodGet, accountURL+"/v2/streaming/hostname", nil, headers)
+	if err != nil {
+		return nil, g.Error(err, "could not get ingest host")
+	}
+	ingestHost := strings.Trim(strings.TrimSpace(string(respBytes)), `"`)
+	scheme := strings.Split(accountURL, "://")[0]
+
+	// key-pair tokens are exchanged for a token scoped to the ingest host
+	if tokenType == "KEYPAIR_JWT" {
+		body := url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"scope":      {ingestHost},
+		}
+		headers["Content-Type"] = "application/x-www-form-urlencoded"
+		_, respBytes, err = net.ClientDo(http.MethodPost, accountURL+"/oauth/token", strings.NewReader(body.Encode()), headers)
+		if err != nil {
+			return nil, g.Error(err, "could not get scoped token")
+		}
+		headers = map[string]string{"Authorization": "Bearer " + strings.TrimSpace(string(respBytes))}
+	}
+
+	channel = &snowpipeStreamingChannel{
+		conn:      conn,
+		ingestURL: scheme + "://" + ingestHost + "/v2/streaming",
+		headers:   headers,
+		path:      g.F("databases/%s/schemas/%s/pipes/%s", url.PathEscape(database), url.PathEscape(schema), url.PathEscape(pipe)),
+		name:      "SLING_" + strings.ToUpper(g.RandSuffix("", 8)),
+	}
+
+	resp, err := channel.do(http.MethodPut, channel.channelURL(), []byte("{}"))
+	if err != nil {
+		return nil, err
+	}
+	channel.continuation = cast.ToString(resp["next_continuation_token"])
+
+	return channel, nil
+}
+
+// streamingToken returns the bearer token and its type, from the private key,
+// the OAuth token or the programmatic access token of the connection
+func (conn *SnowflakeConn) streamingToken(account, user string) (token, tokenType string, err error) {
+	authenticator := strings.ToLower(conn.GetProp("authenticator"))
+	switch {
+	case conn.GetProp("private_key") != "":
+		token, err = snowflakeKeyPairJWT(account, user, conn.GetProp("private_key"), conn.GetProp("private_key_passphrase"))
+		return token, "KEYPAIR_JWT", err
+	case authenticator == "oauth" && conn.OAuthTokenSource() != nil:
+		t, err := conn.OAuthTokenSource().Token()
+		if err != nil {
+			return "", "", g.Error(err, "could not get oauth token")
+		}
+		return t.AccessToken, "OAUTH", nil
+	case authenticator == "oauth" && conn.GetProp("token") != "":
+		return conn.GetProp("token"), "OAUTH", nil
+	case authenticator == "programmatic_access_token" && conn.GetProp("token") != "":
+		return conn.GetProp("token"), "PROGRAMMATIC_ACCESS_TOKEN", nil
+	}
+	return "", "", g.Error("snowpipe streaming requires a private key, an oauth token or a programmatic access token")
+}
+
+// snowflakeKeyPairJWT returns the JWT for key-pair authentication
+// https://docs.snowflake.com/en/developer-guide/sql-api/authenticating#using-key-pair-authentication
+func snowflakeKeyPairJWT(account, user, privateKey, passphrase string) (string, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return "", g.Error("invalid private key data: no PEM block found")
+	}
+
+	key, err := pkcs8.ParsePKCS8PrivateKeyRSA(block.Bytes, []byte(passphrase))
+	if err != nil {
+		return "", g.Error(err, "could not parse RSA private key")
+	}
+
+	pubDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", g.Error(err, "could not marshal public key")
+	}
+	fingerprint := sha256.Sum256(pubDER)
+
+	// the account locator, without the region or cloud
+	account = strings.ToUpper(strings.Split(account, ".")[0])
+	qualifiedUser := account + "." + strings.ToUpper(user)
+
+	now := time.Now()
+	header, _ := json.Marshal(g.M("alg", "RS256", "typ", "JWT"))
+	claims, _ := json.Marshal(g.M(
+		"iss", qualifiedUser+".SHA256:"+base64.StdEncoding.EncodeToString(fingerprint[:]),
+		"sub", qualifiedUser,
+		"iat", now.Unix(),
+		"exp", now.Add(59*time.Minute).Unix(),
+	))
+
+	encode := base64.RawURLEncoding.EncodeToString
+	unsigned := encode(header) + "." + encode(claims)
+	hashed := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", g.Error(err, "could not sign JWT")
+	}
+
+	return unsigned + "." + encode(signature), nil
+}
+
+func (c *snowpipeStreamingChannel) channelURL() string {
+	return g.F("%s/%s/channels/%s", c.ingestURL, c.path, url.PathEscape(c.name))
+}
+
+func (c *snowpipeStreamingChannel) do(method, URL string, body []byte, contentType ...string) (resp map[string]any, err error) {
+	headers := map[string]string{"Content-Type": "application/json"}
+	if len(contentType) > 0 {
+		headers["Content-Type"] = contentType[0]
+	}
+	for k, v := range c.headers {
+		headers[k] = v
+	}
+
+	_, respBytes, err := net.ClientDo(method, URL, bytes.NewReader(body), headers, 120)
+	if err != nil {
+		return nil, g.Error(err, "snowpipe streaming request failed: %s %s", method, URL)
+	}
+
+	resp = map[string]any{}
+	if len(bytes.TrimSpace(respBytes)) > 0 {
+		if err = json.Unmarshal(respBytes, &resp); err != nil {
+			return nil, g.Error(err, "could not parse snowpipe streaming response: %s", string(respBytes))
+		}
+	}
+	return resp, nil
+}
+
+// appendRows appends a batch of NDJSON rows, the offset being the total row count
+func (c *snowpipeStreamingChannel) appendRows(rows []byte, offset uint64) error {
+	params := url.Values{
+		"continuationToken": {c.continuation},
+		"offsetToken":       {cast.ToString(offset)},
+	}
+	URL := g.F("%s/data/%s/channels/%s/rows?%s", c.ingestURL, c.path, url.PathEscape(c.name), params.Encode())
+
+	resp, err := c.do(http.MethodPost, URL, rows, "application/x-ndjson")
+	if err != nil {
+		return g.Error(err, "could not append rows")
+	}
+	c.continuation = cast.ToString(resp["next_continuation_token"])
+	return nil
+}
+
+// waitCommitted waits until the rows up to the offset are committed, and
+// returns an error if any row was rejected
+func (c *snowpipeStreamingChannel) waitCommitted(offset uint64, timeout time.Duration) error {
+	if offset == 0 {
+		return nil
+	}
+
+	body, _ := json.Marshal(g.M("channel_names", []string{c.name}))
+	deadline := time.Now().Add(timeout)
+	for {
+		resp, err := c.do(http.MethodPost, g.F("%s/%s:bulk-channel-status", c.ingestURL, c.path), body)
+		if err != nil {
+			return g.Error(err, "could not get channel status")
+		}
+
+		statuses, _ := resp["channel_statuses"].(map[string]any)
+		status, _ := statuses[c.name].(map[string]any)
+		if errCount := cast.ToInt64(status["rows_error_count"]); errCount > 0 {
+			return g.Error("%d row(s) were rejected: %s", errCount, cast.ToString(status["last_error_message"]))
+		} else if cast.ToUint64(status["committed_offset_token"]) >= offset {
+			g.Debug("snowpipe streaming committed %d rows (channel %s)", offset, c.name)
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return g.Error("timed out after %s, committed offset is %s of %d", timeout, cast.ToString(status["committed_offset_token"]), offset)
+		}
+
+		select {
+		case <-c.conn.Context().Ctx.Done():
+			return g.Error(c.conn.Context().Ctx.Err())
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// drop drops the channel, the committed rows are kept
+func (c *snowpipeStreamingChannel) drop() {
+	if _, err := c.do(http.MethodDelete, c.channelURL(), nil); err != nil {
+		g.Debug("could not drop snowpipe streaming channel %s: %s", c.name, g.ErrMsgSimple(err))
+	}
+}
@@ -253,13 +253,21 @@ func (conn *SQLiteConn) GenerateUpsertSQL(srcTable string, tgtTable string, pkFi
 		return
 	}
 
-	_, indexTable := SplitTableFullName(tgtTable)
+	table, err := ParseTableName(tgtTable, conn.GetType())
+	if err != nil {
+		err = g.Error(err, "could not parse target table name")
+		return
+	} else if table.Schema == "" {
+		table.Schema = "main"
+	}
 
 	pkFieldsQ := lo.Map(pkFields, func(f string, i int) string { return conn.Quote(f) })
 	indexSQL := g.R(
 		conn.GetTemplateValue("core.create_unique_index"),
 		"index", strings.Join(pkFields, "_")+g.RandSuffix("_", 3)+"_idx",
-		"table", indexTable,
+		"table", table.Name,
+		"schema", table.SchemaQ(),
+		"table_name", conn.Quote(table.Name),
 		"cols", strings.Join(pkFieldsQ, ", "),
 	)
 
@@ -607,6 +615,10 @@ func (conn *SQLiteConn) GetSchemata(level SchemataLevel, schemaName string, tabl
 					DbType:   dataType,
 				}
 
+				if val := rec["is_nullable"]; val != nil {
+					column.SetMetadata("nullable", cast.ToString(cast.ToBool(data.Sp.ProcessVal(val))))
+				}
+
 				table.Columns = append(table.Columns, column)
 			}
 
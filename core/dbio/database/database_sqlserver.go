@@ -219,6 +219,8 @@ func (conn *MsSQLServerConn) BulkImportFlow(tableFName string, df *iop.Dataflow)
 
 	if conn.isAzureDWH {
 		return conn.CopyViaAzure(tableFName, df)
+	} else if conn.GetProp("bulk_insert_data_source") != "" && conn.GetProp("allow_bulk_import") == "true" {
+		return conn.BulkInsertViaAzure(tableFName, df)
 	}
 
 	return conn.BaseConn.BulkImportFlow(tableFName, df)
@@ -451,6 +453,14 @@ func (conn *MsSQLServerConn) bcpPath() string {
 	return "bcp"
 }
 
+// bcpBatchSize returns the rows per batch of bcp and BULK INSERT (prop `bcp_batch_size`)
+func (conn *MsSQLServerConn) bcpBatchSize() int {
+	if val := cast.ToInt(conn.GetProp("bcp_batch_size")); val > 0 {
+		return val
+	}
+	return 50000
+}
+
 // BcpImportFile Import using bcp tool
 // https://docs.microsoft.com/en-us/sql/tools/bcp-utility?view=sql-server-ver15
 // bcp dbo.test1 in '/tmp/LargeDataset.csv' -S tcp:sqlserver.host,51433 -d master -U sa -P 'password' -c -t ',' -b 5000
@@ -484,7 +494,7 @@ func (conn *MsSQLServerConn) BcpImportFile(tableFName, filePath string) (count u
 	g.Debug("bcp version is %d", version)
 
 	// Import to Database
-	batchSize := 50000
+	batchSize := conn.bcpBatchSize()
 	password, _ := url.User.Password()
 	port := url.Port()
 	host := strings.ReplaceAll(url.Host, ":"+port, "")
@@ -519,6 +529,10 @@ func (conn *MsSQLServerConn) BcpImportFile(tableFName, filePath string) (count u
 		"-e", errPath,
 	}
 
+	if cast.ToBool(conn.GetProp("bcp_tablock")) {
+		bcpArgs = append(bcpArgs, "-h", "TABLOCK")
+	}
+
 	if bcpAuthString := conn.GetProp("bcp_auth_string"); bcpAuthString != "" {
 		bcpAuthParts := []string{}
 		err = g.Unmarshal(bcpAuthString, &bcpAuthParts)
@@ -562,7 +576,7 @@ func (conn *MsSQLServerConn) BcpImportFile(tableFName, filePath string) (count u
 
 	// build cmdStr
 	args := lo.Map(proc.Args, func(v string, i int) string {
-		if !g.In(v, "in", "-S", "-d", "-U", "-P", "-t", "-u", "-m", "-c", "-q", "-b", "-F", "-e", conn.bcpPath()) {
+		if !g.In(v, "in", "-S", "-d", "-U", "-P", "-t", "-u", "-m", "-c", "-q", "-b", "-F", "-e", "-h", conn.bcpPath()) {
 			v = strings.ReplaceAll(v, hostPort, "****")
 			if password != "" {
 				v = strings.ReplaceAll(v, password, "****")
@@ -762,6 +776,87 @@ func (conn *MsSQLServerConn) CopyFromAzure(tableFName, azPath string) (count uin
 	return 0, nil
 }
 
+// BulkInsertViaAzure stages the dataflow as CSV files in an Azure Blob container, and loads
+// them with BULK INSERT from an external data source (prop `bulk_insert_data_source`),
+// for Azure SQL targets where bcp is not available. The data source LOCATION must be
+// the container URL, with a SAS credential.
+// https://learn.microsoft.com/en-us/sql/t-sql/statements/bulk-insert-transact-sql
+func (conn *MsSQLServerConn) BulkInsertViaAzure(tableFName string, df *iop.Dataflow) (count uint64, err error) {
+	if !conn.BaseConn.credsProvided("AZURE") {
+		err = g.Error("Need to set 'AZURE_SAS_SVC_URL', 'AZURE_CONTAINER' and 'AZURE_ACCOUNT' to stage files for BULK INSERT")
+		return
+	}
+
+	conn.Commit() // cannot have transaction lock table
+
+	azPath := fmt.Sprintf(
+		"https://%s.blob.core.windows.net/%s/%s/%s",
+		conn.GetProp("AZURE_ACCOUNT"),
+		conn.GetProp("AZURE_CONTAINER"),
+		tempCloudStorageFolder,
+		g.NewTsID(env.CleanTableName(tableFName)),
+	)
+
+	azFs, err := filesys.NewFileSysClient(dbio.TypeFileAzure, conn.PropArr()...)
+	if err != nil {
+		err = g.Error(err, "Could not get fs client for Azure")
+		return
+	}
+
+	defer func() { filesys.Delete(azFs, azPath) }() // cleanup
+
+	// quoted CSV, with the datetime layout of sql server
+	sc := iop.LoaderStreamConfig(true)
+	sc.NullAs = ""
+	sc.DatetimeFormat = conn.GetTemplateValue("variable.timestamp_layout")
+
+	fileReadyChn := make(chan filesys.FileReady, 10000)
+	go func() {
+		bw, err := azFs.WriteDataflowReady(df, azPath, fileReadyChn, sc)
+		g.DebugLow("total written: %s to %s", humanize.Bytes(cast.ToUint64(bw)), azPath)
+		if err != nil {
+			df.Context.CaptureErr(g.Error(err, "Error writing dataflow to azure blob: "+azPath))
+		}
+	}()
+
+	g.Info("writing to azure container for BULK INSERT")
+
+	for file := range fileReadyChn {
+		if df.Err() != nil {
+			continue
+		}
+
+		sql := conn.bulkInsertAzureSQL(tableFName, file.Node.URI)
+		if _, err = conn.Exec(sql); err != nil {
+			df.Context.CaptureErr(g.Error(err, "could not BULK INSERT from %s", file.Node.URI))
+		}
+	}
+
+	if df.Err() != nil {
+		return df.Count(), g.Error(df.Err())
+	}
+
+	return df.Count(), nil
+}
+
+// bulkInsertAzureSQL returns the BULK INSERT statement of a staged file, with its
+// path relative to the container of the external data source
+func (conn *MsSQLServerConn) bulkInsertAzureSQL(tableFName, fileURI string) string {
+	filePath := fileURI
+	if u, err := net.NewURL(fileURI); err == nil {
+		filePath = strings.TrimPrefix(u.Path(), "/"+conn.GetProp("AZURE_CONTAINER")+"/")
+	}
+
+	return g.R(
+		conn.GetTemplateValue("core.bulk_insert_azure"),
+		"table", tableFName,
+		"file_path", filePath,
+		"data_source", conn.GetProp("bulk_insert_data_source"),
+		"batch_size", cast.ToString(conn.bcpBatchSize()),
+		"tablock", lo.Ternary(cast.ToBool(conn.GetProp("bcp_tablock")), ",\n  TABLOCK", ""),
+	)
+}
+
 // writeCsvWithoutQuotes writes a CSV file without quotes
 // It takes the following parameters:
 //   - path: the file path where the CSV will be written
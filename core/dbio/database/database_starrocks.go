@@ -124,10 +124,16 @@ func (conn *StarRocksConn) OptimizeTable(table *Table, newColumns iop.Columns, i
 		IsTemp = isTemp[0]
 	}
 
+	oldColumns := table.Columns.Clone()
 	ok, ddlParts, err := GetOptimizeTableStatements(conn, table, newColumns, IsTemp)
 	if err != nil {
 		return
 	}
+	defer func() {
+		if err == nil {
+			logColumnTypeChanges(*table, oldColumns)
+		}
+	}()
 
 	for _, ddlPart := range ddlParts {
 		for _, sql := range ParseSQLMultiStatements(ddlPart) {
@@ -2,7 +2,6 @@ package database
 
 import (
 	"context"
-	"crypto/tls"
 	"database/sql"
 	"net"
 	"net/http"
@@ -61,10 +60,13 @@ func (conn *TrinoConn) ConnString() string {
 		}
 	}
 
-	if cast.ToBool(conn.GetProp("skip_tls")) {
-
-		// register client to skip tls
-		skipTLSClient := &http.Client{
+	// custom CA bundle or skip verification
+	skipVerify := cast.ToBool(conn.GetProp("skip_tls")) || cast.ToBool(conn.GetProp("tls_skip_verify"))
+	if tlsConfig, err := dbio.TLSConfig(conn.GetProp("ssl_root_cert"), skipVerify); err != nil {
+		g.Warn("could not make tls config: %s", err.Error())
+	} else if tlsConfig != nil {
+		// register custom client
+		tlsClient := &http.Client{
 			Transport: &http.Transport{
 				Proxy: http.ProxyFromEnvironment,
 				DialContext: (&net.Dialer{
@@ -76,13 +78,12 @@ func (conn *TrinoConn) ConnString() string {
 				IdleConnTimeout:       90 * time.Second,
 				TLSHandshakeTimeout:   10 * time.Second,
 				ExpectContinueTimeout: 1 * time.Second,
-				TLSClientConfig: &tls.Config{
-					InsecureSkipVerify: true,
-				},
+				TLSClientConfig:       tlsConfig,
 			},
 		}
-		trino.RegisterCustomClient("skip_tls", skipTLSClient)
-		configMap["CustomClientName"] = "skip_tls"
+		clientName := "tls_" + conn.GetProp("sling_conn_id")
+		trino.RegisterCustomClient(clientName, tlsClient)
+		configMap["CustomClientName"] = clientName
 	}
 
 	URI := g.F(
@@ -0,0 +1,168 @@
+package database
+
+import (
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+)
+
+// KeysetOptions are the options for reading a table with keyset pagination
+type KeysetOptions struct {
+	Key       string   // the column to paginate on, must be unique and sortable
+	Fields    []string // the fields to select, must include the key
+	Where     string   // optional where clause
+	ChunkSize int      // number of rows per query. 0 means a single query
+	Offset    int      // number of rows to skip
+	Limit     int      // max number of rows to read. 0 means no limit
+}
+
+// StreamRowsKeyset streams the rows of a table ordered by the key column, using
+// keyset pagination (`where key > last_value`) instead of OFFSET, which is slow
+// on large tables. The offset is resolved with a single index-only query, then
+// rows are read in chunks of ChunkSize, into a single datastream.
+func StreamRowsKeyset(conn Connection, table Table, opts KeysetOptions) (ds *iop.Datastream, err error) {
+	if opts.Key == "" {
+		return nil, g.Error("keyset pagination requires a key column")
+	}
+
+	keyQ := conn.Quote(opts.Key)
+	fields := "*"
+	if len(opts.Fields) > 0 && strings.TrimSpace(opts.Fields[0]) != "*" {
+		fields = strings.Join(lo.Map(opts.Fields, func(f string, i int) string {
+			return conn.Quote(strings.TrimSpace(f))
+		}), ", ")
+	}
+	baseWhere := lo.Ternary(strings.TrimSpace(opts.Where) == "", "1=1", opts.Where)
+
+	// determine the starting key value
+	var lastValue any
+	if opts.Offset > 0 {
+		sql := g.R(
+			conn.GetTemplateValue("core.keyset_offset_value"),
+			"key", keyQ,
+			"table", table.FDQN(),
+			"where", baseWhere,
+			"offset", cast.ToString(opts.Offset),
+		)
+		data, err := conn.Query(sql)
+		if err != nil {
+			return nil, g.Error(err, "could not get keyset offset value")
+		} else if len(data.Rows) == 0 || data.Rows[0][0] == nil {
+			g.Debug("offset %d is beyond the rows of %s", opts.Offset, table.FullName())
+			baseWhere = "1=0" // no rows
+		} else {
+			lastValue = data.Rows[0][0]
+		}
+	}
+
+	// makeSQL returns the query for the next chunk
+	makeSQL := func(limit int) string {
+		where := baseWhere
+		if lastValue != nil {
+			where = g.F("(%s) and %s > %s", where, keyQ, keysetValue(conn, lastValue))
+		}
+		key := lo.Ternary(limit > 0, "core.keyset_select_limit", "core.keyset_select")
+		sql := g.R(
+			conn.GetTemplateValue(key),
+			"fields", fields,
+			"table", table.FDQN(),
+			"where", where,
+			"key", keyQ,
+			"limit", cast.ToString(limit),
+		)
+		g.Debug("reading keyset chunk: %s", sql)
+
+		// rows are processed by the parent stream, so that
+		// the last key value is taken before any filtering
+		return sql + noDebugKey
+	}
+
+	// nextLimit returns the limit of the next chunk
+	total := 0
+	nextLimit := func() int {
+		limit := opts.ChunkSize
+		if opts.Limit > 0 && (limit == 0 || opts.Limit-total < limit) {
+			limit = opts.Limit - total
+		}
+		return limit
+	}
+
+	chunkLimit, chunkCount := nextLimit(), 0
+	chunk, err := conn.StreamRows(makeSQL(chunkLimit), g.M("columns", table.Columns))
+	if err != nil {
+		return nil, g.Error(err, "could not stream first chunk")
+	}
+
+	keyIndex := -1
+	if col := chunk.Columns.GetColumn(opts.Key); col != nil {
+		keyIndex = col.Position - 1
+	} else {
+		chunk.Close()
+		return nil, g.Error("key column %s must be selected for keyset pagination", opts.Key)
+	}
+
+	chunkRows := chunk.Rows()
+
+	nextFunc := func(it *iop.Iterator) bool {
+		for {
+			if row, ok := <-chunkRows; ok {
+				lastValue = row[keyIndex]
+				chunkCount++
+				total++
+				it.Row = row
+				return true
+			}
+
+			if err := chunk.Err(); err != nil {
+				it.Context.CaptureErr(g.Error(err, "error reading keyset chunk"))
+				return false
+			}
+
+			// last chunk if it was not full, or limit reached
+			if chunkLimit == 0 || chunkCount < chunkLimit || (opts.Limit > 0 && total >= opts.Limit) || lastValue == nil {
+				return false
+			}
+
+			chunkLimit, chunkCount = nextLimit(), 0
+			chunk, err = conn.StreamRows(makeSQL(chunkLimit), g.M("columns", table.Columns))
+			if err != nil {
+				it.Context.CaptureErr(g.Error(err, "could not stream keyset chunk"))
+				return false
+			}
+			chunkRows = chunk.Rows()
+		}
+	}
+
+	ds = iop.NewDatastreamIt(conn.Context().Ctx, chunk.Columns, nextFunc)
+	ds.Inferred = !InferDBStream && ds.Columns.Sourced()
+	ds.SetMetadata(conn.GetProp("METADATA"))
+	conn.Base().setTransforms(ds.Columns)
+	ds.SetConfig(conn.Props())
+
+	err = ds.Start()
+	if err != nil {
+		return ds, g.Error(err, "could start keyset datastream")
+	}
+
+	return ds, nil
+}
+
+// keysetValue returns the SQL literal of a key value
+func keysetValue(conn Connection, val any) string {
+	switch v := val.(type) {
+	case nil:
+		return "null"
+	case time.Time:
+		return g.R(
+			conn.GetTemplateValue("variable.timestamp_layout_str"),
+			"value", v.Format(conn.GetTemplateValue("variable.timestamp_layout")),
+		)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return cast.ToString(v)
+	}
+	return "'" + strings.ReplaceAll(cast.ToString(val), "'", "''") + "'"
+}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/flarco/g"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// oauthTokenKey is the placeholder of the OAuth access token, which can be
+// used in the connection properties (e.g. `password: '{oauth_token}'`)
+const oauthTokenKey = "{oauth_token}"
+
+var (
+	oauthTokenSources   = map[string]oauth2.TokenSource{}
+	oauthTokenSourceMux sync.Mutex
+)
+
+// OAuthTokenSource returns the source of the OAuth access tokens of the connection,
+// from the `oauth_token_url`, `oauth_client_id`, `oauth_client_secret` and `oauth_scopes`
+// properties (client credentials flow, e.g. machine-to-machine), with `oauth_refresh_token`
+// for the refresh token flow. Tokens are refreshed when expired. Returns nil if
+// `oauth_token_url` is not provided.
+func (conn *BaseConn) OAuthTokenSource() oauth2.TokenSource {
+	tokenURL := conn.GetProp("oauth_token_url")
+	if tokenURL == "" {
+		return nil
+	}
+
+	clientID, clientSecret := conn.GetProp("oauth_client_id"), conn.GetProp("oauth_client_secret")
+	refreshToken := conn.GetProp("oauth_refresh_token")
+	scopes := strings.FieldsFunc(conn.GetProp("oauth_scopes"), func(r rune) bool { return r == ',' || r == ' ' })
+
+	// shared across the connection instances, so that tokens are reused
+	key := g.MD5(tokenURL, clientID, clientSecret, refreshToken, strings.Join(scopes, " "))
+	oauthTokenSourceMux.Lock()
+	defer oauthTokenSourceMux.Unlock()
+	if ts, ok := oauthTokenSources[key]; ok {
+		return ts
+	}
+
+	var ts oauth2.TokenSource
+	if refreshToken != "" {
+		config := &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: tokenURL},
+			Scopes:       scopes,
+		}
+		ts = config.TokenSource(context.Background(), &oauth2.Token{RefreshToken: refreshToken})
+	} else {
+		config := &clientcredentials.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			TokenURL:     tokenURL,
+			Scopes:       scopes,
+		}
+		ts = config.TokenSource(context.Background())
+	}
+
+	oauthTokenSources[key] = ts
+	return ts
+}
+
+// withOAuthToken replaces the token placeholder in the connection URL
+func withOAuthToken(connURL string, ts oauth2.TokenSource) (string, error) {
+	token, err := ts.Token()
+	if err != nil {
+		return "", g.Error(err, "could not get OAuth access token")
+	}
+
+	escaped := url.QueryEscape(token.AccessToken)
+	connURL = strings.ReplaceAll(connURL, oauthTokenKey, escaped)
+	connURL = strings.ReplaceAll(connURL, url.QueryEscape(oauthTokenKey), escaped)
+	return connURL, nil
+}
+
+// tokenConnector opens each connection with a fresh access token, so that
+// the connections opened during long runs do not use an expired token
+type tokenConnector struct {
+	driver  driver.Driver
+	connURL string
+	tokens  oauth2.TokenSource
+}
+
+// newTokenDB opens a database, which connections are opened with fresh access tokens
+func newTokenDB(driverName, connURL string, tokens oauth2.TokenSource) (db *sql.DB, err error) {
+	// obtain the driver, sql.Open does not connect
+	initial, err := withOAuthToken(connURL, tokens)
+	if err != nil {
+		return nil, err
+	}
+	tmpDB, err := sql.Open(driverName, initial)
+	if err != nil {
+		return nil, g.Error(err, "could not open %s", driverName)
+	}
+	defer tmpDB.Close()
+
+	return sql.OpenDB(&tokenConnector{driver: tmpDB.Driver(), connURL: connURL, tokens: tokens}), nil
+}
+
+func (tc *tokenConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	connURL, err := withOAuthToken(tc.connURL, tc.tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	if dc, ok := tc.driver.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(connURL)
+		if err != nil {
+			return nil, err
+		}
+		return connector.Connect(ctx)
+	}
+	return tc.driver.Open(connURL)
+}
+
+func (tc *tokenConnector) Driver() driver.Driver {
+	return tc.driver
+}
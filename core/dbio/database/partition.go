@@ -0,0 +1,67 @@
+package database
+
+import (
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+)
+
+// GeneratePartitionOverwriteSQL returns the sql to replace the target partitions
+// present in the source table. Uses the native template `core.partition_overwrite`
+// if the dialect has one, otherwise deletes the partitions and inserts the rows
+// (to be run in a transaction).
+func (conn *BaseConn) GeneratePartitionOverwriteSQL(srcTable string, tgtTable string, partitionCol string) (sql string, err error) {
+	srcColumns, err := conn.GetColumns(srcTable)
+	if err != nil {
+		return "", g.Error(err, "could not get columns for "+srcTable)
+	}
+	tgtColumns, err := conn.GetColumns(tgtTable)
+	if err != nil {
+		return "", g.Error(err, "could not get columns for "+tgtTable)
+	}
+
+	partCols, err := conn.ValidateColumnNames(tgtColumns, []string{partitionCol}, false)
+	if err != nil {
+		return "", g.Error(err, "partition column mismatch")
+	}
+	partitionCol = partCols[0].Name
+
+	// partition column last, for dynamic partition inserts
+	isPartitionCol := func(col iop.Column, i int) bool { return strings.EqualFold(col.Name, partitionCol) }
+	srcColumns = append(
+		lo.Reject(srcColumns, isPartitionCol),
+		lo.Filter(srcColumns, isPartitionCol)...,
+	)
+
+	tgtCols, err := conn.ValidateColumnNames(tgtColumns, srcColumns.Names(), true)
+	if err != nil {
+		return "", g.Error(err, "columns mismatch")
+	}
+
+	srcFields := conn.Self().CastColumnsForSelect(srcColumns, tgtColumns)
+	srcNames := lo.Map(srcColumns.Names(), func(name string, i int) string {
+		return "src." + conn.Self().Quote(name)
+	})
+
+	values := []string{
+		"src_table", srcTable,
+		"tgt_table", tgtTable,
+		"partition_col", conn.Self().Quote(partitionCol),
+		"tgt_fields", strings.Join(tgtCols.Names(), ", "),
+		"src_fields", strings.Join(srcFields, ", "),
+		"src_names", strings.Join(srcNames, ", "),
+	}
+
+	if template := conn.GetTemplateValue("core.partition_overwrite"); template != "" {
+		return g.R(template, values...), nil
+	}
+
+	sql = strings.Join([]string{
+		g.R(conn.GetTemplateValue("core.partition_delete"), values...),
+		g.R(conn.GetTemplateValue("core.insert_from_table"), values...),
+	}, ";\n")
+
+	return sql, nil
+}
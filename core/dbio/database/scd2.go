@@ -0,0 +1,159 @@
+package database
+
+import (
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+)
+
+// SCD2Options are the options of a slowly changing dimension (type 2) load
+type SCD2Options struct {
+	PrimaryKey     []string  `json:"-" yaml:"-"`
+	CompareColumns []string  `json:"compare_columns,omitempty" yaml:"compare_columns,omitempty"` // defaults to all non-key columns
+	ValidFrom      string    `json:"valid_from,omitempty" yaml:"valid_from,omitempty"`
+	ValidTo        string    `json:"valid_to,omitempty" yaml:"valid_to,omitempty"`
+	IsCurrent      string    `json:"is_current,omitempty" yaml:"is_current,omitempty"`
+	UpdateKey      string    `json:"-" yaml:"-"` // the latest row of a key in a batch is kept, by update key
+	Timestamp      time.Time `json:"-" yaml:"-"` // the valid_from value of new versions
+}
+
+// SetDefaults sets the default column names
+func (o *SCD2Options) SetDefaults() {
+	o.ValidFrom = lo.Ternary(o.ValidFrom == "", "valid_from", o.ValidFrom)
+	o.ValidTo = lo.Ternary(o.ValidTo == "", "valid_to", o.ValidTo)
+	o.IsCurrent = lo.Ternary(o.IsCurrent == "", "is_current", o.IsCurrent)
+	if o.Timestamp.IsZero() {
+		o.Timestamp = time.Now()
+	}
+}
+
+// Columns returns the version columns to add to the target table
+func (o SCD2Options) Columns() iop.Columns {
+	return iop.Columns{
+		{Name: o.ValidFrom, Type: iop.TimestampType, Sourced: true, Description: "Sling.SCD2.ValidFrom"},
+		{Name: o.ValidTo, Type: iop.TimestampType, Sourced: true, Description: "Sling.SCD2.ValidTo"},
+		{Name: o.IsCurrent, Type: iop.BoolType, Sourced: true, Description: "Sling.SCD2.IsCurrent"},
+	}
+}
+
+// GenerateSCD2SQL returns the sql to close the current target records whose compare
+// columns changed (valid_to, is_current), and to insert the new versions of changed
+// and new records (valid_from, valid_to, is_current)
+func (conn *BaseConn) GenerateSCD2SQL(srcTable string, tgtTable string, opts SCD2Options) (sql string, err error) {
+	srcColumns, err := conn.GetColumns(srcTable)
+	if err != nil {
+		return "", g.Error(err, "could not get columns for "+srcTable)
+	}
+	tgtColumns, err := conn.GetColumns(tgtTable)
+	if err != nil {
+		return "", g.Error(err, "could not get columns for "+tgtTable)
+	}
+
+	return conn.scd2SQL(srcTable, tgtTable, srcColumns, tgtColumns, opts)
+}
+
+// scd2SQL renders the scd2 templates of the dialect. Only the latest row of
+// each primary key in the source is applied, so a batch adds one version per key.
+func (conn *BaseConn) scd2SQL(srcTable, tgtTable string, srcColumns, tgtColumns iop.Columns, opts SCD2Options) (sql string, err error) {
+	opts.SetDefaults()
+
+	pkCols, err := conn.ValidateColumnNames(tgtColumns, opts.PrimaryKey, false)
+	if err != nil {
+		return "", g.Error(err, "PK columns mismatch")
+	}
+
+	versionCols, err := conn.ValidateColumnNames(tgtColumns, []string{opts.ValidFrom, opts.ValidTo, opts.IsCurrent}, false)
+	if err != nil {
+		return "", g.Error(err, "target table is missing the scd2 columns")
+	}
+
+	// source columns to insert, excluding the version columns
+	srcNames := lo.Filter(srcColumns.Names(), func(name string, i int) bool {
+		return versionCols.GetColumn(name) == nil
+	})
+	cols, err := conn.ValidateColumnNames(tgtColumns, srcNames, false)
+	if err != nil {
+		return "", g.Error(err, "columns mismatch")
+	}
+
+	compareNames := opts.CompareColumns
+	if len(compareNames) == 0 {
+		compareNames = lo.Filter(cols.Names(), func(name string, i int) bool {
+			return pkCols.GetColumn(name) == nil
+		})
+	}
+	compareCols, err := conn.ValidateColumnNames(tgtColumns, compareNames, false)
+	if err != nil {
+		return "", g.Error(err, "compare columns mismatch")
+	} else if len(compareCols) == 0 {
+		return "", g.Error("no columns to compare for scd2")
+	}
+
+	q := conn.Self().Quote
+	tgtPkEqual := lo.Map(pkCols.Names(), func(name string, i int) string {
+		return g.F("src.%s = tgt.%s", q(name), q(name))
+	})
+	curPkEqual := lo.Map(pkCols.Names(), func(name string, i int) string {
+		return g.F("src.%s = cur.%s", q(name), q(name))
+	})
+
+	// null-safe comparison of each column
+	changedConds := lo.Map(compareCols.Names(), func(name string, i int) string {
+		src, tgt := "src."+q(name), "tgt."+q(name)
+		return g.F("%s <> %s or (%s is null and %s is not null) or (%s is not null and %s is null)", src, tgt, src, tgt, src, tgt)
+	})
+
+	// latest row of each key first, defaults to the key (no preference)
+	latestOrder := strings.Join(conn.Type.QuoteNames(pkCols.Names()...), ", ")
+	if opts.UpdateKey != "" {
+		updateCols, err := conn.ValidateColumnNames(srcColumns, []string{opts.UpdateKey}, false)
+		if err != nil {
+			return "", g.Error(err, "update key not found")
+		}
+		latestOrder = q(updateCols[0].Name) + " desc"
+	}
+
+	// boolean literals, some targets store booleans as strings
+	boolTrue, boolFalse := "true", "false"
+	if nativeType, _ := conn.Self().GetNativeType(iop.Column{Type: iop.BoolType}); !strings.Contains(strings.ToLower(nativeType), "bool") {
+		boolTrue, boolFalse = "'true'", "'false'"
+	}
+
+	timestamp := g.R(
+		conn.GetTemplateValue("variable.timestamp_layout_str"),
+		"value", opts.Timestamp.Format(conn.GetTemplateValue("variable.timestamp_layout")),
+	)
+
+	insertFields := strings.Join(conn.Type.QuoteNames(cols.Names()...), ", ")
+	srcLatest := g.F(
+		"(select %s from (select %s, row_number() over (partition by %s order by %s) as sling_scd2_rn from %s) latest where sling_scd2_rn = 1)",
+		insertFields, insertFields, strings.Join(conn.Type.QuoteNames(pkCols.Names()...), ", "), latestOrder, srcTable,
+	)
+
+	values := []string{
+		"src_latest", srcLatest,
+		"src_table", srcTable,
+		"tgt_table", tgtTable,
+		"src_tgt_pk_equal", strings.Join(tgtPkEqual, " and "),
+		"src_cur_pk_equal", strings.Join(curPkEqual, " and "),
+		"changed_cond", strings.Join(changedConds, " or "),
+		"insert_fields", insertFields,
+		"src_fields", strings.Join(lo.Map(cols.Names(), func(name string, i int) string { return "src." + q(name) }), ", "),
+		"valid_from", q(versionCols[0].Name),
+		"valid_to", q(versionCols[1].Name),
+		"is_current", q(versionCols[2].Name),
+		"timestamp", timestamp,
+		"true", boolTrue,
+		"false", boolFalse,
+	}
+
+	sql = strings.Join([]string{
+		g.R(conn.GetTemplateValue("core.scd2_close"), values...),
+		g.R(conn.GetTemplateValue("core.scd2_insert"), values...),
+	}, ";\n")
+
+	return sql, nil
+}
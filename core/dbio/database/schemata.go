@@ -106,6 +106,15 @@ func (t *Table) SetKeys(sourcePKCols []string, updateCol string, tableKeys Table
 	return eG.Err()
 }
 
+// keyFields returns the fields of a table key: the table_keys values as is
+// (allowing SQL expressions), or else the quoted names of the key columns
+func keyFields(conn Connection, table Table, columns iop.Columns, keyType iop.KeyType) []string {
+	if keys, ok := table.Keys[keyType]; ok {
+		return keys
+	}
+	return conn.GetType().QuoteNames(columns.GetKeys(keyType).Names()...)
+}
+
 func (t *Table) FullName() string {
 	q := GetQualifierQuote(t.Dialect)
 
@@ -204,7 +213,8 @@ func (t *Table) Select(Opts ...SelectOptions) (sql string) {
 	switch t.Dialect {
 	case dbio.TypeDbPrometheus:
 		return t.SQL
-	case dbio.TypeDbMongoDB, dbio.TypeDbElasticsearch:
+	case dbio.TypeDbMongoDB, dbio.TypeDbElasticsearch, dbio.TypeApiHubSpot, dbio.TypeApiStripe, dbio.TypeApiShopify,
+		dbio.TypeApiGA4, dbio.TypeApiGoogleAds, dbio.TypeApiJira, dbio.TypeApiGitHub:
 		m, _ := g.UnmarshalMap(t.SQL)
 		if m == nil {
 			m = g.M()
@@ -792,7 +802,8 @@ func GetQualifierQuote(dialect dbio.Type) string {
 	switch dialect {
 	case dbio.TypeDbMySQL, dbio.TypeDbMariaDB, dbio.TypeDbStarRocks, dbio.TypeDbBigQuery, dbio.TypeDbClickhouse, dbio.TypeDbProton:
 		quote = "`"
-	case dbio.TypeDbBigTable, dbio.TypeDbMongoDB, dbio.TypeDbPrometheus:
+	case dbio.TypeDbBigTable, dbio.TypeDbMongoDB, dbio.TypeDbPrometheus, dbio.TypeApiHubSpot, dbio.TypeApiStripe, dbio.TypeApiShopify,
+		dbio.TypeApiGA4, dbio.TypeApiGoogleAds, dbio.TypeApiJira, dbio.TypeApiGitHub:
 		quote = ""
 	}
 	return quote
@@ -959,11 +970,19 @@ func (ti *TableIndex) CreateDDL() string {
 	dialect := ti.Table.Dialect
 	quotedNames := dialect.QuoteNames(ti.Columns.Names()...)
 
+	// sqlite requires the schema to prefix the index name
+	schema := ti.Table.SchemaQ()
+	if dialect == dbio.TypeDbSQLite && ti.Table.Schema == "" {
+		schema = dialect.Quote("main")
+	}
+
 	if ti.Unique {
 		return g.R(
 			dialect.GetTemplateValue("core.create_unique_index"),
 			"index", dialect.Quote(ti.Name),
 			"table", ti.Table.FDQN(),
+			"schema", schema,
+			"table_name", dialect.Quote(ti.Table.Name),
 			"cols", strings.Join(quotedNames, ", "),
 		)
 	}
@@ -972,6 +991,8 @@ func (ti *TableIndex) CreateDDL() string {
 		dialect.GetTemplateValue("core.create_index"),
 		"index", dialect.Quote(ti.Name),
 		"table", ti.Table.FDQN(),
+		"schema", schema,
+		"table_name", dialect.Quote(ti.Table.Name),
 		"cols", strings.Join(quotedNames, ", "),
 	)
 }
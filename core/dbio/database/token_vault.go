@@ -0,0 +1,128 @@
+package database
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+)
+
+// TableTokenVault is a token vault stored in a database table
+type TableTokenVault struct {
+	GetConn   func() (Connection, error)
+	TableName string
+
+	conn  Connection
+	table Table
+	mux   sync.Mutex
+}
+
+// NewTableTokenVault returns a token vault stored in the provided table.
+// The connection is obtained lazily, on first use.
+func NewTableTokenVault(tableName string, getConn func() (Connection, error)) *TableTokenVault {
+	return &TableTokenVault{GetConn: getConn, TableName: tableName}
+}
+
+// init connects and creates the vault table if needed
+func (v *TableTokenVault) init() (err error) {
+	if v.conn != nil {
+		return nil
+	}
+
+	conn, err := v.GetConn()
+	if err != nil {
+		return g.Error(err, "could not get connection for token vault")
+	}
+
+	v.table, err = ParseTableName(v.TableName, conn.GetType())
+	if err != nil {
+		return g.Error(err, "could not parse token vault table name")
+	}
+
+	exists, err := TableExists(conn, v.table.FullName())
+	if err != nil {
+		return g.Error(err, "could not check token vault table")
+	}
+
+	if !exists {
+		data := iop.NewDataset(iop.Columns{
+			{Name: "token", Type: iop.StringType, Position: 1, Stats: iop.ColumnStats{MaxLen: 36}},
+			{Name: "value", Type: iop.TextType, Position: 2},
+		})
+		v.table.Columns = data.Columns
+		ddl, err := conn.GenerateDDL(v.table, data, false)
+		if err != nil {
+			return g.Error(err, "could not generate DDL for token vault table")
+		}
+
+		if _, err = conn.ExecMulti(ddl); err != nil {
+			return g.Error(err, "could not create token vault table")
+		}
+	}
+
+	v.conn = conn
+	return nil
+}
+
+func (v *TableTokenVault) quoteValue(val string) string {
+	return "'" + strings.ReplaceAll(val, "'", "''") + "'"
+}
+
+// SetTokens inserts the tokens that do not exist yet
+func (v *TableTokenVault) SetTokens(tokenValues map[string]string) (err error) {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+
+	if err = v.init(); err != nil {
+		return err
+	}
+
+	for token, value := range tokenValues {
+		sql := g.F(
+			"select count(1) from %s where %s = %s",
+			v.table.FullName(), v.conn.Quote("token"), v.quoteValue(token),
+		)
+		data, err := v.conn.Query(sql)
+		if err != nil {
+			return g.Error(err, "could not check token in vault")
+		} else if len(data.Rows) > 0 && cast.ToInt(data.Rows[0][0]) > 0 {
+			continue
+		}
+
+		sql = g.F(
+			"insert into %s (%s, %s) values (%s, %s)",
+			v.table.FullName(), v.conn.Quote("token"), v.conn.Quote("value"),
+			v.quoteValue(token), v.quoteValue(value),
+		)
+		if _, err = v.conn.Exec(sql); err != nil {
+			return g.Error(err, "could not insert token in vault")
+		}
+	}
+
+	return nil
+}
+
+// GetValue returns the original value of the token
+func (v *TableTokenVault) GetValue(token string) (value string, found bool, err error) {
+	v.mux.Lock()
+	defer v.mux.Unlock()
+
+	if err = v.init(); err != nil {
+		return "", false, err
+	}
+
+	sql := g.F(
+		"select %s from %s where %s = %s",
+		v.conn.Quote("value"), v.table.FullName(), v.conn.Quote("token"), v.quoteValue(token),
+	)
+	data, err := v.conn.Query(sql)
+	if err != nil {
+		return "", false, g.Error(err, "could not get token from vault")
+	} else if len(data.Rows) == 0 {
+		return "", false, nil
+	}
+
+	return cast.ToString(data.Rows[0][0]), true, nil
+}
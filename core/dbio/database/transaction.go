@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/flarco/g"
 	"github.com/jmoiron/sqlx"
@@ -110,7 +111,9 @@ func (t *BaseTransaction) Exec(sql string, args ...interface{}) (result sql.Resu
 func (t *BaseTransaction) QueryContext(ctx context.Context, q string, args ...interface{}) (result *sqlx.Rows, err error) {
 	t.log = append(t.log, q)
 
+	start := time.Now()
 	result, err = t.Tx.QueryxContext(ctx, q, args...)
+	t.Conn.Base().logSQLResult(q, args, start, nil, err)
 	if err != nil {
 		err = g.Error(err, "Error executing query")
 	}
@@ -127,7 +130,9 @@ func (t *BaseTransaction) ExecContext(ctx context.Context, q string, args ...int
 	t.Conn.Base().LogSQL(q, args...)
 
 	t.log = append(t.log, q)
+	start := time.Now()
 	result, err = t.Tx.ExecContext(ctx, q, args...)
+	t.Conn.Base().logSQLResult(q, args, start, result, err)
 	if err != nil {
 		if strings.Contains(q, noDebugKey) && !g.IsDebugLow() {
 			err = g.Error(err, "Error executing query")
@@ -396,6 +401,7 @@ func InsertBatchStream(conn Connection, tx Transaction, tableFName string, ds *i
 			batchSize = 50
 		} else {
 			batchSize = cast.ToInt(conn.GetTemplateValue("variable.batch_values")) / len(columns)
+			batchSize = int(ds.AdaptiveLimit(int64(batchSize)))
 		}
 
 		for row := range batch.Rows {
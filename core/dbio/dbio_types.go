@@ -47,6 +47,7 @@ const (
 	TypeFileFtp    Type = "ftp"
 	TypeFileSftp   Type = "sftp"
 	TypeFileHTTP   Type = "http"
+	TypeFileImap   Type = "imap"
 
 	TypeDbPostgres      Type = "postgres"
 	TypeDbRedshift      Type = "redshift"
@@ -70,6 +71,14 @@ const (
 	TypeDbElasticsearch Type = "elasticsearch"
 	TypeDbPrometheus    Type = "prometheus"
 	TypeDbProton        Type = "proton"
+
+	TypeApiHubSpot   Type = "hubspot"
+	TypeApiStripe    Type = "stripe"
+	TypeApiShopify   Type = "shopify"
+	TypeApiGA4       Type = "ga4"
+	TypeApiGoogleAds Type = "googleads"
+	TypeApiJira      Type = "jira"
+	TypeApiGitHub    Type = "github"
 )
 
 var AllType = []struct {
@@ -85,6 +94,7 @@ var AllType = []struct {
 	{TypeFileFtp, "TypeFileFtp"},
 	{TypeFileSftp, "TypeFileSftp"},
 	{TypeFileHTTP, "TypeFileHTTP"},
+	{TypeFileImap, "TypeFileImap"},
 	{TypeDbPostgres, "TypeDbPostgres"},
 	{TypeDbRedshift, "TypeDbRedshift"},
 	{TypeDbStarRocks, "TypeDbStarRocks"},
@@ -107,6 +117,13 @@ var AllType = []struct {
 	{TypeDbMongoDB, "TypeDbMongoDB"},
 	{TypeDbPrometheus, "TypeDbPrometheus"},
 	{TypeDbProton, "TypeDbProton"},
+	{TypeApiHubSpot, "TypeApiHubSpot"},
+	{TypeApiStripe, "TypeApiStripe"},
+	{TypeApiShopify, "TypeApiShopify"},
+	{TypeApiGA4, "TypeApiGA4"},
+	{TypeApiGoogleAds, "TypeApiGoogleAds"},
+	{TypeApiJira, "TypeApiJira"},
+	{TypeApiGitHub, "TypeApiGitHub"},
 }
 
 // ValidateType returns true is type is valid
@@ -125,8 +142,9 @@ func ValidateType(tStr string) (Type, bool) {
 
 	switch t {
 	case
-		TypeFileLocal, TypeFileS3, TypeFileAzure, TypeFileGoogle, TypeFileSftp, TypeFileFtp,
-		TypeDbPostgres, TypeDbRedshift, TypeDbStarRocks, TypeDbMySQL, TypeDbMariaDB, TypeDbOracle, TypeDbBigQuery, TypeDbSnowflake, TypeDbSQLite, TypeDbD1, TypeDbSQLServer, TypeDbAzure, TypeDbAzureDWH, TypeDbDuckDb, TypeDbMotherDuck, TypeDbClickhouse, TypeDbTrino, TypeDbMongoDB, TypeDbElasticsearch, TypeDbPrometheus:
+		TypeFileLocal, TypeFileS3, TypeFileAzure, TypeFileGoogle, TypeFileSftp, TypeFileFtp, TypeFileImap,
+		TypeDbPostgres, TypeDbRedshift, TypeDbStarRocks, TypeDbMySQL, TypeDbMariaDB, TypeDbOracle, TypeDbBigQuery, TypeDbSnowflake, TypeDbSQLite, TypeDbD1, TypeDbSQLServer, TypeDbAzure, TypeDbAzureDWH, TypeDbDuckDb, TypeDbMotherDuck, TypeDbClickhouse, TypeDbTrino, TypeDbMongoDB, TypeDbElasticsearch, TypeDbPrometheus,
+		TypeApiHubSpot, TypeApiStripe, TypeApiShopify, TypeApiGA4, TypeApiGoogleAds, TypeApiJira, TypeApiGitHub:
 		return t, true
 	}
 
@@ -157,6 +175,7 @@ func (t Type) DefPort() int {
 		TypeDbProton:        8463,
 		TypeFileFtp:         21,
 		TypeFileSftp:        22,
+		TypeFileImap:        993,
 	}
 	return connTypesDefPort[t]
 }
@@ -170,9 +189,10 @@ func (t Type) DBNameUpperCase() bool {
 func (t Type) Kind() Kind {
 	switch t {
 	case TypeDbPostgres, TypeDbRedshift, TypeDbStarRocks, TypeDbMySQL, TypeDbMariaDB, TypeDbOracle, TypeDbBigQuery, TypeDbBigTable,
-		TypeDbSnowflake, TypeDbSQLite, TypeDbD1, TypeDbSQLServer, TypeDbAzure, TypeDbClickhouse, TypeDbTrino, TypeDbDuckDb, TypeDbMotherDuck, TypeDbMongoDB, TypeDbElasticsearch, TypeDbPrometheus, TypeDbProton:
+		TypeDbSnowflake, TypeDbSQLite, TypeDbD1, TypeDbSQLServer, TypeDbAzure, TypeDbClickhouse, TypeDbTrino, TypeDbDuckDb, TypeDbMotherDuck, TypeDbMongoDB, TypeDbElasticsearch, TypeDbPrometheus, TypeDbProton,
+		TypeApiHubSpot, TypeApiStripe, TypeApiShopify, TypeApiGA4, TypeApiGoogleAds, TypeApiJira, TypeApiGitHub: // api objects are read as tables
 		return KindDatabase
-	case TypeFileLocal, TypeFileHDFS, TypeFileS3, TypeFileAzure, TypeFileGoogle, TypeFileSftp, TypeFileFtp, TypeFileHTTP, Type("https"):
+	case TypeFileLocal, TypeFileHDFS, TypeFileS3, TypeFileAzure, TypeFileGoogle, TypeFileSftp, TypeFileFtp, TypeFileHTTP, Type("https"), TypeFileImap:
 		return KindFile
 	}
 	return KindUnknown
@@ -210,6 +230,7 @@ func (t Type) NameLong() string {
 		TypeFileFtp:         "FileSys - Ftp",
 		TypeFileHTTP:        "FileSys - HTTP",
 		Type("https"):       "FileSys - HTTP",
+		TypeFileImap:        "FileSys - IMAP",
 		TypeDbPostgres:      "DB - PostgreSQL",
 		TypeDbRedshift:      "DB - Redshift",
 		TypeDbStarRocks:     "DB - StarRocks",
@@ -228,6 +249,13 @@ func (t Type) NameLong() string {
 		TypeDbTrino:         "DB - Trino",
 		TypeDbClickhouse:    "DB - Clickhouse",
 		TypeDbPrometheus:    "DB - Prometheus",
+		TypeApiHubSpot:      "API - HubSpot",
+		TypeApiStripe:       "API - Stripe",
+		TypeApiShopify:      "API - Shopify",
+		TypeApiGA4:          "API - Google Analytics 4",
+		TypeApiGoogleAds:    "API - Google Ads",
+		TypeApiJira:         "API - Jira",
+		TypeApiGitHub:       "API - GitHub",
 		TypeDbElasticsearch: "DB - Elasticsearch",
 		TypeDbMongoDB:       "DB - MongoDB",
 		TypeDbProton:        "DB - Proton",
@@ -248,6 +276,7 @@ func (t Type) Name() string {
 		TypeFileFtp:         "Ftp",
 		TypeFileHTTP:        "HTTP",
 		Type("https"):       "HTTP",
+		TypeFileImap:        "IMAP",
 		TypeDbPostgres:      "PostgreSQL",
 		TypeDbRedshift:      "Redshift",
 		TypeDbStarRocks:     "StarRocks",
@@ -265,6 +294,13 @@ func (t Type) Name() string {
 		TypeDbTrino:         "Trino",
 		TypeDbClickhouse:    "Clickhouse",
 		TypeDbPrometheus:    "Prometheus",
+		TypeApiHubSpot:      "HubSpot",
+		TypeApiStripe:       "Stripe",
+		TypeApiShopify:      "Shopify",
+		TypeApiGA4:          "Google Analytics 4",
+		TypeApiGoogleAds:    "Google Ads",
+		TypeApiJira:         "Jira",
+		TypeApiGitHub:       "GitHub",
 		TypeDbElasticsearch: "Elasticsearch",
 		TypeDbMongoDB:       "MongoDB",
 		TypeDbAzure:         "Azure",
@@ -546,11 +582,13 @@ const (
 	FileTypeJson      FileType = "json"
 	FileTypeParquet   FileType = "parquet"
 	FileTypeAvro      FileType = "avro"
+	FileTypeArrow     FileType = "arrow"
 	FileTypeSAS       FileType = "sas7bdat"
 	FileTypeJsonLines FileType = "jsonlines"
 	FileTypeIceberg   FileType = "iceberg"
 	FileTypeDelta     FileType = "delta"
 	FileTypeRaw       FileType = "raw"
+	FileTypeLog       FileType = "log"
 )
 
 var AllFileType = []struct {
@@ -564,11 +602,13 @@ var AllFileType = []struct {
 	{FileTypeJson, "FileTypeJson"},
 	{FileTypeParquet, "FileTypeParquet"},
 	{FileTypeAvro, "FileTypeAvro"},
+	{FileTypeArrow, "FileTypeArrow"},
 	{FileTypeSAS, "FileTypeSAS"},
 	{FileTypeJsonLines, "FileTypeJsonLines"},
 	{FileTypeIceberg, "FileTypeIceberg"},
 	{FileTypeDelta, "FileTypeDelta"},
 	{FileTypeRaw, "FileTypeRaw"},
+	{FileTypeLog, "FileTypeLog"},
 }
 
 func (ft FileType) Ext() string {
@@ -2,7 +2,11 @@ package filesys
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
@@ -90,6 +94,9 @@ func NewFileSysClientContext(ctx context.Context, fst dbio.Type, props ...string
 		concurrencyLimit = 1 // can only write 1 file at a time
 	case dbio.TypeFileSftp:
 		fsClient = &SftpFileSysClient{}
+	case dbio.TypeFileImap:
+		fsClient = &ImapFileSysClient{}
+		concurrencyLimit = 1 // single imap session
 	// case HDFSFileSys:
 	// 	fsClient = fsClient
 	case dbio.TypeFileAzure:
@@ -155,6 +162,9 @@ func NewFileSysClientFromURLContext(ctx context.Context, url string, props ...st
 	case strings.HasPrefix(url, "sftp://"):
 		props = append(props, "URL="+url)
 		return NewFileSysClientContext(ctx, dbio.TypeFileSftp, props...)
+	case strings.HasPrefix(url, "imap://"):
+		props = append(props, "URL="+url)
+		return NewFileSysClientContext(ctx, dbio.TypeFileImap, props...)
 	case strings.HasPrefix(url, "gs://"):
 		props = append(props, "URL="+url)
 		return NewFileSysClientContext(ctx, dbio.TypeFileGoogle, props...)
@@ -225,7 +235,7 @@ func NormalizeURI(fs FileSysClient, uri string) string {
 			path = strings.TrimPrefix(path, "/")
 		}
 		return fs.Prefix("/") + path
-	case dbio.TypeFileFtp:
+	case dbio.TypeFileFtp, dbio.TypeFileImap:
 		path := strings.TrimPrefix(uri, fs.FsType().String()+"://")
 		u, err := net.NewURL(uri)
 		if strings.Contains(uri, "://") && err == nil {
@@ -494,10 +504,16 @@ func (fs *BaseFileSysClient) GetDatastream(uri string, cfg ...iop.FileStreamConf
 			err = ds.ConsumeParquetReader(reader)
 		case dbio.FileTypeAvro:
 			err = ds.ConsumeAvroReader(reader)
+		case dbio.FileTypeArrow:
+			err = ds.ConsumeArrowReader(reader)
 		case dbio.FileTypeSAS:
 			err = ds.ConsumeSASReader(reader)
 		case dbio.FileTypeExcel:
 			err = ds.ConsumeExcelReader(reader, fs.properties)
+		case dbio.FileTypeLog:
+			if reader, err = tailReader(fs, uri, reader); err == nil {
+				err = ds.ConsumeLogReader(reader)
+			}
 		case dbio.FileTypeCsv:
 			err = ds.ConsumeCsvReader(reader)
 		default:
@@ -576,6 +592,7 @@ func (fs *BaseFileSysClient) ReadDataflow(url string, cfg ...iop.FileStreamConfi
 			err = g.Error(err, "Error getting paths")
 			return
 		}
+		nodes = nodes.Selected(Cfg.FileSelect)
 	}
 
 	if Cfg.Format == dbio.FileTypeNone {
@@ -621,7 +638,12 @@ func (fs *BaseFileSysClient) ReadDataflow(url string, cfg ...iop.FileStreamConfi
 
 // WriteDataflow writes a dataflow to a file sys.
 func WriteDataflow(fs FileSysClient, df *iop.Dataflow, url string) (bw int64, err error) {
+	bw, _, err = WriteDataflowFiles(fs, df, url)
+	return
+}
 
+// WriteDataflowFiles writes the dataflow like WriteDataflow, and returns the written files
+func WriteDataflowFiles(fs FileSysClient, df *iop.Dataflow, url string) (bw int64, files []FileReady, err error) {
 	// if ignore_existing is specified, check if files exists.
 	// if exists, then don't delete / overwrite
 	if cast.ToBool(fs.GetProp("ignore_existing")) {
@@ -641,23 +663,144 @@ func WriteDataflow(fs FileSysClient, df *iop.Dataflow, url string) (bw int64, er
 				ds.Close()
 			}
 			df.Close() // close dataflow
-			return 0, nil
+			return 0, nil, nil
+		}
+	}
+
+	// write into a temporary location, published once all files are written
+	if cast.ToBool(fs.GetProp("atomic")) {
+		return writeDataflowAtomic(fs, df, url)
+	}
+
+	return writeDataflowFiles(fs, df, url)
+}
+
+// writeDataflowAtomic writes the dataflow into a temporary folder next to the
+// url (`_sling_tmp_*`), then renames the files into the url once all are written,
+// so that partially written files are never visible. The existing files of the
+// url are deleted only after all files are published.
+func writeDataflowAtomic(fs FileSysClient, df *iop.Dataflow, url string) (bw int64, files []FileReady, err error) {
+	url = strings.TrimSuffix(NormalizeURI(fs, url), "/")
+
+	// the base is the file or folder, without the partitioning notation (*)
+	base, suffix := url, ""
+	if i := strings.Index(url, "/*"); i > -1 {
+		base, suffix = url[:i], url[i:]
+	}
+	i := strings.LastIndex(base, "/")
+	tempFolder := base[:i+1] + g.RandSuffix("_sling_tmp_", 6)
+	tempBase := tempFolder + "/" + base[i+1:]
+
+	defer func() {
+		if errD := Delete(fs, tempFolder); errD != nil {
+			g.Warn("could not delete temporary folder %s: %s", tempFolder, errD.Error())
+		}
+	}()
+
+	bw, files, err = writeDataflowFiles(fs, df, tempBase+suffix)
+	if err != nil {
+		return bw, files, err
+	}
+
+	// the existing files, to delete once replaced
+	existing, err := fs.ListRecursive(base)
+	if err != nil && !g.In(true, strings.Contains(err.Error(), "exist"), strings.Contains(err.Error(), "no such file"), strings.Contains(err.Error(), "not found")) {
+		return bw, files, g.Error(err, "could not list %s", base)
+	}
+
+	published := map[string]bool{}
+	for i, file := range files {
+		uri := base + strings.TrimPrefix(file.Node.URI, tempBase)
+		if err = Rename(fs, file.Node.URI, uri); err != nil {
+			return bw, files, g.Error(err, "could not publish %s (%d of %d files published)", uri, i, len(files))
+		}
+		files[i].Node.URI = uri
+		published[uri] = true
+	}
+	g.Debug("published %d files from %s", len(files), tempBase)
+
+	for _, node := range existing.Files() {
+		uri := strings.TrimSuffix(node.URI, "/")
+		if published[uri] || (uri != base && !strings.HasPrefix(uri, base+"/")) {
+			continue // replaced, or another file sharing the prefix
+		} else if isPrefixOf(uri, published) {
+			continue // a prefixed delete would remove a published file
+		}
+		if err = Delete(fs, uri); err != nil {
+			return bw, files, g.Error(err, "could not delete replaced file %s", uri)
+		}
+	}
+
+	return bw, files, nil
+}
+
+// isPrefixOf returns true if uri is a prefix of one of the uris
+func isPrefixOf(uri string, uris map[string]bool) bool {
+	for u := range uris {
+		if strings.HasPrefix(u, uri) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rename moves a file, natively for local and s3, otherwise
+// by copying it and deleting the original
+func Rename(fs FileSysClient, fromURI, toURI string) (err error) {
+	switch client := fs.Self().(type) {
+	case *LocalFileSysClient:
+		fromPath, _ := client.GetPath(fromURI)
+		toPath, _ := client.GetPath(toURI)
+		if err = os.MkdirAll(filepath.Dir(toPath), 0755); err != nil {
+			return g.Error(err, "could not create folder for %s", toPath)
+		}
+		if err = os.Rename(fromPath, toPath); err != nil {
+			return g.Error(err, "could not rename %s to %s", fromPath, toPath)
 		}
+		return nil
+	case *S3FileSysClient:
+		if err = client.copyObject(fromURI, toURI); err == nil {
+			return Delete(fs, fromURI)
+		}
+		g.Debug("could not copy object %s, streaming instead: %s", fromURI, err.Error())
+	}
+
+	reader, err := fs.Self().GetReader(fromURI)
+	if err != nil {
+		return g.Error(err, "could not read %s", fromURI)
+	}
+
+	_, err = fs.Self().Write(toURI, reader)
+	if closer, ok := reader.(io.Closer); ok {
+		closer.Close()
 	}
+	if err != nil {
+		return g.Error(err, "could not write %s", toURI)
+	}
+
+	return Delete(fs, fromURI)
+}
 
+// writeDataflowFiles writes the dataflow and returns the files written
+func writeDataflowFiles(fs FileSysClient, df *iop.Dataflow, url string) (bw int64, files []FileReady, err error) {
 	fileReadyChn := make(chan FileReady, 10000)
+	done := make(chan struct{})
 
 	g.Trace("writing dataflow to %s", url)
 	go func() {
-		for range fileReadyChn {
-			// do nothing, wait for completion
+		defer close(done)
+		for file := range fileReadyChn {
+			files = append(files, file)
 		}
 	}()
 
 	sp := iop.NewStreamProcessor()
 	sp.SetConfig(fs.Client().Props())
 
-	return fs.Self().WriteDataflowReady(df, url, fileReadyChn, sp.Config)
+	bw, err = fs.Self().WriteDataflowReady(df, url, fileReadyChn, sp.Config)
+	<-done
+
+	return bw, files, err
 }
 
 // GetReaders returns one or more readers from specified paths in specified FileSysClient
@@ -683,6 +826,14 @@ type FileReady struct {
 	Node    FileNode
 	BytesW  int64
 	BatchID string
+	Rows    int64  // -1 if unknown
+	MD5     string // checksum of the bytes written
+	SHA256  string // checksum of the bytes written
+}
+
+// metadataSetter is a client which can set the custom metadata of objects
+type metadataSetter interface {
+	SetMetadata(uri string, metadata map[string]string) error
 }
 
 // WriteDataflowReady writes to a file sys and notifies the fileReady chan.
@@ -694,6 +845,13 @@ func (fs *BaseFileSysClient) WriteDataflowReady(df *iop.Dataflow, url string, fi
 	concurrency := cast.ToInt(fs.GetProp("CONCURRENCY"))
 	fileFormat := dbio.FileType(strings.ToLower(cast.ToString(fs.GetProp("FORMAT"))))
 	fileExt := cast.ToString(fs.GetProp("FILE_EXTENSION"))
+	checksumMetadata := cast.ToBool(fs.GetProp("CHECKSUM_METADATA"))
+	contentAddressed := cast.ToBool(fs.GetProp("CONTENT_ADDRESSED"))
+
+	if _, ok := fsClient.(metadataSetter); checksumMetadata && !ok {
+		g.Warn("checksum_metadata is not supported for %s, ignoring", fs.FsType())
+		checksumMetadata = false
+	}
 
 	// use provided config or get from dataflow
 	if val := fs.GetProp("COMPRESSION"); val != "" && sc.Compression == iop.NoneCompressorType {
@@ -747,13 +905,36 @@ func (fs *BaseFileSysClient) WriteDataflowReady(df *iop.Dataflow, url string, fi
 		defer df.Context.Wg.Read.Done()
 		localCtx := g.NewContext(ds.Context.Ctx, concurrency)
 
-		writePart := func(reader io.Reader, batchR *iop.BatchReader, partURL string) {
+		writePart := func(reader io.Reader, batchR *iop.BatchReader, partURL, partSuffix string) {
 			defer localCtx.Wg.Read.Done()
 
-			bw0, err := fsClient.Write(partURL, reader)
+			hash, sha := md5.New(), sha256.New()
+			bw0, err := fsClient.Write(partURL, io.TeeReader(reader, io.MultiWriter(hash, sha)))
+			checksum := hex.EncodeToString(sha.Sum(nil))
+
+			// name the part by its content hash
+			if err == nil && contentAddressed && !singleFile {
+				hashURL := partURL[:strings.LastIndex(partURL, "/")+1] + checksum + partSuffix
+				if err = Rename(fsClient, partURL, hashURL); err == nil {
+					partURL = hashURL
+				}
+			}
+
+			if err == nil && checksumMetadata {
+				err = fsClient.(metadataSetter).SetMetadata(partURL, map[string]string{"sha256": checksum})
+			}
+
 			bID := lo.Ternary(batchR.Batch != nil, batchR.Batch.ID(), "")
 			node := FileNode{URI: partURL, Size: cast.ToUint64(bw0)}
-			fileReadyChn <- FileReady{batchR.Columns, node, bw0, bID}
+			fileReadyChn <- FileReady{
+				Columns: batchR.Columns,
+				Node:    node,
+				BytesW:  bw0,
+				BatchID: bID,
+				Rows:    batchR.Counter,
+				MD5:     hex.EncodeToString(hash.Sum(nil)),
+				SHA256:  checksum,
+			}
 
 			if err != nil {
 				g.LogError(err)
@@ -797,8 +978,10 @@ func (fs *BaseFileSysClient) WriteDataflowReady(df *iop.Dataflow, url string, fi
 				subPartURL = subPartURL + compressor.Suffix()
 			}
 
+			partSuffix := strings.TrimPrefix(subPartURL, fmt.Sprintf("%s.%04d", partURL, fileCount))
+
 			g.Trace("writing stream to " + subPartURL)
-			go writePart(compressor.Compress(batchR.Reader), batchR, subPartURL)
+			go writePart(compressor.Compress(batchR.Reader), batchR, subPartURL, partSuffix)
 			localCtx.Wg.Read.Add()
 			// localCtx.MemBasedLimit(98) // wait until memory is lower than 90%
 
@@ -827,6 +1010,13 @@ func (fs *BaseFileSysClient) WriteDataflowReady(df *iop.Dataflow, url string, fi
 					break
 				}
 			}
+		case dbio.FileTypeArrow:
+			for reader := range ds.NewArrowReaderChnl(sc) {
+				err := processReader(reader)
+				if err != nil {
+					break
+				}
+			}
 		case dbio.FileTypeExcel:
 			for reader := range ds.NewExcelReaderChnl(sc) {
 				err := processReader(reader)
@@ -1051,6 +1241,13 @@ func GetDataflow(fs FileSysClient, nodes FileNodes, cfg iop.FileStreamConfig) (d
 			return // done
 		}
 
+		// up to N files are read in parallel, one at a time from local disk or when ordered
+		concurrency := cast.ToInt(fs.GetProp("CONCURRENCY"))
+		if cast.ToBool(fs.GetProp("ORDERED")) || (concurrency == 0 && fs.FsType() == dbio.TypeFileLocal) {
+			concurrency = 1
+		}
+
+		var streams []*iop.Datastream
 		for _, node := range nodes {
 			uri := node.URI
 			if strings.HasSuffix(uri, "/") {
@@ -1070,9 +1267,9 @@ func GetDataflow(fs FileSysClient, nodes FileNodes, cfg iop.FileStreamConfig) (d
 			}
 			pushDatastream(ds)
 
-			// when pulling from local disk, process one file at a time
-			if fs.FsType() == dbio.TypeFileLocal {
-				ds.WaitClosed()
+			streams = append(streams, ds)
+			if concurrency > 0 && len(streams) >= concurrency {
+				streams[len(streams)-concurrency].WaitClosed()
 			}
 		}
 
@@ -1163,31 +1360,71 @@ func GetDataflowViaDuckDB(fs FileSysClient, uri string, nodes FileNodes, cfg iop
 // MakeDatastream create a datastream from a reader
 func MakeDatastream(reader io.Reader, cfg map[string]string) (ds *iop.Datastream, err error) {
 
+	// decompress first, so that the format can be detected
+	reader, err = iop.AutoDecompress(reader)
+	if err != nil {
+		return nil, g.Error(err, "could not decompress stream")
+	}
+
 	data, reader2, err := g.Peek(reader, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	peekStr := string(data)
-	if strings.HasPrefix(peekStr, "[") || strings.HasPrefix(peekStr, "{") {
-		ds = iop.NewDatastream(iop.Columns{})
-		ds.SafeInference = true
-		ds.SetConfig(cfg)
-		err = ds.ConsumeJsonReader(reader2)
-		if err != nil {
-			return nil, err
-		}
-	} else {
+	format := dbio.FileType(strings.ToLower(cfg["format"]))
+	if format == dbio.FileTypeNone {
+		format = DetectFileFormat(data)
+	}
+
+	if format == dbio.FileTypeCsv {
 		csv := iop.CSV{Reader: reader2, Config: cfg}
-		ds, err = csv.ReadStream()
-		if err != nil {
-			return nil, err
-		}
+		return csv.ReadStream()
+	}
+
+	ds = iop.NewDatastream(iop.Columns{})
+	ds.SafeInference = true
+	ds.SetConfig(cfg)
+
+	switch format {
+	case dbio.FileTypeJson, dbio.FileTypeJsonLines:
+		err = ds.ConsumeJsonReader(reader2)
+	case dbio.FileTypeXml:
+		err = ds.ConsumeXmlReader(reader2)
+	case dbio.FileTypeParquet:
+		err = ds.ConsumeParquetReader(reader2)
+	case dbio.FileTypeAvro:
+		err = ds.ConsumeAvroReader(reader2)
+	case dbio.FileTypeArrow:
+		err = ds.ConsumeArrowReader(reader2)
+	case dbio.FileTypeLog:
+		err = ds.ConsumeLogReader(reader2)
+	default:
+		return nil, g.Error("unsupported stream format: %s", format)
+	}
+	if err != nil {
+		return nil, g.Error(err, "could not read %s stream", format)
 	}
 
 	return ds, nil
 }
 
+// DetectFileFormat detects the format from the first bytes of a stream. Defaults to csv.
+func DetectFileFormat(data []byte) dbio.FileType {
+	switch {
+	case bytes.HasPrefix(data, []byte("PAR1")):
+		return dbio.FileTypeParquet
+	case bytes.HasPrefix(data, []byte("Obj\x01")):
+		return dbio.FileTypeAvro
+	case bytes.HasPrefix(data, []byte("ARROW1")), bytes.HasPrefix(data, []byte{0xff, 0xff, 0xff, 0xff}):
+		return dbio.FileTypeArrow
+	case bytes.HasPrefix(data, []byte("[")), bytes.HasPrefix(data, []byte("{")):
+		return dbio.FileTypeJson
+	case bytes.HasPrefix(data, []byte("<")):
+		return dbio.FileTypeXml
+	}
+	return dbio.FileTypeCsv
+}
+
 // Write writer to a writer from a reader
 func Write(reader io.Reader, writer io.Writer) (bw int64, err error) {
 	bw, err = io.Copy(writer, reader)
@@ -1289,18 +1526,7 @@ func MergeReaders(fs FileSysClient, fileType dbio.FileType, nodes FileNodes, cfg
 	}
 
 	// excluding files
-	includeAll := cfg.FileSelect == nil // nil means include all. if not nil and empty, include none
-	includeMap := map[string]struct{}{}
-	excludeMap := map[string]struct{}{}
-	if !includeAll {
-		for _, name := range *cfg.FileSelect {
-			if strings.HasPrefix(name, "-!") {
-				excludeMap[strings.TrimPrefix(name, "-!")] = struct{}{}
-			} else {
-				includeMap[name] = struct{}{}
-			}
-		}
-	}
+	nodes = nodes.Selected(cfg.FileSelect)
 
 	concurrency := runtime.NumCPU()
 	switch {
@@ -1320,32 +1546,44 @@ func MergeReaders(fs FileSysClient, fileType dbio.FileType, nodes FileNodes, cfg
 		concurrency = 3
 	}
 
-	g.DebugLow("merging %s readers of %d files [concurrency=%d] from %s", fileType, len(nodes), concurrency, url)
+	// readers are obtained by up to N workers. When ordered, they are
+	// forwarded in the order of the files, otherwise as they are ready
+	ordered := cast.ToBool(fs.GetProp("ORDERED"))
+
+	g.DebugLow("merging %s readers of %d files [concurrency=%d ordered=%v] from %s", fileType, len(nodes), concurrency, ordered, url)
 	readerChn := make(chan *iop.ReaderReady, concurrency)
 	go func() {
 		defer close(readerChn)
 
+		slotChn := make(chan chan *iop.ReaderReady, concurrency)
+		forwarded := make(chan struct{})
+		go func() {
+			defer close(forwarded)
+			for slot := range slotChn {
+				if r, ok := <-slot; ok {
+					readerChn <- r
+				}
+			}
+		}()
+
+		workers := make(chan struct{}, concurrency)
 		for _, node := range nodes {
 			if strings.HasSuffix(node.URI, "/") {
 				g.DebugLow("skipping %s because is not file", node.URI)
 				continue
 			}
 
+			slot := make(chan *iop.ReaderReady, 1)
+			if ordered {
+				slotChn <- slot
+			}
+
+			workers <- struct{}{}
 			ds.Context.Wg.Read.Add()
 			go func(node FileNode) {
 				defer ds.Context.Wg.Read.Done()
-
-				if !includeAll {
-					_, uriExclude := excludeMap[node.URI]
-					_, pathExclude := excludeMap[node.Path()]
-					_, uriInclude := includeMap[node.URI]
-					_, pathInclude := includeMap[node.Path()]
-
-					if (uriExclude || pathExclude) || (!uriInclude && !pathInclude) {
-						g.Debug("skipping %s", node.URI)
-						return
-					}
-				}
+				defer func() { <-workers }()
+				defer close(slot)
 
 				g.Debug("processing reader from %s", node.URI)
 
@@ -1356,12 +1594,17 @@ func MergeReaders(fs FileSysClient, fileType dbio.FileType, nodes FileNodes, cfg
 				}
 
 				r := &iop.ReaderReady{Reader: reader, URI: node.URI}
-				readerChn <- r
+				if ordered {
+					slot <- r
+				} else {
+					readerChn <- r
+				}
 			}(node)
 		}
 
 		ds.Context.Wg.Read.Wait()
-
+		close(slotChn)
+		<-forwarded
 	}()
 
 	if g.In(fileType, dbio.FileTypeCsv, dbio.FileTypeJson, dbio.FileTypeJsonLines) {
@@ -1410,10 +1653,29 @@ func MergeReaders(fs FileSysClient, fileType dbio.FileType, nodes FileNodes, cfg
 	return ds, nil
 }
 
+// tailReader returns the byte range of a tailed file (watch mode), from the
+// `tail_offsets` prop (uri => [start, end])
+func tailReader(fs FileSysClient, uri string, reader io.Reader) (io.Reader, error) {
+	offsets := map[string][2]int64{}
+	if val := fs.GetProp("tail_offsets"); val == "" {
+		return reader, nil
+	} else if err := g.Unmarshal(val, &offsets); err != nil {
+		return nil, g.Error(err, "invalid tail offsets")
+	}
+
+	offset, ok := offsets[uri]
+	if !ok {
+		return reader, nil
+	} else if _, err := io.CopyN(io.Discard, reader, offset[0]); err != nil {
+		return nil, g.Error(err, "could not skip to offset %d of %s", offset[0], uri)
+	}
+	return io.LimitReader(reader, offset[1]-offset[0]), nil
+}
+
 func InferFileFormat(path string, defaults ...dbio.FileType) dbio.FileType {
 	path = strings.TrimSpace(strings.ToLower(path))
 
-	for _, fileType := range []dbio.FileType{dbio.FileTypeCsv, dbio.FileTypeJsonLines, dbio.FileTypeJson, dbio.FileTypeXml, dbio.FileTypeParquet, dbio.FileTypeAvro, dbio.FileTypeSAS, dbio.FileTypeExcel} {
+	for _, fileType := range []dbio.FileType{dbio.FileTypeCsv, dbio.FileTypeJsonLines, dbio.FileTypeJson, dbio.FileTypeXml, dbio.FileTypeParquet, dbio.FileTypeAvro, dbio.FileTypeArrow, dbio.FileTypeSAS, dbio.FileTypeExcel, dbio.FileTypeLog} {
 		ext := fileType.Ext()
 		if strings.HasSuffix(path, ext) || strings.Contains(path, ext+".") {
 			return fileType
@@ -14,6 +14,7 @@ import (
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
 	"github.com/flarco/g"
 	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio"
 	"github.com/spf13/cast"
 )
 
@@ -82,13 +83,27 @@ func (fs *AzureFileSysClient) GetPath(uri string) (path string, err error) {
 // Connect initiates the fs client connection
 func (fs *AzureFileSysClient) Connect() (err error) {
 
+	// custom CA bundle, skip verification or proxy
+	clientOptions := &azblob.ClientOptions{}
+	tlsConfig, err := dbio.TLSConfig(fs.GetProp("ssl_root_cert"), cast.ToBool(fs.GetProp("tls_skip_verify")))
+	if err != nil {
+		return g.Error(err, "could not make tls config")
+	}
+	proxyURL, err := dbio.ProxyURL(fs.GetProp("proxy"))
+	if err != nil {
+		return g.Error(err, "could not make proxy config")
+	}
+	if tlsConfig != nil || proxyURL != nil {
+		clientOptions.Transport = dbio.HTTPClient(tlsConfig, proxyURL)
+	}
+
 	serviceURL := g.F("https://%s.blob.core.windows.net/", fs.account)
 	if cs := fs.GetProp("CONN_STR"); cs != "" {
 		connProps := g.KVArrToMap(strings.Split(cs, ";")...)
 		fs.account = connProps["AccountName"]
 		fs.key = connProps["AccountKey"]
 
-		fs.client, err = azblob.NewClientFromConnectionString(cs, &azblob.ClientOptions{})
+		fs.client, err = azblob.NewClientFromConnectionString(cs, clientOptions)
 		if err != nil {
 			err = g.Error(err, "Could not connect to Azure using provided CONN_STR")
 			return
@@ -100,7 +115,7 @@ func (fs *AzureFileSysClient) Connect() (err error) {
 			return
 		}
 
-		fs.client, err = azblob.NewClientWithNoCredential(cs, &azblob.ClientOptions{})
+		fs.client, err = azblob.NewClientWithNoCredential(cs, clientOptions)
 		if err != nil {
 			err = g.Error(err, "Could not connect to Azure using provided SAS_SVC_URL")
 			return
@@ -111,7 +126,7 @@ func (fs *AzureFileSysClient) Connect() (err error) {
 			return g.Error(err, "Could not process shared key / account key")
 		}
 
-		fs.client, err = azblob.NewClientWithSharedKeyCredential(serviceURL, cred, &azblob.ClientOptions{})
+		fs.client, err = azblob.NewClientWithSharedKeyCredential(serviceURL, cred, clientOptions)
 		if err != nil {
 			return g.Error(err, "Could not connect to Azure using shared key credentials")
 		}
@@ -121,7 +136,7 @@ func (fs *AzureFileSysClient) Connect() (err error) {
 			return g.Error(err, "No Azure credentials provided")
 		}
 
-		fs.client, err = azblob.NewClient(serviceURL, cred, &azblob.ClientOptions{})
+		fs.client, err = azblob.NewClient(serviceURL, cred, clientOptions)
 		if err != nil {
 			return g.Error(err, "Could not connect to Azure using default credentials")
 		}
@@ -206,6 +221,9 @@ func (fs *AzureFileSysClient) List(uri string) (nodes FileNodes, err error) {
 					node.Created = blob.Properties.CreationTime.Unix()
 					node.Updated = blob.Properties.LastModified.Unix()
 					node.IsDir = strings.HasSuffix(blobName, "/")
+					if blob.Properties.ETag != nil {
+						node.ETag = string(*blob.Properties.ETag)
+					}
 				}
 				nodes.Add(node)
 			}
@@ -257,6 +275,9 @@ func (fs *AzureFileSysClient) ListRecursive(uri string) (nodes FileNodes, err er
 				Updated: lastModified.Unix(),
 				Size:    cast.ToUint64(blob.Properties.ContentLength),
 			}
+			if blob.Properties.ETag != nil {
+				file.ETag = string(*blob.Properties.ETag)
+			}
 			nodes.AddWhere(pattern, ts, file)
 		}
 	}
@@ -299,6 +320,25 @@ func (fs *AzureFileSysClient) Write(uri string, reader io.Reader) (bw int64, err
 	return
 }
 
+// SetMetadata sets the custom metadata of a blob
+func (fs *AzureFileSysClient) SetMetadata(uri string, metadata map[string]string) (err error) {
+	path, err := fs.GetPath(uri)
+	if err != nil {
+		return g.Error(err, "Error Parsing url: "+uri)
+	}
+
+	blobMetadata := map[string]*string{}
+	for k, v := range metadata {
+		blobMetadata[k] = g.String(v)
+	}
+
+	blobClient := fs.client.ServiceClient().NewContainerClient(fs.container).NewBlobClient(path)
+	if _, err = blobClient.SetMetadata(fs.Context().Ctx, blobMetadata, nil); err != nil {
+		return g.Error(err, "could not set metadata of %s", uri)
+	}
+	return nil
+}
+
 // GetReader returns an Azure FS reader
 func (fs *AzureFileSysClient) GetReader(uri string) (reader io.Reader, err error) {
 	key, err := fs.GetPath(uri)
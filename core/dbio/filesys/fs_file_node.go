@@ -24,6 +24,7 @@ type FileNode struct {
 	Created  int64       `json:"created,omitempty"`
 	Updated  int64       `json:"updated,omitempty"`
 	Owner    string      `json:"owner,omitempty"`
+	ETag     string      `json:"etag,omitempty"`
 	Columns  iop.Columns `json:"columns,omitempty"`
 	Children FileNodes   `json:"children,omitempty"`
 
@@ -193,6 +194,38 @@ func (fns FileNodes) After(ts time.Time) (nodes FileNodes) {
 	return
 }
 
+// Selected returns the nodes included by a file selection (uris or paths,
+// prefixed with `-!` to exclude). A nil selection includes all.
+func (fns FileNodes) Selected(fileSelect *[]string) (nodes FileNodes) {
+	if fileSelect == nil {
+		return fns
+	}
+
+	includeMap := map[string]struct{}{}
+	excludeMap := map[string]struct{}{}
+	for _, name := range *fileSelect {
+		if strings.HasPrefix(name, "-!") {
+			excludeMap[strings.TrimPrefix(name, "-!")] = struct{}{}
+		} else {
+			includeMap[name] = struct{}{}
+		}
+	}
+
+	for _, fn := range fns {
+		_, uriExclude := excludeMap[fn.URI]
+		_, pathExclude := excludeMap[fn.Path()]
+		_, uriInclude := includeMap[fn.URI]
+		_, pathInclude := includeMap[fn.Path()]
+
+		if (uriExclude || pathExclude) || (!uriInclude && !pathInclude) {
+			g.Debug("skipping %s", fn.URI)
+			continue
+		}
+		nodes = append(nodes, fn)
+	}
+	return
+}
+
 // Files returns only files (no folders)
 func (fns FileNodes) Files() (nodes FileNodes) {
 	for _, fn := range fns {
@@ -12,6 +12,7 @@ import (
 
 	"github.com/flarco/g"
 	"github.com/jlaffaye/ftp"
+	"github.com/slingdata-io/sling-cli/core/dbio"
 	"github.com/slingdata-io/sling-cli/core/dbio/iop"
 	"github.com/spf13/cast"
 )
@@ -121,6 +122,17 @@ func (fs *FtpFileSysClient) Connect() (err error) {
 			InsecureSkipVerify: true,
 			MinVersion:         tls.VersionTLS12,
 		}
+
+		// verify the server with the custom CA bundle
+		if rootCert := fs.GetProp("ssl_root_cert"); rootCert != "" && !cast.ToBool(fs.GetProp("tls_skip_verify")) {
+			customTLS, err := dbio.TLSConfig(rootCert, false)
+			if err != nil {
+				return g.Error(err, "could not load ssl_root_cert")
+			}
+			tlsConfig.InsecureSkipVerify = false
+			tlsConfig.RootCAs = customTLS.RootCAs
+			tlsConfig.ServerName = fs.GetProp("host")
+		}
 		options = append(options, ftp.DialWithExplicitTLS(tlsConfig))
 	}
 
@@ -9,7 +9,9 @@ import (
 	gcstorage "cloud.google.com/go/storage"
 	"github.com/flarco/g"
 	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio"
 	"github.com/spf13/cast"
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
@@ -104,6 +106,27 @@ func (fs *GoogleFileSysClient) Connect() (err error) {
 		fs.projectID = cast.ToString(m["project_id"])
 	}
 
+	// route the requests through the proxy, with the credentials
+	if proxyURL, err := dbio.ProxyURL(fs.GetProp("proxy")); err != nil {
+		return g.Error(err, "could not make proxy config")
+	} else if proxyURL != nil {
+		if fs.GetProp("CRED_API_KEY") != "" && credJsonBody == "" {
+			return g.Error("proxy is not supported with CRED_API_KEY, use a service account key")
+		}
+
+		ctx := context.WithValue(fs.Context().Ctx, oauth2.HTTPClient, dbio.HTTPClient(nil, proxyURL))
+		var creds *google.Credentials
+		if credJsonBody != "" {
+			creds, err = google.CredentialsFromJSON(ctx, []byte(credJsonBody), gcstorage.ScopeFullControl)
+		} else {
+			creds, err = google.FindDefaultCredentials(ctx, gcstorage.ScopeFullControl)
+		}
+		if err != nil {
+			return g.Error(err, "could not load google credentials")
+		}
+		authOption = option.WithHTTPClient(oauth2.NewClient(ctx, creds.TokenSource))
+	}
+
 	fs.client, err = gcstorage.NewClient(fs.Context().Ctx, authOption)
 	if err != nil {
 		err = g.Error(err, "Could not connect to GS Storage")
@@ -148,6 +171,20 @@ func (fs *GoogleFileSysClient) GetReader(path string) (reader io.Reader, err err
 	return
 }
 
+// SetMetadata sets the custom metadata of an object
+func (fs *GoogleFileSysClient) SetMetadata(uri string, metadata map[string]string) (err error) {
+	key, err := fs.GetPath(uri)
+	if err != nil {
+		return g.Error(err, "Error Parsing url: "+uri)
+	}
+
+	obj := fs.client.Bucket(fs.bucket).Object(key)
+	if _, err = obj.Update(fs.Context().Ctx, gcstorage.ObjectAttrsToUpdate{Metadata: metadata}); err != nil {
+		return g.Error(err, "could not set metadata of %s", uri)
+	}
+	return nil
+}
+
 // Buckets returns the buckets found in the project
 func (fs *GoogleFileSysClient) Buckets() (paths []string, err error) {
 	// Create S3 service client
@@ -218,6 +255,7 @@ func (fs *GoogleFileSysClient) List(uri string) (nodes FileNodes, err error) {
 				node.Created = attrs.Created.Unix()
 				node.Updated = attrs.Updated.Unix()
 				node.Owner = attrs.Owner
+				node.ETag = attrs.Etag
 				node.IsDir = strings.HasSuffix(attrs.Name, "/")
 			}
 			nodes.Add(node)
@@ -264,6 +302,7 @@ func (fs *GoogleFileSysClient) ListRecursive(uri string) (nodes FileNodes, err e
 			Created: attrs.Created.Unix(),
 			Updated: attrs.Updated.Unix(),
 			Owner:   attrs.Owner,
+			ETag:    attrs.Etag,
 		}
 		nodes.AddWhere(pattern, ts, node)
 	}
@@ -10,7 +10,9 @@ import (
 	"github.com/PuerkitoBio/goquery"
 	"github.com/flarco/g"
 	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio"
 	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
 )
 
 // HTTPFileSysClient is for HTTP files
@@ -53,7 +55,15 @@ func (fs *HTTPFileSysClient) Init(ctx context.Context) (err error) {
 // Connect initiates the http client
 func (fs *HTTPFileSysClient) Connect() (err error) {
 	// fs.client = &http.Client{Timeout: 20 * time.Second}
-	fs.client = &http.Client{}
+	tlsConfig, err := dbio.TLSConfig(fs.GetProp("ssl_root_cert"), cast.ToBool(fs.GetProp("tls_skip_verify")))
+	if err != nil {
+		return g.Error(err, "could not make tls config")
+	}
+	proxyURL, err := dbio.ProxyURL(fs.GetProp("proxy"))
+	if err != nil {
+		return g.Error(err, "could not make proxy config")
+	}
+	fs.client = dbio.HTTPClient(tlsConfig, proxyURL)
 	fs.username = fs.GetProp("HTTP_USER")
 	fs.password = fs.GetProp("HTTP_PASSWORD")
 	fs.isHttps = strings.HasPrefix(fs.GetProp("url"), "https://")
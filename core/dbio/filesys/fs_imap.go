@@ -0,0 +1,639 @@
+package filesys
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/spf13/cast"
+)
+
+// ImapFileSysClient reads the email attachments of an IMAP mailbox (read-only).
+// Paths are `<mailbox>/<message uid>/<attachment name>`, e.g. `INBOX/*/*.csv`.
+// Messages are filtered with the `search` property, as IMAP search criteria
+// (e.g. `FROM "reports@acme.com" SUBJECT "daily"`), default `ALL`.
+// The connection uses implicit TLS, or STARTTLS on port 143 (`tls=starttls`);
+// plain text must be allowed explicitly with `tls=false`
+type ImapFileSysClient struct {
+	BaseFileSysClient
+	client    *imapClient
+	mailboxes []string
+	selected  string
+	messages  map[string]*imapMessage // by mailbox/uid, fetched once
+	mux       sync.Mutex
+}
+
+type imapMessage struct {
+	date        time.Time
+	attachments map[string][]byte
+}
+
+// Init initializes the fs client
+func (fs *ImapFileSysClient) Init(ctx context.Context) (err error) {
+	var instance FileSysClient
+	instance = fs
+	fs.BaseFileSysClient.instance = &instance
+	fs.BaseFileSysClient.context = g.NewContext(ctx)
+	fs.messages = map[string]*imapMessage{}
+	return fs.Connect()
+}
+
+// Prefix returns the url prefix
+func (fs *ImapFileSysClient) Prefix(suffix ...string) string {
+	return g.F("%s://%s:%s", fs.FsType().String(), fs.GetProp("host"), fs.GetProp("port")) + strings.Join(suffix, "")
+}
+
+// GetPath returns the path of url
+func (fs *ImapFileSysClient) GetPath(uri string) (path string, err error) {
+	// normalize, in case url is provided without prefix
+	uri = NormalizeURI(fs, uri)
+
+	host, path, err := ParseURL(uri)
+	if err != nil {
+		return
+	}
+
+	if fs.GetProp("host") != host {
+		err = g.Error("URL host differs from connection host. %s != %s", host, fs.GetProp("host"))
+	}
+
+	return path, err
+}
+
+// Connect connects and logs into the IMAP server
+func (fs *ImapFileSysClient) Connect() (err error) {
+	if fs.GetProp("url") != "" {
+		u, err := url.Parse(fs.GetProp("url"))
+		if err != nil {
+			return g.Error(err, "could not parse IMAP URL")
+		}
+
+		password, _ := u.User.Password()
+		for key, val := range map[string]string{"user": u.User.Username(), "password": password, "host": u.Hostname(), "port": u.Port()} {
+			if val != "" {
+				fs.SetProp(key, val)
+			}
+		}
+	}
+
+	if fs.GetProp("port") == "" {
+		fs.SetProp("port", cast.ToString(dbio.TypeFileImap.DefPort()))
+	}
+
+	// implicit TLS, STARTTLS on port 143 (or with tls=starttls),
+	// plain text only when explicitly set with tls=false
+	tlsMode := "implicit"
+	if fs.GetProp("port") == "143" {
+		tlsMode = "starttls"
+	}
+	if val := strings.ToLower(fs.GetProp("tls")); val == "starttls" {
+		tlsMode = "starttls"
+	} else if val != "" && cast.ToBool(val) {
+		tlsMode = "implicit"
+	} else if val != "" {
+		tlsMode = "none"
+	}
+
+	tlsConfig, err := dbio.TLSConfig(fs.GetProp("ssl_root_cert"), cast.ToBool(fs.GetProp("tls_skip_verify")))
+	if err != nil {
+		return g.Error(err, "could not make tls config")
+	} else if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConfig.ServerName = fs.GetProp("host")
+
+	timeout := cast.ToInt(fs.GetProp("timeout"))
+	if timeout == 0 {
+		timeout = 30
+	}
+
+	address := net.JoinHostPort(fs.GetProp("host"), fs.GetProp("port"))
+	dialer := &net.Dialer{Timeout: time.Duration(timeout) * time.Second}
+
+	var conn net.Conn
+	if tlsMode == "implicit" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return g.Error(err, "unable to connect to imap server")
+	}
+
+	fs.client = &imapClient{conn: conn, reader: bufio.NewReader(conn)}
+	if greeting, err := fs.client.readResponse(); err != nil {
+		conn.Close()
+		return g.Error(err, "unable to connect to imap server")
+	} else if !strings.HasPrefix(greeting.line, "* OK") && !strings.HasPrefix(greeting.line, "* PREAUTH") {
+		conn.Close()
+		return g.Error("unexpected imap server greeting: %s", greeting.line)
+	}
+
+	switch tlsMode {
+	case "starttls":
+		if _, err = fs.client.execute("STARTTLS"); err != nil {
+			conn.Close()
+			return g.Error(err, "could not start TLS with imap server (set tls=false to allow plain text)")
+		}
+
+		tlsConn := tls.Client(conn, tlsConfig)
+		tlsConn.SetDeadline(time.Now().Add(dialer.Timeout))
+		if err = tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return g.Error(err, "could not start TLS with imap server")
+		}
+		tlsConn.SetDeadline(time.Time{})
+		fs.client = &imapClient{conn: tlsConn, reader: bufio.NewReader(tlsConn), tag: fs.client.tag}
+	case "none":
+		g.Warn("connecting to imap server %s without TLS (tls=false), the credentials are sent in plain text", address)
+	}
+
+	login := g.F("LOGIN %s %s", imapQuote(fs.GetProp("user")), imapQuote(fs.GetProp("password")))
+	if _, err = fs.client.execute(login); err != nil {
+		return g.Error(err, "unable to login into imap server")
+	}
+
+	return nil
+}
+
+// Close logs out of the IMAP server
+func (fs *ImapFileSysClient) Close() error {
+	if fs.client != nil {
+		fs.client.execute("LOGOUT")
+		fs.client.conn.Close()
+		fs.client = nil
+	}
+	return nil
+}
+
+// List lists the mailboxes, the messages of a mailbox,
+// or the attachments of a message
+func (fs *ImapFileSysClient) List(uri string) (nodes FileNodes, err error) {
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
+
+	p, err := fs.GetPath(uri)
+	if err != nil {
+		return nodes, g.Error(err, "Error Parsing url: "+uri)
+	}
+
+	mailbox, uid, name, err := fs.splitPath(p)
+	if err != nil {
+		return nodes, err
+	}
+
+	switch {
+	case mailbox == "":
+		for _, mailbox := range fs.mailboxes {
+			nodes.Add(FileNode{URI: fs.Prefix("/", mailbox, "/"), IsDir: true})
+		}
+	case uid == "":
+		uids, err := fs.search(mailbox, 0)
+		if err != nil {
+			return nodes, err
+		}
+		for _, uid := range uids {
+			nodes.Add(FileNode{URI: fs.Prefix("/", mailbox, "/", uid, "/"), IsDir: true})
+		}
+	default:
+		message, err := fs.message(mailbox, uid)
+		if err != nil {
+			return nodes, err
+		}
+		for _, node := range fs.attachmentNodes(mailbox, uid, message) {
+			if name == "" || node.Name() == name {
+				nodes.Add(node)
+			}
+		}
+	}
+
+	return nodes, nil
+}
+
+// ListRecursive lists the attachments of the messages of a mailbox
+func (fs *ImapFileSysClient) ListRecursive(uri string) (nodes FileNodes, err error) {
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
+
+	p, err := fs.GetPath(uri)
+	if err != nil {
+		return nodes, g.Error(err, "Error Parsing url: "+uri)
+	}
+
+	pattern, err := makeGlob(NormalizeURI(fs, uri))
+	if err != nil {
+		return nodes, g.Error(err, "Error Parsing url pattern: "+uri)
+	}
+
+	mailbox, uid, name, err := fs.splitPath(p)
+	if err != nil {
+		return nodes, err
+	} else if mailbox == "" {
+		return nodes, g.Error("a mailbox is required in the path, e.g. INBOX/*/*.csv (available: %s)", strings.Join(fs.mailboxes, ", "))
+	}
+
+	ts := fs.GetRefTs().Unix()
+
+	uids := []string{uid}
+	if !imapUIDRegex.MatchString(uid) {
+		if uids, err = fs.search(mailbox, ts); err != nil {
+			return nodes, err
+		}
+	}
+
+	for _, uid := range uids {
+		message, err := fs.message(mailbox, uid)
+		if err != nil {
+			return nodes, err
+		}
+		for _, node := range fs.attachmentNodes(mailbox, uid, message) {
+			if pattern == nil && name != "" && node.Name() != name {
+				continue
+			}
+			nodes.AddWhere(pattern, ts, node)
+		}
+	}
+
+	return nodes, nil
+}
+
+// GetReader returns the reader of an attachment
+func (fs *ImapFileSysClient) GetReader(uri string) (reader io.Reader, err error) {
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
+
+	p, err := fs.GetPath(uri)
+	if err != nil {
+		return nil, g.Error(err, "Error Parsing url: "+uri)
+	}
+
+	mailbox, uid, name, err := fs.splitPath(p)
+	if err != nil {
+		return nil, err
+	} else if mailbox == "" || uid == "" || name == "" {
+		return nil, g.Error("invalid attachment path, expected <mailbox>/<uid>/<name>: %s", p)
+	}
+
+	message, err := fs.message(mailbox, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := message.attachments[name]
+	if !ok {
+		return nil, g.Error("attachment %s not found in message %s of %s", name, uid, mailbox)
+	}
+
+	return bytes.NewReader(data), nil
+}
+
+// Write is not supported
+func (fs *ImapFileSysClient) Write(uri string, reader io.Reader) (bw int64, err error) {
+	return 0, g.Error("cannot write to an IMAP mailbox")
+}
+
+// delete is not supported
+func (fs *ImapFileSysClient) delete(uri string) (err error) {
+	return g.Error("cannot delete from an IMAP mailbox")
+}
+
+// MkdirAll is not supported
+func (fs *ImapFileSysClient) MkdirAll(path string) (err error) {
+	return g.Error("cannot create folders in an IMAP mailbox")
+}
+
+// splitPath splits a path into the mailbox (longest match),
+// the message uid and the attachment name
+func (fs *ImapFileSysClient) splitPath(p string) (mailbox, uid, name string, err error) {
+	if fs.mailboxes == nil {
+		responses, err := fs.client.execute(`LIST "" "*"`)
+		if err != nil {
+			return "", "", "", g.Error(err, "could not list mailboxes")
+		}
+		fs.mailboxes = []string{}
+		for _, resp := range responses {
+			if mailbox := parseImapListResponse(resp); mailbox != "" {
+				fs.mailboxes = append(fs.mailboxes, mailbox)
+			}
+		}
+	}
+
+	p = strings.Trim(p, "/")
+	for _, m := range fs.mailboxes {
+		if (p == m || strings.HasPrefix(p, m+"/")) && len(m) > len(mailbox) {
+			mailbox = m
+		}
+	}
+	if mailbox == "" {
+		if p != "" {
+			err = g.Error("mailbox not found for path %s (available: %s)", p, strings.Join(fs.mailboxes, ", "))
+		}
+		return
+	}
+
+	if rest := strings.TrimPrefix(strings.TrimPrefix(p, mailbox), "/"); rest != "" {
+		parts := strings.SplitN(rest, "/", 2)
+		uid = parts[0]
+		if len(parts) > 1 {
+			name = parts[1]
+		}
+	}
+
+	return mailbox, uid, name, nil
+}
+
+// selectMailbox opens a mailbox read-only
+func (fs *ImapFileSysClient) selectMailbox(mailbox string) (err error) {
+	if fs.selected == mailbox {
+		return nil
+	}
+	if _, err = fs.client.execute("EXAMINE " + imapQuote(mailbox)); err != nil {
+		return g.Error(err, "could not open mailbox %s", mailbox)
+	}
+	fs.selected = mailbox
+	return nil
+}
+
+// search returns the uids of the messages matching the `search` criteria,
+// since the timestamp (if provided)
+func (fs *ImapFileSysClient) search(mailbox string, since int64) (uids []string, err error) {
+	if err = fs.selectMailbox(mailbox); err != nil {
+		return nil, err
+	}
+
+	criteria := strings.TrimSpace(fs.GetProp("search"))
+	if criteria == "" {
+		criteria = "ALL"
+	}
+	if since > 0 {
+		criteria = g.F("%s SINCE %s", criteria, time.Unix(since, 0).UTC().Format("2-Jan-2006"))
+	}
+
+	responses, err := fs.client.execute("UID SEARCH " + criteria)
+	if err != nil {
+		return nil, g.Error(err, "could not search mailbox %s", mailbox)
+	}
+
+	for _, resp := range responses {
+		if strings.HasPrefix(resp.line, "* SEARCH") {
+			uids = append(uids, strings.Fields(strings.TrimPrefix(resp.line, "* SEARCH"))...)
+		}
+	}
+	sort.Slice(uids, func(i, j int) bool { return cast.ToInt(uids[i]) < cast.ToInt(uids[j]) })
+
+	return uids, nil
+}
+
+// message fetches and parses a message, without marking it as seen
+func (fs *ImapFileSysClient) message(mailbox, uid string) (message *imapMessage, err error) {
+	key := mailbox + "/" + uid
+	if message, ok := fs.messages[key]; ok {
+		return message, nil
+	}
+
+	if !imapUIDRegex.MatchString(uid) {
+		return nil, g.Error("invalid message uid: %s", uid)
+	} else if err = fs.selectMailbox(mailbox); err != nil {
+		return nil, err
+	}
+
+	responses, err := fs.client.execute(g.F("UID FETCH %s (INTERNALDATE BODY.PEEK[])", uid))
+	if err != nil {
+		return nil, g.Error(err, "could not fetch message %s of %s", uid, mailbox)
+	}
+
+	for _, resp := range responses {
+		if !strings.Contains(resp.line, "FETCH") || len(resp.literals) == 0 {
+			continue
+		}
+
+		message = &imapMessage{}
+		if m := imapDateRegex.FindStringSubmatch(resp.line); m != nil {
+			message.date, _ = time.Parse("_2-Jan-2006 15:04:05 -0700", m[1])
+		}
+		if message.attachments, err = parseAttachments(resp.literals[0]); err != nil {
+			return nil, g.Error(err, "could not parse message %s of %s", uid, mailbox)
+		}
+	}
+
+	if message == nil {
+		return nil, g.Error("message %s not found in %s", uid, mailbox)
+	}
+	fs.messages[key] = message
+
+	return message, nil
+}
+
+func (fs *ImapFileSysClient) attachmentNodes(mailbox, uid string, message *imapMessage) (nodes FileNodes) {
+	names := make([]string, 0, len(message.attachments))
+	for name := range message.attachments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		nodes = append(nodes, FileNode{
+			URI:     fs.Prefix("/", mailbox, "/", uid, "/", name),
+			Updated: message.date.Unix(),
+			Size:    uint64(len(message.attachments[name])),
+		})
+	}
+	return nodes
+}
+
+// parseAttachments returns the attachments of a raw message, by file name
+func parseAttachments(raw []byte) (attachments map[string][]byte, err error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, g.Error(err, "could not read message")
+	}
+
+	attachments = map[string][]byte{}
+	err = collectAttachments(textproto.MIMEHeader(msg.Header), msg.Body, attachments)
+	return attachments, err
+}
+
+func collectAttachments(header textproto.MIMEHeader, body io.Reader, attachments map[string][]byte) (err error) {
+	mediaType, params, _ := mime.ParseMediaType(header.Get("Content-Type"))
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return g.Error(err, "could not read message part")
+			}
+			if err = collectAttachments(part.Header, part, attachments); err != nil {
+				return err
+			}
+		}
+	}
+
+	name := ""
+	if _, dParams, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		name = dParams["filename"]
+	}
+	if name == "" {
+		name = params["name"]
+	}
+	if name == "" {
+		return nil // not an attachment
+	}
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(name); err == nil {
+		name = decoded
+	}
+	name = strings.ReplaceAll(path.Base(strings.ReplaceAll(name, `\`, "/")), " ", "_")
+
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return g.Error(err, "could not read attachment %s", name)
+	}
+
+	// de-duplicate names within a message
+	key := name
+	for i := 2; attachments[key] != nil; i++ {
+		ext := path.Ext(name)
+		key = g.F("%s_%d%s", strings.TrimSuffix(name, ext), i, ext)
+	}
+	attachments[key] = data
+
+	return nil
+}
+
+// imapMaxLiteral is the maximum size of a literal (e.g. a message) sent by the server
+var imapMaxLiteral int64 = 256 * 1024 * 1024
+
+var (
+	imapUIDRegex     = regexp.MustCompile(`^\d+$`)
+	imapLiteralRegex = regexp.MustCompile(`\{(\d+)\}$`)
+	imapDateRegex    = regexp.MustCompile(`INTERNALDATE "([^"]+)"`)
+)
+
+// imapClient is a minimal IMAP4rev1 client
+type imapClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	tag    int
+}
+
+// imapResponse is a response line, with the literals read separately
+type imapResponse struct {
+	line     string
+	literals [][]byte
+}
+
+// execute sends a command, and returns the untagged responses
+func (c *imapClient) execute(command string) (responses []imapResponse, err error) {
+	c.tag++
+	tag := g.F("S%d", c.tag)
+	if _, err = fmt.Fprintf(c.conn, "%s %s\r\n", tag, command); err != nil {
+		return nil, g.Error(err, "could not send imap command")
+	}
+
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+
+		if status, ok := strings.CutPrefix(resp.line, tag+" "); ok {
+			if !strings.HasPrefix(strings.ToUpper(status), "OK") {
+				return responses, g.Error("imap command failed: %s", status)
+			}
+			return responses, nil
+		}
+		responses = append(responses, resp)
+	}
+}
+
+// readResponse reads a response line, including its literals (`{size}`)
+func (c *imapClient) readResponse() (resp imapResponse, err error) {
+	var sb strings.Builder
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			return resp, g.Error(err, "could not read imap response")
+		}
+		line = strings.TrimRight(line, "\r\n")
+		sb.WriteString(line)
+
+		m := imapLiteralRegex.FindStringSubmatch(line)
+		if m == nil {
+			resp.line = sb.String()
+			return resp, nil
+		}
+
+		// read as received, not allocated upfront from the announced size
+		size := cast.ToInt64(m[1])
+		if size > imapMaxLiteral {
+			return resp, g.Error("imap literal of %d bytes exceeds the maximum of %d bytes", size, imapMaxLiteral)
+		}
+		var literal bytes.Buffer
+		if _, err = io.CopyN(&literal, c.reader, size); err != nil {
+			return resp, g.Error(err, "could not read imap literal")
+		}
+		resp.literals = append(resp.literals, literal.Bytes())
+	}
+}
+
+// parseImapListResponse returns the mailbox name of a LIST response,
+// e.g. `* LIST (\HasNoChildren) "/" "INBOX"`
+func parseImapListResponse(resp imapResponse) string {
+	if !strings.HasPrefix(resp.line, "* LIST") || strings.Contains(strings.ToLower(resp.line), `\noselect`) {
+		return ""
+	}
+
+	// skip the attributes and delimiter
+	rest := resp.line[strings.Index(resp.line, ")")+1:]
+	rest = strings.TrimSpace(rest)
+	if strings.HasPrefix(rest, `"`) {
+		if end := strings.Index(rest[1:], `"`); end >= 0 {
+			rest = rest[end+2:]
+		}
+	} else {
+		rest = strings.TrimPrefix(rest, "NIL")
+	}
+	rest = strings.TrimSpace(rest)
+
+	switch {
+	case imapLiteralRegex.MatchString(rest) && len(resp.literals) > 0:
+		return string(resp.literals[0])
+	case strings.HasPrefix(rest, `"`):
+		unquoted := strings.TrimSuffix(strings.TrimPrefix(rest, `"`), `"`)
+		return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(unquoted)
+	}
+	return rest
+}
+
+// imapQuote returns a quoted string
+func imapQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
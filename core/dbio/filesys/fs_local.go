@@ -175,10 +175,17 @@ func (fs *LocalFileSysClient) GetDatastream(uri string, cfg ...iop.FileStreamCon
 			err = ds.ConsumeParquetReaderSeeker(file)
 		case dbio.FileTypeAvro:
 			err = ds.ConsumeAvroReaderSeeker(file)
+		case dbio.FileTypeArrow:
+			err = ds.ConsumeArrowReader(bufio.NewReader(file))
 		case dbio.FileTypeSAS:
 			err = ds.ConsumeSASReaderSeeker(file)
 		case dbio.FileTypeExcel:
 			err = ds.ConsumeExcelReaderSeeker(file, fs.properties)
+		case dbio.FileTypeLog:
+			var reader io.Reader
+			if reader, err = tailReader(fs, uri, file); err == nil {
+				err = ds.ConsumeLogReader(bufio.NewReader(reader))
+			}
 		case dbio.FileTypeCsv:
 			err = ds.ConsumeCsvReader(bufio.NewReader(file))
 		default:
@@ -22,6 +22,7 @@ import (
 	"github.com/flarco/g/net"
 	"github.com/gobwas/glob"
 	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio"
 	"github.com/slingdata-io/sling-cli/core/dbio/iop"
 	"github.com/spf13/cast"
 )
@@ -135,6 +136,19 @@ func (fs *S3FileSysClient) Connect() (err error) {
 		// LogLevel: aws.LogLevel(aws.LogDebugWithHTTPBody),
 	}
 
+	// custom CA bundle, skip verification or proxy
+	tlsConfig, err := dbio.TLSConfig(fs.GetProp("ssl_root_cert"), cast.ToBool(fs.GetProp("tls_skip_verify")))
+	if err != nil {
+		return g.Error(err, "could not make tls config")
+	}
+	proxyURL, err := dbio.ProxyURL(fs.GetProp("proxy"))
+	if err != nil {
+		return g.Error(err, "could not make proxy config")
+	}
+	if tlsConfig != nil || proxyURL != nil {
+		awsConfig.HTTPClient = dbio.HTTPClient(tlsConfig, proxyURL)
+	}
+
 	if cast.ToBool(fs.GetProp("USE_ENVIRONMENT")) {
 		goto useEnv
 	} else if profile := fs.GetProp("PROFILE"); profile != "" {
@@ -565,6 +579,9 @@ func (fs *S3FileSysClient) doList(svc *s3.S3, input *s3.ListObjectsV2Input, urlP
 			if obj.Owner != nil {
 				node.Owner = *obj.Owner.DisplayName
 			}
+			if obj.ETag != nil {
+				node.ETag = strings.Trim(*obj.ETag, `"`)
+			}
 
 			nodes.AddWhere(pattern, ts, node)
 			if len(nodes) >= maxItems {
@@ -610,3 +627,105 @@ func (fs *S3FileSysClient) GenerateS3PreSignedURL(s3URL string, dur time.Duratio
 
 	return
 }
+
+// SetTags adds the tags to an object, keeping its other tags
+func (fs *S3FileSysClient) SetTags(uri string, tags map[string]string) (err error) {
+	key, err := fs.GetPath(uri)
+	if err != nil {
+		return g.Error(err, "Error Parsing url: "+uri)
+	}
+
+	svc := s3.New(fs.getSession())
+	current, err := svc.GetObjectTaggingWithContext(fs.Context().Ctx, &s3.GetObjectTaggingInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return g.Error(err, "could not get tags of %s", uri)
+	}
+
+	tagSet := []*s3.Tag{}
+	for _, tag := range current.TagSet {
+		if _, ok := tags[aws.StringValue(tag.Key)]; !ok {
+			tagSet = append(tagSet, tag)
+		}
+	}
+	for k, v := range tags {
+		tagSet = append(tagSet, &s3.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	_, err = svc.PutObjectTaggingWithContext(fs.Context().Ctx, &s3.PutObjectTaggingInput{
+		Bucket:  aws.String(fs.bucket),
+		Key:     aws.String(key),
+		Tagging: &s3.Tagging{TagSet: tagSet},
+	})
+	if err != nil {
+		return g.Error(err, "could not set tags of %s", uri)
+	}
+
+	return nil
+}
+
+// copyObject copies an object server-side, within the bucket
+func (fs *S3FileSysClient) copyObject(fromURI, toURI string) (err error) {
+	fromKey, err := fs.GetPath(fromURI)
+	if err != nil {
+		return g.Error(err, "Error Parsing url: "+fromURI)
+	}
+	toKey, err := fs.GetPath(toURI)
+	if err != nil {
+		return g.Error(err, "Error Parsing url: "+toURI)
+	}
+
+	svc := s3.New(fs.getSession())
+	_, err = svc.CopyObjectWithContext(fs.Context().Ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(fs.bucket),
+		CopySource: aws.String((&url.URL{Path: fs.bucket + "/" + fromKey}).EscapedPath()),
+		Key:        aws.String(toKey),
+	})
+	if err != nil {
+		return g.Error(err, "could not copy %s to %s", fromURI, toURI)
+	}
+
+	return nil
+}
+
+// SetMetadata sets the custom metadata of an object, merged with its
+// existing metadata. The object is copied in place.
+func (fs *S3FileSysClient) SetMetadata(uri string, metadata map[string]string) (err error) {
+	key, err := fs.GetPath(uri)
+	if err != nil {
+		return g.Error(err, "Error Parsing url: "+uri)
+	}
+
+	svc := s3.New(fs.getSession())
+	head, err := svc.HeadObjectWithContext(fs.Context().Ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(fs.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return g.Error(err, "could not get metadata of %s", uri)
+	}
+
+	objMetadata := head.Metadata
+	if objMetadata == nil {
+		objMetadata = map[string]*string{}
+	}
+	for k, v := range metadata {
+		objMetadata[k] = aws.String(v)
+	}
+
+	_, err = svc.CopyObjectWithContext(fs.Context().Ctx, &s3.CopyObjectInput{
+		Bucket:            aws.String(fs.bucket),
+		CopySource:        aws.String((&url.URL{Path: fs.bucket + "/" + key}).EscapedPath()),
+		Key:               aws.String(key),
+		ContentType:       head.ContentType,
+		Metadata:          objMetadata,
+		MetadataDirective: aws.String(s3.MetadataDirectiveReplace),
+	})
+	if err != nil {
+		return g.Error(err, "could not set metadata of %s", uri)
+	}
+
+	return nil
+}
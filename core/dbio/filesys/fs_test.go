@@ -1,20 +1,37 @@
 package filesys
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/pem"
 	"fmt"
 	"io"
+	stdnet "net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/ipc"
+	"github.com/apache/arrow/go/v16/arrow/memory"
 	arrowParquet "github.com/apache/arrow/go/v16/parquet"
 	"github.com/apache/arrow/go/v16/parquet/compress"
 	"github.com/clbanning/mxj/v2"
 	"github.com/flarco/g/net"
 	"github.com/linkedin/goavro/v2"
 	"github.com/parquet-go/parquet-go"
+	"github.com/samber/lo"
 	"github.com/slingdata-io/sling-cli/core/dbio"
 	"github.com/spf13/cast"
 
@@ -198,6 +215,30 @@ func TestFileSysLocalFormat(t *testing.T) {
 	}
 }
 
+func TestFileSysLocalLogTail(t *testing.T) {
+	logPath := path.Join(t.TempDir(), "access.log")
+	content := "level=info msg=start\nlevel=warn msg=\"slow query\"\nlevel=error msg=crash\n"
+	assert.NoError(t, os.WriteFile(logPath, []byte(content), 0644))
+
+	fs, err := NewFileSysClient(dbio.TypeFileLocal, "log_parser=logfmt")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// only the byte range of the second line
+	uri := "file://" + logPath
+	fs.SetProp("tail_offsets", g.Marshal(map[string][2]int64{uri: {21, 49}}))
+	df, err := fs.ReadDataflow(uri)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	data, err := df.Collect()
+	if assert.NoError(t, err) && assert.Len(t, data.Rows, 1) {
+		assert.Equal(t, "slow query", data.Records()[0]["msg"])
+	}
+}
+
 func TestFileSysLocalJson(t *testing.T) {
 	t.Parallel()
 	iop.SampleSize = 4
@@ -694,6 +735,125 @@ func TestFileSysLocalAvro(t *testing.T) {
 
 }
 
+func TestFileSysConcurrentReads(t *testing.T) {
+	t.Setenv("SLING_DUCKDB_COMPUTE", "false")
+	folder := t.TempDir()
+	for i := 0; i < 30; i++ {
+		content := g.F("id,name\n%d,a\n%d,b\n", i*2, i*2+1)
+		os.WriteFile(path.Join(folder, g.F("file_%02d.csv", i)), []byte(content), 0644)
+	}
+
+	for _, ordered := range []bool{true, false} {
+		fs, err := NewFileSysClient(dbio.TypeFileLocal, "concurrency=4", g.F("ordered=%v", ordered))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		df, err := fs.ReadDataflow("file://" + folder)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		data, err := df.Collect()
+		if !assert.NoError(t, err) || !assert.Len(t, data.Rows, 60) {
+			return
+		}
+
+		ids := lo.Map(data.Rows, func(row []any, i int) int { return cast.ToInt(row[0]) })
+		if ordered {
+			assert.True(t, sort.IntsAreSorted(ids), ids)
+		} else {
+			sort.Ints(ids)
+			assert.Equal(t, lo.Range(60), ids)
+		}
+	}
+}
+
+func TestMakeDatastream(t *testing.T) {
+	// arrow IPC stream & file formats
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "ts", Type: &arrow.TimestampType{Unit: arrow.Microsecond}},
+	}, nil)
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	builder.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2}, nil)
+	builder.Field(1).(*array.StringBuilder).AppendValues([]string{"a", ""}, []bool{true, false})
+	builder.Field(2).(*array.TimestampBuilder).AppendValues([]arrow.Timestamp{0, 1000000}, nil)
+	record := builder.NewRecord()
+	defer record.Release()
+
+	var arrowStream bytes.Buffer
+	streamWriter := ipc.NewWriter(&arrowStream, ipc.WithSchema(schema))
+	assert.NoError(t, streamWriter.Write(record))
+	assert.NoError(t, streamWriter.Close())
+
+	arrowFile, err := os.Create(path.Join(t.TempDir(), "test.arrow"))
+	assert.NoError(t, err)
+	fileWriter, err := ipc.NewFileWriter(arrowFile, ipc.WithSchema(schema))
+	assert.NoError(t, err)
+	assert.NoError(t, fileWriter.Write(record))
+	assert.NoError(t, fileWriter.Close())
+	arrowFile.Close()
+	arrowFileBytes, err := os.ReadFile(arrowFile.Name())
+	assert.NoError(t, err)
+
+	// arrow stream written by the sling arrow writer
+	var arrowWritten bytes.Buffer
+	arrowWriter := iop.NewArrowWriter(&arrowWritten, iop.Columns{
+		{Name: "id", Type: iop.BigIntType},
+		{Name: "name", Type: iop.StringType},
+	})
+	assert.NoError(t, arrowWriter.WriteRow([]any{1, "a"}))
+	assert.NoError(t, arrowWriter.WriteRow([]any{2, nil}))
+	assert.NoError(t, arrowWriter.Close())
+
+	// gzipped json lines
+	var jsonlGz bytes.Buffer
+	gzWriter := gzip.NewWriter(&jsonlGz)
+	gzWriter.Write([]byte("{\"id\": 1, \"name\": \"a\"}\n{\"id\": 2, \"name\": null}\n"))
+	gzWriter.Close()
+
+	type testCase struct {
+		name   string
+		data   []byte
+		format dbio.FileType
+	}
+	cases := []testCase{
+		{name: "csv", data: []byte("id,name\n1,a\n2,\n")},
+		{name: "arrow_stream", data: arrowStream.Bytes()},
+		{name: "arrow_file", data: arrowFileBytes},
+		{name: "writer_arrow", data: arrowWritten.Bytes()},
+		{name: "jsonl_gzip", data: jsonlGz.Bytes()},
+		{name: "jsonl_format", data: []byte("{\"id\": 1, \"name\": \"a\"}\n{\"id\": 2}\n"), format: dbio.FileTypeJsonLines},
+	}
+
+	for _, c := range cases {
+		ds, err := MakeDatastream(bytes.NewReader(c.data), map[string]string{"format": string(c.format), "flatten": "true"})
+		if !assert.NoError(t, err, c.name) {
+			continue
+		}
+
+		data, err := ds.Collect(0)
+		if !assert.NoError(t, err, c.name) || !assert.Len(t, data.Rows, 2, c.name) {
+			continue
+		}
+		assert.EqualValues(t, 2, cast.ToInt(data.Rows[1][data.Columns.GetColumn("id").Position-1]), c.name)
+		assert.Nil(t, data.Rows[1][data.Columns.GetColumn("name").Position-1], c.name)
+
+		if strings.HasPrefix(c.name, "arrow") {
+			assert.Equal(t, iop.BigIntType, data.Columns.GetColumn("id").Type)
+			assert.True(t, data.Columns.GetColumn("ts").Type.IsDatetime())
+		}
+	}
+
+	assert.Equal(t, dbio.FileTypeParquet, DetectFileFormat([]byte("PAR1....")))
+	assert.Equal(t, dbio.FileTypeArrow, DetectFileFormat(arrowStream.Bytes()))
+	assert.Equal(t, dbio.FileTypeJson, DetectFileFormat([]byte(`[{"a": 1}]`)))
+	assert.Equal(t, dbio.FileTypeCsv, DetectFileFormat([]byte("a,b\n1,2")))
+}
+
 func TestFileSysDOSpaces(t *testing.T) {
 	fs, err := NewFileSysClient(
 		dbio.TypeFileS3,
@@ -1343,6 +1503,172 @@ func TestFileSysHTTP(t *testing.T) {
 	}
 }
 
+func TestFileSysHTTPTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("id,name\n1,a\n2,b\n"))
+	}))
+	defer server.Close()
+
+	certPath := path.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	err := os.WriteFile(certPath, certPEM, 0600)
+	assert.NoError(t, err)
+
+	read := func(props ...string) (rows int, err error) {
+		fs, err := NewFileSysClient(dbio.TypeFileHTTP, props...)
+		if err != nil {
+			return 0, err
+		}
+		df, err := fs.ReadDataflow(server.URL + "/data.csv")
+		if err != nil {
+			return 0, err
+		}
+		data, err := iop.MergeDataflow(df).Collect(0)
+		return len(data.Rows), err
+	}
+
+	// untrusted certificate
+	_, err = read()
+	assert.Error(t, err)
+
+	// custom CA bundle
+	rows, err := read("ssl_root_cert=" + certPath)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, rows)
+	}
+
+	// skip verification
+	rows, err = read("tls_skip_verify=true")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, rows)
+	}
+}
+
+func TestFileSysHTTPProxy(t *testing.T) {
+	// plain http requests are sent to the proxy, with the absolute URL
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") == "" || r.URL.Host != "data.example.invalid" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		w.Write([]byte("id,name\n1,a\n2,b\n"))
+	}))
+	defer proxy.Close()
+
+	read := func(props ...string) (rows int, err error) {
+		fs, err := NewFileSysClient(dbio.TypeFileHTTP, props...)
+		if err != nil {
+			return 0, err
+		}
+		df, err := fs.ReadDataflow("http://data.example.invalid/data.csv")
+		if err != nil {
+			return 0, err
+		}
+		data, err := iop.MergeDataflow(df).Collect(0)
+		return len(data.Rows), err
+	}
+
+	rows, err := read("proxy=" + strings.Replace(proxy.URL, "http://", "http://user:pass@", 1))
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, rows)
+	}
+
+	// missing credentials
+	_, err = read("proxy=" + proxy.URL)
+	assert.Error(t, err)
+
+	// unsupported scheme
+	_, err = read("proxy=ftp://proxy.example.invalid")
+	assert.Error(t, err)
+}
+
+func TestFileSysImap(t *testing.T) {
+	// fake imap server, with one message having a csv attachment
+	message := strings.Join([]string{
+		"From: reports@acme.com",
+		"Subject: daily report",
+		"MIME-Version: 1.0",
+		`Content-Type: multipart/mixed; boundary="XYZ"`,
+		"",
+		"--XYZ",
+		"Content-Type: text/plain",
+		"",
+		"see attached",
+		"--XYZ",
+		`Content-Type: text/csv; name="report.csv"`,
+		`Content-Disposition: attachment; filename="report.csv"`,
+		"Content-Transfer-Encoding: base64",
+		"",
+		base64.StdEncoding.EncodeToString([]byte("id,name\n1,a\n2,b\n")),
+		"--XYZ--",
+		"",
+	}, "\r\n")
+
+	listener, err := stdnet.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer listener.Close()
+
+	commands := []string{}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn stdnet.Conn) {
+				defer conn.Close()
+				fmt.Fprint(conn, "* OK IMAP4rev1 ready\r\n")
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					parts := strings.SplitN(scanner.Text(), " ", 2)
+					tag, command := parts[0], parts[1]
+					commands = append(commands, command)
+					switch {
+					case strings.HasPrefix(command, "LIST"):
+						fmt.Fprint(conn, "* LIST (\\HasNoChildren) \"/\" \"INBOX\"\r\n")
+						fmt.Fprint(conn, "* LIST (\\HasNoChildren) \"/\" \"Reports/Daily\"\r\n")
+					case strings.HasPrefix(command, "UID SEARCH"):
+						fmt.Fprint(conn, "* SEARCH 7\r\n")
+					case strings.HasPrefix(command, "UID FETCH"):
+						fmt.Fprintf(conn, "* 1 FETCH (UID 7 INTERNALDATE \"15-Jan-2024 10:00:00 +0000\" BODY[] {%d}\r\n%s)\r\n", len(message), message)
+					}
+					fmt.Fprintf(conn, "%s OK done\r\n", tag)
+				}
+			}(conn)
+		}
+	}()
+
+	uri := g.F("imap://user:pass@%s/Reports/Daily/*/*.csv", listener.Addr().String())
+	fs, err := NewFileSysClientFromURL(uri, "tls=false", `search=FROM "reports@acme.com"`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer fs.Close()
+
+	nodes, err := fs.ListRecursive(uri)
+	if assert.NoError(t, err) && assert.Len(t, nodes, 1) {
+		assert.True(t, strings.HasSuffix(nodes[0].URI, "/Reports/Daily/7/report.csv"))
+		assert.EqualValues(t, 16, nodes[0].Size)
+		assert.EqualValues(t, 1705312800, nodes[0].Updated)
+	}
+	assert.Contains(t, commands, `LOGIN "user" "pass"`)
+	assert.Contains(t, commands, `EXAMINE "Reports/Daily"`)
+	assert.Contains(t, commands, `UID SEARCH FROM "reports@acme.com"`)
+
+	df, err := fs.ReadDataflow(uri)
+	if assert.NoError(t, err) {
+		data, err := iop.MergeDataflow(df).Collect(0)
+		if assert.NoError(t, err) {
+			assert.Len(t, data.Rows, 2)
+		}
+	}
+
+	_, err = fs.Write(g.F("imap://%s/INBOX/1/out.csv", listener.Addr().String()), strings.NewReader("a"))
+	assert.Error(t, err)
+}
+
 func testManyCSV(t *testing.T) {
 	fs, err := NewFileSysClient(dbio.TypeFileHTTP, "concurrencyLimit=5")
 	nodes, err := fs.List("https://people.sc.fsu.edu/~jburkardt/data/csv/csv.html")
@@ -1440,3 +1766,165 @@ func testManyCSV(t *testing.T) {
 // 	}
 
 // }
+
+func TestWriteDataflowAtomic(t *testing.T) {
+	fs, err := NewFileSysClient(dbio.TypeFileLocal, "atomic=true", "file_max_rows=2")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	folder := t.TempDir()
+	writeFile := func(name, content string) {
+		os.MkdirAll(path.Dir(path.Join(folder, name)), 0755)
+		os.WriteFile(path.Join(folder, name), []byte(content), 0644)
+	}
+	writeFile("parts/old.csv", "id,name\n0,z\n")
+	writeFile("parts_other/keep.csv", "id,name\n0,z\n")
+	writeFile("parts.csv", "id,name\n0,z\n")
+	writeFile("single.csv", "id,name\n0,z\n")
+	writeFile("single.csv.bak", "id,name\n0,z\n")
+
+	newDataflow := func() *iop.Dataflow {
+		data := iop.NewDataset(iop.NewColumnsFromFields("id", "name"))
+		data.Rows = [][]any{{1, "a"}, {2, "b"}, {3, "c"}}
+		df, err := iop.MakeDataFlow(data.Stream())
+		assert.NoError(t, err)
+		return df
+	}
+
+	// a folder, existing files are deleted once replaced
+	_, files, err := WriteDataflowFiles(fs, newDataflow(), "file://"+folder+"/parts/*.csv")
+	if assert.NoError(t, err) && assert.Len(t, files, 2) {
+		for _, file := range files {
+			assert.FileExists(t, strings.TrimPrefix(file.Node.URI, "file://"))
+		}
+	}
+	entries, _ := os.ReadDir(path.Join(folder, "parts"))
+	assert.Len(t, entries, 2)
+	assert.NoFileExists(t, path.Join(folder, "parts/old.csv"))
+
+	// a single file
+	fs, _ = NewFileSysClient(dbio.TypeFileLocal, "atomic=true")
+	_, err = WriteDataflow(fs, newDataflow(), "file://"+folder+"/single.csv")
+	if assert.NoError(t, err) {
+		data, _ := os.ReadFile(path.Join(folder, "single.csv"))
+		assert.True(t, strings.HasPrefix(string(data), "id,name\n1,a\n"))
+	}
+
+	// files sharing the prefix are kept, no temporary files are left
+	entries, _ = os.ReadDir(folder)
+	names := lo.Map(entries, func(e os.DirEntry, i int) string { return e.Name() })
+	assert.ElementsMatch(t, []string{"parts", "parts_other", "parts.csv", "single.csv", "single.csv.bak"}, names)
+	assert.FileExists(t, path.Join(folder, "parts_other/keep.csv"))
+
+	// a failed write keeps the existing files
+	df := newDataflow()
+	df.Context.CaptureErr(g.Error("failed"))
+	_, err = WriteDataflow(fs, df, "file://"+folder+"/single.csv")
+	assert.Error(t, err)
+	data, _ := os.ReadFile(path.Join(folder, "single.csv"))
+	assert.True(t, strings.HasPrefix(string(data), "id,name\n1,a\n"))
+	entries, _ = os.ReadDir(folder)
+	assert.Len(t, entries, 5)
+}
+
+func TestFileSysImapTLS(t *testing.T) {
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	tlsServer.Close()
+	certPath := path.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw}), 0600)
+
+	// fake imap server, supporting STARTTLS unless disabled
+	var startTLS atomic.Bool
+	startTLS.Store(true)
+	listener, err := stdnet.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer listener.Close()
+
+	var mux sync.Mutex
+	logins := []bool{} // whether each login was over TLS
+	getLogins := func() []bool {
+		mux.Lock()
+		defer mux.Unlock()
+		return append([]bool{}, logins...)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn stdnet.Conn) {
+				defer conn.Close()
+				fmt.Fprint(conn, "* OK IMAP4rev1 ready\r\n")
+				isTLS := false
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					tag, command, _ := strings.Cut(strings.TrimSpace(line), " ")
+					switch {
+					case command == "STARTTLS" && startTLS.Load():
+						fmt.Fprintf(conn, "%s OK begin TLS\r\n", tag)
+						tlsConn := tls.Server(conn, tlsServer.TLS)
+						if tlsConn.Handshake() != nil {
+							return
+						}
+						conn, reader, isTLS = tlsConn, bufio.NewReader(tlsConn), true
+						continue
+					case command == "STARTTLS":
+						fmt.Fprintf(conn, "%s BAD unknown command\r\n", tag)
+						continue
+					case strings.HasPrefix(command, "LOGIN"):
+						mux.Lock()
+						logins = append(logins, isTLS)
+						mux.Unlock()
+					case strings.HasPrefix(command, "LIST"):
+						fmt.Fprint(conn, "* LIST (\\HasNoChildren) \"/\" {5}\r\nINBOX\r\n")
+					case strings.HasPrefix(command, "UID FETCH"):
+						fmt.Fprint(conn, "* 1 FETCH (UID 7 BODY[] {999999999}\r\n")
+						return
+					}
+					fmt.Fprintf(conn, "%s OK done\r\n", tag)
+				}
+			}(conn)
+		}
+	}()
+
+	_, port, _ := stdnet.SplitHostPort(listener.Addr().String())
+	connect := func(props ...string) (FileSysClient, error) {
+		props = append(props, "host=127.0.0.1", "port="+port, "user=user", "password=pass", "ssl_root_cert="+certPath)
+		return NewFileSysClient(dbio.TypeFileImap, props...)
+	}
+
+	// STARTTLS, before login
+	fs, err := connect("tls=starttls")
+	if assert.NoError(t, err) {
+		fs.Close()
+		assert.Equal(t, []bool{true}, getLogins())
+	}
+
+	// no downgrade to plain text if STARTTLS is not supported
+	startTLS.Store(false)
+	_, err = connect("tls=starttls")
+	assert.ErrorContains(t, err, "tls=false")
+	assert.Len(t, getLogins(), 1)
+
+	// plain text, explicitly
+	fs, err = connect("tls=false")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer fs.Close()
+	assert.Equal(t, []bool{true, false}, getLogins())
+
+	// literals are read up to the maximum size
+	_, err = fs.List("imap://127.0.0.1:" + port + "/")
+	assert.NoError(t, err)
+	_, err = fs.Self().(*ImapFileSysClient).message("INBOX", "7")
+	assert.ErrorContains(t, err, "exceeds the maximum")
+}
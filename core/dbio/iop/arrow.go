@@ -0,0 +1,267 @@
+package iop
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/array"
+	"github.com/apache/arrow/go/v16/arrow/ipc"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/flarco/g"
+	"github.com/spf13/cast"
+)
+
+// arrowFileMagic is the prefix of the arrow IPC file format
+var arrowFileMagic = []byte("ARROW1")
+
+// ArrowStream reads the record batches of an arrow IPC stream or file
+type ArrowStream struct {
+	schema *arrow.Schema
+	next   func() (arrow.Record, error) // returns a nil record when done
+	record arrow.Record
+	row    int
+}
+
+// NewArrowStream creates a reader of an arrow IPC stream or file. The
+// file format needs random access (footer), so it is read in memory.
+func NewArrowStream(reader io.Reader) (a *ArrowStream, err error) {
+	bufReader := bufio.NewReader(reader)
+	if magic, _ := bufReader.Peek(len(arrowFileMagic)); !bytes.Equal(magic, arrowFileMagic) {
+		streamReader, err := ipc.NewReader(bufReader)
+		if err != nil {
+			return nil, g.Error(err, "could not read arrow stream")
+		}
+
+		next := func() (arrow.Record, error) {
+			if streamReader.Next() {
+				return streamReader.Record(), nil
+			}
+			return nil, streamReader.Err()
+		}
+		return &ArrowStream{schema: streamReader.Schema(), next: next}, nil
+	}
+
+	data, err := io.ReadAll(bufReader)
+	if err != nil {
+		return nil, g.Error(err, "could not read arrow file")
+	}
+
+	fileReader, err := ipc.NewFileReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, g.Error(err, "could not read arrow file")
+	}
+
+	i := 0
+	next := func() (arrow.Record, error) {
+		if i >= fileReader.NumRecords() {
+			return nil, nil
+		}
+		i++
+		return fileReader.Record(i - 1)
+	}
+	return &ArrowStream{schema: fileReader.Schema(), next: next}, nil
+}
+
+func (a *ArrowStream) Columns() Columns {
+	typeMap := map[arrow.Type]ColumnType{
+		arrow.BOOL:         BoolType,
+		arrow.INT8:         IntegerType,
+		arrow.INT16:        IntegerType,
+		arrow.INT32:        IntegerType,
+		arrow.UINT8:        IntegerType,
+		arrow.UINT16:       IntegerType,
+		arrow.INT64:        BigIntType,
+		arrow.UINT32:       BigIntType,
+		arrow.UINT64:       BigIntType,
+		arrow.FLOAT16:      FloatType,
+		arrow.FLOAT32:      FloatType,
+		arrow.FLOAT64:      FloatType,
+		arrow.DECIMAL128:   DecimalType,
+		arrow.DECIMAL256:   DecimalType,
+		arrow.STRING:       StringType,
+		arrow.LARGE_STRING: StringType,
+		arrow.BINARY:       BinaryType,
+		arrow.LARGE_BINARY: BinaryType,
+		arrow.DATE32:       DateType,
+		arrow.DATE64:       DateType,
+		arrow.TIMESTAMP:    DatetimeType,
+		arrow.LIST:         JsonType,
+		arrow.LARGE_LIST:   JsonType,
+		arrow.STRUCT:       JsonType,
+		arrow.MAP:          JsonType,
+	}
+
+	fields := a.schema.Fields()
+	cols := make(Columns, len(fields))
+	for i, field := range fields {
+		cols[i] = Column{Name: field.Name, Position: i + 1, Type: StringType}
+		if typ, ok := typeMap[field.Type.ID()]; ok {
+			cols[i].Type = typ
+			cols[i].Sourced = true
+		}
+	}
+
+	return cols
+}
+
+func (a *ArrowStream) nextFunc(it *Iterator) bool {
+	// advance to the next non-empty record batch
+	for a.record == nil || a.row >= int(a.record.NumRows()) {
+		record, err := a.next()
+		if err != nil {
+			it.Context.CaptureErr(g.Error(err, "could not read arrow record batch"))
+			return false
+		} else if record == nil {
+			return false
+		}
+		a.record, a.row = record, 0
+	}
+
+	it.Row = make([]any, len(it.ds.Columns))
+	for i, arr := range a.record.Columns() {
+		if i >= len(it.Row) || arr.IsNull(a.row) {
+			continue
+		}
+
+		switch arr := arr.(type) {
+		case *array.Timestamp:
+			toTime, _ := arr.DataType().(*arrow.TimestampType).GetToTimeFunc()
+			it.Row[i] = toTime(arr.Value(a.row))
+		case *array.Date32:
+			it.Row[i] = arr.Value(a.row).ToTime()
+		case *array.Date64:
+			it.Row[i] = arr.Value(a.row).ToTime()
+		case *array.Decimal128, *array.Decimal256:
+			it.Row[i] = arr.ValueStr(a.row)
+		default:
+			if it.ds.Columns[i].Type == JsonType {
+				it.Row[i] = g.Marshal(arr.GetOneForMarshal(a.row))
+			} else {
+				it.Row[i] = arr.GetOneForMarshal(a.row)
+			}
+		}
+	}
+	a.row++
+
+	return true
+}
+
+// ArrowWriter writes rows as the record batches of an arrow IPC stream
+type ArrowWriter struct {
+	Columns   Columns
+	BatchSize int
+
+	schema  *arrow.Schema
+	builder *array.RecordBuilder
+	writer  ArrowRecordWriter
+	rows    int
+}
+
+// ArrowRecordWriter writes record batches (an IPC stream, or an arrow flight stream)
+type ArrowRecordWriter interface {
+	Write(rec arrow.Record) error
+	Close() error
+}
+
+// NewArrowWriter creates an arrow IPC stream writer
+func NewArrowWriter(w io.Writer, columns Columns) *ArrowWriter {
+	return NewArrowRecordWriter(ipc.NewWriter(w, ipc.WithSchema(ArrowSchema(columns))), columns)
+}
+
+// NewArrowRecordWriter creates a writer of rows into a record batch writer,
+// which must use the schema of the columns (see ArrowSchema)
+func NewArrowRecordWriter(w ArrowRecordWriter, columns Columns) *ArrowWriter {
+	schema := ArrowSchema(columns)
+	return &ArrowWriter{
+		Columns:   columns,
+		BatchSize: 10000,
+		schema:    schema,
+		builder:   array.NewRecordBuilder(memory.DefaultAllocator, schema),
+		writer:    w,
+	}
+}
+
+// ArrowSchema returns the arrow schema of columns
+func ArrowSchema(columns Columns) *arrow.Schema {
+	fields := make([]arrow.Field, len(columns))
+	for i, col := range columns {
+		fields[i] = arrow.Field{Name: col.Name, Type: arrowDataType(col.Type), Nullable: true}
+	}
+	return arrow.NewSchema(fields, nil)
+}
+
+func arrowDataType(ct ColumnType) arrow.DataType {
+	switch {
+	case ct.IsBool():
+		return arrow.FixedWidthTypes.Boolean
+	case ct.IsInteger():
+		return arrow.PrimitiveTypes.Int64
+	case ct.IsFloat():
+		return arrow.PrimitiveTypes.Float64
+	case ct.IsDate(), ct.IsDatetime():
+		return &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}
+	case ct.IsBinary():
+		return arrow.BinaryTypes.Binary
+	}
+	return arrow.BinaryTypes.String // including decimals, to keep the precision
+}
+
+// WriteRow appends a row, and writes a record batch when full
+func (aw *ArrowWriter) WriteRow(row []any) (err error) {
+	for i, field := range aw.builder.Fields() {
+		var val any
+		if i < len(row) {
+			val = row[i]
+		}
+		if val == nil {
+			field.AppendNull()
+			continue
+		}
+
+		switch fb := field.(type) {
+		case *array.BooleanBuilder:
+			fb.Append(cast.ToBool(val))
+		case *array.Int64Builder:
+			fb.Append(cast.ToInt64(val))
+		case *array.Float64Builder:
+			fb.Append(cast.ToFloat64(val))
+		case *array.TimestampBuilder:
+			fb.Append(arrow.Timestamp(cast.ToTime(val).UnixMicro()))
+		case *array.BinaryBuilder:
+			fb.Append([]byte(cast.ToString(val)))
+		case *array.StringBuilder:
+			fb.Append(cast.ToString(val))
+		}
+	}
+
+	if aw.rows++; aw.rows >= aw.BatchSize {
+		return aw.flush()
+	}
+	return nil
+}
+
+func (aw *ArrowWriter) flush() (err error) {
+	if aw.rows == 0 {
+		return nil
+	}
+
+	record := aw.builder.NewRecord()
+	defer record.Release()
+	aw.rows = 0
+
+	if err = aw.writer.Write(record); err != nil {
+		return g.Error(err, "could not write arrow record batch")
+	}
+	return nil
+}
+
+// Close writes the remaining rows and the end of the stream
+func (aw *ArrowWriter) Close() (err error) {
+	defer aw.builder.Release()
+	if err = aw.flush(); err != nil {
+		return err
+	}
+	return aw.writer.Close()
+}
@@ -0,0 +1,174 @@
+package iop
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"sync/atomic"
+
+	"github.com/apache/arrow/go/v16/arrow"
+	"github.com/apache/arrow/go/v16/arrow/ipc"
+	"github.com/apache/arrow/go/v16/arrow/memory"
+	"github.com/apache/arrow/go/v16/arrow/util"
+	"github.com/apache/arrow/go/v16/parquet"
+	arrowCompress "github.com/apache/arrow/go/v16/parquet/compress"
+	"github.com/apache/arrow/go/v16/parquet/file"
+	"github.com/apache/arrow/go/v16/parquet/pqarrow"
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/env"
+)
+
+// ArrowRecordReader reads the record batches of a parquet or arrow file as is,
+// without converting the values into rows (experimental arrow pipeline)
+type ArrowRecordReader struct {
+	Schema *arrow.Schema
+	next   func() (arrow.Record, error) // returns a nil record when done
+	close  func()
+}
+
+// NewArrowRecordReader creates a record batch reader of a parquet or arrow file
+func NewArrowRecordReader(ctx context.Context, reader io.Reader, format dbio.FileType) (r *ArrowRecordReader, err error) {
+	switch format {
+	case dbio.FileTypeArrow:
+		stream, err := NewArrowStream(reader)
+		if err != nil {
+			return nil, err
+		}
+		return &ArrowRecordReader{Schema: stream.schema, next: stream.next, close: func() {}}, nil
+	case dbio.FileTypeParquet:
+	default:
+		return nil, g.Error("arrow pipeline does not support format: %s", format)
+	}
+
+	// need random access, write to temp file prior
+	parquetPath := path.Join(env.GetTempFolder(), g.NewTsID("parquet.temp")+".parquet")
+	f, err := os.Create(parquetPath)
+	if err != nil {
+		return nil, g.Error(err, "Unable to create temp file: "+parquetPath)
+	}
+
+	closeFile := func() {
+		f.Close()
+		env.RemoveLocalTempFile(parquetPath)
+	}
+
+	if _, err = io.Copy(f, reader); err != nil {
+		closeFile()
+		return nil, g.Error(err, "Unable to write to temp file: "+parquetPath)
+	} else if _, err = f.Seek(0, 0); err != nil {
+		closeFile()
+		return nil, g.Error(err, "Unable to seek to beginning of temp file: "+parquetPath)
+	}
+
+	pf, err := file.NewParquetReader(f)
+	if err != nil {
+		closeFile()
+		return nil, g.Error(err, "could not read parquet file")
+	}
+
+	fr, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{BatchSize: 10000, Parallel: true}, memory.DefaultAllocator)
+	if err != nil {
+		pf.Close()
+		closeFile()
+		return nil, g.Error(err, "could not read parquet file")
+	}
+
+	rr, err := fr.GetRecordReader(ctx, nil, nil)
+	if err != nil {
+		pf.Close()
+		closeFile()
+		return nil, g.Error(err, "could not read parquet record batches")
+	}
+
+	next := func() (arrow.Record, error) {
+		if rr.Next() {
+			return rr.Record(), nil
+		}
+		if err := rr.Err(); err != nil && err != io.EOF {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	closeAll := func() {
+		rr.Release()
+		pf.Close()
+		closeFile()
+	}
+
+	return &ArrowRecordReader{Schema: rr.Schema(), next: next, close: closeAll}, nil
+}
+
+// Columns returns the columns of the schema
+func (r *ArrowRecordReader) Columns() Columns {
+	return (&ArrowStream{schema: r.Schema}).Columns()
+}
+
+// Close releases the reader
+func (r *ArrowRecordReader) Close() {
+	r.close()
+}
+
+// CopyArrowRecords writes the record batches of the reader in the parquet or
+// arrow format, and counts the rows and bytes into the datastream
+func (ds *Datastream) CopyArrowRecords(reader *ArrowRecordReader, w io.Writer, format dbio.FileType, compression CompressorType) (err error) {
+	var write func(rec arrow.Record) error
+	var closeWriter func() error
+
+	switch format {
+	case dbio.FileTypeParquet:
+		codec := arrowCompress.Codecs.Snappy
+		switch compression {
+		case ZStandardCompressorType:
+			codec = arrowCompress.Codecs.Zstd
+		case GzipCompressorType:
+			codec = arrowCompress.Codecs.Gzip
+		case NoneCompressorType:
+			codec = arrowCompress.Codecs.Uncompressed
+		}
+
+		fw, err := pqarrow.NewFileWriter(
+			reader.Schema, w,
+			parquet.NewWriterProperties(parquet.WithCompression(codec)),
+			pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema()),
+		)
+		if err != nil {
+			return g.Error(err, "could not create parquet writer")
+		}
+		write, closeWriter = fw.WriteBuffered, fw.Close
+	case dbio.FileTypeArrow:
+		iw := ipc.NewWriter(w, ipc.WithSchema(reader.Schema))
+		write, closeWriter = iw.Write, iw.Close
+	default:
+		return g.Error("arrow pipeline does not support format: %s", format)
+	}
+
+	for {
+		if err = ds.Context.Err(); err != nil {
+			closeWriter()
+			return g.Error(err, "arrow pipeline canceled")
+		}
+
+		rec, err := reader.next()
+		if err != nil {
+			closeWriter()
+			return g.Error(err, "could not read arrow record batch")
+		} else if rec == nil {
+			break
+		}
+
+		if err = write(rec); err != nil {
+			closeWriter()
+			return g.Error(err, "could not write arrow record batch")
+		}
+		atomic.AddUint64(&ds.Count, uint64(rec.NumRows()))
+		ds.AddBytes(util.TotalRecordSize(rec))
+	}
+
+	if err = closeWriter(); err != nil {
+		return g.Error(err, "could not close %s writer", format)
+	}
+	return nil
+}
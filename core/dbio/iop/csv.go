@@ -517,6 +517,12 @@ func detectCarrRet(testBytes []byte) (needsCleanUp bool) {
 	return
 }
 
+// DetectDelimiter returns the most likely delimiter of a csv sample
+func DetectDelimiter(testBytes []byte) rune {
+	deli, _, _ := detectDelimiter("", testBytes)
+	return deli
+}
+
 func detectDelimiter(delimiter string, testBytes []byte) (bestDeli rune, numCols int, err error) {
 	bestDeli = ','
 	deliSuggested := false
@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/flarco/g"
@@ -31,6 +32,7 @@ type Dataflow struct {
 	StreamMap       map[string]*Datastream
 	closed          bool
 	mux             sync.Mutex
+	rateLimiter     *RateLimiter
 	SchemaVersion   int // for column type version
 }
 
@@ -129,13 +131,22 @@ func (df *Dataflow) SetBatchLimit(limit int64) {
 	df.mux.Lock()
 	defer df.mux.Unlock()
 	for _, ds := range df.Streams {
+		ds.Context.Lock()
 		ds.Sp.Config.BatchLimit = limit
 		if ds.CurrentBatch != nil {
-			ds.CurrentBatch.Limit = limit
+			ds.CurrentBatch.Limit.Store(limit)
 		}
+		ds.Context.Unlock()
 	}
 }
 
+// SetRateLimit throttles the rows of all the streams to the rate limit
+func (df *Dataflow) SetRateLimit(rl RateLimit) {
+	df.mux.Lock()
+	defer df.mux.Unlock()
+	df.rateLimiter = NewRateLimiter(rl)
+}
+
 // Defer runs a given function as close of Dataflow
 func (df *Dataflow) Defer(f func()) {
 	df.mux.Lock()
@@ -500,6 +511,7 @@ func (df *Dataflow) SyncStats() {
 					dfCols[i].Stats.LastVal = colStats.LastVal
 				}
 			}
+			dfCols[i].Stats.setMaxVal(colStats.MaxVal, col.Type)
 
 			if col.Constraint != nil {
 				dfCols[i].Constraint.FailCnt = dfCols[i].Constraint.FailCnt + col.Constraint.FailCnt
@@ -521,7 +533,7 @@ func (df *Dataflow) Count() (cnt uint64) {
 	if df != nil && df.Ready {
 		for _, ds := range df.Streams {
 			if ds.Ready {
-				cnt += ds.Count
+				cnt += atomic.LoadUint64(&ds.Count)
 			}
 		}
 	}
@@ -530,11 +542,11 @@ func (df *Dataflow) Count() (cnt uint64) {
 
 // AddEgressBytes add egress bytes
 func (df *Dataflow) AddEgressBytes(bytes uint64) {
-	df.EgressBytes = df.EgressBytes + bytes
+	atomic.AddUint64(&df.EgressBytes, bytes)
 }
 
 func (df *Dataflow) Bytes() (inBytes, outBytes uint64) {
-	return df.DsTotalBytes(), df.EgressBytes
+	return df.DsTotalBytes(), atomic.LoadUint64(&df.EgressBytes)
 }
 
 func (df *Dataflow) DsTotalBytes() (bytes uint64) {
@@ -750,7 +762,9 @@ func MergeDataflow(df *Dataflow) (dsN *Datastream) {
 	go func() {
 		defer close(rows)
 		for ds := range df.StreamCh {
+			ds.Context.Lock()
 			dsN.Sp.Config = ds.Sp.Config // copy config
+			ds.Context.Unlock()
 			for batch := range ds.BatchChan {
 				if !dsN.Columns.IsSimilarTo(df.Columns) {
 					dsN.AddColumns(df.Columns, false)
@@ -4,12 +4,16 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/xml"
 	"io"
 	"os"
 	"path"
 	"runtime"
 	"runtime/debug"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -19,6 +23,7 @@ import (
 	"github.com/flarco/g"
 	"github.com/flarco/g/csv"
 	"github.com/flarco/g/json"
+	"github.com/google/uuid"
 	jit "github.com/json-iterator/go"
 	parquet "github.com/parquet-go/parquet-go"
 	"github.com/parquet-go/parquet-go/compress"
@@ -63,6 +68,9 @@ type Datastream struct {
 	ID            string
 	Metadata      Metadata // map of column name to metadata type
 	paused        bool
+	memBudgetOnce sync.Once
+	memBudget     int64 // the memory budget of a batch, read once per stream
+	memBudgetSet  bool  // whether SLING_MEM_BUDGET was set explicitly
 	pauseChan     chan struct{}
 	unpauseChan   chan struct{}
 }
@@ -120,11 +128,75 @@ type KeyValue struct {
 }
 
 type Metadata struct {
-	StreamURL KeyValue `json:"stream_url"`
-	LoadedAt  KeyValue `json:"loaded_at"`
-	RowNum    KeyValue `json:"row_num"`
-	RowID     KeyValue `json:"row_id"`
-	ExecID    KeyValue `json:"exec_id"`
+	StreamURL    KeyValue          `json:"stream_url"`
+	LoadedAt     KeyValue          `json:"loaded_at"`
+	RowNum       KeyValue          `json:"row_num"`
+	RowID        KeyValue          `json:"row_id"`
+	ExecID       KeyValue          `json:"exec_id"`
+	RowHash      KeyValue          `json:"row_hash"`
+	FileLine     KeyValue          `json:"file_line"` // value is the number of header lines
+	SurrogateKey SurrogateKey      `json:"surrogate_key,omitempty"`
+	Collision    MetadataCollision `json:"collision,omitempty"`
+}
+
+// SurrogateKeyType is the type of generated surrogate key
+type SurrogateKeyType string
+
+const (
+	// SurrogateKeyHash is the md5 hash of the key column values (deterministic)
+	SurrogateKeyHash SurrogateKeyType = "hash"
+	// SurrogateKeyUUID is a random uuid
+	SurrogateKeyUUID SurrogateKeyType = "uuid"
+)
+
+// SurrogateKey is a generated key column, appended to each row
+type SurrogateKey struct {
+	Name    string           `json:"name,omitempty" yaml:"name,omitempty"`
+	Type    SurrogateKeyType `json:"type,omitempty" yaml:"type,omitempty"`
+	Columns []string         `json:"columns,omitempty" yaml:"columns,omitempty"`
+}
+
+// Value returns the surrogate key value of the row. For the hash type, the values
+// of the key columns are length-prefixed (`<len>:<value>`, nulls as `-1:`) so that
+// the encoding is unambiguous, then md5 hashed.
+func (sk SurrogateKey) Value(row []any, indexes []int) any {
+	if sk.Type == SurrogateKeyUUID {
+		return uuid.NewString()
+	}
+
+	b := strings.Builder{}
+	for _, index := range indexes {
+		if index >= len(row) || row[index] == nil {
+			b.WriteString("-1:")
+			continue
+		}
+		value := cast.ToString(row[index])
+		b.WriteString(strconv.Itoa(len(value)))
+		b.WriteByte(':')
+		b.WriteString(value)
+	}
+	sum := md5.Sum([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// MetadataCollision is the policy to apply when a metadata column
+// name already exists in the source columns
+type MetadataCollision string
+
+const (
+	// MetadataCollisionSuffix appends underscores to the metadata column name until unique (default)
+	MetadataCollisionSuffix MetadataCollision = "suffix"
+	// MetadataCollisionOverwrite replaces the source column values with the metadata values
+	MetadataCollisionOverwrite MetadataCollision = "overwrite"
+	// MetadataCollisionSkip keeps the source column and does not add the metadata column
+	MetadataCollisionSkip MetadataCollision = "skip"
+	// MetadataCollisionError returns an error
+	MetadataCollisionError MetadataCollision = "error"
+)
+
+// IsValid returns true if the policy is known
+func (mc MetadataCollision) IsValid() bool {
+	return g.In(mc, "", MetadataCollisionSuffix, MetadataCollisionOverwrite, MetadataCollisionSkip, MetadataCollisionError)
 }
 
 // AsMap return as map
@@ -591,7 +663,9 @@ func (ds *Datastream) transformReader(reader io.Reader) (newReader io.Reader, de
 
 		if len(applied) > 0 {
 			// re-apply transforms
-			ds.Sp.applyTransforms(g.Marshal(columnTransforms))
+			if err := ds.Sp.applyTransforms(g.Marshal(columnTransforms)); err != nil {
+				ds.Context.CaptureErr(err)
+			}
 
 			return newReader, true
 		}
@@ -766,18 +840,10 @@ skipBuffer:
 	// add metadata
 	metaValuesMap := map[int]func(it *Iterator) any{}
 	{
-		// ensure there are no duplicates
-		ensureName := func(name string) string {
-			colNames := lo.Keys(ds.Columns.FieldMap(true))
-			for lo.Contains(colNames, strings.ToLower(name)) {
-				name = name + "_"
-			}
-			return name
-		}
+		// the source column indexes, for the row hash
+		srcIndexes := lo.Range(len(ds.Columns))
 
 		if ds.Metadata.LoadedAt.Key != "" && ds.Metadata.LoadedAt.Value != nil {
-			ds.Metadata.LoadedAt.Key = ensureName(ds.Metadata.LoadedAt.Key)
-
 			// handle timestamp value
 			isTimestamp := false
 			if tVal, err := cast.ToTimeE(ds.Metadata.LoadedAt.Value); err == nil {
@@ -790,85 +856,178 @@ skipBuffer:
 			col := Column{
 				Name:        ds.Metadata.LoadedAt.Key,
 				Type:        lo.Ternary(isTimestamp, TimestampzType, IntegerType),
-				Position:    len(ds.Columns) + 1,
 				Description: "Sling.Metadata.LoadedAt",
 				Metadata:    map[string]string{"sling_metadata": "loaded_at"},
 			}
-			ds.Columns = append(ds.Columns, col)
-			metaValuesMap[col.Position-1] = func(it *Iterator) any {
-				return ds.Metadata.LoadedAt.Value
+			index, err := ds.addMetadataColumn(&col)
+			if err != nil {
+				return err
+			} else if index > -1 {
+				ds.Metadata.LoadedAt.Key = col.Name
+				metaValuesMap[index] = func(it *Iterator) any {
+					return ds.Metadata.LoadedAt.Value
+				}
 			}
 		}
 
 		if ds.Metadata.StreamURL.Key != "" && ds.Metadata.StreamURL.Value != nil {
-			ds.Metadata.StreamURL.Key = ensureName(ds.Metadata.StreamURL.Key)
 			col := Column{
 				Name:        ds.Metadata.StreamURL.Key,
 				Type:        StringType,
-				Position:    len(ds.Columns) + 1,
 				Description: "Sling.Metadata.StreamURL",
 				Metadata:    map[string]string{"sling_metadata": "stream_url"},
 			}
-			ds.Columns = append(ds.Columns, col)
-			metaValuesMap[col.Position-1] = func(it *Iterator) any {
-				return ds.Metadata.StreamURL.Value
+			index, err := ds.addMetadataColumn(&col)
+			if err != nil {
+				return err
+			} else if index > -1 {
+				ds.Metadata.StreamURL.Key = col.Name
+				metaValuesMap[index] = func(it *Iterator) any {
+					return ds.Metadata.StreamURL.Value
+				}
 			}
 		}
 
 		if ds.Metadata.RowNum.Key != "" {
-			ds.Metadata.RowNum.Key = ensureName(ds.Metadata.RowNum.Key)
 			col := Column{
 				Name:        ds.Metadata.RowNum.Key,
 				Type:        BigIntType,
-				Position:    len(ds.Columns) + 1,
 				Description: "Sling.Metadata.RowNum",
 				Metadata:    map[string]string{"sling_metadata": "row_num"},
 			}
-			ds.Columns = append(ds.Columns, col)
-			metaValuesMap[col.Position-1] = func(it *Iterator) any {
-				return it.StreamRowNum
+			index, err := ds.addMetadataColumn(&col)
+			if err != nil {
+				return err
+			} else if index > -1 {
+				ds.Metadata.RowNum.Key = col.Name
+				metaValuesMap[index] = func(it *Iterator) any {
+					return it.StreamRowNum
+				}
 			}
 		}
 
 		if ds.Metadata.RowID.Key != "" {
-			ds.Metadata.RowID.Key = ensureName(ds.Metadata.RowID.Key)
 			col := Column{
 				Name:        ds.Metadata.RowID.Key,
 				Type:        StringType,
-				Position:    len(ds.Columns) + 1,
 				Description: "Sling.Metadata.RowID",
 				Metadata:    map[string]string{"sling_metadata": "row_id"},
 			}
-			ds.Columns = append(ds.Columns, col)
-			metaValuesMap[col.Position-1] = func(it *Iterator) any {
-				for {
-					uid, err := ksuid.NewRandom()
-					if err == nil {
-						return uid.String()
+			index, err := ds.addMetadataColumn(&col)
+			if err != nil {
+				return err
+			} else if index > -1 {
+				ds.Metadata.RowID.Key = col.Name
+				metaValuesMap[index] = func(it *Iterator) any {
+					for {
+						uid, err := ksuid.NewRandom()
+						if err == nil {
+							return uid.String()
+						}
 					}
 				}
 			}
 		}
 
 		if ds.Metadata.ExecID.Key != "" {
-			ds.Metadata.ExecID.Key = ensureName(ds.Metadata.ExecID.Key)
 			col := Column{
 				Name:        ds.Metadata.ExecID.Key,
 				Type:        StringType,
-				Position:    len(ds.Columns) + 1,
 				Description: "Sling.Metadata.ExecID",
 				Metadata:    map[string]string{"sling_metadata": "exec_id"},
 			}
-			ds.Columns = append(ds.Columns, col)
-			metaValuesMap[col.Position-1] = func(it *Iterator) any {
-				return ds.Metadata.ExecID.Value
+			index, err := ds.addMetadataColumn(&col)
+			if err != nil {
+				return err
+			} else if index > -1 {
+				ds.Metadata.ExecID.Key = col.Name
+				metaValuesMap[index] = func(it *Iterator) any {
+					return ds.Metadata.ExecID.Value
+				}
+			}
+		}
+
+		if ds.Metadata.RowHash.Key != "" {
+			col := Column{
+				Name:        ds.Metadata.RowHash.Key,
+				Type:        StringType,
+				Description: "Sling.Metadata.RowHash",
+				Metadata:    map[string]string{"sling_metadata": "row_hash"},
+			}
+			index, err := ds.addMetadataColumn(&col)
+			if err != nil {
+				return err
+			} else if index > -1 {
+				ds.Metadata.RowHash.Key = col.Name
+				hasher := SurrogateKey{Type: SurrogateKeyHash}
+				metaValuesMap[index] = func(it *Iterator) any {
+					return hasher.Value(it.Row, srcIndexes)
+				}
+			}
+		}
+
+		if ds.Metadata.FileLine.Key != "" {
+			col := Column{
+				Name:        ds.Metadata.FileLine.Key,
+				Type:        BigIntType,
+				Description: "Sling.Metadata.FileLine",
+				Metadata:    map[string]string{"sling_metadata": "file_line"},
+			}
+			index, err := ds.addMetadataColumn(&col)
+			if err != nil {
+				return err
+			} else if index > -1 {
+				ds.Metadata.FileLine.Key = col.Name
+				metaValuesMap[index] = func(it *Iterator) any {
+					// assumes single-line rows
+					return it.StreamRowNum + cast.ToUint64(ds.Metadata.FileLine.Value)
+				}
+			}
+		}
+	}
+
+	// add surrogate key column
+	if sk := ds.Metadata.SurrogateKey; sk.Name != "" {
+		var indexes []int
+		if sk.Type != SurrogateKeyUUID {
+			for _, name := range sk.Columns {
+				keyCol := ds.Columns.GetColumn(name)
+				if keyCol == nil {
+					return g.Error("surrogate key column '%s' not found", name)
+				}
+				indexes = append(indexes, keyCol.Position-1)
+			}
+			if len(indexes) == 0 {
+				return g.Error("surrogate key of type hash requires columns")
+			}
+		}
+
+		col := Column{
+			Name:        sk.Name,
+			Type:        StringType,
+			Description: "Sling.Metadata.SurrogateKey",
+			Metadata:    map[string]string{"sling_metadata": "surrogate_key"},
+		}
+		index, err := ds.addMetadataColumn(&col)
+		if err != nil {
+			return err
+		} else if index > -1 {
+			ds.Metadata.SurrogateKey.Name = col.Name
+			metaValuesMap[index] = func(it *Iterator) any {
+				return sk.Value(it.Row, indexes)
 			}
 		}
 	}
 
+	// add computed columns
+	computedSetters, err := ds.addComputedColumns()
+	if err != nil {
+		return err
+	}
+
 	// setMetaValues sets mata column values
 	setMetaValues := func(it *Iterator) []any { return it.Row }
-	if len(metaValuesMap) > 0 {
+	if len(metaValuesMap) > 0 || len(computedSetters) > 0 {
 		setMetaValues = func(it *Iterator) []any {
 			for len(it.Row) < len(ds.Columns) {
 				it.Row = append(it.Row, nil)
@@ -876,6 +1035,9 @@ skipBuffer:
 			for i, f := range metaValuesMap {
 				it.Row[i] = f(it)
 			}
+			for _, setValue := range computedSetters {
+				setValue(it)
+			}
 			return it.Row
 		}
 	}
@@ -901,7 +1063,7 @@ skipBuffer:
 		defer ds.Close()
 
 		if ds.CurrentBatch == nil {
-			ds.CurrentBatch = ds.NewBatch(ds.Columns)
+			ds.NewBatch(ds.Columns)
 		}
 
 		row := make([]any, len(ds.Columns))
@@ -940,6 +1102,11 @@ skipBuffer:
 					goto loop
 				}
 
+				// apply filter expression and shard, reprocessed rows were already filtered
+				if !ds.it.RowIsCasted && !(ds.Sp.FilterRow(row, ds.Columns) && ds.Sp.ShardRow(row, ds.Columns)) {
+					goto loop
+				}
+
 				if ds.Limited() {
 					break loop
 				}
@@ -977,7 +1144,7 @@ skipBuffer:
 
 				default:
 					if ds.CurrentBatch.closed {
-						ds.CurrentBatch = ds.NewBatch(ds.Columns)
+						ds.NewBatch(ds.Columns)
 					}
 					break schemaChgLoop
 				}
@@ -999,6 +1166,10 @@ skipBuffer:
 				}
 				break loop
 			default:
+				ds.Sp.rateLimiter.Wait(ds.Context.Ctx, row)
+				if ds.df != nil {
+					ds.df.rateLimiter.Wait(ds.Context.Ctx, row)
+				}
 				ds.CurrentBatch.Push(row)
 			}
 		}
@@ -1031,6 +1202,98 @@ func (ds *Datastream) Rows() chan []any {
 	return rows
 }
 
+// addMetadataColumn adds the metadata column to the datastream columns,
+// resolving any name collision with the source columns according to
+// the metadata collision policy. Returns the index of the column,
+// or -1 if the column was skipped.
+func (ds *Datastream) addMetadataColumn(col *Column) (index int, err error) {
+	existing := ds.Columns.GetColumn(col.Name)
+	if existing == nil {
+		col.Position = len(ds.Columns) + 1
+		ds.Columns = append(ds.Columns, *col)
+		return col.Position - 1, nil
+	}
+
+	switch ds.Metadata.Collision {
+	case MetadataCollisionOverwrite:
+		g.Debug("metadata column %s already exists in source, overwriting values", existing.Name)
+		col.Name = existing.Name
+		col.Position = existing.Position
+		ds.Columns[col.Position-1] = *col
+		return col.Position - 1, nil
+	case MetadataCollisionSkip:
+		g.Debug("metadata column %s already exists in source, skipping", existing.Name)
+		return -1, nil
+	case MetadataCollisionError:
+		return -1, g.Error("metadata column %s already exists in source columns", existing.Name)
+	}
+
+	// ensure there are no duplicates
+	colNames := lo.Keys(ds.Columns.FieldMap(true))
+	for lo.Contains(colNames, strings.ToLower(col.Name)) {
+		col.Name = col.Name + "_"
+	}
+	col.Position = len(ds.Columns) + 1
+	ds.Columns = append(ds.Columns, *col)
+
+	return col.Position - 1, nil
+}
+
+// addComputedColumns adds the columns of the `computed_columns` source option,
+// in alphabetical order. A computed column with the name of an existing column
+// overwrites its values. Returns the functions that set the values of each row.
+func (ds *Datastream) addComputedColumns() (setters []func(it *Iterator), err error) {
+	names := lo.Keys(ds.Sp.Config.ComputedColumns)
+	sort.Strings(names)
+
+	for _, name := range names {
+		expr, err := ParseExpression(ds.Sp.Config.ComputedColumns[name])
+		if err != nil {
+			return nil, g.Error(err, "invalid expression for computed column %s", name)
+		}
+
+		// infer type from the first sampled row
+		colType := StringType
+		if len(ds.Buffer) > 0 {
+			if val, err := expr.EvalRow(ds.Columns, ds.Buffer[0]); err == nil && val != nil {
+				colType = ds.Sp.GetType(val)
+			}
+		}
+
+		var index int
+		if existing := ds.Columns.GetColumn(name); existing != nil {
+			g.Debug("computed column %s already exists in source, overwriting values", existing.Name)
+			index = existing.Position - 1
+			ds.Columns[index].Type = colType
+		} else {
+			col := Column{
+				Name:        name,
+				Type:        colType,
+				Position:    len(ds.Columns) + 1,
+				Description: "Sling.ComputedColumn",
+				Metadata:    map[string]string{"computed": expr.Text},
+			}
+			ds.Columns = append(ds.Columns, col)
+			index = col.Position - 1
+		}
+
+		errored := false
+		setters = append(setters, func(it *Iterator) {
+			val, err := expr.EvalRow(ds.Columns, it.Row)
+			if err != nil {
+				if !errored {
+					ds.Context.CaptureErr(g.Error(err, "could not evaluate computed column %s", name))
+					errored = true
+				}
+				return
+			}
+			it.Row[index] = val
+		})
+	}
+
+	return setters, nil
+}
+
 func (ds *Datastream) SetMetadata(jsonStr string) {
 	if jsonStr != "" {
 		streamValue := ds.Metadata.StreamURL.Value
@@ -1253,6 +1516,7 @@ func (ds *Datastream) ConsumeCsvReaderChl(readerChn chan *ReaderReady) (err erro
 	} else if c.FieldsPerRecord == 0 || len(ds.Columns) != len(row0) {
 		ds.SetFields(CleanHeaderRow(row0))
 	}
+	ds.Metadata.FileLine.Value = lo.Ternary(ds.config.Header, 1, 0)
 
 	var colMap map[int]int
 	nextFunc := func(it *Iterator) bool {
@@ -1419,6 +1683,7 @@ func (ds *Datastream) ConsumeCsvReader(reader io.Reader) (err error) {
 	if c.FieldsPerRecord == 0 || len(ds.Columns) != len(row0) {
 		ds.SetFields(CleanHeaderRow(row0))
 	}
+	ds.Metadata.FileLine.Value = lo.Ternary(ds.config.Header, 1, 0)
 
 	nextFunc := func(it *Iterator) bool {
 
@@ -1644,6 +1909,31 @@ func (ds *Datastream) ConsumeAvroReader(reader io.Reader) (err error) {
 	return ds.ConsumeAvroReaderSeeker(file)
 }
 
+// ConsumeArrowReader uses the provided reader to stream the rows of an arrow IPC stream
+func (ds *Datastream) ConsumeArrowReader(reader io.Reader) (err error) {
+	reader2, err := AutoDecompress(reader)
+	if err != nil {
+		return g.Error(err, "Could not decompress reader")
+	}
+
+	a, err := NewArrowStream(reader2)
+	if err != nil {
+		return g.Error(err, "could create arrow stream")
+	}
+
+	ds.Columns = a.Columns()
+	ds.Inferred = ds.Columns.Sourced()
+	ds.it = ds.NewIterator(ds.Columns, a.nextFunc)
+	ds.SetFileURI()
+
+	err = ds.Start()
+	if err != nil {
+		return g.Error(err, "could start datastream")
+	}
+
+	return
+}
+
 // ConsumeSASReaderSeeker uses the provided reader to stream rows
 func (ds *Datastream) ConsumeSASReaderSeeker(reader io.ReadSeeker) (err error) {
 	s, err := NewSASStream(reader, Columns{})
@@ -1817,6 +2107,70 @@ func (ds *Datastream) Chunk(limit uint64) (chDs chan *Datastream) {
 	return
 }
 
+// ChunkBy splits the datastream into chunk datastreams (in sequence), of up to
+// maxRows rows or maxDuration, whichever comes first (zero is no limit).
+// A chunk is sent once the previous one has been read entirely.
+func (ds *Datastream) ChunkBy(maxRows uint64, maxDuration time.Duration) (chDs chan *Datastream) {
+	chDs = make(chan *Datastream)
+	rows := ds.Rows()
+
+	go func() {
+		defer close(chDs)
+		defer ds.SetEmpty()
+
+		for {
+			firstRow, ok := <-rows
+			if !ok {
+				return
+			}
+
+			var count uint64
+			var once sync.Once
+			exhausted := false
+			start := time.Now()
+			chunkDone := make(chan struct{})
+
+			nextFunc := func(it *Iterator) bool {
+				switch {
+				case firstRow != nil:
+					it.Row, firstRow = firstRow, nil
+				case maxRows > 0 && count >= maxRows, maxDuration > 0 && time.Since(start) >= maxDuration:
+					once.Do(func() { close(chunkDone) })
+					return false
+				default:
+					if it.Row, ok = <-rows; !ok {
+						exhausted = true
+						once.Do(func() { close(chunkDone) })
+						return false
+					}
+				}
+				count++
+				return true
+			}
+
+			nDs := NewDatastreamIt(ds.Context.Ctx, ds.Columns.Clone(), nextFunc)
+			nDs.it.IsCasted = true
+			nDs.Inferred = true
+			nDs.Sp.Config = ds.Sp.Config
+			if err := nDs.Start(); err != nil {
+				ds.Context.CaptureErr(g.Error(err, "could not start chunk datastream"))
+				return
+			}
+			chDs <- nDs
+
+			select {
+			case <-chunkDone:
+			case <-ds.Context.Ctx.Done():
+				return
+			}
+			if exhausted {
+				return
+			}
+		}
+	}()
+	return
+}
+
 // Split splits the datastream into parallel datastreams
 func (ds *Datastream) Split(numStreams ...int) (dss []*Datastream) {
 	// TODO: Split freezes the flow in some situation, such as S3 -> PG.
@@ -1961,7 +2315,7 @@ func (ds *Datastream) MapParallel(transf func([]any) []any, numWorkers int) (nDs
 				break loop
 			default:
 				nDs.Rows() <- transf(row)
-				nDs.Count++
+				atomic.AddUint64(&nDs.Count, 1)
 			}
 		}
 	}
@@ -2036,15 +2390,21 @@ func (ds *Datastream) NewCsvBufferReader(sc StreamConfig) *bytes.Reader {
 }
 
 // NewCsvBufferReaderChnl provides a channel of readers as the limit is reached
-// data is read in memory, whereas NewCsvReaderChnl does not hold in memory
-func (ds *Datastream) NewCsvBufferReaderChnl(sc StreamConfig) (readerChn chan *bytes.Reader) {
+// data is read in memory, whereas NewCsvReaderChnl does not hold in memory.
+// Beyond the SLING_MEM_CAP memory cap, data is spilled to disk.
+func (ds *Datastream) NewCsvBufferReaderChnl(sc StreamConfig) (readerChn chan io.Reader) {
 
-	readerChn = make(chan *bytes.Reader) // not buffered, will block if receiver isn't ready
+	readerChn = make(chan io.Reader) // not buffered, will block if receiver isn't ready
 
 	go func() {
 		defer close(readerChn)
+		memCap := MemCap()
 		for !ds.empty {
-			readerChn <- ds.NewCsvBufferReader(sc)
+			if memCap > 0 {
+				readerChn <- ds.NewCsvSpillReader(sc, memCap)
+			} else {
+				readerChn <- ds.NewCsvBufferReader(sc)
+			}
 		}
 	}()
 
@@ -2304,6 +2664,7 @@ func (ds *Datastream) NewJsonLinesReaderChnl(sc StreamConfig) (readerChn chan *i
 // WARN: Not using this one since it doesn't write Decimals properly.
 func (ds *Datastream) NewParquetArrowReaderChnl(sc StreamConfig) (readerChn chan *BatchReader) {
 	readerChn = make(chan *BatchReader, 100)
+	fileMaxRows := sc.FileMaxRows
 
 	pipeR, pipeW := io.Pipe()
 
@@ -2317,6 +2678,7 @@ func (ds *Datastream) NewParquetArrowReaderChnl(sc StreamConfig) (readerChn chan
 		defer close(readerChn)
 
 		nextPipe := func(batch *Batch) error {
+			fileMaxRows = ds.AdaptiveLimit(sc.FileMaxRows) // wide rows are buffered by the writer
 			if pw != nil {
 				pw.Close()
 			}
@@ -2372,7 +2734,7 @@ func (ds *Datastream) NewParquetArrowReaderChnl(sc StreamConfig) (readerChn chan
 
 				br.Counter++
 
-				if (sc.FileMaxRows > 0 && br.Counter >= sc.FileMaxRows) || (sc.FileMaxBytes > 0 && tbw >= sc.FileMaxBytes) {
+				if (fileMaxRows > 0 && br.Counter >= fileMaxRows) || (sc.FileMaxBytes > 0 && tbw >= sc.FileMaxBytes) {
 					err = nextPipe(batch)
 					if err != nil {
 						ds.Context.CaptureErr(err)
@@ -2427,10 +2789,85 @@ func (ds *Datastream) NewExcelReaderChnl(sc StreamConfig) (readerChn chan *Batch
 
 }
 
+// NewArrowReaderChnl provides a channel of arrow IPC stream readers as the limit is reached
+// each channel flows as fast as the consumer consumes
+func (ds *Datastream) NewArrowReaderChnl(sc StreamConfig) (readerChn chan *BatchReader) {
+	readerChn = make(chan *BatchReader, 100)
+	fileMaxRows := sc.FileMaxRows
+
+	pipeR, pipeW := io.Pipe()
+
+	go func() {
+		var aw *ArrowWriter
+		var br *BatchReader
+
+		defer close(readerChn)
+
+		nextPipe := func(batch *Batch) error {
+			fileMaxRows = ds.AdaptiveLimit(sc.FileMaxRows) // wide rows are buffered by the writer
+			if aw != nil {
+				if err := aw.Close(); err != nil {
+					return g.Error(err, "could not close arrow writer")
+				}
+			}
+
+			pipeW.Close() // close the prior reader
+
+			// new reader
+			pipeR, pipeW = io.Pipe()
+
+			br = &BatchReader{batch, batch.Columns, pipeR, 0}
+			readerChn <- br
+
+			aw = NewArrowWriter(pipeW, batch.Columns)
+			return nil
+		}
+
+		for batch := range ds.BatchChan {
+			if batch.ColumnsChanged() || batch.IsFirst() {
+				if err := nextPipe(batch); err != nil {
+					ds.Context.CaptureErr(err)
+					pipeW.Close()
+					return
+				}
+			}
+
+			for row := range batch.Rows {
+				if err := aw.WriteRow(row); err != nil {
+					ds.Context.CaptureErr(g.Error(err, "error writing row"))
+					ds.Context.Cancel()
+					pipeW.Close()
+					return
+				}
+
+				br.Counter++
+
+				if fileMaxRows > 0 && br.Counter >= fileMaxRows {
+					if err := nextPipe(batch); err != nil {
+						ds.Context.CaptureErr(err)
+						pipeW.Close()
+						return
+					}
+				}
+			}
+		}
+
+		if aw != nil {
+			if err := aw.Close(); err != nil {
+				ds.Context.CaptureErr(g.Error(err, "could not close arrow writer"))
+			}
+		}
+		pipeW.Close()
+	}()
+
+	return readerChn
+}
+
 // NewParquetReaderChnl provides a channel of readers as the limit is reached
 // each channel flows as fast as the consumer consumes
 func (ds *Datastream) NewParquetReaderChnl(sc StreamConfig) (readerChn chan *BatchReader) {
 	readerChn = make(chan *BatchReader, 100)
+	fileMaxRows := sc.FileMaxRows
 
 	pipeR, pipeW := io.Pipe()
 
@@ -2444,6 +2881,7 @@ func (ds *Datastream) NewParquetReaderChnl(sc StreamConfig) (readerChn chan *Bat
 		defer close(readerChn)
 
 		nextPipe := func(batch *Batch) error {
+			fileMaxRows = ds.AdaptiveLimit(sc.FileMaxRows) // wide rows are buffered by the writer
 			if pw != nil {
 				pw.Close()
 			}
@@ -2501,7 +2939,7 @@ func (ds *Datastream) NewParquetReaderChnl(sc StreamConfig) (readerChn chan *Bat
 
 				br.Counter++
 
-				if (sc.FileMaxRows > 0 && br.Counter >= sc.FileMaxRows) || (sc.FileMaxBytes > 0 && tbw >= sc.FileMaxBytes) {
+				if (fileMaxRows > 0 && br.Counter >= fileMaxRows) || (sc.FileMaxBytes > 0 && tbw >= sc.FileMaxBytes) {
 					err = nextPipe(batch)
 					if err != nil {
 						ds.Context.CaptureErr(err)
@@ -1,20 +1,36 @@
 package iop
 
 import (
+	"os"
+	"runtime"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/flarco/g"
 	"github.com/samber/lo"
 )
 
+const (
+	// defaultMemBudget is the target memory of a batch when SLING_MEM_BUDGET is not set
+	defaultMemBudget = 1024 * 1024 * 1024
+
+	// adaptiveMinRows is the minimum number of rows of an adaptive limit,
+	// as well as the rows needed before measuring the average row size
+	adaptiveMinRows = 100
+
+	// adaptiveCheckRows is the interval of rows to re-check the limit of a batch
+	adaptiveCheckRows = 10000
+)
+
 type Batch struct {
 	id         int
 	Columns    Columns
 	Rows       chan []any
 	Previous   *Batch
 	Count      int64
-	Limit      int64
+	Limit      atomic.Int64 // set by Dataflow.SetBatchLimit while running
 	ds         *Datastream
 	closed     bool
 	closeChan  chan struct{}
@@ -25,23 +41,33 @@ type Batch struct {
 // NewBatch create new batch with fixed columns
 // should be used each time column type changes, or columns are added
 func (ds *Datastream) NewBatch(columns Columns) *Batch {
+	ds.Context.Lock()
+	batchLimit := ds.Sp.Config.BatchLimit // set by Dataflow.SetBatchLimit while running
+	ds.Context.Unlock()
+
 	batch := &Batch{
 		id:         len(ds.Batches),
 		Columns:    columns,
 		Rows:       MakeRowsChan(),
 		Previous:   ds.LatestBatch(),
 		ds:         ds,
-		Limit:      ds.Sp.Config.BatchLimit,
 		closeChan:  make(chan struct{}),
 		transforms: []func(row []any) []any{},
 		context:    g.NewContext(ds.Context.Ctx),
 	}
+	batch.Limit.Store(ds.AdaptiveLimit(batchLimit))
+
+	if limit := batch.Limit.Load(); limit != batchLimit && (batch.Previous == nil || batch.Previous.Limit.Load() != limit) {
+		g.Debug("adaptive batch limit set to %d rows", limit)
+	}
 
 	if batch.Previous != nil && !batch.Previous.closed {
 		batch.Previous.Close() // close previous batch
 	}
+	ds.Context.Lock()
 	ds.Batches = append(ds.Batches, batch)
-	ds.CurrentBatch = batch
+	ds.CurrentBatch = batch // read by Dataflow.SetBatchLimit
+	ds.Context.Unlock()
 	if !ds.closed {
 		ds.BatchChan <- batch
 	}
@@ -86,6 +112,10 @@ func (b *Batch) Close() {
 	b.context.Lock()
 	defer b.context.Unlock()
 	if !b.closed {
+		if err := b.ds.Sp.flushTransforms(); err != nil {
+			b.ds.Sp.captureErr(err)
+		}
+
 		timer := time.NewTimer(4 * time.Millisecond)
 		select {
 		case b.closeChan <- struct{}{}:
@@ -206,12 +236,76 @@ func (b *Batch) Push(row []any) {
 		b.ds.schemaChgChan <- v
 	case b.Rows <- newRow:
 		b.Count++
-		b.ds.Count++
+		atomic.AddUint64(&b.ds.Count, 1)
 		b.ds.bwRows <- newRow
 		b.ds.Sp.commitChecksum()
 
-		if b.Limit > 0 && b.Count == b.Limit {
+		limit := b.Limit.Load()
+		if limit > 0 && b.Count%adaptiveCheckRows == 0 {
+			limit = b.ds.AdaptiveLimit(limit)
+			b.Limit.Store(limit)
+		}
+
+		if limit > 0 && b.Count >= limit {
 			b.Close()
 		}
 	}
 }
+
+// MemBudget returns the target memory in bytes of a batch, from the
+// SLING_MEM_BUDGET env var (e.g. `512MB`). Zero disables adaptive limits.
+func MemBudget() int64 {
+	budget, _ := parseMemBudget()
+	return budget
+}
+
+// parseMemBudget returns the memory budget, and whether it was set explicitly
+func parseMemBudget() (budget int64, set bool) {
+	val := os.Getenv("SLING_MEM_BUDGET")
+	if val == "" {
+		return defaultMemBudget, false
+	}
+
+	parsed, err := humanize.ParseBytes(val)
+	if err != nil {
+		g.Warn("invalid SLING_MEM_BUDGET value: %s", val)
+		return defaultMemBudget, false
+	}
+	return int64(parsed), true
+}
+
+// AdaptiveLimit lowers a row limit so that a batch of rows of the average
+// size measured so far fits in the memory budget. The limit is halved when
+// the heap is already beyond an explicit budget. A zero limit is left unlimited.
+func (ds *Datastream) AdaptiveLimit(limit int64) int64 {
+	ds.memBudgetOnce.Do(func() { ds.memBudget, ds.memBudgetSet = parseMemBudget() })
+
+	budget := ds.memBudget
+	if limit <= 0 || budget <= 0 || ds.Count < adaptiveMinRows {
+		return limit
+	}
+
+	avgRowBytes := int64(ds.Bytes.Load() / ds.Count)
+	if avgRowBytes == 0 {
+		return limit // bytes not measured
+	}
+
+	adaptive := budget / avgRowBytes
+
+	// reading the heap stops the world, so only with an explicit budget
+	if ds.memBudgetSet {
+		var memStats runtime.MemStats
+		runtime.ReadMemStats(&memStats)
+		if int64(memStats.HeapInuse) > budget {
+			adaptive = adaptive / 2
+		}
+	}
+
+	if adaptive < adaptiveMinRows {
+		adaptive = adaptiveMinRows
+	}
+	if adaptive < limit {
+		return adaptive
+	}
+	return limit
+}
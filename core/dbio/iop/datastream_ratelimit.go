@@ -0,0 +1,136 @@
+package iop
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/flarco/g"
+	"github.com/spf13/cast"
+)
+
+// RateLimit is a throughput limit, in rows and/or bytes per second
+type RateLimit struct {
+	RowsPerSec  float64
+	BytesPerSec float64
+}
+
+// ParseRateLimit parses a rate limit such as `5000` or `5000 rows/s`
+// (rows per second), `10MB/s` (bytes per second), or both comma separated
+func ParseRateLimit(val string) (rl RateLimit, err error) {
+	for _, part := range strings.Split(val, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+
+		part = strings.TrimSuffix(strings.TrimSuffix(part, "/sec"), "/s")
+		if num, ok := strings.CutSuffix(part, "rows"); ok || cast.ToFloat64(part) > 0 {
+			if rl.RowsPerSec, err = cast.ToFloat64E(strings.TrimSpace(num)); err != nil || rl.RowsPerSec <= 0 {
+				return rl, g.Error("invalid rate_limit (%s), must be rows or bytes per second, e.g. `5000 rows/s` or `10MB/s`", val)
+			}
+			continue
+		}
+
+		bytes, err := humanize.ParseBytes(part)
+		if err != nil || bytes == 0 {
+			return rl, g.Error("invalid rate_limit (%s), must be rows or bytes per second, e.g. `5000 rows/s` or `10MB/s`", val)
+		}
+		rl.BytesPerSec = float64(bytes)
+	}
+
+	if rl.RowsPerSec == 0 && rl.BytesPerSec == 0 {
+		return rl, g.Error("invalid rate_limit (%s), must be rows or bytes per second, e.g. `5000 rows/s` or `10MB/s`", val)
+	}
+	return rl, nil
+}
+
+// tokenBucket allows a rate of tokens per second, with bursts of up to one second
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+	mux    sync.Mutex
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	return &tokenBucket{rate: rate, tokens: rate, last: time.Now()}
+}
+
+// take removes n tokens, and returns how long to wait for the bucket to be positive
+func (tb *tokenBucket) take(n float64) time.Duration {
+	tb.mux.Lock()
+	defer tb.mux.Unlock()
+
+	now := time.Now()
+	tb.tokens = min(tb.rate, tb.tokens+now.Sub(tb.last).Seconds()*tb.rate)
+	tb.last = now
+
+	tb.tokens -= n
+	if tb.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+}
+
+// RateLimiter throttles rows to a RateLimit
+type RateLimiter struct {
+	Limit RateLimit
+	rows  *tokenBucket
+	bytes *tokenBucket
+}
+
+// NewRateLimiter creates a rate limiter
+func NewRateLimiter(rl RateLimit) *RateLimiter {
+	limiter := &RateLimiter{Limit: rl}
+	if rl.RowsPerSec > 0 {
+		limiter.rows = newTokenBucket(rl.RowsPerSec)
+	}
+	if rl.BytesPerSec > 0 {
+		limiter.bytes = newTokenBucket(rl.BytesPerSec)
+	}
+	return limiter
+}
+
+// Wait blocks until the row is allowed through, or the context is done
+func (rl *RateLimiter) Wait(ctx context.Context, row []any) {
+	if rl == nil {
+		return
+	}
+
+	var wait time.Duration
+	if rl.rows != nil {
+		wait = rl.rows.take(1)
+	}
+	if rl.bytes != nil {
+		wait = max(wait, rl.bytes.take(float64(rowBytes(row))))
+	}
+	if wait <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// rowBytes approximates the size of a row
+func rowBytes(row []any) (size int) {
+	for _, val := range row {
+		switch v := val.(type) {
+		case nil:
+		case string:
+			size += len(v)
+		case []byte:
+			size += len(v)
+		default:
+			size += 8
+		}
+	}
+	return size
+}
@@ -0,0 +1,113 @@
+package iop
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"github.com/dustin/go-humanize"
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/env"
+)
+
+// bufferedBytes is the number of bytes held in memory by buffered readers
+var bufferedBytes atomic.Int64
+
+// MemCap returns the maximum bytes buffered readers can hold in memory, from
+// the SLING_MEM_CAP env var (e.g. `2GB`). Zero means no cap.
+func MemCap() int64 {
+	val := os.Getenv("SLING_MEM_CAP")
+	if val == "" {
+		return 0
+	}
+
+	memCap, err := humanize.ParseBytes(val)
+	if err != nil {
+		g.Warn("invalid SLING_MEM_CAP value: %s", val)
+		return 0
+	}
+	return int64(memCap)
+}
+
+// NewCsvSpillReader creates a Reader like NewCsvBufferReader, but once the
+// memory cap is hit, the data is spilled to a compressed temp file instead
+func (ds *Datastream) NewCsvSpillReader(sc StreamConfig, memCap int64) io.Reader {
+	reader := ds.NewCsvReader(sc)
+
+	available := memCap - bufferedBytes.Load()
+	if available < 0 {
+		available = 0
+	}
+
+	buf := bytes.NewBuffer(nil)
+	n, err := io.Copy(buf, io.LimitReader(reader, available))
+	if err != nil {
+		ds.Context.CaptureErr(g.Error(err, "Error io.Copy(reader)"))
+	}
+
+	// all the data fit in memory
+	if n < available || err != nil {
+		bufferedBytes.Add(n)
+		return &spillReader{Reader: bytes.NewReader(buf.Bytes()), size: n}
+	}
+
+	file, err := os.CreateTemp(env.GetTempFolder(), "sling-spill-*.zst")
+	if err != nil {
+		ds.Context.CaptureErr(g.Error(err, "could not create spill file"))
+		return bytes.NewReader(nil)
+	}
+
+	compressor := NewCompressor(ZStandardCompressorType)
+	size, err := io.Copy(file, compressor.Compress(io.MultiReader(buf, reader)))
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		ds.Context.CaptureErr(g.Error(err, "could not write spill file"))
+		file.Close()
+		os.Remove(file.Name())
+		return bytes.NewReader(nil)
+	}
+	g.Debug("memory cap of %s reached, spilled %s to %s", humanize.Bytes(uint64(memCap)), humanize.Bytes(uint64(size)), file.Name())
+
+	dReader, err := compressor.Decompress(file)
+	if err != nil {
+		ds.Context.CaptureErr(g.Error(err, "could not read spill file"))
+		file.Close()
+		os.Remove(file.Name())
+		return bytes.NewReader(nil)
+	}
+
+	return &spillReader{Reader: dReader, file: file}
+}
+
+// spillReader releases its memory or deletes its spill file once fully read
+type spillReader struct {
+	io.Reader
+	size int64
+	file *os.File
+	done bool
+}
+
+func (r *spillReader) Read(p []byte) (n int, err error) {
+	n, err = r.Reader.Read(p)
+	if err != nil {
+		r.Close()
+	}
+	return
+}
+
+func (r *spillReader) Close() error {
+	if r.done {
+		return nil
+	}
+	r.done = true
+
+	bufferedBytes.Add(-r.size)
+	if r.file != nil {
+		r.file.Close()
+		return os.Remove(r.file.Name())
+	}
+	return nil
+}
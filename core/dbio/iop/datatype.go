@@ -101,7 +101,23 @@ func (kt KeyType) MetadataKey() string {
 	return string(kt) + "_key"
 }
 
-var KeyTypes = []KeyType{AggregateKey, ClusterKey, DuplicateKey, HashKey, IndexKey, PartitionKey, PrimaryKey, SortKey, UniqueKey, UpdateKey}
+// Normalize returns the key type of an alias (e.g. `partition_by`, `cluster_by`, `sort_key`)
+func (kt KeyType) Normalize() KeyType {
+	if keyType, ok := keyTypeAliases[KeyType(strings.ToLower(string(kt)))]; ok {
+		return keyType
+	}
+	return kt
+}
+
+var KeyTypes = []KeyType{AggregateKey, ClusterKey, DistributionKey, DuplicateKey, HashKey, IndexKey, PartitionKey, PrimaryKey, SortKey, UniqueKey, UpdateKey}
+
+var keyTypeAliases = map[KeyType]KeyType{
+	"partition_by": PartitionKey,
+	"cluster_by":   ClusterKey,
+	"sort_key":     SortKey,
+	"order_by":     SortKey,
+	"dist_key":     DistributionKey,
+}
 
 // ColumnStats holds statistics for a column
 type ColumnStats struct {
@@ -122,7 +138,38 @@ type ColumnStats struct {
 	TotalCnt     int64  `json:"total_cnt"`
 	UniqCnt      int64  `json:"uniq_cnt"`
 	Checksum     uint64 `json:"checksum"`
-	LastVal      any    `json:"-"` // last non-empty value. useful for state incremental
+	LastVal      any    `json:"-"` // last non-empty value
+	MaxVal       any    `json:"-"` // max non-empty value. useful for state incremental
+}
+
+// setMaxVal keeps the max non-empty value, compared according to the column type
+func (cs *ColumnStats) setMaxVal(val any, colType ColumnType) {
+	if val == nil || val == "" {
+		return
+	} else if cs.MaxVal == nil {
+		cs.MaxVal = val
+		return
+	}
+
+	greater := false
+	switch {
+	case colType.IsInteger():
+		greater = cast.ToInt64(val) > cast.ToInt64(cs.MaxVal)
+	case colType.IsNumber():
+		greater = cast.ToFloat64(val) > cast.ToFloat64(cs.MaxVal)
+	default:
+		valTime, ok1 := val.(time.Time)
+		maxTime, ok2 := cs.MaxVal.(time.Time)
+		if ok1 && ok2 {
+			greater = valTime.After(maxTime)
+		} else {
+			greater = cast.ToString(val) > cast.ToString(cs.MaxVal)
+		}
+	}
+
+	if greater {
+		cs.MaxVal = val
+	}
 }
 
 func (cs *ColumnStats) DistinctPercent() float64 {
@@ -263,7 +310,7 @@ func (cols Columns) SetKeys(keyType KeyType, colNames ...string) (err error) {
 				found = true
 			}
 		}
-		if !found && !g.In(keyType, ClusterKey, PartitionKey, SortKey) {
+		if !found && !g.In(keyType, ClusterKey, DistributionKey, PartitionKey, SortKey) {
 			return g.Error("could not set %s key. Did not find column %s", keyType, colName)
 		}
 	}
@@ -559,6 +606,8 @@ func (cols Columns) Coerce(castCols Columns, hasHeader bool) (newCols Columns) {
 			if !newCols[i].Type.IsValid() {
 				g.Warn("Provided unknown column type (%s) for column '%s'. Using string.", newCols[i].Type, newCols[i].Name)
 				newCols[i].Type = StringType
+			} else {
+				newCols[i].SetMetadata("declared", "true")
 			}
 			continue
 		}
@@ -572,6 +621,7 @@ func (cols Columns) Coerce(castCols Columns, hasHeader bool) (newCols Columns) {
 				newCols[i].DbPrecision = lo.Ternary(col.DbPrecision > 0, col.DbPrecision, newCols[i].DbPrecision)
 				newCols[i].DbScale = lo.Ternary(col.DbScale > 0, col.DbScale, newCols[i].DbScale)
 				newCols[i].Sourced = true
+				newCols[i].SetMetadata("declared", "true")
 			} else {
 				g.Warn("Provided unknown column type (%s) for column '%s'. Using string.", col.Type, col.Name)
 				newCols[i].Type = StringType
@@ -897,6 +947,12 @@ func (col *Column) SetMetadata(key string, value string) {
 	col.Metadata[key] = value
 }
 
+// IsDeclared returns whether the column type was declared with the columns option,
+// in which case the declared length, precision and scale are used as-is
+func (col *Column) IsDeclared() bool {
+	return col.Metadata != nil && cast.ToBool(col.Metadata["declared"])
+}
+
 func (col *Column) IsKeyType(keyType KeyType) bool {
 	if col.Metadata == nil {
 		return false
@@ -1003,6 +1059,51 @@ func (col *Column) IsDatetime() bool {
 	return col.Type.IsDatetime()
 }
 
+// columnTypeAliases maps common native type names to general column types,
+// so that types such as `varchar(50)` can be declared in the columns option
+var columnTypeAliases = map[string]ColumnType{
+	"varchar":                  StringType,
+	"nvarchar":                 StringType,
+	"char":                     StringType,
+	"nchar":                    StringType,
+	"character":                StringType,
+	"character varying":        StringType,
+	"varchar2":                 StringType,
+	"clob":                     TextType,
+	"numeric":                  DecimalType,
+	"number":                   DecimalType,
+	"int":                      IntegerType,
+	"int4":                     IntegerType,
+	"int8":                     BigIntType,
+	"int2":                     SmallIntType,
+	"double":                   FloatType,
+	"double precision":         FloatType,
+	"real":                     FloatType,
+	"float8":                   FloatType,
+	"boolean":                  BoolType,
+	"timestamptz":              TimestampzType,
+	"timestamp with time zone": TimestampzType,
+	"timetz":                   TimezType,
+	"jsonb":                    JsonType,
+	"varbinary":                BinaryType,
+	"bytea":                    BinaryType,
+	"blob":                     BinaryType,
+}
+
+// Resolve returns the general column type of a type alias (e.g. `varchar(50)`
+// becomes `string(50)`), keeping any length, precision and scale suffix
+func (ct ColumnType) Resolve() ColumnType {
+	base, suffix := strings.TrimSpace(string(ct)), ""
+	if i := strings.Index(base, "("); i > 0 {
+		base, suffix = strings.TrimSpace(base[:i]), base[i:]
+	}
+
+	if colType, ok := columnTypeAliases[strings.ToLower(base)]; ok {
+		return ColumnType(string(colType) + suffix)
+	}
+	return ct
+}
+
 // IsBinary returns whether the column is a binary
 func (ct ColumnType) IsBinary() bool {
 	switch ct {
@@ -1137,7 +1238,7 @@ func (col *Column) GetNativeType(t dbio.Type) (nativeType string, err error) {
 			maxStringType := template.Value("variable.max_string_type")
 			if !isSourced && maxStringType != "" {
 				nativeType = maxStringType // use specified default
-			} else if length > 255 {
+			} else if length > 255 && !(isSourced && col.IsDeclared()) {
 				// let's make text since high
 				nativeType = template.GeneralTypeMap["text"]
 			} else {
@@ -1183,6 +1284,9 @@ func (col *Column) GetNativeType(t dbio.Type) (nativeType string, err error) {
 			"(,)",
 			fmt.Sprintf("(%d,%d)", precision, scale),
 		)
+	} else if nativeType == "numeric" && col.IsDecimal() && col.IsDeclared() && col.DbPrecision > 0 {
+		// unbounded numeric (postgres, bigquery), use declared precision
+		nativeType = fmt.Sprintf("numeric(%d,%d)", col.DbPrecision, col.DbScale)
 	}
 
 	return
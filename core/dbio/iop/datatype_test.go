@@ -6,6 +6,7 @@ import (
 
 	"github.com/flarco/g"
 	"github.com/shopspring/decimal"
+	"github.com/slingdata-io/sling-cli/core/dbio"
 	"github.com/spf13/cast"
 	"github.com/stretchr/testify/assert"
 )
@@ -338,6 +339,55 @@ func TestAddColumns(t *testing.T) {
 	g.Debug("%#v", df.Columns.Names())
 }
 
+func TestDeclaredColumnTypes(t *testing.T) {
+	declared := Columns{
+		{Name: "amount", Type: ColumnType("decimal(38,9)").Resolve()},
+		{Name: "code", Type: ColumnType("varchar(50)").Resolve()},
+		{Name: "notes", Type: ColumnType("VARCHAR(1000)").Resolve()},
+		{Name: "created_at", Type: ColumnType("timestamptz").Resolve()},
+	}
+	for i := range declared {
+		declared[i].SetLengthPrecisionScale()
+	}
+	assert.Equal(t, DecimalType, declared[0].Type)
+	assert.Equal(t, StringType, declared[1].Type)
+	assert.Equal(t, TimestampzType, declared[3].Type)
+
+	cols := NewColumnsFromFields("amount", "code", "notes", "created_at", "other")
+	cols = cols.Coerce(declared, true)
+	assert.True(t, cols[0].IsDeclared())
+	assert.False(t, cols[4].IsDeclared())
+
+	nativeType, err := cols[0].GetNativeType(dbio.TypeDbPostgres)
+	assert.NoError(t, err)
+	assert.Equal(t, "numeric(38,9)", nativeType)
+
+	nativeType, err = cols[1].GetNativeType(dbio.TypeDbPostgres)
+	assert.NoError(t, err)
+	assert.Equal(t, "varchar(50)", nativeType)
+
+	// declared length is kept, even if above 255
+	nativeType, err = cols[2].GetNativeType(dbio.TypeDbPostgres)
+	assert.NoError(t, err)
+	assert.Equal(t, "varchar(1000)", nativeType)
+
+	nativeType, err = cols[3].GetNativeType(dbio.TypeDbPostgres)
+	assert.NoError(t, err)
+	assert.Equal(t, "timestamptz", nativeType)
+}
+
+func TestKeyTypeNormalize(t *testing.T) {
+	assert.Equal(t, PartitionKey, KeyType("partition_by").Normalize())
+	assert.Equal(t, ClusterKey, KeyType("Cluster_By").Normalize())
+	assert.Equal(t, SortKey, KeyType("sort_key").Normalize())
+	assert.Equal(t, DistributionKey, KeyType("distribution").Normalize())
+	assert.Equal(t, KeyType("other"), KeyType("other").Normalize())
+
+	cols := NewColumns(Column{Name: "id"})
+	assert.NoError(t, cols.SetKeys(DistributionKey, "all")) // expression, not a column
+	assert.Error(t, cols.SetKeys(IndexKey, "missing"))
+}
+
 func TestCleanName(t *testing.T) {
 	names := []string{
 		"great-one!9",
@@ -372,3 +422,26 @@ func TestParseString(t *testing.T) {
 	g.P(val)
 	g.P(cast.ToTime(val).Location().String() == "UTC")
 }
+
+func TestColumnStatsMaxVal(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		colType  ColumnType
+		values   []any
+		expected any
+	}{
+		{colType: BigIntType, values: []any{int64(9), int64(10), int64(2)}, expected: int64(10)},
+		{colType: DecimalType, values: []any{"9.5", "10.25", nil, "1"}, expected: "10.25"},
+		{colType: TimestampType, values: []any{day(3), day(12), day(1)}, expected: day(12)},
+		{colType: StringType, values: []any{"b", "", "c", "a"}, expected: "c"},
+		{colType: StringType, values: []any{nil, ""}, expected: nil},
+	}
+
+	for _, tt := range tests {
+		stats := ColumnStats{}
+		for _, val := range tt.values {
+			stats.setMaxVal(val, tt.colType)
+		}
+		assert.Equal(t, tt.expected, stats.MaxVal, tt.colType)
+	}
+}
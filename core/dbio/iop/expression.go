@@ -0,0 +1,824 @@
+package iop
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/flarco/g"
+	"github.com/spf13/cast"
+)
+
+// Expression is a row-level expression, evaluated against the values of a row.
+// Supports column references, literals ('text', 1.5, true, false, null),
+// comparisons (==, !=, <>, <, <=, >, >=), logical operators (&&, ||, !, and, or, not),
+// arithmetic (+, -, *, /, %), `is [not] null`, `[not] in (...)` and functions.
+// `||` is a logical or between booleans, and a string concatenation otherwise.
+// Examples: `status == 'active' && amount > 0`, `first_name || ' ' || last_name`
+type Expression struct {
+	Text string
+
+	root     exprNode
+	colIndex map[string]int
+	colCount int
+}
+
+// ExpressionFunc is a function callable in an expression
+type ExpressionFunc func(args ...any) (any, error)
+
+// ExpressionFunctions are the functions available in expressions
+var ExpressionFunctions = map[string]ExpressionFunc{
+	"lower": func(args ...any) (any, error) {
+		if err := exprCheckArgs("lower", args, 1, 1); err != nil || args[0] == nil {
+			return nil, err
+		}
+		return strings.ToLower(cast.ToString(args[0])), nil
+	},
+	"upper": func(args ...any) (any, error) {
+		if err := exprCheckArgs("upper", args, 1, 1); err != nil || args[0] == nil {
+			return nil, err
+		}
+		return strings.ToUpper(cast.ToString(args[0])), nil
+	},
+	"trim": func(args ...any) (any, error) {
+		if err := exprCheckArgs("trim", args, 1, 1); err != nil || args[0] == nil {
+			return nil, err
+		}
+		return strings.TrimSpace(cast.ToString(args[0])), nil
+	},
+	"length": func(args ...any) (any, error) {
+		if err := exprCheckArgs("length", args, 1, 1); err != nil || args[0] == nil {
+			return nil, err
+		}
+		return int64(len([]rune(cast.ToString(args[0])))), nil
+	},
+	"coalesce": func(args ...any) (any, error) {
+		for _, arg := range args {
+			if arg != nil {
+				return arg, nil
+			}
+		}
+		return nil, nil
+	},
+	"concat": func(args ...any) (any, error) {
+		var sb strings.Builder
+		for _, arg := range args {
+			if arg != nil {
+				sb.WriteString(cast.ToString(arg))
+			}
+		}
+		return sb.String(), nil
+	},
+	"replace": func(args ...any) (any, error) {
+		if err := exprCheckArgs("replace", args, 3, 3); err != nil || args[0] == nil {
+			return nil, err
+		}
+		return strings.ReplaceAll(cast.ToString(args[0]), cast.ToString(args[1]), cast.ToString(args[2])), nil
+	},
+	"substr": func(args ...any) (any, error) {
+		if err := exprCheckArgs("substr", args, 2, 3); err != nil || args[0] == nil {
+			return nil, err
+		}
+
+		// start is 1-based, like in SQL
+		runes := []rune(cast.ToString(args[0]))
+		start := max(cast.ToInt(args[1])-1, 0)
+		start = min(start, len(runes))
+		end := len(runes)
+		if len(args) == 3 {
+			end = min(start+max(cast.ToInt(args[2]), 0), len(runes))
+		}
+		return string(runes[start:end]), nil
+	},
+	"abs": func(args ...any) (any, error) {
+		if err := exprCheckArgs("abs", args, 1, 1); err != nil || args[0] == nil {
+			return nil, err
+		}
+		f, isInt, ok := exprNumber(args[0])
+		if !ok {
+			return nil, g.Error("invalid number for abs: %#v", args[0])
+		} else if isInt {
+			return int64(math.Abs(f)), nil
+		}
+		return math.Abs(f), nil
+	},
+	"round": func(args ...any) (any, error) {
+		if err := exprCheckArgs("round", args, 1, 2); err != nil || args[0] == nil {
+			return nil, err
+		}
+		f, _, ok := exprNumber(args[0])
+		if !ok {
+			return nil, g.Error("invalid number for round: %#v", args[0])
+		}
+		decimals := 0
+		if len(args) == 2 {
+			decimals = cast.ToInt(args[1])
+		}
+		pow := math.Pow(10, float64(decimals))
+		return math.Round(f*pow) / pow, nil
+	},
+	"now": func(args ...any) (any, error) {
+		if err := exprCheckArgs("now", args, 0, 0); err != nil {
+			return nil, err
+		}
+		return time.Now(), nil
+	},
+}
+
+func exprCheckArgs(name string, args []any, min, max int) error {
+	if len(args) < min || (max > -1 && len(args) > max) {
+		return g.Error("invalid number of arguments for function %s (got %d)", name, len(args))
+	}
+	return nil
+}
+
+// ParseExpression parses the expression text
+func ParseExpression(text string) (expr *Expression, err error) {
+	tokens, err := exprTokenize(text)
+	if err != nil {
+		return nil, g.Error(err, "could not parse expression: %s", text)
+	}
+
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, g.Error(err, "could not parse expression: %s", text)
+	} else if p.peek().kind != exprTokenEOF {
+		return nil, g.Error("could not parse expression: %s\nunexpected token '%s'", text, p.peek().text)
+	}
+
+	return &Expression{Text: text, root: root}, nil
+}
+
+// Eval evaluates the expression, using getValue to obtain column values
+func (e *Expression) Eval(getValue func(name string) (any, bool)) (any, error) {
+	return e.root.eval(getValue)
+}
+
+// EvalRow evaluates the expression against a row
+func (e *Expression) EvalRow(columns Columns, row []any) (any, error) {
+	if e.colIndex == nil || e.colCount != len(columns) {
+		e.colIndex = columns.FieldMap(true)
+		e.colCount = len(columns)
+	}
+
+	return e.Eval(func(name string) (any, bool) {
+		i, ok := e.colIndex[strings.ToLower(name)]
+		if !ok {
+			return nil, false
+		} else if i >= len(row) {
+			return nil, true
+		}
+		return row[i], true
+	})
+}
+
+// EvalRowBool evaluates the expression against a row, as a boolean
+func (e *Expression) EvalRowBool(columns Columns, row []any) (bool, error) {
+	val, err := e.EvalRow(columns, row)
+	if err != nil {
+		return false, err
+	}
+	return exprTruthy(val), nil
+}
+
+/////////////////////////////////////// tokenizer
+
+type exprTokenKind int
+
+const (
+	exprTokenEOF exprTokenKind = iota
+	exprTokenIdent
+	exprTokenNumber
+	exprTokenString
+	exprTokenOperator
+)
+
+type exprToken struct {
+	kind   exprTokenKind
+	text   string
+	quoted bool // for quoted identifiers
+}
+
+func (t exprToken) is(texts ...string) bool {
+	if t.kind == exprTokenString || t.kind == exprTokenNumber || t.quoted {
+		return false
+	}
+	for _, text := range texts {
+		if strings.EqualFold(t.text, text) {
+			return true
+		}
+	}
+	return false
+}
+
+func exprTokenize(text string) (tokens []exprToken, err error) {
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '\'':
+			// string literal, with '' as escaped quote
+			var sb strings.Builder
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, g.Error("unterminated string literal")
+				} else if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						sb.WriteRune('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: exprTokenString, text: sb.String()})
+		case r == '"' || r == '`':
+			// quoted identifier
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, g.Error("unterminated quoted identifier")
+			}
+			tokens = append(tokens, exprToken{kind: exprTokenIdent, text: string(runes[i+1 : end]), quoted: true})
+			i = end + 1
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			end := i
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, exprToken{kind: exprTokenNumber, text: string(runes[i:end])})
+			i = end
+		case unicode.IsLetter(r) || r == '_':
+			end := i
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_' || runes[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, exprToken{kind: exprTokenIdent, text: string(runes[i:end])})
+			i = end
+		default:
+			matched := false
+			for _, op := range []string{"==", "!=", "<>", "<=", ">=", "&&", "||", "<", ">", "=", "!", "+", "-", "*", "/", "%", "(", ")", ","} {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					tokens = append(tokens, exprToken{kind: exprTokenOperator, text: op})
+					i += len([]rune(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, g.Error("unexpected character '%s'", string(r))
+			}
+		}
+	}
+
+	tokens = append(tokens, exprToken{kind: exprTokenEOF})
+	return
+}
+
+/////////////////////////////////////// parser
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.pos]
+	if t.kind != exprTokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) expect(text string) error {
+	if t := p.next(); !t.is(text) {
+		return g.Error("expected '%s', got '%s'", text, t.text)
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (node exprNode, err error) {
+	if node, err = p.parseAnd(); err != nil {
+		return
+	}
+	for p.peek().is("||", "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		node = &exprBinary{op: "||", left: node, right: right}
+	}
+	return
+}
+
+func (p *exprParser) parseAnd() (node exprNode, err error) {
+	if node, err = p.parseNot(); err != nil {
+		return
+	}
+	for p.peek().is("&&", "and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		node = &exprBinary{op: "&&", left: node, right: right}
+	}
+	return
+}
+
+func (p *exprParser) parseNot() (node exprNode, err error) {
+	if p.peek().is("!", "not") {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &exprUnary{op: "!", operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (node exprNode, err error) {
+	if node, err = p.parseAdditive(); err != nil {
+		return
+	}
+
+	t := p.peek()
+	switch {
+	case t.is("==", "=", "!=", "<>", "<", "<=", ">", ">="):
+		op := p.next().text
+		switch op {
+		case "=":
+			op = "=="
+		case "<>":
+			op = "!="
+		}
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &exprBinary{op: op, left: node, right: right}, nil
+	case t.is("is"):
+		p.next()
+		negate := false
+		if p.peek().is("not") {
+			p.next()
+			negate = true
+		}
+		if err = p.expect("null"); err != nil {
+			return nil, err
+		}
+		return &exprIsNull{operand: node, negate: negate}, nil
+	case t.is("in"), t.is("not") && p.tokens[p.pos+1].is("in"):
+		negate := false
+		if p.next().is("not") {
+			p.next()
+			negate = true
+		}
+		args, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		return &exprIn{operand: node, list: args, negate: negate}, nil
+	}
+
+	return
+}
+
+func (p *exprParser) parseAdditive() (node exprNode, err error) {
+	if node, err = p.parseMultiplicative(); err != nil {
+		return
+	}
+	for p.peek().is("+", "-") {
+		op := p.next().text
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		node = &exprBinary{op: op, left: node, right: right}
+	}
+	return
+}
+
+func (p *exprParser) parseMultiplicative() (node exprNode, err error) {
+	if node, err = p.parseUnary(); err != nil {
+		return
+	}
+	for p.peek().is("*", "/", "%") {
+		op := p.next().text
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		node = &exprBinary{op: op, left: node, right: right}
+	}
+	return
+}
+
+func (p *exprParser) parseUnary() (node exprNode, err error) {
+	if p.peek().is("-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &exprUnary{op: "-", operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parseArgs parses a parenthesized, comma separated list of expressions
+func (p *exprParser) parseArgs() (args []exprNode, err error) {
+	if err = p.expect("("); err != nil {
+		return nil, err
+	}
+	if p.peek().is(")") {
+		p.next()
+		return
+	}
+	for {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+
+		if t := p.next(); t.is(")") {
+			return args, nil
+		} else if !t.is(",") {
+			return nil, g.Error("expected ',' or ')', got '%s'", t.text)
+		}
+	}
+}
+
+func (p *exprParser) parsePrimary() (node exprNode, err error) {
+	t := p.next()
+	switch t.kind {
+	case exprTokenNumber:
+		if strings.Contains(t.text, ".") {
+			val, err := cast.ToFloat64E(t.text)
+			if err != nil {
+				return nil, g.Error("invalid number: %s", t.text)
+			}
+			return &exprLiteral{value: val}, nil
+		}
+		val, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, g.Error("invalid number: %s", t.text)
+		}
+		return &exprLiteral{value: val}, nil
+	case exprTokenString:
+		return &exprLiteral{value: t.text}, nil
+	case exprTokenIdent:
+		switch {
+		case t.is("true"):
+			return &exprLiteral{value: true}, nil
+		case t.is("false"):
+			return &exprLiteral{value: false}, nil
+		case t.is("null"):
+			return &exprLiteral{value: nil}, nil
+		}
+
+		if !t.quoted && p.peek().is("(") {
+			name := strings.ToLower(t.text)
+			if _, ok := ExpressionFunctions[name]; !ok {
+				return nil, g.Error("unknown function: %s", t.text)
+			}
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			return &exprCall{name: name, args: args}, nil
+		}
+		return &exprColumn{name: t.text}, nil
+	case exprTokenOperator:
+		if t.is("(") {
+			if node, err = p.parseOr(); err != nil {
+				return nil, err
+			}
+			if err = p.expect(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		}
+	case exprTokenEOF:
+		return nil, g.Error("unexpected end of expression")
+	}
+
+	return nil, g.Error("unexpected token '%s'", t.text)
+}
+
+/////////////////////////////////////// nodes
+
+type exprNode interface {
+	eval(getValue func(name string) (any, bool)) (any, error)
+}
+
+type exprLiteral struct{ value any }
+
+func (n *exprLiteral) eval(getValue func(name string) (any, bool)) (any, error) {
+	return n.value, nil
+}
+
+type exprColumn struct{ name string }
+
+func (n *exprColumn) eval(getValue func(name string) (any, bool)) (any, error) {
+	val, ok := getValue(n.name)
+	if !ok {
+		return nil, g.Error("column not found: %s", n.name)
+	}
+	return val, nil
+}
+
+type exprCall struct {
+	name string
+	args []exprNode
+}
+
+func (n *exprCall) eval(getValue func(name string) (any, bool)) (any, error) {
+	args := make([]any, len(n.args))
+	for i, arg := range n.args {
+		val, err := arg.eval(getValue)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = val
+	}
+	return ExpressionFunctions[n.name](args...)
+}
+
+type exprUnary struct {
+	op      string
+	operand exprNode
+}
+
+func (n *exprUnary) eval(getValue func(name string) (any, bool)) (any, error) {
+	val, err := n.operand.eval(getValue)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.op == "!" {
+		return !exprTruthy(val), nil
+	}
+
+	if val == nil {
+		return nil, nil
+	}
+	return exprArithmetic("-", int64(0), val)
+}
+
+type exprIsNull struct {
+	operand exprNode
+	negate  bool
+}
+
+func (n *exprIsNull) eval(getValue func(name string) (any, bool)) (any, error) {
+	val, err := n.operand.eval(getValue)
+	if err != nil {
+		return nil, err
+	}
+	return (val == nil) != n.negate, nil
+}
+
+type exprIn struct {
+	operand exprNode
+	list    []exprNode
+	negate  bool
+}
+
+func (n *exprIn) eval(getValue func(name string) (any, bool)) (any, error) {
+	val, err := n.operand.eval(getValue)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range n.list {
+		itemVal, err := item.eval(getValue)
+		if err != nil {
+			return nil, err
+		}
+		if c, ok := exprCompare(val, itemVal); ok && c == 0 {
+			return !n.negate, nil
+		}
+	}
+	return n.negate, nil
+}
+
+type exprBinary struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *exprBinary) eval(getValue func(name string) (any, bool)) (any, error) {
+	left, err := n.left.eval(getValue)
+	if err != nil {
+		return nil, err
+	}
+
+	// short-circuit logical operators
+	switch n.op {
+	case "&&":
+		if !exprTruthy(left) {
+			return false, nil
+		}
+		right, err := n.right.eval(getValue)
+		return err == nil && exprTruthy(right), err
+	case "||":
+		// logical or for booleans, string concatenation otherwise
+		if _, ok := left.(bool); ok {
+			if exprTruthy(left) {
+				return true, nil
+			}
+			right, err := n.right.eval(getValue)
+			return err == nil && exprTruthy(right), err
+		}
+
+		right, err := n.right.eval(getValue)
+		if err != nil {
+			return nil, err
+		} else if _, ok := right.(bool); ok && left == nil {
+			return right, nil
+		} else if left == nil || right == nil {
+			return nil, nil
+		}
+		return cast.ToString(left) + cast.ToString(right), nil
+	}
+
+	right, err := n.right.eval(getValue)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==", "!=":
+		if left == nil || right == nil {
+			return (left == nil && right == nil) == (n.op == "=="), nil
+		}
+		c, ok := exprCompare(left, right)
+		return (ok && c == 0) == (n.op == "=="), nil
+	case "<", "<=", ">", ">=":
+		c, ok := exprCompare(left, right)
+		if !ok {
+			return false, nil
+		}
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	}
+
+	return exprArithmetic(n.op, left, right)
+}
+
+/////////////////////////////////////// value helpers
+
+// exprTruthy returns the boolean value of a value
+func exprTruthy(val any) bool {
+	switch v := val.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		if b, err := cast.ToBoolE(v); err == nil {
+			return b
+		}
+		return v != ""
+	}
+
+	if f, err := cast.ToFloat64E(val); err == nil {
+		return f != 0
+	}
+	return true
+}
+
+// exprNumber returns the numeric value of a value, and whether it is an integer
+func exprNumber(val any) (f float64, isInt bool, ok bool) {
+	switch v := val.(type) {
+	case nil, bool, time.Time:
+		return 0, false, false
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return cast.ToFloat64(v), true, true
+	case string:
+		v = strings.TrimSpace(v)
+		if i, err := cast.ToInt64E(v); err == nil && !strings.Contains(v, ".") {
+			return float64(i), true, true
+		}
+		f, err := cast.ToFloat64E(v)
+		return f, false, err == nil
+	}
+
+	f, err := cast.ToFloat64E(val)
+	return f, false, err == nil
+}
+
+// exprCompare compares two values, numerically, chronologically or as strings.
+// Returns false if values cannot be compared.
+func exprCompare(left, right any) (int, bool) {
+	if left == nil || right == nil {
+		return 0, false
+	}
+
+	if lf, _, ok := exprNumber(left); ok {
+		if rf, _, ok := exprNumber(right); ok {
+			switch {
+			case lf < rf:
+				return -1, true
+			case lf > rf:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+
+	lt, lIsTime := left.(time.Time)
+	rt, rIsTime := right.(time.Time)
+	if lIsTime || rIsTime {
+		var err error
+		if !lIsTime {
+			if lt, err = cast.ToTimeE(left); err != nil {
+				return 0, false
+			}
+		}
+		if !rIsTime {
+			if rt, err = cast.ToTimeE(right); err != nil {
+				return 0, false
+			}
+		}
+		return lt.Compare(rt), true
+	}
+
+	if lb, ok := left.(bool); ok {
+		if rb, err := cast.ToBoolE(right); err == nil {
+			return cast.ToInt(lb) - cast.ToInt(rb), true
+		}
+	}
+
+	return strings.Compare(cast.ToString(left), cast.ToString(right)), true
+}
+
+// exprArithmetic applies an arithmetic operator. Returns nil if any value is nil.
+func exprArithmetic(op string, left, right any) (any, error) {
+	if left == nil || right == nil {
+		return nil, nil
+	}
+
+	lf, lIsInt, lOk := exprNumber(left)
+	rf, rIsInt, rOk := exprNumber(right)
+	if !lOk || !rOk {
+		return nil, g.Error("cannot apply operator '%s' to values: %#v, %#v", op, left, right)
+	}
+
+	var result float64
+	switch op {
+	case "+":
+		result = lf + rf
+	case "-":
+		result = lf - rf
+	case "*":
+		result = lf * rf
+	case "/":
+		if rf == 0 {
+			return nil, g.Error("division by zero")
+		}
+		result = lf / rf
+		if lIsInt && rIsInt && math.Mod(lf, rf) != 0 {
+			return result, nil
+		}
+	case "%":
+		if rf == 0 {
+			return nil, g.Error("division by zero")
+		}
+		result = math.Mod(lf, rf)
+	default:
+		return nil, g.Error("unknown operator: %s", op)
+	}
+
+	if lIsInt && rIsInt {
+		return int64(result), nil
+	}
+	return result, nil
+}
@@ -0,0 +1,195 @@
+package iop
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/flarco/g"
+)
+
+// logPatterns are the built-in log line patterns, by parser name
+var logPatterns = map[string][]*regexp.Regexp{
+	"syslog": {
+		// RFC 5424: <34>1 2003-10-11T22:14:15.003Z host app 1234 ID47 - message
+		regexp.MustCompile(`^<(?P<priority>\d{1,3})>1 (?P<timestamp>\S+) (?P<hostname>\S+) (?P<app_name>\S+) (?P<proc_id>\S+) (?P<msg_id>\S+) (?P<structured_data>-|\[.*?\])\s?(?P<message>.*)$`),
+		// RFC 3164: <34>Oct 11 22:14:15 host app[1234]: message
+		regexp.MustCompile(`^(?:<(?P<priority>\d{1,3})>)?(?P<timestamp>[A-Z][a-z]{2}\s+\d{1,2} \d{2}:\d{2}:\d{2}) (?P<hostname>\S+) (?P<app_name>[^\s\[:]+)(?:\[(?P<proc_id>\d+)\])?: (?P<message>.*)$`),
+	},
+	// common & combined log formats (apache, nginx)
+	"clf": {
+		regexp.MustCompile(`^(?P<remote_host>\S+) (?P<ident>\S+) (?P<user>\S+) \[(?P<timestamp>[^\]]+)\] "(?P<request>[^"]*)" (?P<status>\d{3}) (?P<bytes>\S+)(?: "(?P<referer>[^"]*)" "(?P<user_agent>[^"]*)")?`),
+	},
+}
+
+// NewLogParser returns the function parsing a log line into a record, for a
+// parser name (`json`, `logfmt`, `syslog`, `clf`) or a regex with named groups.
+// The default (`auto`) parses JSON lines and logfmt lines. Lines which cannot be
+// parsed are returned in a `message` field
+func NewLogParser(parser string) (parse func(line string) map[string]any, err error) {
+	switch strings.ToLower(strings.TrimSpace(parser)) {
+	case "", "auto":
+		return func(line string) map[string]any {
+			if strings.HasPrefix(line, "{") {
+				return parseLogJSON(line)
+			} else if strings.Contains(line, "=") {
+				return parseLogfmt(line)
+			}
+			return map[string]any{"message": line}
+		}, nil
+	case "json":
+		return parseLogJSON, nil
+	case "logfmt":
+		return parseLogfmt, nil
+	case "syslog", "clf":
+		return parseLogRegex(logPatterns[strings.ToLower(strings.TrimSpace(parser))]), nil
+	}
+
+	pattern, err := regexp.Compile(parser)
+	if err != nil {
+		return nil, g.Error(err, "invalid log parser pattern: %s", parser)
+	} else if strings.Join(pattern.SubexpNames(), "") == "" {
+		return nil, g.Error("log parser pattern has no named groups, e.g. (?P<level>\\w+): %s", parser)
+	}
+	return parseLogRegex([]*regexp.Regexp{pattern}), nil
+}
+
+func parseLogJSON(line string) map[string]any {
+	rec := map[string]any{}
+	if err := json.Unmarshal([]byte(line), &rec); err != nil {
+		return map[string]any{"message": line}
+	}
+	return rec
+}
+
+// parseLogRegex returns the named groups of the first matching pattern,
+// with `-` or empty values as null
+func parseLogRegex(patterns []*regexp.Regexp) func(line string) map[string]any {
+	return func(line string) map[string]any {
+		for _, pattern := range patterns {
+			match := pattern.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+
+			rec := map[string]any{}
+			for i, name := range pattern.SubexpNames() {
+				if name == "" {
+					continue
+				} else if match[i] == "" || match[i] == "-" {
+					rec[name] = nil
+				} else {
+					rec[name] = match[i]
+				}
+			}
+			return rec
+		}
+		return map[string]any{"message": line}
+	}
+}
+
+// parseLogfmt parses `key=value key2="quoted value" flag` pairs
+func parseLogfmt(line string) map[string]any {
+	rec := map[string]any{}
+	for i := 0; i < len(line); {
+		if line[i] == ' ' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' {
+			i++
+		}
+		key := line[start:i]
+		if key == "" {
+			i++
+			continue
+		} else if i >= len(line) || line[i] == ' ' {
+			rec[key] = true // key without value
+			continue
+		}
+		i++ // skip =
+
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(line))
+			value, err := strconv.Unquote(line[i:end])
+			if err != nil {
+				value = strings.Trim(line[i:end], `"`)
+			}
+			rec[key] = value
+			i = end
+			continue
+		}
+
+		start = i
+		for i < len(line) && line[i] != ' ' {
+			i++
+		}
+		rec[key] = line[start:i]
+	}
+	return rec
+}
+
+// logDecoder decodes a log stream, one record per line
+type logDecoder struct {
+	scanner *bufio.Scanner
+	parse   func(line string) map[string]any
+}
+
+func (d *logDecoder) Decode(obj any) (err error) {
+	for d.scanner.Scan() {
+		line := strings.TrimRight(d.scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if ptr, ok := obj.(*map[string]any); ok {
+			*ptr = d.parse(line)
+		}
+		return nil
+	}
+
+	if err = d.scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// ConsumeLogReader uses the provided reader to stream log lines, parsed
+// into columns with the `log_parser` config
+func (ds *Datastream) ConsumeLogReader(reader io.Reader) (err error) {
+	reader2, err := AutoDecompress(reader)
+	if err != nil {
+		return g.Error(err, "Could not decompress reader")
+	}
+
+	parse, err := NewLogParser(ds.Sp.Config.LogParser)
+	if err != nil {
+		return g.Error(err, "could not make log parser")
+	}
+
+	scanner := bufio.NewScanner(reader2)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+
+	// the records are always flattened into columns
+	js := NewJSONStream(ds, &logDecoder{scanner: scanner, parse: parse}, true, ds.Sp.Config.Jmespath)
+	js.HasMapPayload = true
+	ds.it = ds.NewIterator(ds.Columns, js.NextFunc)
+
+	err = ds.Start()
+	if err != nil {
+		return g.Error(err, "could start datastream")
+	}
+	return
+}
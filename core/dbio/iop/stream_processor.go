@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"math"
 	"math/big"
 	"os"
@@ -34,6 +35,8 @@ type StreamProcessor struct {
 	unrecognizedDate string
 	warn             bool
 	skipCurrent      bool // whether to skip current row (for constraints)
+	filter           *Expression
+	rateLimiter      *RateLimiter
 	parseFuncs       map[string]func(s string) (interface{}, error)
 	decReplRegex     *regexp.Regexp
 	ds               *Datastream
@@ -63,7 +66,14 @@ type StreamConfig struct {
 	FieldsPerRec      int                      `json:"fields_per_rec"`
 	Jmespath          string                   `json:"jmespath"`
 	Sheet             string                   `json:"sheet"`
+	LogParser         string                   `json:"log_parser"`
 	ColumnCasing      ColumnCasing             `json:"column_casing"`
+	Filter            string                   `json:"filter"`
+	ComputedColumns   map[string]string        `json:"computed_columns"`
+	ShardCount        int                      `json:"shard_count"`
+	ShardIndex        int                      `json:"shard_index"`
+	ShardKey          []string                 `json:"shard_key"`
+	RateLimit         string                   `json:"rate_limit"`
 	BoolAsInt         bool                     `json:"-"`
 	Columns           Columns                  `json:"columns"` // list of column types. Can be partial list! likely is!
 	transforms        map[string]TransformList // array of transform functions to apply
@@ -340,6 +350,10 @@ func (sp *StreamProcessor) SetConfig(configMap map[string]string) {
 		sp.Config.Jmespath = cast.ToString(val)
 	}
 
+	if val, ok := configMap["log_parser"]; ok {
+		sp.Config.LogParser = val
+	}
+
 	if val, ok := configMap["sheet"]; ok {
 		sp.Config.Sheet = cast.ToString(val)
 	}
@@ -352,6 +366,38 @@ func (sp *StreamProcessor) SetConfig(configMap map[string]string) {
 		sp.Config.ColumnCasing = ColumnCasing(val)
 	}
 
+	if val, ok := configMap["filter"]; ok {
+		sp.Config.Filter = strings.TrimSpace(val)
+	}
+
+	if val, ok := configMap["computed_columns"]; ok {
+		g.Unmarshal(val, &sp.Config.ComputedColumns)
+	}
+
+	if val, ok := configMap["shard_count"]; ok {
+		sp.Config.ShardCount = cast.ToInt(val)
+	}
+
+	if val, ok := configMap["shard_index"]; ok {
+		sp.Config.ShardIndex = cast.ToInt(val)
+	}
+
+	if val, ok := configMap["shard_key"]; ok && strings.TrimSpace(val) != "" {
+		sp.Config.ShardKey = []string{}
+		for _, key := range strings.Split(val, ",") {
+			sp.Config.ShardKey = append(sp.Config.ShardKey, strings.TrimSpace(key))
+		}
+	}
+
+	if val, ok := configMap["rate_limit"]; ok && strings.TrimSpace(val) != "" && val != sp.Config.RateLimit {
+		if rl, err := ParseRateLimit(val); err != nil {
+			g.Warn(err.Error())
+		} else {
+			sp.Config.RateLimit = val
+			sp.rateLimiter = NewRateLimiter(rl)
+		}
+	}
+
 	if val, ok := configMap["bool_at_int"]; ok {
 		sp.Config.BoolAsInt = cast.ToBool(val)
 	}
@@ -361,7 +407,9 @@ func (sp *StreamProcessor) SetConfig(configMap map[string]string) {
 	}
 
 	if val, ok := configMap["transforms"]; ok {
-		sp.applyTransforms(val)
+		if err := sp.applyTransforms(val); err != nil {
+			sp.captureErr(err)
+		}
 	}
 
 	if val, ok := configMap["compression"]; ok {
@@ -383,14 +431,29 @@ func makeColumnTransforms(transformsPayload string) map[string][]string {
 	return columnTransforms
 }
 
-func (sp *StreamProcessor) applyTransforms(transformsPayload string) {
+// applyTransforms sets the column transforms. An invalid strict transform
+// (such as `encrypt` with a missing key) returns an error, since skipping it
+// would pass the values as-is.
+func (sp *StreamProcessor) applyTransforms(transformsPayload string) (err error) {
 	columnTransforms := makeColumnTransforms(transformsPayload)
 	sp.Config.transforms = map[string]TransformList{}
 	for key, names := range columnTransforms {
 		sp.Config.transforms[key] = TransformList{}
 		for _, name := range names {
+			// support the `name:param` syntax, e.g. `encrypt:my_key`
+			if n := strings.TrimSpace(name); !strings.Contains(n, "(") && strings.Contains(n, ":") {
+				tName, param, _ := strings.Cut(n, ":")
+				name = g.F("%s(%s)", strings.TrimSpace(tName), strings.TrimSpace(param))
+			}
+
 			t, ok := TransformsMap[name]
-			if ok {
+			if ok && t.strict && t.FuncString == nil {
+				// make with the default params, so that it is not a no-op
+				if err = t.makeFunc(&t); err != nil {
+					return g.Error(err, "invalid transform '%s'", name)
+				}
+				sp.Config.transforms[key] = append(sp.Config.transforms[key], t)
+			} else if ok {
 				sp.Config.transforms[key] = append(sp.Config.transforms[key], t)
 			} else if n := strings.TrimSpace(string(name)); strings.Contains(n, "(") && strings.HasSuffix(n, ")") {
 				// parse transform with a parameter
@@ -411,7 +474,9 @@ func (sp *StreamProcessor) applyTransforms(transformsPayload string) {
 						params = append(params, strings.TrimSpace(p))
 					}
 					err := t.makeFunc(&t, params...)
-					if err != nil {
+					if err != nil && t.strict {
+						return g.Error(err, "invalid parameter for transform '%s'", tName)
+					} else if err != nil {
 						g.Warn("invalid parameter for transform '%s' (%s)", tName, err.Error())
 					} else {
 						sp.Config.transforms[key] = append(sp.Config.transforms[key], t)
@@ -424,6 +489,21 @@ func (sp *StreamProcessor) applyTransforms(transformsPayload string) {
 			}
 		}
 	}
+	return nil
+}
+
+// flushTransforms stores the buffered state of the transforms (such as new tokens)
+func (sp *StreamProcessor) flushTransforms() (err error) {
+	for _, transforms := range sp.Config.transforms {
+		for _, t := range transforms {
+			if t.flush == nil {
+				continue
+			} else if err = t.flush(); err != nil {
+				return g.Error(err, "could not flush transform '%s'", t.Name)
+			}
+		}
+	}
+	return nil
 }
 
 // CastVal  casts the type of an interface based on its value
@@ -627,9 +707,15 @@ func (sp *StreamProcessor) CastVal(i int, val interface{}, col *Column) interfac
 
 		// apply transforms
 		for _, t := range transforms {
-			if t.FuncString != nil {
-				sVal, _ = t.FuncString(sp, sVal)
+			if t.FuncString == nil {
+				continue
+			}
+			newVal, err := t.FuncString(sp, sVal)
+			if err != nil && t.strict {
+				sp.captureErr(g.Error(err, "could not apply transform '%s' on column '%s'", t.Name, col.Name))
+				newVal = "" // never pass the value as-is
 			}
+			sVal = newVal
 		}
 
 		l := len(sVal)
@@ -1247,6 +1333,7 @@ func (sp *StreamProcessor) CastRow(row []interface{}, columns Columns) []interfa
 		row[i] = sp.CastVal(i, val, col)
 		if row[i] != nil && row[i] != "" {
 			sp.colStats[i].LastVal = row[i]
+			sp.colStats[i].setMaxVal(row[i], col.Type)
 		}
 
 		// evaluate constraint
@@ -1275,6 +1362,78 @@ func (sp *StreamProcessor) CastRow(row []interface{}, columns Columns) []interfa
 	return row
 }
 
+// FilterRow returns true if the row satisfies the filter expression (`filter` source option).
+// Any parse or evaluation error is captured in the stream context.
+func (sp *StreamProcessor) FilterRow(row []any, columns Columns) bool {
+	if sp.Config.Filter == "" {
+		return true
+	}
+
+	if sp.filter == nil || sp.filter.Text != sp.Config.Filter {
+		filter, err := ParseExpression(sp.Config.Filter)
+		if err != nil {
+			sp.captureErr(g.Error(err, "invalid filter expression"))
+			return false
+		}
+		sp.filter = filter
+	}
+
+	keep, err := sp.filter.EvalRowBool(columns, row)
+	if err != nil {
+		sp.captureErr(g.Error(err, "could not evaluate filter expression: %s", sp.Config.Filter))
+		return false
+	}
+
+	return keep
+}
+
+// ShardRow returns true if the row belongs to the shard of the stream (`shards` target option).
+// The shard of a row is the FNV-1a hash of its key values, modulo the shard count.
+// If the key values are all null or empty, the whole row is hashed instead, so
+// that those rows are spread across the shards.
+func (sp *StreamProcessor) ShardRow(row []any, columns Columns) bool {
+	if sp.Config.ShardCount <= 1 {
+		return true
+	}
+
+	values := make([]string, len(sp.Config.ShardKey))
+	isBlank := true
+	for k, key := range sp.Config.ShardKey {
+		col := columns.GetColumn(key)
+		if col == nil {
+			sp.captureErr(g.Error("shard key column '%s' not found", key))
+			return false
+		} else if i := col.Position - 1; i < len(row) && row[i] != nil {
+			values[k] = cast.ToString(row[i])
+			isBlank = isBlank && values[k] == ""
+		}
+	}
+
+	if isBlank {
+		values = make([]string, len(row))
+		for i, val := range row {
+			values[i] = cast.ToString(val)
+		}
+	}
+
+	h := fnv.New32a()
+	for _, value := range values {
+		h.Write([]byte(value))
+		h.Write([]byte{0}) // separator
+	}
+
+	return int(h.Sum32()%uint32(sp.Config.ShardCount)) == sp.Config.ShardIndex
+}
+
+// captureErr captures an error into the datastream context, if any
+func (sp *StreamProcessor) captureErr(err error) {
+	if sp.ds != nil {
+		sp.ds.Context.CaptureErr(err)
+	} else {
+		g.LogError(err)
+	}
+}
+
 // ProcessRow processes a row
 func (sp *StreamProcessor) ProcessRow(row []interface{}) []interface{} {
 	// Ensure usable types
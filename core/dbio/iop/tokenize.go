@@ -0,0 +1,200 @@
+package iop
+
+import (
+	"container/list"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/flarco/g"
+)
+
+// TokenVault stores the mapping of tokens to their original values,
+// so that tokenized values can be reversed (detokenized) later on
+type TokenVault interface {
+	// SetTokens stores the token to value mappings, ignoring existing tokens
+	SetTokens(tokenValues map[string]string) error
+	// GetValue returns the original value of a token
+	GetValue(token string) (value string, found bool, err error)
+}
+
+var (
+	// tokenVaults is the registry of named token vaults
+	tokenVaults   = map[string]TokenVault{}
+	tokenVaultMux sync.Mutex
+
+	// NewLocalTokenVault returns the local token vault (sqlite).
+	// Is set by the store package.
+	NewLocalTokenVault = func(name string) (TokenVault, error) {
+		return nil, g.Error("local token vault is not available")
+	}
+)
+
+// RegisterTokenVault registers a token vault under a name, to be
+// referenced in the `tokenize` and `detokenize` transforms
+func RegisterTokenVault(name string, vault TokenVault) {
+	tokenVaultMux.Lock()
+	tokenVaults[strings.ToLower(name)] = vault
+	tokenVaultMux.Unlock()
+}
+
+// GetTokenVault returns the token vault registered under the name.
+// Falls back to a local vault (in the sling home directory) if not registered.
+func GetTokenVault(name string) (vault TokenVault, err error) {
+	name = strings.ToLower(strings.Trim(strings.TrimSpace(name), `"'`))
+	if name == "" {
+		name = "local"
+	}
+
+	tokenVaultMux.Lock()
+	defer tokenVaultMux.Unlock()
+
+	if vault, ok := tokenVaults[name]; ok {
+		return vault, nil
+	}
+
+	if strings.Contains(name, ":") {
+		return nil, g.Error("token vault '%s' is not registered", name)
+	}
+
+	localVault, err := NewLocalTokenVault(name)
+	if err != nil {
+		return nil, g.Error(err, "could not initialize local token vault '%s'", name)
+	}
+
+	tokenVaults[name] = localVault
+
+	return localVault, nil
+}
+
+// tokenCacheSize is the max number of tokens cached in memory per transform
+const tokenCacheSize = 100000
+
+// cachedTokenVault wraps a vault with a bounded in-memory cache (least recently
+// used first out), so that the underlying store is rarely hit more than once per
+// distinct value. New tokens are buffered until Flush, to write them in bulk.
+type cachedTokenVault struct {
+	vault   TokenVault
+	size    int
+	values  map[string]*list.Element // token to element of order
+	order   *list.List               // of tokenEntry, most recently used first
+	pending map[string]string        // tokens not stored in the vault yet
+	mux     sync.Mutex
+}
+
+type tokenEntry struct {
+	token string
+	value string
+}
+
+func newCachedTokenVault(vault TokenVault, size int) *cachedTokenVault {
+	return &cachedTokenVault{
+		vault:   vault,
+		size:    size,
+		values:  map[string]*list.Element{},
+		order:   list.New(),
+		pending: map[string]string{},
+	}
+}
+
+// get returns the cached value of a token, marking it as recently used
+func (cv *cachedTokenVault) get(token string) (value string, ok bool) {
+	if elem, ok := cv.values[token]; ok {
+		cv.order.MoveToFront(elem)
+		return elem.Value.(tokenEntry).value, true
+	}
+	value, ok = cv.pending[token]
+	return value, ok
+}
+
+// put caches the value of a token, evicting the least recently used
+func (cv *cachedTokenVault) put(token, value string) {
+	if elem, ok := cv.values[token]; ok {
+		cv.order.MoveToFront(elem)
+		return
+	}
+	cv.values[token] = cv.order.PushFront(tokenEntry{token, value})
+	for cv.order.Len() > cv.size {
+		oldest := cv.order.Back()
+		cv.order.Remove(oldest)
+		delete(cv.values, oldest.Value.(tokenEntry).token)
+	}
+}
+
+// SetTokens buffers the new tokens, which are stored in the
+// vault on Flush, or once the buffer reaches the cache size
+func (cv *cachedTokenVault) SetTokens(tokenValues map[string]string) error {
+	cv.mux.Lock()
+	defer cv.mux.Unlock()
+
+	for token, value := range tokenValues {
+		if _, ok := cv.get(token); !ok {
+			cv.pending[token] = value
+		}
+	}
+
+	if len(cv.pending) >= cv.size {
+		return cv.flush()
+	}
+	return nil
+}
+
+// Flush stores the buffered tokens in the vault
+func (cv *cachedTokenVault) Flush() error {
+	cv.mux.Lock()
+	defer cv.mux.Unlock()
+	return cv.flush()
+}
+
+func (cv *cachedTokenVault) flush() error {
+	if len(cv.pending) == 0 {
+		return nil
+	}
+
+	if err := cv.vault.SetTokens(cv.pending); err != nil {
+		return err
+	}
+
+	for token, value := range cv.pending {
+		cv.put(token, value)
+	}
+	cv.pending = map[string]string{}
+
+	return nil
+}
+
+func (cv *cachedTokenVault) GetValue(token string) (value string, found bool, err error) {
+	cv.mux.Lock()
+	defer cv.mux.Unlock()
+
+	if value, ok := cv.get(token); ok {
+		return value, true, nil
+	}
+
+	value, found, err = cv.vault.GetValue(token)
+	if err == nil && found {
+		cv.put(token, value)
+	}
+
+	return
+}
+
+// Tokenize returns a deterministic token for the value. The token is the
+// HMAC-SHA256 of the value, keyed with the secret.
+func (t transformsNS) Tokenize(secret []byte, val string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(val))
+	return "tok_" + hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// TokenSecret returns the tokenization secret, from env var `SLING_TOKEN_SECRET`.
+// It is required, since an unkeyed hash can be reversed with a dictionary.
+func (t transformsNS) TokenSecret() (secret []byte, err error) {
+	if secret = []byte(os.Getenv("SLING_TOKEN_SECRET")); len(secret) == 0 {
+		return nil, g.Error("env var SLING_TOKEN_SECRET is required to tokenize values")
+	}
+	return secret, nil
+}
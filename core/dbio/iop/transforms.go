@@ -2,11 +2,17 @@ package iop
 
 import (
 	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
 	"crypto/sha256"
 	"crypto/sha512"
 	"embed"
+	"encoding/base64"
 	"encoding/binary"
+	"encoding/hex"
 	"fmt"
+	"os"
 	"regexp"
 	"strings"
 	"time"
@@ -14,9 +20,11 @@ import (
 
 	"github.com/flarco/g"
 	"github.com/google/uuid"
+	"github.com/samber/lo"
 	"github.com/spf13/cast"
 	"golang.org/x/text/encoding"
 	"golang.org/x/text/transform"
+	"gopkg.in/yaml.v2"
 )
 
 var TransformsMap = map[string]Transform{}
@@ -31,6 +39,8 @@ func init() {
 		TransformDecodeUtf16,
 		TransformDecodeWindows1250,
 		TransformDecodeWindows1252,
+		TransformDecrypt,
+		TransformDetokenize,
 		TransformDuckdbListToText,
 		TransformEncodeLatin1,
 		TransformEncodeLatin5,
@@ -40,6 +50,7 @@ func init() {
 		TransformEncodeUtf16,
 		TransformEncodeWindows1250,
 		TransformEncodeWindows1252,
+		TransformEncrypt,
 		TransformHashMd5,
 		TransformHashSha256,
 		TransformHashSha512,
@@ -54,6 +65,7 @@ func init() {
 		TransformLower,
 		TransformUpper,
 		TransformSetTimezone,
+		TransformTokenize,
 	} {
 		TransformsMap[t.Name] = t
 	}
@@ -72,6 +84,8 @@ type Transform struct {
 	FuncString func(*StreamProcessor, string) (string, error)
 	FuncTime   func(*StreamProcessor, *time.Time) error
 	makeFunc   func(t *Transform, params ...any) error
+	flush      func() error // stores the buffered state, at the end of each batch
+	strict     bool         // errors fail the stream, values are never passed as-is
 }
 
 type TransformList []Transform
@@ -343,6 +357,121 @@ var (
 			return nil
 		},
 	}
+
+	TransformEncrypt = Transform{
+		Name:   "encrypt",
+		strict: true,
+		makeFunc: func(t *Transform, keyID ...any) error {
+			if len(keyID) == 0 {
+				return g.Error("param for 'encrypt' should be the key id")
+			}
+			key, err := Transforms.EncryptionKey(cast.ToString(keyID[0]))
+			if err != nil {
+				return g.Error(err, "could not get encryption key")
+			}
+
+			aead, err := Transforms.NewAEAD(key)
+			if err != nil {
+				return err
+			}
+
+			t.FuncString = func(sp *StreamProcessor, val string) (string, error) {
+				return Transforms.Encrypt(aead, val)
+			}
+
+			return nil
+		},
+	}
+
+	TransformTokenize = Transform{
+		Name:   "tokenize",
+		strict: true,
+		makeFunc: func(t *Transform, vaultName ...any) error {
+			secret, err := Transforms.TokenSecret()
+			if err != nil {
+				return err
+			}
+
+			tokenVault, err := GetTokenVault(cast.ToString(lo.Ternary(len(vaultName) > 0, vaultName[0], any(""))))
+			if err != nil {
+				return g.Error(err, "could not get token vault")
+			}
+
+			// the new tokens are stored at the end of each batch
+			vault := newCachedTokenVault(tokenVault, tokenCacheSize)
+			t.flush = func() error {
+				if err := vault.Flush(); err != nil {
+					return g.Error(err, "could not store tokens")
+				}
+				return nil
+			}
+
+			t.FuncString = func(sp *StreamProcessor, val string) (string, error) {
+				if val == "" {
+					return val, nil
+				}
+				token := Transforms.Tokenize(secret, val)
+				if err := vault.SetTokens(map[string]string{token: val}); err != nil {
+					return "", g.Error(err, "could not store tokens")
+				}
+				return token, nil
+			}
+
+			return nil
+		},
+	}
+
+	TransformDetokenize = Transform{
+		Name:   "detokenize",
+		strict: true,
+		makeFunc: func(t *Transform, vaultName ...any) error {
+			tokenVault, err := GetTokenVault(cast.ToString(lo.Ternary(len(vaultName) > 0, vaultName[0], any(""))))
+			if err != nil {
+				return g.Error(err, "could not get token vault")
+			}
+			vault := newCachedTokenVault(tokenVault, tokenCacheSize)
+
+			t.FuncString = func(sp *StreamProcessor, val string) (string, error) {
+				if val == "" {
+					return val, nil
+				}
+				value, found, err := vault.GetValue(val)
+				if err != nil {
+					return val, g.Error(err, "could not get token value")
+				} else if !found {
+					return val, nil // not a known token
+				}
+				return value, nil
+			}
+
+			return nil
+		},
+	}
+
+	TransformDecrypt = Transform{
+		Name:   "decrypt",
+		strict: true,
+		makeFunc: func(t *Transform, keyID ...any) error {
+			if len(keyID) == 0 {
+				return g.Error("param for 'decrypt' should be the key id")
+			}
+			key, err := Transforms.EncryptionKey(cast.ToString(keyID[0]))
+			if err != nil {
+				return g.Error(err, "could not get encryption key")
+			}
+
+			aead, err := Transforms.NewAEAD(key)
+			if err != nil {
+				return err
+			}
+
+			t.FuncString = func(sp *StreamProcessor, val string) (string, error) {
+				return Transforms.Decrypt(aead, val)
+			}
+
+			return nil
+		},
+	}
 )
 
 var fixDelimiter string
@@ -559,3 +688,107 @@ func (t transformsNS) ReplaceNonPrintable(val string) string {
 
 	return newVal.String()
 }
+
+// EncryptionKey returns the AES key for the provided key id. The key is read
+// from env var `SLING_ENCRYPTION_KEY_<KEY_ID>`, or from the keystore file
+// provided with env var `SLING_KEYSTORE` (a JSON/YAML map of key id to key).
+// The key can be hex or base64 encoded, and must decode to 16, 24 or 32 bytes.
+func (t transformsNS) EncryptionKey(keyID string) (key []byte, err error) {
+	keyID = strings.Trim(strings.TrimSpace(keyID), `"'`)
+	if keyID == "" {
+		return nil, g.Error("key id is empty")
+	}
+
+	envKey := "SLING_ENCRYPTION_KEY_" + strings.ToUpper(keyID)
+	keyStr := os.Getenv(envKey)
+
+	if keyStr == "" {
+		if keystorePath := os.Getenv("SLING_KEYSTORE"); keystorePath != "" {
+			bytes, err := os.ReadFile(keystorePath)
+			if err != nil {
+				return nil, g.Error(err, "could not read keystore: %s", keystorePath)
+			}
+
+			keys := map[string]string{}
+			if err = yaml.Unmarshal(bytes, &keys); err != nil {
+				return nil, g.Error(err, "could not parse keystore: %s", keystorePath)
+			}
+
+			for k, v := range keys {
+				if strings.EqualFold(k, keyID) {
+					keyStr = v
+				}
+			}
+		}
+	}
+
+	if keyStr == "" {
+		return nil, g.Error("did not find key '%s'. Please set env var %s or provide SLING_KEYSTORE", keyID, envKey)
+	}
+
+	keyStr = strings.TrimSpace(keyStr)
+	if key, err = hex.DecodeString(keyStr); err != nil {
+		if key, err = base64.StdEncoding.DecodeString(keyStr); err != nil {
+			return nil, g.Error("could not decode key '%s', should be hex or base64 encoded", keyID)
+		}
+	}
+
+	if !g.In(len(key), 16, 24, 32) {
+		return nil, g.Error("invalid key length for '%s' (%d bytes), should be 16, 24 or 32 bytes", keyID, len(key))
+	}
+
+	return key, nil
+}
+
+// NewAEAD returns an AES-GCM cipher for the key
+func (t transformsNS) NewAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, g.Error(err, "could not create cipher")
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, g.Error(err, "could not create GCM cipher")
+	}
+
+	return aead, nil
+}
+
+// Encrypt encrypts the value with AES-GCM, and returns the base64 encoded
+// nonce + cipher text
+func (t transformsNS) Encrypt(aead cipher.AEAD, val string) (string, error) {
+	if val == "" {
+		return val, nil
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return val, g.Error(err, "could not generate nonce")
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(val), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value encrypted with Encrypt
+func (t transformsNS) Decrypt(aead cipher.AEAD, val string) (string, error) {
+	if val == "" {
+		return val, nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(val)
+	if err != nil {
+		return val, g.Error(err, "could not decode encrypted value")
+	} else if len(sealed) < aead.NonceSize() {
+		return val, g.Error("encrypted value is too short")
+	}
+
+	nonce, cipherText := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, cipherText, nil)
+	if err != nil {
+		return val, g.Error(err, "could not decrypt value")
+	}
+
+	return string(plain), nil
+}
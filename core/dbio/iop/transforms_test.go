@@ -68,3 +68,148 @@ func TestTransformMsUUID(t *testing.T) {
 	val, _ := Transforms.ParseMsUUID(sp, cast.ToString(uuidBytes))
 	assert.Equal(t, "12345678-1234-1234-1234-123456789abc", val)
 }
+
+func TestTransformEncrypt(t *testing.T) {
+	os.Setenv("SLING_ENCRYPTION_KEY_TEST_KEY", "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	defer os.Unsetenv("SLING_ENCRYPTION_KEY_TEST_KEY")
+
+	sp := NewStreamProcessor()
+	sp.applyTransforms(`{"col1": ["encrypt(test_key)"], "col2": ["decrypt:test_key"]}`)
+	if !assert.Len(t, sp.Config.transforms["col1"], 1) || !assert.Len(t, sp.Config.transforms["col2"], 1) {
+		return
+	}
+
+	encrypt := sp.Config.transforms["col1"][0]
+	decrypt := sp.Config.transforms["col2"][0]
+
+	encVal, err := encrypt.FuncString(sp, "secret value")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "secret value", encVal)
+
+	decVal, err := decrypt.FuncString(sp, encVal)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret value", decVal)
+
+	// empty values are untouched
+	encVal, err = encrypt.FuncString(sp, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "", encVal)
+
+	// unknown key
+	_, err = Transforms.EncryptionKey("missing_key")
+	assert.Error(t, err)
+
+	// a missing key or param fails, instead of skipping the transform
+	assert.Error(t, NewStreamProcessor().applyTransforms(`{"col1": ["encrypt:missing_key"]}`))
+	assert.Error(t, NewStreamProcessor().applyTransforms(`{"col1": ["encrypt"]}`))
+
+	ds := NewDatastream(Columns{{Name: "col1", Type: StringType}})
+	ds.SetConfig(map[string]string{"transforms": `{"col1": ["encrypt:missing_key"]}`})
+	assert.Error(t, ds.Err())
+
+	// a value that cannot be decrypted fails the stream, and is not passed as-is
+	ds = NewDatastream(Columns{{Name: "col2", Type: StringType}})
+	ds.SetConfig(map[string]string{"transforms": `{"col2": ["decrypt:test_key"]}`})
+	assert.NoError(t, ds.Err())
+	row := ds.Sp.CastRow([]any{"not-encrypted"}, ds.Columns)
+	assert.Equal(t, "", row[0])
+	assert.Error(t, ds.Err())
+
+	// invalid key length
+	os.Setenv("SLING_ENCRYPTION_KEY_SHORT", "0001")
+	defer os.Unsetenv("SLING_ENCRYPTION_KEY_SHORT")
+	_, err = Transforms.EncryptionKey("short")
+	assert.Error(t, err)
+}
+
+type testTokenVault struct {
+	tokens map[string]string
+}
+
+func (v *testTokenVault) SetTokens(tokenValues map[string]string) error {
+	for token, value := range tokenValues {
+		v.tokens[token] = value
+	}
+	return nil
+}
+
+func (v *testTokenVault) GetValue(token string) (string, bool, error) {
+	value, found := v.tokens[token]
+	return value, found, nil
+}
+
+func TestTransformTokenize(t *testing.T) {
+	vault := &testTokenVault{tokens: map[string]string{}}
+	RegisterTokenVault("target:test.tokens", vault)
+
+	// the secret is required
+	t.Setenv("SLING_TOKEN_SECRET", "")
+	assert.Error(t, NewStreamProcessor().applyTransforms(`{"col1": ["tokenize:target:test.tokens"]}`))
+	assert.Error(t, NewStreamProcessor().applyTransforms(`{"col1": ["tokenize"]}`))
+
+	t.Setenv("SLING_TOKEN_SECRET", "my-secret")
+	sp := NewStreamProcessor()
+	sp.applyTransforms(`{"col1": ["tokenize:target:test.tokens"], "col2": ["detokenize(target:test.tokens)"]}`)
+	if !assert.Len(t, sp.Config.transforms["col1"], 1) || !assert.Len(t, sp.Config.transforms["col2"], 1) {
+		return
+	}
+
+	tokenize := sp.Config.transforms["col1"][0]
+	detokenize := sp.Config.transforms["col2"][0]
+
+	token1, err := tokenize.FuncString(sp, "john@example.com")
+	assert.NoError(t, err)
+	token2, err := tokenize.FuncString(sp, "john@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, token1, token2) // deterministic
+	assert.NotEqual(t, "john@example.com", token1)
+	assert.Empty(t, vault.tokens) // stored at the end of the batch
+	assert.NoError(t, sp.flushTransforms())
+	assert.Len(t, vault.tokens, 1)
+
+	value, err := detokenize.FuncString(sp, token1)
+	assert.NoError(t, err)
+	assert.Equal(t, "john@example.com", value)
+
+	// unknown token is left as is
+	value, err = detokenize.FuncString(sp, "tok_unknown")
+	assert.NoError(t, err)
+	assert.Equal(t, "tok_unknown", value)
+
+	// keyed with the secret
+	assert.Equal(t, token1, Transforms.Tokenize([]byte("my-secret"), "john@example.com"))
+	assert.NotEqual(t, token1, Transforms.Tokenize([]byte("other-secret"), "john@example.com"))
+}
+
+func TestCachedTokenVault(t *testing.T) {
+	vault := &testTokenVault{tokens: map[string]string{}}
+	cv := newCachedTokenVault(vault, 2)
+
+	// buffered until flushed, or the buffer reaches the size
+	assert.NoError(t, cv.SetTokens(map[string]string{"tok_a": "a"}))
+	assert.Empty(t, vault.tokens)
+	value, found, err := cv.GetValue("tok_a")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "a", value)
+
+	assert.NoError(t, cv.SetTokens(map[string]string{"tok_b": "b"}))
+	assert.Len(t, vault.tokens, 2)
+	assert.Empty(t, cv.pending)
+
+	// the least recently used is evicted
+	cv.GetValue("tok_a")
+	assert.NoError(t, cv.SetTokens(map[string]string{"tok_c": "c"}))
+	assert.NoError(t, cv.Flush())
+	assert.Len(t, vault.tokens, 3)
+	assert.Equal(t, 2, cv.order.Len())
+	assert.Contains(t, cv.values, "tok_a")
+	assert.Contains(t, cv.values, "tok_c")
+	assert.NotContains(t, cv.values, "tok_b")
+
+	// evicted tokens are read from the vault
+	value, found, err = cv.GetValue("tok_b")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "b", value)
+}
@@ -0,0 +1,85 @@
+package dbio
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/flarco/g"
+)
+
+// TLSConfig returns the tls config from the `ssl_root_cert` (a file path or
+// a PEM value, for private CAs) and `tls_skip_verify` connection properties.
+// Returns nil if neither is set.
+func TLSConfig(rootCert string, skipVerify bool) (tlsConfig *tls.Config, err error) {
+	if rootCert == "" && !skipVerify {
+		return nil, nil
+	}
+
+	tlsConfig = &tls.Config{InsecureSkipVerify: skipVerify}
+	if rootCert != "" {
+		pem, err := LoadRootCert(rootCert)
+		if err != nil {
+			return nil, err
+		}
+
+		// system CAs, plus the provided ones
+		tlsConfig.RootCAs, _ = x509.SystemCertPool()
+		if tlsConfig.RootCAs == nil {
+			tlsConfig.RootCAs = x509.NewCertPool()
+		}
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, g.Error("could not parse PEM value of ssl_root_cert")
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// LoadRootCert returns the PEM bytes of a root certificate file path or value
+func LoadRootCert(rootCert string) (pem []byte, err error) {
+	rootCert = strings.TrimSpace(rootCert)
+	if strings.HasPrefix(rootCert, "-----BEGIN") {
+		return []byte(rootCert), nil
+	}
+
+	pem, err = os.ReadFile(rootCert)
+	if err != nil {
+		return nil, g.Error(err, "could not read ssl_root_cert file: %s", rootCert)
+	}
+	return pem, nil
+}
+
+// RootCertFile returns the file path of a root certificate file path or value,
+// writing the value to a temp file if needed (for drivers only accepting files)
+func RootCertFile(rootCert string) (filePath string, err error) {
+	rootCert = strings.TrimSpace(rootCert)
+	if !strings.HasPrefix(rootCert, "-----BEGIN") {
+		return rootCert, nil
+	}
+
+	filePath = path.Join(os.TempDir(), g.F("sling_root_cert_%s.pem", g.MD5(rootCert)))
+	if err = os.WriteFile(filePath, []byte(rootCert), 0600); err != nil {
+		return "", g.Error(err, "could not write ssl_root_cert to temp file")
+	}
+	return filePath, nil
+}
+
+// HTTPClient returns an http client honoring the tls config and proxy, if provided.
+// Without a proxy, the HTTP_PROXY, HTTPS_PROXY and NO_PROXY env vars are honored.
+func HTTPClient(tlsConfig *tls.Config, proxyURL *url.URL) *http.Client {
+	if tlsConfig == nil && proxyURL == nil {
+		return &http.Client{}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	if proxyURL != nil {
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return &http.Client{Transport: transport}
+}
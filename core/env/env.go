@@ -3,6 +3,7 @@ package env
 import (
 	"embed"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"strings"
@@ -105,7 +106,13 @@ func SetLogger() {
 		}
 	}
 
-	outputOut := zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02 15:04:05"}
+	// with the json output, stdout is reserved for the final result
+	stdout := io.Writer(os.Stdout)
+	if os.Getenv("SLING_OUTPUT") == "json" {
+		stdout = os.Stderr
+	}
+
+	outputOut := zerolog.ConsoleWriter{Out: stdout, TimeFormat: "2006-01-02 15:04:05"}
 	outputErr := zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: "2006-01-02 15:04:05"}
 	outputOut.FormatErrFieldValue = func(i interface{}) string {
 		return fmt.Sprintf("%s", i)
@@ -124,8 +131,8 @@ func SetLogger() {
 		NoColor = true
 		zerolog.LevelFieldName = "lvl"
 		zerolog.MessageFieldName = "msg"
-		g.ZLogOut = zerolog.New(os.Stdout).With().Timestamp().Logger()
-		g.ZLogErr = zerolog.New(os.Stdout).With().Timestamp().Logger()
+		g.ZLogOut = zerolog.New(stdout).With().Timestamp().Logger()
+		g.ZLogErr = zerolog.New(stdout).With().Timestamp().Logger()
 	} else {
 		outputErr = zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: "3:04PM"}
 		if g.IsDebugLow() {
@@ -353,7 +360,7 @@ func Clean(props map[string]string, line string) string {
 	for k, v := range props {
 		if strings.TrimSpace(v) == "" {
 			continue
-		} else if g.In(k, "password", "access_key_id", "secret_access_key", "session_token", "aws_access_key_id", "aws_secret_access_key", "ssh_private_key", "ssh_passphrase", "sas_svc_url", "conn_str") {
+		} else if g.In(k, secretPropKeys...) {
 			line = strings.ReplaceAll(line, v, "***")
 		}
 	}
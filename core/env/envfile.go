@@ -76,9 +76,14 @@ func (ef *EnvFile) WriteEnvFile() (err error) {
 
 	// fix windows path
 	ef.Path = strings.ReplaceAll(ef.Path, `\`, `/`)
-	err = os.WriteFile(ef.Path, formatYAML(output), 0644)
+	// write to a temp file first, so that a failed write does not corrupt the env file
+	tmpPath := ef.Path + ".tmp"
+	err = os.WriteFile(tmpPath, formatYAML(output), 0644)
 	if err != nil {
 		return g.Error(err, "could not write YAML file")
+	} else if err = os.Rename(tmpPath, ef.Path); err != nil {
+		os.Remove(tmpPath)
+		return g.Error(err, "could not write YAML file")
 	}
 
 	return
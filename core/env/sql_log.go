@@ -0,0 +1,104 @@
+package env
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flarco/g"
+)
+
+var (
+	sqlLogFile *os.File
+	sqlLogPath string
+	sqlLogMux  sync.Mutex
+
+	// sqlLogMaxArgs is the max number of query args logged per statement
+	sqlLogMaxArgs = 50
+
+	secretPropKeys = []string{"password", "access_key_id", "secret_access_key", "session_token", "aws_access_key_id", "aws_secret_access_key", "ssh_private_key", "ssh_passphrase", "sas_svc_url", "conn_str"}
+)
+
+// SQLLogEntry is a statement written to the sql log file
+type SQLLogEntry struct {
+	Time         string  `json:"time"`
+	Conn         string  `json:"conn,omitempty"`
+	Query        string  `json:"query"`
+	Args         []any   `json:"args,omitempty"`
+	Duration     float64 `json:"duration"` // in seconds
+	RowsAffected *int64  `json:"rows_affected,omitempty"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// LogSQLToFile writes the statement to the sql log file (env var `SLING_LOG_SQL`),
+// as a JSON line, with its args (secrets redacted), duration and affected rows.
+// Does nothing if the sql log file is not set.
+func LogSQLToFile(props map[string]string, query string, args []any, duration time.Duration, rowsAffected *int64, err error) {
+	filePath := os.Getenv("SLING_LOG_SQL")
+	if filePath == "" {
+		return
+	}
+
+	query = strings.TrimSpace(strings.ReplaceAll(query, NoDebugKey, ""))
+	entry := SQLLogEntry{
+		Time:         time.Now().Format(time.RFC3339Nano),
+		Conn:         props["sling_conn_id"],
+		Query:        Clean(props, query),
+		Args:         redactSQLArgs(props, args),
+		Duration:     duration.Seconds(),
+		RowsAffected: rowsAffected,
+	}
+	if err != nil {
+		entry.Error = Clean(props, err.Error())
+	}
+
+	sqlLogMux.Lock()
+	defer sqlLogMux.Unlock()
+
+	if sqlLogFile == nil || sqlLogPath != filePath {
+		if sqlLogFile != nil {
+			sqlLogFile.Close()
+		}
+
+		file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			g.Warn("could not open sql log file %s: %s", filePath, err.Error())
+			os.Unsetenv("SLING_LOG_SQL") // warn once
+			return
+		}
+		sqlLogFile, sqlLogPath = file, filePath
+	}
+
+	sqlLogFile.WriteString(g.Marshal(entry) + "\n")
+}
+
+// redactSQLArgs masks the args matching a connection secret,
+// and truncates the args of large batches
+func redactSQLArgs(props map[string]string, args []any) []any {
+	if len(args) == 0 {
+		return nil
+	}
+
+	secrets := map[string]bool{}
+	for _, key := range secretPropKeys {
+		if val := strings.TrimSpace(props[key]); val != "" {
+			secrets[val] = true
+		}
+	}
+
+	redacted := []any{}
+	for i, arg := range args {
+		if i == sqlLogMaxArgs {
+			redacted = append(redacted, g.F("... (%d more)", len(args)-sqlLogMaxArgs))
+			break
+		}
+
+		if val, ok := arg.(string); ok && secrets[val] {
+			arg = "***"
+		}
+		redacted = append(redacted, arg)
+	}
+
+	return redacted
+}
@@ -5,8 +5,10 @@ import (
 	"database/sql/driver"
 	"io"
 	"os"
+	"path"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/samber/lo"
@@ -14,6 +16,7 @@ import (
 	"github.com/slingdata-io/sling-cli/core/dbio/connection"
 	"github.com/slingdata-io/sling-cli/core/dbio/database"
 	"github.com/slingdata-io/sling-cli/core/dbio/filesys"
+	"github.com/slingdata-io/sling-cli/core/env"
 	"github.com/spf13/cast"
 
 	"github.com/flarco/g"
@@ -40,6 +43,10 @@ const (
 	SnapshotMode Mode = "snapshot"
 	// BackfillMode is to backfill
 	BackfillMode Mode = "backfill"
+	// SCD2Mode is to keep the history of changed records (slowly changing dimension type 2)
+	SCD2Mode Mode = "scd2"
+	// PartitionOverwriteMode is to replace the target partitions present in the data
+	PartitionOverwriteMode Mode = "partition-overwrite"
 )
 
 var AllMode = []struct {
@@ -51,6 +58,8 @@ var AllMode = []struct {
 	{TruncateMode, "TruncateMode"},
 	{SnapshotMode, "SnapshotMode"},
 	{BackfillMode, "BackfillMode"},
+	{SCD2Mode, "SCD2Mode"},
+	{PartitionOverwriteMode, "PartitionOverwriteMode"},
 }
 
 // NewConfig return a config object from a YAML / JSON string
@@ -89,6 +98,23 @@ func (cfg *Config) SetDefault() {
 	if cfg.Source.Options == nil {
 		cfg.Source.Options = &SourceOptions{}
 	}
+
+	// the process env is not set when embedded, see Source.Limit
+	if val := cfg.Env["SLING_LIMIT"]; val != "" && cfg.Options.Embedded {
+		cfg.Source.Options.Limit = g.Int(cast.ToInt(val))
+	}
+
+	// apply the connection default options (from env file), beneath the stream options
+	connSourceOptions := SourceOptions{}
+	if cfg.getConnDefaultOptions(cfg.SrcConn, "source_options", &connSourceOptions) {
+		cfg.Source.Options.SetDefaults(connSourceOptions)
+	}
+
+	// apply the global defaults (from defaults file)
+	globalDefaults := GlobalDefaults()
+	if globalDefaults.SourceOptions != nil {
+		cfg.Source.Options.SetDefaults(*globalDefaults.SourceOptions)
+	}
 	cfg.Source.Options.SetDefaults(sourceOptions)
 
 	// https://github.com/slingdata-io/sling-cli/issues/348
@@ -115,6 +141,14 @@ func (cfg *Config) SetDefault() {
 	if cfg.Target.Options == nil {
 		cfg.Target.Options = &TargetOptions{}
 	}
+
+	connTargetOptions := TargetOptions{}
+	if cfg.getConnDefaultOptions(cfg.TgtConn, "target_options", &connTargetOptions) {
+		cfg.Target.Options.SetDefaults(connTargetOptions)
+	}
+	if globalDefaults.TargetOptions != nil {
+		cfg.Target.Options.SetDefaults(*globalDefaults.TargetOptions)
+	}
 	cfg.Target.Options.SetDefaults(targetOptions)
 
 	if cfg.Target.Options.AdjustColumnType == nil && (cfg.SrcConn.Type.Kind() == dbio.KindFile || cfg.Options.StdIn) {
@@ -152,40 +186,163 @@ func (cfg *Config) SetDefault() {
 		cfg.Target.Options.DatetimeFormat = "2006-01-02 15:04:05.000000-07"
 	}
 
-	// set vars
-	for k, v := range cfg.Env {
-		os.Setenv(k, v)
+	// set vars, unless embedded
+	if !cfg.Options.Embedded {
+		for k, v := range cfg.Env {
+			os.Setenv(k, v)
+		}
 	}
 
 	// default mode
-	if cfg.Mode == "" {
+	if cfg.Mode == "" && globalDefaults.Mode != "" {
+		cfg.Mode = globalDefaults.Mode
+	} else if cfg.Mode == "" {
 		cfg.Mode = FullRefreshMode
 	}
 
-	if val := os.Getenv("SLING_LOADED_AT_COLUMN"); val != "" {
+	if val := cfg.GetEnv("SLING_LOADED_AT_COLUMN"); val != "" {
 		if cast.ToBool(val) || val == "unix" || val == "timestamp" {
 			cfg.MetadataLoadedAt = g.Bool(true)
 		} else {
 			cfg.MetadataLoadedAt = g.Bool(false)
 		}
 	}
-	if val := os.Getenv("SLING_STREAM_URL_COLUMN"); val != "" {
+	if val := cfg.GetEnv("SLING_STREAM_URL_COLUMN"); val != "" {
 		cfg.MetadataStreamURL = cast.ToBool(val)
 	}
-	if val := os.Getenv("SLING_ROW_ID_COLUMN"); val != "" {
+	if val := cfg.GetEnv("SLING_ROW_ID_COLUMN"); val != "" {
 		cfg.MetadataRowID = cast.ToBool(val)
 	}
-	if val := os.Getenv("SLING_EXEC_ID_COLUMN"); val != "" {
+	if val := cfg.GetEnv("SLING_EXEC_ID_COLUMN"); val != "" {
 		cfg.MetadataExecID = cast.ToBool(val)
 	}
-	if val := os.Getenv("SLING_ROW_NUM_COLUMN"); val != "" {
+	if val := cfg.GetEnv("SLING_ROW_NUM_COLUMN"); val != "" {
 		cfg.MetadataRowNum = cast.ToBool(val)
 	}
+	if val := cfg.GetEnv("SLING_ROW_HASH_COLUMN"); val != "" {
+		cfg.MetadataRowHash = cast.ToBool(val)
+	}
+	if val := cfg.GetEnv("SLING_FILE_LINE_COLUMN"); val != "" {
+		cfg.MetadataFileLine = cast.ToBool(val)
+	}
+
+	// metadata options take precedence over the env vars
+	if mo := cfg.Target.Options.Metadata; mo != nil {
+		if mo.LoadedAt != nil {
+			cfg.MetadataLoadedAt = mo.LoadedAt
+		}
+		for _, pair := range []struct {
+			option *bool
+			value  *bool
+		}{
+			{mo.StreamURL, &cfg.MetadataStreamURL},
+			{mo.RowNum, &cfg.MetadataRowNum},
+			{mo.RowID, &cfg.MetadataRowID},
+			{mo.ExecID, &cfg.MetadataExecID},
+			{mo.RowHash, &cfg.MetadataRowHash},
+			{mo.FileLine, &cfg.MetadataFileLine},
+		} {
+			if pair.option != nil {
+				*pair.value = *pair.option
+			}
+		}
+	}
 	if val := os.Getenv("SAMPLE_SIZE"); val != "" {
 		iop.SampleSize = cast.ToInt(val)
 	}
 }
 
+// GetEnv returns the value of an env var of the config, or of the process env
+func (cfg *Config) GetEnv(key string) string {
+	if val, ok := cfg.Env[key]; ok {
+		return val
+	}
+	return os.Getenv(key)
+}
+
+// GlobalDefaultsPath returns the path of the global defaults file.
+// Default is `~/.sling/defaults.yaml`, can be set with SLING_DEFAULTS_PATH.
+func GlobalDefaultsPath() string {
+	if val := os.Getenv("SLING_DEFAULTS_PATH"); val != "" {
+		return val
+	}
+	return path.Join(env.HomeDir, "defaults.yaml")
+}
+
+// globalDefaultsCache caches the parsed global defaults file, by path
+var globalDefaultsCache struct {
+	path     string
+	defaults ReplicationStreamConfig
+	mux      sync.Mutex
+}
+
+// GlobalDefaults returns the org-wide defaults from the global defaults file.
+// It has the same structure as the `defaults` key of a replication.
+// The file is parsed once per process, again only if the path changes.
+func GlobalDefaults() ReplicationStreamConfig {
+	filePath := GlobalDefaultsPath()
+
+	globalDefaultsCache.mux.Lock()
+	defer globalDefaultsCache.mux.Unlock()
+
+	if globalDefaultsCache.path != filePath {
+		globalDefaultsCache.defaults = readGlobalDefaults(filePath)
+		globalDefaultsCache.path = filePath
+	}
+	return globalDefaultsCache.defaults
+}
+
+func readGlobalDefaults(filePath string) (defaults ReplicationStreamConfig) {
+	if !g.PathExists(filePath) {
+		return
+	}
+
+	bytes, err := os.ReadFile(filePath)
+	if err != nil {
+		g.Warn("could not read global defaults file (%s): %s", filePath, err.Error())
+		return
+	}
+
+	if err = yaml.Unmarshal([]byte(os.ExpandEnv(string(bytes))), &defaults); err != nil {
+		g.Warn("could not parse global defaults file (%s): %s", filePath, err.Error())
+		return ReplicationStreamConfig{}
+	}
+
+	return
+}
+
+// getConnDefaultOptions reads the default options defined for a connection
+// (e.g. `target_options` key of a connection in the env file) into options.
+// Returns true if options were found.
+func (cfg *Config) getConnDefaultOptions(conn connection.Connection, key string, options any) bool {
+	if conn.Data == nil {
+		return false
+	}
+
+	var val any
+	for k, v := range conn.Data {
+		if strings.EqualFold(k, key) {
+			val = v
+		}
+	}
+
+	payload, ok := val.(string)
+	if !ok && val != nil {
+		bytes, _ := yaml.Marshal(val)
+		payload = string(bytes)
+	}
+
+	if strings.TrimSpace(payload) == "" {
+		return false
+	} else if err := yaml.Unmarshal([]byte(payload), options); err != nil {
+		g.Warn("could not parse %s of connection %s: %s", key, conn.Name, err.Error())
+		return false
+	}
+
+	g.Trace("using %s from connection %s: %s", key, conn.Name, g.Marshal(options))
+	return true
+}
+
 // Unmarshal parse a configuration file path or config text
 func (cfg *Config) Unmarshal(cfgStr string) (err error) {
 	// Add panic recovery
@@ -284,9 +441,9 @@ func (cfg *Config) DetermineType() (Type JobType, err error) {
 		}
 	}
 
-	validMode := g.In(cfg.Mode, FullRefreshMode, IncrementalMode, BackfillMode, SnapshotMode, TruncateMode)
+	validMode := g.In(cfg.Mode, FullRefreshMode, IncrementalMode, BackfillMode, SnapshotMode, TruncateMode, SCD2Mode, PartitionOverwriteMode)
 	if !validMode {
-		err = g.Error("must specify valid mode: full-refresh, incremental, backfill, snapshot or truncate")
+		err = g.Error("must specify valid mode: full-refresh, incremental, backfill, snapshot, truncate, scd2 or partition-overwrite")
 		return
 	}
 
@@ -300,7 +457,7 @@ func (cfg *Config) DetermineType() (Type JobType, err error) {
 			if cfg.Source.UpdateKey == "" {
 				cfg.Source.UpdateKey = "_bigtable_timestamp"
 			}
-		} else if srcFileProvided && cfg.Source.UpdateKey == slingLoadedAtColumn {
+		} else if srcFileProvided && cfg.Source.UpdateKey == cfg.loadedAtColumn() {
 			// need to loaded_at column for file incremental
 			cfg.MetadataLoadedAt = g.Bool(true)
 		} else if cfg.Source.UpdateKey == "" && len(cfg.Source.PrimaryKey()) == 0 {
@@ -327,6 +484,115 @@ func (cfg *Config) DetermineType() (Type JobType, err error) {
 		}
 	} else if cfg.Mode == SnapshotMode {
 		cfg.MetadataLoadedAt = g.Bool(true) // needed for snapshot mode
+	} else if cfg.Mode == SCD2Mode {
+		if len(cfg.Source.PrimaryKey()) == 0 {
+			err = g.Error("must specify value for 'primary_key' for scd2 mode. See docs for more details: https://docs.slingdata.io/sling-cli/run/configuration")
+			return
+		}
+	} else if cfg.Mode == PartitionOverwriteMode {
+		if cfg.PartitionColumn() == "" {
+			err = g.Error("must specify a partition column (target_options.table_keys.partition) or 'update_key' for partition-overwrite mode. See docs for more details: https://docs.slingdata.io/sling-cli/run/configuration")
+			return
+		}
+	}
+
+	if cfg.Target.Options != nil && cfg.Target.Options.SurrogateKey != nil {
+		if skType := cfg.Target.Options.SurrogateKey.Type; skType != "" && !g.In(skType, iop.SurrogateKeyHash, iop.SurrogateKeyUUID) {
+			err = g.Error("invalid surrogate_key type (%s), must be hash or uuid", skType)
+			return
+		}
+	}
+
+	if cfg.Target.Options != nil && cfg.Target.Options.Metadata != nil {
+		if err = cfg.Target.Options.Metadata.Validate(); err != nil {
+			return
+		}
+	}
+
+	if cfg.Target.Options != nil && cfg.Target.Options.Dedup != nil {
+		if cfg.Mode != IncrementalMode || len(cfg.Source.PrimaryKey()) > 0 {
+			err = g.Error("dedup is only supported for incremental mode without a primary_key (append-only)")
+			return
+		} else if len(cfg.Target.Options.Dedup.Keys) == 0 {
+			err = g.Error("must specify dedup keys")
+			return
+		} else if _, err = cfg.Target.Options.Dedup.WindowDuration(); err != nil {
+			return
+		}
+	}
+
+	if cfg.Target.Options != nil && cfg.Target.Options.CommitsPeriodically() {
+		if g.In(cfg.Mode, IncrementalMode, BackfillMode, SCD2Mode) && len(cfg.Source.PrimaryKey()) > 0 {
+			err = g.Error("commit_every_rows / commit_every_seconds are not supported for mode '%s' with a primary-key (append only)", cfg.Mode)
+			return
+		} else if cfg.Mode == PartitionOverwriteMode || cfg.Target.Options.Dedup != nil {
+			err = g.Error("commit_every_rows / commit_every_seconds are not supported with partition-overwrite mode or dedup (append only)")
+			return
+		}
+	}
+
+	if cfg.Target.Options != nil && cfg.Target.Options.ColumnMatch != nil {
+		if match := *cfg.Target.Options.ColumnMatch; !g.In(match, ColumnMatchExact, ColumnMatchCaseInsensitive, ColumnMatchNormalized) {
+			err = g.Error("invalid column_match (%s), must be exact, case_insensitive or normalized", match)
+			return
+		}
+	}
+
+	if cfg.Target.Options != nil && cfg.Target.Options.PartitionType != "" {
+		if pt := strings.ToLower(cfg.Target.Options.PartitionType); !g.In(pt, "hour", "day", "month", "year", "ingestion") {
+			err = g.Error("invalid partition_type (%s), must be hour, day, month, year or ingestion", pt)
+			return
+		}
+	}
+
+	if cfg.Source.Options != nil && cfg.Source.Options.RateLimit != nil {
+		if _, err = iop.ParseRateLimit(*cfg.Source.Options.RateLimit); err != nil {
+			return
+		}
+	}
+
+	if cfg.Source.Options != nil && cfg.Source.Options.Concurrency != nil && *cfg.Source.Options.Concurrency < 1 {
+		err = g.Error("invalid source concurrency (%d), must be greater than 0", *cfg.Source.Options.Concurrency)
+		return
+	}
+
+	if cfg.Source.Options != nil && cfg.Source.Options.FileIncremental != nil {
+		if mode := FileIncrementalMode(strings.ToLower(*cfg.Source.Options.FileIncremental)); !g.In(mode, FileIncrementalModified, FileIncrementalName) {
+			err = g.Error("invalid file_incremental (%s), must be modified or name", mode)
+			return
+		} else if !cfg.sourceIsFile() {
+			err = g.Error("file_incremental requires a file source")
+			return
+		}
+	}
+
+	if cfg.Source.Options != nil && cfg.Source.Options.Sample != nil {
+		if err = cfg.Source.Options.Sample.Validate(); err != nil {
+			return
+		}
+	}
+
+	if cfg.Source.Options != nil && len(cfg.Source.Options.Attach) > 0 && !g.In(cfg.SrcConn.Type, dbio.TypeDbDuckDb, dbio.TypeDbMotherDuck) {
+		err = g.Error("source option attach requires a duckdb source connection, not %s", cfg.SrcConn.Type)
+		return
+	}
+
+	if cfg.Source.Options != nil && cfg.Source.Options.PostRead != nil {
+		if err = cfg.Source.Options.PostRead.Validate(cfg.SrcConn.Info().Type); err != nil {
+			return
+		}
+	}
+
+	if cfg.Target.Options != nil && cfg.Target.Options.RateLimit != nil {
+		if _, err = iop.ParseRateLimit(*cfg.Target.Options.RateLimit); err != nil {
+			return
+		}
+	}
+
+	if cfg.Target.Options != nil && cfg.Target.Options.SchemaEvolution != nil {
+		if err = cfg.Target.Options.SchemaEvolution.Validate(); err != nil {
+			return
+		}
 	}
 
 	if srcDbProvided && tgtDbProvided {
@@ -391,6 +657,63 @@ func (cfg *Config) HasWildcard() bool {
 	return false
 }
 
+// IsSharded returns true if the target is split into multiple shard tables
+func (cfg *Config) IsSharded() bool {
+	return cfg.Target.Options != nil && cfg.Target.Options.Shards != nil && *cfg.Target.Options.Shards > 1
+}
+
+// Shard expands a sharded config into one config per shard, each writing
+// the rows where hash(shard_key) % shards == shard_index into the target
+// object, with the `{shard}` placeholder replaced by the shard index.
+// Each shard task reads the whole source and keeps the rows of its shard,
+// so the source is read once per shard: only database sources are supported.
+func (cfg *Config) Shard() (cfgs []*Config, err error) {
+	if !cfg.IsSharded() || cfg.Target.Options.ShardIndex != nil {
+		return []*Config{cfg}, nil
+	}
+
+	if srcType := cfg.sourceType(); !srcType.IsDb() {
+		return nil, g.Error("shards are only supported with a database source, since the source is read once per shard")
+	}
+
+	shards := *cfg.Target.Options.Shards
+	if !strings.Contains(cfg.Target.Object, "{shard}") {
+		return nil, g.Error("target object must contain the {shard} placeholder when using shards (e.g. `my_schema.my_table_{shard}` or `shard_{shard}.my_table`)")
+	}
+
+	shardKey := cfg.Target.Options.ShardKey
+	if len(shardKey) == 0 {
+		shardKey = cfg.Source.PrimaryKey()
+	}
+	if len(shardKey) == 0 {
+		return nil, g.Error("must provide a shard_key or primary_key when using shards")
+	}
+
+	for i := 0; i < shards; i++ {
+		shardCfg := *cfg
+		shardCfg.Target.Options = nil
+		g.Unmarshal(g.Marshal(cfg.Target.Options), &shardCfg.Target.Options)
+		shardCfg.Target.Options.ShardKey = shardKey
+		shardCfg.Target.Options.ShardIndex = g.Int(i)
+		shardCfg.Target.Object = strings.ReplaceAll(cfg.Target.Object, "{shard}", cast.ToString(i))
+		cfgs = append(cfgs, &shardCfg)
+	}
+
+	return cfgs, nil
+}
+
+// sourceType returns the type of the source connection, before preparing
+func (cfg *Config) sourceType() dbio.Type {
+	if !cfg.SrcConn.Type.IsUnknown() {
+		return cfg.SrcConn.Type
+	} else if conn := connection.GetLocalConns().Get(cfg.Source.Conn); conn.Name != "" {
+		return conn.Connection.Type
+	} else if cfg.Source.Conn != "" {
+		return connection.SchemeType(cfg.Source.Conn)
+	}
+	return connection.SchemeType(cfg.Source.Stream)
+}
+
 func (cfg *Config) AsReplication() (rc ReplicationConfig) {
 	rc = ReplicationConfig{
 		Source: cfg.Source.Conn,
@@ -438,7 +761,7 @@ func (cfg *Config) Prepare() (err error) {
 		return g.Error("invalid target object (blank or not found)")
 	}
 
-	if cfg.Options.Debug && os.Getenv("DEBUG") == "" {
+	if cfg.Options.Debug && os.Getenv("DEBUG") == "" && !cfg.Options.Embedded {
 		os.Setenv("DEBUG", "LOW")
 	}
 	if cfg.Options.StdIn && cfg.Source.Stream == "" {
@@ -504,6 +827,10 @@ func (cfg *Config) Prepare() (err error) {
 		cfg.TgtConn = tgtConn
 	}
 
+	if err = cfg.TgtConn.ResolveSecrets(); err != nil {
+		return g.Error(err, "could not resolve secrets of target connection")
+	}
+
 	if cfg.Options.StdOut && os.Getenv("CONCURRENCY") == "" {
 		os.Setenv("CONCURRENCY", "1")
 	}
@@ -514,17 +841,26 @@ func (cfg *Config) Prepare() (err error) {
 
 	// Set Source
 	cfg.Source.Stream = strings.TrimSpace(cfg.Source.Stream)
+
+	// cache pseudo-connection, wrapping the source connection
+	srcConnName := cfg.Source.Conn
+	if cfg.sourceCache, err = ParseSourceCache(cfg.Source.Conn); err != nil {
+		return g.Error(err, "could not parse source cache")
+	} else if cfg.sourceCache != nil {
+		srcConnName = cfg.sourceCache.Conn
+	}
+
 	if cfg.Source.Data == nil || len(cfg.Source.Data) == 0 {
 		cfg.Source.Data = g.M()
-		if c, ok := connsMap[strings.ToLower(cfg.Source.Conn)]; ok {
+		if c, ok := connsMap[strings.ToLower(srcConnName)]; ok {
 			cfg.SrcConn = *c.Connection.Copy()
-		} else if connType := connection.SchemeType(cfg.Source.Conn); !connType.IsUnknown() {
-			cfg.SrcConn, err = connection.NewConnectionFromURL(connType.String(), cfg.Source.Conn)
+		} else if connType := connection.SchemeType(srcConnName); !connType.IsUnknown() {
+			cfg.SrcConn, err = connection.NewConnectionFromURL(connType.String(), srcConnName)
 			if err != nil {
 				return g.Error(err, "could not init source connection")
 			}
-		} else if !strings.Contains(cfg.Source.Conn, "://") && cfg.Source.Conn != "" && cfg.SrcConn.Data == nil {
-			return g.Error("could not find connection %s", cfg.Source.Conn)
+		} else if !strings.Contains(srcConnName, "://") && srcConnName != "" && cfg.SrcConn.Data == nil {
+			return g.Error("could not find connection %s", srcConnName)
 		} else if cfg.SrcConn.Data == nil {
 			cfg.SrcConn.Data = g.M()
 		}
@@ -563,6 +899,10 @@ func (cfg *Config) Prepare() (err error) {
 		cfg.SrcConn = srcConn
 	}
 
+	if err = cfg.SrcConn.ResolveSecrets(); err != nil {
+		return g.Error(err, "could not resolve secrets of source connection")
+	}
+
 	// format target name, now we have source info
 	err = cfg.FormatTargetObjectName()
 	if err != nil {
@@ -597,12 +937,22 @@ func (cfg *Config) Prepare() (err error) {
 
 	// validate capability to write
 	switch cfg.Target.Type {
-	case dbio.TypeDbPrometheus, dbio.TypeDbMongoDB, dbio.TypeDbElasticsearch, dbio.TypeDbBigTable:
+	case dbio.TypeDbPrometheus, dbio.TypeDbMongoDB, dbio.TypeDbElasticsearch, dbio.TypeDbBigTable,
+		dbio.TypeApiHubSpot, dbio.TypeApiStripe, dbio.TypeApiShopify, dbio.TypeApiGA4, dbio.TypeApiGoogleAds,
+		dbio.TypeApiJira, dbio.TypeApiGitHub:
 		return g.Error("sling cannot currently write to %s", cfg.Target.Type)
 	}
 
 	// validate table keys
 	if tkMap := cfg.Target.Options.TableKeys; tkMap != nil {
+		// aliases, e.g. `partition_by`
+		for _, kt := range lo.Keys(tkMap) {
+			if normalized := kt.Normalize(); normalized != kt {
+				tkMap[normalized] = append(tkMap[normalized], tkMap[kt]...)
+				delete(tkMap, kt)
+			}
+		}
+
 		for _, kt := range lo.Keys(tkMap) {
 			// append _key
 			ktAllowed := g.Marshal(iop.KeyTypes)
@@ -635,6 +985,7 @@ func (cfg *Config) Prepare() (err error) {
 
 	// sql prop
 	cfg.Source.Query = g.Rm(cfg.Source.Query, fMap)
+	cfg.Source.Where = g.Rm(cfg.Source.Where, fMap)
 	if cfg.ReplicationStream != nil {
 		cfg.ReplicationStream.SQL = cfg.Source.Query
 	}
@@ -944,6 +1295,15 @@ func (cfg *Config) GetFormatMap() (m map[string]any, err error) {
 		g.Warn("Could not successfully get format values. Blank values for: %s", strings.Join(blankKeys, ", "))
 	}
 
+	// runtime variables, as `{var.name}`
+	vars, err := RuntimeVars()
+	if err != nil {
+		return m, err
+	}
+	for k, v := range vars {
+		m["var."+k] = v
+	}
+
 	now := time.Now()
 
 	// nested formatting for jmespath lookup
@@ -985,6 +1345,24 @@ func (cfg *Config) GetFormatMap() (m map[string]any, err error) {
 	return
 }
 
+// RuntimeVars returns the runtime variables passed with `--vars`
+// (env var SLING_VARS), in the format `key1=value1,key2=value2`
+func RuntimeVars() (vars map[string]string, err error) {
+	vars = map[string]string{}
+	for _, pair := range strings.Split(os.Getenv("SLING_VARS"), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, g.Error("invalid runtime variable '%s', expected key=value", pair)
+		}
+		vars[key] = strings.TrimSpace(value)
+	}
+	return vars, nil
+}
+
 // Config is the new config struct
 type Config struct {
 	Source     Source            `json:"source,omitempty" yaml:"source,omitempty"`
@@ -1009,8 +1387,11 @@ type Config struct {
 	MetadataRowNum    bool  `json:"-" yaml:"-"`
 	MetadataRowID     bool  `json:"-" yaml:"-"`
 	MetadataExecID    bool  `json:"-" yaml:"-"`
+	MetadataRowHash   bool  `json:"-" yaml:"-"`
+	MetadataFileLine  bool  `json:"-" yaml:"-"`
 
-	extraTransforms []string `json:"-" yaml:"-"`
+	extraTransforms []string     `json:"-" yaml:"-"`
+	sourceCache     *SourceCache `json:"-" yaml:"-"`
 }
 
 // Scan scan value into Jsonb, implements sql.Scanner interface
@@ -1075,6 +1456,7 @@ func (cfg *Config) ColumnsPrepared() (columns iop.Columns) {
 		// parse constraint, length, precision, scale
 		for i := range columns {
 			columns[i].SetConstraint()
+			columns[i].Type = columns[i].Type.Resolve()
 			columns[i].SetLengthPrecisionScale()
 		}
 
@@ -1185,6 +1567,17 @@ func (cfg *Config) MD5() string {
 	return g.MD5(payload)
 }
 
+// PartitionColumn returns the column to overwrite partitions on: the first
+// partition table key, or the update key
+func (cfg *Config) PartitionColumn() string {
+	if cfg.Target.Options != nil {
+		if keys := cfg.Target.Options.TableKeys[iop.PartitionKey]; len(keys) > 0 {
+			return keys[0]
+		}
+	}
+	return cfg.Source.UpdateKey
+}
+
 func (cfg *Config) SrcConnMD5() string {
 	return g.MD5(cfg.SrcConn.URL())
 }
@@ -1197,12 +1590,30 @@ func (cfg *Config) StreamID() string {
 	return g.MD5(cfg.Source.Conn, cfg.Target.Conn, cfg.StreamName, cfg.Target.Object)
 }
 
+// StreamLabel returns the stream name, or the source stream of a single task
+func (cfg *Config) StreamLabel() string {
+	if cfg.StreamName != "" {
+		return cfg.StreamName
+	}
+	return cfg.Source.Stream
+}
+
 // ConfigOptions are configuration options
 type ConfigOptions struct {
 	Debug   bool `json:"debug,omitempty" yaml:"debug,omitempty"`
 	StdIn   bool `json:"-"`                                          // whether stdin is passed
 	StdOut  bool `json:"stdout,omitempty" yaml:"stdout,omitempty"`   // whether to output to stdout
 	Dataset bool `json:"dataset,omitempty" yaml:"dataset,omitempty"` // whether to output to dataset
+
+	// consumes the output dataflow instead of stdout, returning the row count (see Stream)
+	Consume func(df *iop.Dataflow) (uint64, error) `json:"-" yaml:"-"`
+
+	// whether sling is embedded in an application: the env vars of the config are
+	// not set into the process env, and no progress bar is shown. The task settings
+	// (e.g. SLING_LIMIT, SLING_ALLOW_EMPTY, SLING_CHECKSUM_ROWS) are read from the
+	// config env, the other keys (e.g. SAMPLE_SIZE, SLING_STATE, DBT_PATH) only from
+	// the process env
+	Embedded bool `json:"-" yaml:"-"`
 }
 
 // Source is a source of data
@@ -1225,6 +1636,13 @@ func (s *Source) Limit() int {
 		return cast.ToInt(val)
 	}
 
+	// a head sample is a limit
+	if sample := s.Options.Sample; sample != nil && sample.Method != SampleRandom {
+		if s.Options.Limit == nil || *s.Options.Limit > sample.Rows {
+			return sample.Rows
+		}
+	}
+
 	if s.Options.Limit == nil {
 		return 0
 	}
@@ -1238,6 +1656,14 @@ func (s *Source) Offset() int {
 	return *s.Options.Offset
 }
 
+// ChunkSize returns the number of rows to read per query (with keyset pagination)
+func (s *Source) ChunkSize() int {
+	if s.Options.ChunkSize == nil {
+		return 0
+	}
+	return *s.Options.ChunkSize
+}
+
 func (s *Source) HasUpdateKey() bool {
 	return s.UpdateKey != ""
 }
@@ -1301,26 +1727,42 @@ func (t *Target) MD5() string {
 
 // SourceOptions are connection and stream processing options
 type SourceOptions struct {
-	EmptyAsNull    *bool               `json:"empty_as_null,omitempty" yaml:"empty_as_null,omitempty"`
-	Header         *bool               `json:"header,omitempty" yaml:"header,omitempty"`
-	Flatten        *bool               `json:"flatten,omitempty" yaml:"flatten,omitempty"`
-	FieldsPerRec   *int                `json:"fields_per_rec,omitempty" yaml:"fields_per_rec,omitempty"`
-	Compression    *iop.CompressorType `json:"compression,omitempty" yaml:"compression,omitempty"`
-	Format         *dbio.FileType      `json:"format,omitempty" yaml:"format,omitempty"`
-	NullIf         *string             `json:"null_if,omitempty" yaml:"null_if,omitempty"`
-	DatetimeFormat string              `json:"datetime_format,omitempty" yaml:"datetime_format,omitempty"`
-	SkipBlankLines *bool               `json:"skip_blank_lines,omitempty" yaml:"skip_blank_lines,omitempty"`
-	Delimiter      string              `json:"delimiter,omitempty" yaml:"delimiter,omitempty"`
-	Escape         string              `json:"escape,omitempty" yaml:"escape,omitempty"`
-	Quote          string              `json:"quote,omitempty" yaml:"quote,omitempty"`
-	MaxDecimals    *int                `json:"max_decimals,omitempty" yaml:"max_decimals,omitempty"`
-	JmesPath       *string             `json:"jmespath,omitempty" yaml:"jmespath,omitempty"`
-	Sheet          *string             `json:"sheet,omitempty" yaml:"sheet,omitempty"`
-	Range          *string             `json:"range,omitempty" yaml:"range,omitempty"`
-	Limit          *int                `json:"limit,omitempty" yaml:"limit,omitempty"`
-	Offset         *int                `json:"offset,omitempty" yaml:"offset,omitempty"`
-	FileSelect     *[]string           `json:"file_select,omitempty" yaml:"file_select,omitempty"` // include/exclude files
-	ParallelChunks *int                `json:"parallel_chunks,omitempty" yaml:"parallel_chunks,omitempty"`
+	EmptyAsNull     *bool               `json:"empty_as_null,omitempty" yaml:"empty_as_null,omitempty"`
+	Header          *bool               `json:"header,omitempty" yaml:"header,omitempty"`
+	Flatten         *bool               `json:"flatten,omitempty" yaml:"flatten,omitempty"`
+	FieldsPerRec    *int                `json:"fields_per_rec,omitempty" yaml:"fields_per_rec,omitempty"`
+	Compression     *iop.CompressorType `json:"compression,omitempty" yaml:"compression,omitempty"`
+	Format          *dbio.FileType      `json:"format,omitempty" yaml:"format,omitempty"`
+	NullIf          *string             `json:"null_if,omitempty" yaml:"null_if,omitempty"`
+	DatetimeFormat  string              `json:"datetime_format,omitempty" yaml:"datetime_format,omitempty"`
+	SkipBlankLines  *bool               `json:"skip_blank_lines,omitempty" yaml:"skip_blank_lines,omitempty"`
+	Delimiter       string              `json:"delimiter,omitempty" yaml:"delimiter,omitempty"`
+	Escape          string              `json:"escape,omitempty" yaml:"escape,omitempty"`
+	Quote           string              `json:"quote,omitempty" yaml:"quote,omitempty"`
+	MaxDecimals     *int                `json:"max_decimals,omitempty" yaml:"max_decimals,omitempty"`
+	JmesPath        *string             `json:"jmespath,omitempty" yaml:"jmespath,omitempty"`
+	Sheet           *string             `json:"sheet,omitempty" yaml:"sheet,omitempty"`
+	Range           *string             `json:"range,omitempty" yaml:"range,omitempty"`
+	Limit           *int                `json:"limit,omitempty" yaml:"limit,omitempty"`
+	Offset          *int                `json:"offset,omitempty" yaml:"offset,omitempty"`
+	ChunkSize       *int                `json:"chunk_size,omitempty" yaml:"chunk_size,omitempty"`   // rows per query, with keyset pagination
+	FetchSize       *int                `json:"fetch_size,omitempty" yaml:"fetch_size,omitempty"`   // rows per fetch, with a server-side cursor (postgres)
+	FileSelect      *[]string           `json:"file_select,omitempty" yaml:"file_select,omitempty"` // include/exclude files
+	ParallelChunks  *int                `json:"parallel_chunks,omitempty" yaml:"parallel_chunks,omitempty"`
+	Concurrency     *int                `json:"concurrency,omitempty" yaml:"concurrency,omitempty"`           // parallel file readers
+	Ordered         *bool               `json:"ordered,omitempty" yaml:"ordered,omitempty"`                   // keep the order of the files when read in parallel
+	FileIncremental *string             `json:"file_incremental,omitempty" yaml:"file_incremental,omitempty"` // only read new files: `modified` or `name`
+	PostRead        *PostReadOptions    `json:"post_read,omitempty" yaml:"post_read,omitempty"`               // move, delete or tag the files once loaded
+	Sample          *SampleOptions      `json:"sample,omitempty" yaml:"sample,omitempty"`                     // only load a sample of the rows
+	Attach          []string            `json:"attach,omitempty" yaml:"attach,omitempty"`                     // connections attached into a duckdb source, to join them
+	Filter          *string             `json:"filter,omitempty" yaml:"filter,omitempty"`                     // row filter expression
+	RateLimit       *string             `json:"rate_limit,omitempty" yaml:"rate_limit,omitempty"`             // read throughput, e.g. `5000 rows/s` or `10MB/s`
+	ComputedColumns map[string]string   `json:"computed_columns,omitempty" yaml:"computed_columns,omitempty"`
+	LogParser       *string             `json:"log_parser,omitempty" yaml:"log_parser,omitempty"` // log format: auto, json, logfmt, syslog, clf or a regex with named groups
+	Tail            *bool               `json:"tail,omitempty" yaml:"tail,omitempty"`             // watch mode, only read the lines appended to the log files
+
+	// byte ranges of the tailed files to read (uri => [start, end]), set by the watch mode
+	TailOffsets map[string][2]int64 `json:"tail_offsets,omitempty" yaml:"tail_offsets,omitempty"`
 
 	// columns & transforms were moved out of source_options
 	// https://github.com/slingdata-io/sling-cli/issues/348
@@ -1330,28 +1772,242 @@ type SourceOptions struct {
 
 // TargetOptions are target connection and stream processing options
 type TargetOptions struct {
-	Header           *bool               `json:"header,omitempty" yaml:"header,omitempty"`
-	Compression      *iop.CompressorType `json:"compression,omitempty" yaml:"compression,omitempty"`
-	Concurrency      int                 `json:"concurrency,omitempty" yaml:"concurrency,omitempty"`
-	BatchLimit       *int64              `json:"batch_limit,omitempty" yaml:"batch_limit,omitempty"`
-	DatetimeFormat   string              `json:"datetime_format,omitempty" yaml:"datetime_format,omitempty"`
-	Delimiter        string              `json:"delimiter,omitempty" yaml:"delimiter,omitempty"`
-	FileMaxRows      *int64              `json:"file_max_rows,omitempty" yaml:"file_max_rows,omitempty"`
-	FileMaxBytes     *int64              `json:"file_max_bytes,omitempty" yaml:"file_max_bytes,omitempty"`
-	Format           dbio.FileType       `json:"format,omitempty" yaml:"format,omitempty"`
-	MaxDecimals      *int                `json:"max_decimals,omitempty" yaml:"max_decimals,omitempty"`
-	UseBulk          *bool               `json:"use_bulk,omitempty" yaml:"use_bulk,omitempty"`
-	IgnoreExisting   *bool               `json:"ignore_existing,omitempty" yaml:"ignore_existing,omitempty"`
-	DeleteMissing    *string             `json:"delete_missing,omitempty" yaml:"delete_missing,omitempty"`
-	AddNewColumns    *bool               `json:"add_new_columns,omitempty" yaml:"add_new_columns,omitempty"`
-	AdjustColumnType *bool               `json:"adjust_column_type,omitempty" yaml:"adjust_column_type,omitempty"`
-	ColumnCasing     *iop.ColumnCasing   `json:"column_casing,omitempty" yaml:"column_casing,omitempty"`
-
-	TableKeys database.TableKeys `json:"table_keys,omitempty" yaml:"table_keys,omitempty"`
-	TableTmp  string             `json:"table_tmp,omitempty" yaml:"table_tmp,omitempty"`
-	TableDDL  *string            `json:"table_ddl,omitempty" yaml:"table_ddl,omitempty"`
-	PreSQL    *string            `json:"pre_sql,omitempty" yaml:"pre_sql,omitempty"`
-	PostSQL   *string            `json:"post_sql,omitempty" yaml:"post_sql,omitempty"`
+	Header             *bool                 `json:"header,omitempty" yaml:"header,omitempty"`
+	Compression        *iop.CompressorType   `json:"compression,omitempty" yaml:"compression,omitempty"`
+	Concurrency        int                   `json:"concurrency,omitempty" yaml:"concurrency,omitempty"`
+	BatchLimit         *int64                `json:"batch_limit,omitempty" yaml:"batch_limit,omitempty"`
+	DatetimeFormat     string                `json:"datetime_format,omitempty" yaml:"datetime_format,omitempty"`
+	Delimiter          string                `json:"delimiter,omitempty" yaml:"delimiter,omitempty"`
+	FileMaxRows        *int64                `json:"file_max_rows,omitempty" yaml:"file_max_rows,omitempty"`
+	FileMaxBytes       *int64                `json:"file_max_bytes,omitempty" yaml:"file_max_bytes,omitempty"`
+	Format             dbio.FileType         `json:"format,omitempty" yaml:"format,omitempty"`
+	MaxDecimals        *int                  `json:"max_decimals,omitempty" yaml:"max_decimals,omitempty"`
+	UseBulk            *bool                 `json:"use_bulk,omitempty" yaml:"use_bulk,omitempty"`
+	QueryTag           *string               `json:"query_tag,omitempty" yaml:"query_tag,omitempty"`                       // snowflake QUERY_TAG, defaults to the stream identifiers
+	DirectInsert       *bool                 `json:"direct_insert,omitempty" yaml:"direct_insert,omitempty"`               // no temp table, at the cost of atomicity
+	CommitEveryRows    *int64                `json:"commit_every_rows,omitempty" yaml:"commit_every_rows,omitempty"`       // commits periodically (direct insert)
+	CommitEverySeconds *int64                `json:"commit_every_seconds,omitempty" yaml:"commit_every_seconds,omitempty"` // commits periodically (direct insert)
+	IgnoreExisting     *bool                 `json:"ignore_existing,omitempty" yaml:"ignore_existing,omitempty"`
+	DeleteMissing      *string               `json:"delete_missing,omitempty" yaml:"delete_missing,omitempty"`
+	AddNewColumns      *bool                 `json:"add_new_columns,omitempty" yaml:"add_new_columns,omitempty"`
+	AdjustColumnType   *bool                 `json:"adjust_column_type,omitempty" yaml:"adjust_column_type,omitempty"`
+	AllowTypeNarrowing *bool                 `json:"allow_type_narrowing,omitempty" yaml:"allow_type_narrowing,omitempty"`
+	SchemaEvolution    *SchemaEvolution      `json:"schema_evolution,omitempty" yaml:"schema_evolution,omitempty"`
+	ColumnCasing       *iop.ColumnCasing     `json:"column_casing,omitempty" yaml:"column_casing,omitempty"`
+	ColumnTags         map[string][]string   `json:"column_tags,omitempty" yaml:"column_tags,omitempty"`
+	ColumnMapping      map[string]string     `json:"column_mapping,omitempty" yaml:"column_mapping,omitempty"`
+	ColumnMatch        *ColumnMatch          `json:"column_match,omitempty" yaml:"column_match,omitempty"`
+	Shards             *int                  `json:"shards,omitempty" yaml:"shards,omitempty"`
+	ShardKey           []string              `json:"shard_key,omitempty" yaml:"shard_key,omitempty"`
+	ShardIndex         *int                  `json:"shard_index,omitempty" yaml:"shard_index,omitempty"`
+	SurrogateKey       *iop.SurrogateKey     `json:"surrogate_key,omitempty" yaml:"surrogate_key,omitempty"`
+	SCD2               *database.SCD2Options `json:"scd2,omitempty" yaml:"scd2,omitempty"`
+	Dedup              *DedupOptions         `json:"dedup,omitempty" yaml:"dedup,omitempty"`
+	Metadata           *MetadataOptions      `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Checks             []string              `json:"checks,omitempty" yaml:"checks,omitempty"`                       // assertion queries, e.g. `select count(*) from {target_table} where id is null == 0`
+	RateLimit          *string               `json:"rate_limit,omitempty" yaml:"rate_limit,omitempty"`               // write throughput, e.g. `5000 rows/s` or `10MB/s`
+	Manifest           *string               `json:"manifest,omitempty" yaml:"manifest,omitempty"`                   // location of the manifest of the written files (json or csv)
+	Atomic             *bool                 `json:"atomic,omitempty" yaml:"atomic,omitempty"`                       // write files into a temporary location, renamed once all written
+	ChecksumMetadata   *bool                 `json:"checksum_metadata,omitempty" yaml:"checksum_metadata,omitempty"` // set the sha256 of the files as object metadata
+	ContentAddressed   *bool                 `json:"content_addressed,omitempty" yaml:"content_addressed,omitempty"` // name the part files by the sha256 of their content
+
+	TableKeys    database.TableKeys `json:"table_keys,omitempty" yaml:"table_keys,omitempty"`
+	TableTmp     string             `json:"table_tmp,omitempty" yaml:"table_tmp,omitempty"`
+	KeepTmpTable *bool              `json:"keep_tmp_table,omitempty" yaml:"keep_tmp_table,omitempty"` // keep the temp table when the load fails
+	FailureTable string             `json:"failure_table,omitempty" yaml:"failure_table,omitempty"`   // copy of the temp table when the load fails
+	TableDDL     *string            `json:"table_ddl,omitempty" yaml:"table_ddl,omitempty"`
+	PreSQL       *string            `json:"pre_sql,omitempty" yaml:"pre_sql,omitempty"`
+	PostSQL      *string            `json:"post_sql,omitempty" yaml:"post_sql,omitempty"`
+
+	// bigquery table options
+	PartitionType          string `json:"partition_type,omitempty" yaml:"partition_type,omitempty"` // hour, day, month, year or ingestion
+	RequirePartitionFilter *bool  `json:"require_partition_filter,omitempty" yaml:"require_partition_filter,omitempty"`
+	TableExpirationDays    *int   `json:"table_expiration_days,omitempty" yaml:"table_expiration_days,omitempty"`
+}
+
+// ColumnMatch is how the source columns are matched to the columns of an existing target table
+type ColumnMatch string
+
+const (
+	ColumnMatchExact           ColumnMatch = "exact"
+	ColumnMatchCaseInsensitive ColumnMatch = "case_insensitive"
+	ColumnMatchNormalized      ColumnMatch = "normalized" // ignores casing and non-alphanumeric characters, e.g. `OrderID` = `order_id`
+)
+
+var nonAlphanumericRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Key returns the name used to match columns
+func (cm ColumnMatch) Key(name string) string {
+	switch cm {
+	case ColumnMatchCaseInsensitive:
+		return strings.ToLower(name)
+	case ColumnMatchNormalized:
+		return nonAlphanumericRegex.ReplaceAllString(strings.ToLower(name), "")
+	}
+	return name
+}
+
+// SchemaEvolutionAction is the action taken on a change of the source columns
+type SchemaEvolutionAction string
+
+const (
+	SchemaEvolutionAdd    SchemaEvolutionAction = "add"
+	SchemaEvolutionAlter  SchemaEvolutionAction = "alter"
+	SchemaEvolutionCast   SchemaEvolutionAction = "cast"
+	SchemaEvolutionIgnore SchemaEvolutionAction = "ignore"
+	SchemaEvolutionFail   SchemaEvolutionAction = "fail"
+)
+
+// SchemaEvolution is the policy for the changes of the source columns against
+// an existing target table. Empty values default to add_new_columns and adjust_column_type.
+type SchemaEvolution struct {
+	OnNewColumn  SchemaEvolutionAction `json:"on_new_column,omitempty" yaml:"on_new_column,omitempty"`   // add, ignore or fail
+	OnTypeWiden  SchemaEvolutionAction `json:"on_type_widen,omitempty" yaml:"on_type_widen,omitempty"`   // alter, ignore or fail
+	OnTypeNarrow SchemaEvolutionAction `json:"on_type_narrow,omitempty" yaml:"on_type_narrow,omitempty"` // cast (into the existing type) or fail
+	OnColumnDrop SchemaEvolutionAction `json:"on_column_drop,omitempty" yaml:"on_column_drop,omitempty"` // ignore (loaded as null) or fail
+}
+
+// Validate checks the actions of the policy
+func (se *SchemaEvolution) Validate() error {
+	checks := []struct {
+		key     string
+		value   SchemaEvolutionAction
+		allowed []SchemaEvolutionAction
+	}{
+		{"on_new_column", se.OnNewColumn, []SchemaEvolutionAction{SchemaEvolutionAdd, SchemaEvolutionIgnore, SchemaEvolutionFail}},
+		{"on_type_widen", se.OnTypeWiden, []SchemaEvolutionAction{SchemaEvolutionAlter, SchemaEvolutionIgnore, SchemaEvolutionFail}},
+		{"on_type_narrow", se.OnTypeNarrow, []SchemaEvolutionAction{SchemaEvolutionCast, SchemaEvolutionFail}},
+		{"on_column_drop", se.OnColumnDrop, []SchemaEvolutionAction{SchemaEvolutionIgnore, SchemaEvolutionFail}},
+	}
+	for _, check := range checks {
+		if check.value != "" && !g.In(check.value, check.allowed...) {
+			return g.Error("invalid schema_evolution.%s (%s), must be one of %s", check.key, check.value, g.Marshal(check.allowed))
+		}
+	}
+	return nil
+}
+
+// CommitsPeriodically returns whether the load commits into the final table
+// periodically (commit_every_rows / commit_every_seconds)
+func (o *TargetOptions) CommitsPeriodically() bool {
+	return g.PtrVal(o.CommitEveryRows) > 0 || g.PtrVal(o.CommitEverySeconds) > 0
+}
+
+// Evolution returns the schema evolution policy, with the empty actions
+// set from the add_new_columns and adjust_column_type options
+func (o *TargetOptions) Evolution() (se SchemaEvolution) {
+	if o.SchemaEvolution != nil {
+		se = *o.SchemaEvolution
+	}
+	if se.OnNewColumn == "" {
+		se.OnNewColumn = lo.Ternary(g.PtrVal(o.AddNewColumns), SchemaEvolutionAdd, SchemaEvolutionIgnore)
+	}
+	if se.OnTypeWiden == "" {
+		se.OnTypeWiden = lo.Ternary(g.PtrVal(o.AdjustColumnType), SchemaEvolutionAlter, SchemaEvolutionIgnore)
+	}
+	if se.OnTypeNarrow == "" {
+		se.OnTypeNarrow = SchemaEvolutionCast
+	}
+	if se.OnColumnDrop == "" {
+		se.OnColumnDrop = SchemaEvolutionIgnore
+	}
+	return se
+}
+
+// MetadataOptions are the options of the metadata columns (`target.options.metadata`),
+// taking precedence over the env vars
+type MetadataOptions struct {
+	LoadedAt           *bool  `json:"loaded_at,omitempty" yaml:"loaded_at,omitempty"`
+	LoadedAtColumnName string `json:"loaded_at_column_name,omitempty" yaml:"loaded_at_column_name,omitempty"`
+	LoadedAtType       string `json:"loaded_at_type,omitempty" yaml:"loaded_at_type,omitempty"` // `timestamp` or `unix` (default)
+
+	StreamURL           *bool  `json:"stream_url,omitempty" yaml:"stream_url,omitempty"`
+	StreamURLColumnName string `json:"stream_url_column_name,omitempty" yaml:"stream_url_column_name,omitempty"`
+	RowNum              *bool  `json:"row_num,omitempty" yaml:"row_num,omitempty"`
+	RowNumColumnName    string `json:"row_num_column_name,omitempty" yaml:"row_num_column_name,omitempty"`
+	RowID               *bool  `json:"row_id,omitempty" yaml:"row_id,omitempty"`
+	RowIDColumnName     string `json:"row_id_column_name,omitempty" yaml:"row_id_column_name,omitempty"`
+	ExecID              *bool  `json:"exec_id,omitempty" yaml:"exec_id,omitempty"`
+	ExecIDColumnName    string `json:"exec_id_column_name,omitempty" yaml:"exec_id_column_name,omitempty"`
+	RowHash             *bool  `json:"row_hash,omitempty" yaml:"row_hash,omitempty"`
+	RowHashColumnName   string `json:"row_hash_column_name,omitempty" yaml:"row_hash_column_name,omitempty"`
+	FileLine            *bool  `json:"file_line,omitempty" yaml:"file_line,omitempty"`
+	FileLineColumnName  string `json:"file_line_column_name,omitempty" yaml:"file_line_column_name,omitempty"`
+}
+
+// Validate checks the options
+func (mo *MetadataOptions) Validate() error {
+	if !g.In(strings.ToLower(mo.LoadedAtType), "", "timestamp", "unix") {
+		return g.Error("invalid metadata loaded_at_type (%s), must be timestamp or unix", mo.LoadedAtType)
+	}
+	return nil
+}
+
+// metadataColumnName returns the configured name of a metadata column, or the default name
+func metadataColumnName(name, defaultName string) string {
+	if strings.TrimSpace(name) == "" {
+		return defaultName
+	}
+	return strings.TrimSpace(name)
+}
+
+// loadedAtColumn returns the name of the loaded at metadata column
+func (cfg *Config) loadedAtColumn() string {
+	if cfg.Target.Options == nil || cfg.Target.Options.Metadata == nil {
+		return slingLoadedAtColumn
+	}
+	return metadataColumnName(cfg.Target.Options.Metadata.LoadedAtColumnName, slingLoadedAtColumn)
+}
+
+// DedupOptions are the options to deduplicate rows of an append-only incremental load
+type DedupOptions struct {
+	Keys    []string `json:"keys,omitempty" yaml:"keys,omitempty"`
+	OrderBy string   `json:"order_by,omitempty" yaml:"order_by,omitempty"` // e.g. `updated_at desc`, the first row is kept
+	Window  string   `json:"window,omitempty" yaml:"window,omitempty"`     // e.g. `7d`, target rows to check against (on the order_by column)
+}
+
+// OrderByColumns returns the order by columns and their direction
+func (do *DedupOptions) OrderByColumns() (columns []string, directions []string) {
+	for _, part := range strings.Split(do.OrderBy, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		direction := "asc"
+		if len(fields) > 1 && strings.EqualFold(fields[1], "desc") {
+			direction = "desc"
+		}
+		columns = append(columns, fields[0])
+		directions = append(directions, direction)
+	}
+	return
+}
+
+// WindowDuration returns the duration of the window. Accepts a `d` suffix for days.
+func (do *DedupOptions) WindowDuration() (duration time.Duration, err error) {
+	duration, err = parseDuration(do.Window)
+	if err != nil {
+		return 0, g.Error(err, "invalid dedup window: %s", do.Window)
+	}
+	return duration, nil
+}
+
+// parseDuration parses a duration, also accepting days (e.g. `7d`)
+func parseDuration(value string) (duration time.Duration, err error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	if strings.HasSuffix(value, "d") {
+		days, err := cast.ToIntE(strings.TrimSuffix(value, "d"))
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(value)
 }
 
 var SourceFileOptionsDefault = SourceOptions{
@@ -1445,9 +2101,45 @@ func (o *SourceOptions) SetDefaults(sourceOptions SourceOptions) {
 	if o.Sheet == nil {
 		o.Sheet = sourceOptions.Sheet
 	}
+	if o.LogParser == nil {
+		o.LogParser = sourceOptions.LogParser
+	}
 	if o.Range == nil {
 		o.Range = sourceOptions.Range
 	}
+	if o.Filter == nil {
+		o.Filter = sourceOptions.Filter
+	}
+	if o.ChunkSize == nil {
+		o.ChunkSize = sourceOptions.ChunkSize
+	}
+	if o.FetchSize == nil {
+		o.FetchSize = sourceOptions.FetchSize
+	}
+	if o.RateLimit == nil {
+		o.RateLimit = sourceOptions.RateLimit
+	}
+	if o.Concurrency == nil {
+		o.Concurrency = sourceOptions.Concurrency
+	}
+	if o.Ordered == nil {
+		o.Ordered = sourceOptions.Ordered
+	}
+	if o.FileIncremental == nil {
+		o.FileIncremental = sourceOptions.FileIncremental
+	}
+	if o.PostRead == nil {
+		o.PostRead = sourceOptions.PostRead
+	}
+	if o.Sample == nil {
+		o.Sample = sourceOptions.Sample
+	}
+	if o.Attach == nil {
+		o.Attach = sourceOptions.Attach
+	}
+	if o.ComputedColumns == nil {
+		o.ComputedColumns = sourceOptions.ComputedColumns
+	}
 	if o.DatetimeFormat == "" {
 		o.DatetimeFormat = sourceOptions.DatetimeFormat
 	}
@@ -1499,6 +2191,21 @@ func (o *TargetOptions) SetDefaults(targetOptions TargetOptions) {
 	if o.BatchLimit == nil {
 		o.BatchLimit = targetOptions.BatchLimit
 	}
+	if o.RateLimit == nil {
+		o.RateLimit = targetOptions.RateLimit
+	}
+	if o.Manifest == nil {
+		o.Manifest = targetOptions.Manifest
+	}
+	if o.Atomic == nil {
+		o.Atomic = targetOptions.Atomic
+	}
+	if o.ChecksumMetadata == nil {
+		o.ChecksumMetadata = targetOptions.ChecksumMetadata
+	}
+	if o.ContentAddressed == nil {
+		o.ContentAddressed = targetOptions.ContentAddressed
+	}
 	if o.FileMaxRows == nil {
 		o.FileMaxRows = targetOptions.FileMaxRows
 	}
@@ -1508,6 +2215,15 @@ func (o *TargetOptions) SetDefaults(targetOptions TargetOptions) {
 	if o.UseBulk == nil {
 		o.UseBulk = targetOptions.UseBulk
 	}
+	if o.DirectInsert == nil {
+		o.DirectInsert = targetOptions.DirectInsert
+	}
+	if o.CommitEveryRows == nil {
+		o.CommitEveryRows = targetOptions.CommitEveryRows
+	}
+	if o.CommitEverySeconds == nil {
+		o.CommitEverySeconds = targetOptions.CommitEverySeconds
+	}
 	if o.IgnoreExisting == nil {
 		o.IgnoreExisting = targetOptions.IgnoreExisting
 	}
@@ -1523,12 +2239,33 @@ func (o *TargetOptions) SetDefaults(targetOptions TargetOptions) {
 	if o.TableTmp == "" {
 		o.TableTmp = targetOptions.TableTmp
 	}
+	if o.KeepTmpTable == nil {
+		o.KeepTmpTable = targetOptions.KeepTmpTable
+	}
+	if o.FailureTable == "" {
+		o.FailureTable = targetOptions.FailureTable
+	}
 	if o.TableDDL == nil {
 		o.TableDDL = targetOptions.TableDDL
 	}
 	if o.AdjustColumnType == nil {
 		o.AdjustColumnType = targetOptions.AdjustColumnType
 	}
+	if o.AllowTypeNarrowing == nil {
+		o.AllowTypeNarrowing = targetOptions.AllowTypeNarrowing
+	}
+	if o.QueryTag == nil {
+		o.QueryTag = targetOptions.QueryTag
+	}
+	if o.PartitionType == "" {
+		o.PartitionType = targetOptions.PartitionType
+	}
+	if o.RequirePartitionFilter == nil {
+		o.RequirePartitionFilter = targetOptions.RequirePartitionFilter
+	}
+	if o.TableExpirationDays == nil {
+		o.TableExpirationDays = targetOptions.TableExpirationDays
+	}
 
 	if o.AddNewColumns == nil {
 		o.AddNewColumns = targetOptions.AddNewColumns
@@ -1545,6 +2282,39 @@ func (o *TargetOptions) SetDefaults(targetOptions TargetOptions) {
 	if o.ColumnCasing == nil {
 		o.ColumnCasing = targetOptions.ColumnCasing
 	}
+	if o.ColumnTags == nil {
+		o.ColumnTags = targetOptions.ColumnTags
+	}
+	if o.ColumnMapping == nil {
+		o.ColumnMapping = targetOptions.ColumnMapping
+	}
+	if o.ColumnMatch == nil {
+		o.ColumnMatch = targetOptions.ColumnMatch
+	}
+	if o.Shards == nil {
+		o.Shards = targetOptions.Shards
+	}
+	if o.ShardKey == nil {
+		o.ShardKey = targetOptions.ShardKey
+	}
+	if o.SurrogateKey == nil {
+		o.SurrogateKey = targetOptions.SurrogateKey
+	}
+	if o.SCD2 == nil {
+		o.SCD2 = targetOptions.SCD2
+	}
+	if o.Dedup == nil {
+		o.Dedup = targetOptions.Dedup
+	}
+	if o.Metadata == nil {
+		o.Metadata = targetOptions.Metadata
+	}
+	if o.SchemaEvolution == nil {
+		o.SchemaEvolution = targetOptions.SchemaEvolution
+	}
+	if o.Checks == nil {
+		o.Checks = targetOptions.Checks
+	}
 	if o.TableKeys == nil {
 		o.TableKeys = targetOptions.TableKeys
 		if o.TableKeys == nil {
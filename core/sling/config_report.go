@@ -0,0 +1,167 @@
+package sling
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/nqd/flat"
+)
+
+// the layers where a resolved config value can come from,
+// from highest to lowest precedence
+const (
+	ConfigSourceCLI         = "cli"
+	ConfigSourceStream      = "stream"
+	ConfigSourceReplication = "replication"
+	ConfigSourceConnection  = "connection"
+	ConfigSourceGlobal      = "global"
+	ConfigSourceEnv         = "env"
+	ConfigSourceDefault     = "default"
+)
+
+// ConfigValue is a resolved config value, with the layer it comes from
+type ConfigValue struct {
+	Key    string `json:"key"`
+	Value  any    `json:"value"`
+	Source string `json:"source"`
+}
+
+// ResolvedConfig returns the effective config values of each stream
+// of a compiled replication, with the source of each value
+func (rd *ReplicationConfig) ResolvedConfig(cfgOverwrite *Config) (resolved map[string][]ConfigValue) {
+	resolved = map[string][]ConfigValue{}
+
+	globalDefaults := flattenConfigMap(GlobalDefaults())
+	replicationDefaults := flattenConfigMap(rd.maps.Defaults)
+
+	cliValues := map[string]any{}
+	if cfgOverwrite != nil {
+		cliValues = flattenConfigMap(cfgOverwrite.streamMap())
+	}
+
+	for _, task := range rd.Tasks {
+		// copy options so that we don't mutate the task
+		cfg := *task
+		cfg.Source.Options, cfg.Target.Options = nil, nil
+		g.Unmarshal(g.Marshal(task.Source.Options), &cfg.Source.Options)
+		g.Unmarshal(g.Marshal(task.Target.Options), &cfg.Target.Options)
+		cfg.SetDefault()
+
+		streamValues := flattenConfigMap(rd.maps.Streams[task.StreamName])
+
+		connOptions := g.M()
+		srcOptions, tgtOptions := SourceOptions{}, TargetOptions{}
+		if cfg.getConnDefaultOptions(cfg.SrcConn, "source_options", &srcOptions) {
+			connOptions["source_options"] = srcOptions
+		}
+		if cfg.getConnDefaultOptions(cfg.TgtConn, "target_options", &tgtOptions) {
+			connOptions["target_options"] = tgtOptions
+		}
+		connValues := flattenConfigMap(connOptions)
+
+		values := []ConfigValue{}
+		for key, value := range flattenConfigMap(cfg.streamMap()) {
+			source := ConfigSourceDefault
+			for _, layer := range []struct {
+				name   string
+				values map[string]any
+			}{
+				{ConfigSourceCLI, cliValues},
+				{ConfigSourceStream, streamValues},
+				{ConfigSourceReplication, replicationDefaults},
+				{ConfigSourceConnection, connValues},
+				{ConfigSourceGlobal, globalDefaults},
+			} {
+				if _, ok := layer.values[key]; ok {
+					source = layer.name
+					break
+				}
+			}
+			values = append(values, ConfigValue{Key: key, Value: value, Source: source})
+		}
+
+		for key, value := range task.Env {
+			values = append(values, ConfigValue{Key: "env." + key, Value: value, Source: ConfigSourceEnv})
+		}
+
+		sort.Slice(values, func(i, j int) bool { return values[i].Key < values[j].Key })
+		resolved[task.StreamName] = values
+	}
+
+	return resolved
+}
+
+// ShowConfig returns a printable table of the effective config of
+// each stream, with the source of each value
+func (rd *ReplicationConfig) ShowConfig(cfgOverwrite *Config) string {
+	resolved := rd.ResolvedConfig(cfgOverwrite)
+
+	var output strings.Builder
+	for _, task := range rd.Tasks {
+		rows := [][]any{}
+		for _, cv := range resolved[task.StreamName] {
+			value, ok := cv.Value.(string)
+			if !ok {
+				value = g.Marshal(cv.Value)
+			}
+			rows = append(rows, []any{cv.Key, value, cv.Source})
+		}
+
+		output.WriteString(g.F("stream: %s\n", task.StreamName))
+		output.WriteString(g.PrettyTable([]string{"Key", "Value", "Source"}, rows))
+		output.WriteString("\n")
+	}
+
+	return output.String()
+}
+
+// streamMap returns the config as a replication stream map
+func (cfg *Config) streamMap() map[string]any {
+	return g.M(
+		"mode", cfg.Mode,
+		"object", cfg.Target.Object,
+		"select", cfg.Source.Select,
+		"where", cfg.Source.Where,
+		"primary_key", cfg.Source.PrimaryKeyI,
+		"update_key", cfg.Source.UpdateKey,
+		"sql", cfg.Source.Query,
+		"transforms", cfg.Transforms,
+		"columns", cfg.Target.Columns,
+		"source_options", cfg.Source.Options,
+		"target_options", cfg.Target.Options,
+	)
+}
+
+// flattenConfigMap flattens a config into dot-delimited keys, dropping empty values
+func flattenConfigMap(value any) map[string]any {
+	m := g.M()
+	g.Unmarshal(g.Marshal(value), &m)
+
+	flattened, err := flat.Flatten(m, &flat.Options{Delimiter: ".", Safe: true})
+	if err != nil {
+		g.Warn("could not flatten config: %s", err.Error())
+		return g.M()
+	}
+
+	for k, v := range flattened {
+		switch val := v.(type) {
+		case nil:
+			delete(flattened, k)
+		case string:
+			if val == "" {
+				delete(flattened, k)
+			}
+		case []any:
+			if len(val) == 0 {
+				delete(flattened, k)
+			}
+		case map[string]any:
+			if len(val) == 0 {
+				delete(flattened, k)
+			}
+		}
+	}
+
+	return flattened
+}
@@ -0,0 +1,92 @@
+package sling
+
+import (
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio/filesys"
+)
+
+// FileIncrementalMode is how new files are detected (`source.options.file_incremental`)
+type FileIncrementalMode string
+
+const (
+	FileIncrementalModified FileIncrementalMode = "modified" // new files, or with a changed modified time or etag
+	FileIncrementalName     FileIncrementalMode = "name"     // files with a path after the last loaded path
+)
+
+// FileMark is the high-water mark of a loaded file
+type FileMark struct {
+	Updated int64  `json:"updated"`
+	ETag    string `json:"etag,omitempty"`
+}
+
+// FileState is the state of the loaded files of a stream
+type FileState struct {
+	Name  string              `json:"name,omitempty"`  // last loaded path (name mode)
+	Files map[string]FileMark `json:"files,omitempty"` // loaded files (modified mode)
+}
+
+// GetFileState and SetFileState read and write the state of the loaded
+// files of a stream, from / into the sling state
+var (
+	GetFileState = func(cfg *Config) (state FileState, err error) {
+		g.Warn("use the official release of sling-cli to use file_incremental")
+		return FileState{}, nil
+	}
+
+	SetFileState = func(cfg *Config, state FileState) (err error) {
+		return nil
+	}
+)
+
+// selectIncrementalFiles returns the selection of the files which are new
+// since the last run, and keeps the state to save once they are loaded
+func (t *TaskExecution) selectIncrementalFiles(fs filesys.FileSysClient, uri string, fileSelect *[]string) (*[]string, error) {
+	mode := FileIncrementalMode(strings.ToLower(*t.Config.Source.Options.FileIncremental))
+
+	nodes, err := fs.ListRecursive(uri)
+	if err != nil {
+		return nil, g.Error(err, "could not list %s", uri)
+	}
+
+	state, err := GetFileState(t.Config)
+	if err != nil {
+		return nil, g.Error(err, "could not get file state")
+	}
+
+	selected := []string{}
+	newState := FileState{Name: state.Name, Files: map[string]FileMark{}}
+	for _, node := range nodes.Files().Selected(fileSelect) {
+		switch mode {
+		case FileIncrementalName:
+			if path := node.Path(); path > state.Name {
+				selected = append(selected, node.URI)
+				newState.Name = max(newState.Name, path)
+			}
+		case FileIncrementalModified:
+			mark := FileMark{Updated: node.Updated, ETag: node.ETag}
+			if prev, ok := state.Files[node.URI]; !ok || prev != mark {
+				selected = append(selected, node.URI)
+			}
+			newState.Files[node.URI] = mark
+		}
+	}
+
+	g.Debug("file_incremental=%s selected %d new files of %d", mode, len(selected), len(nodes.Files()))
+	t.fileState = &newState
+
+	return &selected, nil
+}
+
+// saveFileState saves the state of the loaded files, if file_incremental
+func (t *TaskExecution) saveFileState() (err error) {
+	if t.fileState == nil {
+		return nil
+	}
+
+	if err = SetFileState(t.Config, *t.fileState); err != nil {
+		return g.Error(err, "could not save file state")
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package sling
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileIncremental(t *testing.T) {
+	t.Setenv("SLING_DUCKDB_COMPUTE", "false")
+	t.Setenv("SLING_FILE_PASSTHROUGH", "false")
+
+	// in-memory file state
+	var saved FileState
+	getFileState, setFileState := GetFileState, SetFileState
+	GetFileState = func(cfg *Config) (FileState, error) { return saved, nil }
+	SetFileState = func(cfg *Config, state FileState) error { saved = state; return nil }
+	defer func() { GetFileState, SetFileState = getFileState, setFileState }()
+
+	folder := t.TempDir()
+	os.MkdirAll(path.Join(folder, "files"), 0755)
+	writeFile := func(name, content string) {
+		os.WriteFile(path.Join(folder, "files", name), []byte(content), 0644)
+	}
+
+	run := func(mode string) (*TaskExecution, error) {
+		cfg := localFileConfig(folder, "files/", "out.csv")
+		cfg.Source.Options = &SourceOptions{FileIncremental: g.String(mode)}
+		return runTestTask(cfg)
+	}
+
+	writeFile("2024-01-01.csv", "id\n1\n2\n")
+	writeFile("2024-01-02.csv", "id\n3\n")
+
+	for _, mode := range []string{"name", "modified"} {
+		saved = FileState{}
+		task, err := run(mode)
+		if assert.NoError(t, err, mode) {
+			assert.EqualValues(t, 3, task.GetCount(), mode)
+		}
+
+		// no new files
+		task, err = run(mode)
+		if assert.NoError(t, err, mode) {
+			assert.EqualValues(t, 0, task.GetCount(), mode)
+		}
+	}
+	assert.Len(t, saved.Files, 2)
+
+	// a new file, and a changed file (modified mode only)
+	writeFile("2024-01-03.csv", "id\n4\n5\n")
+	writeFile("2024-01-01.csv", "id\n1\n2\n6\n")
+	os.Chtimes(path.Join(folder, "files", "2024-01-01.csv"), time.Now(), time.Now().Add(time.Hour))
+
+	task, err := run("modified")
+	if assert.NoError(t, err) {
+		assert.EqualValues(t, 5, task.GetCount())
+		data, _ := os.ReadFile(path.Join(folder, "out.csv"))
+		assert.NotContains(t, string(data), "\n3\n")
+	}
+
+	saved = FileState{Name: path.Join(folder, "files", "2024-01-02.csv")}
+	task, err = run("name")
+	if assert.NoError(t, err) {
+		assert.EqualValues(t, 2, task.GetCount())
+		assert.Equal(t, path.Join(folder, "files", "2024-01-03.csv"), saved.Name)
+	}
+
+	// invalid mode
+	_, err = run("size")
+	assert.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package sling
+
+import (
+	"os"
+	"path"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+)
+
+// Fixtures are source streams recorded into a folder (env var `SLING_RECORD`),
+// one parquet file per stream, to be replayed later instead of reading from the
+// source (env var `SLING_REPLAY`). This allows deterministic tests of configs and
+// downstream logic, without live source access.
+
+// fixtureFolders returns the record and replay folders
+func fixtureFolders() (record, replay string) {
+	return os.Getenv("SLING_RECORD"), os.Getenv("SLING_REPLAY")
+}
+
+// IsReplaying returns true if the source streams are replayed from fixtures
+func IsReplaying() bool {
+	_, replay := fixtureFolders()
+	return replay != ""
+}
+
+// fixturePath returns the fixture file path of the stream
+func fixturePath(folder string, cfg *Config) string {
+	return path.Join(folder, iop.CleanName(cfg.StreamLabel())+".parquet")
+}
+
+// replaySource reads the stream from its fixture, if replaying
+func (t *TaskExecution) replaySource(cfg *Config) (df *iop.Dataflow, ok bool, err error) {
+	_, replay := fixtureFolders()
+	if replay == "" {
+		return nil, false, nil
+	}
+
+	filePath := fixturePath(replay, cfg)
+	if !g.PathExists(filePath) {
+		return nil, false, g.Error("no fixture found for stream %s (%s)", cfg.StreamLabel(), filePath)
+	}
+
+	g.Info("replaying %s from fixture (%s)", cfg.StreamLabel(), filePath)
+	df, err = t.readLocalParquet(filePath)
+	if err != nil {
+		return nil, false, g.Error(err, "could not replay fixture")
+	}
+	return df, true, nil
+}
+
+// recordSource writes the stream into its fixture, if recording,
+// and returns the dataflow reading from the fixture
+func (t *TaskExecution) recordSource(cfg *Config, srcDf *iop.Dataflow) (df *iop.Dataflow, err error) {
+	record, _ := fixtureFolders()
+	if record == "" {
+		return srcDf, nil
+	}
+
+	filePath := fixturePath(record, cfg)
+	g.Info("recording %s into fixture (%s)", cfg.StreamLabel(), filePath)
+	if err = writeLocalParquet(srcDf, filePath); err != nil {
+		return nil, g.Error(err, "could not record fixture")
+	}
+
+	return t.readLocalParquet(filePath)
+}
@@ -0,0 +1,53 @@
+package sling
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordReplay(t *testing.T) {
+	os.Setenv("SLING_DUCKDB_COMPUTE", "false")
+	defer os.Unsetenv("SLING_DUCKDB_COMPUTE")
+
+	fixtureDir := t.TempDir()
+	dbConn := initTestSqlite(t, "SLING_FIXTURE_TEST_DB", `create table orders (id integer, name text); insert into orders values (1, 'a'), (2, 'b')`)
+
+	run := func() (count uint64, err error) {
+		_, err = runTestTask(&Config{
+			Source: Source{Conn: "SLING_FIXTURE_TEST_DB", Stream: "main.orders"},
+			Target: Target{Conn: "SLING_FIXTURE_TEST_DB", Object: "main.orders_copy"},
+			Mode:   FullRefreshMode,
+		})
+		if err != nil {
+			return
+		}
+		return dbConn.GetCount("main.orders_copy")
+	}
+
+	os.Setenv("SLING_RECORD", fixtureDir)
+	count, err := run()
+	os.Unsetenv("SLING_RECORD")
+	if assert.NoError(t, err) {
+		assert.EqualValues(t, 2, count)
+	}
+	files, _ := os.ReadDir(fixtureDir)
+	assert.Len(t, files, 1)
+
+	// the source is not read when replaying
+	_, err = dbConn.Exec(`drop table orders`)
+	assert.NoError(t, err)
+
+	os.Setenv("SLING_REPLAY", fixtureDir)
+	defer os.Unsetenv("SLING_REPLAY")
+	count, err = run()
+	if assert.NoError(t, err) {
+		assert.EqualValues(t, 2, count)
+	}
+
+	// missing fixture
+	os.Setenv("SLING_REPLAY", t.TempDir())
+	_, err = run()
+	assert.Error(t, err)
+}
@@ -1,6 +1,22 @@
 package sling
 
-import "github.com/flarco/g"
+import (
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/nqd/flat"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/database"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+)
 
 type HookType string
 
@@ -36,7 +52,8 @@ const (
 	HookStageEnd   HookStage = "end"
 )
 
-var ParseHook = func(any, ParseOptions) (Hook, error) { return nil, nil }
+// ParseHook parses a hook config, the built-in types by default
+var ParseHook = parseBuiltinHook
 
 func (hs Hooks) Execute() (err error) {
 	for _, hook := range hs {
@@ -53,3 +70,375 @@ func (hs Hooks) Execute() (err error) {
 	}
 	return nil
 }
+
+const (
+	HookTypeSQL     HookType = "sql"
+	HookTypeHTTP    HookType = "http"
+	HookTypeCommand HookType = "command"
+	HookTypeCheck   HookType = "check"
+	HookTypeDBT     HookType = "dbt"
+)
+
+// HookConfig is the config of a built-in hook. Values are rendered
+// with the runtime state and the stream format map, e.g. `{object_name}`
+type HookConfig struct {
+	Type      HookType          `json:"type" yaml:"type"`
+	ID        string            `json:"id,omitempty" yaml:"id,omitempty"`
+	OnFailure string            `json:"on_failure,omitempty" yaml:"on_failure,omitempty"`           // abort (default) or warn
+	Conn      string            `json:"connection,omitempty" yaml:"connection,omitempty"`           // sql & check, defaults to the target connection
+	Query     string            `json:"query,omitempty" yaml:"query,omitempty"`                     // sql & check
+	Message   string            `json:"message,omitempty" yaml:"message,omitempty"`                 // check
+	URL       string            `json:"url,omitempty" yaml:"url,omitempty"`                         // http
+	Method    string            `json:"method,omitempty" yaml:"method,omitempty"`                   // http
+	Headers   map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`                 // http
+	Payload   string            `json:"payload,omitempty" yaml:"payload,omitempty"`                 // http
+	RootCert  string            `json:"ssl_root_cert,omitempty" yaml:"ssl_root_cert,omitempty"`     // http
+	SkipTLS   bool              `json:"tls_skip_verify,omitempty" yaml:"tls_skip_verify,omitempty"` // http
+	Command   string            `json:"command,omitempty" yaml:"command,omitempty"`                 // command
+
+	// dbt, runs the models locally with the dbt CLI, or triggers a dbt Cloud job (if job_id is set)
+	Streams     []string `json:"streams,omitempty" yaml:"streams,omitempty"` // streams which must have succeeded
+	Select      []string `json:"select,omitempty" yaml:"select,omitempty"`
+	DbtCommand  string   `json:"dbt_command,omitempty" yaml:"dbt_command,omitempty"` // run (default), build, test...
+	ProjectDir  string   `json:"project_dir,omitempty" yaml:"project_dir,omitempty"`
+	ProfilesDir string   `json:"profiles_dir,omitempty" yaml:"profiles_dir,omitempty"`
+	Target      string   `json:"target,omitempty" yaml:"target,omitempty"`
+	AccountID   string   `json:"account_id,omitempty" yaml:"account_id,omitempty"`
+	JobID       string   `json:"job_id,omitempty" yaml:"job_id,omitempty"`
+	Token       string   `json:"token,omitempty" yaml:"token,omitempty"`
+}
+
+// builtinHook executes a HookConfig
+type builtinHook struct {
+	cfg   HookConfig
+	stage HookStage
+	state *RuntimeState
+	task  *TaskExecution
+}
+
+// parseBuiltinHook parses a built-in hook from its config map
+func parseBuiltinHook(raw any, opts ParseOptions) (Hook, error) {
+	if raw == nil {
+		return nil, nil
+	}
+
+	hook := &builtinHook{stage: opts.stage, state: opts.state}
+	if err := g.Unmarshal(g.Marshal(raw), &hook.cfg); err != nil {
+		return nil, g.Error(err, "could not parse hook config")
+	}
+
+	hook.cfg.Type = HookType(strings.ToLower(string(hook.cfg.Type)))
+	if hook.cfg.ID == "" {
+		hook.cfg.ID = g.F("%s_%d", opts.stage, opts.index)
+	}
+	if hook.cfg.OnFailure == "" {
+		hook.cfg.OnFailure = "abort"
+	} else if !g.In(hook.cfg.OnFailure, "abort", "warn") {
+		return nil, g.Error("invalid on_failure value for hook %s: %s", hook.cfg.ID, hook.cfg.OnFailure)
+	}
+
+	switch hook.cfg.Type {
+	case HookTypeSQL, HookTypeCheck:
+		if strings.TrimSpace(hook.cfg.Query) == "" {
+			return nil, g.Error("no query provided for %s hook %s", hook.cfg.Type, hook.cfg.ID)
+		}
+	case HookTypeHTTP:
+		if hook.cfg.URL == "" {
+			return nil, g.Error("no url provided for http hook %s", hook.cfg.ID)
+		}
+	case HookTypeCommand:
+		if strings.TrimSpace(hook.cfg.Command) == "" {
+			return nil, g.Error("no command provided for command hook %s", hook.cfg.ID)
+		}
+	case HookTypeDBT:
+		if hook.cfg.JobID != "" && (hook.cfg.AccountID == "" || hook.cfg.Token == "") {
+			return nil, g.Error("account_id and token are required for dbt cloud hook %s", hook.cfg.ID)
+		}
+	default:
+		return nil, g.Error("unsupported hook type for hook %s: %s", hook.cfg.ID, hook.cfg.Type)
+	}
+
+	// the active task, for stream hooks
+	if g.In(opts.stage, HookStagePre, HookStagePost) && opts.state != nil && opts.state.Run != nil {
+		hook.task = opts.state.Run.Task
+	}
+
+	return hook, nil
+}
+
+func (h *builtinHook) Type() HookType   { return h.cfg.Type }
+func (h *builtinHook) ID() string       { return h.cfg.ID }
+func (h *builtinHook) Stage() HookStage { return h.stage }
+
+func (h *builtinHook) Execute() (err error) {
+	switch h.cfg.Type {
+	case HookTypeSQL:
+		_, err = h.query()
+	case HookTypeCheck:
+		err = h.check()
+	case HookTypeHTTP:
+		err = h.request()
+	case HookTypeCommand:
+		err = h.command()
+	case HookTypeDBT:
+		err = h.dbt()
+	}
+	return err
+}
+
+// ExecuteOnDone records the hook status in the runtime state,
+// and ignores the error if on_failure is `warn`
+func (h *builtinHook) ExecuteOnDone(err error) error {
+	status := g.M("status", "success")
+	if err != nil {
+		status = g.M("status", "error", "error", err.Error())
+	}
+
+	if h.state != nil {
+		if h.state.Hooks == nil {
+			h.state.Hooks = map[string]map[string]any{}
+		}
+		h.state.Hooks[h.cfg.ID] = status
+	}
+
+	if err != nil && h.cfg.OnFailure == "warn" {
+		g.Warn("%s hook %s failed: %s", h.cfg.Type, h.cfg.ID, err.Error())
+		return nil
+	}
+	return err
+}
+
+// render renders the template variables of a hook value
+func (h *builtinHook) render(text string) string {
+	values := g.M()
+	if h.state != nil {
+		values, _ = flat.Flatten(g.ToMap(h.state), &flat.Options{Delimiter: ".", Safe: true})
+	}
+	if h.task != nil && h.task.Config != nil {
+		for k, v := range h.task.GetStateMap() {
+			values[k] = v
+		}
+	}
+	return g.Rm(text, values)
+}
+
+// conn returns a connected database connection, the target's by default
+func (h *builtinHook) conn() (conn database.Connection, err error) {
+	if h.cfg.Conn != "" {
+		return getVariableConn(h.render(h.cfg.Conn))
+	} else if h.task == nil || h.task.Config == nil {
+		return nil, g.Error("connection is required for %s hook %s", h.cfg.Type, h.cfg.ID)
+	} else if !h.task.Config.TgtConn.Type.IsDb() {
+		return nil, g.Error("target connection is not a database, connection is required for %s hook %s", h.cfg.Type, h.cfg.ID)
+	}
+
+	conn, err = h.task.Config.TgtConn.AsDatabase(true)
+	if err != nil {
+		return nil, g.Error(err, "could not init target connection")
+	} else if err = conn.Connect(); err != nil {
+		return nil, g.Error(err, "could not connect to target")
+	}
+	return conn, nil
+}
+
+func (h *builtinHook) query() (data iop.Dataset, err error) {
+	conn, err := h.conn()
+	if err != nil {
+		return data, err
+	}
+	defer conn.Close()
+
+	queries := database.ParseSQLMultiStatements(h.render(h.cfg.Query), conn.GetType())
+	for i, sql := range queries {
+		if i == len(queries)-1 {
+			if data, err = conn.Query(sql); err != nil {
+				return data, g.Error(err, "could not execute query of hook %s", h.cfg.ID)
+			}
+		} else if _, err = conn.Exec(sql); err != nil {
+			return data, g.Error(err, "could not execute query of hook %s", h.cfg.ID)
+		}
+	}
+	return data, nil
+}
+
+// check fails if the first value returned by the query is not truthy,
+// e.g. `select count(*) = 0 from my_table where id is null`
+func (h *builtinHook) check() (err error) {
+	data, err := h.query()
+	if err != nil {
+		return err
+	}
+
+	var value any
+	if len(data.Rows) > 0 && len(data.Rows[0]) > 0 {
+		value = data.Rows[0][0]
+	}
+
+	if passed, _ := cast.ToBoolE(value); passed {
+		return nil
+	}
+
+	message := lo.Ternary(h.cfg.Message != "", h.render(h.cfg.Message), "check failed")
+	return g.Error("%s (hook %s returned %#v)", message, h.cfg.ID, value)
+}
+
+func (h *builtinHook) request() (err error) {
+	method := strings.ToUpper(h.cfg.Method)
+	if method == "" {
+		method = lo.Ternary(h.cfg.Payload != "", "POST", "GET")
+	}
+
+	req, err := http.NewRequest(method, h.render(h.cfg.URL), strings.NewReader(h.render(h.cfg.Payload)))
+	if err != nil {
+		return g.Error(err, "could not create request of hook %s", h.cfg.ID)
+	}
+	for k, v := range h.cfg.Headers {
+		req.Header.Set(k, h.render(v))
+	}
+
+	tlsConfig, err := dbio.TLSConfig(h.render(h.cfg.RootCert), h.cfg.SkipTLS)
+	if err != nil {
+		return g.Error(err, "could not make tls config of hook %s", h.cfg.ID)
+	}
+
+	client := dbio.HTTPClient(tlsConfig, nil)
+	client.Timeout = 30 * time.Second
+	resp, err := client.Do(req)
+	if err != nil {
+		return g.Error(err, "could not execute request of hook %s", h.cfg.ID)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return g.Error("request of hook %s returned status %d: %s", h.cfg.ID, resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+func (h *builtinHook) command() (err error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", h.render(h.cfg.Command))
+	} else {
+		cmd = exec.Command("sh", "-c", h.render(h.cfg.Command))
+	}
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return g.Error(err, "command of hook %s failed: %s", h.cfg.ID, strings.TrimSpace(string(out)))
+	}
+	g.Debug("command of hook %s output: %s", h.cfg.ID, strings.TrimSpace(string(out)))
+	return nil
+}
+
+// streamsSucceeded returns false if the stream of the hook failed,
+// or if any of the specified streams did not succeed
+func (h *builtinHook) streamsSucceeded() (ok bool, reason string) {
+	if h.task != nil && h.task.Err != nil {
+		return false, "stream failed"
+	}
+
+	for _, stream := range h.cfg.Streams {
+		var run *RunState
+		if h.state != nil {
+			for key, r := range h.state.Runs {
+				if strings.EqualFold(key, stream) || (r.Stream != nil && strings.EqualFold(r.Stream.Name, stream)) {
+					run = r
+					break
+				}
+			}
+		}
+
+		if run == nil {
+			return false, g.F("stream %s did not run", stream)
+		} else if run.Status != ExecStatusSuccess {
+			return false, g.F("stream %s did not succeed (status: %s)", stream, run.Status)
+		}
+	}
+
+	return true, ""
+}
+
+// dbtArgs returns the arguments of the dbt CLI command
+func (h *builtinHook) dbtArgs() (args []string) {
+	args = []string{lo.Ternary(h.cfg.DbtCommand != "", h.render(h.cfg.DbtCommand), "run")}
+	if len(h.cfg.Select) > 0 {
+		args = append(args, "--select")
+		for _, model := range h.cfg.Select {
+			args = append(args, h.render(model))
+		}
+	}
+	if h.cfg.ProjectDir != "" {
+		args = append(args, "--project-dir", h.render(h.cfg.ProjectDir))
+	}
+	if h.cfg.ProfilesDir != "" {
+		args = append(args, "--profiles-dir", h.render(h.cfg.ProfilesDir))
+	}
+	if h.cfg.Target != "" {
+		args = append(args, "--target", h.render(h.cfg.Target))
+	}
+	return args
+}
+
+// dbt runs the dbt models, after the streams succeeded
+func (h *builtinHook) dbt() (err error) {
+	if ok, reason := h.streamsSucceeded(); !ok {
+		g.Debug("skipping dbt hook %s: %s", h.cfg.ID, reason)
+		return nil
+	}
+
+	args := h.dbtArgs()
+	if h.cfg.JobID != "" {
+		return h.dbtCloud(args)
+	}
+
+	dbtPath := lo.Ternary(os.Getenv("DBT_PATH") != "", os.Getenv("DBT_PATH"), "dbt")
+	g.Info("executing dbt hook %s: dbt %s", h.cfg.ID, strings.Join(args, " "))
+
+	out, err := exec.Command(dbtPath, args...).CombinedOutput()
+	if err != nil {
+		return g.Error(err, "dbt command of hook %s failed: %s", h.cfg.ID, strings.TrimSpace(string(out)))
+	}
+	g.Debug("dbt output of hook %s: %s", h.cfg.ID, strings.TrimSpace(string(out)))
+	return nil
+}
+
+// dbtCloud triggers the dbt Cloud job, overriding its steps if models are selected
+func (h *builtinHook) dbtCloud(args []string) (err error) {
+	baseURL := lo.Ternary(h.cfg.URL != "", h.render(h.cfg.URL), "https://cloud.getdbt.com")
+	URL := g.F("%s/api/v2/accounts/%s/jobs/%s/run/", strings.TrimSuffix(baseURL, "/"), h.render(h.cfg.AccountID), h.render(h.cfg.JobID))
+
+	payload := g.M("cause", g.F("triggered by sling (hook %s)", h.cfg.ID))
+	if len(h.cfg.Select) > 0 {
+		payload["steps_override"] = []string{"dbt " + strings.Join(args, " ")}
+	}
+
+	req, err := http.NewRequest("POST", URL, strings.NewReader(g.Marshal(payload)))
+	if err != nil {
+		return g.Error(err, "could not create dbt cloud request of hook %s", h.cfg.ID)
+	}
+	req.Header.Set("Authorization", "Token "+h.render(h.cfg.Token))
+	req.Header.Set("Content-Type", "application/json")
+
+	tlsConfig, err := dbio.TLSConfig(h.render(h.cfg.RootCert), h.cfg.SkipTLS)
+	if err != nil {
+		return g.Error(err, "could not make tls config of hook %s", h.cfg.ID)
+	}
+
+	client := dbio.HTTPClient(tlsConfig, nil)
+	client.Timeout = 30 * time.Second
+	resp, err := client.Do(req)
+	if err != nil {
+		return g.Error(err, "could not trigger dbt cloud job of hook %s", h.cfg.ID)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return g.Error("dbt cloud job of hook %s returned status %d: %s", h.cfg.ID, resp.StatusCode, string(body))
+	}
+
+	g.Info("triggered dbt cloud job %s (hook %s)", h.cfg.JobID, h.cfg.ID)
+	return nil
+}
@@ -0,0 +1,185 @@
+package sling
+
+import (
+	"bytes"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/spf13/cast"
+)
+
+type NotificationType string
+
+const (
+	NotificationTypeSlack   NotificationType = "slack"
+	NotificationTypeEmail   NotificationType = "email"
+	NotificationTypeWebhook NotificationType = "webhook"
+)
+
+type NotificationEvent string
+
+const (
+	NotificationEventFailure NotificationEvent = "failure"
+	NotificationEventSuccess NotificationEvent = "success"
+	NotificationEventEmpty   NotificationEvent = "empty_stream"
+)
+
+// Notification is sent at the end of a stream run, for the subscribed events.
+// The message and payload are rendered with the run statistics, e.g. `{rows_written}`
+type Notification struct {
+	Type    NotificationType    `json:"type" yaml:"type"`
+	Events  []NotificationEvent `json:"events,omitempty" yaml:"events,omitempty"`   // defaults to failure
+	URL     string              `json:"url,omitempty" yaml:"url,omitempty"`         // slack & webhook
+	Headers map[string]string   `json:"headers,omitempty" yaml:"headers,omitempty"` // webhook
+	Payload string              `json:"payload,omitempty" yaml:"payload,omitempty"` // webhook, defaults to the message and statistics as JSON
+	To      []string            `json:"to,omitempty" yaml:"to,omitempty"`           // email, via the SMTP_* env vars
+	Subject string              `json:"subject,omitempty" yaml:"subject,omitempty"` // email
+	Message string              `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+const defaultNotificationMessage = "Sling stream {stream_name} {event} | {source_name} -> {target_name} | {rows_written} rows in {duration} seconds {error}"
+
+// Validate checks the notification config
+func (n *Notification) Validate() error {
+	n.Type = NotificationType(strings.ToLower(string(n.Type)))
+	switch n.Type {
+	case NotificationTypeSlack, NotificationTypeWebhook:
+		if n.URL == "" {
+			return g.Error("no url provided for %s notification", n.Type)
+		}
+	case NotificationTypeEmail:
+		if len(n.To) == 0 {
+			return g.Error("no recipients (to) provided for email notification")
+		}
+	default:
+		return g.Error("unsupported notification type: %s", n.Type)
+	}
+
+	for _, event := range n.Events {
+		if !g.In(event, NotificationEventFailure, NotificationEventSuccess, NotificationEventEmpty) {
+			return g.Error("invalid event for %s notification: %s (expected failure, success or empty_stream)", n.Type, event)
+		}
+	}
+	return nil
+}
+
+// notificationEvents returns the events of a finished task
+func (t *TaskExecution) notificationEvents() (events []NotificationEvent) {
+	if t.Err != nil {
+		return []NotificationEvent{NotificationEventFailure}
+	}
+
+	events = []NotificationEvent{NotificationEventSuccess}
+	if t.df != nil && t.GetCount() == 0 {
+		events = append(events, NotificationEventEmpty)
+	}
+	return events
+}
+
+// sendNotifications sends the replication notifications subscribed to the
+// events of the finished task. Failing to notify does not fail the task
+func (t *TaskExecution) sendNotifications() {
+	if t.Replication == nil || len(t.Replication.Notifications) == 0 {
+		return
+	}
+
+	events := t.notificationEvents()
+	values := t.GetStateMap()
+	values["event"] = string(events[0])
+	values["error"] = ""
+	values["duration"] = "0"
+	if _, ok := values["stream_name"]; !ok {
+		values["stream_name"] = t.Config.StreamLabel()
+	}
+	if t.Err != nil {
+		values["error"] = "| error: " + strings.Split(g.ErrMsg(t.Err), "\n")[0]
+	}
+	if t.StartTime != nil && t.EndTime != nil {
+		values["duration"] = cast.ToString(t.EndTime.Sub(*t.StartTime).Round(time.Millisecond).Seconds())
+	}
+
+	for _, notification := range t.Replication.Notifications {
+		subscribed := lo.Ternary(len(notification.Events) > 0, notification.Events, []NotificationEvent{NotificationEventFailure})
+		if len(lo.Intersect(subscribed, events)) == 0 {
+			continue
+		}
+
+		if err := notification.send(values); err != nil {
+			g.Warn("could not send %s notification: %s", notification.Type, err.Error())
+		} else {
+			g.Debug("sent %s notification (%s)", notification.Type, values["event"])
+		}
+	}
+}
+
+// send sends the notification, rendered with the values
+func (n Notification) send(values map[string]any) (err error) {
+	message := strings.TrimSpace(g.Rm(lo.Ternary(n.Message != "", n.Message, defaultNotificationMessage), values))
+
+	switch n.Type {
+	case NotificationTypeSlack:
+		return postNotification(n.URL, nil, g.Marshal(g.M("text", message)))
+	case NotificationTypeWebhook:
+		stats := lo.PickByKeys(values, []string{
+			"exec_id", "event", "status", "error", "stream_name", "source_name", "target_name",
+			"object_name", "start_time", "duration", "rows_written", "bytes_written",
+		})
+		payload := g.Marshal(g.M("message", message, "stats", stats))
+		if n.Payload != "" {
+			payload = g.Rm(n.Payload, values)
+		}
+		return postNotification(g.Rm(n.URL, values), n.Headers, payload)
+	case NotificationTypeEmail:
+		subject := lo.Ternary(n.Subject != "", g.Rm(n.Subject, values), g.F("Sling stream %s %s", values["stream_name"], values["event"]))
+		return sendNotificationEmail(n.To, subject, message)
+	}
+	return g.Error("unsupported notification type: %s", n.Type)
+}
+
+func postNotification(url string, headers map[string]string, payload string) (err error) {
+	req, err := http.NewRequest("POST", url, bytes.NewBufferString(payload))
+	if err != nil {
+		return g.Error(err, "could not create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return g.Error(err, "could not send request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return g.Error("request returned status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// sendNotificationEmail sends an email with the SMTP_* env vars
+func sendNotificationEmail(to []string, subject, message string) (err error) {
+	if os.Getenv("SMTP_HOST") == "" {
+		return g.Error("SMTP_HOST is not set")
+	}
+
+	g.SMTPServer = os.Getenv("SMTP_HOST")
+	g.SMTPPort = lo.Ternary(os.Getenv("SMTP_PORT") != "", cast.ToInt(os.Getenv("SMTP_PORT")), 465)
+	g.SMTPUser = os.Getenv("SMTP_USERNAME")
+	g.SMTPPass = os.Getenv("SMTP_PASSWORD")
+	from := lo.Ternary(os.Getenv("SMTP_FROM_EMAIL") != "", os.Getenv("SMTP_FROM_EMAIL"), g.SMTPUser)
+
+	if err = g.SendMail(from, to, subject, "<pre>"+html.EscapeString(message)+"</pre>"); err != nil {
+		return g.Error(err, "could not send email")
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package sling
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/flarco/g"
+)
+
+// ProgressServer serves the live progress of the task runs over a local HTTP
+// endpoint, as JSON (`GET /progress`) and as Server-Sent Events (`GET /progress/events`),
+// so that UIs and wrappers do not need to parse the progress bar output.
+type ProgressServer struct {
+	Port     int           // 0 means a random port
+	Interval time.Duration // the interval between events
+
+	server    *http.Server
+	streams   []string                  // the planned streams, in order
+	tasks     map[string]*TaskExecution // the tracked tasks, by stream
+	startTime time.Time
+	done      chan struct{}
+	mux       sync.Mutex
+}
+
+// Progress is the progress of a run
+type Progress struct {
+	Status  ExecStatus       `json:"status"`
+	Elapsed float64          `json:"elapsed"`       // in seconds
+	ETA     *float64         `json:"eta,omitempty"` // in seconds, when it can be estimated
+	Streams []StreamProgress `json:"streams"`
+}
+
+// StreamProgress is the progress of a stream
+type StreamProgress struct {
+	Stream   string     `json:"stream"`
+	Status   ExecStatus `json:"status"`
+	Progress string     `json:"progress,omitempty"`
+	Rows     uint64     `json:"rows"`
+	Bytes    uint64     `json:"bytes"`
+	RowRate  int64      `json:"row_rate"`  // rows per second
+	ByteRate int64      `json:"byte_rate"` // bytes per second
+	Elapsed  float64    `json:"elapsed"`   // in seconds
+	ETA      *float64   `json:"eta,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// NewProgressServer creates a new progress server
+func NewProgressServer(port int) *ProgressServer {
+	return &ProgressServer{
+		Port:     port,
+		Interval: time.Second,
+		tasks:    map[string]*TaskExecution{},
+		done:     make(chan struct{}),
+	}
+}
+
+// Start listens on the local port, and serves in the background
+func (ps *ProgressServer) Start() (err error) {
+	listener, err := net.Listen("tcp", g.F("127.0.0.1:%d", ps.Port))
+	if err != nil {
+		return g.Error(err, "could not listen on port %d", ps.Port)
+	}
+	ps.Port = listener.Addr().(*net.TCPAddr).Port
+	ps.startTime = time.Now()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/progress", ps.handleProgress)
+	mux.HandleFunc("/progress/events", ps.handleEvents)
+	ps.server = &http.Server{Handler: mux}
+
+	go func() {
+		if err := ps.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			g.Warn("progress server error: %s", err.Error())
+		}
+	}()
+
+	g.Debug("serving progress on http://127.0.0.1:%d/progress", ps.Port)
+	return nil
+}
+
+// Close sends the final event to the connected clients, and stops the server
+func (ps *ProgressServer) Close() {
+	if ps.server == nil {
+		return
+	}
+
+	close(ps.done)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ps.server.Shutdown(ctx)
+}
+
+// Plan sets the streams planned to run, so that they show as queued
+func (ps *ProgressServer) Plan(streams ...string) {
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+	ps.streams = streams
+}
+
+// Track adds a task to follow
+func (ps *ProgressServer) Track(t *TaskExecution) {
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+
+	stream := t.Config.StreamLabel()
+	if !g.In(stream, ps.streams...) {
+		ps.streams = append(ps.streams, stream)
+	}
+	ps.tasks[stream] = t
+}
+
+// Snapshot returns the current progress of the run
+func (ps *ProgressServer) Snapshot() (p Progress) {
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+
+	p = Progress{
+		Status:  ExecStatusQueued,
+		Elapsed: time.Since(ps.startTime).Seconds(),
+		Streams: []StreamProgress{},
+	}
+
+	var finished, queued int
+	var finishedSecs, currentETA float64
+	for _, stream := range ps.streams {
+		t, ok := ps.tasks[stream]
+		if !ok {
+			p.Streams = append(p.Streams, StreamProgress{Stream: stream, Status: ExecStatusQueued})
+			queued++
+			continue
+		}
+
+		sp := newStreamProgress(stream, t)
+		p.Streams = append(p.Streams, sp)
+
+		switch {
+		case sp.Status.IsFinished():
+			finished++
+			finishedSecs += sp.Elapsed
+			if sp.Status.IsFailure() {
+				p.Status = ExecStatusError
+			}
+		case sp.ETA != nil:
+			currentETA = *sp.ETA
+		}
+	}
+
+	switch {
+	case p.Status == ExecStatusError:
+	case len(ps.tasks) == 0:
+	case finished == len(ps.streams):
+		p.Status = ExecStatusSuccess
+	default:
+		p.Status = ExecStatusRunning
+	}
+
+	// estimate the remaining time from the average duration of the finished streams
+	if finished > 0 && queued > 0 {
+		eta := currentETA + finishedSecs/float64(finished)*float64(queued)
+		p.ETA = &eta
+	} else if queued == 0 && currentETA > 0 {
+		p.ETA = &currentETA
+	}
+
+	return p
+}
+
+// newStreamProgress returns the progress of a task
+func newStreamProgress(stream string, t *TaskExecution) (sp StreamProgress) {
+	sp = StreamProgress{
+		Stream:   stream,
+		Status:   t.Status,
+		Progress: t.Progress,
+		Rows:     t.GetCount(),
+	}
+	sp.Bytes, _ = t.GetBytes()
+	sp.RowRate, sp.ByteRate = t.GetRate(0)
+
+	if t.StartTime != nil {
+		endTime := time.Now()
+		if t.EndTime != nil {
+			endTime = *t.EndTime
+		}
+		sp.Elapsed = endTime.Sub(*t.StartTime).Seconds()
+	}
+
+	if t.Err != nil {
+		sp.Error = t.Err.Error()
+	}
+
+	// the total is only known with a limit
+	if t.Status.IsFinished() || t.Config.Source.Options == nil {
+		return sp
+	} else if limit := t.Config.Source.Limit(); limit > 0 && sp.RowRate > 0 {
+		eta := float64(uint64(limit)-min(sp.Rows, uint64(limit))) / float64(sp.RowRate)
+		sp.ETA = &eta
+	}
+
+	return sp
+}
+
+func (ps *ProgressServer) handleProgress(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(g.Marshal(ps.Snapshot())))
+}
+
+func (ps *ProgressServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	send := func() {
+		fmt.Fprintf(w, "event: progress\ndata: %s\n\n", g.Marshal(ps.Snapshot()))
+		flusher.Flush()
+	}
+
+	ticker := time.NewTicker(ps.Interval)
+	defer ticker.Stop()
+
+	send()
+	for {
+		select {
+		case <-ticker.C:
+			send()
+		case <-ps.done:
+			send() // final event
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"database/sql/driver"
 	"io"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/flarco/g"
 	"github.com/gobwas/glob"
@@ -25,15 +27,30 @@ type ReplicationConfig struct {
 	Streams  map[string]*ReplicationStreamConfig `json:"streams,omitempty" yaml:"streams,omitempty"`
 	Env      map[string]any                      `json:"env,omitempty" yaml:"env,omitempty"`
 
+	// Variables are computed once per run, via SQL, and rendered in the streams
+	// when compiling. They are computed at run time only, see ComputeVariables.
+	Variables map[string]any `json:"variables,omitempty" yaml:"variables,omitempty"`
+
+	// OnStreamError is the behavior when a stream fails (continue, fail_fast, quarantine)
+	OnStreamError StreamErrorPolicy `json:"on_stream_error,omitempty" yaml:"on_stream_error,omitempty"`
+
+	// Notifications are sent at the end of each stream run (slack, email, webhook)
+	Notifications []Notification `json:"notifications,omitempty" yaml:"notifications,omitempty"`
+
+	// Report is written after the run (per-stream status, rows, warnings, schema changes)
+	Report *RunReportConfig `json:"report,omitempty" yaml:"report,omitempty"`
+
 	// Tasks are compiled tasks
 	Tasks    []*Config `json:"tasks"`
 	Compiled bool      `json:"compiled"`
 	FailErr  string    // error string to fail all (e.g. when the first tasks fails to connect)
+	Embedded bool      `json:"-" yaml:"-"` // compile the tasks as embedded, see ConfigOptions.Embedded
 
 	streamsOrdered []string
 	originalCfg    string
 	maps           replicationConfigMaps // raw maps for validation
 	state          *RuntimeState
+	variables      map[string]any // computed variables
 }
 
 type replicationConfigMaps struct {
@@ -54,6 +71,7 @@ func (rd *ReplicationConfig) JSON() string {
 		g.M("defaults", rd.Defaults),
 		g.M("streams", rd.Streams),
 		g.M("env", rd.Env),
+		g.M("variables", rd.Variables),
 	})
 
 	// clean up
@@ -551,7 +569,11 @@ func (rd *ReplicationConfig) Compile(cfgOverwrite *Config, selectStreams ...stri
 	matchedStreams := map[string]*ReplicationStreamConfig{}
 	includeTags := []string{}
 	excludeTags := []string{}
+	selectNames := 0 // selections which are not tags
 	for _, selectStream := range selectStreams {
+		if !strings.HasPrefix(selectStream, "tag:") && !strings.HasPrefix(selectStream, "-tag:") {
+			selectNames++
+		}
 		for key, val := range rd.MatchStreams(selectStream) {
 			key = rd.Normalize(key)
 			matchedStreams[key] = val
@@ -568,6 +590,12 @@ func (rd *ReplicationConfig) Compile(cfgOverwrite *Config, selectStreams ...stri
 		return g.Error("cannot include and exclude tags. Either include or exclude.")
 	}
 
+	// variables are rendered if computed, so that compiling does not query
+	variables := rd.variables
+	if len(rd.Variables) > 0 && variables == nil {
+		g.Debug("replication variables are not computed, not rendering them")
+	}
+
 	for _, name := range rd.StreamsOrdered() {
 
 		stream := ReplicationStreamConfig{}
@@ -577,6 +605,13 @@ func (rd *ReplicationConfig) Compile(cfgOverwrite *Config, selectStreams ...stri
 		SetStreamDefaults(name, &stream, *rd)
 		stream.replication = rd
 
+		// render variables
+		if len(variables) > 0 {
+			stream.Object = g.Rm(stream.Object, variables)
+			stream.Where = g.Rm(stream.Where, variables)
+			stream.SQL = g.Rm(stream.SQL, variables)
+		}
+
 		if stream.Object == "" {
 			return g.Error("need to specify `object` for stream `%s`. Please see https://docs.slingdata.io/sling-cli for help.", name)
 		}
@@ -588,8 +623,8 @@ func (rd *ReplicationConfig) Compile(cfgOverwrite *Config, selectStreams ...stri
 				matchedTag = true
 			}
 		}
-		if matchedTag {
-			matchedStreams[rd.Normalize(name)] = &stream
+		if matchedTag || (len(excludeTags) > 0 && selectNames == 0) {
+			matchedStreams[rd.Normalize(name)] = &stream // only excluding, start from all
 		}
 
 		// exclude tags
@@ -666,50 +701,24 @@ func (rd *ReplicationConfig) Compile(cfgOverwrite *Config, selectStreams ...stri
 			}
 		}
 
-		cfg := Config{
-			Source: Source{
-				Conn:        rd.Source,
-				Stream:      name,
-				Query:       stream.SQL,
-				Select:      stream.Select,
-				Where:       stream.Where,
-				PrimaryKeyI: stream.PrimaryKey(),
-				UpdateKey:   stream.UpdateKey,
-			},
-			Target: Target{
-				Conn:    rd.Target,
-				Object:  stream.Object,
-				Columns: stream.Columns,
-			},
-			Mode:              stream.Mode,
-			Transforms:        stream.Transforms,
-			Env:               taskEnv,
-			StreamName:        name,
-			IncrementalVal:    incrementalVal,
-			ReplicationStream: &stream,
-		}
+		cfg := rd.streamTask(name, &stream, taskEnv, incrementalVal)
 
-		// so that the next stream does not retain previous pointer values
-		g.Unmarshal(g.Marshal(stream.SourceOptions), &cfg.Source.Options)
-		g.Unmarshal(g.Marshal(stream.TargetOptions), &cfg.Target.Options)
+		// expand shards, one task per shard
+		shardCfgs, err := cfg.Shard()
+		if err != nil {
+			return g.Error(err, "could not shard stream task: %s", name)
+		}
 
-		// if single file target, set file_row_limit and file_bytes_limit
-		if stream.Single != nil && *stream.Single {
-			if cfg.Target.Options == nil {
-				cfg.Target.Options = &TargetOptions{}
+		for _, shardCfg := range shardCfgs {
+			// prepare config
+			err = shardCfg.Prepare()
+			if err != nil {
+				err = g.Error(err, "could not prepare stream task: %s", name)
+				return err
 			}
-			cfg.Target.Options.FileMaxBytes = g.Int64(0)
-			cfg.Target.Options.FileMaxRows = g.Int64(0)
-		}
 
-		// prepare config
-		err = cfg.Prepare()
-		if err != nil {
-			err = g.Error(err, "could not prepare stream task: %s", name)
-			return
+			rd.Tasks = append(rd.Tasks, shardCfg)
 		}
-
-		rd.Tasks = append(rd.Tasks, &cfg)
 	}
 
 	rd.Compiled = true
@@ -728,6 +737,158 @@ func (rd *ReplicationConfig) Compile(cfgOverwrite *Config, selectStreams ...stri
 	return
 }
 
+// streamTask returns the task config of a compiled stream
+func (rd *ReplicationConfig) streamTask(name string, stream *ReplicationStreamConfig, taskEnv map[string]string, incrementalVal string) *Config {
+	cfg := Config{
+		Source: Source{
+			Conn:        rd.Source,
+			Stream:      name,
+			Query:       stream.SQL,
+			Select:      stream.Select,
+			Where:       stream.Where,
+			PrimaryKeyI: stream.PrimaryKey(),
+			UpdateKey:   stream.UpdateKey,
+		},
+		Target: Target{
+			Conn:    rd.Target,
+			Object:  stream.Object,
+			Columns: stream.Columns,
+		},
+		Mode:              stream.Mode,
+		Transforms:        stream.Transforms,
+		Env:               taskEnv,
+		StreamName:        name,
+		IncrementalVal:    incrementalVal,
+		ReplicationStream: stream,
+		Options:           ConfigOptions{Embedded: rd.Embedded},
+	}
+
+	// so that the next stream does not retain previous pointer values
+	g.Unmarshal(g.Marshal(stream.SourceOptions), &cfg.Source.Options)
+	g.Unmarshal(g.Marshal(stream.TargetOptions), &cfg.Target.Options)
+
+	// if single file target, set file_row_limit and file_bytes_limit
+	if stream.Single != nil && *stream.Single {
+		if cfg.Target.Options == nil {
+			cfg.Target.Options = &TargetOptions{}
+		}
+		cfg.Target.Options.FileMaxBytes = g.Int64(0)
+		cfg.Target.Options.FileMaxRows = g.Int64(0)
+	}
+
+	return &cfg
+}
+
+// ComputeVariables computes the values of the replication variables, to be
+// rendered by Compile. It runs queries, so it should be called before compiling
+// a replication to run (not to validate it or for a dry-run).
+// A string value is a SQL query, run against the source connection.
+// A map value can specify the connection to use, with keys `connection` and `sql`.
+// The value of the first column of the first row is used.
+func (rd *ReplicationConfig) ComputeVariables() (variables map[string]any, err error) {
+	variables = map[string]any{}
+	if len(rd.Variables) == 0 {
+		return
+	}
+	defer func() {
+		if err == nil {
+			rd.variables = variables
+		}
+	}()
+
+	conns := map[string]database.Connection{}
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	names := lo.Keys(rd.Variables)
+	sort.Strings(names)
+
+	for _, name := range names {
+		connName, sql := rd.Source, ""
+		switch value := rd.Variables[name].(type) {
+		case string:
+			sql = value
+		case map[string]any, map[any]any:
+			valueMap, _ := g.UnmarshalMap(g.Marshal(value))
+			sql = cast.ToString(valueMap["sql"])
+			if val := cast.ToString(valueMap["connection"]); val != "" {
+				connName = val
+			}
+		default:
+			return nil, g.Error("invalid value for variable '%s', expected SQL string or map", name)
+		}
+
+		if strings.TrimSpace(sql) == "" {
+			return nil, g.Error("no sql provided for variable '%s'", name)
+		}
+
+		conn, ok := conns[strings.ToLower(connName)]
+		if !ok {
+			if conn, err = getVariableConn(connName); err != nil {
+				return nil, g.Error(err, "could not get connection for variable '%s'", name)
+			}
+			conns[strings.ToLower(connName)] = conn
+		}
+
+		data, err := conn.Query(sql)
+		if err != nil {
+			return nil, g.Error(err, "could not compute variable '%s'", name)
+		}
+
+		var value any
+		if len(data.Rows) > 0 && len(data.Rows[0]) > 0 {
+			value = data.Rows[0][0]
+		}
+
+		switch v := value.(type) {
+		case nil:
+			g.Warn("variable '%s' computed to null, using empty string", name)
+			value = ""
+		case time.Time:
+			value = v.Format("2006-01-02 15:04:05.000000")
+		}
+
+		g.Debug("computed variable %s = %v", name, value)
+		variables[name] = value
+	}
+
+	return
+}
+
+// getVariableConn returns a connected database connection, by name or URL
+func getVariableConn(connName string) (conn database.Connection, err error) {
+	connsMap := lo.KeyBy(connection.GetLocalConns(), func(c connection.ConnEntry) string {
+		return strings.ToLower(c.Connection.Name)
+	})
+
+	c, ok := connsMap[strings.ToLower(connName)]
+	if !ok {
+		if !strings.Contains(connName, "://") {
+			return nil, g.Error("did not find connection: %s", connName)
+		}
+		c.Connection, err = connection.NewConnectionFromURL("variables", connName)
+		if err != nil {
+			return nil, g.Error(err, "could not parse connection URL")
+		}
+	}
+
+	if !c.Connection.Type.IsDb() {
+		return nil, g.Error("connection %s is not a database", connName)
+	}
+
+	conn, err = c.Connection.AsDatabase(true)
+	if err != nil {
+		return nil, g.Error(err, "could not init connection: %s", connName)
+	} else if err = conn.Connect(); err != nil {
+		return nil, g.Error(err, "could not connect to: %s", connName)
+	}
+
+	return conn, nil
+}
+
 type ReplicationStreamConfig struct {
 	Description   string         `json:"description,omitempty" yaml:"description,omitempty"`
 	Mode          Mode           `json:"mode,omitempty" yaml:"mode,omitempty"`
@@ -825,6 +986,18 @@ func UnmarshalReplication(replicYAML string) (config ReplicationConfig, err erro
 	config.originalCfg = replicYAML
 	config.Env = map[string]any{}
 
+	// render the template tags (loops, conditionals)
+	replicYAML, err = renderReplicationTemplate(replicYAML)
+	if err != nil {
+		return
+	}
+
+	// overlay the overrides of the env profile
+	replicYAML, err = applyEnvProfile(replicYAML)
+	if err != nil {
+		return
+	}
+
 	m := g.M()
 	err = yaml.Unmarshal([]byte(replicYAML), &m)
 	if err != nil {
@@ -889,6 +1062,48 @@ func UnmarshalReplication(replicYAML string) (config ReplicationConfig, err erro
 		originalCfg: replicYAML, // set originalCfg
 	}
 
+	// parse variables
+	if variables, ok := m["variables"]; ok {
+		err = g.Unmarshal(g.Marshal(variables), &config.Variables)
+		if err != nil {
+			err = g.Error(err, "could not parse 'variables'")
+			return
+		}
+	}
+
+	// parse failure policy
+	config.OnStreamError = StreamErrorPolicy(cast.ToString(m["on_stream_error"]))
+	if err = config.OnStreamError.Validate(); err != nil {
+		return
+	}
+
+	// parse notifications
+	if notifications, ok := m["notifications"]; ok {
+		if err = g.Unmarshal(g.Marshal(notifications), &config.Notifications); err != nil {
+			err = g.Error(err, "could not parse 'notifications'")
+			return
+		}
+		for i := range config.Notifications {
+			if err = config.Notifications[i].Validate(); err != nil {
+				return
+			}
+		}
+	}
+
+	// parse report, as a path or an object
+	if report, ok := m["report"]; ok {
+		config.Report = &RunReportConfig{}
+		if reportPath, ok := report.(string); ok {
+			config.Report.Path = reportPath
+		} else if err = g.Unmarshal(g.Marshal(report), config.Report); err != nil {
+			err = g.Error(err, "could not parse 'report'")
+			return
+		}
+		if err = config.Report.Validate(); err != nil {
+			return
+		}
+	}
+
 	// parse defaults
 	err = g.Unmarshal(g.Marshal(defaults), &config.Defaults)
 	if err != nil {
@@ -0,0 +1,330 @@
+package sling
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio/database"
+	"github.com/slingdata-io/sling-cli/core/dbio/filesys"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/slingdata-io/sling-cli/core/env"
+	"github.com/spf13/cast"
+)
+
+// CheckStatus is the status of a replication health check
+type CheckStatus string
+
+const (
+	CheckStatusPass CheckStatus = "pass"
+	CheckStatusWarn CheckStatus = "warn"
+	CheckStatusFail CheckStatus = "fail"
+	CheckStatusSkip CheckStatus = "skip"
+)
+
+// the health checks of each stream, in order
+const (
+	CheckSource = "source"
+	CheckKeys   = "keys"
+	CheckTarget = "target"
+	CheckVolume = "volume"
+)
+
+var allChecks = []string{CheckSource, CheckKeys, CheckTarget, CheckVolume}
+
+// CheckResult is the result of a health check of a replication stream
+type CheckResult struct {
+	Stream  string      `json:"stream"`
+	Check   string      `json:"check"`
+	Status  CheckStatus `json:"status"`
+	Message string      `json:"message,omitempty"`
+}
+
+// CheckResults are the health check results of a replication
+type CheckResults []CheckResult
+
+// Failed returns true if any check failed
+func (crs CheckResults) Failed() bool {
+	for _, cr := range crs {
+		if cr.Status == CheckStatusFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the result of a check for a stream
+func (crs CheckResults) Get(stream, check string) (cr CheckResult, found bool) {
+	for _, cr := range crs {
+		if cr.Stream == stream && cr.Check == check {
+			return cr, true
+		}
+	}
+	return CheckResult{}, false
+}
+
+// Matrix returns a printable pass/fail matrix (one row per stream),
+// followed by the details of the checks that did not pass
+func (crs CheckResults) Matrix() string {
+	streams := []string{}
+	for _, cr := range crs {
+		if !g.In(cr.Stream, streams...) {
+			streams = append(streams, cr.Stream)
+		}
+	}
+
+	rows := [][]any{}
+	for _, stream := range streams {
+		row := []any{stream}
+		for _, check := range allChecks {
+			cr, _ := crs.Get(stream, check)
+			row = append(row, strings.ToUpper(string(cr.Status)))
+		}
+		rows = append(rows, row)
+	}
+
+	header := append([]string{"Stream"}, allChecks...)
+	output := g.PrettyTable(header, rows)
+
+	details := [][]any{}
+	for _, cr := range crs {
+		if cr.Status == CheckStatusWarn || cr.Status == CheckStatusFail {
+			details = append(details, []any{cr.Stream, cr.Check, strings.ToUpper(string(cr.Status)), cr.Message})
+		}
+	}
+	if len(details) > 0 {
+		output = output + "\n" + g.PrettyTable([]string{"Stream", "Check", "Status", "Message"}, details)
+	}
+
+	return output
+}
+
+// Check verifies each stream of a compiled replication against the live systems:
+// the source object exists, the keys exist with sortable / comparable types,
+// the target is writable, and the estimated source volume.
+func (rd *ReplicationConfig) Check(ctx context.Context) (results CheckResults) {
+	checker := &replicationChecker{ctx: ctx, dbConns: map[string]database.Connection{}}
+	defer checker.close()
+
+	for _, cfg := range rd.Tasks {
+		add := func(check string, status CheckStatus, message string, args ...any) {
+			results = append(results, CheckResult{
+				Stream:  cfg.StreamName,
+				Check:   check,
+				Status:  status,
+				Message: g.F(message, args...),
+			})
+		}
+
+		// source object & volume
+		var columns iop.Columns
+		if cfg.SrcConn.Info().Type.IsDb() {
+			conn, err := checker.dbConn(cfg.SrcConnMD5(), cfg.SrcConn.AsDatabaseContext)
+			if err != nil {
+				add(CheckSource, CheckStatusFail, "could not connect to source: %s", err.Error())
+				add(CheckVolume, CheckStatusSkip, "")
+			} else if columns, err = checker.sourceColumns(conn, cfg); err != nil {
+				add(CheckSource, CheckStatusFail, "source object not found: %s", err.Error())
+				add(CheckVolume, CheckStatusSkip, "")
+			} else {
+				add(CheckSource, CheckStatusPass, "%d columns", len(columns))
+				if count, err := checker.sourceCount(conn, cfg); err != nil {
+					add(CheckVolume, CheckStatusWarn, "could not count rows: %s", err.Error())
+				} else if count == 0 {
+					add(CheckVolume, CheckStatusWarn, "source has no rows")
+				} else {
+					add(CheckVolume, CheckStatusPass, "%s rows", humanize.Comma(count))
+				}
+			}
+		} else if cfg.SrcConn.Info().Type.IsFile() {
+			nodes, err := checker.sourceFiles(cfg)
+			if err != nil {
+				add(CheckSource, CheckStatusFail, "could not list source: %s", err.Error())
+				add(CheckVolume, CheckStatusSkip, "")
+			} else if files := nodes.Files(); len(files) == 0 {
+				add(CheckSource, CheckStatusFail, "no files found")
+				add(CheckVolume, CheckStatusSkip, "")
+			} else {
+				add(CheckSource, CheckStatusPass, "%d files", len(files))
+				add(CheckVolume, CheckStatusPass, "%d files, %s", len(files), humanize.Bytes(files.TotalSize()))
+			}
+		} else {
+			add(CheckSource, CheckStatusSkip, "")
+			add(CheckVolume, CheckStatusSkip, "")
+		}
+
+		// keys, only when the source columns are known
+		if len(columns) == 0 {
+			add(CheckKeys, CheckStatusSkip, "")
+		} else {
+			status, message := checkKeys(cfg, columns)
+			add(CheckKeys, status, message)
+		}
+
+		// target
+		if cfg.Options.StdOut {
+			add(CheckTarget, CheckStatusSkip, "")
+		} else if cfg.TgtConn.Info().Type.IsDb() {
+			conn, err := checker.dbConn(cfg.TgtConnMD5(), cfg.TgtConn.AsDatabaseContext)
+			if err != nil {
+				add(CheckTarget, CheckStatusFail, "could not connect to target: %s", err.Error())
+			} else if err = checker.targetWritable(conn, cfg); err != nil {
+				add(CheckTarget, CheckStatusFail, "target is not writable: %s", err.Error())
+			} else {
+				add(CheckTarget, CheckStatusPass, "")
+			}
+		} else if cfg.TgtConn.Info().Type.IsFile() {
+			if err := checker.targetFileWritable(cfg); err != nil {
+				add(CheckTarget, CheckStatusFail, "target is not writable: %s", err.Error())
+			} else {
+				add(CheckTarget, CheckStatusPass, "")
+			}
+		} else {
+			add(CheckTarget, CheckStatusSkip, "")
+		}
+	}
+
+	return results
+}
+
+// checkKeys verifies the primary / update keys exist, with sane types
+func checkKeys(cfg *Config, columns iop.Columns) (status CheckStatus, message string) {
+	warnings := []string{}
+
+	for _, pk := range cfg.Source.PrimaryKey() {
+		col := columns.GetColumn(pk)
+		if col == nil {
+			return CheckStatusFail, g.F("primary key column not found: %s", pk)
+		} else if g.In(col.Type, iop.JsonType, iop.BinaryType) {
+			warnings = append(warnings, g.F("primary key column %s is of type %s", col.Name, col.Type))
+		}
+	}
+
+	if uk := cfg.Source.UpdateKey; uk != "" && uk != cfg.loadedAtColumn() {
+		col := columns.GetColumn(uk)
+		if col == nil {
+			return CheckStatusFail, g.F("update key column not found: %s", uk)
+		} else if !col.IsDatetime() && !col.IsNumber() && !col.IsDate() {
+			warnings = append(warnings, g.F("update key column %s is of type %s, expected a datetime or number", col.Name, col.Type))
+		}
+	}
+
+	if len(warnings) > 0 {
+		return CheckStatusWarn, strings.Join(warnings, "; ")
+	}
+	return CheckStatusPass, ""
+}
+
+// replicationChecker holds the connections used during the checks
+type replicationChecker struct {
+	ctx     context.Context
+	dbConns map[string]database.Connection
+}
+
+func (rc *replicationChecker) dbConn(key string, asDatabase func(context.Context, ...bool) (database.Connection, error)) (conn database.Connection, err error) {
+	if conn, ok := rc.dbConns[key]; ok {
+		return conn, nil
+	}
+
+	conn, err = asDatabase(rc.ctx)
+	if err != nil {
+		return nil, g.Error(err, "could not initialize connection")
+	} else if err = conn.Connect(); err != nil {
+		return nil, g.Error(err, "could not connect")
+	}
+
+	rc.dbConns[key] = conn
+	return conn, nil
+}
+
+func (rc *replicationChecker) close() {
+	for _, conn := range rc.dbConns {
+		conn.Close()
+	}
+}
+
+func (rc *replicationChecker) sourceColumns(conn database.Connection, cfg *Config) (columns iop.Columns, err error) {
+	table, err := database.ParseTableName(cfg.Source.Stream, conn.GetType())
+	if err != nil {
+		return nil, g.Error(err, "could not parse source stream")
+	}
+	return conn.GetSQLColumns(table)
+}
+
+func (rc *replicationChecker) sourceCount(conn database.Connection, cfg *Config) (count int64, err error) {
+	table, err := database.ParseTableName(cfg.Source.Stream, conn.GetType())
+	if err != nil {
+		return 0, g.Error(err, "could not parse source stream")
+	}
+
+	from := table.FDQN()
+	if table.IsQuery() {
+		from = "(" + table.SQL + ") t"
+	}
+
+	data, err := conn.Query("select count(*) cnt from " + from + env.NoDebugKey)
+	if err != nil {
+		return 0, err
+	} else if len(data.Rows) == 0 {
+		return 0, nil
+	}
+	return cast.ToInt64(data.Rows[0][0]), nil
+}
+
+func (rc *replicationChecker) sourceFiles(cfg *Config) (nodes filesys.FileNodes, err error) {
+	fs, err := cfg.SrcConn.AsFileContext(rc.ctx)
+	if err != nil {
+		return nil, g.Error(err, "could not initialize connection")
+	}
+
+	uri := cast.ToString(cfg.Source.Data["url"])
+	if uri == "" {
+		return nil, g.Error("source url is empty")
+	}
+	return fs.ListRecursive(uri)
+}
+
+// targetWritable creates and drops a probe table in the target schema
+func (rc *replicationChecker) targetWritable(conn database.Connection, cfg *Config) (err error) {
+	targetTable, err := database.ParseTableName(cfg.Target.Object, conn.GetType())
+	if err != nil {
+		return g.Error(err, "could not parse target object")
+	}
+
+	probeTable := targetTable.Clone()
+	probeTable.Name = g.RandSuffix("sling_check_", 4)
+	probeTable, _ = database.ParseTableName(probeTable.FullName(), conn.GetType())
+
+	data := iop.NewDataset(iop.Columns{{Name: "id", Type: iop.IntegerType, Position: 1}})
+	probeTable.Columns = data.Columns
+	ddl, err := conn.GenerateDDL(probeTable, data, false)
+	if err != nil {
+		return g.Error(err, "could not generate probe table DDL")
+	}
+
+	if _, err = conn.ExecMulti(ddl); err != nil {
+		return g.Error(err, "could not create probe table %s", probeTable.FullName())
+	}
+
+	if err = conn.DropTable(probeTable.FullName()); err != nil {
+		return g.Error(err, "could not drop probe table %s", probeTable.FullName())
+	}
+
+	return nil
+}
+
+// targetFileWritable writes, reads and deletes a probe file in the target folder
+func (rc *replicationChecker) targetFileWritable(cfg *Config) (err error) {
+	fs, err := cfg.TgtConn.AsFileContext(rc.ctx)
+	if err != nil {
+		return g.Error(err, "could not initialize connection")
+	}
+
+	uri := cast.ToString(cfg.Target.Data["url"])
+	if index := strings.LastIndex(uri, "/"); index > 0 {
+		uri = uri[:index]
+	}
+
+	return filesys.TestFsPermissions(fs, uri+"/"+g.RandSuffix("sling_check_", 4)+".txt")
+}
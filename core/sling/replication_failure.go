@@ -0,0 +1,39 @@
+package sling
+
+import (
+	"github.com/flarco/g"
+)
+
+// StreamErrorPolicy is the behavior of a replication when a stream fails
+type StreamErrorPolicy string
+
+const (
+	// StreamErrorContinue records the failure and runs the next streams (default)
+	StreamErrorContinue StreamErrorPolicy = "continue"
+	// StreamErrorFailFast stops the replication at the first failure
+	StreamErrorFailFast StreamErrorPolicy = "fail_fast"
+	// StreamErrorQuarantine continues, and skips the failed stream on the
+	// next runs, until cleared with `sling state clear-quarantine`
+	StreamErrorQuarantine StreamErrorPolicy = "quarantine"
+)
+
+// Validate checks the policy value
+func (p StreamErrorPolicy) Validate() error {
+	switch p {
+	case "", StreamErrorContinue, StreamErrorFailFast, StreamErrorQuarantine:
+		return nil
+	}
+	return g.Error("invalid on_stream_error value '%s', expected one of: continue, fail_fast, quarantine", p)
+}
+
+// GetQuarantine and SetQuarantine read and write the quarantine reason of a
+// stream (blank when not quarantined), from / into the sling state
+var (
+	GetQuarantine = func(cfg *Config) (reason string, err error) {
+		return "", nil
+	}
+
+	SetQuarantine = func(cfg *Config, reason string) (err error) {
+		return nil
+	}
+)
@@ -0,0 +1,178 @@
+package sling
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio/database"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+	"gopkg.in/yaml.v2"
+)
+
+// updateKeyNames are the column names suggested as update keys, by priority
+var updateKeyNames = []string{
+	"updated_at", "modified_at", "last_modified", "last_modified_at", "last_updated",
+	"last_updated_at", "updated", "modified", "update_date", "updated_date",
+	"modified_date", "date_updated", "date_modified", "update_time", "updated_on",
+	"modified_on", "_updated_at", "_sling_loaded_at", "created_at", "inserted_at",
+	"created", "create_date", "created_date", "created_on",
+}
+
+// GeneratedStream is a stream of a generated replication
+type GeneratedStream struct {
+	Name       string   `json:"name"`
+	PrimaryKey []string `json:"primary_key,omitempty"`
+	PKInferred bool     `json:"pk_inferred,omitempty"` // from the column names, not a constraint
+	UpdateKey  string   `json:"update_key,omitempty"`
+}
+
+// GeneratedReplication is a replication scaffolded from a source schema
+type GeneratedReplication struct {
+	Source  string            `json:"source"`
+	Target  string            `json:"target"`
+	Schema  string            `json:"schema"`
+	Streams []GeneratedStream `json:"streams"`
+}
+
+// GenerateReplication introspects the tables of the schema of the source database,
+// and returns a replication with their primary keys and suggested update keys
+func GenerateReplication(conn database.Connection, source, schema, target string) (gr GeneratedReplication, err error) {
+	gr = GeneratedReplication{Source: source, Target: target, Schema: schema}
+
+	schemata, err := conn.GetSchemata(database.SchemataLevelColumn, schema)
+	if err != nil {
+		return gr, g.Error(err, "could not get tables of schema %s", schema)
+	}
+
+	tables := lo.Filter(lo.Values(schemata.Tables()), func(t database.Table, i int) bool { return !t.IsView })
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+	if len(tables) == 0 {
+		return gr, g.Error("did not find any table in schema %s", schema)
+	}
+
+	for _, table := range tables {
+		stream := GeneratedStream{Name: table.Schema + "." + table.Name}
+
+		pkData, err := conn.GetPrimaryKeys(table.FullName())
+		if err != nil {
+			g.Warn("could not get primary key of %s: %s", stream.Name, g.ErrMsgSimple(err))
+		}
+		stream.PrimaryKey = primaryKeyColumns(pkData)
+		if len(stream.PrimaryKey) == 0 {
+			stream.PrimaryKey = inferPrimaryKey(table)
+			stream.PKInferred = len(stream.PrimaryKey) > 0
+		}
+		stream.UpdateKey = suggestUpdateKey(table.Columns)
+
+		gr.Streams = append(gr.Streams, stream)
+	}
+
+	return gr, nil
+}
+
+// primaryKeyColumns returns the column names of the primary key query result, in order
+func primaryKeyColumns(data iop.Dataset) (columns []string) {
+	records := data.Records()
+	sort.SliceStable(records, func(i, j int) bool {
+		return cast.ToInt(records[i]["position"]) < cast.ToInt(records[j]["position"])
+	})
+	for _, rec := range records {
+		if name := cast.ToString(rec["column_name"]); name != "" {
+			columns = append(columns, name)
+		}
+	}
+	return columns
+}
+
+// inferPrimaryKey returns the `id` or `<table>_id` column, when the table has no constraint
+func inferPrimaryKey(table database.Table) []string {
+	candidates := []string{"id", table.Name + "_id", strings.TrimSuffix(table.Name, "s") + "_id"}
+	for _, candidate := range candidates {
+		for _, col := range table.Columns {
+			if strings.EqualFold(col.Name, candidate) && !cast.ToBool(col.Metadata["nullable"]) {
+				return []string{col.Name}
+			}
+		}
+	}
+	return nil
+}
+
+// suggestUpdateKey returns the date/datetime column most likely to be the update key
+func suggestUpdateKey(columns iop.Columns) string {
+	// the native type is checked as well, since some databases store dates as text (e.g. sqlite)
+	timeColumns := lo.Filter(columns, func(col iop.Column, i int) bool {
+		dbType := strings.ToLower(col.DbType)
+		return col.Type.IsDatetime() || col.Type.IsDate() || strings.Contains(dbType, "date") || strings.Contains(dbType, "time")
+	})
+
+	for _, name := range updateKeyNames {
+		for _, col := range timeColumns {
+			if strings.EqualFold(col.Name, name) {
+				return col.Name
+			}
+		}
+	}
+
+	// e.g. `row_updated_ts`, `dt_modified`
+	for _, col := range timeColumns {
+		lower := strings.ToLower(col.Name)
+		if strings.Contains(lower, "updat") || strings.Contains(lower, "modif") {
+			return col.Name
+		}
+	}
+
+	return ""
+}
+
+// YAML returns the replication YAML, with comments to help editing
+func (gr GeneratedReplication) YAML() string {
+	quote := func(val string) string {
+		out, _ := yaml.Marshal(val)
+		return strings.TrimSpace(string(out))
+	}
+	flow := func(vals []string) string {
+		return "[" + strings.Join(lo.Map(vals, func(v string, i int) string { return quote(v) }), ", ") + "]"
+	}
+
+	lines := []string{
+		g.F("# generated from schema %s of %s on %s, review before running", gr.Schema, gr.Source, time.Now().Format("2006-01-02")),
+		"source: " + quote(gr.Source),
+		"target: " + quote(gr.Target),
+		"",
+		"defaults:",
+		"  mode: full-refresh",
+		"  object: '{target_schema}.{stream_table}'",
+		"",
+		"streams:",
+	}
+
+	for _, stream := range gr.Streams {
+		lines = append(lines, "  "+quote(stream.Name)+":")
+		switch {
+		case len(stream.PrimaryKey) > 0 && stream.UpdateKey != "":
+			lines = append(lines, "    mode: incremental")
+		case stream.UpdateKey != "":
+			lines = append(lines, "    mode: incremental # no primary key, new rows are appended")
+		}
+
+		if len(stream.PrimaryKey) > 0 {
+			comment := lo.Ternary(stream.PKInferred, " # inferred from column name, no constraint", "")
+			lines = append(lines, "    primary_key: "+flow(stream.PrimaryKey)+comment)
+		} else {
+			lines = append(lines, "    # primary_key: [] # no primary key found")
+		}
+
+		if stream.UpdateKey != "" {
+			lines = append(lines, "    update_key: "+quote(stream.UpdateKey))
+		} else {
+			lines = append(lines, "    # update_key: # no update column found")
+		}
+		lines = append(lines, "")
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,138 @@
+package sling
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/spf13/cast"
+	"gopkg.in/yaml.v2"
+)
+
+// applyEnvProfile overlays the `overrides` of the selected environment profile
+// (SLING_ENV_PROFILE, e.g. `prod`) onto the replication, and removes the
+// `overrides` section. Maps are merged key by key (source, target, defaults,
+// streams, env...), other values are replaced
+func applyEnvProfile(replicYAML string) (string, error) {
+	profile := strings.TrimSpace(os.Getenv("SLING_ENV_PROFILE"))
+
+	root := yaml.MapSlice{}
+	expanded := map[any]any{}
+	if err := yaml.Unmarshal([]byte(replicYAML), &root); err != nil {
+		return replicYAML, nil // reported when parsing
+	} else if err = yaml.Unmarshal([]byte(replicYAML), &expanded); err != nil {
+		return replicYAML, nil
+	}
+	root = expandMapSlice(root, expanded) // MapSlice drops the merge keys (<<: *anchor)
+
+	var overrides yaml.MapSlice
+	hasOverrides := false
+	for i, node := range root {
+		if cast.ToString(node.Key) == "overrides" {
+			overrides, _ = node.Value.(yaml.MapSlice)
+			root = append(root[:i], root[i+1:]...)
+			hasOverrides = true
+			break
+		}
+	}
+
+	if !hasOverrides {
+		if profile != "" {
+			return replicYAML, g.Error("env profile %s specified, but the replication has no 'overrides' section", profile)
+		}
+		return replicYAML, nil
+	}
+
+	if profile != "" {
+		var overlay yaml.MapSlice
+		matched := false
+		profiles := []string{}
+		for _, node := range overrides {
+			profiles = append(profiles, cast.ToString(node.Key))
+			if cast.ToString(node.Key) == profile {
+				overlay, _ = node.Value.(yaml.MapSlice)
+				matched = true
+			}
+		}
+		if !matched {
+			return replicYAML, g.Error("env profile %s not found in overrides (available: %s)", profile, strings.Join(profiles, ", "))
+		}
+		root = mergeMapSlice(root, overlay)
+		g.Debug("applied env profile %s", profile)
+	}
+
+	payload, err := yaml.Marshal(root)
+	if err != nil {
+		return replicYAML, g.Error(err, "could not apply env profile %s", profile)
+	}
+
+	return string(payload), nil
+}
+
+// mergeMapSlice merges the overlay into the base, recursively for maps,
+// keeping the order of the keys
+func mergeMapSlice(base, overlay yaml.MapSlice) yaml.MapSlice {
+	merged := append(yaml.MapSlice{}, base...)
+	for _, oNode := range overlay {
+		key := cast.ToString(oNode.Key)
+		index := -1
+		for i, bNode := range merged {
+			if cast.ToString(bNode.Key) == key {
+				index = i
+				break
+			}
+		}
+
+		if index == -1 {
+			merged = append(merged, oNode)
+			continue
+		}
+
+		bValue, bIsMap := merged[index].Value.(yaml.MapSlice)
+		oValue, oIsMap := oNode.Value.(yaml.MapSlice)
+		if bIsMap && oIsMap {
+			merged[index].Value = mergeMapSlice(bValue, oValue)
+		} else {
+			merged[index].Value = oNode.Value
+		}
+	}
+	return merged
+}
+
+// expandMapSlice returns the ordered map with the keys merged from anchors,
+// taken from the expanded (unordered) map. Merged keys come last
+func expandMapSlice(ordered yaml.MapSlice, expanded map[any]any) yaml.MapSlice {
+	result := yaml.MapSlice{}
+	seen := map[string]bool{}
+
+	expandValue := func(orderedValue, value any) any {
+		if m, ok := value.(map[any]any); ok {
+			slice, _ := orderedValue.(yaml.MapSlice)
+			return expandMapSlice(slice, m)
+		}
+		return value
+	}
+
+	for _, node := range ordered {
+		if value, ok := expanded[node.Key]; ok {
+			result = append(result, yaml.MapItem{Key: node.Key, Value: expandValue(node.Value, value)})
+			seen[cast.ToString(node.Key)] = true
+		}
+	}
+
+	keys := []string{}
+	byKey := map[string]any{}
+	for key := range expanded {
+		if !seen[cast.ToString(key)] {
+			keys = append(keys, cast.ToString(key))
+			byKey[cast.ToString(key)] = key
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		result = append(result, yaml.MapItem{Key: byKey[key], Value: expandValue(nil, expanded[byKey[key]])})
+	}
+
+	return result
+}
@@ -0,0 +1,98 @@
+package sling
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/flarco/g"
+	"github.com/spf13/cast"
+	"gopkg.in/yaml.v2"
+)
+
+// templateFuncs are the functions available in replication templates
+var templateFuncs = template.FuncMap{
+	"lower":   strings.ToLower,
+	"upper":   strings.ToUpper,
+	"trim":    strings.TrimSpace,
+	"replace": strings.ReplaceAll,
+	"split":   strings.Split,
+	"join": func(sep string, values []any) string {
+		parts := make([]string, len(values))
+		for i, val := range values {
+			parts[i] = g.F("%v", val)
+		}
+		return strings.Join(parts, sep)
+	},
+}
+
+// renderReplicationTemplate renders a replication YAML containing template
+// tags (Go text/template: `{{ range .tables }}`, `{{ if eq .region "eu" }}`),
+// so that similar streams can be generated in a loop. The values are the
+// top-level `vars` section (plain YAML) and the runtime variables (`--vars`).
+// Rendering is opt-in: only when a `vars` section is present, or with
+// SLING_REPLICATION_TEMPLATE=true, so that `{{` in existing SQL is kept as is.
+func renderReplicationTemplate(replicYAML string) (string, error) {
+	block := varsBlock(replicYAML)
+	if !strings.Contains(replicYAML, "{{") {
+		return replicYAML, nil
+	} else if block == "" && !cast.ToBool(os.Getenv("SLING_REPLICATION_TEMPLATE")) {
+		return replicYAML, nil
+	}
+
+	values := map[string]any{}
+	if block != "" {
+		if strings.Contains(block, "{{") {
+			return replicYAML, g.Error("the 'vars' section of the replication cannot contain template tags")
+		}
+
+		root := map[string]any{}
+		if err := yaml.Unmarshal([]byte(block), &root); err != nil {
+			return replicYAML, g.Error(err, "could not parse the 'vars' section")
+		}
+		if vars, ok := root["vars"].(map[any]any); ok {
+			for k, v := range vars {
+				values[g.F("%v", k)] = v
+			}
+		}
+	}
+
+	runtimeVars, err := RuntimeVars()
+	if err != nil {
+		return replicYAML, err
+	}
+	for k, v := range runtimeVars {
+		values[k] = v
+	}
+
+	tmpl, err := template.New("replication").Funcs(templateFuncs).Option("missingkey=error").Parse(replicYAML)
+	if err != nil {
+		return replicYAML, g.Error(err, "could not parse replication template")
+	}
+
+	var output bytes.Buffer
+	if err = tmpl.Execute(&output, values); err != nil {
+		return replicYAML, g.Error(err, "could not render replication template")
+	}
+
+	return output.String(), nil
+}
+
+// varsBlock returns the top-level `vars` section of a YAML text, with its
+// indented lines, since the whole text cannot be parsed before rendering
+func varsBlock(text string) string {
+	lines := []string{}
+	inBlock := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "vars:") {
+			inBlock = true
+		} else if inBlock && line != "" && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, "#") {
+			break
+		}
+		if inBlock {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -1,11 +1,22 @@
 package sling
 
 import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/flarco/g"
+	"github.com/samber/lo"
 	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+	"github.com/slingdata-io/sling-cli/core/dbio/database"
+	"github.com/spf13/cast"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -193,3 +204,603 @@ func TestReplicationWildcards(t *testing.T) {
 
 	}
 }
+
+func TestReplicationVariables(t *testing.T) {
+	dbPath := path.Join(t.TempDir(), "variables.db")
+
+	yaml := `
+source: sqlite://` + dbPath + `
+target: sqlite://` + dbPath + `
+defaults:
+  object: main.{stream_table}_{suffix}
+  mode: full-refresh
+variables:
+  max_id: select 40 + 2
+  suffix:
+    connection: sqlite://` + dbPath + `
+    sql: select 'copy'
+streams:
+  main.table1:
+    where: id > {max_id}
+`
+	replication, err := UnmarshalReplication(yaml)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, replication.Variables, 2)
+
+	// compiling does not compute the variables (no queries)
+	err = replication.Compile(nil)
+	if assert.NoError(t, err) && assert.Len(t, replication.Tasks, 1) {
+		assert.Equal(t, "id > {max_id}", replication.Tasks[0].Source.Where)
+	}
+
+	replication, err = UnmarshalReplication(yaml)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	variables, err := replication.ComputeVariables()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 42, cast.ToInt(variables["max_id"]))
+	assert.Equal(t, "copy", variables["suffix"])
+
+	err = replication.Compile(nil)
+	if assert.NoError(t, err) && assert.Len(t, replication.Tasks, 1) {
+		assert.Equal(t, "id > 42", replication.Tasks[0].Source.Where)
+		assert.Contains(t, replication.Tasks[0].Target.Object, "table1_copy")
+	}
+
+	// invalid variable
+	replication.Variables = map[string]any{"bad": 1}
+	_, err = replication.ComputeVariables()
+	assert.Error(t, err)
+}
+
+func TestReplicationCheck(t *testing.T) {
+	dbPath := path.Join(t.TempDir(), "check.db")
+	conn, err := database.NewConn("sqlite://" + dbPath)
+	if !assert.NoError(t, err) || !assert.NoError(t, conn.Connect()) {
+		return
+	}
+	_, err = conn.ExecMulti(`create table orders (id integer, name text, seq integer); insert into orders values (1, 'a', 1)`)
+	conn.Close()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	yaml := `
+source: sqlite://` + dbPath + `
+target: sqlite://` + dbPath + `
+defaults:
+  object: main.{stream_table}_copy
+  mode: incremental
+streams:
+  main.orders:
+    primary_key: id
+    update_key: seq
+  main.orders_bad_key:
+    sql: select id, name from main.orders
+    object: main.orders_bad_key
+    primary_key: id
+    update_key: name
+  main.missing:
+    primary_key: id
+`
+	replication, err := UnmarshalReplication(yaml)
+	if !assert.NoError(t, err) || !assert.NoError(t, replication.Compile(nil)) {
+		return
+	}
+
+	results := replication.Check(context.Background())
+	assert.True(t, results.Failed())
+
+	status := func(stream, check string) CheckStatus {
+		cr, _ := results.Get(stream, check)
+		return cr.Status
+	}
+	assert.Equal(t, CheckStatusPass, status("main.orders", CheckSource))
+	assert.Equal(t, CheckStatusPass, status("main.orders", CheckKeys))
+	assert.Equal(t, CheckStatusPass, status("main.orders", CheckTarget))
+	assert.Equal(t, CheckStatusPass, status("main.orders", CheckVolume))
+	assert.Equal(t, CheckStatusWarn, status("main.orders_bad_key", CheckKeys))
+	assert.Equal(t, CheckStatusFail, status("main.missing", CheckSource))
+	assert.Equal(t, CheckStatusSkip, status("main.missing", CheckKeys))
+	assert.Contains(t, results.Matrix(), "main.missing")
+}
+
+func TestReplicationValidate(t *testing.T) {
+	dbPath := path.Join(t.TempDir(), "validate.db")
+	conn, err := database.NewConn("sqlite://" + dbPath)
+	if !assert.NoError(t, err) || !assert.NoError(t, conn.Connect()) {
+		return
+	}
+	_, err = conn.ExecMulti(`create table orders (id integer, seq integer)`)
+	conn.Close()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	yaml := `
+source: sqlite://` + dbPath + `
+target: sqlite://` + dbPath + `
+defaults:
+  object: main.{stream_table}_copy
+  primery_key: id
+streams:
+  main.orders:
+    mode: incremental
+    primary_key: id
+    update_key: seq
+  main.no_keys:
+    mode: incremental
+  main.bad_mode:
+    mode: upsert
+  main.scd2:
+    mode: scd2
+    updte_key: seq
+`
+	replication, err := UnmarshalReplication(yaml)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	errs := replication.Validate()
+	if assert.Len(t, errs, 5, errs.Error()) {
+		assert.Equal(t, ValidationError{Stream: "defaults", Message: "unknown key 'primery_key'"}, errs[0])
+		assert.Equal(t, ValidationError{Stream: "main.scd2", Message: "unknown key 'updte_key'"}, errs[1])
+		assert.Equal(t, "main.no_keys", errs[2].Stream)
+		assert.Contains(t, errs[2].Message, "incremental mode")
+		assert.Equal(t, "main.bad_mode", errs[3].Stream)
+		assert.Equal(t, "main.scd2", errs[4].Stream)
+		assert.Contains(t, errs[4].Message, "scd2 mode")
+	}
+
+	// unknown connection
+	replication, err = UnmarshalReplication("source: MISSING_CONN\ntarget: sqlite://" + dbPath + "\nstreams:\n  main.orders:\n")
+	if assert.NoError(t, err) {
+		errs = replication.Validate()
+		if assert.Len(t, errs, 1) {
+			assert.Contains(t, errs[0].Message, "source connection 'MISSING_CONN' not found")
+		}
+	}
+}
+
+func TestGenerateReplication(t *testing.T) {
+	dbPath := path.Join(t.TempDir(), "generate.db")
+	conn, err := database.NewConn("sqlite://" + dbPath)
+	if !assert.NoError(t, err) || !assert.NoError(t, conn.Connect()) {
+		return
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecMulti(`
+		create table orders (id integer primary key, amount real, updated_at timestamp, created_at timestamp);
+		create table order_items (order_id int not null, line int not null, sku text, primary key (order_id, line));
+		create table events (event_id text, created_at datetime);
+		create table customers (customer_id int not null, row_modified_ts timestamp);
+		create view orders_view as select * from orders;
+	`)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	generated, err := GenerateReplication(conn, "SRC", "main", "TGT")
+	if !assert.NoError(t, err) || !assert.Len(t, generated.Streams, 4) {
+		return
+	}
+
+	streams := map[string]GeneratedStream{}
+	for _, stream := range generated.Streams {
+		streams[stream.Name] = stream
+	}
+	assert.Equal(t, []string{"id"}, streams["main.orders"].PrimaryKey)
+	assert.Equal(t, "updated_at", streams["main.orders"].UpdateKey)
+	assert.Equal(t, []string{"order_id", "line"}, streams["main.order_items"].PrimaryKey)
+	assert.Empty(t, streams["main.order_items"].UpdateKey)
+	assert.Empty(t, streams["main.events"].PrimaryKey)
+	assert.Equal(t, "created_at", streams["main.events"].UpdateKey)
+	assert.Equal(t, []string{"customer_id"}, streams["main.customers"].PrimaryKey)
+	assert.True(t, streams["main.customers"].PKInferred)
+	assert.Equal(t, "row_modified_ts", streams["main.customers"].UpdateKey)
+
+	// the generated YAML is a valid replication
+	replication, err := UnmarshalReplication(generated.YAML())
+	if assert.NoError(t, err) {
+		assert.Equal(t, "SRC", replication.Source)
+		assert.Len(t, replication.Streams, 4)
+		assert.Equal(t, "updated_at", replication.Streams["main.orders"].UpdateKey)
+		assert.Equal(t, []string{"order_id", "line"}, replication.Streams["main.order_items"].PrimaryKey())
+	}
+}
+
+func TestBuiltinHooks(t *testing.T) {
+	dbPath := path.Join(t.TempDir(), "hooks.db")
+	outPath := path.Join(t.TempDir(), "hook.txt")
+	state := &RuntimeState{Source: ConnState{Name: "my_source"}}
+
+	parse := func(raw map[string]any) (Hook, error) {
+		return ParseHook(raw, ParseOptions{stage: HookStageStart, index: 0, state: state})
+	}
+
+	// sql & check hooks
+	hooks := Hooks{}
+	for _, raw := range []map[string]any{
+		{"type": "sql", "connection": "sqlite://" + dbPath, "query": "create table t (id integer); insert into t values (1)"},
+		{"type": "check", "id": "has_rows", "connection": "sqlite://" + dbPath, "query": "select count(*) > 0 from t"},
+		{"type": "command", "command": "echo {source.name} > " + outPath},
+	} {
+		hook, err := parse(raw)
+		if assert.NoError(t, err) {
+			hooks = append(hooks, hook)
+		}
+	}
+	assert.Equal(t, "start_0", hooks[0].ID())
+	if assert.NoError(t, hooks.Execute()) {
+		assert.Equal(t, "success", state.Hooks["has_rows"]["status"])
+		bytes, _ := os.ReadFile(outPath)
+		assert.Equal(t, "my_source", strings.TrimSpace(string(bytes)))
+	}
+
+	// failing check
+	hook, err := parse(g.M("type", "check", "connection", "sqlite://"+dbPath, "query", "select count(*) = 0 from t", "message", "expected no rows"))
+	if assert.NoError(t, err) {
+		err = Hooks{hook}.Execute()
+		assert.ErrorContains(t, err, "expected no rows")
+	}
+
+	// on_failure: warn
+	hook, err = parse(g.M("type", "command", "command", "exit 1", "on_failure", "warn"))
+	if assert.NoError(t, err) {
+		assert.NoError(t, Hooks{hook}.Execute())
+		assert.Equal(t, "error", state.Hooks["start_0"]["status"])
+	}
+
+	// invalid hooks
+	_, err = parse(g.M("type", "unknown"))
+	assert.ErrorContains(t, err, "unsupported hook type")
+	_, err = parse(g.M("type", "sql"))
+	assert.ErrorContains(t, err, "no query provided")
+	hook, err = parse(g.M("type", "sql", "query", "select 1"))
+	if assert.NoError(t, err) {
+		assert.ErrorContains(t, Hooks{hook}.Execute(), "connection is required")
+	}
+}
+
+func TestDbtHook(t *testing.T) {
+	requests := []*http.Request{}
+	bodies := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+		w.Write([]byte(`{"status": {"code": 200}}`))
+	}))
+	defer server.Close()
+
+	state := &RuntimeState{Runs: map[string]*RunState{
+		"main_orders":    {Status: ExecStatusSuccess, Stream: &StreamState{Name: "main.orders"}},
+		"main_customers": {Status: ExecStatusError, Stream: &StreamState{Name: "main.customers"}},
+	}}
+	parse := func(raw map[string]any) Hook {
+		hook, err := ParseHook(raw, ParseOptions{stage: HookStageEnd, state: state})
+		assert.NoError(t, err)
+		return hook
+	}
+
+	// local dbt cli arguments
+	hook := parse(g.M("type", "dbt", "select", []string{"stg_orders", "+orders"}, "project_dir", "./dbt", "target", "prod"))
+	assert.Equal(t, []string{"run", "--select", "stg_orders", "+orders", "--project-dir", "./dbt", "--target", "prod"}, hook.(*builtinHook).dbtArgs())
+
+	// dbt cloud, triggered when the streams succeeded
+	cloud := g.M("type", "dbt", "url", server.URL, "account_id", "1", "job_id", "2", "token", "secret", "select", []string{"orders"}, "streams", []string{"main.orders"})
+	if assert.NoError(t, Hooks{parse(cloud)}.Execute()) && assert.Len(t, requests, 1) {
+		assert.Equal(t, "/api/v2/accounts/1/jobs/2/run/", requests[0].URL.Path)
+		assert.Equal(t, "Token secret", requests[0].Header.Get("Authorization"))
+		assert.Contains(t, bodies[0], `"steps_override":["dbt run --select orders"]`)
+	}
+
+	// not triggered when a stream failed
+	cloud["streams"] = []string{"main.orders", "main.customers"}
+	assert.NoError(t, Hooks{parse(cloud)}.Execute())
+	assert.Len(t, requests, 1)
+
+	// cloud requires a token
+	_, err := ParseHook(g.M("type", "dbt", "job_id", "2"), ParseOptions{stage: HookStageEnd})
+	assert.ErrorContains(t, err, "token are required")
+}
+
+func TestProgressServer(t *testing.T) {
+	ps := NewProgressServer(0)
+	ps.Interval = 50 * time.Millisecond
+	if !assert.NoError(t, ps.Start()) {
+		return
+	}
+
+	ps.Plan("public.orders", "public.users")
+
+	startTime := time.Now().Add(-10 * time.Second)
+	endTime := time.Now()
+	ps.Track(&TaskExecution{
+		Config:    &Config{StreamName: "public.orders"},
+		Status:    ExecStatusSuccess,
+		StartTime: &startTime,
+		EndTime:   &endTime,
+	})
+
+	baseURL := g.F("http://127.0.0.1:%d", ps.Port)
+	resp, err := http.Get(baseURL + "/progress")
+	if !assert.NoError(t, err) {
+		return
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	var progress Progress
+	if assert.NoError(t, g.Unmarshal(string(body), &progress)) && assert.Len(t, progress.Streams, 2) {
+		assert.Equal(t, ExecStatusRunning, progress.Status)
+		assert.Equal(t, ExecStatusSuccess, progress.Streams[0].Status)
+		assert.Equal(t, ExecStatusQueued, progress.Streams[1].Status)
+		if assert.NotNil(t, progress.ETA) {
+			assert.InDelta(t, 10, *progress.ETA, 0.5) // average of the finished streams
+		}
+	}
+
+	// events are sent until the server closes
+	resp, err = http.Get(baseURL + "/progress/events")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		ps.Close()
+	}()
+	events, _ := io.ReadAll(resp.Body)
+	assert.GreaterOrEqual(t, strings.Count(string(events), "event: progress\n"), 2)
+	assert.Contains(t, string(events), `"stream":"public.users"`)
+}
+
+func TestReplicationEnvProfile(t *testing.T) {
+	yaml := `
+source: DEV_PG
+target: DEV_SNOWFLAKE
+base: &base
+	mode: full-refresh
+defaults:
+	<<: *base
+	object: dev.{stream_table}
+	source_options:
+		empty_as_null: false
+streams:
+	public.users:
+	public.orders:
+		mode: incremental
+		update_key: updated_at
+env:
+	PREFIX: dev
+overrides:
+	prod:
+		source: PROD_PG
+		target: PROD_SNOWFLAKE
+		defaults:
+			object: prod.{stream_table}
+		streams:
+			public.users:
+				mode: snapshot
+		env:
+			PREFIX: prod
+	staging:
+		target: STAGING_SNOWFLAKE
+`
+	yaml = strings.ReplaceAll(yaml, "\t", "  ")
+
+	// no profile, base config
+	replication, err := UnmarshalReplication(yaml)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "DEV_PG", replication.Source)
+		assert.Equal(t, "dev.{stream_table}", replication.Defaults.Object)
+		assert.Equal(t, FullRefreshMode, replication.Defaults.Mode)
+		assert.Equal(t, "dev", replication.Env["PREFIX"])
+		assert.NotContains(t, replication.OriginalCfg(), "overrides")
+	}
+
+	t.Setenv("SLING_ENV_PROFILE", "prod")
+	replication, err = UnmarshalReplication(yaml)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "PROD_PG", replication.Source)
+		assert.Equal(t, "PROD_SNOWFLAKE", replication.Target)
+		assert.Equal(t, "prod.{stream_table}", replication.Defaults.Object)
+		assert.Equal(t, FullRefreshMode, replication.Defaults.Mode) // kept from the anchor
+		if assert.NotNil(t, replication.Defaults.SourceOptions) && assert.NotNil(t, replication.Defaults.SourceOptions.EmptyAsNull) {
+			assert.False(t, *replication.Defaults.SourceOptions.EmptyAsNull)
+		}
+		assert.Equal(t, SnapshotMode, replication.Streams["public.users"].Mode)
+		assert.Equal(t, IncrementalMode, replication.Streams["public.orders"].Mode)
+		assert.Equal(t, "updated_at", replication.Streams["public.orders"].UpdateKey)
+		assert.Equal(t, []string{"public.users", "public.orders"}, replication.streamsOrdered)
+		assert.Equal(t, "prod", replication.Env["PREFIX"])
+	}
+
+	t.Setenv("SLING_ENV_PROFILE", "staging")
+	replication, err = UnmarshalReplication(yaml)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "DEV_PG", replication.Source)
+		assert.Equal(t, "STAGING_SNOWFLAKE", replication.Target)
+	}
+
+	t.Setenv("SLING_ENV_PROFILE", "qa")
+	_, err = UnmarshalReplication(yaml)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "available: prod, staging")
+	}
+}
+
+func TestReplicationTemplate(t *testing.T) {
+	yaml := `
+source: PG
+target: SNOWFLAKE
+vars:
+	tables: [users, orders, payments]
+	region: us
+defaults:
+	mode: full-refresh
+streams:
+{{- range .tables }}
+	public.{{ . }}:
+		object: {{ $.region }}.{{ . | upper }}
+{{- end }}
+{{- if eq .region "eu" }}
+	public.gdpr_requests:
+{{- end }}
+`
+	yaml = strings.ReplaceAll(yaml, "\t", "  ")
+
+	replication, err := UnmarshalReplication(yaml)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"public.users", "public.orders", "public.payments"}, replication.streamsOrdered)
+		assert.Equal(t, "us.ORDERS", replication.Streams["public.orders"].Object)
+	}
+
+	// runtime vars take precedence
+	t.Setenv("SLING_VARS", "region=eu")
+	replication, err = UnmarshalReplication(yaml)
+	if assert.NoError(t, err) {
+		assert.Len(t, replication.streamsOrdered, 4)
+		assert.Equal(t, "eu.USERS", replication.Streams["public.users"].Object)
+	}
+
+	_, err = UnmarshalReplication(strings.ReplaceAll(yaml, ".tables", ".missing"))
+	assert.Error(t, err)
+
+	// opt-in, without a vars section the tags are kept as is
+	plain := strings.ReplaceAll(`
+source: PG
+target: SNOWFLAKE
+streams:
+	public.users:
+		sql: select '{{ .region }}' as region from public.users
+`, "\t", "  ")
+	replication, err = UnmarshalReplication(plain)
+	if assert.NoError(t, err) {
+		assert.Contains(t, replication.Streams["public.users"].SQL, "'{{ .region }}'")
+	}
+
+	t.Setenv("SLING_REPLICATION_TEMPLATE", "true")
+	replication, err = UnmarshalReplication(plain)
+	if assert.NoError(t, err) {
+		assert.Contains(t, replication.Streams["public.users"].SQL, "select 'eu' as region")
+	}
+}
+
+func TestReplicationTags(t *testing.T) {
+	dbPath := path.Join(t.TempDir(), "tags.db")
+
+	yaml := `
+source: sqlite://` + dbPath + `
+target: sqlite://` + dbPath + `
+defaults:
+  object: main.{stream_table}_copy
+  mode: full-refresh
+streams:
+  main.invoices:
+    tags: [finance, hourly]
+  main.payments:
+    tags: [finance, daily]
+  main.events:
+    tags: [hourly]
+`
+	taskStreams := func(selectStreams ...string) []string {
+		replication, err := UnmarshalReplication(yaml)
+		if !assert.NoError(t, err) || !assert.NoError(t, replication.Compile(nil, selectStreams...)) {
+			return nil
+		}
+		streams := lo.Map(replication.Tasks, func(task *Config, i int) string { return task.StreamName })
+		sort.Strings(streams)
+		return streams
+	}
+
+	assert.Equal(t, []string{"main.events", "main.invoices"}, taskStreams("tag:hourly"))
+	assert.Equal(t, []string{"main.events", "main.invoices", "main.payments"}, taskStreams("tag:hourly", "tag:daily"))
+	assert.Equal(t, []string{"main.events"}, taskStreams("-tag:finance"))
+	assert.Equal(t, []string{"main.events", "main.payments"}, taskStreams("main.events", "tag:daily"))
+}
+
+func TestReplicationOnStreamError(t *testing.T) {
+	yaml := `
+source: PG
+target: SNOWFLAKE
+on_stream_error: quarantine
+streams:
+  public.users:
+`
+	replication, err := UnmarshalReplication(yaml)
+	if assert.NoError(t, err) {
+		assert.Equal(t, StreamErrorQuarantine, replication.OnStreamError)
+	}
+
+	replication, err = UnmarshalReplication(strings.ReplaceAll(yaml, "on_stream_error: quarantine\n", ""))
+	if assert.NoError(t, err) {
+		assert.Empty(t, replication.OnStreamError) // continue
+	}
+
+	_, err = UnmarshalReplication(strings.ReplaceAll(yaml, "quarantine", "retry"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "invalid on_stream_error value")
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	bodies := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+	}))
+	defer server.Close()
+
+	yaml := `
+source: PG
+target: SNOWFLAKE
+notifications:
+  - type: slack
+    url: ` + server.URL + `/slack
+  - type: webhook
+    url: ` + server.URL + `/hook
+    events: [success, empty_stream]
+    payload: '{"stream": "{stream_name}", "rows": {rows_written}}'
+streams:
+  public.users:
+`
+	replication, err := UnmarshalReplication(yaml)
+	if !assert.NoError(t, err) || !assert.Len(t, replication.Notifications, 2) {
+		return
+	}
+
+	task := &TaskExecution{
+		Config:      &Config{StreamName: "public.users", Source: Source{Conn: "PG"}, Target: Target{Conn: "SNOWFLAKE"}},
+		Context:     g.NewContext(context.Background()),
+		Replication: &replication,
+	}
+
+	// failure, only slack
+	task.Err = g.Error("connection refused")
+	task.sendNotifications()
+	if assert.Len(t, bodies, 1) {
+		assert.Contains(t, bodies[0], `Sling stream public.users failure | pg`)
+		assert.Contains(t, bodies[0], `error: connection refused`)
+	}
+
+	// success, only the webhook
+	task.Err = nil
+	task.sendNotifications()
+	if assert.Len(t, bodies, 2) {
+		assert.Equal(t, `{"stream": "public.users", "rows": 0}`, bodies[1])
+	}
+
+	_, err = UnmarshalReplication(strings.ReplaceAll(yaml, "type: slack", "type: pager"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,124 @@
+package sling
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+)
+
+// ValidationError is an error found when validating a replication
+type ValidationError struct {
+	Stream  string `json:"stream,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors are the errors found when validating a replication
+type ValidationErrors []ValidationError
+
+// Error returns the errors, one per line
+func (ves ValidationErrors) Error() string {
+	lines := lo.Map(ves, func(ve ValidationError, i int) string {
+		if ve.Stream == "" {
+			return ve.Message
+		}
+		return g.F("%s: %s", ve.Stream, ve.Message)
+	})
+	return strings.Join(lines, "\n")
+}
+
+// Validate parses the streams of the replication and prepares their tasks
+// (connections, wildcards, modes and keys) without running anything,
+// and returns all the errors found, instead of the first one.
+func (rd *ReplicationConfig) Validate() (errs ValidationErrors) {
+	add := func(stream string, err error) {
+		errs = append(errs, ValidationError{Stream: stream, Message: g.ErrMsgSimple(err)})
+	}
+
+	// unknown keys are ignored by the parser, most likely typos
+	validKeys := streamConfigKeys()
+	checkKeys := func(stream string, values map[string]any) {
+		keys := lo.Keys(values)
+		sort.Strings(keys)
+		for _, key := range keys {
+			if !validKeys[key] {
+				add(stream, g.Error("unknown key '%s'", key))
+			}
+		}
+	}
+	checkKeys("defaults", rd.maps.Defaults)
+	for _, name := range rd.StreamsOrdered() {
+		checkKeys(name, rd.maps.Streams[name])
+	}
+
+	// connections, checked once rather than for each stream
+	connsMap := lo.KeyBy(connection.GetLocalConns(), func(c connection.ConnEntry) string {
+		return strings.ToLower(c.Connection.Name)
+	})
+	connsValid := true
+	for _, conn := range []struct{ kind, name string }{{"source", rd.Source}, {"target", rd.Target}} {
+		if conn.name == "" {
+			add("", g.Error("%s connection is not specified", conn.kind))
+			connsValid = false
+		} else if _, found := connsMap[strings.ToLower(conn.name)]; !found && connection.SchemeType(conn.name).IsUnknown() {
+			add("", g.Error("%s connection '%s' not found", conn.kind, conn.name))
+			connsValid = false
+		}
+	}
+	if !connsValid {
+		return errs
+	}
+
+	// wildcards, connects to the source to list the streams
+	if err := rd.ProcessWildcards(); err != nil {
+		add("", g.Error(err, "could not process streams using wildcard"))
+		return errs
+	}
+
+	for _, name := range rd.StreamsOrdered() {
+		stream := ReplicationStreamConfig{}
+		if rd.Streams[name] != nil {
+			stream = *rd.Streams[name]
+		}
+		SetStreamDefaults(name, &stream, *rd)
+		stream.replication = rd
+
+		if stream.Object == "" {
+			add(name, g.Error("need to specify `object`"))
+			continue
+		}
+
+		shardCfgs, err := rd.streamTask(name, &stream, g.ToMapString(rd.Env), "").Shard()
+		if err != nil {
+			add(name, err)
+			continue
+		}
+
+		for _, shardCfg := range shardCfgs {
+			if err = shardCfg.Prepare(); err != nil {
+				add(name, err)
+				break
+			} else if _, err = shardCfg.DetermineType(); err != nil {
+				add(name, err)
+				break
+			}
+		}
+	}
+
+	return errs
+}
+
+// streamConfigKeys returns the valid keys of a stream config (and of the defaults)
+func streamConfigKeys() map[string]bool {
+	keys := map[string]bool{}
+	streamType := reflect.TypeOf(ReplicationStreamConfig{})
+	for i := 0; i < streamType.NumField(); i++ {
+		if key := strings.Split(streamType.Field(i).Tag.Get("yaml"), ",")[0]; key != "" && key != "-" {
+			keys[key] = true
+		}
+	}
+	return keys
+}
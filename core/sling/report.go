@@ -0,0 +1,285 @@
+package sling
+
+import (
+	"bytes"
+	"context"
+	"html"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+	"github.com/slingdata-io/sling-cli/core/dbio/filesys"
+	"github.com/spf13/cast"
+)
+
+// RunReportConfig is the report written after a replication run
+type RunReportConfig struct {
+	Path   string `json:"path" yaml:"path"`                         // local path or url, supports {run_timestamp} and {exec_id}
+	Format string `json:"format,omitempty" yaml:"format,omitempty"` // `html` or `markdown`, from the path extension by default
+}
+
+// Validate checks the report config
+func (rc *RunReportConfig) Validate() error {
+	if strings.TrimSpace(rc.Path) == "" {
+		return g.Error("no path provided for report")
+	}
+
+	rc.Format = strings.ToLower(rc.Format)
+	if rc.Format == "" {
+		switch strings.ToLower(path.Ext(rc.Path)) {
+		case ".html", ".htm":
+			rc.Format = "html"
+		default:
+			rc.Format = "markdown"
+		}
+	}
+
+	switch rc.Format {
+	case "html", "markdown":
+	case "md":
+		rc.Format = "markdown"
+	default:
+		return g.Error("invalid report format: %s (expected html or markdown)", rc.Format)
+	}
+	return nil
+}
+
+// RunReport is the human-readable report of a replication run, for audit trails
+type RunReport struct {
+	Name      string            `json:"name,omitempty"`
+	ExecID    string            `json:"exec_id"`
+	Source    string            `json:"source"`
+	Target    string            `json:"target"`
+	Status    ExecStatus        `json:"status"`
+	StartTime time.Time         `json:"start_time"`
+	EndTime   time.Time         `json:"end_time"`
+	Streams   []RunReportStream `json:"streams"`
+}
+
+// RunReportStream is the result of a stream run in the report
+type RunReportStream struct {
+	Stream        string         `json:"stream"`
+	Object        string         `json:"object,omitempty"`
+	Status        ExecStatus     `json:"status"`
+	Rows          uint64         `json:"rows"`
+	Bytes         uint64         `json:"bytes"`
+	Duration      float64        `json:"duration"` // in seconds
+	Warnings      []string       `json:"warnings,omitempty"`
+	SchemaChanges []SchemaChange `json:"schema_changes,omitempty"`
+	Error         string         `json:"error,omitempty"`
+}
+
+// NewRunReportStream returns the result of a task run, for the report
+func NewRunReportStream(t *TaskExecution, err error) (rs RunReportStream) {
+	inBytes, outBytes := t.GetBytes()
+	rs = RunReportStream{
+		Stream:        t.Config.StreamName,
+		Object:        t.Config.Target.Object,
+		Status:        t.Status,
+		Rows:          t.GetCount(),
+		Bytes:         lo.Ternary(inBytes == 0, outBytes, inBytes),
+		Warnings:      t.Warnings(),
+		SchemaChanges: t.SchemaChanges(),
+	}
+
+	if t.StartTime != nil {
+		endTime := lo.Ternary(t.EndTime != nil, g.PtrVal(t.EndTime), time.Now())
+		rs.Duration = endTime.Sub(*t.StartTime).Seconds()
+	}
+
+	if err == nil {
+		err = t.Err
+	}
+	if err != nil {
+		rs.Status = ExecStatusError
+		rs.Error = g.ErrMsgSimple(err)
+	}
+
+	return rs
+}
+
+// NewRunReport returns the report of a replication run, with the status
+// being an error if any stream failed
+func NewRunReport(replication *ReplicationConfig, execID string, startTime time.Time, streams []RunReportStream) (r RunReport) {
+	r = RunReport{
+		Name:      cast.ToString(replication.Env["SLING_CONFIG_PATH"]),
+		ExecID:    execID,
+		Source:    replication.Source,
+		Target:    replication.Target,
+		Status:    ExecStatusSuccess,
+		StartTime: startTime,
+		EndTime:   time.Now(),
+		Streams:   streams,
+	}
+
+	for _, stream := range streams {
+		if stream.Status.IsFailure() {
+			r.Status = ExecStatusError
+		} else if stream.Status.IsWarning() && r.Status == ExecStatusSuccess {
+			r.Status = ExecStatusWarning
+		}
+	}
+
+	return r
+}
+
+// counts returns the number of succeeded and failed streams, and the total rows
+func (r RunReport) counts() (successes, failures int, rows uint64) {
+	for _, stream := range r.Streams {
+		if stream.Status.IsFailure() {
+			failures++
+		} else {
+			successes++
+		}
+		rows += stream.Rows
+	}
+	return
+}
+
+// Markdown returns the report as markdown
+func (r RunReport) Markdown() string {
+	successes, failures, rows := r.counts()
+	title := lo.Ternary(r.Name != "", r.Name, g.F("%s -> %s", r.Source, r.Target))
+
+	lines := []string{
+		g.F("# Sling Replication Report: %s", title),
+		"",
+		g.F("- **Status**: %s", strings.ToUpper(string(r.Status))),
+		g.F("- **Exec ID**: %s", r.ExecID),
+		g.F("- **Source -> Target**: %s -> %s", r.Source, r.Target),
+		g.F("- **Start Time**: %s", r.StartTime.Format(time.RFC3339)),
+		g.F("- **Duration**: %s", g.DurationString(r.EndTime.Sub(r.StartTime))),
+		g.F("- **Streams**: %d successes, %d failures", successes, failures),
+		g.F("- **Rows**: %s", humanize.Comma(cast.ToInt64(rows))),
+		"",
+		"## Streams",
+		"",
+		"| Stream | Object | Status | Rows | Bytes | Duration |",
+		"|---|---|---|---:|---:|---:|",
+	}
+
+	cell := func(s string) string { return strings.ReplaceAll(s, "|", `\|`) }
+	for _, stream := range r.Streams {
+		lines = append(lines, g.F("| %s | %s | %s | %s | %s | %s |",
+			cell(stream.Stream), cell(stream.Object), strings.ToUpper(string(stream.Status)),
+			humanize.Comma(cast.ToInt64(stream.Rows)), humanize.Bytes(stream.Bytes),
+			g.DurationString(time.Duration(stream.Duration*float64(time.Second)))))
+	}
+
+	for _, stream := range r.Streams {
+		if stream.Error == "" && len(stream.Warnings) == 0 && len(stream.SchemaChanges) == 0 {
+			continue
+		}
+
+		lines = append(lines, "", g.F("### %s", stream.Stream))
+		if stream.Error != "" {
+			lines = append(lines, "", "**Error**:", "", "```", strings.TrimSpace(stream.Error), "```")
+		}
+		if len(stream.SchemaChanges) > 0 {
+			lines = append(lines, "", "**Schema Changes**:", "")
+			for _, change := range stream.SchemaChanges {
+				lines = append(lines, "- "+change.String())
+			}
+		}
+		if len(stream.Warnings) > 0 {
+			lines = append(lines, "", "**Warnings**:", "")
+			for _, warning := range stream.Warnings {
+				lines = append(lines, "- "+warning)
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// HTML returns the report as a standalone HTML page
+func (r RunReport) HTML() string {
+	successes, failures, rows := r.counts()
+	title := lo.Ternary(r.Name != "", r.Name, g.F("%s -> %s", r.Source, r.Target))
+	esc := html.EscapeString
+	colors := map[bool]string{true: "#c0392b", false: "#27ae60"}
+
+	b := &strings.Builder{}
+	b.WriteString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\">")
+	b.WriteString(g.F("<title>Sling Replication Report: %s</title>", esc(title)))
+	b.WriteString(`<style>body{font-family:sans-serif;margin:2em}table{border-collapse:collapse}td,th{border:1px solid #ccc;padding:4px 8px;text-align:left}td.n{text-align:right}pre{background:#f6f6f6;padding:8px;white-space:pre-wrap}</style>`)
+	b.WriteString("</head><body>\n")
+	b.WriteString(g.F("<h1>Sling Replication Report: %s</h1>\n<ul>\n", esc(title)))
+	b.WriteString(g.F("<li><b>Status</b>: <span style=\"color:%s\">%s</span></li>\n", colors[r.Status.IsFailure()], esc(strings.ToUpper(string(r.Status)))))
+	b.WriteString(g.F("<li><b>Exec ID</b>: %s</li>\n", esc(r.ExecID)))
+	b.WriteString(g.F("<li><b>Source -> Target</b>: %s -> %s</li>\n", esc(r.Source), esc(r.Target)))
+	b.WriteString(g.F("<li><b>Start Time</b>: %s</li>\n", r.StartTime.Format(time.RFC3339)))
+	b.WriteString(g.F("<li><b>Duration</b>: %s</li>\n", g.DurationString(r.EndTime.Sub(r.StartTime))))
+	b.WriteString(g.F("<li><b>Streams</b>: %d successes, %d failures</li>\n", successes, failures))
+	b.WriteString(g.F("<li><b>Rows</b>: %s</li>\n</ul>\n", humanize.Comma(cast.ToInt64(rows))))
+
+	b.WriteString("<h2>Streams</h2>\n<table>\n<tr><th>Stream</th><th>Object</th><th>Status</th><th>Rows</th><th>Bytes</th><th>Duration</th></tr>\n")
+	for _, stream := range r.Streams {
+		b.WriteString(g.F("<tr><td>%s</td><td>%s</td><td style=\"color:%s\">%s</td><td class=\"n\">%s</td><td class=\"n\">%s</td><td class=\"n\">%s</td></tr>\n",
+			esc(stream.Stream), esc(stream.Object), colors[stream.Status.IsFailure()], esc(strings.ToUpper(string(stream.Status))),
+			humanize.Comma(cast.ToInt64(stream.Rows)), humanize.Bytes(stream.Bytes),
+			g.DurationString(time.Duration(stream.Duration*float64(time.Second)))))
+	}
+	b.WriteString("</table>\n")
+
+	for _, stream := range r.Streams {
+		if stream.Error == "" && len(stream.Warnings) == 0 && len(stream.SchemaChanges) == 0 {
+			continue
+		}
+
+		b.WriteString(g.F("<h3>%s</h3>\n", esc(stream.Stream)))
+		if stream.Error != "" {
+			b.WriteString(g.F("<p><b>Error</b>:</p>\n<pre>%s</pre>\n", esc(strings.TrimSpace(stream.Error))))
+		}
+		if len(stream.SchemaChanges) > 0 {
+			b.WriteString("<p><b>Schema Changes</b>:</p>\n<ul>\n")
+			for _, change := range stream.SchemaChanges {
+				b.WriteString(g.F("<li>%s</li>\n", esc(change.String())))
+			}
+			b.WriteString("</ul>\n")
+		}
+		if len(stream.Warnings) > 0 {
+			b.WriteString("<p><b>Warnings</b>:</p>\n<ul>\n")
+			for _, warning := range stream.Warnings {
+				b.WriteString(g.F("<li>%s</li>\n", esc(warning)))
+			}
+			b.WriteString("</ul>\n")
+		}
+	}
+
+	b.WriteString("</body></html>\n")
+	return b.String()
+}
+
+// WriteReport writes the report of a run to the report path, on the target
+// connection if its scheme matches, and returns the written location
+func (rd *ReplicationConfig) WriteReport(ctx context.Context, report RunReport) (location string, err error) {
+	if rd.Report == nil {
+		return "", nil
+	}
+
+	location = strings.TrimSpace(g.Rm(rd.Report.Path, g.M(
+		"run_timestamp", report.StartTime.Format("2006_01_02_150405"),
+		"exec_id", report.ExecID,
+	)))
+
+	var props []string
+	if conn := connection.GetLocalConns().Get(rd.Target); conn.Connection.Type == connection.SchemeType(location) {
+		props = g.MapToKVArr(conn.Connection.DataS())
+	}
+	fs, err := filesys.NewFileSysClientFromURLContext(ctx, location, props...)
+	if err != nil {
+		return location, g.Error(err, "could not obtain client for %s", location)
+	}
+
+	content := lo.Ternary(rd.Report.Format == "html", report.HTML(), report.Markdown())
+	if _, err = fs.Self().Write(location, bytes.NewReader([]byte(content))); err != nil {
+		return location, g.Error(err, "could not write report to %s", location)
+	}
+
+	return location, nil
+}
@@ -0,0 +1,82 @@
+package sling
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunReport(t *testing.T) {
+	folder := t.TempDir()
+	initTestSqlite(t, "SLING_REPORT_TEST_DB", `
+		create table users (id integer, name text, email text);
+		insert into users values (1, 'a', 'a@x.com');
+		create table users_copy (id integer, name text);
+	`)
+
+	replication, err := UnmarshalReplication(g.F(`
+source: SLING_REPORT_TEST_DB
+target: SLING_REPORT_TEST_DB
+report: %s/{exec_id}.md
+streams:
+  main.users:
+    object: main.users_copy
+    mode: truncate
+  main.missing:
+    object: main.missing_copy
+`, folder))
+	if !assert.NoError(t, err) || !assert.NoError(t, replication.Compile(nil)) {
+		return
+	}
+	assert.Equal(t, "markdown", replication.Report.Format)
+
+	startTime := time.Now()
+	streams := []RunReportStream{}
+	for _, cfg := range replication.Tasks {
+		task := NewTask("exec1", cfg)
+		task.Replication = &replication
+		err := task.Execute()
+		streams = append(streams, NewRunReportStream(task, err))
+	}
+
+	// the new column is a schema change
+	if assert.Len(t, streams, 2) {
+		assert.Equal(t, ExecStatusSuccess, streams[0].Status)
+		assert.EqualValues(t, 1, streams[0].Rows)
+		if assert.Len(t, streams[0].SchemaChanges, 1) {
+			assert.Equal(t, "email", streams[0].SchemaChanges[0].Column)
+			assert.Equal(t, SchemaEvolutionAdd, streams[0].SchemaChanges[0].Action)
+		}
+		assert.Equal(t, ExecStatusError, streams[1].Status)
+		assert.NotEmpty(t, streams[1].Error)
+	}
+
+	report := NewRunReport(&replication, "exec1", startTime, streams)
+	assert.Equal(t, ExecStatusError, report.Status)
+
+	location, err := replication.WriteReport(context.Background(), report)
+	if assert.NoError(t, err) {
+		assert.Equal(t, path.Join(folder, "exec1.md"), location)
+		data, _ := os.ReadFile(location)
+		assert.Contains(t, string(data), `| main.users | "main"."users_copy" | SUCCESS | 1 |`)
+		assert.Contains(t, string(data), "- new column email")
+		assert.Contains(t, string(data), "1 successes, 1 failures")
+	}
+
+	replication.Report = &RunReportConfig{Path: path.Join(folder, "report.html")}
+	if assert.NoError(t, replication.Report.Validate()) {
+		_, err = replication.WriteReport(context.Background(), report)
+		if assert.NoError(t, err) {
+			data, _ := os.ReadFile(path.Join(folder, "report.html"))
+			assert.Contains(t, string(data), "<td>main.users</td>")
+			assert.Contains(t, string(data), "<li>new column email")
+		}
+	}
+
+	assert.Error(t, (&RunReportConfig{Path: "report.pdf", Format: "pdf"}).Validate())
+}
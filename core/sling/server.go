@@ -0,0 +1,225 @@
+package sling
+
+import (
+	"context"
+	"crypto/subtle"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/apache/arrow/go/v16/arrow/ipc"
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// FlightServer serves sling over Arrow Flight (gRPC), so that other services
+// can use sling as a long-running transfer service:
+//   - action `RunTask`: runs a task config (JSON or YAML) in the background, returns its exec_id
+//   - action `GetStatus`: returns the status of a task, by exec_id
+//   - action `CancelTask`: cancels a running task, by exec_id
+//   - DoGet (StreamRows): streams the rows of a source as arrow record batches, the
+//     ticket being the source as JSON, e.g. `{"conn": "PG", "stream": "public.users"}`
+type FlightServer struct {
+	flight.BaseFlightServer
+	Token string // the bearer token required from clients, if set
+
+	ctx   context.Context // the serve context, parent of the tasks
+	tasks map[string]*TaskExecution
+	mux   sync.Mutex
+}
+
+// FlightTaskStatus is the status of a task run by the server
+type FlightTaskStatus struct {
+	ExecID    string     `json:"exec_id"`
+	Status    ExecStatus `json:"status"`
+	Error     string     `json:"error,omitempty"`
+	Rows      uint64     `json:"rows"`
+	Bytes     uint64     `json:"bytes"`
+	Progress  string     `json:"progress,omitempty"`
+	StartTime *time.Time `json:"start_time,omitempty"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+}
+
+var flightActions = []*flight.ActionType{
+	{Type: "RunTask", Description: "run a task config (JSON or YAML) in the background, returns the exec_id"},
+	{Type: "GetStatus", Description: "return the status of a task, by exec_id"},
+	{Type: "CancelTask", Description: "cancel a running task, by exec_id"},
+}
+
+// NewFlightServer creates a server
+func NewFlightServer(token string) *FlightServer {
+	return &FlightServer{Token: token, ctx: context.Background(), tasks: map[string]*TaskExecution{}}
+}
+
+// Serve serves on the listener, until the context is done. The running
+// tasks are cancelled when the context is done.
+func (s *FlightServer) Serve(ctx context.Context, listener net.Listener) (err error) {
+	s.mux.Lock()
+	s.ctx = ctx
+	s.mux.Unlock()
+
+	server := flight.NewServerWithMiddleware(nil)
+	server.RegisterFlightService(s)
+	server.InitListener(listener)
+
+	go func() {
+		<-ctx.Done()
+		server.Shutdown()
+	}()
+
+	g.Info("serving on %s (arrow flight)", listener.Addr())
+	if err = server.Serve(); err != nil {
+		return g.Error(err, "could not serve")
+	}
+	return nil
+}
+
+// authorize checks the bearer token of the request
+func (s *FlightServer) authorize(ctx context.Context) error {
+	if s.Token == "" {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, val := range md.Get("authorization") {
+		token := strings.TrimSpace(strings.TrimPrefix(val, "Bearer "))
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid or missing token")
+}
+
+func (s *FlightServer) ListActions(_ *flight.Empty, stream flight.FlightService_ListActionsServer) (err error) {
+	if err = s.authorize(stream.Context()); err != nil {
+		return err
+	}
+
+	for _, action := range flightActions {
+		if err = stream.Send(action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *FlightServer) DoAction(action *flight.Action, stream flight.FlightService_DoActionServer) (err error) {
+	if err = s.authorize(stream.Context()); err != nil {
+		return err
+	}
+
+	var result any
+	switch action.Type {
+	case "RunTask":
+		execID, err := s.runTask(string(action.Body))
+		if err != nil {
+			return status.Error(codes.InvalidArgument, g.ErrMsg(err))
+		}
+		result = g.M("exec_id", execID)
+	case "GetStatus", "CancelTask":
+		task := s.getTask(strings.TrimSpace(string(action.Body)))
+		if task == nil {
+			return status.Errorf(codes.NotFound, "task not found: %s", string(action.Body))
+		}
+		if action.Type == "CancelTask" && task.Context != nil {
+			task.Context.Cancel()
+		}
+		result = taskStatus(task)
+	default:
+		return status.Errorf(codes.Unimplemented, "unknown action: %s", action.Type)
+	}
+
+	return stream.Send(&flight.Result{Body: []byte(g.Marshal(result))})
+}
+
+// runTask starts a task in the background
+func (s *FlightServer) runTask(cfgStr string) (execID string, err error) {
+	cfg := &Config{}
+	if err = cfg.Unmarshal(cfgStr); err != nil {
+		return "", g.Error(err, "invalid task config")
+	}
+	cfg.Options.Embedded = true // do not set the config env into the server process
+
+	task := NewTask("", cfg)
+	if task.Err != nil {
+		return "", g.Error(task.Err, "could not init task")
+	}
+
+	s.mux.Lock()
+	task.Context = g.NewContext(s.ctx)
+	for id, t := range s.tasks {
+		// forget the tasks finished for a day
+		if t.EndTime != nil && time.Since(*t.EndTime) > 24*time.Hour {
+			delete(s.tasks, id)
+		}
+	}
+	s.tasks[task.ExecID] = task
+	s.mux.Unlock()
+
+	go func() {
+		if err := task.Execute(); err != nil {
+			g.LogError(err, "task %s failed", task.ExecID)
+		}
+	}()
+
+	return task.ExecID, nil
+}
+
+func (s *FlightServer) getTask(execID string) *TaskExecution {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.tasks[execID]
+}
+
+func taskStatus(task *TaskExecution) (ts FlightTaskStatus) {
+	ts = FlightTaskStatus{
+		ExecID:    task.ExecID,
+		Status:    task.Status,
+		Rows:      task.GetCount(),
+		Progress:  task.Progress,
+		StartTime: task.StartTime,
+		EndTime:   task.EndTime,
+	}
+	ts.Bytes, _ = task.GetTotalBytes()
+	if task.Err != nil {
+		ts.Error = g.ErrMsg(task.Err)
+	}
+	return ts
+}
+
+// DoGet streams the rows of a source (StreamRows), the ticket being the source as JSON
+func (s *FlightServer) DoGet(ticket *flight.Ticket, stream flight.FlightService_DoGetServer) (err error) {
+	if err = s.authorize(stream.Context()); err != nil {
+		return err
+	}
+
+	cfg := &Config{}
+	if err = g.Unmarshal(string(ticket.Ticket), &cfg.Source); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid ticket, expected the source as JSON: %s", err.Error())
+	}
+	cfg.Options.Embedded = true
+
+	err = Stream(stream.Context(), cfg, func(df *iop.Dataflow) (cnt uint64, err error) {
+		ds := iop.MergeDataflow(df)
+		writer := iop.NewArrowRecordWriter(flight.NewRecordWriter(stream, ipc.WithSchema(iop.ArrowSchema(ds.Columns))), ds.Columns)
+		for row := range ds.Rows() {
+			if err = writer.WriteRow(row); err != nil {
+				return cnt, g.Error(err, "could not write rows")
+			}
+			cnt++
+		}
+		if err = writer.Close(); err != nil {
+			return cnt, g.Error(err, "could not write rows")
+		}
+		return cnt, ds.Err()
+	})
+	if err != nil {
+		return status.Error(codes.Internal, g.ErrMsg(err))
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package sling
+
+import (
+	"context"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v16/arrow/flight"
+	"github.com/flarco/g"
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestFlightServer(t *testing.T) {
+	folder := t.TempDir()
+	srcPath := path.Join(folder, "users.csv")
+	assert.NoError(t, os.WriteFile(srcPath, []byte("id,name\n1,alice\n2,bob\n"), 0644))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server := NewFlightServer("secret")
+	go server.Serve(ctx, listener)
+
+	client, err := flight.NewClientWithMiddleware(listener.Addr().String(), nil, nil, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer client.Close()
+
+	// the token is required
+	actions, err := client.ListActions(ctx, &flight.Empty{})
+	if assert.NoError(t, err) {
+		_, err = actions.Recv()
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	}
+	authCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+
+	doAction := func(actionType, body string) (result map[string]any, err error) {
+		stream, err := client.DoAction(authCtx, &flight.Action{Type: actionType, Body: []byte(body)})
+		if err != nil {
+			return nil, err
+		}
+		res, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		return g.UnmarshalMap(string(res.Body))
+	}
+
+	// run a task, and poll its status
+	cfg := g.Marshal(g.M("source", g.M("conn", "local", "stream", "file://"+srcPath), "target", g.M("conn", "local", "object", "file://"+path.Join(folder, "out.jsonl")), "env", g.M("SLING_FLIGHT_TEST", "1")))
+	result, err := doAction("RunTask", cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	execID := cast.ToString(result["exec_id"])
+	for i := 0; i < 100 && !g.In(ExecStatus(cast.ToString(result["status"])), ExecStatusSuccess, ExecStatusError); i++ {
+		time.Sleep(100 * time.Millisecond)
+		result, err = doAction("GetStatus", execID)
+		assert.NoError(t, err)
+	}
+	assert.EqualValues(t, ExecStatusSuccess, result["status"], result["error"])
+	assert.EqualValues(t, 2, result["rows"])
+	assert.Empty(t, os.Getenv("SLING_FLIGHT_TEST")) // embedded, the env is not set into the process
+
+	_, err = doAction("GetStatus", "unknown")
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// stream the rows of a source
+	stream, err := client.DoGet(authCtx, &flight.Ticket{Ticket: []byte(g.Marshal(g.M("conn", "local", "stream", "file://"+srcPath)))})
+	if !assert.NoError(t, err) {
+		return
+	}
+	reader, err := flight.NewRecordReader(stream)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer reader.Release()
+
+	rows := int64(0)
+	for reader.Next() {
+		rows += reader.Record().NumRows()
+	}
+	assert.NoError(t, reader.Err())
+	assert.EqualValues(t, 2, rows)
+	assert.Equal(t, "name", reader.Schema().Field(1).Name)
+
+	// the tasks are cancelled with the server
+	cancel()
+	assert.Error(t, server.getTask(execID).Context.Ctx.Err())
+}
@@ -0,0 +1,188 @@
+package sling
+
+import (
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/filesys"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/slingdata-io/sling-cli/core/env"
+)
+
+// sourceCachePrefix is the prefix of the cache pseudo-connection, which wraps
+// another source connection, e.g. `cache://MY_PG?ttl=6h`
+const sourceCachePrefix = "cache://"
+
+// SourceCache caches the source stream results locally (as parquet files),
+// to speed up repeated runs against slow sources. An entry is refreshed
+// after its TTL, or when the incremental value changes.
+type SourceCache struct {
+	Conn string        // the wrapped connection
+	TTL  time.Duration // 0 means no expiry
+	Dir  string        // the folder of the cache files
+}
+
+// ParseSourceCache parses a cache pseudo-connection.
+// Returns nil if the connection is not a cache connection.
+func ParseSourceCache(connName string) (sc *SourceCache, err error) {
+	if !strings.HasPrefix(strings.ToLower(connName), sourceCachePrefix) {
+		return nil, nil
+	}
+
+	u, err := url.Parse(sourceCachePrefix + connName[len(sourceCachePrefix):])
+	if err != nil {
+		return nil, g.Error(err, "could not parse cache connection: %s", connName)
+	}
+
+	sc = &SourceCache{
+		Conn: u.Host,
+		TTL:  24 * time.Hour,
+		Dir:  path.Join(env.HomeDir, "cache"),
+	}
+	if sc.Conn == "" {
+		return nil, g.Error("no connection provided to cache: %s", connName)
+	}
+
+	if val := u.Query().Get("ttl"); val != "" {
+		if sc.TTL, err = parseDuration(val); err != nil {
+			return nil, g.Error(err, "invalid cache ttl: %s", val)
+		}
+	}
+	if val := u.Query().Get("dir"); val != "" {
+		sc.Dir = val
+	}
+
+	return sc, nil
+}
+
+// keys returns the key of the stream, and the key of the entry (which includes
+// the incremental value, so that a new value invalidates the previous entries)
+func (sc *SourceCache) keys(cfg *Config) (streamKey, entryKey string) {
+	streamKey = g.MD5(
+		strings.ToLower(sc.Conn),
+		cfg.StreamName,
+		cfg.Source.Stream,
+		cfg.Source.Query,
+		cfg.Source.Where,
+		g.Marshal(cfg.Source.Select),
+		g.Marshal(cfg.Source.Options),
+		string(cfg.Mode),
+		cfg.Source.UpdateKey,
+	)
+	entryKey = g.MD5(cfg.IncrementalVal, g.F("%t", cfg.IncrementalGTE))
+	return
+}
+
+// path returns the file path of the cache entry
+func (sc *SourceCache) path(cfg *Config) string {
+	streamKey, entryKey := sc.keys(cfg)
+	return path.Join(sc.Dir, g.F("%s_%s.parquet", streamKey[:16], entryKey[:8]))
+}
+
+// fresh returns true if the cache entry exists and has not expired
+func (sc *SourceCache) fresh(cfg *Config) bool {
+	stat, err := os.Stat(sc.path(cfg))
+	if err != nil {
+		return false
+	}
+	return sc.TTL == 0 || time.Since(stat.ModTime()) < sc.TTL
+}
+
+// invalidate deletes the other entries of the stream
+func (sc *SourceCache) invalidate(cfg *Config) {
+	streamKey, _ := sc.keys(cfg)
+	paths, _ := filepath.Glob(path.Join(sc.Dir, streamKey[:16]+"_*.parquet"))
+	for _, filePath := range paths {
+		if filePath != sc.path(cfg) {
+			os.Remove(filePath)
+		}
+	}
+}
+
+// readSourceCache reads the stream from the local cache, if fresh
+func (t *TaskExecution) readSourceCache(cfg *Config) (df *iop.Dataflow, ok bool) {
+	sc := cfg.sourceCache
+	if sc == nil || !sc.fresh(cfg) {
+		return nil, false
+	}
+
+	df, err := t.openSourceCache(cfg)
+	if err != nil {
+		g.Warn("could not read from local cache: %s", err.Error())
+		return nil, false
+	}
+
+	g.Info("reading %s from local cache (%s)", cfg.StreamName, sc.path(cfg))
+	return df, true
+}
+
+// openSourceCache returns the dataflow reading from the cache entry
+func (t *TaskExecution) openSourceCache(cfg *Config) (df *iop.Dataflow, err error) {
+	return t.readLocalParquet(cfg.sourceCache.path(cfg))
+}
+
+// writeSourceCache writes the source stream into the local cache,
+// and returns the dataflow reading from the cache entry
+func (t *TaskExecution) writeSourceCache(cfg *Config, srcDf *iop.Dataflow) (df *iop.Dataflow, err error) {
+	sc := cfg.sourceCache
+	if sc == nil {
+		return srcDf, nil
+	}
+
+	g.Debug("writing %s into local cache (%s)", cfg.StreamName, sc.path(cfg))
+	if err = writeLocalParquet(srcDf, sc.path(cfg)); err != nil {
+		return nil, g.Error(err, "could not write to local cache")
+	}
+	sc.invalidate(cfg)
+
+	return t.openSourceCache(cfg)
+}
+
+// readLocalParquet returns the dataflow reading a local parquet file
+func (t *TaskExecution) readLocalParquet(filePath string) (df *iop.Dataflow, err error) {
+	fs, err := filesys.NewFileSysClient(dbio.TypeFileLocal)
+	if err != nil {
+		return nil, g.Error(err, "could not init local file system")
+	}
+
+	df, err = fs.ReadDataflow("file://"+filePath, iop.FileStreamConfig{Format: dbio.FileTypeParquet})
+	if err != nil {
+		return nil, g.Error(err, "could not read %s", filePath)
+	} else if err = t.setColumnKeys(df); err != nil {
+		return nil, g.Error(err, "could not set column keys")
+	}
+
+	return df, nil
+}
+
+// writeLocalParquet writes the dataflow into a local parquet file. It writes into
+// a temp file first, so that a failed run does not leave a partial file.
+func writeLocalParquet(df *iop.Dataflow, filePath string) (err error) {
+	if err = os.MkdirAll(path.Dir(filePath), 0755); err != nil {
+		return g.Error(err, "could not create folder")
+	}
+
+	fs, err := filesys.NewFileSysClient(dbio.TypeFileLocal, "FORMAT=parquet")
+	if err != nil {
+		return g.Error(err, "could not init local file system")
+	}
+
+	tempPath := filePath + ".tmp"
+	if _, err = filesys.WriteDataflow(fs, df, "file://"+tempPath); err != nil {
+		os.Remove(tempPath)
+		return g.Error(err, "could not write %s", filePath)
+	} else if err = df.Err(); err != nil {
+		os.Remove(tempPath)
+		return g.Error(err, "could not read source stream")
+	} else if err = os.Rename(tempPath, filePath); err != nil {
+		return g.Error(err, "could not write %s", filePath)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package sling
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceCache(t *testing.T) {
+	sc, err := ParseSourceCache("MY_PG")
+	assert.NoError(t, err)
+	assert.Nil(t, sc)
+
+	sc, err = ParseSourceCache("cache://MY_PG?ttl=2d")
+	if assert.NoError(t, err) && assert.NotNil(t, sc) {
+		assert.Equal(t, "MY_PG", sc.Conn)
+		assert.Equal(t, 48*time.Hour, sc.TTL)
+	}
+
+	_, err = ParseSourceCache("cache://MY_PG?ttl=abc")
+	assert.Error(t, err)
+
+	// read through the cache, with the native parquet reader
+	os.Setenv("SLING_DUCKDB_COMPUTE", "false")
+	defer os.Unsetenv("SLING_DUCKDB_COMPUTE")
+
+	cacheDir := t.TempDir()
+	dbConn := initTestSqlite(t, "SLING_CACHE_TEST_DB", `create table orders (id integer, name text); insert into orders values (1, 'a'), (2, 'b')`)
+
+	run := func() (count uint64) {
+		_, err := runTestTask(&Config{
+			Source: Source{Conn: "cache://SLING_CACHE_TEST_DB?dir=" + cacheDir, Stream: "main.orders"},
+			Target: Target{Conn: "SLING_CACHE_TEST_DB", Object: "main.orders_copy"},
+			Mode:   FullRefreshMode,
+		})
+		if assert.NoError(t, err) {
+			count, _ = dbConn.GetCount("main.orders_copy")
+		}
+		return count
+	}
+
+	assert.EqualValues(t, 2, run())
+	files, _ := os.ReadDir(cacheDir)
+	assert.Len(t, files, 1)
+
+	// new rows are not read until the entry expires
+	_, err = dbConn.Exec(`insert into orders values (3, 'c')`)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, run())
+}
@@ -4,13 +4,16 @@ import (
 	"math"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dustin/go-humanize"
 	"github.com/flarco/g"
+	"github.com/rs/zerolog"
 	"github.com/segmentio/ksuid"
 	"github.com/slingdata-io/sling-cli/core/dbio"
 	"github.com/slingdata-io/sling-cli/core/dbio/database"
+	"github.com/slingdata-io/sling-cli/core/dbio/filesys"
 	"github.com/slingdata-io/sling-cli/core/dbio/iop"
 	"github.com/slingdata-io/sling-cli/core/env"
 	"github.com/spf13/cast"
@@ -40,12 +43,17 @@ type TaskExecution struct {
 	lastIncrement time.Time       // the time of last row increment (to determine stalling)
 	Output        strings.Builder `json:"-"`
 	OutputLines   chan *g.LogLine
+	warnings      []string          // the warnings logged during the run
+	schemaChanges []SchemaChange    // the schema changes of the target table
+	fileState     *FileState        // the state of the loaded files to save (file_incremental)
+	readFiles     filesys.FileNodes // the source files read (post_read)
 
 	Replication    *ReplicationConfig `json:"replication"`
 	ProgressHist   []string           `json:"progress_hist"`
 	PBar           *ProgressBar       `json:"-"`
 	ProcStatsStart g.ProcStats        `json:"-"` // process stats at beginning
 	cleanupFuncs   []func()
+	mux            sync.RWMutex // guards Status, Progress, StartTime, EndTime, df and warnings, read while running
 }
 
 // ExecutionStatus is an execution status object
@@ -105,7 +113,7 @@ func NewTask(execID string, cfg *Config) (t *TaskExecution) {
 		return
 	}
 
-	if ShowProgress {
+	if ShowProgress && !cfg.Options.Embedded {
 		// progress bar ticker
 		t.PBar = NewPBar(time.Second)
 		ticker1s := time.NewTicker(1 * time.Second)
@@ -143,8 +151,10 @@ func NewTask(execID string, cfg *Config) (t *TaskExecution) {
 // SetProgress sets the progress
 func (t *TaskExecution) SetProgress(progressText string, args ...interface{}) {
 	progressText = g.F(progressText, args...)
+	t.mux.Lock()
 	t.ProgressHist = append(t.ProgressHist, progressText)
 	t.Progress = progressText
+	t.mux.Unlock()
 	if !t.PBar.started || t.PBar.finished {
 		if strings.HasSuffix(progressText, "failed") {
 			progressText = env.RedString(progressText)
@@ -228,17 +238,28 @@ func (t *TaskExecution) IsStalled(window float64) bool {
 
 // GetBytes return the current total of bytes processed
 func (t *TaskExecution) GetBytes() (inBytes, outBytes uint64) {
-	if t.df == nil {
+	df := t.Df()
+	if df == nil {
 		return
 	}
 
-	inBytes, outBytes = t.df.Bytes()
+	inBytes, outBytes = df.Bytes()
 	return
 }
 
 func (t *TaskExecution) AppendOutput(ll *g.LogLine) {
 	t.Output.WriteString(ll.Line() + "\n") // add new-line char
 
+	if ll.Level == zerolog.WarnLevel {
+		text := ll.Text
+		if len(ll.Args) > 0 {
+			text = g.F(ll.Text, ll.Args...)
+		}
+		t.mux.Lock()
+		t.warnings = append(t.warnings, text)
+		t.mux.Unlock()
+	}
+
 	// push line if not full
 	select {
 	case t.OutputLines <- ll:
@@ -246,6 +267,47 @@ func (t *TaskExecution) AppendOutput(ll *g.LogLine) {
 	}
 }
 
+// Warnings returns the warnings logged during the run
+func (t *TaskExecution) Warnings() []string {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+	return append([]string{}, t.warnings...)
+}
+
+// GetStatus returns the status, safe to call while running
+func (t *TaskExecution) GetStatus() ExecStatus {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+	return t.Status
+}
+
+// SetStatus sets the status, safe to call while running
+func (t *TaskExecution) SetStatus(status ExecStatus) {
+	t.mux.Lock()
+	t.Status = status
+	t.mux.Unlock()
+}
+
+// GetProgress returns the current progress text, safe to call while running
+func (t *TaskExecution) GetProgress() string {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+	return t.Progress
+}
+
+// GetTimes returns the start and end times, safe to call while running
+func (t *TaskExecution) GetTimes() (start, end *time.Time) {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
+	return t.StartTime, t.EndTime
+}
+
+// SchemaChanges returns the schema changes of the target table during the run,
+// with the action of the schema_evolution policy
+func (t *TaskExecution) SchemaChanges() []SchemaChange {
+	return t.schemaChanges
+}
+
 func (t *TaskExecution) GetBytesString() (s string) {
 	inBytes, _ := t.GetBytes()
 	if inBytes == 0 {
@@ -260,18 +322,49 @@ func (t *TaskExecution) GetBytesString() (s string) {
 
 // GetCount return the current count of rows processed
 func (t *TaskExecution) GetCount() (count uint64) {
-	if t.StartTime == nil {
+	if start, _ := t.GetTimes(); start == nil {
 		return
 	}
 
-	return t.df.Count()
+	return t.Df().Count()
+}
+
+// RunMetadata returns the current run values (exec_id, status, start_time,
+// rows_written, bytes_written), to be used in post_sql and hooks
+func (t *TaskExecution) RunMetadata() map[string]any {
+	m := g.M(
+		"exec_id", t.ExecID,
+		"status", string(t.Status),
+		"start_time", "",
+		"rows_written", uint64(0),
+		"bytes_written", uint64(0),
+	)
+
+	if t.StartTime != nil {
+		m["start_time"] = t.StartTime.Format("2006-01-02 15:04:05.000000")
+	}
+
+	if t.df != nil {
+		m["rows_written"] = t.GetCount()
+		m["bytes_written"], _ = t.GetBytes()
+	}
+
+	return m
 }
 
 // Df return the dataflow object
 func (t *TaskExecution) Df() *iop.Dataflow {
+	t.mux.RLock()
+	defer t.mux.RUnlock()
 	return t.df
 }
 
+func (t *TaskExecution) setDf(df *iop.Dataflow) {
+	t.mux.Lock()
+	t.df = df
+	t.mux.Unlock()
+}
+
 // Data return the dataset object
 func (t *TaskExecution) Data() *iop.Dataset {
 	return t.data
@@ -283,10 +376,11 @@ func (t *TaskExecution) GetRate(secWindow int) (rowRate, byteRate int64) {
 	var secElapsed float64
 	count := t.GetCount()
 	bytes, _ := t.GetBytes()
-	if t.StartTime == nil || t.StartTime.IsZero() {
+	start, end := t.GetTimes()
+	if start == nil || start.IsZero() {
 		return
-	} else if t.EndTime == nil || t.EndTime.IsZero() {
-		st := *t.StartTime
+	} else if end == nil || end.IsZero() {
+		st := *start
 		if secWindow <= 0 {
 			secElapsed = time.Since(st).Seconds()
 			rowRate = cast.ToInt64(math.Round(cast.ToFloat64(count) / secElapsed))
@@ -301,8 +395,8 @@ func (t *TaskExecution) GetRate(secWindow int) (rowRate, byteRate int64) {
 			t.prevByteCount = bytes
 		}
 	} else {
-		st := *t.StartTime
-		et := *t.EndTime
+		st := *start
+		et := *end
 		secElapsed = cast.ToFloat64(et.UnixNano()-st.UnixNano()) / 1000000000.0
 		rowRate = cast.ToInt64(math.Round(cast.ToFloat64(count) / secElapsed))
 		byteRate = cast.ToInt64(math.Round(cast.ToFloat64(bytes) / secElapsed))
@@ -311,29 +405,68 @@ func (t *TaskExecution) GetRate(secWindow int) (rowRate, byteRate int64) {
 }
 
 func (t *TaskExecution) setGetMetadata() (metadata iop.Metadata) {
+	mo := t.Config.Target.Options.Metadata
+	if mo == nil {
+		mo = &MetadataOptions{}
+	}
+
 	if t.Config.MetadataLoadedAt != nil && *t.Config.MetadataLoadedAt {
-		metadata.LoadedAt.Key = slingLoadedAtColumn
-		if os.Getenv("SLING_LOADED_AT_COLUMN") == "timestamp" {
+		metadata.LoadedAt.Key = t.Config.loadedAtColumn()
+		loadedAtType := t.Config.GetEnv("SLING_LOADED_AT_COLUMN")
+		if mo.LoadedAtType != "" {
+			loadedAtType = strings.ToLower(mo.LoadedAtType)
+		}
+		if loadedAtType == "timestamp" {
 			metadata.LoadedAt.Value = *t.StartTime
 		} else {
 			metadata.LoadedAt.Value = t.StartTime.Unix()
 		}
 	}
 	if t.Config.MetadataStreamURL {
-		metadata.StreamURL.Key = slingStreamURLColumn
+		metadata.StreamURL.Key = metadataColumnName(mo.StreamURLColumnName, slingStreamURLColumn)
 	}
 
 	if t.Config.MetadataRowID {
-		metadata.RowID.Key = slingRowIDColumn
+		metadata.RowID.Key = metadataColumnName(mo.RowIDColumnName, slingRowIDColumn)
 	}
 
 	if t.Config.MetadataExecID {
-		metadata.ExecID.Key = slingExecIDColumn
+		metadata.ExecID.Key = metadataColumnName(mo.ExecIDColumnName, slingExecIDColumn)
 		metadata.ExecID.Value = t.ExecID
 	}
 
 	if t.Config.MetadataRowNum {
-		metadata.RowNum.Key = slingRowNumColumn
+		metadata.RowNum.Key = metadataColumnName(mo.RowNumColumnName, slingRowNumColumn)
+	}
+
+	if t.Config.MetadataRowHash {
+		metadata.RowHash.Key = metadataColumnName(mo.RowHashColumnName, slingRowHashColumn)
+	}
+
+	if t.Config.MetadataFileLine {
+		metadata.FileLine.Key = metadataColumnName(mo.FileLineColumnName, slingFileLineColumn)
+	}
+
+	if sk := t.Config.Target.Options.SurrogateKey; sk != nil {
+		metadata.SurrogateKey = *sk
+		if sk.Name == "" {
+			metadata.SurrogateKey.Name = slingSurrogateKeyColumn
+		}
+		if sk.Type == "" {
+			metadata.SurrogateKey.Type = iop.SurrogateKeyHash
+		}
+		if metadata.SurrogateKey.Type == iop.SurrogateKeyHash && len(sk.Columns) == 0 {
+			metadata.SurrogateKey.Columns = t.Config.Source.PrimaryKey()
+		}
+	}
+
+	// policy when a metadata column name already exists in source
+	if val := t.Config.GetEnv("SLING_METADATA_COLLISION"); val != "" {
+		metadata.Collision = iop.MetadataCollision(strings.ToLower(val))
+		if !metadata.Collision.IsValid() {
+			g.Warn("invalid SLING_METADATA_COLLISION value (%s), using `suffix`", val)
+			metadata.Collision = iop.MetadataCollisionSuffix
+		}
 	}
 
 	// StarRocks: add _sling_row_id column if there is no primary,
@@ -355,14 +488,47 @@ func (t *TaskExecution) setGetMetadata() (metadata iop.Metadata) {
 		}
 
 		if addRowIDCol {
-			metadata.RowID.Key = slingRowIDColumn
-			t.Config.Target.Options.TableKeys[iop.HashKey] = []string{slingRowIDColumn}
+			metadata.RowID.Key = metadataColumnName(mo.RowIDColumnName, slingRowIDColumn)
+			t.Config.Target.Options.TableKeys[iop.HashKey] = []string{metadata.RowID.Key}
 		}
 	}
 
 	return metadata
 }
 
+// registerTokenVaults registers the token vaults referenced as
+// `target:<table>` in the tokenize / detokenize transforms, so that
+// the tokens are stored in a table of the target database
+func (t *TaskExecution) registerTokenVaults() {
+	for _, transforms := range t.Config.TransformsPrepared() {
+		for _, transform := range transforms {
+			transform = strings.ReplaceAll(strings.TrimSpace(transform), " ", "")
+			if !(strings.HasPrefix(transform, "tokenize") || strings.HasPrefix(transform, "detokenize")) {
+				continue
+			}
+
+			// tokenize(target:schema.table) or tokenize:target:schema.table
+			vaultName := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(transform, "de"), "tokenize"), "("), ")")
+			vaultName = strings.TrimPrefix(vaultName, ":")
+			if !strings.HasPrefix(strings.ToLower(vaultName), "target:") {
+				continue
+			} else if !t.Config.TgtConn.Type.IsDb() {
+				g.Warn("cannot use token vault %s since target is not a database", vaultName)
+				continue
+			}
+
+			tableName := vaultName[len("target:"):]
+			iop.RegisterTokenVault(vaultName, database.NewTableTokenVault(tableName, func() (database.Connection, error) {
+				conn, err := t.Config.TgtConn.AsDatabaseContext(t.Context.Ctx, t.isUsingPool())
+				if err != nil {
+					return nil, g.Error(err, "could not initialize target connection")
+				}
+				return conn, conn.Connect()
+			}))
+		}
+	}
+}
+
 func (t *TaskExecution) isUsingPool() bool {
 	if val := os.Getenv("SLING_POOL"); val != "" && !cast.ToBool(val) {
 		return false
@@ -431,14 +597,20 @@ func (t *TaskExecution) isIncrementalWithUpdateKey() bool {
 	return t.Config.Source.HasUpdateKey() && t.Config.Mode == IncrementalMode
 }
 
+// useState means the incremental values are read from / saved into the sling state
+// (env var `SLING_STATE`), or into the shared store (env var `SLING_STATE_CONNECTION`)
+func useState() bool {
+	return os.Getenv("SLING_STATE") != "" || os.Getenv("SLING_STATE_CONNECTION") != ""
+}
+
 // isIncrementalStateWithUpdateKey means it has an update_key, with provided sling state and is incremental mode
 func (t *TaskExecution) isIncrementalStateWithUpdateKey() bool {
-	return os.Getenv("SLING_STATE") != "" && t.isIncrementalWithUpdateKey()
+	return useState() && t.isIncrementalWithUpdateKey()
 }
 
 // hasStateWithUpdateKey means it has an update_key and with provided sling state
 func (t *TaskExecution) hasStateWithUpdateKey() bool {
-	return os.Getenv("SLING_STATE") != "" && t.Config.Source.HasUpdateKey()
+	return useState() && t.Config.Source.HasUpdateKey()
 }
 
 func (t *TaskExecution) getOptionsMap() (options map[string]any) {
@@ -454,10 +626,103 @@ func (t *TaskExecution) getOptionsMap() (options map[string]any) {
 		// set as string so that StreamProcessor parses it
 		options["transforms"] = g.Marshal(colTransforms)
 	}
+
+	if t.Config.Source.Options != nil && len(t.Config.Source.Options.ComputedColumns) > 0 {
+		// set as string so that StreamProcessor parses it
+		options["computed_columns"] = g.Marshal(t.Config.Source.Options.ComputedColumns)
+	}
+
+	if t.Config.Source.Options != nil && len(t.Config.Source.Options.TailOffsets) > 0 {
+		// set as string so that the file system client parses it
+		options["tail_offsets"] = g.Marshal(t.Config.Source.Options.TailOffsets)
+	}
+
+	if t.Config.IsSharded() && t.Config.Target.Options.ShardIndex != nil {
+		// only the rows of the shard are read
+		options["shard_count"] = *t.Config.Target.Options.Shards
+		options["shard_index"] = *t.Config.Target.Options.ShardIndex
+		options["shard_key"] = strings.Join(t.Config.Target.Options.ShardKey, ",")
+	}
 	return
 }
 
+// apply column mapping (renames source columns to target names)
+func applyColumnMappingToDf(df *iop.Dataflow, mapping map[string]string) (err error) {
+	if len(mapping) == 0 {
+		return nil
+	}
+
+	// match source column names case-insensitively
+	mappingLower := map[string]string{}
+	for oldName, newName := range mapping {
+		if newName = strings.TrimSpace(newName); newName != "" {
+			mappingLower[strings.ToLower(strings.TrimSpace(oldName))] = newName
+		}
+	}
+
+	rename := func(name string) string {
+		if newName, ok := mappingLower[strings.ToLower(name)]; ok {
+			return newName
+		}
+		return name
+	}
+
+	// ensure no duplicate names
+	names := map[string]string{}
+	for _, col := range df.Columns {
+		newName := rename(col.Name)
+		if prevName, ok := names[strings.ToLower(newName)]; ok {
+			return g.Error("column mapping results in duplicate column name '%s' (from %s and %s)", newName, prevName, col.Name)
+		}
+		names[strings.ToLower(newName)] = col.Name
+	}
+
+	for i, col := range df.Columns {
+		df.Columns[i].Name = rename(col.Name)
+	}
+
+	// propagate names to streams
+	for _, ds := range df.Streams {
+		for i, col := range ds.Columns {
+			ds.Columns[i].Name = rename(col.Name)
+		}
+
+		if ds.CurrentBatch != nil {
+			for i, col := range ds.CurrentBatch.Columns {
+				ds.CurrentBatch.Columns[i].Name = rename(col.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
 // apply column casing
+// applyColumnMatchToDf renames the columns to the matching columns of the existing
+// target table, so they are not added as new columns (e.g. `OrderID` into `order_id`)
+func applyColumnMatchToDf(df *iop.Dataflow, tgtColumns iop.Columns, match *ColumnMatch) (err error) {
+	if match == nil || *match == ColumnMatchExact || len(tgtColumns) == 0 {
+		return nil
+	}
+
+	tgtNames := map[string]string{}
+	for _, col := range tgtColumns {
+		tgtNames[match.Key(col.Name)] = col.Name
+	}
+
+	mapping := map[string]string{}
+	for _, col := range df.Columns {
+		if tgtName, ok := tgtNames[match.Key(col.Name)]; ok && tgtName != col.Name {
+			mapping[col.Name] = tgtName
+		}
+	}
+
+	if err = applyColumnMappingToDf(df, mapping); err != nil {
+		return g.Error(err, "could not match columns with column_match=%s", *match)
+	}
+	return nil
+}
+
 func applyColumnCasingToDf(df *iop.Dataflow, connType dbio.Type, casing *iop.ColumnCasing) {
 
 	if casing == nil {
@@ -0,0 +1,327 @@
+package sling
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+)
+
+// CompareOptions configures the comparison of two streams
+type CompareOptions struct {
+	PrimaryKey []string `json:"primary_key,omitempty"` // defaults to the source primary key
+	Buckets    int      `json:"buckets,omitempty"`     // number of primary key buckets, default 64
+	Rows       bool     `json:"rows,omitempty"`        // report the differing rows, requires a primary key
+	MaxDiffs   int      `json:"max_diffs,omitempty"`   // maximum number of row diffs reported, default 100
+}
+
+// CompareReport is the result of comparing a source and target stream
+type CompareReport struct {
+	Source            string          `json:"source"`
+	Target            string          `json:"target"`
+	Match             bool            `json:"match"`
+	SourceRows        int64           `json:"source_rows"`
+	TargetRows        int64           `json:"target_rows"`
+	PrimaryKey        []string        `json:"primary_key,omitempty"`
+	Columns           []string        `json:"columns"` // the compared columns, common to both
+	SourceOnlyColumns []string        `json:"source_only_columns,omitempty"`
+	TargetOnlyColumns []string        `json:"target_only_columns,omitempty"`
+	Buckets           int             `json:"buckets"`
+	BucketDiffs       []CompareBucket `json:"bucket_diffs,omitempty"`
+	RowDiffs          []RowDiff       `json:"row_diffs,omitempty"`
+}
+
+// CompareBucket is a primary key bucket whose checksums differ
+type CompareBucket struct {
+	Bucket     int      `json:"bucket"`
+	SourceRows int64    `json:"source_rows"`
+	TargetRows int64    `json:"target_rows"`
+	Columns    []string `json:"columns,omitempty"` // the columns whose checksums differ
+}
+
+// RowDiffStatus is the status of a differing row
+type RowDiffStatus string
+
+const (
+	RowDiffMissingInTarget RowDiffStatus = "missing_in_target"
+	RowDiffMissingInSource RowDiffStatus = "missing_in_source"
+	RowDiffChanged         RowDiffStatus = "changed"
+)
+
+// RowDiff is a row which differs between the source and target
+type RowDiff struct {
+	Key     string        `json:"key"`
+	Status  RowDiffStatus `json:"status"`
+	Columns []string      `json:"columns,omitempty"` // the changed columns
+}
+
+// compareSide is the checksums of a stream
+type compareSide struct {
+	rows    int64
+	columns []string                     // lower case names
+	buckets []map[string]uint64          // column checksums per bucket
+	counts  []int64                      // rows per bucket
+	keys    map[string]map[string]uint64 // column hashes per key, for row diffs
+}
+
+// Compare reads the source and target streams, and compares the row counts,
+// the column checksums per primary key bucket and optionally the rows
+func Compare(source, target *Config, opts CompareOptions) (report CompareReport, err error) {
+	if opts.Buckets <= 0 {
+		opts.Buckets = 64
+	}
+	if opts.MaxDiffs <= 0 {
+		opts.MaxDiffs = 100
+	}
+	if len(opts.PrimaryKey) == 0 {
+		opts.PrimaryKey = source.Source.PrimaryKey()
+	}
+	if len(opts.PrimaryKey) == 0 {
+		opts.Buckets = 1 // checksums of the whole stream
+		if opts.Rows {
+			return report, g.Error("a primary key is required to compare rows")
+		}
+	}
+
+	report = CompareReport{
+		Source:     source.Source.Conn + " / " + source.Source.Stream,
+		Target:     target.Source.Conn + " / " + target.Source.Stream,
+		PrimaryKey: opts.PrimaryKey,
+		Buckets:    opts.Buckets,
+		Columns:    []string{},
+	}
+
+	src, err := readCompareSide(source, opts)
+	if err != nil {
+		return report, g.Error(err, "could not read source stream")
+	}
+
+	tgt, err := readCompareSide(target, opts)
+	if err != nil {
+		return report, g.Error(err, "could not read target stream")
+	}
+
+	report.SourceRows, report.TargetRows = src.rows, tgt.rows
+	for _, col := range src.columns {
+		if lo.Contains(tgt.columns, col) {
+			report.Columns = append(report.Columns, col)
+		} else {
+			report.SourceOnlyColumns = append(report.SourceOnlyColumns, col)
+		}
+	}
+	report.TargetOnlyColumns = lo.Without(tgt.columns, src.columns...)
+
+	for b := 0; b < opts.Buckets; b++ {
+		bucket := CompareBucket{Bucket: b, SourceRows: src.counts[b], TargetRows: tgt.counts[b]}
+		for _, col := range report.Columns {
+			if src.buckets[b][col] != tgt.buckets[b][col] {
+				bucket.Columns = append(bucket.Columns, col)
+			}
+		}
+		if bucket.SourceRows != bucket.TargetRows || len(bucket.Columns) > 0 {
+			report.BucketDiffs = append(report.BucketDiffs, bucket)
+		}
+	}
+
+	if opts.Rows {
+		report.RowDiffs = compareRows(src, tgt, report.Columns, opts.MaxDiffs)
+	}
+
+	report.Match = report.SourceRows == report.TargetRows && len(report.BucketDiffs) == 0
+	return report, nil
+}
+
+// readCompareSide reads a stream and computes its checksums
+func readCompareSide(cfg *Config, opts CompareOptions) (side *compareSide, err error) {
+	task, err := newSourceTask(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer task.Cleanup()
+
+	df, err := task.readSource()
+	if err != nil {
+		return nil, err
+	}
+	defer df.Close()
+
+	side = &compareSide{
+		buckets: make([]map[string]uint64, opts.Buckets),
+		counts:  make([]int64, opts.Buckets),
+		keys:    map[string]map[string]uint64{},
+	}
+	for b := range side.buckets {
+		side.buckets[b] = map[string]uint64{}
+	}
+
+	var keyIndexes []int
+	ds := iop.MergeDataflow(df)
+	for row := range ds.Rows() {
+		if side.columns == nil {
+			for _, col := range ds.Columns {
+				side.columns = append(side.columns, strings.ToLower(col.Name))
+			}
+			for _, key := range opts.PrimaryKey {
+				index := lo.IndexOf(side.columns, strings.ToLower(key))
+				if index < 0 {
+					return nil, g.Error("primary key column %s not found in %s", key, cfg.Source.Stream)
+				}
+				keyIndexes = append(keyIndexes, index)
+			}
+		}
+
+		keyParts := make([]string, len(keyIndexes))
+		for i, index := range keyIndexes {
+			keyParts[i] = compareValue(row[index], ds.Columns[index].Type)
+		}
+		key := strings.Join(keyParts, "|")
+
+		bucket := int(compareHash(key) % uint64(len(side.buckets)))
+		side.counts[bucket]++
+		side.rows++
+
+		var hashes map[string]uint64
+		if opts.Rows {
+			hashes = map[string]uint64{}
+			side.keys[key] = hashes
+		}
+
+		for i, val := range row {
+			if i >= len(side.columns) {
+				break
+			}
+			// bound to the key, so values swapped between rows are caught
+			hash := compareHash(key + "\x00" + compareValue(val, ds.Columns[i].Type))
+			side.buckets[bucket][side.columns[i]] += hash
+			if hashes != nil {
+				hashes[side.columns[i]] = hash
+			}
+		}
+	}
+
+	if err = ds.Err(); err != nil {
+		return nil, g.Error(err, "could not read rows")
+	} else if err = df.Err(); err != nil {
+		return nil, g.Error(err, "could not read rows")
+	}
+
+	return side, nil
+}
+
+// compareRows returns the rows which differ, sorted by key
+func compareRows(src, tgt *compareSide, columns []string, maxDiffs int) (diffs []RowDiff) {
+	keys := lo.Union(lo.Keys(src.keys), lo.Keys(tgt.keys))
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if len(diffs) >= maxDiffs {
+			break
+		}
+
+		srcHashes, inSource := src.keys[key]
+		tgtHashes, inTarget := tgt.keys[key]
+		switch {
+		case !inTarget:
+			diffs = append(diffs, RowDiff{Key: key, Status: RowDiffMissingInTarget})
+		case !inSource:
+			diffs = append(diffs, RowDiff{Key: key, Status: RowDiffMissingInSource})
+		default:
+			diff := RowDiff{Key: key, Status: RowDiffChanged}
+			for _, col := range columns {
+				if srcHashes[col] != tgtHashes[col] {
+					diff.Columns = append(diff.Columns, col)
+				}
+			}
+			if len(diff.Columns) > 0 {
+				diffs = append(diffs, diff)
+			}
+		}
+	}
+
+	return diffs
+}
+
+// compareValue normalizes a value, so that equal values read from
+// different connections (types, precision, time zones) are equal
+func compareValue(val any, typ iop.ColumnType) string {
+	if val == nil {
+		return "\x01" // distinct from an empty string
+	}
+
+	switch v := val.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case []byte:
+		return string(v)
+	}
+
+	if typ.IsBool() {
+		if b, err := cast.ToBoolE(val); err == nil {
+			return cast.ToString(b)
+		}
+	} else if typ.IsInteger() {
+		if i, err := cast.ToInt64E(val); err == nil {
+			return strconv.FormatInt(i, 10)
+		}
+	} else if typ.IsNumber() {
+		if f, err := cast.ToFloat64E(val); err == nil {
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		}
+	}
+
+	return cast.ToString(val)
+}
+
+func compareHash(val string) uint64 {
+	hasher := fnv.New64a()
+	hasher.Write([]byte(val))
+	return mix64(hasher.Sum64())
+}
+
+// String returns a text report of the comparison
+func (cr CompareReport) String() string {
+	lines := []string{
+		g.F("source:  %s (%d rows)", cr.Source, cr.SourceRows),
+		g.F("target:  %s (%d rows)", cr.Target, cr.TargetRows),
+	}
+	if len(cr.PrimaryKey) > 0 {
+		lines = append(lines, g.F("primary key: %s (%d buckets)", strings.Join(cr.PrimaryKey, ", "), cr.Buckets))
+	}
+	lines = append(lines, g.F("columns: %s", strings.Join(cr.Columns, ", ")))
+	if len(cr.SourceOnlyColumns) > 0 {
+		lines = append(lines, g.F("source only columns: %s", strings.Join(cr.SourceOnlyColumns, ", ")))
+	}
+	if len(cr.TargetOnlyColumns) > 0 {
+		lines = append(lines, g.F("target only columns: %s", strings.Join(cr.TargetOnlyColumns, ", ")))
+	}
+
+	if cr.Match {
+		lines = append(lines, "result:  MATCH")
+		return strings.Join(lines, "\n")
+	}
+	lines = append(lines, g.F("result:  MISMATCH (%d of %d buckets differ)", len(cr.BucketDiffs), cr.Buckets))
+
+	for _, bucket := range cr.BucketDiffs {
+		line := g.F("  bucket %d: %d / %d rows", bucket.Bucket, bucket.SourceRows, bucket.TargetRows)
+		if len(bucket.Columns) > 0 {
+			line += ", columns differ: " + strings.Join(bucket.Columns, ", ")
+		}
+		lines = append(lines, line)
+	}
+
+	for _, diff := range cr.RowDiffs {
+		line := g.F("  row [%s]: %s", diff.Key, diff.Status)
+		if len(diff.Columns) > 0 {
+			line += " (" + strings.Join(diff.Columns, ", ") + ")"
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,53 @@
+package sling
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	t.Setenv("SLING_DUCKDB_COMPUTE", "false")
+	t.Setenv("SLING_LOADED_AT_COLUMN", "false")
+	folder := t.TempDir()
+	os.WriteFile(path.Join(folder, "source.csv"), []byte("id,name,amount\n1,a,1.5\n2,b,2.5\n3,c,3.5\n"), 0644)
+	os.WriteFile(path.Join(folder, "same.csv"), []byte("amount,id,name\n3.50,3,c\n1.5,1,a\n2.5,2,b\n"), 0644)
+	os.WriteFile(path.Join(folder, "other.csv"), []byte("id,name,amount\n1,a,1.5\n2,x,2.5\n4,d,4.5\n"), 0644)
+
+	newConfig := func(name string) *Config {
+		return &Config{Source: Source{Conn: "local", Stream: "file://" + path.Join(folder, name)}}
+	}
+
+	// same rows, in a different order and column order
+	report, err := Compare(newConfig("source.csv"), newConfig("same.csv"), CompareOptions{PrimaryKey: []string{"id"}, Rows: true})
+	if assert.NoError(t, err) {
+		assert.True(t, report.Match, report.String())
+		assert.EqualValues(t, 3, report.SourceRows)
+		assert.Equal(t, []string{"id", "name", "amount"}, report.Columns)
+		assert.Empty(t, report.RowDiffs)
+	}
+
+	report, err = Compare(newConfig("source.csv"), newConfig("other.csv"), CompareOptions{PrimaryKey: []string{"id"}, Rows: true})
+	if assert.NoError(t, err) {
+		assert.False(t, report.Match)
+		assert.NotEmpty(t, report.BucketDiffs)
+		assert.Equal(t, []RowDiff{
+			{Key: "2", Status: RowDiffChanged, Columns: []string{"name"}},
+			{Key: "3", Status: RowDiffMissingInTarget},
+			{Key: "4", Status: RowDiffMissingInSource},
+		}, report.RowDiffs)
+		assert.Contains(t, report.String(), "MISMATCH")
+	}
+
+	// without a primary key, the whole stream is compared
+	report, err = Compare(newConfig("source.csv"), newConfig("other.csv"), CompareOptions{})
+	if assert.NoError(t, err) {
+		assert.False(t, report.Match)
+		assert.Equal(t, 1, report.Buckets)
+	}
+
+	_, err = Compare(newConfig("source.csv"), newConfig("other.csv"), CompareOptions{Rows: true})
+	assert.Error(t, err)
+}
@@ -0,0 +1,237 @@
+package sling
+
+import (
+	"context"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio/database"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+)
+
+// SchemaChange is a change of a target table column, from the source columns
+type SchemaChange struct {
+	Column  string                `json:"column"`
+	Kind    string                `json:"kind"` // `add`, `widen`, `narrow` or `drop` (not in source)
+	OldType string                `json:"old_type,omitempty"`
+	NewType string                `json:"new_type,omitempty"`
+	Action  SchemaEvolutionAction `json:"action"` // from the schema_evolution policy
+}
+
+// String describes the change and its action
+func (sc SchemaChange) String() string {
+	switch sc.Kind {
+	case "add":
+		return g.F("new column %s %s => %s", sc.Column, sc.NewType, sc.Action)
+	case "drop":
+		return g.F("column %s %s is not in source => %s", sc.Column, sc.OldType, sc.Action)
+	default:
+		return g.F("%s column %s %s -> %s => %s", sc.Kind, sc.Column, sc.OldType, sc.NewType, sc.Action)
+	}
+}
+
+// SchemaDiff compares the source columns of a stream to its existing target table
+type SchemaDiff struct {
+	Stream     string         `json:"stream"`
+	Table      string         `json:"table"`
+	Exists     bool           `json:"exists"`
+	Changes    []SchemaChange `json:"changes,omitempty"`
+	Statements []string       `json:"statements,omitempty"` // the DDL of the applied changes
+}
+
+// String returns the changes, and the DDL which would be executed
+func (sd SchemaDiff) String() string {
+	lines := []string{g.F("%s -> %s", sd.Stream, sd.Table)}
+	if !sd.Exists {
+		return lines[0] + "\n  table does not exist, it will be created\n"
+	} else if len(sd.Changes) == 0 {
+		return lines[0] + "\n  no changes\n"
+	}
+
+	for _, change := range sd.Changes {
+		prefix := map[string]string{"add": "+", "drop": "-"}[change.Kind]
+		lines = append(lines, g.F("  %s %s", lo.Ternary(prefix != "", prefix, "~"), change))
+	}
+
+	if len(sd.Statements) > 0 {
+		lines = append(lines, "", "  -- DDL")
+		for _, sql := range sd.Statements {
+			lines = append(lines, "  "+strings.TrimSuffix(strings.TrimSpace(sql), ";")+";")
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// schemaEvolution is the evolution of an existing target table to the stream columns
+type schemaEvolution struct {
+	Changes  []SchemaChange
+	AddDDL   []string    // with on_new_column: add
+	AlterDDL []string    // with on_type_widen: alter
+	Ignored  iop.Columns // new columns not added, with on_new_column: ignore
+	Columns  iop.Columns // the table columns after the applied changes
+}
+
+// Err returns the changes refused by the policy (action `fail`)
+func (se schemaEvolution) Err() error {
+	failed := lo.Filter(se.Changes, func(sc SchemaChange, i int) bool { return sc.Action == SchemaEvolutionFail })
+	if len(failed) == 0 {
+		return nil
+	}
+	lines := lo.Map(failed, func(sc SchemaChange, i int) string { return "  " + sc.String() })
+	return g.Error("schema changes refused by the schema_evolution policy:\n%s", strings.Join(lines, "\n"))
+}
+
+// SchemaDiff compares the source columns to the existing target table, and returns
+// the changes that the schema_evolution policy would perform, without altering anything.
+func (t *TaskExecution) SchemaDiff() (diff SchemaDiff, err error) {
+	if t.Context == nil {
+		t.Context = g.NewContext(context.Background())
+	}
+
+	t.Config.SetDefault()
+	diff = SchemaDiff{Stream: t.Config.StreamLabel(), Table: t.Config.Target.Object}
+
+	if t.Type != DbToDb {
+		return diff, g.Error("schema diff requires a database source and target, not %s", t.Type)
+	}
+
+	srcConn, err := t.getSrcDBConn(t.Context.Ctx)
+	if err != nil {
+		return diff, g.Error(err, "Could not initialize source connection")
+	}
+	if !t.isUsingPool() {
+		defer srcConn.Close()
+	}
+
+	tgtConn, err := t.getTgtDBConn(t.Context.Ctx)
+	if err != nil {
+		return diff, g.Error(err, "Could not initialize target connection")
+	}
+	if !t.isUsingPool() {
+		defer tgtConn.Close()
+	}
+
+	sTable, _, _, err := t.sourceTable(t.Config, srcConn)
+	if err != nil {
+		return diff, g.Error(err, "could not prepare source query")
+	}
+
+	columns, err := streamColumns(t.Config, tgtConn, sTable.Columns)
+	if err != nil {
+		return diff, err
+	}
+
+	targetTable, err := initializeTargetTable(t.Config, tgtConn)
+	if err != nil {
+		return diff, err
+	}
+	diff.Table = targetTable.FullName()
+
+	diff.Exists, err = database.TableExists(tgtConn, targetTable.FullName())
+	if err != nil {
+		return diff, g.Error(err, "could not check table "+targetTable.FullName())
+	} else if !diff.Exists {
+		return diff, nil
+	}
+
+	evolution, err := getSchemaEvolution(t.Config, tgtConn, targetTable, columns)
+	if err != nil {
+		return diff, err
+	}
+	diff.Changes = evolution.Changes
+	diff.Statements = append(evolution.AddDDL, evolution.AlterDDL...)
+
+	return diff, nil
+}
+
+// getSchemaEvolution compares the stream columns to the columns of the existing
+// target table, and sets the action of each change from the schema_evolution policy
+func getSchemaEvolution(cfg *Config, tgtConn database.Connection, targetTable database.Table, columns iop.Columns) (evolution schemaEvolution, err error) {
+	policy := cfg.Target.Options.Evolution()
+	allowNarrowing := g.PtrVal(cfg.Target.Options.AllowTypeNarrowing)
+
+	targetTable.Columns, err = tgtConn.GetSQLColumns(targetTable)
+	if err != nil {
+		return evolution, g.Error(err, "could not get columns of "+targetTable.FullName())
+	}
+
+	// columns missing in the target
+	missing, addDDL, err := database.GetAddColumnsStatements(tgtConn, targetTable, columns)
+	if err != nil {
+		return evolution, g.Error(err, "could not generate add column statements")
+	}
+	for _, col := range missing {
+		nativeType, _ := tgtConn.GetNativeType(col)
+		evolution.Changes = append(evolution.Changes, SchemaChange{Column: col.Name, Kind: "add", NewType: nativeType, Action: policy.OnNewColumn})
+	}
+
+	// columns missing in the source, the scd2 version columns are maintained by sling
+	var versionColumns iop.Columns
+	if cfg.Mode == SCD2Mode {
+		versionColumns = scd2Options(cfg).Columns()
+	}
+	for _, col := range targetTable.Columns {
+		if columns.GetColumn(col.Name) == nil && versionColumns.GetColumn(col.Name) == nil {
+			evolution.Changes = append(evolution.Changes, SchemaChange{Column: col.Name, Kind: "drop", OldType: col.DbType, Action: policy.OnColumnDrop})
+		}
+	}
+
+	// column types, of the columns in both
+	existing := lo.Filter(columns, func(col iop.Column, i int) bool {
+		return targetTable.Columns.GetColumn(col.Name) != nil
+	})
+	typeChanges, err := database.GetColumnTypeChanges(tgtConn, targetTable, existing, false)
+	if err != nil {
+		return evolution, g.Error(err, "could not compare column types")
+	}
+	alter := false
+	for _, change := range typeChanges {
+		sc := SchemaChange{Column: change.Column.Name, Kind: "widen", OldType: change.OldColumn.DbType, NewType: change.Column.DbType, Action: policy.OnTypeWiden}
+		if change.Narrowing {
+			sc.Kind, sc.Action = "narrow", policy.OnTypeNarrow
+			if sc.Action == SchemaEvolutionCast && allowNarrowing && policy.OnTypeWiden == SchemaEvolutionAlter {
+				sc.Action = SchemaEvolutionAlter
+			}
+		}
+		alter = alter || sc.Action == SchemaEvolutionAlter
+		evolution.Changes = append(evolution.Changes, sc)
+	}
+
+	evolution.Columns = targetTable.Columns.Clone()
+	if alter {
+		_, evolution.AlterDDL, err = database.GetOptimizeTableStatements(tgtConn, &targetTable, existing, false)
+		if err != nil {
+			return evolution, g.Error(err, "could not generate column type statements")
+		}
+	}
+
+	switch policy.OnNewColumn {
+	case SchemaEvolutionAdd:
+		evolution.AddDDL = addDDL
+		evolution.Columns = append(evolution.Columns, missing...)
+	case SchemaEvolutionIgnore:
+		evolution.Ignored = missing
+	}
+
+	return evolution, nil
+}
+
+// streamColumns returns the source columns as the stream would be prepared for
+// the target (column mapping, casing and matching)
+func streamColumns(cfg *Config, tgtConn database.Connection, columns iop.Columns) (iop.Columns, error) {
+	df := iop.NewDataflow()
+	df.Columns = append(iop.Columns{}, columns...)
+	if err := applyColumnMappingToDf(df, cfg.Target.Options.ColumnMapping); err != nil {
+		return nil, err
+	}
+	applyColumnCasingToDf(df, tgtConn.GetType(), cfg.Target.Options.ColumnCasing)
+	if match := cfg.Target.Options.ColumnMatch; match != nil && *match != ColumnMatchExact {
+		tgtColumns, _ := tgtConn.GetColumns(cfg.Target.Object) // empty if table does not exist
+		if err := applyColumnMatchToDf(df, tgtColumns, match); err != nil {
+			return nil, err
+		}
+	}
+	return df.Columns, nil
+}
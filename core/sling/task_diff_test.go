@@ -0,0 +1,51 @@
+package sling
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaDiff(t *testing.T) {
+	conn := initTestSqlite(t, "SLING_DIFF_TEST_DB", `
+		create table users (id integer, name text, email text);
+		create table users_copy (id integer, name text, legacy text);
+	`)
+
+	diff := func(object string, evolution *SchemaEvolution) (diff SchemaDiff) {
+		task, err := newTestTask(&Config{
+			Source: Source{Conn: "SLING_DIFF_TEST_DB", Stream: "main.users"},
+			Target: Target{Conn: "SLING_DIFF_TEST_DB", Object: object, Options: &TargetOptions{SchemaEvolution: evolution}},
+			Mode:   FullRefreshMode,
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		diff, err = task.SchemaDiff()
+		assert.NoError(t, err)
+		return diff
+	}
+
+	added := diff("main.users_copy", nil)
+	assert.True(t, added.Exists)
+	assert.Equal(t, []SchemaChange{
+		{Column: "email", Kind: "add", NewType: "text", Action: SchemaEvolutionAdd},
+		{Column: "legacy", Kind: "drop", OldType: "TEXT", Action: SchemaEvolutionIgnore},
+	}, added.Changes)
+	assert.Equal(t, []string{`alter table "main"."users_copy" add column "email" text`}, added.Statements)
+
+	// not applied when ignored
+	skipped := diff("main.users_copy", &SchemaEvolution{OnNewColumn: SchemaEvolutionIgnore, OnColumnDrop: SchemaEvolutionFail})
+	assert.Equal(t, SchemaEvolutionIgnore, skipped.Changes[0].Action)
+	assert.Equal(t, SchemaEvolutionFail, skipped.Changes[1].Action)
+	assert.Empty(t, skipped.Statements)
+	assert.Contains(t, skipped.String(), "new column email text => ignore")
+
+	assert.False(t, diff("main.users_new", nil).Exists)
+
+	// nothing was altered
+	columns, err := conn.GetColumns("main.users_copy")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"id", "name", "legacy"}, columns.Names())
+	}
+}
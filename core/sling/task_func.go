@@ -106,7 +106,22 @@ func pullTargetTempTableColumns(cfg *Config, tgtConn database.Connection, force
 }
 
 func insertFromTemp(cfg *Config, tgtConn database.Connection) (err error) {
-	// insert
+	sql, err := insertFromTempSQL(cfg, tgtConn)
+	if err != nil {
+		return
+	}
+
+	_, err = tgtConn.Exec(sql)
+	if err != nil {
+		err = g.Error(err, "Could not execute SQL: "+sql)
+		return
+	}
+	g.Debug("inserted rows into %s from temp table %s", cfg.Target.Object, cfg.Target.Options.TableTmp)
+	return
+}
+
+// insertFromTempSQL returns the SQL inserting the temp table rows into the target table
+func insertFromTempSQL(cfg *Config, tgtConn database.Connection) (sql string, err error) {
 	tmpColumns, err := tgtConn.GetColumns(cfg.Target.Options.TableTmp)
 	if err != nil {
 		err = g.Error(err, "could not get column list for "+cfg.Target.Options.TableTmp)
@@ -152,29 +167,117 @@ func insertFromTemp(cfg *Config, tgtConn database.Connection) (err error) {
 		return
 	}
 
-	sql := g.R(
+	sql = g.R(
 		tgtConn.Template().Core["insert_from_table"],
 		"tgt_table", tgtTable.FullName(),
 		"src_table", srcTable.FullName(),
 		"tgt_fields", strings.Join(tgtCols.Names(), ", "),
 		"src_fields", strings.Join(srcFields, ", "),
 	)
-	_, err = tgtConn.Exec(sql)
-	if err != nil {
-		err = g.Error(err, "Could not execute SQL: "+sql)
-		return
+
+	if dedup := cfg.Target.Options.Dedup; dedup != nil {
+		values, err := dedupValues(cfg, tgtConn, dedup, tmpColumns)
+		if err != nil {
+			return "", g.Error(err, "could not prepare dedup")
+		}
+
+		sql = g.R(
+			tgtConn.Template().Core["insert_from_table_dedup"],
+			append(values,
+				"tgt_table", tgtTable.FullName(),
+				"src_table", srcTable.FullName(),
+				"tgt_fields", strings.Join(tgtCols.Names(), ", "),
+				"src_fields", strings.Join(srcFields, ", "),
+			)...,
+		)
 	}
-	g.Debug("inserted rows into %s from temp table %s", cfg.Target.Object, cfg.Target.Options.TableTmp)
-	return
+
+	return sql, nil
+}
+
+// dedupValues returns the template values to deduplicate the temp table rows,
+// within the batch and against the existing target rows (in the window)
+func dedupValues(cfg *Config, tgtConn database.Connection, dedup *DedupOptions, tmpColumns iop.Columns) (values []string, err error) {
+	casing := cfg.Target.Options.ColumnCasing
+	normalize := func(name string) string {
+		if casing != nil {
+			name = casing.Apply(name, tgtConn.GetType())
+		}
+		if col := tmpColumns.GetColumn(name); col != nil {
+			name = col.Name
+		}
+		return name
+	}
+
+	keys, keysEqual := []string{}, []string{}
+	for _, key := range dedup.Keys {
+		key = normalize(key)
+		if tmpColumns.GetColumn(key) == nil {
+			return nil, g.Error("dedup key not found: %s", key)
+		}
+		keys = append(keys, tgtConn.Quote(key))
+		keysEqual = append(keysEqual, g.F("tgt.%s = src.%s", tgtConn.Quote(key), tgtConn.Quote(key)))
+	}
+
+	// order by, defaults to the update key (latest first)
+	orderColumns, directions := dedup.OrderByColumns()
+	if len(orderColumns) == 0 && cfg.Source.UpdateKey != "" {
+		orderColumns, directions = []string{cfg.Source.UpdateKey}, []string{"desc"}
+	}
+	orders := []string{}
+	for i, col := range orderColumns {
+		col = normalize(col)
+		if tmpColumns.GetColumn(col) == nil {
+			return nil, g.Error("dedup order_by column not found: %s", col)
+		}
+		orderColumns[i] = col
+		orders = append(orders, tgtConn.Quote(col)+" "+directions[i])
+	}
+	if len(orders) == 0 {
+		orders = keys // no preference
+	}
+
+	// only check the target rows in the window
+	windowFilter := ""
+	if window, err := dedup.WindowDuration(); err != nil {
+		return nil, err
+	} else if window > 0 {
+		if len(orderColumns) == 0 {
+			return nil, g.Error("dedup window requires an order_by column or update_key")
+		}
+		windowStart := time.Now().Add(-window)
+		windowFilter = g.F(
+			" and tgt.%s >= %s", tgtConn.Quote(orderColumns[0]),
+			g.R(
+				tgtConn.GetTemplateValue("variable.timestamp_layout_str"),
+				"value", windowStart.Format(tgtConn.GetTemplateValue("variable.timestamp_layout")),
+			),
+		)
+	}
+
+	srcNames := []string{}
+	for _, col := range tmpColumns {
+		srcNames = append(srcNames, tgtConn.Quote(col.Name))
+	}
+
+	return []string{
+		"src_names", strings.Join(srcNames, ", "),
+		"dedup_keys", strings.Join(keys, ", "),
+		"dedup_order", strings.Join(orders, ", "),
+		"dedup_keys_equal", strings.Join(keysEqual, " and "),
+		"dedup_window", windowFilter,
+	}, nil
 }
 
+// GetIncrementalValueViaState and SetIncrementalValueViaState read and write
+// the incremental value of a stream from / into the sling state (env var `SLING_STATE`)
 var (
-	getIncrementalValueViaState = func(*TaskExecution) (err error) {
+	GetIncrementalValueViaState = func(*TaskExecution) (err error) {
 		g.Warn("use the official release of sling-cli to use incremental via sling state")
 		return nil
 	}
 
-	setIncrementalValueViaState = func(*TaskExecution) (err error) {
+	SetIncrementalValueViaState = func(*TaskExecution) (err error) {
 		g.Warn("use the official release of sling-cli to use incremental via sling state")
 		return nil
 	}
@@ -0,0 +1,309 @@
+package sling
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/database"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+)
+
+// TaskPlan is what a task would execute, without running it (dry-run)
+type TaskPlan struct {
+	Stream    string     `json:"stream"`
+	Type      JobType    `json:"type"`
+	Mode      Mode       `json:"mode,omitempty"`
+	SourceSQL string     `json:"source_sql,omitempty"`
+	SourceURI string     `json:"source_uri,omitempty"`
+	Target    string     `json:"target"` // the target table or file path
+	Steps     []PlanStep `json:"steps,omitempty"`
+	Notes     []string   `json:"notes,omitempty"`
+}
+
+// PlanStep is a SQL statement the task would execute on the target
+type PlanStep struct {
+	Name string `json:"name"`
+	SQL  string `json:"sql"`
+}
+
+func (tp *TaskPlan) add(name, sql string) {
+	if sql = strings.TrimSpace(sql); sql != "" {
+		tp.Steps = append(tp.Steps, PlanStep{Name: name, SQL: sql})
+	}
+}
+
+// String returns the plan as commented SQL
+func (tp TaskPlan) String() string {
+	statement := func(sql string) string {
+		return strings.TrimSuffix(strings.TrimSpace(sql), ";") + ";"
+	}
+
+	lines := []string{g.F("-- stream %s [%s | %s]", tp.Stream, tp.Type, tp.Mode)}
+	if tp.SourceSQL != "" {
+		lines = append(lines, "-- source query", statement(tp.SourceSQL))
+	} else {
+		lines = append(lines, "-- source: "+tp.SourceURI)
+	}
+
+	lines = append(lines, "", "-- target: "+tp.Target)
+	for i, step := range tp.Steps {
+		lines = append(lines, g.F("-- %d. %s", i+1, step.Name), statement(step.SQL), "")
+	}
+
+	for _, note := range tp.Notes {
+		lines = append(lines, "-- note: "+note)
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n")) + "\n"
+}
+
+// Plan returns the SQL statements and file paths the task would execute,
+// without writing anything (dry-run). The source and target databases are
+// only read, for the columns, the incremental value and the existing tables.
+func (t *TaskExecution) Plan() (plan TaskPlan, err error) {
+	if t.Context == nil {
+		t.Context = g.NewContext(context.Background())
+	}
+
+	t.Config.SetDefault()
+	if t.Config.Mode == Mode("") {
+		t.Config.Mode = FullRefreshMode
+	}
+
+	plan = TaskPlan{
+		Stream: t.Config.StreamLabel(),
+		Type:   t.Type,
+		Mode:   t.Config.Mode,
+		Target: t.getTargetObjectValue(),
+	}
+
+	if t.Type == DbSQL {
+		plan.Target = t.Config.TgtConn.Name
+		plan.add("execute sql", t.Config.Target.Object)
+		return plan, nil
+	}
+
+	var tgtConn database.Connection
+	if g.In(t.Type, FileToDB, DbToDb) {
+		tgtConn, err = t.getTgtDBConn(t.Context.Ctx)
+		if err != nil {
+			return plan, g.Error(err, "Could not initialize target connection")
+		}
+		if !t.isUsingPool() {
+			defer tgtConn.Close()
+		}
+	}
+
+	// get watermark
+	if t.isIncrementalStateWithUpdateKey() {
+		if err = GetIncrementalValueViaState(t); err != nil {
+			return plan, g.Error(err, "Could not get incremental value")
+		}
+	} else if t.isIncrementalWithUpdateKey() && tgtConn != nil {
+		srcType := t.Config.SrcConn.Type
+		if t.Type == FileToDB {
+			srcType = dbio.TypeDbDuckDb
+			if t.Config.Source.UpdateKey == "." {
+				t.Config.Source.UpdateKey = t.Config.loadedAtColumn()
+			}
+		}
+		if err = getIncrementalValueViaDB(t.Config, tgtConn, srcType); err != nil {
+			return plan, g.Error(err, "Could not get incremental value")
+		}
+	}
+	if t.Config.IncrementalVal != "" {
+		plan.Notes = append(plan.Notes, g.F("incremental value of %s is %s", t.Config.Source.UpdateKey, t.Config.IncrementalVal))
+	}
+
+	// source query or files
+	var columns iop.Columns
+	if g.In(t.Type, DbToDb, DbToFile) {
+		srcConn, err := t.getSrcDBConn(t.Context.Ctx)
+		if err != nil {
+			return plan, g.Error(err, "Could not initialize source connection")
+		}
+		if !t.isUsingPool() {
+			defer srcConn.Close()
+		}
+
+		sTable, _, useKeyset, err := t.sourceTable(t.Config, srcConn)
+		if err != nil {
+			return plan, g.Error(err, "could not prepare source query")
+		}
+		plan.SourceSQL = lo.Ternary(sTable.SQL != "", sTable.SQL, sTable.Select())
+		if useKeyset {
+			plan.Notes = append(plan.Notes, g.F("source is read in chunks with keyset pagination on %s", t.Config.Source.PrimaryKey()[0]))
+		}
+		columns = sTable.Columns
+	} else if t.Config.Options.StdIn && t.Config.SrcConn.Type.IsUnknown() {
+		plan.SourceURI = "stdin"
+	} else {
+		plan.SourceURI = t.Config.SrcConn.URL()
+	}
+
+	switch {
+	case tgtConn != nil:
+		if err = t.planWriteToDb(&plan, tgtConn, columns); err != nil {
+			return plan, g.Error(err, "could not plan write to %s", t.Config.Target.Object)
+		}
+	case t.Config.Options.StdOut:
+	default:
+		plan.Target = g.Rm(plan.Target, iop.GetISO8601DateMap(time.Now()))
+		if opts := t.Config.Target.Options; g.PtrVal(opts.FileMaxRows) > 0 || g.PtrVal(opts.FileMaxBytes) > 0 {
+			plan.Notes = append(plan.Notes, "target is split into multiple files in folder "+plan.Target)
+		}
+	}
+
+	return plan, nil
+}
+
+// planWriteToDb adds the target statements of WriteToDb to the plan. The columns
+// are those of the source query, nil if only known when reading (files)
+func (t *TaskExecution) planWriteToDb(plan *TaskPlan, tgtConn database.Connection, columns iop.Columns) (err error) {
+	cfg := t.Config
+
+	targetTable, err := initializeTargetTable(cfg, tgtConn)
+	if err != nil {
+		return err
+	}
+
+	tableTmp, err := initializeTempTable(cfg, tgtConn, targetTable)
+	if err != nil {
+		return err
+	}
+
+	exists, err := database.TableExists(tgtConn, targetTable.FullName())
+	if err != nil {
+		return g.Error(err, "could not check table "+targetTable.FullName())
+	}
+
+	// the temp table columns, as the stream would be prepared
+	tmpColumns, err := streamColumns(cfg, tgtConn, columns)
+	if err != nil {
+		return err
+	}
+
+	sample := iop.NewDataset(tmpColumns)
+	sample.Inferred = true
+	direct := useDirectInsert(cfg, false)
+
+	if len(columns) > 0 && !direct {
+		tableTmp.Columns = sample.Columns
+		if err = tableTmp.SetKeys(cfg.Source.PrimaryKey(), cfg.Source.UpdateKey, cfg.Target.Options.TableKeys); err != nil {
+			return g.Error(err, "could not set keys for "+tableTmp.FullName())
+		}
+
+		ddl, err := tgtConn.GenerateDDL(tableTmp, sample, true)
+		if err != nil {
+			return g.Error(err, "could not generate DDL for "+tableTmp.FullName())
+		}
+		plan.add("create temp table", ddl)
+		if err = tgtConn.Base().PlanColumns(tableTmp.FullName(), sample.Columns); err != nil {
+			return err
+		}
+	} else if len(columns) == 0 {
+		plan.Notes = append(plan.Notes, "columns are inferred from the source data at runtime, the temp table DDL and the final SQL are not rendered")
+	}
+
+	if sql := cfg.Target.Options.PreSQL; sql != nil {
+		plan.add("pre_sql", g.Rm(*sql, t.GetStateMap()))
+	}
+
+	// prepare final table
+	if cfg.Mode == FullRefreshMode && exists {
+		plan.add("drop table", g.R(tgtConn.GetTemplateValue("core.drop_table"), "table", targetTable.FullName()))
+	}
+
+	if !exists || cfg.Mode == FullRefreshMode {
+		if len(columns) > 0 {
+			// add the version columns for scd2
+			if cfg.Mode == SCD2Mode {
+				sample.Columns = append(iop.Columns{}, sample.Columns...)
+				for _, col := range scd2Options(cfg).Columns() {
+					col.Position = len(sample.Columns) + 1
+					sample.Columns = append(sample.Columns, col)
+				}
+			}
+
+			ddl, err := tgtConn.GenerateDDL(targetTable, sample, false)
+			if err != nil {
+				return g.Error(err, "could not generate DDL for "+targetTable.FullName())
+			}
+			plan.add("create table", ddl)
+			if err = tgtConn.Base().PlanColumns(targetTable.FullName(), sample.Columns); err != nil {
+				return err
+			}
+		}
+	} else if cfg.Mode == TruncateMode {
+		plan.add("truncate table", g.R(tgtConn.GetTemplateValue("core.truncate_table"), "table", targetTable.FullName()))
+	}
+
+	// schema evolution of the existing table
+	if exists && cfg.Mode != FullRefreshMode && len(columns) > 0 {
+		evolution, err := getSchemaEvolution(cfg, tgtConn, targetTable, sample.Columns)
+		if err != nil {
+			return err
+		}
+		if err = evolution.Err(); err != nil {
+			plan.Notes = append(plan.Notes, g.ErrMsgSimple(err))
+		}
+		plan.add("add new columns", strings.Join(evolution.AddDDL, ";\n"))
+		for _, col := range evolution.Ignored {
+			plan.add("drop ignored column from temp table", g.R(
+				tgtConn.GetTemplateValue("core.drop_column"),
+				"table", tableTmp.FullName(),
+				"column", tgtConn.Quote(col.Name),
+			))
+		}
+		plan.add("adjust column types", strings.Join(evolution.AlterDDL, ";\n"))
+		if len(evolution.AddDDL) > 0 {
+			if err = tgtConn.Base().PlanColumns(targetTable.FullName(), evolution.Columns); err != nil {
+				return err
+			}
+		}
+	}
+
+	// transfer from temp to final
+	if len(columns) > 0 && !direct {
+		var name, sql string
+		switch {
+		case (cfg.Mode == IncrementalMode && len(cfg.Source.PrimaryKey()) == 0) || g.In(cfg.Mode, SnapshotMode, FullRefreshMode, TruncateMode):
+			name = "insert from temp table"
+			sql, err = insertFromTempSQL(cfg, tgtConn)
+		case cfg.Mode == PartitionOverwriteMode:
+			name = "overwrite partitions"
+			sql, err = tgtConn.GeneratePartitionOverwriteSQL(tableTmp.FullName(), targetTable.FullName(), targetPartitionColumn(cfg, tgtConn.GetType()))
+		case cfg.Mode == SCD2Mode:
+			name = "merge scd2 versions"
+			sql, err = tgtConn.GenerateSCD2SQL(tableTmp.FullName(), targetTable.FullName(), scd2Options(cfg))
+		case g.In(cfg.Mode, IncrementalMode, BackfillMode):
+			name = "upsert from temp table"
+			sql, err = tgtConn.GenerateUpsertSQL(tableTmp.FullName(), targetTable.FullName(), targetPrimaryKey(cfg, tgtConn.GetType()))
+		default:
+			err = g.Error("unsupported transfer mode: %s", cfg.Mode)
+		}
+		if err != nil {
+			return g.Error(err, "could not generate SQL from temp table")
+		}
+		plan.add(name, sql)
+	}
+
+	if sql := cfg.Target.Options.PostSQL; sql != nil {
+		plan.add("post_sql", g.Rm(*sql, t.GetStateMap()))
+	}
+
+	if direct {
+		plan.Notes = append(plan.Notes, "rows are inserted directly into "+targetTable.FullName()+", without a temp table (direct_insert)")
+		if opts := cfg.Target.Options; opts.CommitsPeriodically() {
+			plan.Notes = append(plan.Notes, g.F("rows are committed every %d rows / %d seconds (0 is no limit)", g.PtrVal(opts.CommitEveryRows), g.PtrVal(opts.CommitEverySeconds)))
+		}
+	} else {
+		plan.add("drop temp table", g.R(tgtConn.GetTemplateValue("core.drop_table"), "table", tableTmp.FullName()))
+	}
+
+	return nil
+}
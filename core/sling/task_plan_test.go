@@ -0,0 +1,52 @@
+package sling
+
+import (
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskPlan(t *testing.T) {
+	conn := initTestSqlite(t, "SLING_PLAN_TEST_DB", `
+		create table orders (id integer, amount real, updated_at timestamp);
+		insert into orders values (1, 1.5, '2024-01-01 00:00:00');
+		create table orders_copy (id integer, amount real, updated_at timestamp);
+		insert into orders_copy values (1, 1.5, '2024-01-01 00:00:00');
+	`)
+
+	plan := func(mode Mode, object string) (plan TaskPlan) {
+		task, err := newTestTask(&Config{
+			Source: Source{Conn: "SLING_PLAN_TEST_DB", Stream: "main.orders", PrimaryKeyI: "id", UpdateKey: "updated_at"},
+			Target: Target{Conn: "SLING_PLAN_TEST_DB", Object: object},
+			Mode:   mode,
+		})
+		if !assert.NoError(t, err) {
+			return
+		}
+		plan, err = task.Plan()
+		assert.NoError(t, err)
+		return plan
+	}
+	stepNames := func(plan TaskPlan) []string {
+		return lo.Map(plan.Steps, func(s PlanStep, i int) string { return s.Name })
+	}
+
+	// existing table, filtered with the incremental value
+	incremental := plan(IncrementalMode, "main.orders_copy")
+	assert.Contains(t, incremental.SourceSQL, `"updated_at" > '2024-01-01 00:00:00'`)
+	assert.Equal(t, []string{"create temp table", "upsert from temp table", "drop temp table"}, stepNames(incremental))
+	assert.Contains(t, incremental.Steps[1].SQL, `ON CONFLICT ("id")`)
+
+	// new table, created from the source columns
+	fullRefresh := plan(FullRefreshMode, "main.orders_new")
+	assert.Equal(t, []string{"create temp table", "create table", "insert from temp table", "drop temp table"}, stepNames(fullRefresh))
+	assert.Contains(t, fullRefresh.Steps[2].SQL, `insert into "main"."orders_new" ("id", "amount", "updated_at")`)
+	assert.Contains(t, fullRefresh.String(), "-- 2. create table\n")
+
+	// nothing was written
+	tables, err := conn.GetTables("main")
+	if assert.NoError(t, err) {
+		assert.Len(t, tables.Rows, 2)
+	}
+}
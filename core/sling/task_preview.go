@@ -0,0 +1,61 @@
+package sling
+
+import (
+	"context"
+	"os"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+)
+
+// Preview returns the first rows of a source stream, with the inferred column
+// types and the transforms applied, without writing to a target
+func Preview(cfg *Config, limit int) (data iop.Dataset, err error) {
+	cfg.Target = Target{Columns: cfg.Target.Columns, Options: cfg.Target.Options}
+	cfg.Options.StdOut = true
+	cfg.Options.Dataset = true
+	if limit > 0 {
+		if cfg.Source.Options == nil {
+			cfg.Source.Options = &SourceOptions{}
+		}
+		cfg.Source.Options.Limit = g.Int(limit)
+	}
+
+	if err = cfg.Prepare(); err != nil {
+		return data, g.Error(err, "could not set task configuration")
+	}
+
+	task := NewTask(os.Getenv("SLING_EXEC_ID"), cfg)
+	if task.Err != nil {
+		return data, g.Error(task.Err, "could not init task")
+	} else if err = task.Execute(); err != nil {
+		return data, g.Error(err, "could not read source stream")
+	}
+
+	if task.Data() == nil {
+		return iop.NewDataset(nil), nil
+	}
+	return *task.Data(), nil
+}
+
+// Stream reads a source stream, with the transforms applied, and passes the
+// dataflow to the consume function, without writing to a target. The read
+// stops when the context is done
+func Stream(ctx context.Context, cfg *Config, consume func(df *iop.Dataflow) (uint64, error)) (err error) {
+	cfg.Target = Target{Columns: cfg.Target.Columns, Options: cfg.Target.Options}
+	cfg.Options.StdOut = true
+	cfg.Options.Consume = consume
+
+	if err = cfg.Prepare(); err != nil {
+		return g.Error(err, "could not set task configuration")
+	}
+
+	task := NewTask(os.Getenv("SLING_EXEC_ID"), cfg)
+	task.Context = g.NewContext(ctx)
+	if task.Err != nil {
+		return g.Error(task.Err, "could not init task")
+	} else if err = task.Execute(); err != nil {
+		return g.Error(err, "could not read source stream")
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package sling
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreview(t *testing.T) {
+	t.Setenv("SLING_DUCKDB_COMPUTE", "false")
+	t.Setenv("SLING_LOADED_AT_COLUMN", "false")
+	folder := t.TempDir()
+	os.WriteFile(path.Join(folder, "data.csv"), []byte("id,name,amount\n1,a,1.5\n2,b,2.5\n3,c,3.5\n"), 0644)
+
+	cfg := &Config{
+		Source:     Source{Conn: "local", Stream: "file://" + path.Join(folder, "data.csv")},
+		Transforms: []string{"upper"},
+	}
+	data, err := Preview(cfg, 2)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []string{"id", "name", "amount"}, data.Columns.Names())
+	assert.Equal(t, []string{"id [bigint]", "name [text]", "amount [decimal]"}, data.Columns.Types())
+	if assert.Len(t, data.Rows, 2) {
+		assert.Equal(t, "A", data.Rows[0][1])
+	}
+
+	// no target is written
+	entries, _ := os.ReadDir(folder)
+	assert.Len(t, entries, 1)
+}
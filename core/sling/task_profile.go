@@ -0,0 +1,350 @@
+package sling
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+)
+
+// StreamProfile is the per-column statistics of a source stream
+type StreamProfile struct {
+	Stream  string          `json:"stream"`
+	Rows    int64           `json:"rows"`
+	Columns []ColumnProfile `json:"columns"`
+}
+
+// ColumnProfile is the statistics of a column
+type ColumnProfile struct {
+	Name          string         `json:"name"`
+	Type          iop.ColumnType `json:"type"`
+	NullCount     int64          `json:"null_count"`
+	NullPercent   float64        `json:"null_percent"`
+	DistinctCount uint64         `json:"distinct_count"` // estimate, via HyperLogLog
+	Min           any            `json:"min"`
+	Max           any            `json:"max"`
+	MinLength     int            `json:"min_length"`
+	MaxLength     int            `json:"max_length"`
+	AvgLength     float64        `json:"avg_length"`
+	Lengths       []LengthBucket `json:"lengths"` // distribution of the value lengths
+
+	hll       *hyperLogLog
+	values    int64
+	lengthSum int64
+}
+
+// LengthBucket is the count of values with a length within a range
+type LengthBucket struct {
+	Range string `json:"range"`
+	Count int64  `json:"count"`
+}
+
+// lengthBuckets are the upper bounds of the length ranges
+var lengthBuckets = []int{0, 8, 16, 32, 64, 128, 256, 1024, math.MaxInt}
+
+// Profile streams the source and returns the statistics of each column
+// (null rate, distinct estimate, min/max, length distribution), without a target
+func Profile(cfg *Config, limit int) (profile StreamProfile, err error) {
+	if limit > 0 {
+		if cfg.Source.Options == nil {
+			cfg.Source.Options = &SourceOptions{}
+		}
+		cfg.Source.Options.Limit = g.Int(limit)
+	}
+
+	task, err := newSourceTask(cfg)
+	if err != nil {
+		return profile, err
+	}
+
+	return task.Profile()
+}
+
+// newSourceTask initializes a task which only reads the source stream
+func newSourceTask(cfg *Config) (task *TaskExecution, err error) {
+	cfg.Target = Target{Columns: cfg.Target.Columns, Options: cfg.Target.Options}
+	cfg.Options.StdOut = true
+
+	if err = cfg.Prepare(); err != nil {
+		return nil, g.Error(err, "could not set task configuration")
+	}
+
+	task = NewTask(os.Getenv("SLING_EXEC_ID"), cfg)
+	if task.Err != nil {
+		return nil, g.Error(task.Err, "could not init task")
+	}
+
+	return task, nil
+}
+
+// readSource reads the source stream of a task from newSourceTask.
+// The source connection is closed on Cleanup
+func (t *TaskExecution) readSource() (df *iop.Dataflow, err error) {
+	if t.Context == nil {
+		t.Context = g.NewContext(context.Background())
+	}
+	t.Config.SetDefault()
+
+	switch t.Type {
+	case DbToFile:
+		srcConn, err := t.getSrcDBConn(t.Context.Ctx)
+		if err != nil {
+			return nil, g.Error(err, "Could not initialize source connection")
+		}
+		if !t.isUsingPool() {
+			t.AddCleanupTaskLast(func() { srcConn.Close() })
+		}
+
+		if df, err = t.ReadFromDB(t.Config, srcConn); err != nil {
+			return nil, g.Error(err, "Could not ReadFromDB")
+		}
+	case FileToFile:
+		if df, err = t.ReadFromFile(t.Config); err != nil {
+			return nil, g.Error(err, "Could not ReadFromFile")
+		}
+	default:
+		return nil, g.Error("requires a database or file source, not %s", t.Type)
+	}
+
+	return df, nil
+}
+
+// Profile reads the source stream and returns the statistics of each column
+func (t *TaskExecution) Profile() (profile StreamProfile, err error) {
+	defer t.Cleanup()
+	if t.df, err = t.readSource(); err != nil {
+		return profile, g.Error(err, "could not read source stream")
+	}
+	defer t.df.Close()
+
+	profile = StreamProfile{Stream: t.Config.StreamLabel(), Columns: []ColumnProfile{}}
+
+	ds := iop.MergeDataflow(t.df)
+	for row := range ds.Rows() {
+		if len(profile.Columns) < len(row) {
+			for _, col := range ds.Columns[len(profile.Columns):] {
+				profile.Columns = append(profile.Columns, ColumnProfile{Name: col.Name, hll: newHyperLogLog()})
+			}
+		}
+
+		profile.Rows++
+		for i, val := range row {
+			profile.Columns[i].add(val, ds.Columns[i].Type)
+		}
+	}
+
+	if err = ds.Err(); err != nil {
+		return profile, g.Error(err, "could not read source rows")
+	} else if err = t.df.Err(); err != nil {
+		return profile, g.Error(err, "could not read source rows")
+	}
+
+	for i := range profile.Columns {
+		if i < len(ds.Columns) {
+			profile.Columns[i].Type = ds.Columns[i].Type
+		}
+		profile.Columns[i].finish(profile.Rows)
+	}
+
+	return profile, nil
+}
+
+func (cp *ColumnProfile) add(val any, typ iop.ColumnType) {
+	if val == nil {
+		cp.NullCount++
+		return
+	}
+
+	var str string
+	switch v := val.(type) {
+	case time.Time:
+		str = v.Format(time.RFC3339Nano)
+	default:
+		str = cast.ToString(val)
+	}
+	cp.values++
+	cp.hll.Add(str)
+
+	if cp.Min == nil || compareValues(val, cp.Min, typ) < 0 {
+		cp.Min = val
+	}
+	if cp.Max == nil || compareValues(val, cp.Max, typ) > 0 {
+		cp.Max = val
+	}
+
+	length := len([]rune(str))
+	if cp.values == 1 || length < cp.MinLength {
+		cp.MinLength = length
+	}
+	if length > cp.MaxLength {
+		cp.MaxLength = length
+	}
+	cp.lengthSum += int64(length)
+
+	if cp.Lengths == nil {
+		cp.Lengths = make([]LengthBucket, len(lengthBuckets))
+	}
+	for i, upper := range lengthBuckets {
+		if length <= upper {
+			cp.Lengths[i].Count++
+			break
+		}
+	}
+}
+
+func (cp *ColumnProfile) finish(rows int64) {
+	if rows > 0 {
+		cp.NullPercent = math.Round(float64(cp.NullCount)*10000/float64(rows)) / 100
+	}
+	if cp.values > 0 {
+		cp.AvgLength = math.Round(float64(cp.lengthSum)*100/float64(cp.values)) / 100
+	}
+	cp.DistinctCount = cp.hll.Estimate()
+
+	// keep only the non-empty ranges
+	buckets := []LengthBucket{}
+	for i, bucket := range cp.Lengths {
+		if bucket.Count == 0 {
+			continue
+		}
+		switch {
+		case i == 0:
+			bucket.Range = "0"
+		case lengthBuckets[i] == math.MaxInt:
+			bucket.Range = g.F("%d+", lengthBuckets[i-1]+1)
+		default:
+			bucket.Range = g.F("%d-%d", lengthBuckets[i-1]+1, lengthBuckets[i])
+		}
+		buckets = append(buckets, bucket)
+	}
+	cp.Lengths = buckets
+}
+
+// compareValues compares two values of a column, as numbers, times or strings.
+// Decimals are held as strings, so the column type decides
+func compareValues(a, b any, typ iop.ColumnType) int {
+	if av, ok := a.(time.Time); ok {
+		if bv, ok := b.(time.Time); ok {
+			return av.Compare(bv)
+		}
+	}
+	if !typ.IsNumber() {
+		return strings.Compare(cast.ToString(a), cast.ToString(b))
+	}
+
+	af, errA := cast.ToFloat64E(a)
+	bf, errB := cast.ToFloat64E(b)
+	if errA == nil && errB == nil {
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(cast.ToString(a), cast.ToString(b))
+}
+
+// Markdown returns the profile as markdown tables
+func (sp StreamProfile) Markdown() string {
+	var sb strings.Builder
+	sb.WriteString(g.F("## %s (%d rows)\n\n", sp.Stream, sp.Rows))
+	sb.WriteString("| Column | Type | Nulls | Null % | Distinct (est.) | Min | Max | Length (min / avg / max) | Lengths |\n")
+	sb.WriteString("|---|---|---|---|---|---|---|---|---|\n")
+
+	cell := func(val any) string {
+		if val == nil {
+			return ""
+		}
+		str := cast.ToString(val)
+		if t, ok := val.(time.Time); ok {
+			str = t.Format(time.RFC3339Nano)
+		}
+		if len(str) > 40 {
+			str = str[:37] + "..."
+		}
+		return strings.NewReplacer("|", "\\|", "\n", " ").Replace(str)
+	}
+
+	for _, col := range sp.Columns {
+		lengths := []string{}
+		for _, bucket := range col.Lengths {
+			lengths = append(lengths, g.F("%s: %d", bucket.Range, bucket.Count))
+		}
+		sb.WriteString(g.F(
+			"| %s | %s | %d | %s | %d | %s | %s | %d / %s / %d | %s |\n",
+			col.Name, col.Type, col.NullCount, cast.ToString(col.NullPercent),
+			col.DistinctCount, cell(col.Min), cell(col.Max),
+			col.MinLength, cast.ToString(col.AvgLength), col.MaxLength,
+			strings.Join(lengths, ", "),
+		))
+	}
+
+	return sb.String()
+}
+
+// hyperLogLog estimates the number of distinct values with a fixed memory
+type hyperLogLog struct {
+	registers []uint8
+	count     uint64
+}
+
+const hllPrecision = 14
+
+func newHyperLogLog() *hyperLogLog {
+	return &hyperLogLog{registers: make([]uint8, 1<<hllPrecision)}
+}
+
+// Add adds a value
+func (h *hyperLogLog) Add(val string) {
+	h.count++
+	hasher := fnv.New64a()
+	hasher.Write([]byte(val))
+	hash := mix64(hasher.Sum64())
+
+	index := hash >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(hash<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+	}
+}
+
+// Estimate returns the estimated number of distinct values
+func (h *hyperLogLog) Estimate() uint64 {
+	m := float64(len(h.registers))
+	sum, zeros := 0.0, 0
+	for _, r := range h.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros)) // linear counting for small sets
+	}
+
+	if uint64(math.Round(estimate)) > h.count {
+		return h.count // cannot exceed the values added
+	}
+	return uint64(math.Round(estimate))
+}
+
+// mix64 spreads the bits of a hash (murmur3 finalizer)
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb53fe9a3ccd3
+	h ^= h >> 33
+	return h
+}
@@ -0,0 +1,45 @@
+package sling
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfile(t *testing.T) {
+	t.Setenv("SLING_DUCKDB_COMPUTE", "false")
+	t.Setenv("SLING_LOADED_AT_COLUMN", "false")
+	folder := t.TempDir()
+	os.WriteFile(path.Join(folder, "data.csv"), []byte("id,name,amount\n1,a,1.5\n2,bb,\n3,a,3.5\n4,,10\n"), 0644)
+
+	cfg := &Config{Source: Source{Conn: "local", Stream: "file://" + path.Join(folder, "data.csv")}}
+	profile, err := Profile(cfg, 0)
+	if !assert.NoError(t, err) || !assert.Len(t, profile.Columns, 3) {
+		return
+	}
+	assert.EqualValues(t, 4, profile.Rows)
+
+	id, name, amount := profile.Columns[0], profile.Columns[1], profile.Columns[2]
+	assert.Equal(t, iop.BigIntType, id.Type)
+	assert.EqualValues(t, 4, id.DistinctCount)
+	assert.EqualValues(t, 1, id.Min)
+	assert.EqualValues(t, 4, id.Max)
+
+	assert.EqualValues(t, 1, name.NullCount)
+	assert.Equal(t, 25.0, name.NullPercent)
+	assert.EqualValues(t, 2, name.DistinctCount)
+	assert.Equal(t, 1, name.MinLength)
+	assert.Equal(t, 2, name.MaxLength)
+	assert.Equal(t, []LengthBucket{{Range: "1-8", Count: 3}}, name.Lengths)
+
+	assert.Equal(t, iop.DecimalType, amount.Type)
+	assert.EqualValues(t, 1, amount.NullCount)
+	assert.Equal(t, "1.5", cast.ToString(amount.Min))
+	assert.Equal(t, "10", cast.ToString(amount.Max))
+
+	assert.Contains(t, profile.Markdown(), "| name | text | 1 | 25 | 2 | a | bb |")
+}
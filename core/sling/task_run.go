@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
 	"runtime/debug"
 	"strings"
@@ -11,11 +12,13 @@ import (
 
 	_ "net/http/pprof"
 
+	"github.com/dustin/go-humanize"
 	"github.com/nqd/flat"
 	"github.com/slingdata-io/sling-cli/core"
 
 	"github.com/flarco/g"
 	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/connection"
 	"github.com/slingdata-io/sling-cli/core/dbio/database"
 	"github.com/slingdata-io/sling-cli/core/env"
 	"github.com/spf13/cast"
@@ -33,6 +36,10 @@ var (
 	slingRowNumColumn    = "_sling_row_num"
 	slingRowIDColumn     = "_sling_row_id"
 	slingExecIDColumn    = "_sling_exec_id"
+	slingRowHashColumn   = "_sling_row_hash"
+	slingFileLineColumn  = "_sling_file_line"
+
+	slingSurrogateKeyColumn = "_sk"
 )
 
 var deleteMissing func(*TaskExecution, database.Connection, database.Connection) error = func(_ *TaskExecution, _, _ database.Connection) error {
@@ -41,6 +48,23 @@ var deleteMissing func(*TaskExecution, database.Connection, database.Connection)
 }
 
 func init() {
+	// allow custom metadata column names
+	metadataColumns := map[string]*string{
+		"SLING_LOADED_AT_COLUMN_NAME":  &slingLoadedAtColumn,
+		"SLING_DELETED_AT_COLUMN_NAME": &slingDeletedAtColumn,
+		"SLING_STREAM_URL_COLUMN_NAME": &slingStreamURLColumn,
+		"SLING_ROW_NUM_COLUMN_NAME":    &slingRowNumColumn,
+		"SLING_ROW_ID_COLUMN_NAME":     &slingRowIDColumn,
+		"SLING_EXEC_ID_COLUMN_NAME":    &slingExecIDColumn,
+		"SLING_ROW_HASH_COLUMN_NAME":   &slingRowHashColumn,
+		"SLING_FILE_LINE_COLUMN_NAME":  &slingFileLineColumn,
+	}
+	for envKey, colName := range metadataColumns {
+		if val := strings.TrimSpace(os.Getenv(envKey)); val != "" {
+			*colName = val
+		}
+	}
+
 	// we need a webserver to get the pprof webserver
 	if cast.ToBool(os.Getenv("SLING_PPROF")) {
 		go func() {
@@ -56,7 +80,9 @@ func (t *TaskExecution) Execute() error {
 
 	done := make(chan struct{})
 	now := time.Now()
+	t.mux.Lock()
 	t.StartTime = &now
+	t.mux.Unlock()
 	t.lastIncrement = now
 
 	if t.Context == nil {
@@ -69,6 +95,9 @@ func (t *TaskExecution) Execute() error {
 	// set defaults
 	t.Config.SetDefault()
 
+	// register token vaults on target tables, if any
+	t.registerTokenVaults()
+
 	// print for debugging
 	g.Trace("using Config:\n%s", g.Pretty(t.Config))
 	env.SetTelVal("stage", "2 - task-execution")
@@ -102,7 +131,7 @@ func (t *TaskExecution) Execute() error {
 			}
 		}()
 
-		t.Status = ExecStatusRunning
+		t.SetStatus(ExecStatusRunning)
 
 		if t.Err != nil {
 			return
@@ -122,7 +151,7 @@ func (t *TaskExecution) Execute() error {
 			return
 		} else if t.skipStream {
 			t.SetProgress("skipping stream")
-			t.Status = ExecStatusSkipped
+			t.SetStatus(ExecStatusSkipped)
 			return
 		}
 
@@ -147,7 +176,7 @@ func (t *TaskExecution) Execute() error {
 			for _, col := range df.Columns {
 				if c := col.Constraint; c != nil && c.FailCnt > 0 {
 					g.Warn("column '%s' had %d constraint failures (%s) ", col.Name, c.FailCnt, c.Expression)
-					t.Status = ExecStatusWarning // set as warning status
+					t.SetStatus(ExecStatusWarning) // set as warning status
 				}
 			}
 		}
@@ -173,11 +202,11 @@ func (t *TaskExecution) Execute() error {
 			t.SetProgress("execution succeeded (with warnings)")
 		} else {
 			t.SetProgress("execution succeeded")
-			t.Status = ExecStatusSuccess
+			t.SetStatus(ExecStatusSuccess)
 		}
 	} else {
 		t.SetProgress("execution failed")
-		t.Status = ExecStatusError
+		t.SetStatus(ExecStatusError)
 		if err := t.df.Context.Err(); err != nil && err.Error() != t.Err.Error() {
 			eG := g.ErrorGroup{}
 			eG.Add(err)
@@ -189,7 +218,9 @@ func (t *TaskExecution) Execute() error {
 	}
 
 	now2 := time.Now()
+	t.mux.Lock()
 	t.EndTime = &now2
+	t.mux.Unlock()
 
 	// update into store
 	StateSet(t)
@@ -201,6 +232,8 @@ func (t *TaskExecution) Execute() error {
 		}
 	}
 
+	t.sendNotifications()
+
 	return t.Err
 }
 
@@ -231,6 +264,11 @@ func (t *TaskExecution) GetStateMap() map[string]any {
 		cMap[k] = v
 	}
 
+	// run metadata, renewed at each call
+	for k, v := range t.RunMetadata() {
+		cMap[k] = v
+	}
+
 	// flatten with dot separator
 	sMap, err := flat.Flatten(cMap, &flat.Options{Delimiter: ".", Safe: true})
 	if err != nil {
@@ -240,6 +278,26 @@ func (t *TaskExecution) GetStateMap() map[string]any {
 	return sMap
 }
 
+// setQueryTag sets the query tag of a snowflake connection, so that the
+// queries can be attributed to the stream. A `query_tag` value is rendered
+// with the runtime variables, the default is the stream identifiers as JSON.
+func (t *TaskExecution) setQueryTag(conn *connection.Connection) {
+	if conn.Type != dbio.TypeDbSnowflake {
+		return
+	}
+
+	if tag := cast.ToString(conn.Data["query_tag"]); tag != "" {
+		conn.Data["query_tag"] = g.Rm(tag, t.GetStateMap())
+		return
+	}
+
+	tag := g.M("app", "sling", "exec_id", t.ExecID, "stream", t.Config.StreamLabel(), "object", t.Config.Target.Object)
+	if cfgPath := t.Config.Env["SLING_CONFIG_PATH"]; cfgPath != "" && t.Config.ReplicationMode() {
+		tag["replication"] = filepath.Base(cfgPath)
+	}
+	conn.Data["query_tag"] = g.Marshal(tag)
+}
+
 func (t *TaskExecution) getSrcDBConn(ctx context.Context) (conn database.Connection, err error) {
 
 	// sets metadata
@@ -252,6 +310,7 @@ func (t *TaskExecution) getSrcDBConn(ctx context.Context) (conn database.Connect
 	for k, v := range options {
 		t.Config.SrcConn.Data[k] = v
 	}
+	t.setQueryTag(&t.Config.SrcConn)
 
 	conn, err = t.Config.SrcConn.AsDatabaseContext(ctx, t.isUsingPool())
 	if err != nil {
@@ -259,6 +318,11 @@ func (t *TaskExecution) getSrcDBConn(ctx context.Context) (conn database.Connect
 		return
 	}
 
+	// no source access needed when replaying fixtures
+	if IsReplaying() {
+		return
+	}
+
 	err = conn.Connect()
 	if err != nil {
 		err = g.Error(err, "Could not connect to source connection")
@@ -282,6 +346,7 @@ func (t *TaskExecution) getTgtDBConn(ctx context.Context) (conn database.Connect
 	for k, v := range options {
 		t.Config.TgtConn.Data[k] = v
 	}
+	t.setQueryTag(&t.Config.TgtConn)
 
 	conn, err = t.Config.TgtConn.AsDatabaseContext(ctx, t.isUsingPool())
 	if err != nil {
@@ -345,7 +410,7 @@ func (t *TaskExecution) runDbToFile() (err error) {
 	}
 
 	if t.isIncrementalStateWithUpdateKey() {
-		if err = getIncrementalValueViaState(t); err != nil {
+		if err = GetIncrementalValueViaState(t); err != nil {
 			err = g.Error(err, "Could not get incremental value")
 			return err
 		}
@@ -360,7 +425,8 @@ func (t *TaskExecution) runDbToFile() (err error) {
 
 	t.SetProgress("reading from source database")
 	defer t.Cleanup()
-	t.df, err = t.ReadFromDB(t.Config, srcConn)
+	df, err := t.ReadFromDB(t.Config, srcConn)
+	t.setDf(df)
 	if err != nil {
 		err = g.Error(err, "Could not ReadFromDB")
 		return
@@ -385,7 +451,7 @@ func (t *TaskExecution) runDbToFile() (err error) {
 	}
 
 	if cnt > 0 && t.hasStateWithUpdateKey() {
-		if err = setIncrementalValueViaState(t); err != nil {
+		if err = SetIncrementalValueViaState(t); err != nil {
 			err = g.Error(err, "Could not set incremental value")
 			return err
 		}
@@ -422,7 +488,7 @@ func (t *TaskExecution) runFileToDB() (err error) {
 	if t.isIncrementalWithUpdateKey() {
 		t.SetProgress("getting checkpoint value")
 		if t.Config.Source.UpdateKey == "." {
-			t.Config.Source.UpdateKey = slingLoadedAtColumn
+			t.Config.Source.UpdateKey = t.Config.loadedAtColumn()
 		}
 
 		if err = getIncrementalValueViaDB(t.Config, tgtConn, dbio.TypeDbDuckDb); err != nil {
@@ -437,7 +503,8 @@ func (t *TaskExecution) runFileToDB() (err error) {
 	} else {
 		t.SetProgress("reading from source file system (%s)", t.Config.SrcConn.Type)
 	}
-	t.df, err = t.ReadFromFile(t.Config)
+	df, err := t.ReadFromFile(t.Config)
+	t.setDf(df)
 	if err != nil {
 		if strings.Contains(err.Error(), "Provided 0 files") {
 			if t.isIncrementalWithUpdateKey() && t.Config.HasIncrementalVal() {
@@ -467,6 +534,10 @@ func (t *TaskExecution) runFileToDB() (err error) {
 
 	if err != nil {
 		err = g.Error(t.df.Err(), "error in transfer")
+	} else if err = t.saveFileState(); err != nil {
+		return err
+	} else if err = t.postReadFiles(); err != nil {
+		return g.Error(err, "could not process source files")
 	}
 	return
 }
@@ -475,12 +546,40 @@ func (t *TaskExecution) runFileToFile() (err error) {
 
 	start = time.Now()
 
+	if t.usePassthrough() {
+		t.SetProgress("copying file as is from %s to %s", t.Config.SrcConn.Type, t.Config.TgtConn.Type)
+		ok, bw, err := t.runPassthrough()
+		if err != nil {
+			t.Cleanup()
+			return g.Error(err, "Error in runFileToFile (passthrough)")
+		} else if ok {
+			t.Cleanup()
+			elapsed := int(time.Since(start).Seconds())
+			t.SetProgress("wrote %s to %s in %d secs", humanize.Bytes(cast.ToUint64(bw)), t.getTargetObjectValue(), elapsed)
+			return nil
+		}
+	}
+
+	if t.useArrowPipeline() {
+		t.SetProgress("copying arrow record batches from %s to %s (experimental)", t.Config.SrcConn.Type, t.Config.TgtConn.Type)
+		defer t.Cleanup()
+		cnt, err := t.runArrowPipeline()
+		if err != nil {
+			return g.Error(err, "Error in runFileToFile (arrow pipeline)")
+		}
+
+		elapsed := int(time.Since(start).Seconds())
+		t.SetProgress("wrote %d rows to %s in %d secs [%s r/s]", cnt, t.getTargetObjectValue(), elapsed, getRate(cnt))
+		return nil
+	}
+
 	if t.Config.Options.StdIn && t.Config.SrcConn.Type.IsUnknown() {
 		t.SetProgress("reading from stream (stdin)")
 	} else {
 		t.SetProgress("reading from source file system (%s)", t.Config.SrcConn.Type)
 	}
-	t.df, err = t.ReadFromFile(t.Config)
+	df, err := t.ReadFromFile(t.Config)
+	t.setDf(df)
 	if err != nil {
 		if strings.Contains(err.Error(), "Provided 0 files") {
 			if t.isIncrementalWithUpdateKey() && t.Config.HasIncrementalVal() {
@@ -514,6 +613,10 @@ func (t *TaskExecution) runFileToFile() (err error) {
 
 	if t.df.Err() != nil {
 		err = g.Error(t.df.Err(), "Error in runFileToFile")
+	} else if err = t.saveFileState(); err != nil {
+		return err
+	} else if err = t.postReadFiles(); err != nil {
+		return g.Error(err, "could not process source files")
 	}
 	return
 }
@@ -554,7 +657,7 @@ func (t *TaskExecution) runDbToDb() (err error) {
 
 	// get watermark
 	if t.isIncrementalStateWithUpdateKey() {
-		if err = getIncrementalValueViaState(t); err != nil {
+		if err = GetIncrementalValueViaState(t); err != nil {
 			err = g.Error(err, "Could not get incremental value")
 			return err
 		}
@@ -569,7 +672,8 @@ func (t *TaskExecution) runDbToDb() (err error) {
 	}
 
 	t.SetProgress("reading from source database")
-	t.df, err = t.ReadFromDB(t.Config, srcConn)
+	df, err := t.ReadFromDB(t.Config, srcConn)
+	t.setDf(df)
 	if err != nil {
 		err = g.Error(err, "Could not ReadFromDB")
 		return
@@ -605,7 +709,7 @@ func (t *TaskExecution) runDbToDb() (err error) {
 	}
 
 	if cnt > 0 && t.hasStateWithUpdateKey() {
-		if err = setIncrementalValueViaState(t); err != nil {
+		if err = SetIncrementalValueViaState(t); err != nil {
 			err = g.Error(err, "Could not set incremental value")
 			return err
 		}
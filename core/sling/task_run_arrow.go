@@ -0,0 +1,144 @@
+package sling
+
+import (
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/filesys"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+)
+
+// useArrowPipeline returns true if the file transfer can carry the arrow record
+// batches end-to-end instead of rows (experimental, with SLING_ARROW_PIPELINE).
+// Only a parquet/arrow file copied as is into a parquet/arrow file qualifies.
+func (t *TaskExecution) useArrowPipeline() bool {
+	if !cast.ToBool(t.Config.GetEnv("SLING_ARROW_PIPELINE")) || !t.copiesFileAsIs() {
+		return false
+	}
+	return g.In(t.sourceFileFormat(), dbio.FileTypeParquet, dbio.FileTypeArrow) &&
+		g.In(t.Config.Target.ObjectFileFormat(), dbio.FileTypeParquet, dbio.FileTypeArrow)
+}
+
+// copiesFileAsIs returns true if a single source file is copied into a single
+// target file, without any row processing (select, transforms, casing, etc.)
+func (t *TaskExecution) copiesFileAsIs() bool {
+	cfg := t.Config
+	if cfg.Options.StdIn || cfg.Options.StdOut || cfg.SrcConn.URL() == "" || cfg.TgtConn.URL() == "" {
+		return false
+	} else if !g.In(cfg.Mode, Mode(""), FullRefreshMode) {
+		return false
+	}
+
+	// a single source file
+	srcFormat := t.sourceFileFormat()
+	if !strings.Contains(strings.ToLower(path.Base(cfg.SrcConn.URL())), srcFormat.Ext()) {
+		return false
+	} else if len(iop.ExtractPartitionFields(cfg.TgtConn.URL())) > 0 {
+		return false
+	}
+
+	// no row processing
+	if cfg.Source.Query != "" || len(cfg.Source.Select) > 0 || cfg.Source.Limit() > 0 || cfg.HasIncrementalVal() {
+		return false
+	} else if len(cfg.ColumnsPrepared()) > 0 || len(cfg.TransformsPrepared()) > 0 {
+		return false
+	}
+
+	metadata := t.setGetMetadata()
+	if metadata.LoadedAt.Key != "" || metadata.StreamURL.Key != "" || metadata.RowID.Key != "" || metadata.ExecID.Key != "" || metadata.RowNum.Key != "" || metadata.RowHash.Key != "" || metadata.FileLine.Key != "" || metadata.SurrogateKey.Name != "" {
+		return false
+	}
+
+	if so := cfg.Source.Options; so != nil && (so.Filter != nil || len(so.ComputedColumns) > 0 || so.RateLimit != nil || so.FileIncremental != nil || so.PostRead != nil || so.Sample != nil) {
+		return false
+	}
+
+	if to := cfg.Target.Options; to != nil {
+		if len(to.ColumnMapping) > 0 || to.RateLimit != nil || to.Manifest != nil || g.PtrVal(to.Atomic) || g.PtrVal(to.ChecksumMetadata) || g.PtrVal(to.ContentAddressed) || g.PtrVal(to.FileMaxRows) > 0 || g.PtrVal(to.FileMaxBytes) > 0 {
+			return false
+		} else if to.ColumnCasing != nil && *to.ColumnCasing != iop.SourceColumnCasing {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (t *TaskExecution) sourceFileFormat() dbio.FileType {
+	if so := t.Config.Source.Options; so != nil && so.Format != nil {
+		return *so.Format
+	}
+	return filesys.InferFileFormat(t.Config.SrcConn.URL())
+}
+
+// runArrowPipeline copies the record batches of the source file into the target file
+func (t *TaskExecution) runArrowPipeline() (cnt uint64, err error) {
+	cfg := t.Config
+	setStage("3 - prepare-dataflow")
+
+	srcURI := cfg.SrcConn.URL()
+	srcFs, err := filesys.NewFileSysClientFromURLContext(t.Context.Ctx, srcURI, g.MapToKVArr(cfg.SrcConn.DataS())...)
+	if err != nil {
+		return 0, g.Error(err, "Could not obtain client for %s ", cfg.SrcConn.Type)
+	}
+
+	reader, err := srcFs.Self().GetReader(srcURI)
+	if err != nil {
+		return 0, g.Error(err, "Could not read %s", srcURI)
+	}
+
+	records, err := iop.NewArrowRecordReader(t.Context.Ctx, reader, t.sourceFileFormat())
+	if err != nil {
+		return 0, g.Error(err, "Could not read record batches of %s", srcURI)
+	}
+	defer records.Close()
+
+	// the dataflow only accounts for the rows and bytes
+	df := iop.NewDataflowContext(t.Context.Ctx)
+	ds := iop.NewDatastreamContext(t.Context.Ctx, records.Columns())
+	ds.SetReady()
+	df.Columns = ds.Columns
+	df.Streams = append(df.Streams, ds)
+	df.SetReady()
+	t.setDf(df)
+
+	setStage("5 - load-into-final")
+	tgtURI := g.Rm(cfg.TgtConn.URL(), iop.GetISO8601DateMap(time.Now()))
+
+	// construct props by merging with options
+	options := g.M()
+	g.Unmarshal(g.Marshal(cfg.Target.Options), &options)
+	props := append(
+		g.MapToKVArr(cfg.TgtConn.DataS()),
+		g.MapToKVArr(g.ToMapString(options))...,
+	)
+
+	tgtFs, err := filesys.NewFileSysClientFromURLContext(t.Context.Ctx, tgtURI, props...)
+	if err != nil {
+		return 0, g.Error(err, "Could not obtain client for: %s", cfg.TgtConn.Type)
+	}
+
+	var compression iop.CompressorType
+	if cfg.Target.Options.Compression != nil {
+		compression = *cfg.Target.Options.Compression
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(ds.CopyArrowRecords(records, pw, cfg.Target.ObjectFileFormat(), compression))
+	}()
+
+	bw, err := tgtFs.Self().Write(tgtURI, pr)
+	if err != nil {
+		pr.CloseWithError(err)
+		return 0, g.Error(err, "Could not write to %s", tgtURI)
+	}
+	t.df.AddEgressBytes(uint64(bw))
+
+	return t.df.Count(), nil
+}
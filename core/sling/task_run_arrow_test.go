@@ -0,0 +1,43 @@
+package sling
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArrowPipeline(t *testing.T) {
+	t.Setenv("SLING_DUCKDB_COMPUTE", "false")
+	t.Setenv("SLING_FILE_PASSTHROUGH", "false")
+	folder := t.TempDir()
+	err := os.WriteFile(path.Join(folder, "data.csv"), []byte("id,name\n1,a\n2,b\n3,c\n"), 0644)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = runTestTask(localFileConfig(folder, "data.csv", "data.parquet"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	t.Setenv("SLING_ARROW_PIPELINE", "true")
+	for _, target := range []string{"copy.parquet", "copy.arrow"} {
+		task, err := runTestTask(localFileConfig(folder, "data.parquet", target))
+		if assert.NoError(t, err, target) {
+			assert.True(t, task.useArrowPipeline())
+			assert.EqualValues(t, 3, task.GetCount())
+		}
+	}
+
+	// rows are read back from the copies
+	for _, source := range []string{"copy.parquet", "copy.arrow"} {
+		task, err := runTestTask(localFileConfig(folder, source, source+".csv"))
+		if assert.NoError(t, err, source) {
+			assert.False(t, task.useArrowPipeline())
+			data, _ := os.ReadFile(path.Join(folder, source+".csv"))
+			assert.Equal(t, "id,name\n1,a\n2,b\n3,c\n", string(data))
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package sling
+
+import (
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+	"github.com/slingdata-io/sling-cli/core/dbio/database"
+	"github.com/slingdata-io/sling-cli/core/env"
+)
+
+// attachConnections attaches the `source.options.attach` connections into the
+// DuckDB source, so that the source SQL can join them (federated query).
+// Databases are attached read-only under their lower case connection name,
+// storage connections get a DuckDB secret for their files to be scanned
+func (t *TaskExecution) attachConnections(srcConn database.Connection) (err error) {
+	so := t.Config.Source.Options
+	if so == nil || len(so.Attach) == 0 {
+		return nil
+	}
+
+	duckConn, ok := srcConn.(*database.DuckDbConn)
+	if !ok {
+		return g.Error("attach requires a duckdb source connection, not %s", srcConn.GetType())
+	}
+
+	conns := connection.GetLocalConns()
+	sqls := []string{}
+	for _, name := range so.Attach {
+		entry := conns.Get(name)
+		if entry.Name == "" {
+			return g.Error("did not find connection %s to attach", name)
+		}
+
+		if entry.Connection.Type.IsFile() {
+			duck := duckConn.DuckDb()
+			duck.SetProp("fs_props", g.Marshal(entry.Connection.DataS()))
+			duck.PrepareFsSecretAndURI(entry.Connection.URL())
+			continue
+		}
+
+		sql, err := attachSQL(entry.Connection)
+		if err != nil {
+			return g.Error(err, "could not attach connection %s", name)
+		}
+		sqls = append(sqls, sql+env.NoDebugKey) // do not log the credentials
+	}
+
+	if len(sqls) > 0 {
+		if _, err = srcConn.ExecMulti(sqls...); err != nil {
+			return g.Error(err, "could not attach connections")
+		}
+	}
+	g.Debug("attached connections %s", strings.Join(so.Attach, ", "))
+
+	return nil
+}
+
+// attachSQL returns the statements attaching a database connection into DuckDB
+func attachSQL(conn connection.Connection) (sql string, err error) {
+	data := conn.DataS(true)
+	alias := dbio.TypeDbDuckDb.Quote(strings.ToLower(conn.Name))
+	quote := func(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" }
+
+	// key=value connection string, from the connection properties
+	keyValues := func(keys map[string]string) string {
+		parts := []string{}
+		for _, key := range []string{"host", "port", "user", "password", "database", "sslmode"} {
+			if val := data[key]; val != "" {
+				parts = append(parts, keys[key]+"="+val)
+			} else if key == "user" && data["username"] != "" {
+				parts = append(parts, keys[key]+"="+data["username"])
+			}
+		}
+		return strings.Join(parts, " ")
+	}
+
+	switch conn.Type {
+	case dbio.TypeDbPostgres:
+		keys := map[string]string{"host": "host", "port": "port", "user": "user", "password": "password", "database": "dbname", "sslmode": "sslmode"}
+		sql = g.F("install postgres; load postgres; attach %s as %s (type postgres, read_only)", quote(keyValues(keys)), alias)
+	case dbio.TypeDbMySQL, dbio.TypeDbMariaDB:
+		keys := map[string]string{"host": "host", "port": "port", "user": "user", "password": "password", "database": "database", "sslmode": "ssl_mode"}
+		sql = g.F("install mysql; load mysql; attach %s as %s (type mysql, read_only)", quote(keyValues(keys)), alias)
+	case dbio.TypeDbSQLite:
+		sql = g.F("install sqlite; load sqlite; attach %s as %s (type sqlite, read_only)", quote(data["instance"]), alias)
+	case dbio.TypeDbDuckDb:
+		sql = g.F("attach %s as %s (read_only)", quote(data["instance"]), alias)
+	default:
+		return "", g.Error("cannot attach a %s connection into duckdb", conn.Type)
+	}
+
+	return sql, nil
+}
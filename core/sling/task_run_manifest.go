@@ -0,0 +1,111 @@
+package sling
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+	"github.com/slingdata-io/sling-cli/core/dbio/filesys"
+	"github.com/spf13/cast"
+)
+
+// Manifest lists the files written to a file target (`target.options.manifest`),
+// so that downstream loaders can consume exactly what was produced
+type Manifest struct {
+	ExecID     string         `json:"exec_id"`
+	Stream     string         `json:"stream"`
+	Object     string         `json:"object"`
+	CreatedAt  time.Time      `json:"created_at"`
+	TotalRows  uint64         `json:"total_rows"`
+	TotalBytes uint64         `json:"total_bytes"`
+	Files      []ManifestFile `json:"files"`
+}
+
+// ManifestFile is a file written
+type ManifestFile struct {
+	URI    string `json:"uri"`
+	Rows   *int64 `json:"rows"` // null if unknown
+	Bytes  int64  `json:"bytes"`
+	MD5    string `json:"md5,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// newManifest creates the manifest of the written files
+func (t *TaskExecution) newManifest(files []filesys.FileReady, totalRows uint64) (manifest Manifest) {
+	manifest = Manifest{
+		ExecID:    t.ExecID,
+		Stream:    t.Config.StreamLabel(),
+		Object:    t.Config.TgtConn.URL(),
+		CreatedAt: time.Now().UTC(),
+		TotalRows: totalRows,
+		Files:     []ManifestFile{},
+	}
+
+	for _, file := range files {
+		mFile := ManifestFile{URI: file.Node.URI, Bytes: file.BytesW, MD5: file.MD5, SHA256: file.SHA256}
+		if file.Rows >= 0 {
+			mFile.Rows = g.Int64(file.Rows)
+		} else if len(files) == 1 {
+			mFile.Rows = g.Int64(cast.ToInt64(totalRows))
+		}
+		manifest.Files = append(manifest.Files, mFile)
+		manifest.TotalBytes += cast.ToUint64(file.BytesW)
+	}
+
+	return manifest
+}
+
+// writeManifest writes the manifest of the written files, in the csv format
+// if the location ends with `.csv`, otherwise in the json format
+func (t *TaskExecution) writeManifest(files []filesys.FileReady, totalRows uint64) (err error) {
+	cfg := t.Config
+	if cfg.Target.Options == nil || cfg.Target.Options.Manifest == nil {
+		return nil
+	}
+
+	fMap, err := cfg.GetFormatMap()
+	if err != nil {
+		return g.Error(err, "could not get formatting variables")
+	}
+	location := strings.TrimSpace(g.Rm(*cfg.Target.Options.Manifest, fMap))
+
+	// on the target connection, or local
+	var props []string
+	if connection.SchemeType(location) == cfg.TgtConn.Type {
+		props = g.MapToKVArr(cfg.TgtConn.DataS())
+	}
+	fs, err := filesys.NewFileSysClientFromURLContext(t.Context.Ctx, location, props...)
+	if err != nil {
+		return g.Error(err, "could not obtain client for %s", location)
+	}
+
+	manifest := t.newManifest(files, totalRows)
+
+	var content []byte
+	if strings.HasSuffix(strings.ToLower(location), ".csv") {
+		buf := bytes.NewBuffer(nil)
+		w := csv.NewWriter(buf)
+		w.Write([]string{"uri", "rows", "bytes", "md5", "sha256"})
+		for _, file := range manifest.Files {
+			rows := ""
+			if file.Rows != nil {
+				rows = cast.ToString(*file.Rows)
+			}
+			w.Write([]string{file.URI, rows, cast.ToString(file.Bytes), file.MD5, file.SHA256})
+		}
+		w.Flush()
+		content = buf.Bytes()
+	} else {
+		content = []byte(g.Pretty(manifest))
+	}
+
+	if _, err = fs.Self().Write(location, bytes.NewReader(content)); err != nil {
+		return g.Error(err, "could not write manifest to %s", location)
+	}
+	g.Debug("wrote manifest of %d files to %s", len(manifest.Files), location)
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package sling
+
+import (
+	"crypto/md5"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/flarco/g"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifest(t *testing.T) {
+	t.Setenv("SLING_DUCKDB_COMPUTE", "false")
+	folder := t.TempDir()
+	os.WriteFile(path.Join(folder, "data.csv"), []byte("id,name\n1,a\n2,b\n3,c\n4,d\n5,e\n"), 0644)
+
+	run := func(target string, tgtOpts *TargetOptions) (*TaskExecution, error) {
+		cfg := localFileConfig(folder, "data.csv", target)
+		cfg.Target.Options = tgtOpts
+		return runTestTask(cfg)
+	}
+
+	// json manifest of the part files
+	manifestPath := path.Join(folder, "manifest.json")
+	_, err := run("parts/*.csv", &TargetOptions{FileMaxRows: g.Int64(2), Manifest: g.String("file://" + manifestPath)})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var manifest Manifest
+	data, _ := os.ReadFile(manifestPath)
+	if assert.NoError(t, g.Unmarshal(string(data), &manifest)) && assert.Len(t, manifest.Files, 3) {
+		assert.EqualValues(t, 5, manifest.TotalRows)
+		rows := int64(0)
+		for _, file := range manifest.Files {
+			content, err := os.ReadFile(strings.TrimPrefix(file.URI, "file://"))
+			if assert.NoError(t, err) {
+				assert.EqualValues(t, len(content), file.Bytes)
+				assert.Equal(t, g.F("%x", md5.Sum(content)), file.MD5)
+			}
+			if assert.NotNil(t, file.Rows) {
+				rows += *file.Rows
+			}
+		}
+		assert.EqualValues(t, 5, rows)
+	}
+
+	// csv manifest of a single file
+	manifestPath = path.Join(folder, "manifest.csv")
+	_, err = run("single.json", &TargetOptions{Manifest: g.String(manifestPath)})
+	if assert.NoError(t, err) {
+		data, _ = os.ReadFile(manifestPath)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if assert.Len(t, lines, 2) {
+			assert.Equal(t, "uri,rows,bytes,md5,sha256", lines[0])
+			assert.Contains(t, lines[1], "single.json,5,")
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package sling
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/filesys"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+)
+
+// passthroughSampleSize is the bytes peeked to detect the csv delimiter
+const passthroughSampleSize = 64 * 1024
+
+// usePassthrough returns true if the source file can be streamed as is into the
+// target file, without being parsed and re-encoded: same format and compression,
+// and no row processing. Only csv and json lines files are passed through, since
+// their rows can be counted while streaming. Opt out with SLING_FILE_PASSTHROUGH=false.
+func (t *TaskExecution) usePassthrough() bool {
+	cfg := t.Config
+	if val := t.Config.GetEnv("SLING_FILE_PASSTHROUGH"); val != "" && !cast.ToBool(val) {
+		return false
+	} else if !t.copiesFileAsIs() {
+		return false
+	}
+
+	// same format and compression (file extensions)
+	format := t.sourceFileFormat()
+	if !g.In(format, dbio.FileTypeCsv, dbio.FileTypeJsonLines) {
+		return false
+	} else if format != cfg.Target.ObjectFileFormat() || fileExtensions(cfg.SrcConn.URL()) != fileExtensions(cfg.TgtConn.URL()) {
+		return false
+	}
+
+	so, to := cfg.Source.Options, cfg.Target.Options
+	if so == nil || to == nil {
+		return true
+	}
+
+	// options changing the encoding
+	if to.Compression != nil && !g.In(*to.Compression, iop.AutoCompressorType, iop.CompressorType("")) {
+		return false
+	} else if g.PtrVal(so.Flatten) || so.JmesPath != nil || g.PtrVal(so.SkipBlankLines) || so.Escape != "" || so.Quote != "" {
+		return false
+	} else if !strings.EqualFold(to.DatetimeFormat, "auto") && to.DatetimeFormat != "" {
+		return false
+	} else if to.MaxDecimals != nil && *to.MaxDecimals != -1 {
+		return false
+	} else if format == dbio.FileTypeCsv && g.PtrVal(so.Header) != g.PtrVal(to.Header) {
+		return false
+	} else if format == dbio.FileTypeCsv && so.Delimiter != "" && so.Delimiter != to.Delimiter {
+		return false
+	}
+
+	return true
+}
+
+// fileExtensions returns the extensions of a file name (e.g. `.csv.gz`)
+func fileExtensions(uri string) string {
+	base := strings.ToLower(path.Base(uri))
+	if i := strings.Index(base, "."); i > -1 {
+		return base[i:]
+	}
+	return ""
+}
+
+// runPassthrough streams the bytes of the source file into the target file.
+// Returns ok=false if the file cannot be passed through (not a single file, csv
+// delimiter mismatch, or any failure), for the rows to be read and written instead.
+func (t *TaskExecution) runPassthrough() (ok bool, bw int64, err error) {
+	bw, err = t.passthrough()
+	if err == errNoPassthrough {
+		return false, 0, nil
+	} else if err != nil {
+		if t.Context.Ctx.Err() != nil {
+			return true, bw, err
+		}
+		g.Warn("could not copy file as is, reading rows instead: %s", err.Error())
+		t.setDf(nil)
+		return false, 0, nil
+	}
+	return true, bw, nil
+}
+
+// errNoPassthrough is returned when the source file cannot be passed through
+var errNoPassthrough = g.Error("file cannot be passed through")
+
+func (t *TaskExecution) passthrough() (bw int64, err error) {
+	cfg := t.Config
+	setStage("3 - prepare-dataflow")
+
+	srcURI := cfg.SrcConn.URL()
+	srcFs, err := filesys.NewFileSysClientFromURLContext(t.Context.Ctx, srcURI, g.MapToKVArr(cfg.SrcConn.DataS())...)
+	if err != nil {
+		return 0, g.Error(err, "Could not obtain client for %s ", cfg.SrcConn.Type)
+	}
+
+	if !isSingleFile(srcFs, srcURI) {
+		g.Debug("not passing through, %s is not a single file", srcURI)
+		return 0, errNoPassthrough
+	}
+
+	reader0, err := srcFs.Self().GetReader(srcURI)
+	if err != nil {
+		return 0, g.Error(err, "Could not read %s", srcURI)
+	}
+	if closer, ok := reader0.(io.Closer); ok {
+		defer closer.Close()
+	}
+	reader := bufio.NewReaderSize(reader0, passthroughSampleSize)
+
+	// the detected delimiter of the source must match the target's
+	if t.sourceFileFormat() == dbio.FileTypeCsv && (cfg.Source.Options == nil || cfg.Source.Options.Delimiter == "") {
+		sample, _ := reader.Peek(passthroughSampleSize)
+		if dReader, err := iop.AutoDecompress(bytes.NewReader(sample)); err == nil {
+			sample, _ = io.ReadAll(dReader) // truncated sample, ignore error
+		}
+
+		delimiter := ","
+		if cfg.Target.Options != nil && cfg.Target.Options.Delimiter != "" {
+			delimiter = cfg.Target.Options.Delimiter
+		}
+
+		if deli := string(iop.DetectDelimiter(sample)); deli != delimiter {
+			g.Debug("not passing through, source delimiter %q differs from target delimiter %q", deli, delimiter)
+			return 0, errNoPassthrough
+		}
+	}
+
+	// the dataflow only accounts for the bytes
+	df := iop.NewDataflowContext(t.Context.Ctx)
+	ds := iop.NewDatastreamContext(t.Context.Ctx, nil)
+	ds.SetReady()
+	df.Streams = append(df.Streams, ds)
+	df.SetReady()
+	t.setDf(df)
+
+	setStage("5 - load-into-final")
+	tgtURI := g.Rm(cfg.TgtConn.URL(), iop.GetISO8601DateMap(time.Now()))
+
+	// construct props by merging with options
+	options := g.M()
+	g.Unmarshal(g.Marshal(cfg.Target.Options), &options)
+	props := append(
+		g.MapToKVArr(cfg.TgtConn.DataS()),
+		g.MapToKVArr(g.ToMapString(options))...,
+	)
+
+	tgtFs, err := filesys.NewFileSysClientFromURLContext(t.Context.Ctx, tgtURI, props...)
+	if err != nil {
+		return 0, g.Error(err, "Could not obtain client for: %s", cfg.TgtConn.Type)
+	}
+
+	// rows are counted from the decompressed bytes, as they are written
+	counter := &rowCounter{}
+	if t.sourceFileFormat() == dbio.FileTypeCsv {
+		counter.quote = '"'
+	}
+	countReader, countWriter := io.Pipe()
+	counted := make(chan error, 1)
+	go func() {
+		dReader, err := iop.AutoDecompress(countReader)
+		if err == nil {
+			_, err = io.Copy(counter, dReader)
+		}
+		io.Copy(io.Discard, countReader) // drain on error
+		counted <- err
+	}()
+
+	bw, err = tgtFs.Self().Write(tgtURI, io.TeeReader(&passthroughReader{Reader: reader, ds: ds}, countWriter))
+	countWriter.CloseWithError(err)
+	if cErr := <-counted; err != nil {
+		return bw, g.Error(err, "Could not write to %s", tgtURI)
+	} else if cErr != nil {
+		return bw, g.Error(cErr, "Could not count rows of %s", srcURI)
+	}
+	t.df.AddEgressBytes(uint64(bw))
+
+	count := counter.Lines()
+	if so := cfg.Source.Options; counter.quote != 0 && count > 0 && (so == nil || so.Header == nil || *so.Header) {
+		count-- // header line
+	}
+	atomic.StoreUint64(&ds.Count, count)
+
+	return bw, nil
+}
+
+// isSingleFile returns true if the uri resolves to exactly one existing file
+// (not a folder or a glob pattern)
+func isSingleFile(fs filesys.FileSysClient, uri string) bool {
+	nodes, err := fs.List(uri)
+	if err != nil || len(nodes) != 1 || nodes[0].IsDir {
+		return false
+	}
+	return strings.TrimSuffix(nodes[0].URI, "/") == strings.TrimSuffix(filesys.NormalizeURI(fs, uri), "/")
+}
+
+// passthroughReader counts the bytes read into the datastream
+type passthroughReader struct {
+	io.Reader
+	ds *iop.Datastream
+}
+
+func (r *passthroughReader) Read(p []byte) (n int, err error) {
+	n, err = r.Reader.Read(p)
+	r.ds.AddBytes(int64(n))
+	return
+}
+
+// rowCounter counts the non-empty lines written into it. Line breaks within
+// quotes are not counted, when quote is set.
+type rowCounter struct {
+	quote   byte
+	inQuote bool
+	partial bool // bytes since the last line break
+	lines   uint64
+}
+
+func (c *rowCounter) Write(p []byte) (n int, err error) {
+	for _, b := range p {
+		switch {
+		case c.quote != 0 && b == c.quote:
+			c.inQuote = !c.inQuote
+			c.partial = true
+		case b == '\n' && !c.inQuote:
+			if c.partial {
+				c.lines++
+			}
+			c.partial = false
+		case b != '\r':
+			c.partial = true
+		}
+	}
+	return len(p), nil
+}
+
+// Lines returns the lines counted, including a last line without a line break
+func (c *rowCounter) Lines() uint64 {
+	if c.partial {
+		return c.lines + 1
+	}
+	return c.lines
+}
@@ -0,0 +1,104 @@
+package sling
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilePassthrough(t *testing.T) {
+	t.Setenv("SLING_DUCKDB_COMPUTE", "false")
+	folder := t.TempDir()
+	content := "id,name\n1,a\n2,\"b, c\"\n3,c\n"
+	os.WriteFile(path.Join(folder, "data.csv"), []byte(content), 0644)
+	os.WriteFile(path.Join(folder, "semi.csv"), []byte(strings.ReplaceAll(content, ",", ";")), 0644)
+	os.WriteFile(path.Join(folder, "multi.csv"), []byte("id,name\n1,\"a\nb\"\r\n2,c"), 0644)
+	os.WriteFile(path.Join(folder, "data.jsonl"), []byte("{\"id\":1}\n{\"id\":2}\n\n"), 0644)
+	os.MkdirAll(path.Join(folder, "parts"), 0755)
+	os.WriteFile(path.Join(folder, "parts", "part1.csv"), []byte("id,name\n1,a\n"), 0644)
+	os.WriteFile(path.Join(folder, "parts", "part2.csv"), []byte("id,name\n2,b\n"), 0644)
+
+	run := func(source, target string, tgtOpts *TargetOptions) (*TaskExecution, error) {
+		cfg := localFileConfig(folder, source, target)
+		cfg.Target.Options = tgtOpts
+		return runTestTask(cfg)
+	}
+
+	// bytes are copied as is, rows are counted
+	task, err := run("data.csv", "copy.csv", nil)
+	if assert.NoError(t, err) {
+		assert.True(t, task.usePassthrough())
+		assert.EqualValues(t, 3, task.GetCount())
+		inBytes, outBytes := task.df.Bytes()
+		assert.EqualValues(t, len(content), inBytes)
+		assert.EqualValues(t, len(content), outBytes)
+		data, _ := os.ReadFile(path.Join(folder, "copy.csv"))
+		assert.Equal(t, content, string(data))
+	}
+
+	tests := []struct {
+		source string
+		target string
+		rows   uint64
+	}{
+		{source: "multi.csv", target: "multi_copy.csv", rows: 2}, // quoted line break, no last line break
+		{source: "data.jsonl", target: "copy.jsonl", rows: 2},    // blank line
+		{source: "copy.csv.gz", target: "copy2.csv.gz", rows: 3}, // compressed
+	}
+	_, err = run("data.csv", "copy.csv.gz", nil)
+	require.NoError(t, err)
+	for _, tt := range tests {
+		task, err := run(tt.source, tt.target, nil)
+		if assert.NoError(t, err, tt.source) {
+			assert.True(t, task.usePassthrough(), tt.source)
+			assert.Equal(t, tt.rows, task.GetCount(), tt.source)
+			inBytes, _ := task.df.Bytes()
+			assert.NotZero(t, inBytes, tt.source)
+		}
+	}
+
+	// a glob or folder into a single file, rows are read instead
+	for source, target := range map[string]string{"parts/*.csv": "parts_glob.csv", "parts/": "parts_folder.csv"} {
+		task, err = run(source, target, nil)
+		if assert.NoError(t, err, source) {
+			assert.EqualValues(t, 2, task.GetCount(), source)
+			data, _ := os.ReadFile(path.Join(folder, target))
+			assert.Equal(t, "id,name\n1,a\n2,b\n", string(data), source)
+		}
+	}
+
+	// different compression or column casing
+	task, err = run("data.csv", "copy.csv.gz", nil)
+	if assert.NoError(t, err) {
+		assert.False(t, task.usePassthrough())
+	}
+	task, err = run("data.csv", "upper.csv", &TargetOptions{ColumnCasing: g.Ptr(iop.UpperColumnCasing)})
+	if assert.NoError(t, err) {
+		assert.False(t, task.usePassthrough())
+		data, _ := os.ReadFile(path.Join(folder, "upper.csv"))
+		assert.True(t, strings.HasPrefix(string(data), "ID,NAME\n"))
+	}
+
+	// detected delimiter differs from target delimiter, file is re-encoded
+	task, err = run("semi.csv", "semi_copy.csv", nil)
+	if assert.NoError(t, err) {
+		assert.True(t, task.usePassthrough())
+		assert.EqualValues(t, 3, task.GetCount())
+		data, _ := os.ReadFile(path.Join(folder, "semi_copy.csv"))
+		assert.Equal(t, "id,name\n1,a\n2,b; c\n3,c\n", string(data))
+	}
+
+	// opt-out
+	t.Setenv("SLING_FILE_PASSTHROUGH", "false")
+	task, err = run("data.csv", "copy2.csv", nil)
+	if assert.NoError(t, err) {
+		assert.False(t, task.usePassthrough())
+		assert.EqualValues(t, 3, task.GetCount())
+	}
+}
@@ -0,0 +1,144 @@
+package sling
+
+import (
+	"io"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+	"github.com/slingdata-io/sling-cli/core/dbio/filesys"
+	"github.com/spf13/cast"
+)
+
+// PostReadAction is the action on the source files once loaded
+type PostReadAction string
+
+const (
+	PostReadMove   PostReadAction = "move"   // move into the location
+	PostReadDelete PostReadAction = "delete" // delete the files
+	PostReadTag    PostReadAction = "tag"    // add object tags (s3)
+)
+
+// PostReadOptions processes the source files once loaded (`source.options.post_read`),
+// for the "pick up, load, archive" pattern of drop folders
+type PostReadOptions struct {
+	Action   PostReadAction    `json:"action" yaml:"action"`
+	Location string            `json:"location,omitempty" yaml:"location,omitempty"` // folder to move into, e.g. `s3://bucket/processed/{run_timestamp}/`
+	Tags     map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`         // tags to add, default is `sling_processed: {run_timestamp}`
+}
+
+// Validate checks the options for a source connection type
+func (o *PostReadOptions) Validate(srcType dbio.Type) error {
+	if !srcType.IsFile() {
+		return g.Error("post_read requires a file source")
+	}
+
+	switch o.Action {
+	case PostReadMove:
+		if o.Location == "" {
+			return g.Error("post_read move action requires a location")
+		} else if locType := connection.SchemeType(o.Location); locType != srcType {
+			return g.Error("post_read location must be a url of the source connection (%s): %s", srcType, o.Location)
+		}
+	case PostReadDelete:
+	case PostReadTag:
+		if srcType != dbio.TypeFileS3 {
+			return g.Error("post_read tag action is only supported for s3 sources")
+		}
+	default:
+		return g.Error("invalid post_read action (%s), must be move, delete or tag", o.Action)
+	}
+	return nil
+}
+
+// postReadFiles processes the source files which were read, once loaded
+func (t *TaskExecution) postReadFiles() (err error) {
+	cfg := t.Config
+	if cfg.Source.Options == nil || cfg.Source.Options.PostRead == nil || len(t.readFiles) == 0 {
+		return nil
+	}
+	postRead := cfg.Source.Options.PostRead
+
+	srcURI := cfg.SrcConn.URL()
+	props := g.MapToKVArr(cfg.SrcConn.DataS())
+	fs, err := filesys.NewFileSysClientFromURLContext(t.Context.Ctx, srcURI, props...)
+	if err != nil {
+		return g.Error(err, "could not obtain client for %s", cfg.SrcConn.Type)
+	}
+
+	fMap, err := cfg.GetFormatMap()
+	if err != nil {
+		return g.Error(err, "could not get formatting variables")
+	}
+
+	switch postRead.Action {
+	case PostReadDelete:
+		for _, node := range t.readFiles {
+			if err = filesys.Delete(fs, node.URI); err != nil {
+				return g.Error(err, "could not delete %s", node.URI)
+			}
+		}
+		t.SetProgress("deleted %d source files", len(t.readFiles))
+
+	case PostReadMove:
+		location := strings.TrimSuffix(g.Rm(postRead.Location, fMap), "/")
+
+		locFs, err := filesys.NewFileSysClientFromURLContext(t.Context.Ctx, location, props...)
+		if err != nil {
+			return g.Error(err, "could not obtain client for %s", location)
+		}
+
+		// keep the paths relative to the source folder
+		srcFolder := srcURI
+		if i := strings.IndexAny(srcFolder, "*?[{"); i > -1 {
+			srcFolder = srcFolder[:i]
+		}
+		srcFolder = srcFolder[:strings.LastIndex(srcFolder, "/")+1]
+
+		for _, node := range t.readFiles {
+			locURI := location + "/" + strings.TrimPrefix(strings.TrimPrefix(node.URI, srcFolder), "/")
+
+			reader, err := fs.Self().GetReader(node.URI)
+			if err != nil {
+				return g.Error(err, "could not read %s", node.URI)
+			}
+
+			_, err = locFs.Self().Write(locURI, reader)
+			if closer, ok := reader.(io.Closer); ok {
+				closer.Close()
+			}
+			if err != nil {
+				return g.Error(err, "could not move %s to %s", node.URI, locURI)
+			}
+
+			if err = filesys.Delete(fs, node.URI); err != nil {
+				return g.Error(err, "could not delete %s", node.URI)
+			}
+		}
+		t.SetProgress("moved %d source files to %s", len(t.readFiles), location)
+
+	case PostReadTag:
+		s3Fs, ok := fs.Self().(*filesys.S3FileSysClient)
+		if !ok {
+			return g.Error("post_read tag action is only supported for s3 sources")
+		}
+
+		tags := map[string]string{"sling_processed": cast.ToString(fMap["run_timestamp"])}
+		if len(postRead.Tags) > 0 {
+			tags = map[string]string{}
+			for k, v := range postRead.Tags {
+				tags[k] = g.Rm(v, fMap)
+			}
+		}
+
+		for _, node := range t.readFiles {
+			if err = s3Fs.SetTags(node.URI, tags); err != nil {
+				return g.Error(err, "could not tag %s", node.URI)
+			}
+		}
+		t.SetProgress("tagged %d source files", len(t.readFiles))
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package sling
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostRead(t *testing.T) {
+	t.Setenv("SLING_DUCKDB_COMPUTE", "false")
+	folder := t.TempDir()
+	os.MkdirAll(path.Join(folder, "drop", "sub"), 0755)
+	os.WriteFile(path.Join(folder, "drop", "a.csv"), []byte("id\n1\n2\n"), 0644)
+	os.WriteFile(path.Join(folder, "drop", "sub", "b.csv"), []byte("id\n3\n"), 0644)
+
+	run := func(stream string, postRead *PostReadOptions) (*TaskExecution, error) {
+		cfg := localFileConfig(folder, stream, "out.csv")
+		cfg.Source.Options = &SourceOptions{PostRead: postRead}
+		return runTestTask(cfg)
+	}
+
+	// files are moved, keeping the relative paths
+	location := "file://" + path.Join(folder, "processed", "{run_timestamp}") + "/"
+	task, err := run("drop/", &PostReadOptions{Action: PostReadMove, Location: location})
+	if assert.NoError(t, err) {
+		assert.EqualValues(t, 3, task.GetCount())
+		assert.NoFileExists(t, path.Join(folder, "drop", "a.csv"))
+		assert.NoFileExists(t, path.Join(folder, "drop", "sub", "b.csv"))
+
+		moved, _ := filepath.Glob(path.Join(folder, "processed", "*", "sub", "b.csv"))
+		if assert.Len(t, moved, 1) {
+			data, _ := os.ReadFile(moved[0])
+			assert.Equal(t, "id\n3\n", string(data))
+		}
+	}
+
+	// files are deleted
+	os.WriteFile(path.Join(folder, "drop", "c.csv"), []byte("id\n4\n"), 0644)
+	task, err = run("drop/c.csv", &PostReadOptions{Action: PostReadDelete})
+	if assert.NoError(t, err) {
+		assert.EqualValues(t, 1, task.GetCount())
+		assert.NoFileExists(t, path.Join(folder, "drop", "c.csv"))
+	}
+
+	// invalid options
+	_, err = run("drop", &PostReadOptions{Action: PostReadMove})
+	assert.Error(t, err)
+	_, err = run("drop", &PostReadOptions{Action: PostReadTag})
+	assert.Error(t, err)
+	_, err = run("drop", &PostReadOptions{Action: "archive"})
+	assert.Error(t, err)
+}
@@ -18,13 +18,93 @@ func (t *TaskExecution) ReadFromDB(cfg *Config, srcConn database.Connection) (df
 
 	setStage("3 - prepare-dataflow")
 
-	selectFieldsStr := "*"
-	sTable, err := t.GetSourceTable()
+	// replay the source stream from its fixture
+	if df, ok, err := t.replaySource(cfg); err != nil {
+		return t.df, err
+	} else if ok {
+		return df, nil
+	}
+
+	// read from the local source cache, if fresh
+	if df, ok := t.readSourceCache(cfg); ok {
+		return df, nil
+	}
+
+	if err = t.attachConnections(srcConn); err != nil {
+		return t.df, err
+	}
+
+	sTable, selectFieldsStr, useKeyset, err := t.sourceTable(cfg, srcConn)
 	if err != nil {
-		err = g.Error(err, "Could not parse source stream text")
 		return t.df, err
 	}
 
+	if useKeyset {
+		g.Debug("using keyset pagination on key %s", cfg.Source.PrimaryKey()[0])
+		ds, err := database.StreamRowsKeyset(srcConn, sTable, database.KeysetOptions{
+			Key:       cfg.Source.PrimaryKey()[0],
+			Fields:    strings.Split(selectFieldsStr, ","),
+			Where:     cfg.Source.Where,
+			ChunkSize: cfg.Source.ChunkSize(),
+			Offset:    cfg.Source.Offset(),
+			Limit:     cfg.Source.Limit(),
+		})
+		if err != nil {
+			err = g.Error(err, "Could not stream with keyset pagination")
+			return t.df, err
+		}
+
+		df, err = iop.MakeDataFlow(ds)
+		if err != nil {
+			err = g.Error(err, "Could not make dataflow")
+			return t.df, err
+		}
+	} else {
+		df, err = srcConn.BulkExportFlow(sTable)
+		if err != nil {
+			err = g.Error(err, "Could not BulkExportFlow")
+			return t.df, err
+		}
+	}
+
+	err = t.setColumnKeys(df)
+	if err != nil {
+		err = g.Error(err, "Could not set column keys")
+		return t.df, err
+	}
+
+	// write into the local source cache
+	if df, err = t.writeSourceCache(cfg, df); err != nil {
+		err = g.Error(err, "could not cache source stream")
+		return t.df, err
+	}
+
+	// record the source stream into its fixture
+	if df, err = t.recordSource(cfg, df); err != nil {
+		return t.df, err
+	}
+
+	// keep a random sample of the rows
+	if df, err = t.sampleDataflow(df); err != nil {
+		return t.df, err
+	}
+
+	g.Trace("%#v", df.Columns.Types())
+	setStage("3 - dataflow-stream")
+
+	return
+}
+
+// sourceTable returns the source table with the SQL to read it, for the mode
+// and incremental value of the config
+func (t *TaskExecution) sourceTable(cfg *Config, srcConn database.Connection) (sTable database.Table, selectFieldsStr string, useKeyset bool, err error) {
+	selectFieldsStr = "*"
+	sTable, err = t.GetSourceTable()
+	if err != nil {
+		err = g.Error(err, "Could not parse source stream text")
+		return sTable, selectFieldsStr, useKeyset, err
+	}
+
 	// get source columns
 	st := sTable
 	st.SQL = g.R(st.SQL, "incremental_where_cond", "1=1") // so we get the columns, and not change the orig SQL
@@ -32,7 +112,7 @@ func (t *TaskExecution) ReadFromDB(cfg *Config, srcConn database.Connection) (df
 	sTable.Columns, err = srcConn.GetSQLColumns(st)
 	if err != nil {
 		err = g.Error(err, "Could not get source columns")
-		return t.df, err
+		return sTable, selectFieldsStr, useKeyset, err
 	}
 
 	if len(cfg.Source.Select) > 0 {
@@ -46,7 +126,7 @@ func (t *TaskExecution) ReadFromDB(cfg *Config, srcConn database.Connection) (df
 
 		if len(excluded) > 0 {
 			if len(excluded) != len(cfg.Source.Select) {
-				return t.df, g.Error("All specified select columns must be excluded with prefix '-'. Cannot do partial exclude.")
+				return sTable, selectFieldsStr, useKeyset, g.Error("All specified select columns must be excluded with prefix '-'. Cannot do partial exclude.")
 			}
 
 			q := database.GetQualifierQuote(srcConn.GetType())
@@ -61,7 +141,7 @@ func (t *TaskExecution) ReadFromDB(cfg *Config, srcConn database.Connection) (df
 			})
 
 			if len(includedCols) == 0 {
-				return t.df, g.Error("All available columns were excluded")
+				return sTable, selectFieldsStr, useKeyset, g.Error("All available columns were excluded")
 			}
 			fields = iop.Columns(includedCols).Names()
 		}
@@ -144,10 +224,17 @@ func (t *TaskExecution) ReadFromDB(cfg *Config, srcConn database.Connection) (df
 				"incremental_where_cond", incrementalWhereCond,
 				"update_key", srcConn.Quote(cfg.Source.UpdateKey, false),
 			)
+		} else if definition, err := g.UnmarshalMap(sTable.SQL); err == nil {
+			// json stream definition (e.g. a report), receives the incremental / backfill options
+			options, _ := g.UnmarshalMap(incrementalWhereCond)
+			for k, v := range options {
+				definition[k] = v
+			}
+			sTable.SQL = g.Marshal(definition)
 		} else {
 			if g.In(t.Config.Mode, IncrementalMode, BackfillMode) && !(strings.Contains(sTable.SQL, "{incremental_where_cond}") || strings.Contains(sTable.SQL, "{incremental_value}")) {
 				err = g.Error("Since using %s mode + custom SQL, with an `update_key`, the SQL text needs to contain a placeholder: {incremental_where_cond} or {incremental_value}. See https://docs.slingdata.io for help.", t.Config.Mode)
-				return t.df, err
+				return sTable, selectFieldsStr, useKeyset, err
 			}
 
 			sTable.SQL = g.R(
@@ -166,14 +253,22 @@ func (t *TaskExecution) ReadFromDB(cfg *Config, srcConn database.Connection) (df
 	sTable.SQL = g.R(sTable.SQL, "incremental_where_cond", "1=1") // if running non-incremental mode
 	sTable.SQL = g.R(sTable.SQL, "incremental_value", "null")     // if running non-incremental mode
 
-	// construct select statement for selected fields
-	if selectFieldsStr != "*" || cfg.Source.Limit() > 0 {
+	// use keyset pagination for offset / chunked reads of a table, if a single key is provided
+	useKeyset = !sTable.IsQuery() && len(cfg.Source.PrimaryKey()) == 1 &&
+		(cfg.Source.Offset() > 0 || cfg.Source.ChunkSize() > 0)
+
+	// construct select statement for selected fields, or a sample of the table
+	if sampleSQL := t.sampleTableSQL(srcConn, sTable, selectFieldsStr); sampleSQL != "" && !useKeyset {
+		sTable.SQL = sampleSQL
+	} else if !useKeyset && (selectFieldsStr != "*" || cfg.Source.Limit() > 0) {
 		sTable.SQL = sTable.Select(database.SelectOptions{
 			Fields: strings.Split(selectFieldsStr, ","),
 			Where:  cfg.Source.Where,
 			Limit:  cfg.Source.Limit(),
 			Offset: cfg.Source.Offset(),
 		})
+	} else if !useKeyset && cfg.Source.Offset() > 0 {
+		g.Warn("offset is ignored without a limit or a single-column primary key")
 	}
 
 	// set constraints
@@ -183,22 +278,7 @@ func (t *TaskExecution) ReadFromDB(cfg *Config, srcConn database.Connection) (df
 		}
 	}
 
-	df, err = srcConn.BulkExportFlow(sTable)
-	if err != nil {
-		err = g.Error(err, "Could not BulkExportFlow")
-		return t.df, err
-	}
-
-	err = t.setColumnKeys(df)
-	if err != nil {
-		err = g.Error(err, "Could not set column keys")
-		return t.df, err
-	}
-
-	g.Trace("%#v", df.Columns.Types())
-	setStage("3 - dataflow-stream")
-
-	return
+	return sTable, selectFieldsStr, useKeyset, nil
 }
 
 // ReadFromFile reads from a source file
@@ -206,6 +286,18 @@ func (t *TaskExecution) ReadFromFile(cfg *Config) (df *iop.Dataflow, err error)
 
 	setStage("3 - prepare-dataflow")
 
+	// replay the source stream from its fixture
+	if df, ok, err := t.replaySource(cfg); err != nil {
+		return t.df, err
+	} else if ok {
+		return df, nil
+	}
+
+	// read from the local source cache, if fresh
+	if df, ok := t.readSourceCache(cfg); ok {
+		return df, nil
+	}
+
 	// sets metadata
 	metadata := t.setGetMetadata()
 
@@ -215,7 +307,7 @@ func (t *TaskExecution) ReadFromFile(cfg *Config) (df *iop.Dataflow, err error)
 
 	if t.Config.HasIncrementalVal() {
 		// file stream incremental mode
-		if t.Config.Source.UpdateKey == slingLoadedAtColumn {
+		if t.Config.Source.UpdateKey == t.Config.loadedAtColumn() {
 			options["SLING_FS_TIMESTAMP"] = t.Config.IncrementalVal
 			g.Debug(`file stream using file_sys_timestamp=%#v and update_key=%s`, t.Config.IncrementalVal, t.Config.Source.UpdateKey)
 		} else {
@@ -247,6 +339,11 @@ func (t *TaskExecution) ReadFromFile(cfg *Config) (df *iop.Dataflow, err error)
 			IncrementalValue: cfg.IncrementalVal,
 		}
 
+		// loaded_at incremental is handled via file timestamps (SLING_FS_TIMESTAMP)
+		if cfg.Source.UpdateKey == cfg.loadedAtColumn() {
+			fsCfg.IncrementalKey = ""
+		}
+
 		// set incrementalValue if incremental or backfill
 		if t.isIncrementalWithUpdateKey() || t.Config.Mode == BackfillMode {
 			fsCfg.IncrementalValue = cfg.IncrementalVal
@@ -255,6 +352,24 @@ func (t *TaskExecution) ReadFromFile(cfg *Config) (df *iop.Dataflow, err error)
 		if ffmt := cfg.Source.Options.Format; ffmt != nil {
 			fsCfg.Format = *ffmt
 		}
+
+		// only read the new files since the last run
+		if cfg.Source.Options.FileIncremental != nil {
+			if fsCfg.FileSelect, err = t.selectIncrementalFiles(fs, uri, fsCfg.FileSelect); err != nil {
+				return t.df, g.Error(err, "could not select incremental files")
+			}
+		}
+
+		// snapshot the files read, to process them once loaded
+		if cfg.Source.Options.PostRead != nil {
+			nodes, err := fs.ListRecursive(uri)
+			if err != nil {
+				return t.df, g.Error(err, "could not list %s", uri)
+			}
+			t.readFiles = nodes.Files().Selected(fsCfg.FileSelect)
+			fsCfg.FileSelect = g.Ptr(t.readFiles.URIs())
+		}
+
 		df, err = fs.ReadDataflow(uri, fsCfg)
 		if err != nil {
 			err = g.Error(err, "Could not FileSysReadDataflow for %s", cfg.SrcConn.Type)
@@ -286,6 +401,22 @@ func (t *TaskExecution) ReadFromFile(cfg *Config) (df *iop.Dataflow, err error)
 		return t.df, err
 	}
 
+	// write into the local source cache
+	if df, err = t.writeSourceCache(cfg, df); err != nil {
+		err = g.Error(err, "could not cache source stream")
+		return t.df, err
+	}
+
+	// record the source stream into its fixture
+	if df, err = t.recordSource(cfg, df); err != nil {
+		return t.df, err
+	}
+
+	// keep a random sample of the rows
+	if df, err = t.sampleDataflow(df); err != nil {
+		return t.df, err
+	}
+
 	g.Trace("%#v", df.Columns.Types())
 	setStage("3 - dataflow-stream")
 
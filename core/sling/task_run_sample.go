@@ -0,0 +1,106 @@
+package sling
+
+import (
+	"math/rand"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio/database"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+)
+
+// SampleMethod is how the rows of a sample are picked
+type SampleMethod string
+
+const (
+	SampleHead   SampleMethod = "head"   // the first rows
+	SampleRandom SampleMethod = "random" // random rows, via TABLESAMPLE for tables, otherwise reservoir sampling
+)
+
+// SampleOptions loads only a sample of the source rows (`source.options.sample`),
+// to test pipelines cheaply before full runs
+type SampleOptions struct {
+	Rows   int          `json:"rows" yaml:"rows"`
+	Method SampleMethod `json:"method,omitempty" yaml:"method,omitempty"` // head (default) or random
+}
+
+// Validate checks the options
+func (so *SampleOptions) Validate() error {
+	if so.Rows < 1 {
+		return g.Error("sample rows must be greater than 0")
+	}
+	so.Method = SampleMethod(strings.ToLower(string(so.Method)))
+	if !g.In(so.Method, "", SampleHead, SampleRandom) {
+		return g.Error("invalid sample method (%s), must be head or random", so.Method)
+	}
+	return nil
+}
+
+// sampleRandom returns the random sample options, if specified
+func (cfg *Config) sampleRandom() *SampleOptions {
+	if so := cfg.Source.Options; so != nil && so.Sample != nil && so.Sample.Method == SampleRandom {
+		return so.Sample
+	}
+	return nil
+}
+
+// sampleTableSQL returns the sampling query of a source table (TABLESAMPLE),
+// or blank if the database has no sample template
+func (t *TaskExecution) sampleTableSQL(srcConn database.Connection, sTable database.Table, selectFieldsStr string) string {
+	sample := t.Config.sampleRandom()
+	if sample == nil || sTable.IsQuery() || t.Config.Source.Where != "" {
+		return ""
+	}
+
+	template := srcConn.GetTemplateValue("core.sample")
+	if template == "" {
+		return ""
+	}
+
+	return g.R(
+		template,
+		"fields", selectFieldsStr,
+		"table", sTable.FDQN(),
+		"n", cast.ToString(sample.Rows),
+	)
+}
+
+// sampleDataflow returns the dataflow of a random sample of the rows,
+// picked via reservoir sampling
+func (t *TaskExecution) sampleDataflow(df *iop.Dataflow) (*iop.Dataflow, error) {
+	sample := t.Config.sampleRandom()
+	if sample == nil {
+		return df, nil
+	}
+
+	ds := iop.MergeDataflow(df)
+	reservoir := make([][]any, 0, sample.Rows)
+	count := 0
+	for row := range ds.Rows() {
+		count++
+		if len(reservoir) < sample.Rows {
+			reservoir = append(reservoir, append([]any{}, row...))
+		} else if i := rand.Intn(count); i < sample.Rows {
+			reservoir[i] = append([]any{}, row...)
+		}
+	}
+
+	if err := ds.Err(); err != nil {
+		return df, g.Error(err, "could not read source rows")
+	} else if err = df.Err(); err != nil {
+		return df, g.Error(err, "could not read source rows")
+	}
+	g.Debug("sampled %d random rows of %d", len(reservoir), count)
+
+	data := iop.NewDataset(ds.Columns)
+	data.Rows = reservoir
+	data.Inferred = true
+
+	sampleDf, err := iop.MakeDataFlow(data.Stream())
+	if err != nil {
+		return df, g.Error(err, "could not make sample dataflow")
+	}
+
+	return sampleDf, nil
+}
@@ -0,0 +1,61 @@
+package sling
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSample(t *testing.T) {
+	t.Setenv("SLING_DUCKDB_COMPUTE", "false")
+	folder := t.TempDir()
+
+	content := "id,name\n"
+	for i := 1; i <= 100; i++ {
+		content += g.F("%d,name_%d\n", i, i)
+	}
+	os.WriteFile(path.Join(folder, "data.csv"), []byte(content), 0644)
+
+	run := func(sample *SampleOptions) ([]string, error) {
+		cfg := localFileConfig(folder, "data.csv", "out.csv")
+		cfg.Source.Options = &SourceOptions{Sample: sample}
+		if _, err := runTestTask(cfg); err != nil {
+			return nil, err
+		}
+		data, _ := os.ReadFile(path.Join(folder, "out.csv"))
+		return strings.Split(strings.TrimSpace(string(data)), "\n")[1:], nil
+	}
+
+	// head
+	rows, err := run(&SampleOptions{Rows: 10})
+	if assert.NoError(t, err) && assert.Len(t, rows, 10) {
+		assert.Equal(t, "1,name_1", rows[0])
+		assert.Equal(t, "10,name_10", rows[9])
+	}
+
+	// random, via reservoir sampling
+	rows, err = run(&SampleOptions{Rows: 10, Method: SampleRandom})
+	if assert.NoError(t, err) && assert.Len(t, rows, 10) {
+		assert.Len(t, lo.Uniq(rows), 10)
+		for _, row := range rows {
+			assert.Contains(t, content, "\n"+row+"\n")
+		}
+	}
+
+	// fewer rows than the sample
+	rows, err = run(&SampleOptions{Rows: 1000, Method: SampleRandom})
+	if assert.NoError(t, err) {
+		assert.Len(t, rows, 100)
+	}
+
+	_, err = run(&SampleOptions{Rows: 0})
+	assert.ErrorContains(t, err, "sample rows must be greater than 0")
+
+	_, err = run(&SampleOptions{Rows: 10, Method: "tail"})
+	assert.ErrorContains(t, err, "invalid sample method")
+}
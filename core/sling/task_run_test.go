@@ -0,0 +1,44 @@
+package sling
+
+import (
+	"context"
+	"testing"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryTag(t *testing.T) {
+	newTask := func(tag *string) *TaskExecution {
+		cfg := &Config{
+			StreamName: "public.orders",
+			Target:     Target{Object: "analytics.orders", Options: &TargetOptions{QueryTag: tag}},
+			Env:        map[string]string{},
+		}
+		cfg.TgtConn, _ = connection.NewConnectionFromURL("SF", "snowflake://user1@account1/DB1")
+		cfg.TgtConn.Data["query_tag"] = g.PtrVal(tag)
+		return &TaskExecution{Config: cfg, ExecID: "exec1", Context: g.NewContext(context.Background())}
+	}
+
+	// default, the stream identifiers
+	task := newTask(nil)
+	task.setQueryTag(&task.Config.TgtConn)
+	tag, _ := g.UnmarshalMap(cast.ToString(task.Config.TgtConn.Data["query_tag"]))
+	assert.Equal(t, "sling", tag["app"])
+	assert.Equal(t, "exec1", tag["exec_id"])
+	assert.Equal(t, "public.orders", tag["stream"])
+	assert.Equal(t, "analytics.orders", tag["object"])
+
+	// custom, rendered with the runtime variables
+	task = newTask(g.String("sling/{object_name}/{exec_id}"))
+	task.setQueryTag(&task.Config.TgtConn)
+	assert.Equal(t, "sling/analytics.orders/exec1", task.Config.TgtConn.Data["query_tag"])
+
+	// only for snowflake
+	task = newTask(nil)
+	task.Config.TgtConn, _ = connection.NewConnectionFromURL("PG", "postgres://user1@host1/db1")
+	task.setQueryTag(&task.Config.TgtConn)
+	assert.Nil(t, task.Config.TgtConn.Data["query_tag"])
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"strings"
@@ -26,12 +27,13 @@ func (t *TaskExecution) WriteToFile(cfg *Config, df *iop.Dataflow) (cnt uint64,
 	var bw int64
 	defer t.PBar.Finish()
 	setStage("5 - load-into-final")
+	setRateLimit(cfg, df)
 
 	if uri := cfg.TgtConn.URL(); uri != "" {
 		dateMap := iop.GetISO8601DateMap(time.Now())
 		cfg.TgtConn.Set(g.M("url", g.Rm(uri, dateMap)))
 
-		if len(df.Buffer) == 0 && !cast.ToBool(os.Getenv("SLING_ALLOW_EMPTY")) {
+		if len(df.Buffer) == 0 && !cast.ToBool(cfg.GetEnv("SLING_ALLOW_EMPTY")) {
 			g.Warn("No data or records found in stream. Nothing to do. To allow Sling to create empty files, set SLING_ALLOW_EMPTY=TRUE")
 			return
 		}
@@ -50,15 +52,24 @@ func (t *TaskExecution) WriteToFile(cfg *Config, df *iop.Dataflow) (cnt uint64,
 			return cnt, err
 		}
 
+		// apply column mapping
+		if err = applyColumnMappingToDf(df, t.Config.Target.Options.ColumnMapping); err != nil {
+			return cnt, err
+		}
+
 		// apply column casing
 		applyColumnCasingToDf(df, fs.FsType(), t.Config.Target.Options.ColumnCasing)
 
 		// use duckdb for writing parquet
+		var files []filesys.FileReady
 		if t.shouldWriteViaDuckDB(uri) {
+			if g.PtrVal(cfg.Target.Options.Atomic) {
+				g.Warn("target option `atomic` is not supported for partitioned targets, ignoring")
+			}
 			// push to temp duck file
 			bw, err = writeDataflowViaDuckDB(t, df, fs, uri)
 		} else {
-			bw, err = filesys.WriteDataflow(fs, df, uri)
+			bw, files, err = filesys.WriteDataflowFiles(fs, df, uri)
 		}
 		if err != nil {
 			err = g.Error(err, "Could not write")
@@ -69,12 +80,36 @@ func (t *TaskExecution) WriteToFile(cfg *Config, df *iop.Dataflow) (cnt uint64,
 		df.SyncColumns()
 		df.SyncStats()
 
+		if cfg.Target.Options.Manifest != nil {
+			// files written via duckdb are listed
+			if files == nil {
+				nodes, _ := fs.ListRecursive(uri)
+				for _, node := range nodes.Files() {
+					files = append(files, filesys.FileReady{Node: node, BytesW: cast.ToInt64(node.Size), Rows: -1})
+				}
+			}
+
+			if err = t.writeManifest(files, cnt); err != nil {
+				return cnt, g.Error(err, "could not write manifest")
+			}
+		}
+
 	} else if cfg.Options.StdOut {
+		// apply column mapping
+		if err = applyColumnMappingToDf(df, t.Config.Target.Options.ColumnMapping); err != nil {
+			return cnt, err
+		}
+
 		// apply column casing
 		applyColumnCasingToDf(df, dbio.TypeFileLocal, t.Config.Target.Options.ColumnCasing)
 
 		limit := cast.ToUint64(cfg.Source.Limit())
 
+		// pass to the consumer (e.g. served)
+		if cfg.Options.Consume != nil {
+			return cfg.Options.Consume(df)
+		}
+
 		// store as dataset
 		if cfg.Options.Dataset {
 			df.Limit = limit
@@ -88,6 +123,14 @@ func (t *TaskExecution) WriteToFile(cfg *Config, df *iop.Dataflow) (cnt uint64,
 			return cnt, nil
 		}
 
+		// write in another format than csv (e.g. to pipe into jq)
+		if format := stdoutFormat(cfg.Target.Options.Format); format != dbio.FileTypeCsv {
+			if bw, err = writeToStdout(df, format, df.StreamConfig()); err != nil {
+				return cnt, g.Error(err, "Could not write to Stdout")
+			}
+			return df.Count(), nil
+		}
+
 		options := map[string]string{"delimiter": ","}
 		g.Unmarshal(g.Marshal(cfg.Target.Options), &options)
 
@@ -141,12 +184,82 @@ func (t *TaskExecution) WriteToFile(cfg *Config, df *iop.Dataflow) (cnt uint64,
 	return
 }
 
+// stdoutFormat returns the format to write to stdout, csv by default
+func stdoutFormat(format dbio.FileType) dbio.FileType {
+	switch strings.ToLower(string(format)) {
+	case "", "csv":
+		return dbio.FileTypeCsv
+	case "jsonl", "ndjson", "jsonlines":
+		return dbio.FileTypeJsonLines
+	}
+	return dbio.FileType(strings.ToLower(string(format)))
+}
+
+// writeToStdout writes the dataflow to stdout as a single stream
+func writeToStdout(df *iop.Dataflow, format dbio.FileType, sc iop.StreamConfig) (bw int64, err error) {
+	if !g.In(format, dbio.FileTypeJson, dbio.FileTypeJsonLines, dbio.FileTypeParquet, dbio.FileTypeArrow) {
+		return 0, g.Error("unsupported stdout format: %s (expected csv, json, jsonl, parquet or arrow)", format)
+	}
+
+	ds := iop.MergeDataflow(df)
+	sc.FileMaxRows, sc.FileMaxBytes = 0, 0 // single stream
+
+	readerChn := make(chan io.Reader)
+	go func() {
+		defer close(readerChn)
+		switch format {
+		case dbio.FileTypeJson:
+			for reader := range ds.NewJsonReaderChnl(sc) {
+				readerChn <- reader
+			}
+		case dbio.FileTypeJsonLines:
+			for reader := range ds.NewJsonLinesReaderChnl(sc) {
+				readerChn <- reader
+			}
+		case dbio.FileTypeParquet:
+			for batchR := range ds.NewParquetReaderChnl(sc) {
+				readerChn <- batchR.Reader
+			}
+		case dbio.FileTypeArrow:
+			for batchR := range ds.NewArrowReaderChnl(sc) {
+				readerChn <- batchR.Reader
+			}
+		}
+	}()
+
+	bufStdout := bufio.NewWriter(os.Stdout)
+	defer bufStdout.Flush()
+	for reader := range readerChn {
+		bw0, err := filesys.Write(reader, bufStdout)
+		bw += bw0
+		if err != nil {
+			return bw, err
+		}
+	}
+
+	if err = ds.Err(); err != nil {
+		return bw, g.Error(err, "encountered stream error")
+	}
+	return bw, nil
+}
+
 // WriteToDb writes to a target DB
 // create temp table
 // load into temp table
 // insert / incremental / replace into target table
+// setRateLimit throttles the dataflow to the target rate_limit
+func setRateLimit(cfg *Config, df *iop.Dataflow) {
+	if cfg.Target.Options == nil || cfg.Target.Options.RateLimit == nil {
+		return
+	}
+	if rl, err := iop.ParseRateLimit(*cfg.Target.Options.RateLimit); err == nil {
+		df.SetRateLimit(rl)
+	}
+}
+
 func (t *TaskExecution) WriteToDb(cfg *Config, df *iop.Dataflow, tgtConn database.Connection) (cnt uint64, err error) {
 	defer t.PBar.Finish()
+	setRateLimit(cfg, df)
 
 	// Detect empty columns
 	if len(df.Columns) == 0 {
@@ -155,12 +268,8 @@ func (t *TaskExecution) WriteToDb(cfg *Config, df *iop.Dataflow, tgtConn databas
 	}
 
 	// write directly to the final table (no temp table)
-	if directInsert := cast.ToBool(os.Getenv("SLING_DIRECT_INSERT")); directInsert {
-		if g.In(cfg.Mode, IncrementalMode, BackfillMode) && len(cfg.Source.PrimaryKey()) > 0 {
-			g.Warn("mode '%s' with a primary-key is not supported for direct write, falling back to using a temporary table.", cfg.Mode)
-		} else {
-			return t.writeToDbDirectly(cfg, df, tgtConn)
-		}
+	if useDirectInsert(cfg, true) {
+		return t.writeToDbDirectly(cfg, df, tgtConn)
 	}
 
 	// Initialize target and temp tables
@@ -217,10 +326,13 @@ func (t *TaskExecution) WriteToDb(cfg *Config, df *iop.Dataflow, tgtConn databas
 	setStage("4 - load-into-temp")
 
 	// Add cleanup task for temp table
+	failed := true // until the final transaction is committed
+	defer func() { failed = err != nil }()
 	t.AddCleanupTaskFirst(func() {
-		if cast.ToBool(os.Getenv("SLING_KEEP_TEMP")) {
+		if cast.ToBool(cfg.GetEnv("SLING_KEEP_TEMP")) {
 			return
 		}
+		failed = failed || t.Context.Ctx.Err() != nil
 
 		conn := tgtConn
 		if tgtConn.Context().Err() != nil {
@@ -229,8 +341,21 @@ func (t *TaskExecution) WriteToDb(cfg *Config, df *iop.Dataflow, tgtConn databas
 				conn.Connect()
 			}
 		}
+		defer conn.Close()
+
+		if failed && cfg.Target.Options.FailureTable != "" {
+			if err := copyToFailureTable(conn, tableTmp, cfg.Target.Options.FailureTable); err != nil {
+				g.LogError(err)
+			} else {
+				g.Warn("the staged data of the failed load was copied into %s", cfg.Target.Options.FailureTable)
+			}
+		}
+
+		if failed && g.PtrVal(cfg.Target.Options.KeepTmpTable) {
+			g.Warn("keeping temp table %s of the failed load (keep_tmp_table)", tableTmp.FullName())
+			return
+		}
 		g.LogError(conn.DropTable(tableTmp.FullName()))
-		conn.Close()
 	})
 
 	// Begin transaction for temp table operations
@@ -294,7 +419,7 @@ func (t *TaskExecution) WriteToDb(cfg *Config, df *iop.Dataflow, tgtConn databas
 	}
 
 	// Handle empty data case
-	if cnt == 0 && !cast.ToBool(os.Getenv("SLING_ALLOW_EMPTY_TABLES")) && !cast.ToBool(os.Getenv("SLING_ALLOW_EMPTY")) {
+	if cnt == 0 && !cast.ToBool(cfg.GetEnv("SLING_ALLOW_EMPTY_TABLES")) && !cast.ToBool(cfg.GetEnv("SLING_ALLOW_EMPTY")) {
 		g.Warn("no data or records found in stream. Nothing to do. To allow Sling to create empty tables, set SLING_ALLOW_EMPTY=TRUE")
 		return 0, nil
 	} else if cnt > 0 {
@@ -306,7 +431,7 @@ func (t *TaskExecution) WriteToDb(cfg *Config, df *iop.Dataflow, tgtConn databas
 		df.SyncStats()
 
 		// Checksum Comparison, data quality. Limit to env var SLING_CHECKSUM_ROWS, cause sums get too high
-		if val := cast.ToUint64(os.Getenv("SLING_CHECKSUM_ROWS")); val > 0 && df.Count() <= val {
+		if val := cast.ToUint64(cfg.GetEnv("SLING_CHECKSUM_ROWS")); val > 0 && df.Count() <= val {
 			err = tgtConn.CompareChecksums(tableTmp.FullName(), df.Columns)
 			if err != nil {
 				return
@@ -338,7 +463,7 @@ func (t *TaskExecution) WriteToDb(cfg *Config, df *iop.Dataflow, tgtConn databas
 	// Transfer data from temp to final table
 	if cnt == 0 {
 		t.SetProgress("0 rows inserted. Nothing to do.")
-	} else if err := transferData(cfg, tgtConn, tableTmp, targetTable); err != nil {
+	} else if err := transferData(t, cfg, tgtConn, tableTmp, targetTable); err != nil {
 		err = g.Error(err, "error transferring data from temp to final table")
 		return 0, err
 	}
@@ -349,12 +474,23 @@ func (t *TaskExecution) WriteToDb(cfg *Config, df *iop.Dataflow, tgtConn databas
 		return 0, err
 	}
 
+	// Execute assertion checks, before commit
+	if err := executeChecks(t, tgtConn, targetTable, cfg.Target.Options.Checks); err != nil {
+		return 0, err
+	}
+
 	// Commit transaction
 	if err := tgtConn.Commit(); err != nil {
 		err = g.Error(err, "could not commit final transaction")
 		return 0, err
 	}
 
+	// Apply column classification tags
+	if err := applyColumnTags(cfg, tgtConn, targetTable, df.Columns); err != nil {
+		err = g.Error(err, "could not apply column tags")
+		return 0, err
+	}
+
 	// Set progress as finished
 	if err := df.Err(); err != nil {
 		setStage("6 - closing")
@@ -366,10 +502,33 @@ func (t *TaskExecution) WriteToDb(cfg *Config, df *iop.Dataflow, tgtConn databas
 	return cnt, nil
 }
 
+// useDirectInsert returns whether to write directly to the final table, with the
+// direct_insert or commit_every_* target options (or SLING_DIRECT_INSERT). Falls back to a temp table
+// when the mode requires one.
+func useDirectInsert(cfg *Config, warn bool) bool {
+	if !g.PtrVal(cfg.Target.Options.DirectInsert) && !cfg.Target.Options.CommitsPeriodically() && !cast.ToBool(cfg.GetEnv("SLING_DIRECT_INSERT")) {
+		return false
+	}
+
+	fallback := ""
+	if g.In(cfg.Mode, IncrementalMode, BackfillMode, SCD2Mode) && len(cfg.Source.PrimaryKey()) > 0 {
+		fallback = g.F("mode '%s' with a primary-key is not supported for direct write", cfg.Mode)
+	} else if cfg.Target.Options.Dedup != nil {
+		fallback = "dedup is not supported for direct write"
+	} else if cfg.Mode == PartitionOverwriteMode {
+		fallback = g.F("mode '%s' is not supported for direct write", cfg.Mode)
+	}
+
+	if fallback != "" && warn {
+		g.Warn(fallback + ", falling back to using a temporary table.")
+	}
+	return fallback == ""
+}
+
 func (t *TaskExecution) writeToDbDirectly(cfg *Config, df *iop.Dataflow, tgtConn database.Connection) (cnt uint64, err error) {
 	// writing directly does not support incremental/backfill with a primary key
 	// (which requires a merge/upsert). We can only insert.
-	if g.In(cfg.Mode, IncrementalMode, BackfillMode) && len(cfg.Source.PrimaryKey()) > 0 {
+	if g.In(cfg.Mode, IncrementalMode, BackfillMode, SCD2Mode) && len(cfg.Source.PrimaryKey()) > 0 {
 		return 0, g.Error("mode '%s' with a primary-key is not supported for direct write.", cfg.Mode)
 	}
 
@@ -440,7 +599,11 @@ func (t *TaskExecution) writeToDbDirectly(cfg *Config, df *iop.Dataflow, tgtConn
 	}
 
 	// Bulk import data directly into final table
-	cnt, err = tgtConn.BulkImportFlow(targetTable.FullName(), df)
+	if cfg.Target.Options.CommitsPeriodically() {
+		cnt, err = t.importInCommits(cfg, tgtConn, targetTable, df, &txOptions)
+	} else {
+		cnt, err = tgtConn.BulkImportFlow(targetTable.FullName(), df)
+	}
 	if err != nil {
 		tgtConn.Rollback()
 		err = g.Error(err, "could not insert into "+targetTable.FullName())
@@ -465,7 +628,7 @@ func (t *TaskExecution) writeToDbDirectly(cfg *Config, df *iop.Dataflow, tgtConn
 
 		if cnt > 0 {
 			// Checksum Comparison, data quality. Limit to env var SLING_CHECKSUM_ROWS, cause sums get too high
-			if val := cast.ToUint64(os.Getenv("SLING_CHECKSUM_ROWS")); val > 0 && df.Count() <= val {
+			if val := cast.ToUint64(cfg.GetEnv("SLING_CHECKSUM_ROWS")); val > 0 && df.Count() <= val {
 				err = tgtConn.CompareChecksums(targetTable.FullName(), df.Columns)
 				if err != nil {
 					return cnt, g.Error(err, "error validating checksums")
@@ -474,12 +637,23 @@ func (t *TaskExecution) writeToDbDirectly(cfg *Config, df *iop.Dataflow, tgtConn
 		}
 	}
 
+	// Execute assertion checks, before commit
+	if err := executeChecks(t, tgtConn, targetTable, cfg.Target.Options.Checks); err != nil {
+		return 0, err
+	}
+
 	// Commit final transaction
 	if err := tgtConn.Commit(); err != nil {
 		err = g.Error(err, "could not commit final transaction")
 		return 0, err
 	}
 
+	// Apply column classification tags
+	if err := applyColumnTags(cfg, tgtConn, targetTable, df.Columns); err != nil {
+		err = g.Error(err, "could not apply column tags")
+		return 0, err
+	}
+
 	// Handle empty data case
 	if cnt == 0 {
 		g.Warn("no data or records found in stream. Nothing to insert.")
@@ -606,6 +780,93 @@ func dropTableIfExists(tgtConn database.Connection, tableName string) error {
 	return nil
 }
 
+// importInCommits imports the dataflow into the final table in chunks, committing
+// each one (commit_every_rows / commit_every_seconds), to shorten the transactions
+func (t *TaskExecution) importInCommits(cfg *Config, tgtConn database.Connection, targetTable database.Table, df *iop.Dataflow, txOptions *sql.TxOptions) (cnt uint64, err error) {
+	everyRows := cast.ToUint64(g.PtrVal(cfg.Target.Options.CommitEveryRows))
+	every := time.Duration(g.PtrVal(cfg.Target.Options.CommitEverySeconds)) * time.Second
+
+	for chunk := range iop.MergeDataflow(df).ChunkBy(everyRows, every) {
+		chunkDf, err := iop.MakeDataFlow(chunk)
+		if err != nil {
+			return cnt, g.Error(err, "could not make chunk dataflow")
+		}
+
+		chunkCnt, err := tgtConn.BulkImportFlow(targetTable.FullName(), chunkDf)
+		cnt += chunkCnt
+		if err != nil {
+			return cnt, err
+		}
+
+		if err = tgtConn.Commit(); err != nil {
+			return cnt, g.Error(err, "could not commit into final table")
+		}
+		t.SetProgress("committed %d rows into %s", cnt, targetTable.FullName())
+
+		if err = tgtConn.BeginContext(df.Context.Ctx, txOptions); err != nil {
+			return cnt, g.Error(err, "could not open transaction to write to final table")
+		}
+	}
+
+	return cnt, df.Err()
+}
+
+// copyToFailureTable copies the temp table into the failure table, replacing it
+func copyToFailureTable(conn database.Connection, tableTmp database.Table, name string) (err error) {
+	failureTable, err := database.ParseTableName(name, conn.GetType())
+	if err != nil {
+		return g.Error(err, "could not parse failure table name: %s", name)
+	}
+
+	columns, err := conn.GetColumns(tableTmp.FullName())
+	if err != nil {
+		return g.Error(err, "could not get columns of temp table "+tableTmp.FullName())
+	}
+
+	if err = conn.DropTable(failureTable.FullName()); err != nil {
+		return g.Error(err, "could not drop failure table "+failureTable.FullName())
+	}
+
+	sample := iop.NewDataset(columns)
+	sample.Inferred = true
+	if _, err = createTableIfNotExists(conn, sample, &failureTable, false); err != nil {
+		return g.Error(err, "could not create failure table "+failureTable.FullName())
+	}
+
+	fields := conn.GetType().QuoteNames(columns.Names()...)
+	sql := g.R(
+		conn.Template().Core["insert_from_table"],
+		"tgt_table", failureTable.FullName(),
+		"src_table", tableTmp.FullName(),
+		"tgt_fields", strings.Join(fields, ", "),
+		"src_fields", strings.Join(fields, ", "),
+	)
+	if _, err = conn.Exec(sql); err != nil {
+		return g.Error(err, "could not copy temp table into failure table "+failureTable.FullName())
+	}
+	return nil
+}
+
+// dropTempColumns drops the columns from the temp table, so they are not loaded into the final table
+func dropTempColumns(t *TaskExecution, cfg *Config, tgtConn database.Connection, columns iop.Columns) error {
+	for _, col := range columns {
+		sql := g.R(
+			tgtConn.GetTemplateValue("core.drop_column"),
+			"table", cfg.Target.Options.TableTmp,
+			"column", tgtConn.Quote(col.Name),
+		)
+		if _, err := tgtConn.Exec(sql); err != nil {
+			return g.Error(err, "could not drop column %s from temp table", col.Name)
+		}
+	}
+
+	if _, err := pullTargetTempTableColumns(cfg, tgtConn, true); err != nil {
+		return g.Error(err, "could not get temp table columns")
+	}
+	t.SetProgress("ignored new columns %s", strings.Join(columns.Names(), ", "))
+	return nil
+}
+
 func createTable(t *TaskExecution, tgtConn database.Connection, table database.Table, sampleData iop.Dataset, isTemp bool) error {
 	created, err := createTableIfNotExists(tgtConn, sampleData, &table, isTemp)
 	if err != nil {
@@ -618,39 +879,59 @@ func createTable(t *TaskExecution, tgtConn database.Connection, table database.T
 }
 
 func configureColumnHandlers(t *TaskExecution, cfg *Config, df *iop.Dataflow, tgtConn database.Connection, table database.Table) error {
-	adjustColumnType := cfg.Target.Options.AdjustColumnType != nil && *cfg.Target.Options.AdjustColumnType
+	policy := cfg.Target.Options.Evolution()
 
-	// set OnColumnChanged
-	if adjustColumnType {
-		df.OnColumnChanged = func(col iop.Column) error {
-			var err error
-			table.Columns, err = tgtConn.GetSQLColumns(table)
-			if err != nil {
-				return g.Error(err, "could not get table columns for schema change")
-			}
+	// alterTemp changes the column type of the temp table
+	alterTemp := func(col iop.Column) error {
+		var err error
+		table.Columns, err = tgtConn.GetSQLColumns(table)
+		if err != nil {
+			return g.Error(err, "could not get table columns for schema change")
+		}
 
-			// preserve keys
-			if err := table.SetKeys(cfg.Source.PrimaryKey(), cfg.Source.UpdateKey, cfg.Target.Options.TableKeys); err != nil {
-				return g.Error(err, "could not set keys for "+table.FullName())
-			}
+		// preserve keys
+		if err := table.SetKeys(cfg.Source.PrimaryKey(), cfg.Source.UpdateKey, cfg.Target.Options.TableKeys); err != nil {
+			return g.Error(err, "could not set keys for "+table.FullName())
+		}
 
-			ok, err := tgtConn.OptimizeTable(&table, iop.Columns{col}, true)
-			if err != nil {
-				return g.Error(err, "could not change table schema")
-			} else if ok {
-				cfg.Target.Columns = table.Columns
-			} else {
-				// revert to old type
-				col.Type = df.Columns[col.Position-1].Type
-			}
-			df.Columns.Merge(iop.Columns{col}, true)
+		ok, err := tgtConn.OptimizeTable(&table, iop.Columns{col}, true)
+		if err != nil {
+			return g.Error(err, "could not change table schema")
+		} else if ok {
+			cfg.Target.Columns = table.Columns
+		} else {
+			// revert to old type
+			col.Type = df.Columns[col.Position-1].Type
+		}
+		df.Columns.Merge(iop.Columns{col}, true)
 
-			return nil
+		return nil
+	}
+
+	// set OnColumnChanged
+	switch policy.OnTypeWiden {
+	case SchemaEvolutionAlter:
+		df.OnColumnChanged = alterTemp
+	case SchemaEvolutionFail:
+		df.OnColumnChanged = func(col iop.Column) error {
+			// only the columns of an existing target table are refused
+			if exists, err := database.TableExists(tgtConn, cfg.Target.Object); err != nil {
+				return g.Error(err, "could not check table "+cfg.Target.Object)
+			} else if exists {
+				tgtColumns, err := tgtConn.GetColumns(cfg.Target.Object)
+				if err != nil {
+					return g.Error(err, "could not get columns of "+cfg.Target.Object)
+				} else if tgtCol := tgtColumns.GetColumn(col.Name); tgtCol != nil && tgtCol.Type != col.Type {
+					return g.Error("type of column %s changed from %s to %s, refused by schema_evolution.on_type_widen", col.Name, tgtCol.Type, col.Type)
+				}
+			}
+			return alterTemp(col)
 		}
 	}
 
-	// set OnColumnAdded handler if adding new columns is enabled
-	if cfg.Target.Options.AddNewColumns != nil && *cfg.Target.Options.AddNewColumns {
+	// set OnColumnAdded
+	switch policy.OnNewColumn {
+	case SchemaEvolutionAdd:
 		df.OnColumnAdded = func(col iop.Column) error {
 
 			// sleep to allow transaction to close
@@ -670,12 +951,20 @@ func configureColumnHandlers(t *TaskExecution, cfg *Config, df *iop.Dataflow, tg
 			}
 			return nil
 		}
+	case SchemaEvolutionFail:
+		df.OnColumnAdded = func(col iop.Column) error {
+			return g.Error("new column %s, refused by schema_evolution.on_new_column", col.Name)
+		}
 	}
 
 	return nil
 }
 
 func prepareDataflow(t *TaskExecution, df *iop.Dataflow, tgtConn database.Connection) (iop.Dataset, error) {
+	// apply column mapping
+	if err := applyColumnMappingToDf(df, t.Config.Target.Options.ColumnMapping); err != nil {
+		return iop.Dataset{}, err
+	}
 
 	// if final target column is string and source col is uuid, we need to match type
 	// otherwise, there could be a upper case/lower case difference, since sling now supports uuid type
@@ -695,6 +984,14 @@ func prepareDataflow(t *TaskExecution, df *iop.Dataflow, tgtConn database.Connec
 	// apply column casing
 	applyColumnCasingToDf(df, tgtConn.GetType(), t.Config.Target.Options.ColumnCasing)
 
+	// match the columns of the existing table
+	if match := t.Config.Target.Options.ColumnMatch; match != nil && *match != ColumnMatchExact {
+		tgtColumns, _ := pullTargetTableColumns(t.Config, tgtConn, false) // empty if table does not exist
+		if err := applyColumnMatchToDf(df, tgtColumns, match); err != nil {
+			return iop.Dataset{}, err
+		}
+	}
+
 	sampleData := df.BufferDataset()
 	if !sampleData.Inferred {
 		sampleData.SafeInference = true
@@ -725,6 +1022,15 @@ func prepareFinal(
 	sample.Rows = df.Buffer
 	sample.Inferred = true // already inferred with SyncStats
 
+	// add the version columns for scd2
+	if cfg.Mode == SCD2Mode {
+		sample.Columns = append(iop.Columns{}, sample.Columns...)
+		for _, col := range scd2Options(cfg).Columns() {
+			col.Position = len(sample.Columns) + 1
+			sample.Columns = append(sample.Columns, col)
+		}
+	}
+
 	created, err := createTableIfNotExists(tgtConn, sample, &targetTable, false)
 	if err != nil {
 		return g.Error(err, "could not create table "+targetTable.FullName())
@@ -740,8 +1046,17 @@ func prepareFinal(
 
 	// If the table wasn't created and we're not in Full Refresh Mode, handle schema updates
 	if !created && cfg.Mode != FullRefreshMode {
-		// Add missing columns if the option is enabled
-		if cfg.Target.Options.AddNewColumns != nil && *cfg.Target.Options.AddNewColumns {
+		evolution, err := getSchemaEvolution(cfg, tgtConn, targetTable, sample.Columns)
+		if err != nil {
+			return g.Error(err, "could not compare table schema")
+		} else if err = evolution.Err(); err != nil {
+			return err
+		}
+		t.schemaChanges = append(t.schemaChanges, evolution.Changes...)
+		policy := cfg.Target.Options.Evolution()
+
+		// Add missing columns, or drop them from the temp table if ignored
+		if policy.OnNewColumn == SchemaEvolutionAdd {
 			if ok, err := tgtConn.AddMissingColumns(targetTable, sample.Columns); err != nil {
 				return g.Error(err, "could not add missing columns")
 			} else if ok {
@@ -749,10 +1064,14 @@ func prepareFinal(
 					return g.Error(err, "could not get table columns")
 				}
 			}
+		} else if len(evolution.Ignored) > 0 {
+			if err := dropTempColumns(t, cfg, tgtConn, evolution.Ignored); err != nil {
+				return err
+			}
 		}
 
-		// Adjust column types if the option is enabled
-		if cfg.Target.Options.AdjustColumnType != nil && *cfg.Target.Options.AdjustColumnType {
+		// Adjust column types if the policy alters them
+		if policy.OnTypeWiden == SchemaEvolutionAlter {
 			if targetTable.Columns, err = tgtConn.GetSQLColumns(targetTable); err != nil {
 				return g.Error(err, "could not get table columns for optimization")
 			}
@@ -785,7 +1104,7 @@ func prepareFinal(
 	return nil
 }
 
-func transferData(cfg *Config, tgtConn database.Connection, tableTmp, targetTable database.Table) error {
+func transferData(t *TaskExecution, cfg *Config, tgtConn database.Connection, tableTmp, targetTable database.Table) error {
 	if cfg.Mode == "drop (need to optimize temp table in place)" {
 		// Use swap
 		return transferBySwappingTables(tgtConn, tableTmp, targetTable)
@@ -800,6 +1119,24 @@ func transferData(cfg *Config, tgtConn database.Connection, tableTmp, targetTabl
 		return nil
 	}
 
+	if cfg.Mode == PartitionOverwriteMode {
+		// replace the partitions present in the temp table
+		if err := performPartitionOverwrite(tgtConn, tableTmp, targetTable, cfg); err != nil {
+			err = g.Error(err, "could not overwrite partitions from temp")
+			return err
+		}
+		return nil
+	}
+
+	if cfg.Mode == SCD2Mode {
+		// close changed records and insert new versions
+		if err := performSCD2(t, tgtConn, tableTmp, targetTable, cfg); err != nil {
+			err = g.Error(err, "could not perform scd2 from temp")
+			return err
+		}
+		return nil
+	}
+
 	if cfg.Mode == IncrementalMode || cfg.Mode == BackfillMode {
 		// execute upsert
 		if err := performUpsert(tgtConn, tableTmp, targetTable, cfg); err != nil {
@@ -940,12 +1277,7 @@ func writeDataflowViaDuckDB(t *TaskExecution, df *iop.Dataflow, fs filesys.FileS
 }
 
 func performUpsert(tgtConn database.Connection, tableTmp, targetTable database.Table, cfg *Config) error {
-	tgtPrimaryKey := cfg.Source.PrimaryKey()
-	if casing := cfg.Target.Options.ColumnCasing; casing != nil {
-		for i, pk := range tgtPrimaryKey {
-			tgtPrimaryKey[i] = casing.Apply(pk, tgtConn.GetType())
-		}
-	}
+	tgtPrimaryKey := targetPrimaryKey(cfg, tgtConn.GetType())
 	g.Debug("performing upsert from temporary table %s to target table %s with primary keys %v",
 		tableTmp.FullName(), targetTable.FullName(), tgtPrimaryKey)
 	rowAffCnt, err := tgtConn.Upsert(tableTmp.FullName(), targetTable.FullName(), tgtPrimaryKey)
@@ -959,6 +1291,90 @@ func performUpsert(tgtConn database.Connection, tableTmp, targetTable database.T
 	return nil
 }
 
+// targetPrimaryKey returns the primary key of the source, with the target casing applied
+func targetPrimaryKey(cfg *Config, tgtType dbio.Type) []string {
+	tgtPrimaryKey := cfg.Source.PrimaryKey()
+	if casing := cfg.Target.Options.ColumnCasing; casing != nil {
+		for i, pk := range tgtPrimaryKey {
+			tgtPrimaryKey[i] = casing.Apply(pk, tgtType)
+		}
+	}
+	return tgtPrimaryKey
+}
+
+// targetPartitionColumn returns the partition column, with the target casing applied
+func targetPartitionColumn(cfg *Config, tgtType dbio.Type) string {
+	partitionCol := cfg.PartitionColumn()
+	if casing := cfg.Target.Options.ColumnCasing; casing != nil {
+		partitionCol = casing.Apply(partitionCol, tgtType)
+	}
+	return partitionCol
+}
+
+func performPartitionOverwrite(tgtConn database.Connection, tableTmp, targetTable database.Table, cfg *Config) error {
+	partitionCol := targetPartitionColumn(cfg, tgtConn.GetType())
+
+	sql, err := tgtConn.GeneratePartitionOverwriteSQL(tableTmp.FullName(), targetTable.FullName(), partitionCol)
+	if err != nil {
+		return g.Error(err, "could not generate partition overwrite sql")
+	}
+
+	g.Debug("overwriting partitions of target table %s from temporary table %s on column %s",
+		targetTable.FullName(), tableTmp.FullName(), partitionCol)
+	if _, err = tgtConn.ExecMulti(sql); err != nil {
+		return g.Error(err, "could not execute partition overwrite sql")
+	}
+	return nil
+}
+
+// scd2Options returns the scd2 options of the config, with the target casing applied
+func scd2Options(cfg *Config) database.SCD2Options {
+	opts := database.SCD2Options{}
+	if cfg.Target.Options.SCD2 != nil {
+		opts = *cfg.Target.Options.SCD2
+		opts.CompareColumns = append([]string{}, opts.CompareColumns...)
+	}
+	opts.PrimaryKey = cfg.Source.PrimaryKey()
+	opts.UpdateKey = cfg.Source.UpdateKey
+	opts.SetDefaults()
+
+	if casing := cfg.Target.Options.ColumnCasing; casing != nil {
+		tgtType := cfg.TgtConn.Type
+		for i, pk := range opts.PrimaryKey {
+			opts.PrimaryKey[i] = casing.Apply(pk, tgtType)
+		}
+		for i, col := range opts.CompareColumns {
+			opts.CompareColumns[i] = casing.Apply(col, tgtType)
+		}
+		opts.ValidFrom = casing.Apply(opts.ValidFrom, tgtType)
+		opts.ValidTo = casing.Apply(opts.ValidTo, tgtType)
+		opts.IsCurrent = casing.Apply(opts.IsCurrent, tgtType)
+		if opts.UpdateKey != "" {
+			opts.UpdateKey = casing.Apply(opts.UpdateKey, tgtType)
+		}
+	}
+	return opts
+}
+
+func performSCD2(t *TaskExecution, tgtConn database.Connection, tableTmp, targetTable database.Table, cfg *Config) error {
+	opts := scd2Options(cfg)
+	if t.StartTime != nil {
+		opts.Timestamp = *t.StartTime // same valid_from for all the versions of a run
+	}
+
+	sql, err := tgtConn.GenerateSCD2SQL(tableTmp.FullName(), targetTable.FullName(), opts)
+	if err != nil {
+		return g.Error(err, "could not generate scd2 sql")
+	}
+
+	g.Debug("performing scd2 from temporary table %s to target table %s with primary keys %v",
+		tableTmp.FullName(), targetTable.FullName(), opts.PrimaryKey)
+	if _, err = tgtConn.ExecMulti(sql); err != nil {
+		return g.Error(err, "could not execute scd2 sql")
+	}
+	return nil
+}
+
 func executeSQL(t *TaskExecution, tgtConn database.Connection, sqlStatements *string, stage string) error {
 	if sqlStatements == nil || *sqlStatements == "" {
 		return nil
@@ -974,3 +1390,124 @@ func executeSQL(t *TaskExecution, tgtConn database.Connection, sqlStatements *st
 	}
 	return nil
 }
+
+// executeChecks runs the assertion checks against the target, within the final
+// transaction. A check is a query returning a single value, optionally followed
+// by `== <expected>`. Without an expected value, the query must return true or 0.
+func executeChecks(t *TaskExecution, tgtConn database.Connection, targetTable database.Table, checks []string) error {
+	if len(checks) == 0 {
+		return nil
+	}
+
+	values := t.GetStateMap()
+	values["target_table"] = targetTable.FullName()
+
+	t.SetProgress("executing %d checks", len(checks))
+	for _, check := range checks {
+		query, expected := parseCheck(g.Rm(check, values))
+
+		data, err := tgtConn.Query(query)
+		if err != nil {
+			return g.Error(err, "could not execute check: %s", check)
+		}
+
+		var value any
+		if len(data.Rows) > 0 && len(data.Rows[0]) > 0 {
+			value = data.Rows[0][0]
+		}
+
+		if !checkPassed(value, expected) {
+			return g.Error("check failed (got %v): %s", value, check)
+		}
+	}
+
+	return nil
+}
+
+// parseCheck splits a check into its query and expected value
+func parseCheck(check string) (query, expected string) {
+	check = strings.TrimSpace(check)
+	if index := strings.LastIndex(check, "=="); index > 0 {
+		return strings.TrimSpace(check[:index]), strings.TrimSpace(check[index+2:])
+	}
+	return check, ""
+}
+
+// checkPassed returns true if the check value matches the expected value.
+// Without an expected value, the value must be true or 0.
+func checkPassed(value any, expected string) bool {
+	if value == nil {
+		return false
+	} else if expected != "" {
+		expected = strings.Trim(expected, `'"`)
+		if valNum, err := cast.ToFloat64E(value); err == nil {
+			if expNum, err := cast.ToFloat64E(expected); err == nil {
+				return valNum == expNum
+			}
+		}
+		return strings.EqualFold(cast.ToString(value), expected)
+	}
+
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		if b, err := cast.ToBoolE(v); err == nil && !g.In(v, "0", "1") {
+			return b
+		}
+	}
+
+	if num, err := cast.ToFloat64E(value); err == nil {
+		return num == 0
+	}
+	return false
+}
+
+// applyColumnTags sets the classification tags (pii, sensitive, etc.) on the
+// target table columns. Tags are collected from the `column_tags` target option,
+// from the `columns` config and from the source column metadata (`tags` key).
+func applyColumnTags(cfg *Config, tgtConn database.Connection, targetTable database.Table, dfColumns iop.Columns) (err error) {
+	columnTags := map[string][]string{}
+	addTags := func(colName string, tags ...string) {
+		for _, tag := range tags {
+			if tag = strings.TrimSpace(tag); tag != "" && !lo.Contains(columnTags[colName], tag) {
+				columnTags[colName] = append(columnTags[colName], tag)
+			}
+		}
+	}
+
+	// copy, so that the dataflow columns are not modified
+	columns := append(iop.Columns{}, dfColumns...)
+	for _, col := range append(columns, cfg.ColumnsPrepared()...) {
+		if tags := col.Metadata["tags"]; tags != "" {
+			addTags(strings.ToLower(col.Name), strings.Split(tags, ",")...)
+		}
+	}
+
+	for colName, tags := range cfg.Target.Options.ColumnTags {
+		addTags(strings.ToLower(colName), tags...)
+	}
+
+	if len(columnTags) == 0 {
+		return nil
+	}
+
+	// match the target column names
+	tgtColumns, err := pullTargetTableColumns(cfg, tgtConn, true)
+	if err != nil {
+		return err
+	}
+
+	tgtColumnTags := map[string][]string{}
+	for colName, tags := range columnTags {
+		if col := tgtColumns.GetColumn(colName); col != nil {
+			tgtColumnTags[col.Name] = tags
+		} else {
+			g.Warn("did not find column %s in target table to set tags", colName)
+		}
+	}
+
+	g.Debug("setting column tags on %s: %s", targetTable.FullName(), g.Marshal(tgtColumnTags))
+
+	return tgtConn.SetColumnTags(targetTable, tgtColumnTags)
+}
@@ -0,0 +1,285 @@
+package sling
+
+import (
+	"crypto/sha256"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecks(t *testing.T) {
+	query, expected := parseCheck("select count(*) from {target_table} where id is null == 0")
+	assert.Equal(t, "select count(*) from {target_table} where id is null", query)
+	assert.Equal(t, "0", expected)
+
+	query, expected = parseCheck(" select max(id) > 10 from my_table ")
+	assert.Equal(t, "select max(id) > 10 from my_table", query)
+	assert.Equal(t, "", expected)
+
+	assert.True(t, checkPassed(int64(0), "0"))
+	assert.True(t, checkPassed(0.0, "0"))
+	assert.True(t, checkPassed("abc", "'abc'"))
+	assert.False(t, checkPassed(int64(3), "0"))
+	assert.False(t, checkPassed(nil, "0"))
+
+	assert.True(t, checkPassed(true, ""))
+	assert.True(t, checkPassed("true", ""))
+	assert.True(t, checkPassed(int64(0), ""))
+	assert.False(t, checkPassed(false, ""))
+	assert.False(t, checkPassed(int64(2), ""))
+	assert.False(t, checkPassed(nil, ""))
+}
+
+func TestSchemaEvolution(t *testing.T) {
+	opts := TargetOptions{AddNewColumns: g.Bool(false), AdjustColumnType: g.Bool(true)}
+	assert.Equal(t, SchemaEvolution{
+		OnNewColumn:  SchemaEvolutionIgnore,
+		OnTypeWiden:  SchemaEvolutionAlter,
+		OnTypeNarrow: SchemaEvolutionCast,
+		OnColumnDrop: SchemaEvolutionIgnore,
+	}, opts.Evolution())
+
+	opts.SchemaEvolution = &SchemaEvolution{OnNewColumn: SchemaEvolutionFail}
+	assert.Equal(t, SchemaEvolutionFail, opts.Evolution().OnNewColumn)
+	assert.Error(t, (&SchemaEvolution{OnTypeNarrow: SchemaEvolutionAlter}).Validate())
+
+	conn := initTestSqlite(t, "SLING_EVOLUTION_TEST_DB", `
+		create table users (id integer, name text, email text);
+		insert into users values (1, 'a', 'a@x.com');
+		create table users_copy (id integer, name text);
+	`)
+
+	run := func(evolution *SchemaEvolution) error {
+		_, err := runTestTask(&Config{
+			Source: Source{Conn: "SLING_EVOLUTION_TEST_DB", Stream: "main.users"},
+			Target: Target{Conn: "SLING_EVOLUTION_TEST_DB", Object: "main.users_copy", Options: &TargetOptions{SchemaEvolution: evolution}},
+			Mode:   TruncateMode,
+		})
+		return err
+	}
+
+	err := run(&SchemaEvolution{OnNewColumn: SchemaEvolutionFail})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "new column email")
+	}
+
+	// the new column is not loaded
+	assert.NoError(t, run(&SchemaEvolution{OnNewColumn: SchemaEvolutionIgnore}))
+	columns, err := conn.GetColumns("main.users_copy")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"id", "name"}, columns.Names())
+	}
+
+	assert.NoError(t, run(nil))
+	columns, err = conn.GetColumns("main.users_copy")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"id", "name", "email"}, columns.Names())
+	}
+}
+
+func TestFailureTable(t *testing.T) {
+	conn := initTestSqlite(t, "SLING_FAILURE_TEST_DB", `
+		create table users (id integer, name text);
+		insert into users values (1, 'a'), (2, 'b');
+	`)
+
+	run := func(options *TargetOptions) error {
+		options.TableTmp = "main.users_copy_tmp"
+		options.PostSQL = g.String("select * from not_a_table") // fails the final transaction
+		_, err := runTestTask(&Config{
+			Source: Source{Conn: "SLING_FAILURE_TEST_DB", Stream: "main.users"},
+			Target: Target{Conn: "SLING_FAILURE_TEST_DB", Object: "main.users_copy", Options: options},
+			Mode:   FullRefreshMode,
+		})
+		return err
+	}
+	tableExists := func(name string) bool {
+		exists, err := database.TableExists(conn, name)
+		assert.NoError(t, err)
+		return exists
+	}
+
+	// dropped by default
+	assert.Error(t, run(&TargetOptions{}))
+	assert.False(t, tableExists("main.users_copy_tmp"))
+
+	assert.Error(t, run(&TargetOptions{KeepTmpTable: g.Bool(true), FailureTable: "main.users_failed"}))
+	assert.True(t, tableExists("main.users_copy_tmp"))
+	data, err := conn.Query("select id, name from main.users_failed order by id")
+	if assert.NoError(t, err) {
+		assert.Len(t, data.Rows, 2)
+	}
+}
+
+func TestDirectInsert(t *testing.T) {
+	cfg := &Config{Mode: FullRefreshMode, Target: Target{Options: &TargetOptions{DirectInsert: g.Bool(true)}}}
+	assert.True(t, useDirectInsert(cfg, false))
+
+	cfg.Mode, cfg.Source.PrimaryKeyI = IncrementalMode, "id"
+	assert.False(t, useDirectInsert(cfg, false)) // requires a merge
+
+	conn := initTestSqlite(t, "SLING_DIRECT_TEST_DB", `
+		create table users (id integer, name text);
+		insert into users values (1, 'a'), (2, 'b');
+	`)
+
+	newCfg := func() *Config {
+		return &Config{
+			Source: Source{Conn: "SLING_DIRECT_TEST_DB", Stream: "main.users"},
+			Target: Target{Conn: "SLING_DIRECT_TEST_DB", Object: "main.users_copy", Options: &TargetOptions{DirectInsert: g.Bool(true)}},
+			Mode:   FullRefreshMode,
+		}
+	}
+
+	task, err := newTestTask(newCfg())
+	if assert.NoError(t, err) {
+		plan, err := task.Plan()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"create table"}, lo.Map(plan.Steps, func(s PlanStep, i int) string { return s.Name }))
+	}
+
+	_, err = runTestTask(newCfg())
+	assert.NoError(t, err)
+	count, err := conn.GetCount("main.users_copy")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+}
+
+func TestCommitEveryRows(t *testing.T) {
+	conn := initTestSqlite(t, "SLING_COMMIT_TEST_DB", `
+		create table events (id integer, name text);
+		insert into events values (1, 'a'), (2, 'b'), (3, 'c'), (4, 'd'), (5, 'e');
+	`)
+
+	newCfg := func(primaryKey any) *Config {
+		return &Config{
+			Source: Source{Conn: "SLING_COMMIT_TEST_DB", Stream: "main.events", PrimaryKeyI: primaryKey, UpdateKey: "id"},
+			Target: Target{Conn: "SLING_COMMIT_TEST_DB", Object: "main.events_copy", Options: &TargetOptions{CommitEveryRows: g.Int64(2)}},
+			Mode:   IncrementalMode,
+		}
+	}
+
+	// append only
+	_, err := newTestTask(newCfg("id"))
+	assert.ErrorContains(t, err, "append only")
+
+	cfg := newCfg(nil)
+	if _, err = runTestTask(cfg); assert.NoError(t, err) {
+		assert.True(t, useDirectInsert(cfg, false))
+		count, err := conn.GetCount("main.events_copy")
+		assert.NoError(t, err)
+		assert.EqualValues(t, 5, count)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	conn := initTestSqlite(t, "SLING_RATE_TEST_DB", `
+		create table events (id integer, name text);
+		insert into events values (1, 'a'), (2, 'b'), (3, 'c'), (4, 'd'), (5, 'e');
+	`)
+
+	newCfg := func(sourceLimit, targetLimit *string) *Config {
+		return &Config{
+			Source: Source{Conn: "SLING_RATE_TEST_DB", Stream: "main.events", Options: &SourceOptions{RateLimit: sourceLimit}},
+			Target: Target{Conn: "SLING_RATE_TEST_DB", Object: "main.events_copy", Options: &TargetOptions{RateLimit: targetLimit}},
+			Mode:   FullRefreshMode,
+		}
+	}
+
+	_, err := newTestTask(newCfg(g.String("fast"), nil))
+	assert.ErrorContains(t, err, "invalid rate_limit")
+
+	for _, cfg := range []*Config{newCfg(g.String("2 rows/s"), nil), newCfg(nil, g.String("2 rows/s"))} {
+		start := time.Now()
+		if _, err := runTestTask(cfg); assert.NoError(t, err) {
+			assert.Greater(t, time.Since(start), time.Second)
+			count, err := conn.GetCount("main.events_copy")
+			assert.NoError(t, err)
+			assert.EqualValues(t, 5, count)
+		}
+	}
+}
+
+func TestAtomicWrite(t *testing.T) {
+	t.Setenv("SLING_DUCKDB_COMPUTE", "false")
+	folder := t.TempDir()
+	os.WriteFile(path.Join(folder, "data.csv"), []byte("id,name\n1,a\n2,b\n3,c\n4,d\n5,e\n"), 0644)
+
+	run := func(target string, tgtOpts *TargetOptions) error {
+		cfg := localFileConfig(folder, "data.csv", target)
+		cfg.Target.Options = tgtOpts
+		_, err := runTestTask(cfg)
+		return err
+	}
+
+	// existing files of the folder are replaced
+	os.MkdirAll(path.Join(folder, "parts"), 0755)
+	os.WriteFile(path.Join(folder, "parts", "old.csv"), []byte("id,name\n0,z\n"), 0644)
+
+	manifestPath := path.Join(folder, "manifest.json")
+	err := run("parts/*.csv", &TargetOptions{FileMaxRows: g.Int64(2), Atomic: g.Bool(true), Manifest: g.String(manifestPath)})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	entries, _ := os.ReadDir(path.Join(folder, "parts"))
+	assert.Len(t, entries, 3)
+	for _, entry := range entries {
+		assert.NotEqual(t, "old.csv", entry.Name())
+	}
+
+	// the manifest lists the published files
+	var manifest Manifest
+	data, _ := os.ReadFile(manifestPath)
+	if assert.NoError(t, g.Unmarshal(string(data), &manifest)) && assert.Len(t, manifest.Files, 3) {
+		for _, file := range manifest.Files {
+			assert.FileExists(t, strings.TrimPrefix(file.URI, "file://"))
+		}
+	}
+
+	// single file
+	err = run("single.csv", &TargetOptions{Atomic: g.Bool(true)})
+	if assert.NoError(t, err) {
+		data, _ = os.ReadFile(path.Join(folder, "single.csv"))
+		assert.Equal(t, "id,name\n1,a\n2,b\n3,c\n4,d\n5,e\n", string(data))
+	}
+
+	// no temporary files are left
+	entries, _ = os.ReadDir(folder)
+	for _, entry := range entries {
+		assert.NotContains(t, entry.Name(), "_sling_tmp_")
+	}
+}
+
+func TestContentAddressed(t *testing.T) {
+	t.Setenv("SLING_DUCKDB_COMPUTE", "false")
+	folder := t.TempDir()
+	os.WriteFile(path.Join(folder, "data.csv"), []byte("id,name\n1,a\n2,b\n3,c\n4,d\n5,e\n"), 0644)
+
+	manifestPath := path.Join(folder, "manifest.json")
+	cfg := localFileConfig(folder, "data.csv", "parts/*.csv")
+	cfg.Target.Options = &TargetOptions{FileMaxRows: g.Int64(2), ContentAddressed: g.Bool(true), Manifest: g.String(manifestPath)}
+	if _, err := runTestTask(cfg); !assert.NoError(t, err) {
+		return
+	}
+
+	var manifest Manifest
+	data, _ := os.ReadFile(manifestPath)
+	if assert.NoError(t, g.Unmarshal(string(data), &manifest)) && assert.Len(t, manifest.Files, 3) {
+		for _, file := range manifest.Files {
+			content, err := os.ReadFile(strings.TrimPrefix(file.URI, "file://"))
+			if assert.NoError(t, err) {
+				checksum := g.F("%x", sha256.Sum256(content))
+				assert.Equal(t, checksum, file.SHA256)
+				assert.Equal(t, checksum+".csv", path.Base(file.URI))
+			}
+		}
+	}
+}
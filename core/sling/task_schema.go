@@ -0,0 +1,218 @@
+package sling
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/database"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/spf13/cast"
+)
+
+// SchemaCopyOptions configures the copy of a schema to another database
+type SchemaCopyOptions struct {
+	Schema       string   `json:"schema"`
+	TargetSchema string   `json:"target_schema,omitempty"` // defaults to Schema
+	Tables       []string `json:"tables,omitempty"`        // table names or glob patterns, defaults to all
+	DDLOnly      bool     `json:"ddl_only,omitempty"`      // only return the statements, without executing
+}
+
+// SchemaCopyTable is a table of a schema copy
+type SchemaCopyTable struct {
+	Source     string   `json:"source"`
+	Target     string   `json:"target"`
+	Statements []string `json:"statements"`
+	Exists     bool     `json:"exists,omitempty"` // the target table exists, and was not created
+}
+
+// primaryKeyDialects are the databases accepting an inline primary key clause
+var primaryKeyDialects = []dbio.Type{
+	dbio.TypeDbPostgres, dbio.TypeDbRedshift, dbio.TypeDbMySQL, dbio.TypeDbMariaDB,
+	dbio.TypeDbSQLite, dbio.TypeDbD1, dbio.TypeDbDuckDb, dbio.TypeDbMotherDuck,
+	dbio.TypeDbSQLServer, dbio.TypeDbAzure, dbio.TypeDbOracle, dbio.TypeDbSnowflake,
+}
+
+// indexDialects are the databases where the source indexes are created
+var indexDialects = []dbio.Type{
+	dbio.TypeDbPostgres, dbio.TypeDbMySQL, dbio.TypeDbMariaDB, dbio.TypeDbSQLite,
+	dbio.TypeDbD1, dbio.TypeDbDuckDb, dbio.TypeDbSQLServer, dbio.TypeDbAzure, dbio.TypeDbOracle,
+}
+
+// SchemaCopy reads the tables of a source schema (columns, primary keys, indexes)
+// and creates the equivalent empty tables in the target database dialect.
+// Existing target tables are left as is
+func SchemaCopy(srcConn, tgtConn database.Connection, opts SchemaCopyOptions) (tables []SchemaCopyTable, err error) {
+	if opts.Schema == "" {
+		return nil, g.Error("source schema is required")
+	}
+	if opts.TargetSchema == "" {
+		opts.TargetSchema = opts.Schema
+	}
+
+	schemata, err := srcConn.GetSchemata(database.SchemataLevelColumn, opts.Schema)
+	if err != nil {
+		return nil, g.Error(err, "could not get schemata of %s", opts.Schema)
+	}
+
+	srcTables := lo.Filter(lo.Values(schemata.Tables(opts.Tables...)), func(t database.Table, i int) bool {
+		return !t.IsView
+	})
+	sort.Slice(srcTables, func(i, j int) bool { return srcTables[i].Name < srcTables[j].Name })
+
+	if !opts.DDLOnly && len(srcTables) > 0 {
+		if _, err = createSchemaIfNotExists(tgtConn, opts.TargetSchema); err != nil {
+			return nil, g.Error(err, "could not create schema %s", opts.TargetSchema)
+		}
+	}
+
+	for _, srcTable := range srcTables {
+		table, err := schemaCopyTable(srcConn, tgtConn, srcTable, opts)
+		if err != nil {
+			return tables, g.Error(err, "could not copy table %s", srcTable.FullName())
+		}
+		tables = append(tables, table)
+	}
+
+	return tables, nil
+}
+
+// schemaCopyTable generates the statements creating a table, and executes them
+func schemaCopyTable(srcConn, tgtConn database.Connection, srcTable database.Table, opts SchemaCopyOptions) (table SchemaCopyTable, err error) {
+	tgtType := tgtConn.GetType()
+	tgtTable, err := database.ParseTableName(opts.TargetSchema+"."+srcTable.Name, tgtType)
+	if err != nil {
+		return table, g.Error(err, "could not parse target table name")
+	}
+	table = SchemaCopyTable{Source: srcTable.FullName(), Target: tgtTable.FullName(), Statements: []string{}}
+
+	columns := append(iop.Columns{}, srcTable.Columns...)
+	sort.SliceStable(columns, func(i, j int) bool { return columns[i].Position < columns[j].Position })
+	for i := range columns {
+		columns[i].Table = ""
+		columns[i].Schema = ""
+		columns[i].DbType = "" // map from the general type
+	}
+	tgtTable.Columns = columns
+
+	pkCols, err := sourcePrimaryKey(srcConn, srcTable)
+	if err != nil {
+		return table, err
+	}
+
+	// add the primary key to the create table template
+	if len(pkCols) > 0 {
+		if lo.Contains(primaryKeyDialects, tgtType) {
+			tgtTable.DDL = strings.Replace(
+				tgtConn.GetTemplateValue("core.create_table"),
+				"{col_types}", "{col_types},\nprimary key ("+strings.Join(tgtType.QuoteNames(pkCols...), ", ")+")", 1,
+			)
+		} else {
+			g.Warn("primary key of %s not translated to %s", srcTable.FullName(), tgtType)
+		}
+	}
+
+	data := iop.NewDataset(columns)
+	data.Inferred = true // types come from the source table
+	ddl, err := tgtConn.GenerateDDL(tgtTable, data, false)
+	if err != nil {
+		return table, g.Error(err, "could not generate DDL")
+	}
+	table.Statements = append(table.Statements, ddl)
+
+	// indexes, excluding the primary key index
+	indexes, err := sourceIndexes(srcConn, srcTable, pkCols)
+	if err != nil {
+		g.Warn("could not get indexes of %s: %s", srcTable.FullName(), err.Error())
+	} else if len(indexes) > 0 && !lo.Contains(indexDialects, tgtType) {
+		g.Warn("indexes of %s not translated to %s", srcTable.FullName(), tgtType)
+	} else {
+		for _, index := range indexes {
+			index.Table = &tgtTable
+			table.Statements = append(table.Statements, index.CreateDDL())
+		}
+	}
+
+	if opts.DDLOnly {
+		return table, nil
+	}
+
+	table.Exists, err = database.TableExists(tgtConn, tgtTable.FullName())
+	if err != nil {
+		return table, g.Error(err, "could not check table %s", tgtTable.FullName())
+	} else if table.Exists {
+		g.Warn("table %s already exists, skipping", tgtTable.FullName())
+		return table, nil
+	}
+
+	if _, err = tgtConn.ExecMulti(table.Statements...); err != nil {
+		return table, g.Error(err, "could not create table %s", tgtTable.FullName())
+	}
+	g.Info("created table %s", tgtTable.FullName())
+
+	return table, nil
+}
+
+// sourcePrimaryKey returns the primary key columns of a table, in order
+func sourcePrimaryKey(conn database.Connection, table database.Table) (pkCols []string, err error) {
+	if conn.GetTemplateValue("metadata.primary_keys") == "" {
+		return nil, nil
+	}
+
+	data, err := conn.GetPrimaryKeys(table.FullName())
+	if err != nil {
+		return nil, g.Error(err, "could not get primary key of %s", table.FullName())
+	}
+
+	records := data.Records(true)
+	sort.SliceStable(records, func(i, j int) bool {
+		return cast.ToInt(records[i]["position"]) < cast.ToInt(records[j]["position"])
+	})
+	for _, rec := range records {
+		if name := cast.ToString(rec["column_name"]); name != "" {
+			pkCols = append(pkCols, name)
+		}
+	}
+
+	return lo.Uniq(pkCols), nil
+}
+
+// sourceIndexes returns the indexes of a table, without the primary key index
+func sourceIndexes(conn database.Connection, table database.Table, pkCols []string) (indexes []database.TableIndex, err error) {
+	if conn.GetTemplateValue("metadata.indexes") == "" {
+		return nil, nil
+	}
+
+	data, err := conn.GetIndexes(table.FullName())
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	columns := map[string]iop.Columns{}
+	for _, rec := range data.Records(true) {
+		name := cast.ToString(rec["index_name"])
+		col := table.Columns.GetColumn(cast.ToString(rec["column_name"]))
+		if name == "" || col == nil {
+			continue
+		}
+		if _, ok := columns[name]; !ok {
+			names = append(names, name)
+		}
+		columns[name] = append(columns[name], iop.Column{Name: col.Name, Type: col.Type})
+	}
+
+	for _, name := range names {
+		isPrimary := strings.EqualFold(name, "primary") ||
+			strings.HasPrefix(strings.ToLower(name), "sqlite_autoindex_") ||
+			strings.EqualFold(strings.Join(columns[name].Names(), ","), strings.Join(pkCols, ","))
+		if isPrimary {
+			continue
+		}
+		indexes = append(indexes, database.TableIndex{Name: name, Columns: columns[name]})
+	}
+
+	return indexes, nil
+}
@@ -0,0 +1,74 @@
+package sling
+
+import (
+	"path"
+	"testing"
+
+	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+	"github.com/slingdata-io/sling-cli/core/dbio/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaCopy(t *testing.T) {
+	newConn := func(name string) database.Connection {
+		conn, err := connection.NewConnectionFromURL(name, "sqlite://"+path.Join(t.TempDir(), name+".db"))
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		dbConn, err := conn.AsDatabase()
+		if !assert.NoError(t, err) || !assert.NoError(t, dbConn.Connect()) {
+			t.FailNow()
+		}
+		t.Cleanup(func() { dbConn.Close() })
+		return dbConn
+	}
+	srcConn, tgtConn := newConn("source"), newConn("target")
+
+	_, err := srcConn.ExecMulti(
+		`create table orders (id integer primary key, customer text, amount decimal(10,2))`,
+		`create index orders_customer on orders (customer)`,
+		`create table customers (name text)`,
+		`create view orders_view as select * from orders`,
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tables, err := SchemaCopy(srcConn, tgtConn, SchemaCopyOptions{Schema: "main", DDLOnly: true})
+	if !assert.NoError(t, err) || !assert.Len(t, tables, 2) {
+		return
+	}
+	assert.Equal(t, `"main"."customers"`, tables[0].Target)
+	if assert.Len(t, tables[1].Statements, 2) {
+		assert.Contains(t, tables[1].Statements[0], `primary key ("id")`)
+		assert.Contains(t, tables[1].Statements[1], `"orders_customer" on "orders" ("customer")`)
+	}
+
+	// nothing created with ddl only
+	exists, _ := database.TableExists(tgtConn, "main.orders")
+	assert.False(t, exists)
+
+	_, err = SchemaCopy(srcConn, tgtConn, SchemaCopyOptions{Schema: "main", Tables: []string{"orders"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	columns, err := tgtConn.GetColumns("main.orders")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"id", "customer", "amount"}, columns.Names())
+	}
+	count, err := tgtConn.GetCount("main.orders")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, count)
+	indexes, err := tgtConn.GetIndexes("main.orders")
+	if assert.NoError(t, err) {
+		assert.Contains(t, indexes.ColValuesStr(1), "orders_customer")
+	}
+	exists, _ = database.TableExists(tgtConn, "main.customers")
+	assert.False(t, exists)
+
+	// existing tables are left as is
+	tables, err = SchemaCopy(srcConn, tgtConn, SchemaCopyOptions{Schema: "main", Tables: []string{"orders"}})
+	if assert.NoError(t, err) && assert.Len(t, tables, 1) {
+		assert.True(t, tables[0].Exists)
+	}
+}
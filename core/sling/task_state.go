@@ -55,6 +55,7 @@ func (dts *DateTimeState) Update() {
 }
 
 type RunState struct {
+	ExecID     string                  `json:"exec_id,omitempty"`
 	Stream     *StreamState            `json:"stream,omitempty"`
 	Object     *ObjectState            `json:"object,omitempty"`
 	TotalBytes uint64                  `json:"total_bytes,omitempty"`
@@ -141,6 +142,7 @@ func StateSet(t *TaskExecution) {
 		state.Object = run.Object
 
 		bytes, _ := t.GetBytes()
+		run.ExecID = t.ExecID
 		run.TotalBytes = bytes
 		run.RowCount = t.GetCount()
 		run.Status = t.Status
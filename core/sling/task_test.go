@@ -0,0 +1,203 @@
+package sling
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/rs/zerolog"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+	"github.com/slingdata-io/sling-cli/core/dbio/database"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestTask prepares the config and inits its task
+func newTestTask(cfg *Config) (*TaskExecution, error) {
+	if err := cfg.Prepare(); err != nil {
+		return nil, err
+	}
+	task := NewTask("", cfg)
+	return task, task.Err
+}
+
+// runTestTask prepares the config and executes its task
+func runTestTask(cfg *Config) (*TaskExecution, error) {
+	task, err := newTestTask(cfg)
+	if err != nil {
+		return task, err
+	}
+	return task, task.Execute()
+}
+
+// initTestSqlite creates a sqlite database declared as the envKey connection,
+// and runs the setup sql
+func initTestSqlite(t *testing.T, envKey, setupSQL string) database.Connection {
+	url := "sqlite://" + path.Join(t.TempDir(), "test.db")
+	t.Setenv(envKey, url)
+	connection.GetLocalConns(true)
+
+	conn, err := connection.NewConnectionFromURL("test", url)
+	require.NoError(t, err)
+	dbConn, err := conn.AsDatabase()
+	require.NoError(t, err)
+	require.NoError(t, dbConn.Connect())
+	t.Cleanup(func() { dbConn.Close() })
+
+	_, err = dbConn.ExecMulti(setupSQL)
+	require.NoError(t, err)
+	return dbConn
+}
+
+// localFileConfig returns the config copying the source file into the target
+// file, both relative to folder. A name ending with "/" is a folder.
+func localFileConfig(folder, source, target string) *Config {
+	uri := func(name string) string {
+		if strings.HasSuffix(name, "/") {
+			return "file://" + path.Join(folder, name) + "/"
+		}
+		return "file://" + path.Join(folder, name)
+	}
+	return &Config{
+		Source: Source{Conn: "local", Stream: uri(source)},
+		Target: Target{Conn: "local", Object: uri(target)},
+	}
+}
+
+func TestColumnMapping(t *testing.T) {
+	df := iop.NewDataflow(0)
+
+	df.Columns = iop.NewColumns(iop.Column{Name: "vnd_id"}, iop.Column{Name: "VND_Name"}, iop.Column{Name: "order"})
+	err := applyColumnMappingToDf(df, map[string]string{"vnd_id": "id", "vnd_name": "name", "order": "order_num"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "order_num"}, df.Columns.Names())
+
+	// duplicate names
+	df.Columns = iop.NewColumns(iop.Column{Name: "id"}, iop.Column{Name: "vnd_id"})
+	err = applyColumnMappingToDf(df, map[string]string{"vnd_id": "ID"})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"id", "vnd_id"}, df.Columns.Names())
+
+	// swap is allowed
+	err = applyColumnMappingToDf(df, map[string]string{"vnd_id": "id", "id": "vnd_id"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"vnd_id", "id"}, df.Columns.Names())
+}
+
+func TestRunMetadata(t *testing.T) {
+	startTime := time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)
+	task := &TaskExecution{ExecID: "abc123", Status: ExecStatusRunning, StartTime: &startTime}
+
+	sql := g.Rm("insert into runs values ('{exec_id}', '{status}', '{start_time}', {rows_written})", task.RunMetadata())
+	assert.Equal(t, "insert into runs values ('abc123', 'running', '2024-03-01 10:30:00.000000', 0)", sql)
+}
+
+func TestTaskWarnings(t *testing.T) {
+	task := NewTask("", &Config{})
+	task.AppendOutput(&g.LogLine{Level: zerolog.InfoLevel, Text: "info"})
+	task.AppendOutput(&g.LogLine{Level: zerolog.WarnLevel, Text: "column %s was truncated", Args: []any{"name"}})
+	task.AppendOutput(&g.LogLine{Level: zerolog.WarnLevel, Text: "100% done"})
+	assert.Equal(t, []string{"column name was truncated", "100% done"}, task.Warnings())
+}
+
+func TestColumnMatch(t *testing.T) {
+	assert.Equal(t, "orderid", ColumnMatchNormalized.Key("Order_ID"))
+	assert.Equal(t, "order_id", ColumnMatchCaseInsensitive.Key("Order_ID"))
+	assert.Equal(t, "Order_ID", ColumnMatchExact.Key("Order_ID"))
+
+	tgtColumns := iop.NewColumns(iop.Column{Name: "order_id"}, iop.Column{Name: "customer_name"})
+	newDf := func() *iop.Dataflow {
+		df := iop.NewDataflow(0)
+		df.Columns = iop.NewColumns(iop.Column{Name: "OrderID"}, iop.Column{Name: "CUSTOMER_NAME"}, iop.Column{Name: "Total"})
+		return df
+	}
+
+	df := newDf()
+	assert.NoError(t, applyColumnMatchToDf(df, tgtColumns, g.Ptr(ColumnMatchExact)))
+	assert.Equal(t, []string{"OrderID", "CUSTOMER_NAME", "Total"}, df.Columns.Names())
+
+	df = newDf()
+	assert.NoError(t, applyColumnMatchToDf(df, tgtColumns, g.Ptr(ColumnMatchCaseInsensitive)))
+	assert.Equal(t, []string{"OrderID", "customer_name", "Total"}, df.Columns.Names())
+
+	df = newDf()
+	assert.NoError(t, applyColumnMatchToDf(df, tgtColumns, g.Ptr(ColumnMatchNormalized)))
+	assert.Equal(t, []string{"order_id", "customer_name", "Total"}, df.Columns.Names())
+
+	// two source columns matching the same target column
+	df = iop.NewDataflow(0)
+	df.Columns = iop.NewColumns(iop.Column{Name: "OrderID"}, iop.Column{Name: "order_id"})
+	assert.Error(t, applyColumnMatchToDf(df, tgtColumns, g.Ptr(ColumnMatchNormalized)))
+}
+
+func TestMetadataRowHashFileLine(t *testing.T) {
+	t.Setenv("SLING_DUCKDB_COMPUTE", "false")
+	t.Setenv("SLING_LOADED_AT_COLUMN", "false")
+	t.Setenv("SLING_ROW_HASH_COLUMN", "true")
+	t.Setenv("SLING_FILE_LINE_COLUMN", "true")
+	folder := t.TempDir()
+	os.WriteFile(path.Join(folder, "data.csv"), []byte("id,name\n1,a\n2,b\n1,a\n"), 0644)
+
+	if _, err := runTestTask(localFileConfig(folder, "data.csv", "out.csv")); !assert.NoError(t, err) {
+		return
+	}
+
+	data, _ := os.ReadFile(path.Join(folder, "out.csv"))
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if !assert.Len(t, lines, 4) {
+		return
+	}
+	assert.Equal(t, "id,name,_sling_row_hash,_sling_file_line", lines[0])
+
+	rows := lo.Map(lines[1:], func(line string, i int) []string { return strings.Split(line, ",") })
+	assert.Equal(t, rows[0][2], rows[2][2]) // same values, same hash
+	assert.NotEqual(t, rows[0][2], rows[1][2])
+	assert.Equal(t, []string{"2", "3", "4"}, lo.Map(rows, func(row []string, i int) string { return row[3] }))
+}
+
+func TestMetadataOptions(t *testing.T) {
+	t.Setenv("SLING_DUCKDB_COMPUTE", "false")
+	t.Setenv("SLING_ROW_HASH_COLUMN", "true")
+	folder := t.TempDir()
+	os.WriteFile(path.Join(folder, "data.csv"), []byte("id,name\n1,a\n2,b\n"), 0644)
+
+	run := func(metadata *MetadataOptions) ([]string, error) {
+		cfg := localFileConfig(folder, "data.csv", "out.csv")
+		cfg.Target.Options = &TargetOptions{Metadata: metadata}
+		if _, err := runTestTask(cfg); err != nil {
+			return nil, err
+		}
+		data, _ := os.ReadFile(path.Join(folder, "out.csv"))
+		return strings.Split(strings.TrimSpace(string(data)), "\n"), nil
+	}
+
+	// renamed, timestamp type, options take precedence over env vars
+	lines, err := run(&MetadataOptions{
+		LoadedAt:           g.Bool(true),
+		LoadedAtColumnName: "etl_ts",
+		LoadedAtType:       "timestamp",
+		RowHash:            g.Bool(false),
+		RowNum:             g.Bool(true),
+		RowNumColumnName:   "etl_row",
+	})
+	if assert.NoError(t, err) && assert.Len(t, lines, 3) && assert.Equal(t, "id,name,etl_ts,etl_row", lines[0]) {
+		fields := strings.Split(lines[1], ",")
+		assert.Regexp(t, `^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}`, fields[2])
+		assert.Equal(t, "1", fields[3])
+	}
+
+	// epoch int type
+	lines, err = run(&MetadataOptions{LoadedAt: g.Bool(true), LoadedAtType: "unix", RowHash: g.Bool(false)})
+	if assert.NoError(t, err) && assert.Len(t, lines, 3) {
+		assert.Equal(t, "id,name,_sling_loaded_at", lines[0])
+		assert.Regexp(t, `^1,a,\d+$`, lines[1])
+	}
+
+	_, err = run(&MetadataOptions{LoadedAtType: "date"})
+	assert.ErrorContains(t, err, "invalid metadata loaded_at_type")
+}
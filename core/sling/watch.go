@@ -0,0 +1,175 @@
+package sling
+
+import (
+	"context"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/filesys"
+)
+
+// GetWatchedFiles and SetWatchedFiles read and write the already loaded files
+// (uri => modified timestamp) of a watched stream, from / into the sling state
+var (
+	GetWatchedFiles = func(cfg *Config) (files map[string]int64, err error) {
+		return map[string]int64{}, nil
+	}
+
+	SetWatchedFiles = func(cfg *Config, files map[string]int64) (err error) {
+		return nil
+	}
+)
+
+// Watcher monitors a file source for new or modified files. With the `tail`
+// source option, the files are logs read from the offset of the previous poll
+type Watcher struct {
+	Config   *Config
+	Interval time.Duration // polling interval (env var `SLING_WATCH_INTERVAL`)
+	Debounce time.Duration // min age of a file before loading (env var `SLING_WATCH_DEBOUNCE`)
+	Tail     bool          // only read the bytes appended to the files
+
+	seen    map[string]int64 // uri => modified timestamp, or bytes read when tailing
+	polled  map[string]int64
+	offsets map[string][2]int64 // byte ranges of the polled files, when tailing
+	fs      filesys.FileSysClient
+	uri     string
+}
+
+// NewWatcher creates a watcher for a task with a file source
+func NewWatcher(ctx context.Context, cfg *Config) (w *Watcher, err error) {
+	w = &Watcher{Config: cfg, Interval: 30 * time.Second, Debounce: 10 * time.Second}
+
+	if val := os.Getenv("SLING_WATCH_INTERVAL"); val != "" {
+		if w.Interval, err = time.ParseDuration(val); err != nil {
+			return nil, g.Error(err, "invalid watch interval: %s", val)
+		}
+	}
+	if val := os.Getenv("SLING_WATCH_DEBOUNCE"); val != "" {
+		if w.Debounce, err = time.ParseDuration(val); err != nil {
+			return nil, g.Error(err, "invalid watch debounce: %s", val)
+		}
+	}
+
+	// prepare a copy, to resolve the source connection
+	prepared := *cfg
+	if err = prepared.Prepare(); err != nil {
+		return nil, g.Error(err, "could not prepare watched task")
+	} else if _, err = prepared.DetermineType(); err != nil {
+		return nil, g.Error(err, "could not determine type of watched task")
+	} else if !prepared.SrcConn.Type.IsFile() {
+		return nil, g.Error("watch mode requires a file source")
+	}
+
+	if so := prepared.Source.Options; so != nil && g.PtrVal(so.Tail) {
+		format := lo.Ternary(so.Format != nil, g.PtrVal(so.Format), filesys.InferFileFormat(prepared.Source.Stream))
+		if format != dbio.FileTypeLog {
+			return nil, g.Error("the tail source option requires the log format (source option `format: log`)")
+		}
+		w.Tail = true
+	}
+
+	w.uri = prepared.SrcConn.URL()
+	w.fs, err = filesys.NewFileSysClientFromURLContext(ctx, w.uri, g.MapToKVArr(prepared.SrcConn.DataS())...)
+	if err != nil {
+		return nil, g.Error(err, "could not connect to %s", prepared.SrcConn.Type)
+	}
+
+	if w.seen, err = GetWatchedFiles(cfg); err != nil {
+		return nil, g.Error(err, "could not get watched files")
+	} else if w.seen == nil {
+		w.seen = map[string]int64{}
+	}
+
+	return w, nil
+}
+
+// Poll returns the new or modified files, which have not been modified
+// during the debounce window (so files being written are not loaded)
+func (w *Watcher) Poll() (files []string, err error) {
+	nodes, err := w.fs.ListRecursive(w.uri)
+	if err != nil {
+		return nil, g.Error(err, "could not list %s", w.uri)
+	}
+
+	w.polled = map[string]int64{}
+	w.offsets = map[string][2]int64{}
+	cutoff := time.Now().Add(-w.Debounce).Unix()
+	for _, node := range nodes.Files() {
+		if w.Tail {
+			// log writers append whole lines, so no debounce is needed
+			offset, size := w.seen[node.URI], int64(node.Size)
+			if size == offset {
+				continue
+			} else if size < offset {
+				g.Debug("%s was truncated or rotated, reading from the start", node.URI)
+				offset = 0
+			}
+			files = append(files, node.URI)
+			w.polled[node.URI] = size
+			w.offsets[node.URI] = [2]int64{offset, size}
+			continue
+		}
+
+		if updated, ok := w.seen[node.URI]; ok && updated == node.Updated {
+			continue
+		} else if node.Updated > cutoff {
+			g.Debug("waiting for %s to settle", node.URI)
+			continue
+		}
+		files = append(files, node.URI)
+		w.polled[node.URI] = node.Updated
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// TailOffsets returns the byte ranges to read of the polled files (uri => [start, end]),
+// when tailing
+func (w *Watcher) TailOffsets() map[string][2]int64 {
+	if !w.Tail {
+		return nil
+	}
+	return w.offsets
+}
+
+// markLoaded records the files as loaded, with the timestamps they had when polled
+func (w *Watcher) markLoaded(files []string) (err error) {
+	for _, file := range files {
+		w.seen[file] = w.polled[file]
+	}
+
+	return SetWatchedFiles(w.Config, w.seen)
+}
+
+// Run polls the source until the context is done, calling load with
+// each batch of new or modified files. A failed batch is retried at the next poll.
+func (w *Watcher) Run(ctx context.Context, load func(files []string) error) (err error) {
+	g.Info("watching %s for new files (every %s)", w.uri, w.Interval)
+
+	for {
+		files, err := w.Poll()
+		if err != nil {
+			return err
+		}
+
+		if len(files) > 0 {
+			g.Info("found %d new or modified files", len(files))
+			if err = load(files); err != nil {
+				g.LogError(err, "could not load files, will retry")
+			} else if err = w.markLoaded(files); err != nil {
+				return g.Error(err, "could not save watched files")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(w.Interval):
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package sling
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/filesys"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatcherPoll(t *testing.T) {
+	folder := t.TempDir()
+	fs, err := filesys.NewFileSysClient(dbio.TypeFileLocal)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	old := time.Now().Add(-time.Minute)
+	for _, name := range []string{"a.csv", "b.csv"} {
+		filePath := path.Join(folder, name)
+		assert.NoError(t, os.WriteFile(filePath, []byte("id\n1\n"), 0644))
+		assert.NoError(t, os.Chtimes(filePath, old, old))
+	}
+
+	w := &Watcher{Debounce: 10 * time.Second, seen: map[string]int64{}, fs: fs, uri: "file://" + folder + "/"}
+	files, err := w.Poll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"file://" + folder + "/a.csv", "file://" + folder + "/b.csv"}, files)
+
+	// loaded files are skipped, a file being written waits for the debounce
+	w.markLoaded(files)
+	assert.NoError(t, os.WriteFile(path.Join(folder, "c.csv"), []byte("id\n1\n"), 0644))
+	files, err = w.Poll()
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+
+	// modified files are loaded again
+	assert.NoError(t, os.Chtimes(path.Join(folder, "a.csv"), time.Now().Add(-30*time.Second), time.Now().Add(-30*time.Second)))
+	files, err = w.Poll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"file://" + folder + "/a.csv"}, files)
+}
+
+func TestWatcherTail(t *testing.T) {
+	folder := t.TempDir()
+	fs, err := filesys.NewFileSysClient(dbio.TypeFileLocal)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	logPath := path.Join(folder, "app.log")
+	uri := "file://" + logPath
+	assert.NoError(t, os.WriteFile(logPath, []byte("level=info\n"), 0644))
+
+	// tailed files are read without waiting for the debounce
+	w := &Watcher{Debounce: time.Minute, Tail: true, seen: map[string]int64{}, fs: fs, uri: "file://" + folder + "/"}
+	files, err := w.Poll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{uri}, files)
+	assert.Equal(t, map[string][2]int64{uri: {0, 11}}, w.TailOffsets())
+
+	// only the appended bytes are read
+	w.markLoaded(files)
+	files, err = w.Poll()
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+
+	f, _ := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString("level=warn\n")
+	f.Close()
+	files, err = w.Poll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{uri}, files)
+	assert.Equal(t, map[string][2]int64{uri: {11, 22}}, w.TailOffsets())
+
+	// a rotated file is read from the start
+	w.markLoaded(files)
+	assert.NoError(t, os.WriteFile(logPath, []byte("level=debug\n"), 0644))
+	files, err = w.Poll()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][2]int64{uri: {0, 12}}, w.TailOffsets())
+}
@@ -0,0 +1,329 @@
+package store
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+	"github.com/slingdata-io/sling-cli/core/dbio/filesys"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+// Backend persists the executions and the incremental states. It is the local
+// .sling.db by default, or a remote backend (env var `SLING_STATE_CONNECTION`),
+// so that multiple machines / containers share the same state.
+type Backend interface {
+	StateBackend
+	SaveExecution(exec *Execution) error
+	ListExecutions(filter ExecutionFilter) (execs []Execution, err error)
+	Lock(key, owner string, ttl time.Duration) (acquired bool, holder string, err error)
+	Unlock(key, owner string) error
+}
+
+// ExecutionFilter filters the listed executions, which are sorted newest first
+type ExecutionFilter struct {
+	ExecID         string
+	ReplicationMD5 string
+	Stream         string // supports `*` wildcards
+	Last           int    // 0 means no limit
+}
+
+// apply filters, sorts and limits the executions (for backends without queries)
+func (f ExecutionFilter) apply(execs []Execution) []Execution {
+	filtered := []Execution{}
+	for _, exec := range execs {
+		if f.ExecID != "" && exec.ExecID != f.ExecID {
+			continue
+		} else if f.ReplicationMD5 != "" && exec.ReplicationMD5 != f.ReplicationMD5 {
+			continue
+		} else if f.Stream != "" && !g.IsMatched([]string{f.Stream}, exec.StreamName) {
+			continue
+		}
+		filtered = append(filtered, exec)
+	}
+
+	sort.Slice(filtered, func(i, j int) bool { return filtered[i].ID > filtered[j].ID })
+	if f.Last > 0 && len(filtered) > f.Last {
+		filtered = filtered[:f.Last]
+	}
+	return filtered
+}
+
+var (
+	backends   = map[string]Backend{}
+	backendMux sync.Mutex
+)
+
+// GetBackend returns the remote backend (env var `SLING_STATE_CONNECTION`)
+// if provided, otherwise the local .sling.db
+func GetBackend() (Backend, error) {
+	location := os.Getenv("SLING_STATE_CONNECTION")
+	if location == "" {
+		if InitDB(); Db == nil {
+			return nil, g.Error("local .sling.db is not available")
+		}
+		return &dbBackend{db: Db}, nil
+	}
+	return NewBackend(location)
+}
+
+// NewBackend returns the backend of the location, which is a connection name
+// (database or file system), optionally followed by a folder (`AWS_S3/sling_state`),
+// or a redis URL (`redis://host:6379/0`). Backends are cached.
+func NewBackend(location string) (backend Backend, err error) {
+	backendMux.Lock()
+	defer backendMux.Unlock()
+
+	if backend, ok := backends[location]; ok {
+		return backend, nil
+	}
+
+	connName, folder := location, "sling_state"
+	if !strings.Contains(location, "://") && strings.Contains(location, "/") {
+		connName, folder, _ = strings.Cut(location, "/")
+	}
+
+	// a redis URL, directly or via an env var
+	redisURL := connName
+	if val := os.Getenv(connName); val != "" {
+		redisURL = val
+	}
+	if strings.HasPrefix(redisURL, "redis://") || strings.HasPrefix(redisURL, "rediss://") {
+		if backend, err = newRedisBackend(redisURL); err != nil {
+			return nil, g.Error(err, "could not init redis state backend")
+		}
+		backends[location] = backend
+		return backend, nil
+	}
+
+	conn := connection.GetLocalConns().Get(connName).Connection
+	if conn.Name == "" {
+		if !strings.Contains(connName, "://") {
+			return nil, g.Error("did not find state connection: %s", connName)
+		} else if conn, err = connection.NewConnectionFromURL("state", connName); err != nil {
+			return nil, g.Error(err, "could not parse state connection URL")
+		}
+	}
+
+	switch {
+	case conn.Type.IsDb():
+		dbConn, err := conn.AsDatabase(true)
+		if err != nil {
+			return nil, g.Error(err, "could not init state connection: %s", connName)
+		}
+
+		db, err := dbConn.GetGormConn(&gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		if err != nil {
+			return nil, g.Error(err, "could not connect to state connection: %s", connName)
+		} else if err = db.AutoMigrate(&Execution{}, &State{}, &Lock{}); err != nil {
+			return nil, g.Error(err, "could not create state tables in %s", connName)
+		}
+		backend = &dbBackend{db: db}
+	case conn.Type.IsFile():
+		fs, err := conn.AsFile(true)
+		if err != nil {
+			return nil, g.Error(err, "could not init state connection: %s", connName)
+		}
+		backend = &fileBackend{fs: fs, folder: strings.Trim(folder, "/")}
+	default:
+		return nil, g.Error("unsupported state connection type: %s", conn.Type)
+	}
+
+	backends[location] = backend
+	return backend, nil
+}
+
+// dbBackend stores into a database (the local .sling.db, or a postgres)
+type dbBackend struct {
+	db *gorm.DB
+}
+
+func (b *dbBackend) Get(key string) (value string, found bool, err error) {
+	var states []State
+	if err = b.db.Where("key = ?", key).Limit(1).Find(&states).Error; err != nil {
+		return "", false, g.Error(err, "could not get state %s", key)
+	} else if len(states) == 0 {
+		return "", false, nil
+	}
+	return states[0].Value, true, nil
+}
+
+func (b *dbBackend) Set(states ...State) error {
+	if len(states) == 0 {
+		return nil
+	}
+
+	err := b.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&states).Error
+	if err != nil {
+		return g.Error(err, "could not save states")
+	}
+	return nil
+}
+
+func (b *dbBackend) List() (states []State, err error) {
+	if err = b.db.Order("key").Find(&states).Error; err != nil {
+		return nil, g.Error(err, "could not list states")
+	}
+	return states, nil
+}
+
+func (b *dbBackend) SaveExecution(exec *Execution) error {
+	if err := b.db.Omit("output").Save(exec).Error; err != nil {
+		return g.Error(err, "could not save execution")
+	}
+	return nil
+}
+
+func (b *dbBackend) ListExecutions(filter ExecutionFilter) (execs []Execution, err error) {
+	query := b.db.Order("id desc")
+	if filter.ExecID != "" {
+		query = query.Where("exec_id = ?", filter.ExecID)
+	}
+	if filter.ReplicationMD5 != "" {
+		query = query.Where("replication_md5 = ?", filter.ReplicationMD5)
+	}
+	if filter.Stream != "" {
+		query = query.Where("stream_name like ?", strings.ReplaceAll(filter.Stream, "*", "%"))
+	}
+	if filter.Last > 0 {
+		query = query.Limit(filter.Last)
+	}
+
+	if err = query.Find(&execs).Error; err != nil {
+		return nil, g.Error(err, "could not list executions")
+	}
+	return execs, nil
+}
+
+// fileBackend stores JSON files into a file system (such as S3): the states
+// in the folder (one per stream), and the executions in the `executions` sub-folder
+type fileBackend struct {
+	fs     filesys.FileSysClient
+	folder string
+}
+
+func (b *fileBackend) uri(path string) string {
+	return filesys.NormalizeURI(b.fs, b.folder+"/"+path)
+}
+
+func (b *fileBackend) read(uri string, obj any) (err error) {
+	reader, err := b.fs.GetReader(uri)
+	if err != nil {
+		return g.Error(err, "could not read %s", uri)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return g.Error(err, "could not read %s", uri)
+	} else if err = g.Unmarshal(string(data), obj); err != nil {
+		return g.Error(err, "could not parse %s", uri)
+	}
+	return nil
+}
+
+func (b *fileBackend) write(uri string, obj any) (err error) {
+	if _, err = b.fs.Write(uri, bytes.NewReader([]byte(g.Marshal(obj)))); err != nil {
+		return g.Error(err, "could not write %s", uri)
+	}
+	return nil
+}
+
+// isNotFound returns true if the error is a missing file or folder
+func isNotFound(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, text := range []string{"no such file", "not exist", "doesn't exist", "not found", "notfound", "nosuchkey"} {
+		if strings.Contains(msg, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// readAll reads all the JSON files of a folder
+func readAll[T any](b *fileBackend, folder string) (objs []T, err error) {
+	nodes, err := b.fs.List(b.uri(folder))
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil // folder does not exist yet
+		}
+		return nil, g.Error(err, "could not list %s", b.uri(folder))
+	}
+
+	for _, node := range nodes.Files() {
+		if !strings.HasSuffix(node.URI, ".json") {
+			continue
+		}
+		var obj T
+		if err = b.read(node.URI, &obj); err != nil {
+			return nil, err
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+func (b *fileBackend) statePath(key string) string {
+	return iop.CleanName(key) + ".json"
+}
+
+func (b *fileBackend) Get(key string) (value string, found bool, err error) {
+	nodes, err := b.fs.List(b.uri(b.statePath(key)))
+	if err != nil && !isNotFound(err) {
+		return "", false, g.Error(err, "could not get state %s", key)
+	} else if err != nil || len(nodes) == 0 {
+		return "", false, nil // no state yet
+	}
+
+	var state State
+	if err = b.read(b.uri(b.statePath(key)), &state); err != nil {
+		return "", false, err
+	}
+	return state.Value, true, nil
+}
+
+func (b *fileBackend) Set(states ...State) error {
+	for _, state := range states {
+		state.UpdatedDt = time.Now().UTC()
+		if err := b.write(b.uri(b.statePath(state.Key)), state); err != nil {
+			return g.Error(err, "could not save state %s", state.Key)
+		}
+	}
+	return nil
+}
+
+func (b *fileBackend) List() (states []State, err error) {
+	if states, err = readAll[State](b, "/"); err != nil {
+		return nil, g.Error(err, "could not list states")
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Key < states[j].Key })
+	return states, nil
+}
+
+func (b *fileBackend) SaveExecution(exec *Execution) error {
+	if exec.ID == 0 {
+		exec.ID = time.Now().UnixNano() // sortable
+	}
+
+	record := *exec
+	record.Output, record.Task, record.Replication = "", nil, nil
+	if err := b.write(b.uri(g.F("executions/%d.json", exec.ID)), record); err != nil {
+		return g.Error(err, "could not save execution")
+	}
+	return nil
+}
+
+func (b *fileBackend) ListExecutions(filter ExecutionFilter) (execs []Execution, err error) {
+	if execs, err = readAll[Execution](b, "executions/"); err != nil {
+		return nil, g.Error(err, "could not list executions")
+	}
+	return filter.apply(execs), nil
+}
@@ -0,0 +1,305 @@
+package store
+
+import (
+	"bufio"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/spf13/cast"
+)
+
+const (
+	redisStateKey      = "sling:state"
+	redisExecutionsKey = "sling:executions"
+	redisExecutionSeq  = "sling:execution_seq"
+)
+
+// redisBackend stores into redis hashes: the states by key,
+// and the executions by id
+type redisBackend struct {
+	addr     string
+	username string
+	password string
+	db       int
+	tls      bool
+
+	conn   net.Conn // reused between commands
+	reader *bufio.Reader
+	mux    sync.Mutex
+}
+
+// redisError is an error reply of the server (the connection is still usable)
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// newRedisBackend parses a redis URL: `redis://[user:password@]host[:port][/db]`
+func newRedisBackend(redisURL string) (b *redisBackend, err error) {
+	u, err := url.Parse(redisURL)
+	if err != nil {
+		return nil, g.Error(err, "invalid redis URL")
+	}
+
+	b = &redisBackend{addr: u.Host, tls: u.Scheme == "rediss"}
+	if u.Port() == "" {
+		b.addr = u.Host + ":6379"
+	}
+	if u.User != nil {
+		b.username = u.User.Username()
+		b.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if b.db, err = strconv.Atoi(db); err != nil {
+			return nil, g.Error("invalid redis database: %s", db)
+		}
+	}
+
+	// test connection
+	if _, err = b.do("PING"); err != nil {
+		return nil, g.Error(err, "could not connect to redis")
+	}
+
+	return b, nil
+}
+
+// connect opens the connection, authenticating and selecting the database
+func (b *redisBackend) connect() (err error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+
+	var conn net.Conn
+	if b.tls {
+		conn, err = tls.DialWithDialer(dialer, "tcp", b.addr, &tls.Config{})
+	} else {
+		conn, err = dialer.Dial("tcp", b.addr)
+	}
+	if err != nil {
+		return g.Error(err, "could not connect to %s", b.addr)
+	}
+	b.conn, b.reader = conn, bufio.NewReader(conn)
+
+	commands := [][]string{}
+	if b.password != "" {
+		if b.username != "" {
+			commands = append(commands, []string{"AUTH", b.username, b.password})
+		} else {
+			commands = append(commands, []string{"AUTH", b.password})
+		}
+	}
+	if b.db > 0 {
+		commands = append(commands, []string{"SELECT", cast.ToString(b.db)})
+	}
+
+	for _, command := range commands {
+		if _, err = b.send(command...); err != nil {
+			b.close()
+			return g.Error(err, "redis command %s failed", command[0])
+		}
+	}
+	return nil
+}
+
+// close closes the connection, a new one is opened on the next command
+func (b *redisBackend) close() {
+	if b.conn != nil {
+		b.conn.Close()
+	}
+	b.conn, b.reader = nil, nil
+}
+
+// send writes a command on the connection and reads its reply
+func (b *redisBackend) send(args ...string) (reply any, err error) {
+	b.conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	payload := g.F("*%d\r\n", len(args))
+	for _, arg := range args {
+		payload += g.F("$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err = b.conn.Write([]byte(payload)); err != nil {
+		return nil, g.Error(err, "could not send redis command")
+	}
+
+	return redisReadReply(b.reader)
+}
+
+// do runs a command on the connection, which is opened on first use and reused.
+// On a connection error (e.g. closed by the server when idle), the command is retried once
+// on a new connection.
+func (b *redisBackend) do(args ...string) (reply any, err error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		reused := b.conn != nil
+		if !reused {
+			if err = b.connect(); err != nil {
+				return nil, err
+			}
+		}
+
+		reply, err = b.send(args...)
+		if _, ok := err.(redisError); err == nil || ok {
+			break
+		}
+
+		b.close()
+		if !reused {
+			break // a new connection failed, no retry
+		}
+	}
+
+	if err != nil {
+		return nil, g.Error(err, "redis command %s failed", args[0])
+	}
+	return reply, nil
+}
+
+// redisReadReply reads a reply of the RESP protocol
+func redisReadReply(reader *bufio.Reader) (reply any, err error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, g.Error("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, _ := strconv.Atoi(line[1:])
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err = io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	case '*':
+		size, _ := strconv.Atoi(line[1:])
+		if size < 0 {
+			return nil, nil
+		}
+		items := make([]any, size)
+		for i := range items {
+			if items[i], err = redisReadReply(reader); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+
+	return nil, g.Error("invalid redis reply: %s", line)
+}
+
+// hashValues returns the values of a hash
+func (b *redisBackend) hashValues(key string) (values []string, err error) {
+	reply, err := b.do("HVALS", key)
+	if err != nil {
+		return nil, err
+	}
+
+	items, _ := reply.([]any)
+	for _, item := range items {
+		values = append(values, cast.ToString(item))
+	}
+	return values, nil
+}
+
+func (b *redisBackend) Get(key string) (value string, found bool, err error) {
+	reply, err := b.do("HGET", redisStateKey, key)
+	if err != nil {
+		return "", false, g.Error(err, "could not get state %s", key)
+	} else if reply == nil {
+		return "", false, nil
+	}
+
+	var state State
+	if err = g.Unmarshal(cast.ToString(reply), &state); err != nil {
+		return "", false, g.Error(err, "could not parse state %s", key)
+	}
+	return state.Value, true, nil
+}
+
+func (b *redisBackend) Set(states ...State) error {
+	if len(states) == 0 {
+		return nil
+	}
+
+	args := []string{"HSET", redisStateKey}
+	for _, state := range states {
+		state.UpdatedDt = time.Now().UTC()
+		args = append(args, state.Key, g.Marshal(state))
+	}
+
+	if _, err := b.do(args...); err != nil {
+		return g.Error(err, "could not save states")
+	}
+	return nil
+}
+
+func (b *redisBackend) List() (states []State, err error) {
+	values, err := b.hashValues(redisStateKey)
+	if err != nil {
+		return nil, g.Error(err, "could not list states")
+	}
+
+	for _, value := range values {
+		var state State
+		if err = g.Unmarshal(value, &state); err != nil {
+			return nil, g.Error(err, "could not parse state")
+		}
+		states = append(states, state)
+	}
+
+	sort.Slice(states, func(i, j int) bool { return states[i].Key < states[j].Key })
+	return states, nil
+}
+
+func (b *redisBackend) SaveExecution(exec *Execution) error {
+	if exec.ID == 0 {
+		reply, err := b.do("INCR", redisExecutionSeq)
+		if err != nil {
+			return g.Error(err, "could not get execution id")
+		}
+		exec.ID = cast.ToInt64(reply)
+	}
+
+	record := *exec
+	record.Output, record.Task, record.Replication = "", nil, nil
+	if _, err := b.do("HSET", redisExecutionsKey, cast.ToString(exec.ID), g.Marshal(record)); err != nil {
+		return g.Error(err, "could not save execution")
+	}
+	return nil
+}
+
+func (b *redisBackend) ListExecutions(filter ExecutionFilter) (execs []Execution, err error) {
+	values, err := b.hashValues(redisExecutionsKey)
+	if err != nil {
+		return nil, g.Error(err, "could not list executions")
+	}
+
+	for _, value := range values {
+		var exec Execution
+		if err = g.Unmarshal(value, &exec); err != nil {
+			return nil, g.Error(err, "could not parse execution")
+		}
+		execs = append(execs, exec)
+	}
+
+	return filter.apply(execs), nil
+}
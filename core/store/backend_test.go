@@ -0,0 +1,198 @@
+package store
+
+import (
+	"bufio"
+	"net"
+	"path"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/flarco/g"
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/filesys"
+	"github.com/slingdata-io/sling-cli/core/sling"
+	"github.com/spf13/cast"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBackend checks the states and executions of a backend
+func testBackend(t *testing.T, b Backend) {
+	_, found, err := b.Get("PG/SF/public.orders")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	states, err := b.List()
+	assert.NoError(t, err)
+	assert.Empty(t, states)
+
+	err = b.Set(State{Key: "PG/SF/public.users", Value: "'2024-01-01'"}, State{Key: "PG/SF/public.orders", Value: "10"})
+	require.NoError(t, err)
+	require.NoError(t, b.Set(State{Key: "PG/SF/public.orders", Value: "20"}))
+
+	value, found, err := b.Get("PG/SF/public.orders")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "20", value)
+
+	states, err = b.List()
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"PG/SF/public.orders", "PG/SF/public.users"}, lo.Map(states, func(s State, i int) string { return s.Key }))
+	}
+
+	execs, err := b.ListExecutions(ExecutionFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, execs)
+
+	for i, stream := range []string{"public.orders", "public.users", "sales.items"} {
+		exec := &Execution{ExecID: g.F("exec%d", i/2), StreamName: stream, Status: sling.ExecStatusSuccess, Rows: uint64(i)}
+		require.NoError(t, b.SaveExecution(exec))
+		assert.NotZero(t, exec.ID)
+	}
+
+	execs, err = b.ListExecutions(ExecutionFilter{Stream: "public.*"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{"public.users", "public.orders"}, lo.Map(execs, func(e Execution, i int) string { return e.StreamName }))
+	}
+	execs, err = b.ListExecutions(ExecutionFilter{ExecID: "exec1"})
+	if assert.NoError(t, err) && assert.Len(t, execs, 1) {
+		assert.Equal(t, "sales.items", execs[0].StreamName)
+	}
+	execs, err = b.ListExecutions(ExecutionFilter{Last: 1})
+	if assert.NoError(t, err) && assert.Len(t, execs, 1) {
+		assert.Equal(t, "sales.items", execs[0].StreamName)
+	}
+}
+
+func TestBackendDatabase(t *testing.T) {
+	b, err := NewBackend("sqlite://" + path.Join(t.TempDir(), "state.db"))
+	require.NoError(t, err)
+	testBackend(t, b)
+}
+
+func TestBackendFile(t *testing.T) {
+	fs, err := filesys.NewFileSysClient(dbio.TypeFileLocal)
+	require.NoError(t, err)
+	testBackend(t, &fileBackend{fs: fs, folder: t.TempDir()})
+
+	assert.True(t, isNotFound(g.Error("open /tmp/x: no such file or directory")))
+	assert.True(t, isNotFound(g.Error("storage: object doesn't exist")))
+	assert.False(t, isNotFound(g.Error("AccessDenied: access denied")))
+}
+
+func TestBackendRedis(t *testing.T) {
+	server := newFakeRedis(t)
+	b, err := NewBackend("redis://" + server.addr + "/1")
+	require.NoError(t, err)
+	testBackend(t, b)
+
+	// the connection is reused between commands
+	assert.EqualValues(t, 1, server.connections.Load())
+	server.mux.Lock()
+	assert.Equal(t, []string{"SELECT", "PING"}, server.commands[:2]) // database selected once
+	server.mux.Unlock()
+
+	// a connection closed by the server is re-opened
+	server.closeAll()
+	_, _, err = b.Get("PG/SF/public.orders")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, server.connections.Load())
+
+	// error replies are returned
+	_, err = b.(*redisBackend).do("UNKNOWN")
+	assert.ErrorContains(t, err, "unknown command")
+	assert.EqualValues(t, 2, server.connections.Load())
+}
+
+// fakeRedis is a minimal RESP server, with the hash commands used by the backend
+type fakeRedis struct {
+	addr        string
+	connections atomic.Int64
+	commands    []string
+	hashes      map[string]map[string]string
+	seq         int64
+	conns       []net.Conn
+	mux         sync.Mutex
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeRedis{addr: listener.Addr().String(), hashes: map[string]map[string]string{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			server.connections.Add(1)
+			server.mux.Lock()
+			server.conns = append(server.conns, conn)
+			server.mux.Unlock()
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *fakeRedis) closeAll() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		request, err := redisReadReply(reader)
+		if err != nil {
+			return
+		}
+		args := lo.Map(request.([]any), func(arg any, i int) string { return cast.ToString(arg) })
+
+		s.mux.Lock()
+		s.commands = append(s.commands, args[0])
+		reply := "+OK\r\n"
+		switch args[0] {
+		case "PING":
+			reply = "+PONG\r\n"
+		case "SELECT":
+		case "INCR":
+			s.seq++
+			reply = g.F(":%d\r\n", s.seq)
+		case "HSET":
+			if s.hashes[args[1]] == nil {
+				s.hashes[args[1]] = map[string]string{}
+			}
+			for i := 2; i+1 < len(args); i += 2 {
+				s.hashes[args[1]][args[i]] = args[i+1]
+			}
+			reply = g.F(":%d\r\n", (len(args)-2)/2)
+		case "HGET":
+			if value, ok := s.hashes[args[1]][args[2]]; ok {
+				reply = g.F("$%d\r\n%s\r\n", len(value), value)
+			} else {
+				reply = "$-1\r\n"
+			}
+		case "HVALS":
+			reply = g.F("*%d\r\n", len(s.hashes[args[1]]))
+			for _, value := range s.hashes[args[1]] {
+				reply += g.F("$%d\r\n%s\r\n", len(value), value)
+			}
+		default:
+			reply = g.F("-ERR unknown command '%s'\r\n", args[0])
+		}
+		s.mux.Unlock()
+
+		if _, err = conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
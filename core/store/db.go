@@ -5,8 +5,10 @@ import (
 	"github.com/flarco/g"
 	"github.com/jmoiron/sqlx"
 	"github.com/slingdata-io/sling-cli/core/dbio/database"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
 	"github.com/slingdata-io/sling-cli/core/env"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
@@ -20,6 +22,15 @@ var (
 	DropAll = false
 )
 
+func init() {
+	iop.NewLocalTokenVault = func(name string) (iop.TokenVault, error) {
+		if InitDB(); Db == nil {
+			return nil, g.Error("local .sling.db is not available")
+		}
+		return &localTokenVault{name: name}, nil
+	}
+}
+
 // InitDB initializes the database
 func InitDB() {
 	var err error
@@ -46,6 +57,10 @@ func InitDB() {
 
 	allTables := []interface{}{
 		&Setting{},
+		&Token{},
+		&Execution{},
+		&State{},
+		&Lock{},
 	}
 
 	for _, table := range allTables {
@@ -70,6 +85,42 @@ type Setting struct {
 	Value string `json:"value"`
 }
 
+// Token is a tokenized value, used for reversible pseudonymization
+type Token struct {
+	Vault string `json:"vault" gorm:"primaryKey"`
+	Token string `json:"token" gorm:"primaryKey"`
+	Value string `json:"value"`
+}
+
+// localTokenVault is a token vault stored in the local .sling.db
+type localTokenVault struct {
+	name string
+}
+
+func (v *localTokenVault) SetTokens(tokenValues map[string]string) error {
+	tokens := make([]Token, 0, len(tokenValues))
+	for token, value := range tokenValues {
+		tokens = append(tokens, Token{Vault: v.name, Token: token, Value: value})
+	}
+
+	err := Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&tokens).Error
+	if err != nil {
+		return g.Error(err, "could not insert tokens into local vault")
+	}
+	return nil
+}
+
+func (v *localTokenVault) GetValue(token string) (value string, found bool, err error) {
+	var tokens []Token
+	err = Db.Where("vault = ? and token = ?", v.name, token).Limit(1).Find(&tokens).Error
+	if err != nil {
+		return "", false, g.Error(err, "could not get token from local vault")
+	} else if len(tokens) == 0 {
+		return "", false, nil
+	}
+	return tokens[0].Value, true, nil
+}
+
 func settings() {
 	// ProtectedID returns a hashed version of the machine ID in a cryptographically secure way,
 	// using a fixed, application-specific key.
@@ -0,0 +1,317 @@
+package store
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio/filesys"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/slingdata-io/sling-cli/core/sling"
+	"github.com/spf13/cast"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// lockTTL is the expiry of a run lock, so that the lock of a killed
+	// process is released. The lock is refreshed while running.
+	lockTTL = 5 * time.Minute
+
+	// lockRetryInterval is the interval between attempts, when waiting for a lock
+	lockRetryInterval = 5 * time.Second
+)
+
+// Lock is an advisory run lock, held by an owner until it expires
+type Lock struct {
+	Key       string    `json:"key" gorm:"primaryKey"`
+	Owner     string    `json:"owner"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// StreamLock is an acquired run lock of a stream
+type StreamLock struct {
+	backend Backend
+	key     string
+	owner   string
+	done    chan struct{}
+	lost    chan struct{} // closed when the lock is lost
+	lostErr error
+}
+
+// LockStream acquires the run lock of a stream, so that concurrent invocations
+// of the same stream do not double-load (env var `SLING_RUN_LOCK`):
+// `fail` returns an error if the stream is already running, `wait` waits for
+// the lock to be released, up to `SLING_RUN_LOCK_TIMEOUT` (default 1h).
+// Returns a nil lock if locking is disabled.
+func LockStream(ctx context.Context, cfg *sling.Config, execID string) (lock *StreamLock, err error) {
+	mode := strings.ToLower(os.Getenv("SLING_RUN_LOCK"))
+	switch mode {
+	case "", "false", "off":
+		return nil, nil
+	case "fail", "wait":
+	default:
+		return nil, g.Error("invalid run lock mode: %s (expected fail or wait)", mode)
+	}
+
+	timeout := time.Hour
+	if val := os.Getenv("SLING_RUN_LOCK_TIMEOUT"); val != "" {
+		if timeout, err = time.ParseDuration(val); err != nil {
+			return nil, g.Error(err, "invalid run lock timeout: %s", val)
+		}
+	}
+
+	backend, err := GetBackend()
+	if err != nil {
+		return nil, g.Error(err, "could not get store backend for run lock")
+	}
+
+	hostname, _ := os.Hostname()
+	lock = &StreamLock{
+		backend: backend,
+		key:     "run/" + StateKey(cfg),
+		owner:   g.F("%s@%s:%d", execID, hostname, os.Getpid()),
+		done:    make(chan struct{}),
+		lost:    make(chan struct{}),
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		acquired, holder, err := backend.Lock(lock.key, lock.owner, lockTTL)
+		if err != nil {
+			return nil, g.Error(err, "could not acquire run lock")
+		} else if acquired {
+			break
+		}
+
+		if mode == "fail" {
+			return nil, g.Error("stream %s is already running (locked by %s)", cfg.StreamLabel(), holder)
+		} else if time.Now().After(deadline) {
+			return nil, g.Error("timed out waiting for stream %s to finish running (locked by %s)", cfg.StreamLabel(), holder)
+		}
+
+		g.Info("stream %s is already running (locked by %s), waiting...", cfg.StreamLabel(), holder)
+		select {
+		case <-ctx.Done():
+			return nil, g.Error("interrupted while waiting for run lock")
+		case <-time.After(lockRetryInterval):
+		}
+	}
+
+	g.Debug("acquired run lock %s (%s)", lock.key, lock.owner)
+
+	go lock.refresh()
+
+	return lock, nil
+}
+
+// refresh extends the lock while running. The lock is lost if another owner
+// holds it, or if it could not be refreshed before expiring.
+func (l *StreamLock) refresh() {
+	ticker := time.NewTicker(lockTTL / 5)
+	defer ticker.Stop()
+
+	refreshed := time.Now()
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+		}
+
+		acquired, holder, err := l.backend.Lock(l.key, l.owner, lockTTL)
+		switch {
+		case err != nil && time.Since(refreshed) < lockTTL:
+			g.Warn("could not refresh run lock: %s", err.Error())
+			continue
+		case err != nil:
+			l.lostErr = g.Error(err, "run lock %s expired, could not refresh it", l.key)
+		case !acquired:
+			l.lostErr = g.Error("run lock %s was lost (now locked by %s)", l.key, holder)
+		default:
+			refreshed = time.Now()
+			continue
+		}
+
+		close(l.lost)
+		return
+	}
+}
+
+// Context returns a child context of the task, which is cancelled if the lock
+// is lost, so that the run stops. Returns the parent if there is no lock.
+func (l *StreamLock) Context(parent *g.Context) *g.Context {
+	if l == nil {
+		return parent
+	}
+
+	ctx := g.NewContext(parent.Ctx)
+	go func() {
+		select {
+		case <-l.lost:
+			g.Warn(l.lostErr.Error())
+			ctx.Cancel()
+		case <-l.done:
+		case <-ctx.Ctx.Done():
+		}
+	}()
+	return ctx
+}
+
+// Err returns the reason the lock was lost, if it was
+func (l *StreamLock) Err() error {
+	if l == nil {
+		return nil
+	}
+
+	select {
+	case <-l.lost:
+		return l.lostErr
+	default:
+		return nil
+	}
+}
+
+// Release releases the lock
+func (l *StreamLock) Release() {
+	if l == nil {
+		return
+	}
+
+	close(l.done)
+	if l.Err() != nil {
+		return // held by another owner
+	}
+	if err := l.backend.Unlock(l.key, l.owner); err != nil {
+		g.Warn("could not release run lock: %s", err.Error())
+	}
+}
+
+// Lock acquires (or extends) a lock, unless held by another owner
+func (b *dbBackend) Lock(key, owner string, ttl time.Duration) (acquired bool, holder string, err error) {
+	now := time.Now()
+	err = b.db.Transaction(func(tx *gorm.DB) error {
+		// remove the expired lock, or our own to extend it
+		err := tx.Where("key = ? and (expires_at < ? or owner = ?)", key, now, owner).Delete(&Lock{}).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&Lock{Key: key, Owner: owner, ExpiresAt: now.Add(ttl)})
+		if result.Error != nil {
+			return result.Error
+		} else if acquired = result.RowsAffected == 1; acquired {
+			return nil
+		}
+
+		var locks []Lock
+		if err = tx.Where("key = ?", key).Limit(1).Find(&locks).Error; err == nil && len(locks) > 0 {
+			holder = locks[0].Owner
+		}
+		return err
+	})
+	if err != nil {
+		return false, "", g.Error(err, "could not acquire lock %s", key)
+	}
+	return acquired, holder, nil
+}
+
+// Unlock releases a lock, if held by the owner
+func (b *dbBackend) Unlock(key, owner string) error {
+	if err := b.db.Where("key = ? and owner = ?", key, owner).Delete(&Lock{}).Error; err != nil {
+		return g.Error(err, "could not release lock %s", key)
+	}
+	return nil
+}
+
+func (b *fileBackend) lockPath(key string) string {
+	return b.uri("locks/" + iop.CleanName(key) + ".json")
+}
+
+// readLock returns the current lock, if any
+func (b *fileBackend) readLock(key string) (lock Lock, found bool, err error) {
+	nodes, err := b.fs.List(b.lockPath(key))
+	if err != nil && !isNotFound(err) {
+		return lock, false, g.Error(err, "could not read lock %s", key)
+	} else if err != nil || len(nodes) == 0 {
+		return lock, false, nil
+	}
+
+	if err = b.read(b.lockPath(key), &lock); err != nil {
+		return lock, false, err
+	}
+	return lock, true, nil
+}
+
+// Lock acquires (or extends) a lock, unless held by another owner. Object stores do not
+// offer an atomic create, so the lock is read back after writing, to detect a race.
+// This is best effort: two owners writing at nearly the same time may both read back
+// their own lock, so the file backend should not be relied on when concurrent runs
+// must be strictly prevented (prefer a database or redis backend).
+func (b *fileBackend) Lock(key, owner string, ttl time.Duration) (acquired bool, holder string, err error) {
+	lock, found, err := b.readLock(key)
+	if err != nil {
+		return false, "", g.Error(err, "could not acquire lock %s", key)
+	} else if found && lock.Owner != owner && lock.ExpiresAt.After(time.Now()) {
+		return false, lock.Owner, nil
+	}
+
+	if err = b.write(b.lockPath(key), Lock{Key: key, Owner: owner, ExpiresAt: time.Now().Add(ttl)}); err != nil {
+		return false, "", g.Error(err, "could not acquire lock %s", key)
+	}
+
+	if lock, _, err = b.readLock(key); err != nil {
+		return false, "", g.Error(err, "could not acquire lock %s", key)
+	}
+	return lock.Owner == owner, lock.Owner, nil
+}
+
+// Unlock releases a lock, if held by the owner
+func (b *fileBackend) Unlock(key, owner string) error {
+	if lock, found, err := b.readLock(key); err != nil {
+		return g.Error(err, "could not release lock %s", key)
+	} else if !found || lock.Owner != owner {
+		return nil
+	}
+
+	if err := filesys.Delete(b.fs, b.lockPath(key)); err != nil {
+		return g.Error(err, "could not release lock %s", key)
+	}
+	return nil
+}
+
+const (
+	// redisLockScript sets the lock if free or already owned, and returns the holder
+	redisLockScript = `local v = redis.call('GET', KEYS[1])
+if v == false or v == ARGV[1] then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return ARGV[1]
+end
+return v`
+
+	// redisUnlockScript deletes the lock if owned
+	redisUnlockScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`
+)
+
+// Lock acquires (or extends) a lock, unless held by another owner
+func (b *redisBackend) Lock(key, owner string, ttl time.Duration) (acquired bool, holder string, err error) {
+	reply, err := b.do("EVAL", redisLockScript, "1", "sling:lock:"+key, owner, cast.ToString(ttl.Milliseconds()))
+	if err != nil {
+		return false, "", g.Error(err, "could not acquire lock %s", key)
+	}
+	holder = cast.ToString(reply)
+	return holder == owner, holder, nil
+}
+
+// Unlock releases a lock, if held by the owner
+func (b *redisBackend) Unlock(key, owner string) error {
+	if _, err := b.do("EVAL", redisUnlockScript, "1", "sling:lock:"+key, owner); err != nil {
+		return g.Error(err, "could not release lock %s", key)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"context"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio"
+	"github.com/slingdata-io/sling-cli/core/dbio/filesys"
+	"github.com/slingdata-io/sling-cli/core/sling"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testLock checks the acquire, contend, expiry and release of a backend lock
+func testLock(t *testing.T, b Backend) {
+	acquired, _, err := b.Lock("run/orders", "a", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// contend
+	acquired, holder, err := b.Lock("run/orders", "b", time.Hour)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	assert.Equal(t, "a", holder)
+
+	// extend
+	acquired, _, err = b.Lock("run/orders", "a", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// release, by the owner only
+	require.NoError(t, b.Unlock("run/orders", "b"))
+	acquired, _, _ = b.Lock("run/orders", "b", time.Hour)
+	assert.False(t, acquired)
+
+	require.NoError(t, b.Unlock("run/orders", "a"))
+	acquired, _, err = b.Lock("run/orders", "b", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// expiry
+	acquired, _, _ = b.Lock("run/users", "a", 50*time.Millisecond)
+	assert.True(t, acquired)
+	time.Sleep(100 * time.Millisecond)
+	acquired, _, err = b.Lock("run/users", "b", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+}
+
+func TestLockDatabase(t *testing.T) {
+	b, err := NewBackend("sqlite://" + path.Join(t.TempDir(), "lock.db"))
+	require.NoError(t, err)
+	testLock(t, b)
+}
+
+func TestLockFile(t *testing.T) {
+	fs, err := filesys.NewFileSysClient(dbio.TypeFileLocal)
+	require.NoError(t, err)
+	testLock(t, &fileBackend{fs: fs, folder: t.TempDir()})
+}
+
+func TestLockStream(t *testing.T) {
+	initTestDB(t)
+	t.Setenv("SLING_RUN_LOCK", "fail")
+
+	ttl := lockTTL
+	lockTTL = 100 * time.Millisecond
+	t.Cleanup(func() { lockTTL = ttl })
+
+	cfg := &sling.Config{
+		Source: sling.Source{Conn: "PG", Stream: "public.orders"},
+		Target: sling.Target{Conn: "SF", Object: "public.orders"},
+	}
+
+	lock, err := LockStream(context.Background(), cfg, "exec1")
+	require.NoError(t, err)
+	require.NotNil(t, lock)
+
+	// a concurrent run fails
+	_, err = LockStream(context.Background(), cfg, "exec2")
+	assert.ErrorContains(t, err, "already running")
+
+	// the lock is kept while running
+	time.Sleep(2 * lockTTL)
+	assert.NoError(t, lock.Err())
+
+	// the run is cancelled when the lock is lost
+	taskCtx := lock.Context(g.NewContext(context.Background()))
+	require.NoError(t, lock.backend.Unlock(lock.key, lock.owner))
+	acquired, _, err := lock.backend.Lock(lock.key, "other", time.Hour)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	select {
+	case <-taskCtx.Ctx.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("task context was not cancelled")
+	}
+	assert.ErrorContains(t, lock.Err(), "locked by other")
+
+	// the lock of the other owner is not released
+	lock.Release()
+	acquired, holder, _ := lock.backend.Lock(lock.key, "third", time.Hour)
+	assert.False(t, acquired)
+	assert.Equal(t, "other", holder)
+
+	// no lock when disabled
+	t.Setenv("SLING_RUN_LOCK", "")
+	lock, err = LockStream(context.Background(), cfg, "exec3")
+	assert.NoError(t, err)
+	assert.Nil(t, lock)
+	assert.NoError(t, lock.Err())
+	parent := g.NewContext(context.Background())
+	assert.Equal(t, parent, lock.Context(parent))
+}
@@ -0,0 +1,205 @@
+package store
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core/dbio/iop"
+	"github.com/slingdata-io/sling-cli/core/sling"
+)
+
+func init() {
+	sling.GetIncrementalValueViaState = getIncrementalValueViaState
+	sling.SetIncrementalValueViaState = setIncrementalValueViaState
+	sling.GetWatchedFiles = getWatchedFiles
+	sling.SetWatchedFiles = setWatchedFiles
+	sling.GetFileState = getFileState
+	sling.SetFileState = setFileState
+	sling.GetQuarantine = getQuarantine
+	sling.SetQuarantine = setQuarantine
+}
+
+// State is the persisted incremental value (max update key value) of a stream
+type State struct {
+	Key       string    `json:"key" gorm:"primaryKey"`
+	Value     string    `json:"value"`
+	UpdatedDt time.Time `json:"updated_dt" gorm:"autoUpdateTime"`
+}
+
+// StateBackend stores the incremental values of the streams
+type StateBackend interface {
+	Get(key string) (value string, found bool, err error)
+	Set(states ...State) error
+	List() (states []State, err error)
+}
+
+// StateKey returns the state key of a stream, which does not depend on
+// the target object, so that the value survives target rebuilds
+func StateKey(cfg *sling.Config) string {
+	return g.F("%s/%s/%s",
+		strings.ToUpper(cfg.Source.Conn),
+		strings.ToUpper(cfg.Target.Conn),
+		strings.ToLower(cfg.StreamLabel()),
+	)
+}
+
+// NewStateBackend returns the state backend of the location (env var `SLING_STATE`):
+// `local` (or blank) for the store backend, or a connection name with a folder (`AWS_S3/sling_state`)
+func NewStateBackend(location string) (StateBackend, error) {
+	if location == "" || strings.EqualFold(location, "local") {
+		return GetBackend()
+	}
+	return NewBackend(location)
+}
+
+// getIncrementalValueViaState sets the incremental value from the state,
+// unless already set (from an override)
+func getIncrementalValueViaState(t *sling.TaskExecution) (err error) {
+	if t.Config.IncrementalVal != "" {
+		return nil
+	}
+
+	backend, err := NewStateBackend(os.Getenv("SLING_STATE"))
+	if err != nil {
+		return g.Error(err, "could not init state backend")
+	}
+
+	key := StateKey(t.Config)
+	value, found, err := backend.Get(key)
+	if err != nil {
+		return g.Error(err, "could not get state")
+	} else if found {
+		g.Debug("got incremental value from state (%s): %s", key, value)
+		t.Config.IncrementalVal = value
+	}
+
+	return nil
+}
+
+// setIncrementalValueViaState saves the max update key value read into the state
+func setIncrementalValueViaState(t *sling.TaskExecution) (err error) {
+	df := t.Df()
+	if df == nil {
+		return nil
+	}
+
+	col := df.Columns.GetColumn(t.Config.Source.UpdateKey)
+	if col == nil || col.Stats.MaxVal == nil {
+		return nil
+	}
+
+	value := iop.FormatValue(col.Stats.MaxVal, col.Type, t.Config.SrcConn.Type)
+	if value == "" {
+		return nil
+	}
+
+	backend, err := NewStateBackend(os.Getenv("SLING_STATE"))
+	if err != nil {
+		return g.Error(err, "could not init state backend")
+	}
+
+	key := StateKey(t.Config)
+	if err = backend.Set(State{Key: key, Value: value}); err != nil {
+		return g.Error(err, "could not set state")
+	}
+	g.Debug("saved incremental value into state (%s): %s", key, value)
+
+	return nil
+}
+
+// getWatchedFiles returns the already loaded files of a watched stream
+func getWatchedFiles(cfg *sling.Config) (files map[string]int64, err error) {
+	backend, err := NewStateBackend(os.Getenv("SLING_STATE"))
+	if err != nil {
+		return nil, g.Error(err, "could not init state backend")
+	}
+
+	files = map[string]int64{}
+	value, found, err := backend.Get("watch/" + StateKey(cfg))
+	if err != nil {
+		return nil, g.Error(err, "could not get watched files")
+	} else if found {
+		if err = g.Unmarshal(value, &files); err != nil {
+			return nil, g.Error(err, "could not parse watched files")
+		}
+	}
+
+	return files, nil
+}
+
+// setWatchedFiles saves the already loaded files of a watched stream
+func setWatchedFiles(cfg *sling.Config, files map[string]int64) (err error) {
+	backend, err := NewStateBackend(os.Getenv("SLING_STATE"))
+	if err != nil {
+		return g.Error(err, "could not init state backend")
+	}
+
+	if err = backend.Set(State{Key: "watch/" + StateKey(cfg), Value: g.Marshal(files)}); err != nil {
+		return g.Error(err, "could not set watched files")
+	}
+	return nil
+}
+
+// getFileState returns the state of the loaded files of a stream (file_incremental)
+func getFileState(cfg *sling.Config) (state sling.FileState, err error) {
+	backend, err := NewStateBackend(os.Getenv("SLING_STATE"))
+	if err != nil {
+		return state, g.Error(err, "could not init state backend")
+	}
+
+	value, found, err := backend.Get("files/" + StateKey(cfg))
+	if err != nil {
+		return state, g.Error(err, "could not get file state")
+	} else if found {
+		if err = g.Unmarshal(value, &state); err != nil {
+			return state, g.Error(err, "could not parse file state")
+		}
+	}
+
+	return state, nil
+}
+
+// setFileState saves the state of the loaded files of a stream (file_incremental)
+func setFileState(cfg *sling.Config, state sling.FileState) (err error) {
+	backend, err := NewStateBackend(os.Getenv("SLING_STATE"))
+	if err != nil {
+		return g.Error(err, "could not init state backend")
+	}
+
+	if err = backend.Set(State{Key: "files/" + StateKey(cfg), Value: g.Marshal(state)}); err != nil {
+		return g.Error(err, "could not set file state")
+	}
+	return nil
+}
+
+// QuarantinePrefix is the state key prefix of the quarantined streams
+const QuarantinePrefix = "quarantine/"
+
+// getQuarantine returns the quarantine reason of a stream, blank if not quarantined
+func getQuarantine(cfg *sling.Config) (reason string, err error) {
+	backend, err := NewStateBackend(os.Getenv("SLING_STATE"))
+	if err != nil {
+		return "", g.Error(err, "could not init state backend")
+	}
+
+	reason, _, err = backend.Get(QuarantinePrefix + StateKey(cfg))
+	if err != nil {
+		return "", g.Error(err, "could not get quarantine state")
+	}
+	return reason, nil
+}
+
+// setQuarantine quarantines a stream, or clears it with a blank reason
+func setQuarantine(cfg *sling.Config, reason string) (err error) {
+	backend, err := NewStateBackend(os.Getenv("SLING_STATE"))
+	if err != nil {
+		return g.Error(err, "could not init state backend")
+	}
+
+	if err = backend.Set(State{Key: QuarantinePrefix + StateKey(cfg), Value: reason}); err != nil {
+		return g.Error(err, "could not set quarantine state")
+	}
+	return nil
+}
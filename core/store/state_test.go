@@ -0,0 +1,75 @@
+package store
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/slingdata-io/sling-cli/core/dbio/connection"
+	"github.com/slingdata-io/sling-cli/core/sling"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncrementalState(t *testing.T) {
+	initTestDB(t)
+	t.Setenv("SLING_STATE", "local")
+	t.Setenv("SLING_DUCKDB_COMPUTE", "false")
+
+	folder := t.TempDir()
+	t.Setenv("SLING_STATE_TEST_DB", "sqlite://"+path.Join(folder, "source.db"))
+	connection.GetLocalConns(true)
+	t.Cleanup(func() {
+		os.Unsetenv("SLING_STATE_TEST_DB")
+		connection.GetLocalConns(true)
+	})
+
+	conn, err := connection.NewConnectionFromURL("test", "sqlite://"+path.Join(folder, "source.db"))
+	require.NoError(t, err)
+	dbConn, err := conn.AsDatabase()
+	require.NoError(t, err)
+	require.NoError(t, dbConn.Connect())
+	defer dbConn.Close()
+
+	_, err = dbConn.ExecMulti(`create table orders (id integer, seq integer); insert into orders values (1, 9), (2, 10), (3, 2)`)
+	require.NoError(t, err)
+
+	cfg := &sling.Config{
+		Source: sling.Source{Conn: "SLING_STATE_TEST_DB", Stream: "main.orders", UpdateKey: "seq"},
+		Target: sling.Target{Conn: "local", Object: "file://" + path.Join(folder, "orders.csv")},
+		Mode:   sling.IncrementalMode,
+	}
+	key := StateKey(cfg)
+	assert.Equal(t, "SLING_STATE_TEST_DB/LOCAL/main.orders", key)
+
+	run := func() (count uint64) {
+		cfg := &sling.Config{Source: cfg.Source, Target: cfg.Target, Mode: cfg.Mode}
+		require.NoError(t, cfg.Prepare())
+		task := sling.NewTask("", cfg)
+		require.NoError(t, task.Execute())
+		return task.GetCount()
+	}
+
+	backend, err := NewStateBackend("local")
+	require.NoError(t, err)
+
+	// the max value is saved, compared as numbers
+	assert.EqualValues(t, 3, run())
+	value, found, err := backend.Get(key)
+	if assert.NoError(t, err) && assert.True(t, found) {
+		assert.Equal(t, "10", value)
+	}
+
+	// only the new rows are read
+	_, err = dbConn.Exec(`insert into orders values (4, 11), (5, 1)`)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, run())
+	value, _, _ = backend.Get(key)
+	assert.Equal(t, "11", value)
+
+	// the value can be overridden (sling state set)
+	require.NoError(t, backend.Set(State{Key: key, Value: "0"}))
+	assert.EqualValues(t, 5, run())
+	value, _, _ = backend.Get(key)
+	assert.Equal(t, "11", value)
+}
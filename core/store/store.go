@@ -46,6 +46,10 @@ type Execution struct {
 	// Is an MD5 construct:`md5(Source, Target, Stream, Object)`.
 	StreamID string `json:"stream_id,omitempty" sql:"not null" gorm:"index"`
 
+	// StreamName is the name of the stream, and Object the target object
+	StreamName string `json:"stream_name,omitempty" gorm:"index"`
+	Object     string `json:"object,omitempty"`
+
 	// ConfigMD5 points to config table. not null
 	TaskMD5        string `json:"task_md5,omitempty" sql:"not null" gorm:"index"`
 	ReplicationMD5 string `json:"replication_md5,omitempty" sql:"not null" gorm:"index"`
@@ -143,20 +147,22 @@ func ToExecutionObject(t *sling.TaskExecution) *Execution {
 	bytes, _ := t.GetBytes()
 
 	exec := Execution{
-		ExecID:    t.ExecID,
-		StreamID:  t.Config.StreamID(),
-		Status:    t.Status,
-		StartTime: t.StartTime,
-		EndTime:   t.EndTime,
-		Bytes:     bytes,
-		Output:    t.Output.String(),
-		Rows:      t.GetCount(),
-		ProjectID: g.String(t.Config.Env["SLING_PROJECT_ID"]),
-		FilePath:  g.String(t.Config.Env["SLING_CONFIG_PATH"]),
-		WorkPath:  g.String(t.Config.Env["SLING_WORK_PATH"]),
-		Pid:       os.Getpid(),
-		Version:   core.Version,
-		TaskExec:  t,
+		ExecID:     t.ExecID,
+		StreamID:   t.Config.StreamID(),
+		StreamName: t.Config.StreamLabel(),
+		Object:     t.Config.Target.Object,
+		Status:     t.Status,
+		StartTime:  t.StartTime,
+		EndTime:    t.EndTime,
+		Bytes:      bytes,
+		Output:     t.Output.String(),
+		Rows:       t.GetCount(),
+		ProjectID:  g.String(t.Config.Env["SLING_PROJECT_ID"]),
+		FilePath:   g.String(t.Config.Env["SLING_CONFIG_PATH"]),
+		WorkPath:   g.String(t.Config.Env["SLING_WORK_PATH"]),
+		Pid:        os.Getpid(),
+		Version:    core.Version,
+		TaskExec:   t,
 	}
 
 	if t.Err != nil {
@@ -264,6 +270,99 @@ func StoreSet(t *sling.TaskExecution) {
 	// set in memory store
 	Store.Set(key, exec)
 
+	// persist finished replication streams, so that an interrupted run can be resumed
+	if exec.ReplicationMD5 != "" && (exec.Status.IsFinished() || exec.Status == sling.ExecStatusSkipped) {
+		if backend, err := GetBackend(); err != nil {
+			g.Debug("could not get store backend: %s", err.Error())
+		} else if err = backend.SaveExecution(exec); err != nil {
+			g.Debug("could not persist execution: %s", err.Error())
+		}
+	}
+
 	// sync status
 	syncStatus(exec)
 }
+
+// ReplicationRun is a persisted run of a replication, with
+// the status of each of its streams (by stream id)
+type ReplicationRun struct {
+	ExecID   string
+	Statuses map[string]sling.ExecStatus
+}
+
+// ReplicationRuns returns the persisted runs of a replication, newest first
+func ReplicationRuns(replicationMD5 string) (runs []ReplicationRun, err error) {
+	backend, err := GetBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	execs, err := backend.ListExecutions(ExecutionFilter{ReplicationMD5: replicationMD5})
+	if err != nil {
+		return nil, g.Error(err, "could not get replication executions")
+	}
+
+	index := map[string]int{}
+	for _, exec := range execs {
+		if exec.ExecID == "" {
+			continue
+		}
+		i, ok := index[exec.ExecID]
+		if !ok {
+			i = len(runs)
+			index[exec.ExecID] = i
+			runs = append(runs, ReplicationRun{ExecID: exec.ExecID, Statuses: map[string]sling.ExecStatus{}})
+		}
+		if _, ok := runs[i].Statuses[exec.StreamID]; !ok {
+			runs[i].Statuses[exec.StreamID] = exec.Status // newest first
+		}
+	}
+
+	return runs, nil
+}
+
+// HistoryOptions are the filters of the executions history
+type HistoryOptions struct {
+	Last   int    // the number of most recent executions, 20 by default
+	Stream string // the stream name, supports `*` wildcards
+}
+
+// History returns the most recent persisted executions, newest first
+func History(opts HistoryOptions) (execs []Execution, err error) {
+	backend, err := GetBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Last <= 0 {
+		opts.Last = 20
+	}
+
+	execs, err = backend.ListExecutions(ExecutionFilter{Stream: opts.Stream, Last: opts.Last})
+	if err != nil {
+		return nil, g.Error(err, "could not get executions")
+	}
+
+	return execs, nil
+}
+
+// GetExecutions returns the persisted executions (one per stream) of a run
+func GetExecutions(execID string) (execs []Execution, err error) {
+	backend, err := GetBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	if execs, err = backend.ListExecutions(ExecutionFilter{ExecID: execID}); err != nil {
+		return nil, g.Error(err, "could not get executions of %s", execID)
+	} else if len(execs) == 0 {
+		return nil, g.Error("no executions found for %s", execID)
+	}
+
+	// in stream order
+	for i, j := 0, len(execs)-1; i < j; i, j = i+1, j-1 {
+		execs[i], execs[j] = execs[j], execs[i]
+	}
+
+	return execs, nil
+}
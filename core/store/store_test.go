@@ -0,0 +1,119 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/slingdata-io/sling-cli/core/env"
+	"github.com/slingdata-io/sling-cli/core/sling"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// initTestDB points the store to a temporary .sling.db
+func initTestDB(t *testing.T) {
+	t.Setenv("SLING_STATE_CONNECTION", "")
+	t.Setenv("SLING_STATE", "")
+
+	homeDir, db, conn := env.HomeDir, Db, Conn
+	env.HomeDir, Db = t.TempDir(), nil
+	InitDB()
+	require.NotNil(t, Db)
+
+	t.Cleanup(func() {
+		Conn.Close()
+		env.HomeDir, Db, Conn = homeDir, db, conn
+	})
+}
+
+func TestHistory(t *testing.T) {
+	initTestDB(t)
+
+	execs := []Execution{
+		{ExecID: "exec1", StreamName: "public.orders", Status: sling.ExecStatusSuccess, Rows: 10},
+		{ExecID: "exec1", StreamName: "public.users", Status: sling.ExecStatusError},
+		{ExecID: "exec2", StreamName: "public.orders", Status: sling.ExecStatusSuccess, Rows: 5},
+		{ExecID: "exec2", StreamName: "sales.items", Status: sling.ExecStatusSuccess, Rows: 1},
+	}
+	require.NoError(t, Db.Create(&execs).Error)
+
+	streamsOf := func(execs []Execution) []string {
+		return lo.Map(execs, func(exec Execution, i int) string { return exec.ExecID + "/" + exec.StreamName })
+	}
+
+	tests := []struct {
+		name     string
+		opts     HistoryOptions
+		expected []string
+	}{
+		{
+			name:     "all newest first",
+			opts:     HistoryOptions{},
+			expected: []string{"exec2/sales.items", "exec2/public.orders", "exec1/public.users", "exec1/public.orders"},
+		},
+		{
+			name:     "last",
+			opts:     HistoryOptions{Last: 2},
+			expected: []string{"exec2/sales.items", "exec2/public.orders"},
+		},
+		{
+			name:     "stream wildcard",
+			opts:     HistoryOptions{Stream: "public.*"},
+			expected: []string{"exec2/public.orders", "exec1/public.users", "exec1/public.orders"},
+		},
+		{
+			name:     "stream and last",
+			opts:     HistoryOptions{Stream: "public.orders", Last: 1},
+			expected: []string{"exec2/public.orders"},
+		},
+		{
+			name:     "no match",
+			opts:     HistoryOptions{Stream: "missing.*"},
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execs, err := History(tt.opts)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.expected, streamsOf(execs))
+			}
+		})
+	}
+
+	t.Run("show", func(t *testing.T) {
+		execs, err := GetExecutions("exec1")
+		if assert.NoError(t, err) {
+			assert.Equal(t, []string{"exec1/public.orders", "exec1/public.users"}, streamsOf(execs)) // in stream order
+			assert.EqualValues(t, 10, execs[0].Rows)
+		}
+
+		_, err = GetExecutions("missing")
+		assert.Error(t, err)
+	})
+}
+
+func TestReplicationRuns(t *testing.T) {
+	initTestDB(t)
+
+	execs := []Execution{
+		{ExecID: "exec1", ReplicationMD5: "rep", StreamID: "s1", Status: sling.ExecStatusSuccess},
+		{ExecID: "exec1", ReplicationMD5: "rep", StreamID: "s2", Status: sling.ExecStatusError},
+		{ExecID: "exec2", ReplicationMD5: "other", StreamID: "s1", Status: sling.ExecStatusError},
+		{ExecID: "exec3", ReplicationMD5: "rep", StreamID: "s2", Status: sling.ExecStatusRunning},
+		{ExecID: "exec3", ReplicationMD5: "rep", StreamID: "s2", Status: sling.ExecStatusSuccess},
+	}
+	require.NoError(t, Db.Create(&execs).Error)
+
+	runs, err := ReplicationRuns("rep")
+	require.NoError(t, err)
+	assert.Equal(t, []ReplicationRun{
+		{ExecID: "exec3", Statuses: map[string]sling.ExecStatus{"s2": sling.ExecStatusSuccess}},
+		{ExecID: "exec1", Statuses: map[string]sling.ExecStatus{"s1": sling.ExecStatusSuccess, "s2": sling.ExecStatusError}},
+	}, runs)
+
+	runs, err = ReplicationRuns("missing")
+	assert.NoError(t, err)
+	assert.Empty(t, runs)
+}
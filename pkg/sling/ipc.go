@@ -0,0 +1,190 @@
+package sling
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/flarco/g"
+	"github.com/slingdata-io/sling-cli/core"
+)
+
+// IPCProtocolVersion is the version of the IPC protocol, incremented on breaking changes
+const IPCProtocolVersion = 1
+
+// IPCRequest is a request of the IPC protocol, one JSON object per line:
+//   - `{"id": "1", "command": "run", "config": {...}}`: runs a task config (JSON object, or JSON / YAML string)
+//   - `{"id": "1", "command": "cancel"}`: cancels the running task of the request id
+type IPCRequest struct {
+	ID      string          `json:"id"`
+	Command string          `json:"command"`
+	Config  json.RawMessage `json:"config,omitempty"`
+}
+
+// IPCEvent is an event of the IPC protocol, one JSON object per line:
+//   - `ready`: sent once, with the protocol and sling versions
+//   - `started`: the task of the request started, with its exec_id
+//   - `progress`: the progress of the task, at each interval
+//   - `done`: the final stats of the task, with the error if it failed
+//   - `error`: the request could not be processed (invalid request or config)
+type IPCEvent struct {
+	ID       string    `json:"id,omitempty"`
+	Event    string    `json:"event"`
+	Protocol int       `json:"protocol,omitempty"`
+	Version  string    `json:"version,omitempty"`
+	ExecID   string    `json:"exec_id,omitempty"`
+	Progress *Progress `json:"progress,omitempty"`
+	Stats    *Stats    `json:"stats,omitempty"`
+	Error    *IPCError `json:"error,omitempty"`
+}
+
+// IPCError is a structured error
+type IPCError struct {
+	Message string `json:"message"` // the root cause
+	Details string `json:"details"` // the full error, with its context
+}
+
+func newIPCError(err error) *IPCError {
+	return &IPCError{Message: g.ErrMsgSimple(err), Details: g.ErrMsg(err)}
+}
+
+// ipcSession serves the requests of one client
+type ipcSession struct {
+	ctx     context.Context
+	encoder *json.Encoder
+	cancels map[string]context.CancelFunc // the running tasks, by request id
+	wg      sync.WaitGroup
+	mux     sync.Mutex
+}
+
+// ServeIPC reads the requests from r and writes the events to w (JSON lines),
+// until r is closed (the running tasks are then waited for) or the context is done.
+func ServeIPC(ctx context.Context, r io.Reader, w io.Writer) (err error) {
+	s := &ipcSession{
+		ctx:     ctx,
+		encoder: json.NewEncoder(w),
+		cancels: map[string]context.CancelFunc{},
+	}
+	defer s.wg.Wait()
+
+	s.send(IPCEvent{Event: "ready", Protocol: IPCProtocolVersion, Version: core.Version})
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var req IPCRequest
+		if err = json.Unmarshal([]byte(line), &req); err != nil {
+			s.send(IPCEvent{Event: "error", Error: newIPCError(g.Error(err, "invalid request"))})
+			continue
+		}
+		s.handle(req)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return g.Error(err, "could not read requests")
+	}
+	return nil
+}
+
+func (s *ipcSession) send(event IPCEvent) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if err := s.encoder.Encode(event); err != nil {
+		g.Debug("could not send ipc event: %s", err.Error())
+	}
+}
+
+func (s *ipcSession) handle(req IPCRequest) {
+	switch req.Command {
+	case "run":
+		if err := s.run(req); err != nil {
+			s.send(IPCEvent{ID: req.ID, Event: "error", Error: newIPCError(err)})
+		}
+	case "cancel":
+		s.mux.Lock()
+		cancel, ok := s.cancels[req.ID]
+		s.mux.Unlock()
+		if !ok {
+			s.send(IPCEvent{ID: req.ID, Event: "error", Error: newIPCError(g.Error("no running task for id: %s", req.ID))})
+			return
+		}
+		cancel()
+	default:
+		s.send(IPCEvent{ID: req.ID, Event: "error", Error: newIPCError(g.Error("unknown command: %s", req.Command))})
+	}
+}
+
+// run starts the task of the request in the background
+func (s *ipcSession) run(req IPCRequest) (err error) {
+	if req.ID == "" {
+		return g.Error("request id is required")
+	}
+
+	// the config is a JSON object, or a JSON / YAML string
+	cfgStr := string(req.Config)
+	if strings.HasPrefix(strings.TrimSpace(cfgStr), `"`) {
+		if err = json.Unmarshal(req.Config, &cfgStr); err != nil {
+			return g.Error(err, "invalid config")
+		}
+	}
+
+	cfg, err := NewConfig(cfgStr)
+	if err != nil {
+		return g.Error(err, "invalid config")
+	}
+
+	task, err := NewTask(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.mux.Lock()
+	if _, ok := s.cancels[req.ID]; ok {
+		s.mux.Unlock()
+		cancel()
+		return g.Error("a task is already running for id: %s", req.ID)
+	}
+	s.cancels[req.ID] = cancel
+	s.mux.Unlock()
+
+	s.send(IPCEvent{ID: req.ID, Event: "started", ExecID: task.ExecID()})
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+
+		progressDone := make(chan struct{})
+		go func() {
+			defer close(progressDone)
+			for p := range task.Progress() {
+				s.send(IPCEvent{ID: req.ID, Event: "progress", ExecID: task.ExecID(), Progress: &p})
+			}
+		}()
+
+		err := task.Run(ctx)
+		<-progressDone // the done event is the last one
+
+		s.mux.Lock()
+		delete(s.cancels, req.ID)
+		s.mux.Unlock()
+
+		stats := task.Stats()
+		event := IPCEvent{ID: req.ID, Event: "done", ExecID: task.ExecID(), Stats: &stats}
+		if err != nil {
+			event.Error = newIPCError(err)
+		}
+		s.send(event)
+	}()
+
+	return nil
+}
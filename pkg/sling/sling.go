@@ -0,0 +1,203 @@
+// Package sling is the Go SDK to embed sling tasks into applications.
+//
+// Unlike the CLI, the tasks do not set the env vars of their config into the
+// process env, do not show a progress bar and are not recorded in the local
+// store, so that several tasks can run concurrently in the same process:
+//
+//	cfg, err := sling.NewConfig(`{"source": {"conn": "PG", "stream": "public.users"}, "target": {"conn": "SNOWFLAKE", "object": "public.users"}}`)
+//	task, err := sling.NewTask(cfg)
+//	go func() {
+//		for p := range task.Progress() {
+//			log.Printf("%s: %d rows", p.Status, p.Rows)
+//		}
+//	}()
+//	err = task.Run(ctx)
+//	stats := task.Stats()
+//
+// The `env` of a config only applies the task settings (e.g. SLING_LIMIT,
+// SLING_ALLOW_EMPTY, SLING_LOADED_AT_COLUMN). The process settings, such as
+// SAMPLE_SIZE or the connection env vars, must be set in the process env.
+package sling
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/flarco/g"
+	slingcore "github.com/slingdata-io/sling-cli/core/sling"
+)
+
+// Config is the config of a task (source, target, mode, options)
+type Config = slingcore.Config
+
+// ExecStatus is the status of a task
+type ExecStatus = slingcore.ExecStatus
+
+// NewConfig parses a task config, as JSON or YAML
+func NewConfig(cfgStr string) (cfg *Config, err error) {
+	cfg = &Config{}
+	if err = cfg.Unmarshal(cfgStr); err != nil {
+		return nil, g.Error(err, "Unable to parse config payload")
+	}
+
+	cfg.Options.Embedded = true // before preparing, to not set the env into the process
+	if err = cfg.Prepare(); err != nil {
+		return nil, g.Error(err, "Unable to prepare config")
+	}
+	return cfg, nil
+}
+
+// Task is an embedded sling task
+type Task struct {
+	ProgressInterval time.Duration // the interval between progress updates, 1 second by default
+
+	exec     *slingcore.TaskExecution
+	progress chan Progress
+	started  bool
+	mux      sync.Mutex
+}
+
+// Progress is the progress of a running task
+type Progress struct {
+	Status   ExecStatus `json:"status"`
+	Step     string     `json:"step,omitempty"` // the current step, e.g. "writing to target database"
+	Rows     uint64     `json:"rows"`
+	Bytes    uint64     `json:"bytes"`
+	RowRate  int64      `json:"row_rate"`  // rows per second
+	ByteRate int64      `json:"byte_rate"` // bytes per second
+	Elapsed  float64    `json:"elapsed"`   // in seconds
+}
+
+// Stats are the stats of a task
+type Stats struct {
+	ExecID    string     `json:"exec_id"`
+	Status    ExecStatus `json:"status"`
+	Rows      uint64     `json:"rows"`
+	InBytes   uint64     `json:"in_bytes"`
+	OutBytes  uint64     `json:"out_bytes"`
+	StartTime *time.Time `json:"start_time,omitempty"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+	Warnings  []string   `json:"warnings,omitempty"`
+	Error     error      `json:"-"`
+}
+
+// NewTask prepares a task from a config (connections, type, defaults)
+func NewTask(cfg *Config) (*Task, error) {
+	if cfg == nil {
+		return nil, g.Error("config is nil")
+	}
+	cfg.Options.Embedded = true
+
+	exec := slingcore.NewTask("", cfg)
+	if exec.Err != nil {
+		return nil, g.Error(exec.Err, "could not init task")
+	}
+
+	return &Task{
+		ProgressInterval: time.Second,
+		exec:             exec,
+		progress:         make(chan Progress, 100),
+	}, nil
+}
+
+// ExecID returns the execution ID of the task
+func (t *Task) ExecID() string {
+	return t.exec.ExecID
+}
+
+// Run runs the task until it is done, or until the context is cancelled.
+// A task can only run once.
+func (t *Task) Run(ctx context.Context) (err error) {
+	t.mux.Lock()
+	if t.started {
+		t.mux.Unlock()
+		return g.Error("task %s has already run", t.exec.ExecID)
+	}
+	t.started = true
+	t.mux.Unlock()
+
+	t.exec.Context = g.NewContext(ctx)
+
+	done := make(chan struct{})
+	reported := make(chan struct{})
+	go t.report(done, reported)
+
+	err = t.exec.Execute()
+
+	close(done)
+	<-reported
+
+	if err != nil {
+		return g.Error(err, "error running task")
+	}
+	return nil
+}
+
+// report sends the progress at each interval, then the final progress,
+// and closes the progress channel
+func (t *Task) report(done, reported chan struct{}) {
+	defer close(reported)
+	defer close(t.progress)
+
+	interval := t.ProgressInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			select {
+			case t.progress <- t.snapshot():
+			default: // do not block the task if not consumed
+			}
+		case <-done:
+			select {
+			case t.progress <- t.snapshot():
+			default:
+			}
+			return
+		}
+	}
+}
+
+func (t *Task) snapshot() (p Progress) {
+	p = Progress{
+		Status: t.exec.GetStatus(),
+		Step:   t.exec.GetProgress(),
+		Rows:   t.exec.GetCount(),
+	}
+	p.Bytes, _ = t.exec.GetBytes()
+	p.RowRate, p.ByteRate = t.exec.GetRate(0)
+	if start, end := t.exec.GetTimes(); start != nil {
+		endTime := time.Now()
+		if end != nil {
+			endTime = *end
+		}
+		p.Elapsed = endTime.Sub(*start).Seconds()
+	}
+	return p
+}
+
+// Progress returns the channel of the progress updates, closed when the run is done.
+// Updates are dropped if the channel is not consumed.
+func (t *Task) Progress() <-chan Progress {
+	return t.progress
+}
+
+// Stats returns the stats of the task
+func (t *Task) Stats() (stats Stats) {
+	stats = Stats{
+		ExecID:   t.exec.ExecID,
+		Status:   t.exec.GetStatus(),
+		Rows:     t.exec.GetCount(),
+		Warnings: t.exec.Warnings(),
+		Error:    t.exec.Err,
+	}
+	stats.StartTime, stats.EndTime = t.exec.GetTimes()
+	stats.InBytes, stats.OutBytes = t.exec.GetBytes()
+	return stats
+}
@@ -0,0 +1,168 @@
+package sling
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flarco/g"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTask(t *testing.T) {
+	folder := t.TempDir()
+	srcPath := path.Join(folder, "in.csv")
+	err := os.WriteFile(srcPath, []byte("id,name\n1,a\n2,b\n3,c\n"), 0644)
+	require.NoError(t, err)
+
+	// run concurrently, with different env vars
+	tasks := make([]*Task, 3)
+	for i := range tasks {
+		cfg, err := NewConfig(g.Marshal(g.M(
+			"source", g.M("conn", "local", "stream", "file://"+srcPath),
+			"target", g.M("conn", "local", "object", "file://"+path.Join(folder, g.F("out%d.jsonl", i))),
+			"env", g.M("SLING_TEST_SDK_VAR", g.F("val%d", i), "SLING_EXEC_ID_COLUMN", "true"),
+		)))
+		require.NoError(t, err)
+
+		tasks[i], err = NewTask(cfg)
+		require.NoError(t, err)
+		tasks[i].ProgressInterval = time.Millisecond // read while running
+	}
+
+	wg := sync.WaitGroup{}
+	for _, task := range tasks {
+		wg.Add(1)
+		go func(task *Task) {
+			defer wg.Done()
+			var last Progress
+			for p := range task.Progress() {
+				last = p
+			}
+			assert.Equal(t, "success", string(last.Status))
+			assert.EqualValues(t, 3, last.Rows)
+		}(task)
+	}
+
+	for _, task := range tasks {
+		wg.Add(1)
+		go func(task *Task) {
+			defer wg.Done()
+			assert.NoError(t, task.Run(context.Background()))
+		}(task)
+	}
+	wg.Wait()
+
+	// env vars are not set into the process env, but are applied
+	assert.Empty(t, os.Getenv("SLING_TEST_SDK_VAR"))
+	for i, task := range tasks {
+		stats := task.Stats()
+		assert.Equal(t, "success", string(stats.Status))
+		assert.EqualValues(t, 3, stats.Rows)
+		assert.NoError(t, stats.Error)
+
+		out, err := os.ReadFile(path.Join(folder, g.F("out%d.jsonl", i)))
+		require.NoError(t, err)
+		assert.Contains(t, string(out), task.ExecID())
+	}
+
+	// a task runs once
+	assert.Error(t, tasks[0].Run(context.Background()))
+
+	// task settings apply from the config env
+	cfg, err := NewConfig(g.Marshal(g.M(
+		"source", g.M("conn", "local", "stream", "file://"+srcPath),
+		"target", g.M("conn", "local", "object", "file://"+path.Join(folder, "limit.jsonl")),
+		"env", g.M("SLING_LIMIT", "1"),
+	)))
+	require.NoError(t, err)
+	task, err := NewTask(cfg)
+	require.NoError(t, err)
+	require.NoError(t, task.Run(context.Background()))
+	assert.EqualValues(t, 1, task.Stats().Rows)
+	assert.Empty(t, os.Getenv("SLING_LIMIT"))
+
+	// cancelled context
+	cfg, err = NewConfig(g.Marshal(g.M(
+		"source", g.M("conn", "local", "stream", "file://"+srcPath),
+		"target", g.M("conn", "local", "object", "file://"+path.Join(folder, "out.jsonl")),
+	)))
+	require.NoError(t, err)
+	task, err = NewTask(cfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, task.Run(ctx))
+}
+
+func TestServeIPC(t *testing.T) {
+	folder := t.TempDir()
+	srcPath := path.Join(folder, "in.csv")
+	err := os.WriteFile(srcPath, []byte("id,name\n1,a\n2,b\n"), 0644)
+	require.NoError(t, err)
+
+	requests := []string{
+		g.Marshal(g.M("id", "1", "command", "run", "config", g.M(
+			"source", g.M("conn", "local", "stream", "file://"+srcPath),
+			"target", g.M("conn", "local", "object", "file://"+path.Join(folder, "out.jsonl")),
+		))),
+		// config as a YAML string
+		g.Marshal(g.M("id", "2", "command", "run", "config", g.F("source: {conn: local, stream: 'file://%s'}\ntarget: {conn: local, object: 'file://%s/out2.jsonl'}", srcPath, folder))),
+		g.Marshal(g.M("id", "3", "command", "run", "config", g.M("source", g.M("conn", "local", "stream", "file://"+path.Join(folder, "missing.csv")), "target", g.M("conn", "local", "object", "file://"+path.Join(folder, "out3.jsonl"))))),
+		g.Marshal(g.M("id", "4", "command", "unknown")),
+		`not json`,
+	}
+
+	out := &bytes.Buffer{}
+	err = ServeIPC(context.Background(), bytes.NewBufferString(strings.Join(requests, "\n")), out)
+	require.NoError(t, err)
+
+	events := map[string][]IPCEvent{}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	for i, line := range lines {
+		var event IPCEvent
+		require.NoError(t, json.Unmarshal([]byte(line), &event), line)
+		if i == 0 {
+			assert.Equal(t, "ready", event.Event)
+			assert.Equal(t, IPCProtocolVersion, event.Protocol)
+		}
+		events[event.ID] = append(events[event.ID], event)
+	}
+
+	for _, id := range []string{"1", "2"} {
+		if assert.GreaterOrEqual(t, len(events[id]), 2, id) {
+			first, last := events[id][0], events[id][len(events[id])-1]
+			assert.Equal(t, "started", first.Event)
+			assert.Equal(t, "done", last.Event)
+			assert.Nil(t, last.Error)
+			if assert.NotNil(t, last.Stats) {
+				assert.Equal(t, "success", string(last.Stats.Status))
+				assert.EqualValues(t, 2, last.Stats.Rows)
+				assert.Equal(t, first.ExecID, last.Stats.ExecID)
+			}
+		}
+	}
+
+	if assert.NotEmpty(t, events["3"]) {
+		last := events["3"][len(events["3"])-1]
+		assert.Equal(t, "done", last.Event)
+		if assert.NotNil(t, last.Error) {
+			assert.NotEmpty(t, last.Error.Message)
+			assert.Equal(t, "error", string(last.Stats.Status))
+		}
+	}
+
+	if assert.Len(t, events["4"], 1) {
+		assert.Equal(t, "error", events["4"][0].Event)
+		assert.Contains(t, events["4"][0].Error.Message, "unknown command")
+	}
+	assert.Equal(t, "error", events[""][1].Event) // invalid request, after ready
+}